@@ -0,0 +1,319 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"mts/internal/domain"
+	"shared/idgen"
+)
+
+const (
+	// defaultAccessTokenLifetime applies when service.token_lifetime is not set
+	defaultAccessTokenLifetime = time.Hour
+	// defaultRefreshTokenLifetime applies when service.refresh_token_lifetime is not set
+	defaultRefreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+// TokenOptions configures the issued tokens; zero lifetimes keep the defaults (1h access, 30 days refresh)
+type TokenOptions struct {
+	// Secret is the HS256 key signing access tokens
+	Secret          []byte
+	AccessLifetime  time.Duration
+	RefreshLifetime time.Duration
+}
+
+// revokedTokenKeyPrefix starts the cache keys of the revocation list, followed by the jti of the token
+const revokedTokenKeyPrefix = "revoked_token:"
+
+// NewAuthAppService keeps the revoked access tokens in cache, shared by the instances when it is
+func NewAuthAppService(
+	userStorage domain.UserStorage,
+	refreshTokens domain.RefreshTokenStorage,
+	cache domain.Cache,
+	tokens TokenOptions,
+) domain.AuthAppService {
+	if tokens.AccessLifetime <= 0 {
+		tokens.AccessLifetime = defaultAccessTokenLifetime
+	}
+	if tokens.RefreshLifetime <= 0 {
+		tokens.RefreshLifetime = defaultRefreshTokenLifetime
+	}
+
+	return &authAppService{
+		userStorage:   userStorage,
+		refreshTokens: refreshTokens,
+		cache:         cache,
+		tokens:        tokens,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+			jwt.WithExpirationRequired(),
+			jwt.WithTimeFunc(domain.Now),
+		),
+	}
+}
+
+// accessTokenClaims adds the user's roles to the registered claims, so authorization needs no user lookup
+type accessTokenClaims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles"`
+}
+
+type authAppService struct {
+	userStorage   domain.UserStorage
+	refreshTokens domain.RefreshTokenStorage
+	cache         domain.Cache
+	tokens        TokenOptions
+	parser        *jwt.Parser
+}
+
+func (s *authAppService) Login(ctx context.Context, req *domain.LoginRequest) (*domain.AuthTokens, error) {
+	ctx, span := tracer.Start(ctx, "AuthAppService.Login")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "Login").
+		Logger()
+
+	logger.Info().Msg("logging in user")
+
+	user, err := s.userStorage.UserByEmail(ctx, req.Email)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			logger.Warn().Msg("login with unknown email")
+			return nil, domain.ErrInvalidCredentials
+		}
+		logger.Error().Err(err).Msg("failed to find user in storage")
+		return nil, err
+	}
+
+	if !user.VerifyPassword(req.Password) {
+		logger.Warn().Str("user_id", user.Id.String()).Msg("login with wrong password")
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	refreshToken, refreshSecret, err := domain.NewRefreshToken(user.Id, s.tokens.RefreshLifetime)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate refresh token")
+		return nil, err
+	}
+
+	if err = s.refreshTokens.CreateRefreshToken(ctx, refreshToken); err != nil {
+		logger.Error().Err(err).Msg("failed to store refresh token")
+		return nil, err
+	}
+
+	tokens, err := s.issue(user, refreshToken, refreshSecret)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to sign access token")
+		return nil, err
+	}
+
+	logger.Info().
+		Str("user_id", user.Id.String()).
+		Msg("user logged in successfully")
+
+	return tokens, nil
+}
+
+func (s *authAppService) Refresh(ctx context.Context, refreshSecret string) (*domain.AuthTokens, error) {
+	ctx, span := tracer.Start(ctx, "AuthAppService.Refresh")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "Refresh").
+		Logger()
+
+	logger.Info().Msg("refreshing tokens")
+
+	current, err := s.refreshTokens.RefreshToken(ctx, domain.HashRefreshToken(refreshSecret))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidRefreshToken) {
+			logger.Warn().Msg("refresh with unknown token")
+		} else {
+			logger.Error().Err(err).Msg("failed to find refresh token in storage")
+		}
+		return nil, err
+	}
+	logger = logger.With().Str("user_id", current.UserId.String()).Logger()
+
+	if current.IsRotated() {
+		return nil, s.revokeReused(ctx, logger, current)
+	}
+
+	if !current.IsActive() {
+		logger.Warn().Msg("refresh with revoked or expired token")
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	users, err := s.userStorage.Users(ctx, &domain.GetUsersRequest{Ids: []uuid.UUID{current.UserId}, Limit: 1})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch user from storage")
+		return nil, err
+	}
+	if len(users) == 0 {
+		logger.Warn().Msg("refresh for deleted user")
+		return nil, domain.ErrInvalidRefreshToken
+	}
+
+	next, nextSecret, err := domain.NewRefreshToken(current.UserId, s.tokens.RefreshLifetime)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to generate refresh token")
+		return nil, err
+	}
+
+	if err = s.refreshTokens.RotateRefreshToken(ctx, current.Id, next); err != nil {
+		if errors.Is(err, domain.ErrRefreshTokenReused) {
+			// another refresh with the same token got there first
+			return nil, s.revokeReused(ctx, logger, current)
+		}
+		logger.Error().Err(err).Msg("failed to rotate refresh token")
+		return nil, err
+	}
+
+	tokens, err := s.issue(users[0], next, nextSecret)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to sign access token")
+		return nil, err
+	}
+
+	logger.Info().Msg("tokens refreshed successfully")
+
+	return tokens, nil
+}
+
+// revokeReused answers a rotated token presented again. Either the client or someone who stole the token
+// refreshed with it before, and there is no telling which, so the user's every session is ended.
+func (s *authAppService) revokeReused(ctx context.Context, logger zerolog.Logger, reused *domain.RefreshToken) error {
+	logger.Warn().
+		Str("security_event", "refresh_token_reuse").
+		Str("refresh_token_id", reused.Id.String()).
+		Msg("rotated refresh token reused, revoking all refresh tokens of the user")
+
+	if err := s.refreshTokens.RevokeUserRefreshTokens(ctx, reused.UserId); err != nil {
+		logger.Error().Err(err).Msg("failed to revoke refresh tokens")
+		return err
+	}
+
+	return domain.ErrRefreshTokenReused
+}
+
+func (s *authAppService) issue(user *domain.User, refreshToken *domain.RefreshToken, refreshSecret string) (*domain.AuthTokens, error) {
+	now := domain.Now()
+	expiresAt := now.Add(s.tokens.AccessLifetime)
+
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        idgen.New().String(),
+			Subject:   user.Id.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Roles: user.Roles,
+	}).SignedString(s.tokens.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.AuthTokens{
+		AccessToken:      accessToken,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshSecret,
+		RefreshExpiresAt: refreshToken.ExpiresAt,
+	}, nil
+}
+
+func (s *authAppService) Authenticate(ctx context.Context, token string) (*domain.AccessClaims, error) {
+	ctx, span := tracer.Start(ctx, "AuthAppService.Authenticate")
+	defer span.End()
+
+	var claims accessTokenClaims
+	if _, err := s.parser.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return s.tokens.Secret, nil
+	}); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidToken, err)
+	}
+
+	userId, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: subject is not a user id", domain.ErrInvalidToken)
+	}
+
+	if claims.ID == "" {
+		return nil, fmt.Errorf("%w: token has no id", domain.ErrInvalidToken)
+	}
+
+	_, revoked, err := s.cache.Get(ctx, revokedTokenKeyPrefix+claims.ID)
+	if err != nil {
+		// a token that may have been logged out is not let through
+		zerolog.Ctx(ctx).Error().Err(err).Msg("token revocation check failed")
+		return nil, fmt.Errorf("%w: %v", domain.ErrAuthUnavailable, err)
+	}
+	if revoked {
+		return nil, fmt.Errorf("%w: token was revoked", domain.ErrInvalidToken)
+	}
+
+	return &domain.AccessClaims{
+		TokenId:   claims.ID,
+		UserId:    userId,
+		Roles:     claims.Roles,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+func (s *authAppService) Logout(ctx context.Context, claims *domain.AccessClaims, refreshToken string) error {
+	ctx, span := tracer.Start(ctx, "AuthAppService.Logout")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "Logout").
+		Str("user_id", claims.UserId.String()).
+		Logger()
+
+	current, err := s.refreshTokens.RefreshToken(ctx, domain.HashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidRefreshToken) {
+			logger.Warn().Msg("logout with unknown refresh token")
+		} else {
+			logger.Error().Err(err).Msg("failed to find refresh token in storage")
+		}
+		return err
+	}
+	if current.UserId != claims.UserId {
+		logger.Warn().Str("refresh_token_id", current.Id.String()).Msg("logout with refresh token of another user")
+		return domain.ErrInvalidRefreshToken
+	}
+
+	if current.IsRotated() {
+		// the session went on with a later token, which can't be told apart from the other sessions of the user
+		err = s.refreshTokens.RevokeUserRefreshTokens(ctx, current.UserId)
+	} else {
+		err = s.refreshTokens.RevokeRefreshToken(ctx, current.Id)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to revoke refresh token")
+		return err
+	}
+
+	// the entry only has to outlive the token, after that the token is rejected as expired anyway
+	ttl := claims.ExpiresAt.Sub(domain.Now())
+	if ttl <= 0 {
+		logger.Info().Msg("user logged out successfully")
+		return nil
+	}
+
+	if err = s.cache.Set(ctx, revokedTokenKeyPrefix+claims.TokenId, []byte{1}, ttl); err != nil {
+		logger.Error().Err(err).Msg("failed to revoke access token")
+		return err
+	}
+
+	logger.Info().Msg("user logged out successfully")
+
+	return nil
+}
@@ -0,0 +1,360 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/domain"
+	"mts/internal/repository/cache"
+)
+
+var testJwtSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// setClockForTest makes the domain read the time from clock until t ends
+func setClockForTest(t *testing.T, clock domain.Clock) {
+	t.Helper()
+	previous := domain.CurrentConfig()
+	domainConfig := previous
+	domainConfig.Clock = clock
+	domain.Configure(domainConfig)
+	t.Cleanup(func() { domain.Configure(previous) })
+}
+
+func TestAuthAppService_Login(t *testing.T) {
+	user := (&domain.Factory{}).User()
+	user.Roles = []string{domain.RoleUser, domain.RoleAdmin}
+	userStorage := new(mockUserStorage)
+	userStorage.On("UserByEmail", mock.Anything, user.Email).Return(user, nil)
+	userStorage.On("UserByEmail", mock.Anything, mock.Anything).Return(nil, domain.ErrUserNotFound)
+	service := NewAuthAppService(userStorage, newMemoryRefreshTokenStorage(), cache.NewMemoryCache(), TokenOptions{Secret: testJwtSecret})
+
+	token, err := service.Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), token.ExpiresAt, time.Minute)
+
+	assert.NotEmpty(t, token.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(defaultRefreshTokenLifetime), token.RefreshExpiresAt, time.Minute)
+
+	claims, err := service.Authenticate(context.Background(), token.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.Id, claims.UserId)
+	assert.Equal(t, user.Roles, claims.Roles)
+
+	_, err = service.Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "wrong password"})
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+
+	_, err = service.Login(context.Background(), &domain.LoginRequest{Email: "nobody@example.com", Password: "password123"})
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+}
+
+func TestAuthAppService_Authenticate_RejectsInvalidTokens(t *testing.T) {
+	clock := domain.NewFakeClock(time.Now())
+	setClockForTest(t, clock)
+
+	user := (&domain.Factory{}).User()
+	userStorage := new(mockUserStorage)
+	userStorage.On("UserByEmail", mock.Anything, user.Email).Return(user, nil)
+	service := NewAuthAppService(userStorage, newMemoryRefreshTokenStorage(), cache.NewMemoryCache(), TokenOptions{Secret: testJwtSecret})
+
+	token, err := service.Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	forged, err := NewAuthAppService(userStorage, newMemoryRefreshTokenStorage(), cache.NewMemoryCache(), TokenOptions{Secret: []byte("another secret")}).
+		Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	_, err = service.Authenticate(context.Background(), forged.AccessToken)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken, "token signed with another key")
+
+	_, err = service.Authenticate(context.Background(), "not a token")
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+
+	clock.Advance(time.Hour + time.Second)
+	_, err = service.Authenticate(context.Background(), token.AccessToken)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken, "expired token")
+}
+
+func TestAuthAppService_Refresh(t *testing.T) {
+	user := (&domain.Factory{}).User()
+	userStorage := new(mockUserStorage)
+	userStorage.On("UserByEmail", mock.Anything, user.Email).Return(user, nil)
+	userStorage.On("Users", mock.Anything, mock.Anything).Return([]*domain.User{user}, nil)
+	refreshTokens := newMemoryRefreshTokenStorage()
+	service := NewAuthAppService(userStorage, refreshTokens, cache.NewMemoryCache(), TokenOptions{Secret: testJwtSecret})
+
+	login, err := service.Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	refreshed, err := service.Refresh(context.Background(), login.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken)
+
+	claims, err := service.Authenticate(context.Background(), refreshed.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.Id, claims.UserId)
+
+	// the rotated token is single use, presenting it again ends every session of the user
+	_, err = service.Refresh(context.Background(), login.RefreshToken)
+	assert.ErrorIs(t, err, domain.ErrRefreshTokenReused)
+
+	_, err = service.Refresh(context.Background(), refreshed.RefreshToken)
+	assert.ErrorIs(t, err, domain.ErrInvalidRefreshToken, "the latest token survived the reuse")
+
+	_, err = service.Refresh(context.Background(), "unknown")
+	assert.ErrorIs(t, err, domain.ErrInvalidRefreshToken)
+}
+
+func TestAuthAppService_Refresh_Expired(t *testing.T) {
+	clock := domain.NewFakeClock(time.Now())
+	setClockForTest(t, clock)
+
+	user := (&domain.Factory{}).User()
+	userStorage := new(mockUserStorage)
+	userStorage.On("UserByEmail", mock.Anything, user.Email).Return(user, nil)
+	service := NewAuthAppService(userStorage, newMemoryRefreshTokenStorage(), cache.NewMemoryCache(), TokenOptions{
+		Secret:          testJwtSecret,
+		RefreshLifetime: time.Hour,
+	})
+
+	login, err := service.Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	clock.Advance(time.Hour)
+	_, err = service.Refresh(context.Background(), login.RefreshToken)
+	assert.ErrorIs(t, err, domain.ErrInvalidRefreshToken)
+}
+
+func TestAuthAppService_Logout(t *testing.T) {
+	user := (&domain.Factory{}).User()
+	userStorage := new(mockUserStorage)
+	userStorage.On("UserByEmail", mock.Anything, user.Email).Return(user, nil)
+	userStorage.On("Users", mock.Anything, mock.Anything).Return([]*domain.User{user}, nil)
+	revocations := &recordingCache{Cache: cache.NewMemoryCache()}
+	service := NewAuthAppService(userStorage, newMemoryRefreshTokenStorage(), revocations, TokenOptions{
+		Secret:         testJwtSecret,
+		AccessLifetime: 15 * time.Minute,
+	})
+
+	login := func() *domain.AuthTokens {
+		tokens, err := service.Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "password123"})
+		require.NoError(t, err)
+		return tokens
+	}
+	loggedOut, other := login(), login()
+
+	claims, err := service.Authenticate(context.Background(), loggedOut.AccessToken)
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.TokenId)
+	require.NoError(t, service.Logout(context.Background(), claims, loggedOut.RefreshToken))
+
+	_, err = service.Authenticate(context.Background(), loggedOut.AccessToken)
+	assert.ErrorIs(t, err, domain.ErrInvalidToken)
+
+	// the session can't mint new access tokens either
+	_, err = service.Refresh(context.Background(), loggedOut.RefreshToken)
+	assert.ErrorIs(t, err, domain.ErrInvalidRefreshToken)
+
+	// other sessions of the user go on
+	_, err = service.Authenticate(context.Background(), other.AccessToken)
+	assert.NoError(t, err)
+	_, err = service.Refresh(context.Background(), other.RefreshToken)
+	assert.NoError(t, err)
+
+	// the revocation entry lives as long as the token would have
+	require.Len(t, revocations.ttls, 1)
+	assert.InDelta(t, (15 * time.Minute).Seconds(), revocations.ttls[0].Seconds(), 5)
+}
+
+func TestAuthAppService_Logout_RejectsForeignRefreshToken(t *testing.T) {
+	user := (&domain.Factory{}).User()
+	stranger := (&domain.Factory{}).User()
+	userStorage := new(mockUserStorage)
+	userStorage.On("UserByEmail", mock.Anything, user.Email).Return(user, nil)
+	userStorage.On("UserByEmail", mock.Anything, stranger.Email).Return(stranger, nil)
+	userStorage.On("Users", mock.Anything, mock.Anything).Return([]*domain.User{stranger}, nil)
+	service := NewAuthAppService(userStorage, newMemoryRefreshTokenStorage(), cache.NewMemoryCache(), TokenOptions{Secret: testJwtSecret})
+
+	tokens, err := service.Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+	strangerTokens, err := service.Login(context.Background(), &domain.LoginRequest{Email: stranger.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	claims, err := service.Authenticate(context.Background(), tokens.AccessToken)
+	require.NoError(t, err)
+
+	err = service.Logout(context.Background(), claims, strangerTokens.RefreshToken)
+	assert.ErrorIs(t, err, domain.ErrInvalidRefreshToken)
+	err = service.Logout(context.Background(), claims, "unknown")
+	assert.ErrorIs(t, err, domain.ErrInvalidRefreshToken)
+
+	// nothing was revoked
+	_, err = service.Authenticate(context.Background(), tokens.AccessToken)
+	assert.NoError(t, err)
+	_, err = service.Refresh(context.Background(), strangerTokens.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestAuthAppService_Logout_RotatedRefreshTokenRevokesAll(t *testing.T) {
+	user := (&domain.Factory{}).User()
+	userStorage := new(mockUserStorage)
+	userStorage.On("UserByEmail", mock.Anything, user.Email).Return(user, nil)
+	userStorage.On("Users", mock.Anything, mock.Anything).Return([]*domain.User{user}, nil)
+	service := NewAuthAppService(userStorage, newMemoryRefreshTokenStorage(), cache.NewMemoryCache(), TokenOptions{Secret: testJwtSecret})
+
+	login, err := service.Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+	refreshed, err := service.Refresh(context.Background(), login.RefreshToken)
+	require.NoError(t, err)
+
+	claims, err := service.Authenticate(context.Background(), refreshed.AccessToken)
+	require.NoError(t, err)
+	require.NoError(t, service.Logout(context.Background(), claims, login.RefreshToken))
+
+	_, err = service.Refresh(context.Background(), refreshed.RefreshToken)
+	assert.ErrorIs(t, err, domain.ErrInvalidRefreshToken)
+}
+
+func TestAuthAppService_Logout_RevocationExpiresWithToken(t *testing.T) {
+	clock := domain.NewFakeClock(time.Now())
+	setClockForTest(t, clock)
+
+	refreshTokens := newMemoryRefreshTokenStorage()
+	revocations := &recordingCache{Cache: cache.NewMemoryCache()}
+	service := NewAuthAppService(nil, refreshTokens, revocations, TokenOptions{Secret: testJwtSecret})
+
+	claims := &domain.AccessClaims{TokenId: "jti", UserId: uuid.New(), ExpiresAt: clock.Now().Add(10 * time.Minute)}
+	refreshToken, refreshSecret, err := domain.NewRefreshToken(claims.UserId, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, refreshTokens.CreateRefreshToken(context.Background(), refreshToken))
+
+	require.NoError(t, service.Logout(context.Background(), claims, refreshSecret))
+	require.Len(t, revocations.ttls, 1)
+	assert.Equal(t, 10*time.Minute, revocations.ttls[0])
+
+	// an expired token has nothing left to revoke
+	clock.Advance(10 * time.Minute)
+	require.NoError(t, service.Logout(context.Background(), claims, refreshSecret))
+	assert.Len(t, revocations.ttls, 1)
+}
+
+func TestAuthAppService_Authenticate_RejectsWhenRevocationsUnavailable(t *testing.T) {
+	user := (&domain.Factory{}).User()
+	userStorage := new(mockUserStorage)
+	userStorage.On("UserByEmail", mock.Anything, user.Email).Return(user, nil)
+	revocations := &failingCache{Cache: cache.NewMemoryCache()}
+	service := NewAuthAppService(userStorage, newMemoryRefreshTokenStorage(), revocations, TokenOptions{Secret: testJwtSecret})
+
+	tokens, err := service.Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	revocations.err = errors.New("connection refused")
+	_, err = service.Authenticate(context.Background(), tokens.AccessToken)
+	assert.ErrorIs(t, err, domain.ErrAuthUnavailable)
+}
+
+// failingCache fails every read with err once it is set
+type failingCache struct {
+	domain.Cache
+	err error
+}
+
+func (c *failingCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if c.err != nil {
+		return nil, false, c.err
+	}
+	return c.Cache.Get(ctx, key)
+}
+
+// recordingCache remembers the ttls of the entries set
+type recordingCache struct {
+	domain.Cache
+	ttls []time.Duration
+}
+
+func (c *recordingCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.ttls = append(c.ttls, ttl)
+	return c.Cache.Set(ctx, key, value, ttl)
+}
+
+// memoryRefreshTokenStorage keeps refresh tokens in a map keyed by their hash
+type memoryRefreshTokenStorage struct {
+	mu     sync.Mutex
+	tokens map[string]*domain.RefreshToken
+}
+
+func newMemoryRefreshTokenStorage() *memoryRefreshTokenStorage {
+	return &memoryRefreshTokenStorage{tokens: make(map[string]*domain.RefreshToken)}
+}
+
+func (s *memoryRefreshTokenStorage) CreateRefreshToken(_ context.Context, token *domain.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *token
+	s.tokens[string(token.TokenHash)] = &stored
+	return nil
+}
+
+func (s *memoryRefreshTokenStorage) RefreshToken(_ context.Context, hash []byte) (*domain.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[string(hash)]
+	if !ok {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+	found := *token
+	return &found, nil
+}
+
+func (s *memoryRefreshTokenStorage) RotateRefreshToken(ctx context.Context, id uuid.UUID, next *domain.RefreshToken) error {
+	s.mu.Lock()
+	for _, token := range s.tokens {
+		if token.Id != id {
+			continue
+		}
+		if token.RotatedAt != nil || token.RevokedAt != nil {
+			s.mu.Unlock()
+			return domain.ErrRefreshTokenReused
+		}
+		now := domain.Now()
+		token.RotatedAt = &now
+	}
+	s.mu.Unlock()
+
+	return s.CreateRefreshToken(ctx, next)
+}
+
+func (s *memoryRefreshTokenStorage) RevokeRefreshToken(_ context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := domain.Now()
+	for _, token := range s.tokens {
+		if token.Id == id && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *memoryRefreshTokenStorage) RevokeUserRefreshTokens(_ context.Context, userId uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := domain.Now()
+	for _, token := range s.tokens {
+		if token.UserId == userId && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package application
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"mts/internal/domain"
+)
+
+// NewSyncEventPublisher returns a publisher that runs the handlers one after another in the publishing goroutine
+func NewSyncEventPublisher() domain.EventPublisher {
+	return &syncEventPublisher{}
+}
+
+type syncEventPublisher struct {
+	mu       sync.RWMutex
+	handlers []domain.EventHandler
+}
+
+func (p *syncEventPublisher) Subscribe(handler domain.EventHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.handlers = append(p.handlers, handler)
+}
+
+func (p *syncEventPublisher) Publish(ctx context.Context, event *domain.OrderEvent) {
+	ctx, span := tracer.Start(ctx, "EventPublisher.Publish")
+	defer span.End()
+
+	p.mu.RLock()
+	handlers := p.handlers
+	p.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).
+				Str("event", string(event.Type)).
+				Str("order_id", event.Order.Id.String()).
+				Msg("order event handler failed")
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/domain"
+)
+
+// spyEventHandler records every event it receives
+type spyEventHandler struct {
+	events []*domain.OrderEvent
+}
+
+func (h *spyEventHandler) handle(_ context.Context, event *domain.OrderEvent) error {
+	h.events = append(h.events, event)
+	return nil
+}
+
+func (h *spyEventHandler) types() []domain.OrderEventType {
+	types := make([]domain.OrderEventType, 0, len(h.events))
+	for _, event := range h.events {
+		types = append(types, event.Type)
+	}
+	return types
+}
+
+func newSpiedOrderAppService(
+	orderStorage domain.OrderStorage,
+	productStorage domain.ProductStorage,
+	userStorage domain.UserStorage,
+) (domain.OrderAppService, *spyEventHandler) {
+	spy := &spyEventHandler{}
+	events := NewSyncEventPublisher()
+	events.Subscribe(spy.handle)
+
+	unitOfWork := &txUnitOfWork{tx: &domain.TxStorages{
+		Users:    userStorage,
+		Products: productStorage,
+		Orders:   orderStorage,
+	}}
+	return NewOrderAppService(orderStorage, productStorage, userStorage, unitOfWork, events), spy
+}
+
+func TestSyncEventPublisher_HandlerFailureDoesNotStopDelivery(t *testing.T) {
+	spy := &spyEventHandler{}
+	events := NewSyncEventPublisher()
+	events.Subscribe(func(context.Context, *domain.OrderEvent) error { return errors.New("webhook down") })
+	events.Subscribe(spy.handle)
+
+	order := (&domain.Factory{}).Order(uuid.New(), uuid.New())
+	events.Publish(context.Background(), domain.NewOrderEvent(domain.OrderCreated, order))
+
+	require.Len(t, spy.events, 1)
+	assert.Equal(t, domain.OrderCreated, spy.events[0].Type)
+	assert.Same(t, order, spy.events[0].Order)
+	assert.False(t, spy.events[0].OccurredAt.IsZero())
+}
+
+func TestOrderAppService_PublishesOrderCreated(t *testing.T) {
+	factory := &domain.Factory{}
+	user := factory.User()
+	product := factory.ProductWithQuantity(10)
+
+	orderStorage := new(mockOrderStorage)
+	productStorage := new(mockProductStorage)
+	userStorage := new(mockUserStorage)
+
+	userStorage.On("Users", mock.Anything, mock.Anything).Return([]*domain.User{user}, nil)
+	productStorage.On("Products", mock.Anything, mock.Anything).Return([]*domain.Product{product}, nil)
+	productStorage.On("AdjustQuantity", mock.Anything, product.Id, -2).Return(product, nil)
+	orderStorage.On("CreateOrder", mock.Anything, mock.Anything).Return(nil)
+
+	service, spy := newSpiedOrderAppService(orderStorage, productStorage, userStorage)
+	order, err := service.CreateOrder(context.Background(), &domain.CreateOrderRequest{
+		UserId: user.Id,
+		Items:  []domain.CreateOrderItemRequest{{ProductId: product.Id, Quantity: 2}},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []domain.OrderEventType{domain.OrderCreated}, spy.types())
+	assert.Same(t, order, spy.events[0].Order)
+}
+
+func TestOrderAppService_CreateOrderFailure_PublishesNothing(t *testing.T) {
+	userStorage := new(mockUserStorage)
+	userStorage.On("Users", mock.Anything, mock.Anything).Return([]*domain.User{}, nil)
+
+	service, spy := newSpiedOrderAppService(new(mockOrderStorage), new(mockProductStorage), userStorage)
+	_, err := service.CreateOrder(context.Background(), &domain.CreateOrderRequest{
+		UserId: uuid.New(),
+		Items:  []domain.CreateOrderItemRequest{{ProductId: uuid.New(), Quantity: 1}},
+	})
+	require.ErrorIs(t, err, domain.ErrUserNotFound)
+
+	assert.Empty(t, spy.events)
+}
+
+func TestOrderAppService_UpdateOrder_PublishesTransition(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     domain.OrderStatus
+		to       domain.OrderStatus
+		expected []domain.OrderEventType
+	}{
+		{name: "confirmed", from: domain.OrderStatusPending, to: domain.OrderStatusConfirmed, expected: []domain.OrderEventType{domain.OrderConfirmed}},
+		{name: "completed", from: domain.OrderStatusConfirmed, to: domain.OrderStatusCompleted, expected: []domain.OrderEventType{domain.OrderCompleted}},
+		{name: "cancelled", from: domain.OrderStatusPending, to: domain.OrderStatusCancelled, expected: []domain.OrderEventType{domain.OrderCancelled}},
+		{name: "unchanged status", from: domain.OrderStatusConfirmed, to: domain.OrderStatusConfirmed, expected: []domain.OrderEventType{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory := &domain.Factory{}
+			before := factory.Order(uuid.New(), uuid.New())
+			before.Status = tt.from
+			after := *before
+			after.Status = tt.to
+
+			orderStorage := new(mockOrderStorage)
+			orderStorage.On("LockOrder", mock.Anything, before.Id).Return(before, nil)
+			orderStorage.On("UpdateOrder", mock.Anything, mock.Anything).Return(&after, nil)
+
+			service, spy := newSpiedOrderAppService(orderStorage, new(mockProductStorage), new(mockUserStorage))
+			_, err := service.UpdateOrder(context.Background(), &domain.UpdateOrderRequest{Id: before.Id, Status: tt.to})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, spy.types())
+			for _, event := range spy.events {
+				assert.Same(t, &after, event.Order)
+			}
+		})
+	}
+}
+
+func TestOrderAppService_CancelOrder_PublishesOrderCancelled(t *testing.T) {
+	factory := &domain.Factory{}
+	product := factory.ProductWithQuantity(3)
+	order := factory.Order(uuid.New(), product.Id)
+	cancelled := *order
+	cancelled.Status = domain.OrderStatusCancelled
+
+	orderStorage := new(mockOrderStorage)
+	productStorage := new(mockProductStorage)
+	orderStorage.On("LockOrder", mock.Anything, order.Id).Return(order, nil)
+	productStorage.On("AdjustQuantity", mock.Anything, product.Id, 1).Return(product, nil)
+	orderStorage.On("UpdateOrder", mock.Anything, mock.Anything).Return(&cancelled, nil)
+
+	service, spy := newSpiedOrderAppService(orderStorage, productStorage, new(mockUserStorage))
+	_, err := service.CancelOrder(context.Background(), order.Id)
+	require.NoError(t, err)
+	productStorage.AssertExpectations(t)
+
+	require.Equal(t, []domain.OrderEventType{domain.OrderCancelled}, spy.types())
+	assert.Equal(t, order.Id, spy.events[0].Order.Id)
+	assert.Equal(t, domain.OrderStatusCancelled, spy.events[0].Order.Status)
+}
+
+func TestOrderAppService_BulkUpdateStatus_PublishesPerUpdatedOrder(t *testing.T) {
+	factory := &domain.Factory{}
+	userId, productId := uuid.New(), uuid.New()
+
+	first := factory.Order(userId, productId)
+	second := factory.Order(userId, productId)
+	completed := factory.Order(userId, productId)
+	completed.Status = domain.OrderStatusCompleted
+
+	orderStorage := new(mockOrderStorage)
+	orderStorage.On("LockOrders", mock.Anything, mock.Anything).Return([]*domain.Order{first, second, completed}, nil)
+	orderStorage.On("UpdateOrderStatuses", mock.Anything, []uuid.UUID{first.Id, second.Id}, domain.OrderStatusConfirmed).
+		Return(nil)
+
+	service, spy := newSpiedOrderAppService(orderStorage, new(mockProductStorage), new(mockUserStorage))
+	_, err := service.BulkUpdateStatus(context.Background(), &domain.BulkUpdateOrderStatusRequest{
+		Ids:    []uuid.UUID{first.Id, second.Id, completed.Id},
+		Status: domain.OrderStatusConfirmed,
+	})
+	require.NoError(t, err)
+
+	// the skipped order gets no event
+	require.Equal(t, []domain.OrderEventType{domain.OrderConfirmed, domain.OrderConfirmed}, spy.types())
+	assert.Equal(t, first.Id, spy.events[0].Order.Id)
+	assert.Equal(t, second.Id, spy.events[1].Order.Id)
+}
+
+func TestOrderAppService_BulkUpdateStatus_StorageFailure_PublishesNothing(t *testing.T) {
+	order := (&domain.Factory{}).Order(uuid.New(), uuid.New())
+
+	orderStorage := new(mockOrderStorage)
+	orderStorage.On("LockOrders", mock.Anything, mock.Anything).Return([]*domain.Order{order}, nil)
+	orderStorage.On("UpdateOrderStatuses", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("connection reset"))
+
+	service, spy := newSpiedOrderAppService(orderStorage, new(mockProductStorage), new(mockUserStorage))
+	_, err := service.BulkUpdateStatus(context.Background(), &domain.BulkUpdateOrderStatusRequest{
+		Ids:    []uuid.UUID{order.Id},
+		Status: domain.OrderStatusConfirmed,
+	})
+	require.Error(t, err)
+
+	assert.Empty(t, spy.events)
+}
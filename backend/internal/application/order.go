@@ -1,8 +1,12 @@
 package application
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -14,11 +18,15 @@ func NewOrderAppService(
 	orderStorage domain.OrderStorage,
 	productStorage domain.ProductStorage,
 	userStorage domain.UserStorage,
+	unitOfWork domain.UnitOfWork,
+	events domain.EventPublisher,
 ) domain.OrderAppService {
 	return &orderAppService{
 		orderStorage:   orderStorage,
 		productStorage: productStorage,
 		userStorage:    userStorage,
+		unitOfWork:     unitOfWork,
+		events:         events,
 	}
 }
 
@@ -26,9 +34,15 @@ type orderAppService struct {
 	orderStorage   domain.OrderStorage
 	productStorage domain.ProductStorage
 	userStorage    domain.UserStorage
+	unitOfWork     domain.UnitOfWork
+	// events are published once a change is persisted
+	events domain.EventPublisher
 }
 
 func (s *orderAppService) CreateOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.CreateOrder")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx).With().
 		Str("operation", "CreateOrder").
 		Str("user_id", req.UserId.String()).
@@ -42,8 +56,76 @@ func (s *orderAppService) CreateOrder(ctx context.Context, req *domain.CreateOrd
 		return nil, err
 	}
 
+	// the stock reservations and the order are committed together or not at all
+	var order *domain.Order
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+		// concurrent orders of the user wait for each other here, so each counts the others' open orders
+		if domain.CurrentConfig().OrderLimits.MaxOpenOrders > 0 {
+			if _, err := tx.Users.LockUser(ctx, req.UserId); err != nil {
+				if errors.Is(err, domain.ErrUserNotFound) {
+					logger.Error().Msg("user not found")
+				} else {
+					logger.Error().Err(err).Msg("failed to lock user")
+				}
+				return err
+			}
+		}
+
+		draft, err := draftOrder(ctx, logger, tx, req)
+		if err != nil {
+			return err
+		}
+		order = draft.order
+
+		logger.Info().Msg("reserving product quantities")
+
+		// the stock checked by the draft may be gone by now, the storage takes it only if it is still there;
+		// products are taken in id order, so orders sharing products can't deadlock
+		productIds := slices.Collect(maps.Keys(draft.quantities))
+		slices.SortFunc(productIds, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+
+		for _, productId := range productIds {
+			if _, err = tx.Products.AdjustQuantity(ctx, productId, -draft.quantities[productId]); err != nil {
+				logger.Error().
+					Err(err).
+					Str("product_id", productId.String()).
+					Msg("failed to reserve product quantity")
+				return err
+			}
+		}
+
+		err = tx.Orders.CreateOrder(ctx, order)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to create order in storage")
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info().
+		Str("order_id", order.Id.String()).
+		Msg("order created successfully")
+
+	s.events.Publish(ctx, domain.NewOrderEvent(domain.OrderCreated, order))
+
+	return order, nil
+}
+
+// orderDraft is an order that passed the checks of CreateOrder, with no stock reserved and nothing stored yet
+type orderDraft struct {
+	order      *domain.Order
+	quantities map[uuid.UUID]int // requested per product, summed over the lines
+}
+
+// draftOrder checks the user, the open order limit, the products and their stock, and builds the order
+// with its product snapshots; it only reads, so CreateOrder and ValidateOrder run the same checks
+func draftOrder(ctx context.Context, logger zerolog.Logger, tx *domain.TxStorages, req *domain.CreateOrderRequest) (*orderDraft, error) {
 	// Check if user exists
-	users, err := s.userStorage.Users(ctx, &domain.GetUsersRequest{
+	users, err := tx.Users.Users(ctx, &domain.GetUsersRequest{
 		Ids:   []uuid.UUID{req.UserId},
 		Limit: 1,
 	})
@@ -56,12 +138,33 @@ func (s *orderAppService) CreateOrder(ctx context.Context, req *domain.CreateOrd
 		return nil, domain.ErrUserNotFound
 	}
 
+	// CreateOrder holds the user's lock, so concurrent orders of the user can't all pass the check
+	if limit := domain.CurrentConfig().OrderLimits.MaxOpenOrders; limit > 0 {
+		open, err := tx.Orders.CountOrders(ctx, &domain.GetOrdersRequest{
+			UserIds:  []uuid.UUID{req.UserId},
+			Statuses: domain.OpenOrderStatuses(),
+		})
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to count open orders")
+			return nil, err
+		}
+		if open >= limit {
+			logger.Warn().
+				Int("open_orders", open).
+				Int("limit", limit).
+				Msg("open order limit reached")
+			return nil, fmt.Errorf("%w: user already has %d open orders, at most %d allowed", domain.ErrOrderLimitExceeded, open, limit)
+		}
+	}
+
 	// Get all products from request
 	productIds := make([]uuid.UUID, 0, len(req.Items))
 	requestedQuantities := make(map[uuid.UUID]int)
 
 	for _, item := range req.Items {
-		productIds = append(productIds, item.ProductId)
+		if _, seen := requestedQuantities[item.ProductId]; !seen {
+			productIds = append(productIds, item.ProductId)
+		}
 		requestedQuantities[item.ProductId] += item.Quantity
 	}
 
@@ -69,8 +172,10 @@ func (s *orderAppService) CreateOrder(ctx context.Context, req *domain.CreateOrd
 		Int("unique_products", len(productIds)).
 		Msg("fetching products for order")
 
-	products, err := s.productStorage.Products(ctx, &domain.GetProductsRequest{
-		Ids: productIds,
+	// without a limit the lookup would stop at the default page size, orders may have more products
+	products, err := tx.Products.Products(ctx, &domain.GetProductsRequest{
+		Ids:   productIds,
+		Limit: len(productIds),
 	})
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch products")
@@ -83,7 +188,9 @@ func (s *orderAppService) CreateOrder(ctx context.Context, req *domain.CreateOrd
 		productMap[product.Id] = product
 	}
 
-	for productId, requestedQty := range requestedQuantities {
+	// every short product is reported, in request order, so the client can fix the whole cart at once
+	stockErr := &domain.InsufficientStockError{}
+	for _, productId := range productIds {
 		product, exists := productMap[productId]
 		if !exists {
 			logger.Error().
@@ -92,43 +199,18 @@ func (s *orderAppService) CreateOrder(ctx context.Context, req *domain.CreateOrd
 			return nil, fmt.Errorf("%w: product %s not found", domain.ErrProductNotFound, productId)
 		}
 
-		if product.Quantity < requestedQty {
+		if requestedQty := requestedQuantities[productId]; product.Quantity < requestedQty {
 			logger.Error().
 				Str("product_id", productId.String()).
 				Int("available", product.Quantity).
 				Int("requested", requestedQty).
 				Msg("insufficient stock")
-			return nil, fmt.Errorf("%w: product %s has only %d items but %d requested",
-				domain.ErrInsufficientStock, productId, product.Quantity, requestedQty)
+			stockErr.Add(productId, requestedQty, product.Quantity)
 		}
 	}
 
-	logger.Info().Msg("reserving product quantities")
-
-	// Reserve products (decrease quantities)
-	for productId, requestedQty := range requestedQuantities {
-		product := productMap[productId]
-		err = product.ReserveQuantity(requestedQty)
-		if err != nil {
-			logger.Error().
-				Err(err).
-				Str("product_id", productId.String()).
-				Msg("failed to reserve product quantity")
-			return nil, err
-		}
-
-		// Update product in storage
-		_, err = s.productStorage.UpdateProduct(ctx, &domain.UpdateProductRequest{
-			Id:       product.Id,
-			Quantity: &product.Quantity,
-		})
-		if err != nil {
-			logger.Error().
-				Err(err).
-				Str("product_id", productId.String()).
-				Msg("failed to update product quantity in storage")
-			return nil, err
-		}
+	if err = stockErr.Err(); err != nil {
+		return nil, err
 	}
 
 	// Create order with historical product snapshots
@@ -152,21 +234,51 @@ func (s *orderAppService) CreateOrder(ctx context.Context, req *domain.CreateOrd
 
 		order.Items = append(order.Items, item)
 	}
+	order.TotalQuantity = order.ItemsQuantity()
+
+	return &orderDraft{order: order, quantities: requestedQuantities}, nil
+}
 
-	err = s.orderStorage.CreateOrder(ctx, order)
+func (s *orderAppService) ValidateOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.ValidateOrder")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "ValidateOrder").
+		Str("user_id", req.UserId.String()).
+		Int("items_count", len(req.Items)).
+		Logger()
+
+	logger.Info().Msg("validating order")
+
+	if err := req.Validate(); err != nil {
+		logger.Error().Err(err).Msg("order request validation failed")
+		return nil, err
+	}
+
+	// the checks read inside a transaction like CreateOrder's, so they see the primary and not the caches
+	var order *domain.Order
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+		draft, err := draftOrder(ctx, logger, tx, req)
+		if err != nil {
+			return err
+		}
+		order = draft.order
+		return nil
+	})
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to create order in storage")
 		return nil, err
 	}
 
-	logger.Info().
-		Str("order_id", order.Id.String()).
-		Msg("order created successfully")
+	logger.Info().Msg("order is valid")
 
 	return order, nil
 }
 
 func (s *orderAppService) UpdateOrder(ctx context.Context, req *domain.UpdateOrderRequest) (*domain.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.UpdateOrder")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx).With().
 		Str("operation", "UpdateOrder").
 		Str("order_id", req.Id.String()).
@@ -174,18 +286,277 @@ func (s *orderAppService) UpdateOrder(ctx context.Context, req *domain.UpdateOrd
 
 	logger.Info().Msg("updating order")
 
-	order, err := s.orderStorage.UpdateOrder(ctx, req)
+	// the previous status tells whether the update is a transition worth an event; the order is locked
+	// in the transaction of the update, so a concurrent update can't change it in between
+	var previous *domain.Order
+	var order *domain.Order
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+		var err error
+		previous, err = tx.Orders.LockOrder(ctx, req.Id)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to fetch order")
+			return err
+		}
+
+		order, err = tx.Orders.UpdateOrder(ctx, req)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to update order in storage")
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
-		logger.Error().Err(err).Msg("failed to update order in storage")
 		return nil, err
 	}
 
 	logger.Info().Msg("order updated successfully")
 
+	if previous.Status != order.Status {
+		if event := domain.NewOrderStatusEvent(order); event != nil {
+			s.events.Publish(ctx, event)
+		}
+	}
+
+	return order, nil
+}
+
+func (s *orderAppService) BulkUpdateStatus(ctx context.Context, req *domain.BulkUpdateOrderStatusRequest) ([]*domain.OrderStatusUpdateResult, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.BulkUpdateStatus")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "BulkUpdateStatus").
+		Str("status", string(req.Status)).
+		Int("orders_count", len(req.Ids)).
+		Logger()
+
+	logger.Info().Msg("updating order statuses")
+
+	if err := req.Validate(); err != nil {
+		logger.Error().Err(err).Msg("bulk status update validation failed")
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.Ids))
+	seen := make(map[uuid.UUID]bool, len(req.Ids))
+	for _, id := range req.Ids {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	// the transitions are checked on locked rows, so a concurrent update can't make one illegal before it is stored
+	var results []*domain.OrderStatusUpdateResult
+	var updated []*domain.Order
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+		orders, err := tx.Orders.LockOrders(ctx, ids)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to fetch orders")
+			return err
+		}
+
+		ordersById := make(map[uuid.UUID]*domain.Order, len(orders))
+		for _, order := range orders {
+			ordersById[order.Id] = order
+		}
+
+		// the callback may run again, so nothing from a previous attempt is kept
+		results = make([]*domain.OrderStatusUpdateResult, 0, len(ids))
+		updated = nil
+		var updateIds []uuid.UUID
+		for _, id := range ids {
+			result := &domain.OrderStatusUpdateResult{OrderId: id}
+			results = append(results, result)
+
+			order, ok := ordersById[id]
+			if !ok {
+				result.Err = domain.ErrOrderNotFound
+				continue
+			}
+
+			result.Status = order.Status
+			if err := order.TransitionTo(req.Status); err != nil {
+				result.Err = err
+				continue
+			}
+
+			result.Status = order.Status
+			updateIds = append(updateIds, id)
+			updated = append(updated, order)
+		}
+
+		if len(updateIds) > 0 {
+			if err := tx.Orders.UpdateOrderStatuses(ctx, updateIds, req.Status); err != nil {
+				logger.Error().Err(err).Msg("failed to update order statuses in storage")
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info().
+		Int("updated_count", len(updated)).
+		Int("skipped_count", len(ids)-len(updated)).
+		Msg("order statuses updated successfully")
+
+	for _, order := range updated {
+		if event := domain.NewOrderStatusEvent(order); event != nil {
+			s.events.Publish(ctx, event)
+		}
+	}
+
+	return results, nil
+}
+
+func (s *orderAppService) UpdateOrderItems(ctx context.Context, req *domain.UpdateOrderItemsRequest) (*domain.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.UpdateOrderItems")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "UpdateOrderItems").
+		Str("order_id", req.Id.String()).
+		Int("items_count", len(req.Items)).
+		Logger()
+
+	logger.Info().Msg("updating order items")
+
+	if err := req.Validate(); err != nil {
+		logger.Error().Err(err).Msg("order items request validation failed")
+		return nil, err
+	}
+
+	// the stock adjustments and the new items are committed together or not at all
+	var order *domain.Order
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+		// the lock keeps a concurrent update from computing its deltas from the same items
+		current, err := tx.Orders.LockOrder(ctx, req.Id)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to fetch order")
+			return err
+		}
+
+		if current.Status != domain.OrderStatusPending {
+			logger.Error().Str("status", string(current.Status)).Msg("order is not pending")
+			return fmt.Errorf("%w: order in status %s cannot be modified", domain.ErrOrderValidation, current.Status)
+		}
+
+		// Positive delta reserves more stock, negative delta restores it
+		quantityDeltas := make(map[uuid.UUID]int)
+		for _, item := range current.Items {
+			quantityDeltas[item.ProductId] -= item.Quantity
+		}
+		for _, item := range req.Items {
+			quantityDeltas[item.ProductId] += item.Quantity
+		}
+
+		productIds := make([]uuid.UUID, 0, len(quantityDeltas))
+		for productId := range quantityDeltas {
+			productIds = append(productIds, productId)
+		}
+		// a fixed order keeps concurrent updates from locking the same products in opposite orders
+		slices.SortFunc(productIds, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+
+		products, err := tx.Products.Products(ctx, &domain.GetProductsRequest{
+			Ids:   productIds,
+			Limit: len(productIds),
+		})
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to fetch products")
+			return err
+		}
+
+		productMap := make(map[uuid.UUID]*domain.Product)
+		for _, product := range products {
+			productMap[product.Id] = product
+		}
+
+		for _, item := range req.Items {
+			if _, exists := productMap[item.ProductId]; !exists {
+				logger.Error().
+					Str("product_id", item.ProductId.String()).
+					Msg("product not found")
+				return fmt.Errorf("%w: product %s not found", domain.ErrProductNotFound, item.ProductId)
+			}
+		}
+
+		// requested is the additional quantity the new items need on top of the current reservation
+		stockErr := &domain.InsufficientStockError{}
+		for _, productId := range productIds {
+			product, exists := productMap[productId]
+			if delta := quantityDeltas[productId]; exists && delta > 0 && product.Quantity < delta {
+				logger.Error().
+					Str("product_id", productId.String()).
+					Int("available", product.Quantity).
+					Int("requested", delta).
+					Msg("insufficient stock")
+				stockErr.Add(productId, delta, product.Quantity)
+			}
+		}
+
+		if err = stockErr.Err(); err != nil {
+			return err
+		}
+
+		logger.Info().Msg("adjusting product quantities")
+
+		// the deltas are applied in the database, so stock changed since the read above is not overwritten
+		for _, productId := range productIds {
+			delta := quantityDeltas[productId]
+			if _, exists := productMap[productId]; !exists || delta == 0 {
+				continue // removed item of a product that no longer exists
+			}
+
+			if _, err = tx.Products.AdjustQuantity(ctx, productId, -delta); err != nil {
+				logger.Error().
+					Err(err).
+					Str("product_id", productId.String()).
+					Msg("failed to adjust product quantity")
+				return err
+			}
+		}
+
+		// Rebuild items with fresh product snapshots
+		current.Items = make([]*domain.OrderItem, 0, len(req.Items))
+		for _, itemReq := range req.Items {
+			product := productMap[itemReq.ProductId]
+
+			current.Items = append(current.Items, &domain.OrderItem{
+				ProductId: itemReq.ProductId,
+				Quantity:  itemReq.Quantity,
+				ProductSnapshot: domain.ProductSnapshot{
+					Description: product.Description,
+					Tags:        product.Tags,
+				},
+			})
+		}
+
+		order, err = tx.Orders.ReplaceOrderItems(ctx, current)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to replace order items in storage")
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info().Msg("order items updated successfully")
+
 	return order, nil
 }
 
 func (s *orderAppService) Orders(ctx context.Context, req *domain.GetOrdersRequest) ([]*domain.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.Orders")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx).With().
 		Str("operation", "Orders").
 		Int("ids_count", len(req.Ids)).
@@ -206,67 +577,369 @@ func (s *orderAppService) Orders(ctx context.Context, req *domain.GetOrdersReque
 	return orders, nil
 }
 
-func (s *orderAppService) CancelOrder(ctx context.Context, orderId uuid.UUID) (*domain.Order, error) {
-	// Get the order
-	orders, err := s.orderStorage.Orders(ctx, &domain.GetOrdersRequest{
-		Ids:   []uuid.UUID{orderId},
+func (s *orderAppService) CountOrders(ctx context.Context, req *domain.GetOrdersRequest) (int, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.CountOrders")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "CountOrders").
+		Int("ids_count", len(req.Ids)).
+		Int("user_ids_count", len(req.UserIds)).
+		Logger()
+
+	logger.Debug().Msg("counting orders")
+
+	count, err := s.orderStorage.CountOrders(ctx, req)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to count orders in storage")
+		return 0, err
+	}
+
+	logger.Debug().
+		Int("count", count).
+		Msg("orders counted successfully")
+
+	return count, nil
+}
+
+func (s *orderAppService) StatusCounts(ctx context.Context, req *domain.GetOrdersRequest) (map[domain.OrderStatus]*domain.OrderStatusStats, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.StatusCounts")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "StatusCounts").
+		Int("user_ids_count", len(req.UserIds)).
+		Int("product_ids_count", len(req.ProductIds)).
+		Logger()
+
+	logger.Debug().Msg("aggregating orders by status")
+
+	stats, err := s.orderStorage.StatusCounts(ctx, req)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to aggregate orders in storage")
+		return nil, err
+	}
+
+	logger.Debug().Msg("orders aggregated successfully")
+
+	return stats, nil
+}
+
+func (s *orderAppService) UserOrders(ctx context.Context, userId uuid.UUID, req *domain.GetOrdersRequest) ([]*domain.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.UserOrders")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "UserOrders").
+		Str("user_id", userId.String()).
+		Logger()
+
+	logger.Info().Msg("fetching user orders")
+
+	users, err := s.userStorage.Users(ctx, &domain.GetUsersRequest{
+		Ids:   []uuid.UUID{userId},
 		Limit: 1,
 	})
 	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch user")
 		return nil, err
 	}
-	if len(orders) == 0 {
-		return nil, domain.ErrOrderNotFound
+	if len(users) == 0 {
+		logger.Error().Msg("user not found")
+		return nil, domain.ErrUserNotFound
 	}
 
-	order := orders[0]
+	req.UserIds = []uuid.UUID{userId}
 
-	// Check if order can be cancelled
-	if !order.CanBeCancelled() {
-		return nil, fmt.Errorf("%w: order in status %s cannot be cancelled", domain.ErrOrderValidation, order.Status)
+	orders, err := s.orderStorage.Orders(ctx, req)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch orders from storage")
+		return nil, err
 	}
 
-	// Restore product quantities
-	productQuantityToRestore := make(map[uuid.UUID]int)
-	for _, item := range order.Items {
-		productQuantityToRestore[item.ProductId] += item.Quantity
-	}
+	logger.Info().
+		Int("orders_count", len(orders)).
+		Msg("user orders fetched successfully")
 
-	for productId, quantityToRestore := range productQuantityToRestore {
-		products, err := s.productStorage.Products(ctx, &domain.GetProductsRequest{
-			Ids:   []uuid.UUID{productId},
-			Limit: 1,
-		})
+	return orders, nil
+}
+
+func (s *orderAppService) CancelOrder(ctx context.Context, orderId uuid.UUID) (*domain.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.CancelOrder")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "CancelOrder").
+		Str("order_id", orderId.String()).
+		Logger()
+
+	logger.Info().Msg("cancelling order")
+
+	// the cancellation and the stock it gives back are committed together
+	var cancelled *domain.Order
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+		// the lock keeps two concurrent cancellations from both restoring the stock
+		order, err := tx.Orders.LockOrder(ctx, orderId)
 		if err != nil {
-			return nil, err
+			logger.Error().Err(err).Msg("failed to fetch order")
+			return err
 		}
-		if len(products) == 0 {
-			continue // Product might have been deleted
+
+		if err = order.Cancel(); err != nil {
+			logger.Error().Str("status", string(order.Status)).Msg("order cannot be cancelled")
+			return err
 		}
 
-		product := products[0]
-		err = product.RestoreQuantity(quantityToRestore)
-		if err != nil {
-			return nil, err
+		// Restore product quantities
+		productQuantityToRestore := make(map[uuid.UUID]int)
+		productIds := make([]uuid.UUID, 0, len(order.Items))
+		for _, item := range order.Items {
+			if _, ok := productQuantityToRestore[item.ProductId]; !ok {
+				productIds = append(productIds, item.ProductId)
+			}
+			productQuantityToRestore[item.ProductId] += item.Quantity
+		}
+		// a fixed order keeps concurrent cancellations from locking the same products in opposite orders
+		slices.SortFunc(productIds, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+
+		for _, productId := range productIds {
+			_, err = tx.Products.AdjustQuantity(ctx, productId, productQuantityToRestore[productId])
+			if errors.Is(err, domain.ErrProductNotFound) {
+				logger.Warn().Str("product_id", productId.String()).Msg("product of the cancelled order no longer exists")
+				continue
+			}
+			if err != nil {
+				logger.Error().Err(err).Str("product_id", productId.String()).Msg("failed to restore product quantity")
+				return err
+			}
 		}
 
-		_, err = s.productStorage.UpdateProduct(ctx, &domain.UpdateProductRequest{
-			Id:       product.Id,
-			Quantity: &product.Quantity,
+		cancelled, err = tx.Orders.UpdateOrder(ctx, &domain.UpdateOrderRequest{
+			Id:     order.Id,
+			Status: order.Status,
 		})
 		if err != nil {
-			return nil, err
+			logger.Error().Err(err).Msg("failed to cancel order in storage")
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info().Msg("order cancelled successfully")
+
+	s.events.Publish(ctx, domain.NewOrderEvent(domain.OrderCancelled, cancelled))
+
+	return cancelled, nil
+}
+
+func (s *orderAppService) CancelItem(ctx context.Context, orderId, itemId uuid.UUID) (*domain.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.CancelItem")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "CancelItem").
+		Str("order_id", orderId.String()).
+		Str("item_id", itemId.String()).
+		Logger()
+
+	logger.Info().Msg("cancelling order item")
+
+	// the item removal and the stock it gives back are committed together, on the locked order
+	var order *domain.Order
+	var cancelled bool
+	err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+		var err error
+		order, err = tx.Orders.LockOrder(ctx, orderId)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to fetch order")
+			return err
+		}
+
+		if !order.CanBeCancelled() {
+			logger.Error().Str("status", string(order.Status)).Msg("order cannot be cancelled")
+			return fmt.Errorf("%w: items of an order in status %s cannot be cancelled", domain.ErrOrderValidation, order.Status)
+		}
+
+		index := slices.IndexFunc(order.Items, func(item *domain.OrderItem) bool { return item.Id == itemId })
+		if index < 0 {
+			logger.Error().Msg("order item not found")
+			return domain.ErrOrderItemNotFound
+		}
+		item := order.Items[index]
+
+		// without items left there is nothing to deliver, so the whole order is cancelled
+		cancelled = len(order.Items) == 1
+		if cancelled {
+			if err = order.Cancel(); err != nil {
+				return err
+			}
+			order, err = tx.Orders.UpdateOrder(ctx, &domain.UpdateOrderRequest{
+				Id:     order.Id,
+				Status: order.Status,
+			})
+		} else {
+			order, err = tx.Orders.RemoveOrderItem(ctx, orderId, itemId)
+		}
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to cancel order item in storage")
+			return err
 		}
+
+		if _, err = tx.Products.AdjustQuantity(ctx, item.ProductId, item.Quantity); err != nil {
+			if errors.Is(err, domain.ErrProductNotFound) {
+				logger.Warn().Str("product_id", item.ProductId.String()).Msg("product of the cancelled item no longer exists")
+				return nil
+			}
+			logger.Error().Err(err).Str("product_id", item.ProductId.String()).Msg("failed to restore product quantity")
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Cancel the order
-	err = order.Cancel()
+	logger.Info().Bool("order_cancelled", cancelled).Msg("order item cancelled successfully")
+
+	if cancelled {
+		s.events.Publish(ctx, domain.NewOrderEvent(domain.OrderCancelled, order))
+	}
+
+	return order, nil
+}
+
+func (s *orderAppService) OrderStatusHistory(ctx context.Context, orderId uuid.UUID) ([]*domain.OrderStatusChange, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.OrderStatusHistory")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "OrderStatusHistory").
+		Str("order_id", orderId.String()).
+		Logger()
+
+	logger.Info().Msg("fetching order status history")
+
+	count, err := s.orderStorage.CountOrders(ctx, &domain.GetOrdersRequest{
+		Ids: []uuid.UUID{orderId},
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch order")
+		return nil, err
+	}
+	if count == 0 {
+		logger.Error().Msg("order not found")
+		return nil, domain.ErrOrderNotFound
+	}
+
+	history, err := s.orderStorage.OrderStatusHistory(ctx, orderId)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch order status history from storage")
+		return nil, err
+	}
+
+	logger.Info().
+		Int("changes_count", len(history)).
+		Msg("order status history fetched successfully")
+
+	return history, nil
+}
+
+func (s *orderAppService) OrderUser(ctx context.Context, order *domain.Order) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.OrderUser")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "OrderUser").
+		Str("order_id", order.Id.String()).
+		Str("user_id", order.UserId.String()).
+		Logger()
+
+	logger.Debug().Msg("fetching order user")
+
+	// a soft-deleted user still placed the order, so it is resolved too
+	users, err := s.userStorage.UsersByIds(ctx, []uuid.UUID{order.UserId})
 	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch order user from storage")
 		return nil, err
 	}
 
-	return s.orderStorage.UpdateOrder(ctx, &domain.UpdateOrderRequest{
-		Id:     order.Id,
-		Status: order.Status,
+	user, ok := users[order.UserId]
+	if !ok {
+		logger.Warn().Msg("order user not found")
+		return nil, nil
+	}
+
+	return user, nil
+}
+
+func (s *orderAppService) OrderProducts(ctx context.Context, orders []*domain.Order) (map[uuid.UUID]*domain.Product, error) {
+	ctx, span := tracer.Start(ctx, "OrderAppService.OrderProducts")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "OrderProducts").
+		Int("orders", len(orders)).
+		Logger()
+
+	seen := make(map[uuid.UUID]bool)
+	var productIds []uuid.UUID
+	for _, order := range orders {
+		for _, item := range order.Items {
+			if !seen[item.ProductId] {
+				seen[item.ProductId] = true
+				productIds = append(productIds, item.ProductId)
+			}
+		}
+	}
+
+	products := make(map[uuid.UUID]*domain.Product, len(productIds))
+	if len(productIds) == 0 {
+		return products, nil
+	}
+
+	logger.Debug().Int("products", len(productIds)).Msg("fetching order products")
+
+	// the orders keep referencing soft-deleted products, so they are resolved too
+	found, err := s.productStorage.Products(ctx, &domain.GetProductsRequest{
+		Ids:            productIds,
+		IncludeDeleted: true,
+		Limit:          len(productIds),
 	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch order products from storage")
+		return nil, err
+	}
+
+	for _, product := range found {
+		products[product.Id] = product
+	}
+
+	return products, nil
+}
+
+func (s *orderAppService) DeleteOrder(ctx context.Context, orderId uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "OrderAppService.DeleteOrder")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "DeleteOrder").
+		Str("order_id", orderId.String()).
+		Logger()
+
+	logger.Info().Msg("deleting order")
+
+	// stock is deliberately not restored, deletion is for cleaning up data rather than undoing a purchase
+	if err := s.orderStorage.DeleteOrder(ctx, orderId); err != nil {
+		logger.Error().Err(err).Msg("failed to delete order in storage")
+		return err
+	}
+
+	logger.Info().Msg("order deleted successfully")
+
+	return nil
 }
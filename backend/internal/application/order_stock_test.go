@@ -0,0 +1,247 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+
+	"mts/internal/domain"
+	"mts/internal/repository/storage"
+	"shared"
+	sharedConfig "shared/config"
+)
+
+type OrderStockSuite struct {
+	shared.Suite[any]
+	userStorage    domain.UserStorage
+	productStorage domain.ProductStorage
+	orderStorage   domain.OrderStorage
+	service        domain.OrderAppService
+}
+
+func (s *OrderStockSuite) SetupSuite() {
+	s.PostgresEnabled = true
+	s.Suite.SetupSuite()
+
+	s.userStorage = storage.NewUserStorage(s.PostgresConn, nil, storage.CacheOptions{}, nil)
+	s.productStorage = storage.NewProductStorage(s.PostgresConn, nil, storage.CacheOptions{}, nil)
+	s.orderStorage = storage.NewOrderStorage(s.PostgresConn, nil, storage.CacheOptions{}, sharedConfig.Retry{}, nil)
+
+	s.service = NewOrderAppService(
+		s.orderStorage,
+		s.productStorage,
+		s.userStorage,
+		storage.NewUnitOfWork(s.PostgresConn, sharedConfig.Retry{}, nil, s.userStorage, s.productStorage, s.orderStorage),
+		NewSyncEventPublisher(),
+	)
+}
+
+func (s *OrderStockSuite) TearDownTest() {
+	_, err := s.PostgresConn.Exec(s.Ctx, "DROP TRIGGER IF EXISTS reject_order_items ON order_items")
+	s.Require().NoError(err)
+	_, err = s.PostgresConn.Exec(s.Ctx, "DROP FUNCTION IF EXISTS reject_order_items()")
+	s.Require().NoError(err)
+	_, err = s.PostgresConn.Exec(s.Ctx, "TRUNCATE TABLE order_status_history, order_items, orders, products, users RESTART IDENTITY CASCADE")
+	s.Require().NoError(err)
+}
+
+func (s *OrderStockSuite) quantity(id uuid.UUID) int {
+	products, err := s.productStorage.Products(s.Ctx, &domain.GetProductsRequest{Ids: []uuid.UUID{id}})
+	s.Require().NoError(err)
+	s.Require().Len(products, 1)
+	return products[0].Quantity
+}
+
+func (s *OrderStockSuite) TestCreateOrder_AsManyProductsAsAllowed() {
+	factory := &domain.Factory{}
+	user := factory.User()
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+
+	// more products than a default page, so they must all be looked up at once
+	maxItems := domain.DefaultOrderLimits().MaxItems
+	s.Require().Greater(maxItems, domain.DefaultPageSize().Default)
+	req := &domain.CreateOrderRequest{UserId: user.Id}
+	for range maxItems {
+		product := factory.ProductWithQuantity(5)
+		s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, product))
+		req.Items = append(req.Items, domain.CreateOrderItemRequest{ProductId: product.Id, Quantity: 2})
+	}
+
+	validated, err := s.service.ValidateOrder(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Len(validated.Items, maxItems)
+
+	order, err := s.service.CreateOrder(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Len(order.Items, maxItems)
+	s.Equal(2*maxItems, order.TotalQuantity)
+	s.Equal(3, s.quantity(req.Items[maxItems-1].ProductId))
+}
+
+func (s *OrderStockSuite) TestUpdateOrderItems_AdjustsStock() {
+	factory := &domain.Factory{}
+	user := factory.User()
+	kept, added := factory.ProductWithQuantity(10), factory.ProductWithQuantity(10)
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, kept))
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, added))
+
+	order, err := s.service.CreateOrder(s.Ctx, factory.CreateOrderRequest(user.Id, kept.Id))
+	s.Require().NoError(err)
+
+	_, err = s.service.UpdateOrderItems(s.Ctx, &domain.UpdateOrderItemsRequest{
+		Id: order.Id,
+		Items: []domain.CreateOrderItemRequest{
+			{ProductId: added.Id, Quantity: 4},
+		},
+	})
+	s.Require().NoError(err)
+
+	s.Equal(10, s.quantity(kept.Id))
+	s.Equal(6, s.quantity(added.Id))
+}
+
+func (s *OrderStockSuite) TestUpdateOrderItems_FailedReplaceKeepsStock() {
+	factory := &domain.Factory{}
+	user := factory.User()
+	kept, added := factory.ProductWithQuantity(10), factory.ProductWithQuantity(10)
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, kept))
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, added))
+
+	order, err := s.service.CreateOrder(s.Ctx, factory.CreateOrderRequest(user.Id, kept.Id))
+	s.Require().NoError(err)
+	s.Require().Equal(9, s.quantity(kept.Id))
+
+	// the stock is adjusted before the items are replaced, so a failing insert has to roll it back
+	_, err = s.PostgresConn.Exec(s.Ctx, `
+		CREATE FUNCTION reject_order_items() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'order items are read-only';
+		END
+		$$ LANGUAGE plpgsql`)
+	s.Require().NoError(err)
+	_, err = s.PostgresConn.Exec(s.Ctx, `
+		CREATE TRIGGER reject_order_items BEFORE INSERT ON order_items
+		FOR EACH ROW EXECUTE FUNCTION reject_order_items()`)
+	s.Require().NoError(err)
+
+	_, err = s.service.UpdateOrderItems(s.Ctx, &domain.UpdateOrderItemsRequest{
+		Id: order.Id,
+		Items: []domain.CreateOrderItemRequest{
+			{ProductId: kept.Id, Quantity: 3},
+			{ProductId: added.Id, Quantity: 4},
+		},
+	})
+	s.Require().Error(err)
+
+	s.Equal(9, s.quantity(kept.Id))
+	s.Equal(10, s.quantity(added.Id))
+
+	orders, err := s.orderStorage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+	s.Require().Len(orders[0].Items, 1)
+	s.Equal(1, orders[0].Items[0].Quantity)
+}
+
+func (s *OrderStockSuite) TestCancelOrder_RestoresStockOnTopOfLaterChanges() {
+	factory := &domain.Factory{}
+	user := factory.User()
+	product := factory.ProductWithQuantity(10)
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, product))
+
+	order, err := s.service.CreateOrder(s.Ctx, &domain.CreateOrderRequest{
+		UserId: user.Id,
+		Items:  []domain.CreateOrderItemRequest{{ProductId: product.Id, Quantity: 3}},
+	})
+	s.Require().NoError(err)
+
+	// a restock between the order and its cancellation is kept
+	_, err = s.productStorage.AdjustQuantity(s.Ctx, product.Id, 5)
+	s.Require().NoError(err)
+
+	cancelled, err := s.service.CancelOrder(s.Ctx, order.Id)
+	s.Require().NoError(err)
+	s.Equal(domain.OrderStatusCancelled, cancelled.Status)
+	s.Equal(15, s.quantity(product.Id))
+
+	_, err = s.service.CancelOrder(s.Ctx, order.Id)
+	s.ErrorIs(err, domain.ErrOrderValidation)
+	s.Equal(15, s.quantity(product.Id))
+}
+
+func (s *OrderStockSuite) TestCreateOrder_OpenOrderLimitHoldsConcurrently() {
+	previous := domain.CurrentConfig()
+	domainConfig := previous
+	domainConfig.OrderLimits = domain.OrderLimits{MaxOpenOrders: 1}
+	domain.Configure(domainConfig)
+	defer domain.Configure(previous)
+
+	factory := &domain.Factory{}
+	user := factory.User()
+	product := factory.ProductWithQuantity(100)
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, product))
+
+	const requests = 5
+	errs := make(chan error, requests)
+	for range requests {
+		go func() {
+			_, err := s.service.CreateOrder(s.Ctx, factory.CreateOrderRequest(user.Id, product.Id))
+			errs <- err
+		}()
+	}
+
+	created := 0
+	for range requests {
+		if err := <-errs; err == nil {
+			created++
+		} else {
+			s.ErrorIs(err, domain.ErrOrderLimitExceeded)
+		}
+	}
+	s.Equal(1, created)
+
+	open, err := s.orderStorage.CountOrders(s.Ctx, &domain.GetOrdersRequest{UserIds: []uuid.UUID{user.Id}})
+	s.Require().NoError(err)
+	s.Equal(1, open)
+}
+
+func (s *OrderStockSuite) TestCreateOrder_ConcurrentOrdersDontOversell() {
+	factory := &domain.Factory{}
+	product := factory.ProductWithQuantity(3)
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, product))
+
+	const requests = 5
+	users := make([]*domain.User, requests)
+	for i := range users {
+		users[i] = factory.User()
+		s.Require().NoError(s.userStorage.CreateUser(s.Ctx, users[i]))
+	}
+
+	errs := make(chan error, requests)
+	for _, user := range users {
+		go func() {
+			_, err := s.service.CreateOrder(s.Ctx, factory.CreateOrderRequest(user.Id, product.Id))
+			errs <- err
+		}()
+	}
+
+	created := 0
+	for range requests {
+		if err := <-errs; err == nil {
+			created++
+		} else {
+			s.ErrorIs(err, domain.ErrInsufficientStock)
+		}
+	}
+	s.Equal(3, created)
+	s.Equal(0, s.quantity(product.Id))
+}
+
+func TestOrderStockSuite(t *testing.T) {
+	suite.Run(t, new(OrderStockSuite))
+}
@@ -0,0 +1,751 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/domain"
+)
+
+// Mock для OrderStorage
+type mockOrderStorage struct {
+	mock.Mock
+}
+
+func (m *mockOrderStorage) CreateOrder(ctx context.Context, order *domain.Order) error {
+	args := m.Called(ctx, order)
+	return args.Error(0)
+}
+
+func (m *mockOrderStorage) UpdateOrder(ctx context.Context, req *domain.UpdateOrderRequest) (*domain.Order, error) {
+	args := m.Called(ctx, req)
+	order, _ := args.Get(0).(*domain.Order)
+	return order, args.Error(1)
+}
+
+func (m *mockOrderStorage) UpdateOrderStatuses(ctx context.Context, ids []uuid.UUID, status domain.OrderStatus) error {
+	args := m.Called(ctx, ids, status)
+	return args.Error(0)
+}
+
+func (m *mockOrderStorage) LockOrder(ctx context.Context, id uuid.UUID) (*domain.Order, error) {
+	args := m.Called(ctx, id)
+	order, _ := args.Get(0).(*domain.Order)
+	return order, args.Error(1)
+}
+
+func (m *mockOrderStorage) LockOrders(ctx context.Context, ids []uuid.UUID) ([]*domain.Order, error) {
+	args := m.Called(ctx, ids)
+	orders, _ := args.Get(0).([]*domain.Order)
+	return orders, args.Error(1)
+}
+
+func (m *mockOrderStorage) ReplaceOrderItems(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	args := m.Called(ctx, order)
+	result, _ := args.Get(0).(*domain.Order)
+	return result, args.Error(1)
+}
+
+func (m *mockOrderStorage) RemoveOrderItem(ctx context.Context, orderId, itemId uuid.UUID) (*domain.Order, error) {
+	args := m.Called(ctx, orderId, itemId)
+	result, _ := args.Get(0).(*domain.Order)
+	return result, args.Error(1)
+}
+
+func (m *mockOrderStorage) DeleteOrder(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockOrderStorage) Orders(ctx context.Context, req *domain.GetOrdersRequest) ([]*domain.Order, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).([]*domain.Order), args.Error(1)
+}
+
+func (m *mockOrderStorage) CountOrders(ctx context.Context, req *domain.GetOrdersRequest) (int, error) {
+	args := m.Called(ctx, req)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockOrderStorage) StatusCounts(ctx context.Context, req *domain.GetOrdersRequest) (map[domain.OrderStatus]*domain.OrderStatusStats, error) {
+	args := m.Called(ctx, req)
+	stats, _ := args.Get(0).(map[domain.OrderStatus]*domain.OrderStatusStats)
+	return stats, args.Error(1)
+}
+
+func (m *mockOrderStorage) OrderStatusHistory(ctx context.Context, orderId uuid.UUID) ([]*domain.OrderStatusChange, error) {
+	args := m.Called(ctx, orderId)
+	history, _ := args.Get(0).([]*domain.OrderStatusChange)
+	return history, args.Error(1)
+}
+
+func (m *mockOrderStorage) CacheStats() domain.CacheStats {
+	args := m.Called()
+	return args.Get(0).(domain.CacheStats)
+}
+
+func (m *mockOrderStorage) Close() {
+	m.Called()
+}
+
+// Mock для ProductStorage
+type mockProductStorage struct {
+	mock.Mock
+}
+
+func (m *mockProductStorage) CreateProduct(ctx context.Context, product *domain.Product) error {
+	args := m.Called(ctx, product)
+	return args.Error(0)
+}
+
+func (m *mockProductStorage) UpsertProduct(ctx context.Context, product *domain.Product) (*domain.Product, bool, error) {
+	args := m.Called(ctx, product)
+	stored, _ := args.Get(0).(*domain.Product)
+	return stored, args.Bool(1), args.Error(2)
+}
+
+func (m *mockProductStorage) UpdateProduct(ctx context.Context, req *domain.UpdateProductRequest) (*domain.Product, error) {
+	args := m.Called(ctx, req)
+	product, _ := args.Get(0).(*domain.Product)
+	return product, args.Error(1)
+}
+
+func (m *mockProductStorage) AdjustQuantity(ctx context.Context, id uuid.UUID, delta int) (*domain.Product, error) {
+	args := m.Called(ctx, id, delta)
+	product, _ := args.Get(0).(*domain.Product)
+	return product, args.Error(1)
+}
+
+func (m *mockProductStorage) Products(ctx context.Context, req *domain.GetProductsRequest) ([]*domain.Product, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).([]*domain.Product), args.Error(1)
+}
+
+func (m *mockProductStorage) CountProducts(ctx context.Context, req *domain.GetProductsRequest) (int, error) {
+	args := m.Called(ctx, req)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockProductStorage) TagCounts(ctx context.Context, req *domain.GetProductsRequest) ([]*domain.ProductTagCount, error) {
+	args := m.Called(ctx, req)
+	tags, _ := args.Get(0).([]*domain.ProductTagCount)
+	return tags, args.Error(1)
+}
+
+func (m *mockProductStorage) DeleteProduct(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockProductStorage) CacheStats() domain.CacheStats {
+	args := m.Called()
+	return args.Get(0).(domain.CacheStats)
+}
+
+func (m *mockProductStorage) Close() {
+	m.Called()
+}
+
+// txUnitOfWork runs the callback on the mocks without a transaction
+type txUnitOfWork struct {
+	tx *domain.TxStorages
+}
+
+func (u *txUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx *domain.TxStorages) error) error {
+	return fn(ctx, u.tx)
+}
+
+func newTestOrderAppService(
+	orderStorage domain.OrderStorage,
+	productStorage domain.ProductStorage,
+	userStorage domain.UserStorage,
+) domain.OrderAppService {
+	service, _ := newSpiedOrderAppService(orderStorage, productStorage, userStorage)
+	return service
+}
+
+func TestOrderAppService_CreateOrder_ReportsAllShortages(t *testing.T) {
+	factory := &domain.Factory{}
+	user := factory.User()
+	enough := factory.ProductWithQuantity(10)
+	short := factory.ProductWithQuantity(1)
+	empty := factory.ProductWithQuantity(0)
+
+	orderStorage := new(mockOrderStorage)
+	productStorage := new(mockProductStorage)
+	userStorage := new(mockUserStorage)
+
+	userStorage.On("Users", mock.Anything, mock.Anything).Return([]*domain.User{user}, nil)
+	productStorage.On("Products", mock.Anything, mock.Anything).Return([]*domain.Product{enough, short, empty}, nil)
+
+	service := newTestOrderAppService(orderStorage, productStorage, userStorage)
+	_, err := service.CreateOrder(context.Background(), &domain.CreateOrderRequest{
+		UserId: user.Id,
+		Items: []domain.CreateOrderItemRequest{
+			{ProductId: enough.Id, Quantity: 2},
+			{ProductId: short.Id, Quantity: 3},
+			{ProductId: empty.Id, Quantity: 1},
+			{ProductId: short.Id, Quantity: 1},
+		},
+	})
+
+	var stockErr *domain.InsufficientStockError
+	require.ErrorAs(t, err, &stockErr)
+	assert.ErrorIs(t, err, domain.ErrInsufficientStock)
+	assert.Equal(t, []domain.StockShortage{
+		{ProductId: short.Id, Requested: 4, Available: 1},
+		{ProductId: empty.Id, Requested: 1, Available: 0},
+	}, stockErr.Shortages)
+
+	// nothing is reserved when any product is short
+	productStorage.AssertNotCalled(t, "AdjustQuantity", mock.Anything, mock.Anything, mock.Anything)
+	orderStorage.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+}
+
+func TestOrderAppService_CreateOrder_MergesDuplicateLines(t *testing.T) {
+	factory := &domain.Factory{}
+	user := factory.User()
+	phone := factory.ProductWithQuantity(10)
+	cable := factory.ProductWithQuantity(10)
+
+	orderStorage := new(mockOrderStorage)
+	productStorage := new(mockProductStorage)
+	userStorage := new(mockUserStorage)
+
+	userStorage.On("Users", mock.Anything, mock.Anything).Return([]*domain.User{user}, nil)
+	productStorage.On("Products", mock.Anything, mock.Anything).Return([]*domain.Product{phone, cable}, nil)
+	productStorage.On("AdjustQuantity", mock.Anything, phone.Id, -4).Return(phone, nil).Once()
+	productStorage.On("AdjustQuantity", mock.Anything, cable.Id, -1).Return(cable, nil).Once()
+	orderStorage.On("CreateOrder", mock.Anything, mock.Anything).Return(nil)
+
+	service := newTestOrderAppService(orderStorage, productStorage, userStorage)
+	order, err := service.CreateOrder(context.Background(), &domain.CreateOrderRequest{
+		UserId: user.Id,
+		Items: []domain.CreateOrderItemRequest{
+			{ProductId: phone.Id, Quantity: 1},
+			{ProductId: cable.Id, Quantity: 1},
+			{ProductId: phone.Id, Quantity: 3},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, order.Items, 2)
+	assert.Equal(t, phone.Id, order.Items[0].ProductId)
+	assert.Equal(t, 4, order.Items[0].Quantity)
+	assert.Equal(t, cable.Id, order.Items[1].ProductId)
+	assert.Equal(t, 1, order.Items[1].Quantity)
+	productStorage.AssertExpectations(t)
+}
+
+func TestOrderAppService_ValidateOrder(t *testing.T) {
+	factory := &domain.Factory{}
+	user := factory.User()
+	phone := factory.ProductWithQuantity(10)
+	cable := factory.ProductWithQuantity(2)
+
+	orderStorage := new(mockOrderStorage)
+	productStorage := new(mockProductStorage)
+	userStorage := new(mockUserStorage)
+
+	userStorage.On("Users", mock.Anything, mock.Anything).Return([]*domain.User{user}, nil)
+	productStorage.On("Products", mock.Anything, mock.Anything).Return([]*domain.Product{phone, cable}, nil)
+
+	service, events := newSpiedOrderAppService(orderStorage, productStorage, userStorage)
+
+	t.Run("would-be order is returned without reserving stock", func(t *testing.T) {
+		order, err := service.ValidateOrder(context.Background(), &domain.CreateOrderRequest{
+			UserId: user.Id,
+			Items: []domain.CreateOrderItemRequest{
+				{ProductId: phone.Id, Quantity: 3},
+				{ProductId: cable.Id, Quantity: 2},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, user.Id, order.UserId)
+		assert.Equal(t, domain.OrderStatusPending, order.Status)
+		assert.Equal(t, 5, order.TotalQuantity)
+		require.Len(t, order.Items, 2)
+		assert.Equal(t, phone.Description, order.Items[0].ProductSnapshot.Description)
+
+		assert.Equal(t, 10, phone.Quantity)
+		assert.Equal(t, 2, cable.Quantity)
+	})
+
+	t.Run("insufficient stock is reported", func(t *testing.T) {
+		_, err := service.ValidateOrder(context.Background(), &domain.CreateOrderRequest{
+			UserId: user.Id,
+			Items:  []domain.CreateOrderItemRequest{{ProductId: cable.Id, Quantity: 3}},
+		})
+
+		var stockErr *domain.InsufficientStockError
+		require.ErrorAs(t, err, &stockErr)
+		assert.Equal(t, []domain.StockShortage{{ProductId: cable.Id, Requested: 3, Available: 2}}, stockErr.Shortages)
+	})
+
+	productStorage.AssertNotCalled(t, "AdjustQuantity", mock.Anything, mock.Anything, mock.Anything)
+	orderStorage.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+	assert.Empty(t, events.events)
+}
+
+func TestOrderAppService_CreateOrder_MaxOpenOrders(t *testing.T) {
+	previous := domain.CurrentConfig()
+	domainConfig := previous
+	domainConfig.OrderLimits = domain.OrderLimits{MaxOpenOrders: 3}
+	domain.Configure(domainConfig)
+	t.Cleanup(func() { domain.Configure(previous) })
+
+	factory := &domain.Factory{}
+	user := factory.User()
+
+	tests := []struct {
+		name       string
+		openOrders int
+		wantErr    error
+	}{
+		{"below the limit", 2, nil},
+		{"at the limit", 3, domain.ErrOrderLimitExceeded},
+		{"above the limit", 4, domain.ErrOrderLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product := factory.ProductWithQuantity(10)
+
+			orderStorage := new(mockOrderStorage)
+			productStorage := new(mockProductStorage)
+			userStorage := new(mockUserStorage)
+
+			userStorage.On("LockUser", mock.Anything, user.Id).Return(user, nil)
+			userStorage.On("Users", mock.Anything, mock.Anything).Return([]*domain.User{user}, nil)
+			orderStorage.On("CountOrders", mock.Anything, &domain.GetOrdersRequest{
+				UserIds:  []uuid.UUID{user.Id},
+				Statuses: []domain.OrderStatus{domain.OrderStatusPending, domain.OrderStatusConfirmed},
+			}).Return(tt.openOrders, nil)
+			productStorage.On("Products", mock.Anything, mock.Anything).Return([]*domain.Product{product}, nil)
+			productStorage.On("AdjustQuantity", mock.Anything, product.Id, -1).Return(product, nil)
+			orderStorage.On("CreateOrder", mock.Anything, mock.Anything).Return(nil)
+
+			service := newTestOrderAppService(orderStorage, productStorage, userStorage)
+			_, err := service.CreateOrder(context.Background(), &domain.CreateOrderRequest{
+				UserId: user.Id,
+				Items:  []domain.CreateOrderItemRequest{{ProductId: product.Id, Quantity: 1}},
+			})
+			// counted while holding the user's lock
+			userStorage.AssertCalled(t, "LockUser", mock.Anything, user.Id)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				orderStorage.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			orderStorage.AssertCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestOrderAppService_CreateOrder_UnlimitedOpenOrdersAreNotCounted(t *testing.T) {
+	factory := &domain.Factory{}
+	user := factory.User()
+	product := factory.ProductWithQuantity(10)
+
+	orderStorage := new(mockOrderStorage)
+	productStorage := new(mockProductStorage)
+	userStorage := new(mockUserStorage)
+
+	userStorage.On("Users", mock.Anything, mock.Anything).Return([]*domain.User{user}, nil)
+	productStorage.On("Products", mock.Anything, mock.Anything).Return([]*domain.Product{product}, nil)
+	productStorage.On("AdjustQuantity", mock.Anything, product.Id, -1).Return(product, nil)
+	orderStorage.On("CreateOrder", mock.Anything, mock.Anything).Return(nil)
+
+	service := newTestOrderAppService(orderStorage, productStorage, userStorage)
+	_, err := service.CreateOrder(context.Background(), &domain.CreateOrderRequest{
+		UserId: user.Id,
+		Items:  []domain.CreateOrderItemRequest{{ProductId: product.Id, Quantity: 1}},
+	})
+	require.NoError(t, err)
+	orderStorage.AssertNotCalled(t, "CountOrders", mock.Anything, mock.Anything)
+}
+
+func TestOrderAppService_BulkUpdateStatus(t *testing.T) {
+	factory := &domain.Factory{}
+	userId, productId := uuid.New(), uuid.New()
+
+	pending := factory.Order(userId, productId)
+	confirmed := factory.Order(userId, productId)
+	confirmed.Status = domain.OrderStatusConfirmed
+	cancelled := factory.Order(userId, productId)
+	cancelled.Status = domain.OrderStatusCancelled
+	missing := uuid.New()
+
+	orderStorage := new(mockOrderStorage)
+	orderStorage.On("LockOrders", mock.Anything, []uuid.UUID{pending.Id, confirmed.Id, missing, cancelled.Id}).
+		Return([]*domain.Order{pending, confirmed, cancelled}, nil)
+	// only the legal transitions are persisted, together
+	orderStorage.On("UpdateOrderStatuses", mock.Anything, []uuid.UUID{pending.Id}, domain.OrderStatusConfirmed).
+		Return(nil)
+
+	service := newTestOrderAppService(orderStorage, new(mockProductStorage), new(mockUserStorage))
+	results, err := service.BulkUpdateStatus(context.Background(), &domain.BulkUpdateOrderStatusRequest{
+		Ids:    []uuid.UUID{pending.Id, confirmed.Id, missing, cancelled.Id, pending.Id},
+		Status: domain.OrderStatusConfirmed,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	assert.Equal(t, pending.Id, results[0].OrderId)
+	assert.Equal(t, domain.OrderStatusConfirmed, results[0].Status)
+	assert.NoError(t, results[0].Err)
+
+	assert.Equal(t, confirmed.Id, results[1].OrderId)
+	assert.Equal(t, domain.OrderStatusConfirmed, results[1].Status)
+	assert.ErrorIs(t, results[1].Err, domain.ErrOrderValidation)
+
+	assert.Equal(t, missing, results[2].OrderId)
+	assert.ErrorIs(t, results[2].Err, domain.ErrOrderNotFound)
+
+	assert.Equal(t, cancelled.Id, results[3].OrderId)
+	assert.Equal(t, domain.OrderStatusCancelled, results[3].Status)
+	assert.ErrorIs(t, results[3].Err, domain.ErrOrderValidation)
+
+	orderStorage.AssertExpectations(t)
+}
+
+func TestOrderAppService_BulkUpdateStatus_RejectsCancellation(t *testing.T) {
+	orderStorage := new(mockOrderStorage)
+
+	service := newTestOrderAppService(orderStorage, new(mockProductStorage), new(mockUserStorage))
+	_, err := service.BulkUpdateStatus(context.Background(), &domain.BulkUpdateOrderStatusRequest{
+		Ids:    []uuid.UUID{uuid.New()},
+		Status: domain.OrderStatusCancelled,
+	})
+	assert.ErrorIs(t, err, domain.ErrOrderValidation)
+	orderStorage.AssertNotCalled(t, "LockOrders", mock.Anything, mock.Anything)
+}
+
+func TestOrderAppService_UpdateOrderItems(t *testing.T) {
+	factory := &domain.Factory{}
+
+	t.Run("add item", func(t *testing.T) {
+		kept := factory.ProductWithQuantity(10)
+		added := factory.ProductWithQuantity(5)
+		order := factory.Order(uuid.New(), kept.Id)
+		order.Items[0].Quantity = 2
+
+		orderStorage := new(mockOrderStorage)
+		productStorage := new(mockProductStorage)
+
+		orderStorage.On("LockOrder", mock.Anything, order.Id).Return(order, nil)
+		productStorage.On("Products", mock.Anything, mock.Anything).Return([]*domain.Product{kept, added}, nil)
+		productStorage.On("AdjustQuantity", mock.Anything, added.Id, -3).Return(added, nil)
+		orderStorage.On("ReplaceOrderItems", mock.Anything, mock.MatchedBy(func(o *domain.Order) bool {
+			return len(o.Items) == 2 && o.Items[1].ProductSnapshot.Description == added.Description
+		})).Return(order, nil)
+
+		service := newTestOrderAppService(orderStorage, productStorage, new(mockUserStorage))
+		_, err := service.UpdateOrderItems(context.Background(), &domain.UpdateOrderItemsRequest{
+			Id: order.Id,
+			Items: []domain.CreateOrderItemRequest{
+				{ProductId: kept.Id, Quantity: 2},
+				{ProductId: added.Id, Quantity: 3},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, 10, kept.Quantity)
+		orderStorage.AssertExpectations(t)
+		productStorage.AssertExpectations(t)
+	})
+
+	t.Run("remove item", func(t *testing.T) {
+		kept := factory.ProductWithQuantity(10)
+		removed := factory.ProductWithQuantity(1)
+		order := factory.Order(uuid.New(), kept.Id, removed.Id)
+		order.Items[0].Quantity = 2
+		order.Items[1].Quantity = 4
+
+		orderStorage := new(mockOrderStorage)
+		productStorage := new(mockProductStorage)
+
+		orderStorage.On("LockOrder", mock.Anything, order.Id).Return(order, nil)
+		productStorage.On("Products", mock.Anything, mock.Anything).Return([]*domain.Product{kept, removed}, nil)
+		productStorage.On("AdjustQuantity", mock.Anything, removed.Id, 4).Return(removed, nil)
+		productStorage.On("AdjustQuantity", mock.Anything, kept.Id, -1).Return(kept, nil)
+		orderStorage.On("ReplaceOrderItems", mock.Anything, mock.MatchedBy(func(o *domain.Order) bool {
+			return len(o.Items) == 1 && o.Items[0].ProductId == kept.Id && o.Items[0].Quantity == 3
+		})).Return(order, nil)
+
+		service := newTestOrderAppService(orderStorage, productStorage, new(mockUserStorage))
+		_, err := service.UpdateOrderItems(context.Background(), &domain.UpdateOrderItemsRequest{
+			Id:    order.Id,
+			Items: []domain.CreateOrderItemRequest{{ProductId: kept.Id, Quantity: 3}},
+		})
+		require.NoError(t, err)
+
+		orderStorage.AssertExpectations(t)
+		productStorage.AssertExpectations(t)
+	})
+
+	t.Run("insufficient stock for added quantity", func(t *testing.T) {
+		product := factory.ProductWithQuantity(1)
+		order := factory.Order(uuid.New(), product.Id)
+		order.Items[0].Quantity = 2
+
+		orderStorage := new(mockOrderStorage)
+		productStorage := new(mockProductStorage)
+
+		orderStorage.On("LockOrder", mock.Anything, order.Id).Return(order, nil)
+		productStorage.On("Products", mock.Anything, mock.Anything).Return([]*domain.Product{product}, nil)
+
+		service := newTestOrderAppService(orderStorage, productStorage, new(mockUserStorage))
+		_, err := service.UpdateOrderItems(context.Background(), &domain.UpdateOrderItemsRequest{
+			Id:    order.Id,
+			Items: []domain.CreateOrderItemRequest{{ProductId: product.Id, Quantity: 4}},
+		})
+		assert.ErrorIs(t, err, domain.ErrInsufficientStock)
+
+		productStorage.AssertNotCalled(t, "AdjustQuantity", mock.Anything, mock.Anything, mock.Anything)
+		orderStorage.AssertNotCalled(t, "ReplaceOrderItems", mock.Anything, mock.Anything)
+	})
+
+	t.Run("confirmed order is rejected", func(t *testing.T) {
+		product := factory.ProductWithQuantity(10)
+		order := factory.Order(uuid.New(), product.Id)
+		order.Status = domain.OrderStatusConfirmed
+
+		orderStorage := new(mockOrderStorage)
+		productStorage := new(mockProductStorage)
+
+		orderStorage.On("LockOrder", mock.Anything, order.Id).Return(order, nil)
+
+		service := newTestOrderAppService(orderStorage, productStorage, new(mockUserStorage))
+		_, err := service.UpdateOrderItems(context.Background(), &domain.UpdateOrderItemsRequest{
+			Id:    order.Id,
+			Items: []domain.CreateOrderItemRequest{{ProductId: product.Id, Quantity: 1}},
+		})
+		assert.ErrorIs(t, err, domain.ErrOrderValidation)
+
+		productStorage.AssertNotCalled(t, "Products", mock.Anything, mock.Anything)
+		orderStorage.AssertNotCalled(t, "ReplaceOrderItems", mock.Anything, mock.Anything)
+	})
+
+	t.Run("order not found", func(t *testing.T) {
+		orderStorage := new(mockOrderStorage)
+		orderStorage.On("LockOrder", mock.Anything, mock.Anything).Return(nil, domain.ErrOrderNotFound)
+
+		service := newTestOrderAppService(orderStorage, new(mockProductStorage), new(mockUserStorage))
+		_, err := service.UpdateOrderItems(context.Background(), &domain.UpdateOrderItemsRequest{
+			Id:    uuid.New(),
+			Items: []domain.CreateOrderItemRequest{{ProductId: uuid.New(), Quantity: 1}},
+		})
+		assert.ErrorIs(t, err, domain.ErrOrderNotFound)
+	})
+}
+
+func TestOrderAppService_UserOrders(t *testing.T) {
+	factory := &domain.Factory{}
+	user := factory.User()
+
+	tests := []struct {
+		name        string
+		users       []*domain.User
+		orders      []*domain.Order
+		expectedErr error
+	}{
+		{
+			name:  "user with multiple orders",
+			users: []*domain.User{user},
+			orders: []*domain.Order{
+				factory.Order(user.Id, uuid.New()),
+				factory.Order(user.Id, uuid.New()),
+			},
+		},
+		{
+			name:   "user without orders",
+			users:  []*domain.User{user},
+			orders: []*domain.Order{},
+		},
+		{
+			name:        "nonexistent user",
+			users:       []*domain.User{},
+			expectedErr: domain.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userStorage := new(mockUserStorage)
+			orderStorage := new(mockOrderStorage)
+
+			userStorage.On("Users", mock.Anything, mock.Anything).Return(tt.users, nil)
+			if tt.expectedErr == nil {
+				orderStorage.On("Orders", mock.Anything, mock.MatchedBy(func(req *domain.GetOrdersRequest) bool {
+					return len(req.UserIds) == 1 && req.UserIds[0] == user.Id && req.Limit == 10
+				})).Return(tt.orders, nil)
+			}
+
+			service := newTestOrderAppService(orderStorage, new(mockProductStorage), userStorage)
+			orders, err := service.UserOrders(context.Background(), user.Id, &domain.GetOrdersRequest{Limit: 10})
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				orderStorage.AssertNotCalled(t, "Orders", mock.Anything, mock.Anything)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, orders, len(tt.orders))
+			userStorage.AssertExpectations(t)
+			orderStorage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestOrderAppService_CancelItem(t *testing.T) {
+	factory := &domain.Factory{}
+	kept, cancelledProduct := uuid.New(), uuid.New()
+
+	t.Run("one of several items", func(t *testing.T) {
+		order := factory.Order(uuid.New(), kept, cancelledProduct)
+		item := order.Items[1]
+		item.Quantity = 3
+		remaining := factory.Order(order.UserId, kept)
+		remaining.Id = order.Id
+
+		orderStorage := new(mockOrderStorage)
+		productStorage := new(mockProductStorage)
+		orderStorage.On("LockOrder", mock.Anything, order.Id).Return(order, nil)
+		orderStorage.On("RemoveOrderItem", mock.Anything, order.Id, item.Id).Return(remaining, nil)
+		productStorage.On("AdjustQuantity", mock.Anything, cancelledProduct, 3).Return(factory.Product(), nil)
+
+		service, spy := newSpiedOrderAppService(orderStorage, productStorage, new(mockUserStorage))
+		got, err := service.CancelItem(context.Background(), order.Id, item.Id)
+		require.NoError(t, err)
+		assert.Same(t, remaining, got)
+		assert.Equal(t, domain.OrderStatusPending, got.Status)
+		assert.Empty(t, spy.events)
+		orderStorage.AssertNotCalled(t, "UpdateOrder", mock.Anything, mock.Anything)
+		productStorage.AssertExpectations(t)
+	})
+
+	t.Run("last item cancels the order", func(t *testing.T) {
+		order := factory.Order(uuid.New(), cancelledProduct)
+		item := order.Items[0]
+		cancelled := *order
+		cancelled.Status = domain.OrderStatusCancelled
+
+		orderStorage := new(mockOrderStorage)
+		productStorage := new(mockProductStorage)
+		orderStorage.On("LockOrder", mock.Anything, order.Id).Return(order, nil)
+		orderStorage.On("UpdateOrder", mock.Anything, &domain.UpdateOrderRequest{Id: order.Id, Status: domain.OrderStatusCancelled}).
+			Return(&cancelled, nil)
+		productStorage.On("AdjustQuantity", mock.Anything, cancelledProduct, 1).Return(factory.Product(), nil)
+
+		service, spy := newSpiedOrderAppService(orderStorage, productStorage, new(mockUserStorage))
+		got, err := service.CancelItem(context.Background(), order.Id, item.Id)
+		require.NoError(t, err)
+		assert.Equal(t, domain.OrderStatusCancelled, got.Status)
+		assert.Equal(t, []domain.OrderEventType{domain.OrderCancelled}, spy.types())
+		orderStorage.AssertNotCalled(t, "RemoveOrderItem", mock.Anything, mock.Anything, mock.Anything)
+		productStorage.AssertExpectations(t)
+	})
+
+	t.Run("deleted product is not restocked", func(t *testing.T) {
+		order := factory.Order(uuid.New(), kept, cancelledProduct)
+
+		orderStorage := new(mockOrderStorage)
+		productStorage := new(mockProductStorage)
+		orderStorage.On("LockOrder", mock.Anything, order.Id).Return(order, nil)
+		orderStorage.On("RemoveOrderItem", mock.Anything, order.Id, order.Items[1].Id).Return(order, nil)
+		productStorage.On("AdjustQuantity", mock.Anything, cancelledProduct, 1).Return(nil, domain.ErrProductNotFound)
+
+		_, err := newTestOrderAppService(orderStorage, productStorage, new(mockUserStorage)).
+			CancelItem(context.Background(), order.Id, order.Items[1].Id)
+		assert.NoError(t, err)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		order := factory.Order(uuid.New(), kept)
+		completed := factory.Order(uuid.New(), kept)
+		completed.Status = domain.OrderStatusCompleted
+
+		orderStorage := new(mockOrderStorage)
+		orderStorage.On("LockOrder", mock.Anything, order.Id).Return(order, nil)
+		orderStorage.On("LockOrder", mock.Anything, completed.Id).Return(completed, nil)
+		orderStorage.On("LockOrder", mock.Anything, mock.Anything).Return(nil, domain.ErrOrderNotFound)
+		service := newTestOrderAppService(orderStorage, new(mockProductStorage), new(mockUserStorage))
+
+		_, err := service.CancelItem(context.Background(), uuid.New(), uuid.New())
+		assert.ErrorIs(t, err, domain.ErrOrderNotFound)
+
+		_, err = service.CancelItem(context.Background(), order.Id, uuid.New())
+		assert.ErrorIs(t, err, domain.ErrOrderItemNotFound)
+
+		_, err = service.CancelItem(context.Background(), completed.Id, completed.Items[0].Id)
+		assert.ErrorIs(t, err, domain.ErrOrderValidation)
+	})
+}
+
+func TestOrderAppService_OrderProducts(t *testing.T) {
+	factory := &domain.Factory{}
+	shared, other := factory.Product(), factory.Product()
+	orders := []*domain.Order{
+		factory.Order(uuid.New(), shared.Id),
+		factory.Order(uuid.New(), shared.Id, other.Id),
+	}
+
+	productStorage := new(mockProductStorage)
+	productStorage.On("Products", mock.Anything, &domain.GetProductsRequest{
+		Ids:            []uuid.UUID{shared.Id, other.Id},
+		IncludeDeleted: true,
+		Limit:          2,
+	}).Return([]*domain.Product{shared}, nil).Once()
+
+	service := newTestOrderAppService(new(mockOrderStorage), productStorage, new(mockUserStorage))
+	products, err := service.OrderProducts(context.Background(), orders)
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]*domain.Product{shared.Id: shared}, products)
+	productStorage.AssertExpectations(t)
+
+	// orders without items need no query
+	products, err = service.OrderProducts(context.Background(), []*domain.Order{factory.Order(uuid.New())})
+	require.NoError(t, err)
+	assert.Empty(t, products)
+}
+
+func TestOrderAppService_OrderUser(t *testing.T) {
+	factory := &domain.Factory{}
+	user := factory.User()
+	deletedAt := time.Now()
+	user.DeletedAt = &deletedAt
+	order := factory.Order(user.Id, uuid.New())
+
+	t.Run("soft-deleted user is resolved", func(t *testing.T) {
+		userStorage := new(mockUserStorage)
+		userStorage.On("UsersByIds", mock.Anything, []uuid.UUID{user.Id}).
+			Return(map[uuid.UUID]*domain.User{user.Id: user}, nil)
+
+		service := newTestOrderAppService(new(mockOrderStorage), new(mockProductStorage), userStorage)
+		got, err := service.OrderUser(context.Background(), order)
+		require.NoError(t, err)
+		assert.Equal(t, user, got)
+	})
+
+	t.Run("missing user is nil", func(t *testing.T) {
+		userStorage := new(mockUserStorage)
+		userStorage.On("UsersByIds", mock.Anything, []uuid.UUID{user.Id}).
+			Return(map[uuid.UUID]*domain.User{}, nil)
+
+		service := newTestOrderAppService(new(mockOrderStorage), new(mockProductStorage), userStorage)
+		got, err := service.OrderUser(context.Background(), order)
+		require.NoError(t, err)
+		assert.Nil(t, got)
+	})
+}
@@ -2,23 +2,31 @@ package application
 
 import (
 	"context"
+	"errors"
+
+	"github.com/google/uuid"
 
 	"mts/internal/domain"
 
 	"github.com/rs/zerolog"
 )
 
-func NewProductAppService(productStorage domain.ProductStorage) domain.ProductAppService {
+func NewProductAppService(productStorage domain.ProductStorage, unitOfWork domain.UnitOfWork) domain.ProductAppService {
 	return &productAppService{
 		productStorage: productStorage,
+		unitOfWork:     unitOfWork,
 	}
 }
 
 type productAppService struct {
 	productStorage domain.ProductStorage
+	unitOfWork     domain.UnitOfWork
 }
 
 func (s *productAppService) CreateProduct(ctx context.Context, req *domain.CreateProductRequest) (*domain.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductAppService.CreateProduct")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx).With().
 		Str("operation", "CreateProduct").
 		Str("description", req.Description).
@@ -46,7 +54,41 @@ func (s *productAppService) CreateProduct(ctx context.Context, req *domain.Creat
 	return product, nil
 }
 
+func (s *productAppService) UpsertProduct(ctx context.Context, req *domain.CreateProductRequest) (*domain.Product, bool, error) {
+	ctx, span := tracer.Start(ctx, "ProductAppService.UpsertProduct")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "UpsertProduct").
+		Str("description", req.Description).
+		Logger()
+
+	logger.Info().Msg("upserting product")
+
+	product, err := req.ToDomain()
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to convert request to domain")
+		return nil, false, err
+	}
+
+	product, created, err := s.productStorage.UpsertProduct(ctx, product)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to upsert product in storage")
+		return nil, false, err
+	}
+
+	logger.Info().
+		Str("product_id", product.Id.String()).
+		Bool("created", created).
+		Msg("product upserted successfully")
+
+	return product, created, nil
+}
+
 func (s *productAppService) UpdateProduct(ctx context.Context, req *domain.UpdateProductRequest) (*domain.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductAppService.UpdateProduct")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx).With().
 		Str("operation", "UpdateProduct").
 		Str("product_id", req.Id.String()).
@@ -65,7 +107,106 @@ func (s *productAppService) UpdateProduct(ctx context.Context, req *domain.Updat
 	return product, nil
 }
 
+func (s *productAppService) RestockProduct(ctx context.Context, req *domain.RestockProductRequest) (*domain.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductAppService.RestockProduct")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "RestockProduct").
+		Str("product_id", req.Id.String()).
+		Int("quantity", req.Quantity).
+		Logger()
+
+	logger.Info().Msg("restocking product")
+
+	if err := req.Validate(); err != nil {
+		logger.Error().Err(err).Msg("invalid restock request")
+		return nil, err
+	}
+
+	product, err := s.productStorage.AdjustQuantity(ctx, req.Id, req.Quantity)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to adjust product quantity in storage")
+		return nil, err
+	}
+
+	logger.Info().
+		Int("stock", product.Quantity).
+		Msg("product restocked successfully")
+
+	return product, nil
+}
+
+func (s *productAppService) ImportProducts(ctx context.Context, reqs []*domain.CreateProductRequest) []*domain.ProductImportResult {
+	ctx, span := tracer.Start(ctx, "ProductAppService.ImportProducts")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "ImportProducts").
+		Int("products_count", len(reqs)).
+		Logger()
+
+	logger.Info().Msg("importing products")
+
+	results := make([]*domain.ProductImportResult, len(reqs))
+	var valid []*domain.ProductImportResult
+	for i, req := range reqs {
+		product, err := req.ToDomain()
+		results[i] = &domain.ProductImportResult{Product: product, Err: err}
+		if err == nil {
+			valid = append(valid, results[i])
+		}
+	}
+
+	created := 0
+	if len(valid) > 0 {
+		products := make([]*domain.Product, len(valid))
+		for i, result := range valid {
+			products[i] = result.Product
+		}
+
+		err := s.unitOfWork.Do(ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+			// the outcomes of an attempt that is retried don't count
+			created = 0
+			for i, result := range valid {
+				result.Product, result.Err = products[i], nil
+
+				// a rejected row is rolled back to its savepoint, the rest of the batch goes on
+				err := tx.Products.CreateProduct(ctx, products[i])
+				switch {
+				case err == nil:
+					created++
+				case errors.Is(err, domain.ErrProductExists) || errors.Is(err, domain.ErrProductValidation):
+					logger.Warn().Err(err).Int("row", i).Msg("imported product rejected")
+					result.Product, result.Err = nil, err
+				default:
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// the transaction is rolled back, so none of the batch was stored
+			logger.Error().Err(err).Msg("failed to store imported products")
+			for _, result := range valid {
+				result.Product, result.Err = nil, err
+			}
+			created = 0
+		}
+	}
+
+	logger.Info().
+		Int("created_count", created).
+		Int("failed_count", len(reqs)-created).
+		Msg("products imported")
+
+	return results
+}
+
 func (s *productAppService) Products(ctx context.Context, req *domain.GetProductsRequest) ([]*domain.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductAppService.Products")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx).With().
 		Str("operation", "Products").
 		Int("ids_count", len(req.Ids)).
@@ -87,6 +228,9 @@ func (s *productAppService) Products(ctx context.Context, req *domain.GetProduct
 }
 
 func (s *productAppService) CountProducts(ctx context.Context, req *domain.GetProductsRequest) (int, error) {
+	ctx, span := tracer.Start(ctx, "ProductAppService.CountProducts")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx).With().
 		Str("operation", "CountProducts").
 		Int("ids_count", len(req.Ids)).
@@ -106,3 +250,47 @@ func (s *productAppService) CountProducts(ctx context.Context, req *domain.GetPr
 
 	return count, nil
 }
+
+func (s *productAppService) TagCounts(ctx context.Context, req *domain.GetProductsRequest) ([]*domain.ProductTagCount, error) {
+	ctx, span := tracer.Start(ctx, "ProductAppService.TagCounts")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "TagCounts").
+		Logger()
+
+	logger.Debug().Msg("counting product tags")
+
+	tags, err := s.productStorage.TagCounts(ctx, req)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to count product tags in storage")
+		return nil, err
+	}
+
+	logger.Debug().
+		Int("tags_count", len(tags)).
+		Msg("product tags counted successfully")
+
+	return tags, nil
+}
+
+func (s *productAppService) DeleteProduct(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "ProductAppService.DeleteProduct")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "DeleteProduct").
+		Str("product_id", id.String()).
+		Logger()
+
+	logger.Info().Msg("deleting product")
+
+	if err := s.productStorage.DeleteProduct(ctx, id); err != nil {
+		logger.Error().Err(err).Msg("failed to delete product in storage")
+		return err
+	}
+
+	logger.Info().Msg("product deleted successfully")
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+
+	"mts/internal/domain"
+	"mts/internal/repository/storage"
+	"shared"
+	sharedConfig "shared/config"
+)
+
+type ProductImportSuite struct {
+	shared.Suite[any]
+	productStorage domain.ProductStorage
+	service        domain.ProductAppService
+}
+
+func (s *ProductImportSuite) SetupSuite() {
+	s.PostgresEnabled = true
+	s.Suite.SetupSuite()
+
+	userStorage := storage.NewUserStorage(s.PostgresConn, nil, storage.CacheOptions{}, nil)
+	s.productStorage = storage.NewProductStorage(s.PostgresConn, nil, storage.CacheOptions{}, nil)
+	orderStorage := storage.NewOrderStorage(s.PostgresConn, nil, storage.CacheOptions{}, sharedConfig.Retry{}, nil)
+
+	s.service = NewProductAppService(
+		s.productStorage,
+		storage.NewUnitOfWork(s.PostgresConn, sharedConfig.Retry{}, nil, userStorage, s.productStorage, orderStorage),
+	)
+}
+
+func (s *ProductImportSuite) TearDownTest() {
+	_, err := s.PostgresConn.Exec(s.Ctx, "TRUNCATE TABLE order_items, orders, products, users RESTART IDENTITY CASCADE")
+	s.Require().NoError(err)
+}
+
+func (s *ProductImportSuite) TestImportProducts_StoresValidRows() {
+	results := s.service.ImportProducts(s.Ctx, []*domain.CreateProductRequest{
+		{Description: "Phone", Tags: []string{"electronics"}, Quantity: 5},
+		{Description: "", Quantity: 1},
+		{Description: "Cable", Quantity: 0},
+		{Description: "Charger", Quantity: -3},
+	})
+	s.Require().Len(results, 4)
+
+	s.Require().NoError(results[0].Err)
+	s.ErrorIs(results[1].Err, domain.ErrProductValidation)
+	s.Require().NoError(results[2].Err)
+	s.ErrorIs(results[3].Err, domain.ErrProductValidation)
+
+	stored, err := s.productStorage.Products(s.Ctx, &domain.GetProductsRequest{
+		Ids: []uuid.UUID{results[0].Product.Id, results[2].Product.Id},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(stored, 2)
+
+	count, err := s.productStorage.CountProducts(s.Ctx, &domain.GetProductsRequest{})
+	s.Require().NoError(err)
+	s.Equal(2, count)
+}
+
+func (s *ProductImportSuite) TestImportProducts_MixedRows() {
+	taken := (&domain.Factory{}).Product()
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, taken))
+
+	results := s.service.ImportProducts(s.Ctx, []*domain.CreateProductRequest{
+		{Description: "Phone", Quantity: 5},
+		{Description: taken.Description, Quantity: 1},
+		{Description: "", Quantity: 1},
+		{Description: "Phone", Quantity: 2},
+		{Description: "Cable", Quantity: 3},
+	})
+	s.Require().Len(results, 5)
+
+	// the rows the database rejects are rolled back alone, the rows around them are stored
+	s.Require().NoError(results[0].Err)
+	s.ErrorIs(results[1].Err, domain.ErrProductExists)
+	s.ErrorIs(results[2].Err, domain.ErrProductValidation)
+	s.ErrorIs(results[3].Err, domain.ErrProductExists, "taken by an earlier row of the batch")
+	s.Require().NoError(results[4].Err)
+
+	stored, err := s.productStorage.Products(s.Ctx, &domain.GetProductsRequest{
+		Ids: []uuid.UUID{results[0].Product.Id, results[4].Product.Id},
+	})
+	s.Require().NoError(err)
+	s.Require().Len(stored, 2)
+
+	count, err := s.productStorage.CountProducts(s.Ctx, &domain.GetProductsRequest{})
+	s.Require().NoError(err)
+	s.Equal(3, count)
+}
+
+func TestProductImportSuite(t *testing.T) {
+	suite.Run(t, new(ProductImportSuite))
+}
@@ -0,0 +1,118 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/domain"
+)
+
+func TestProductAppService_RestockProduct(t *testing.T) {
+	product := (&domain.Factory{}).ProductWithQuantity(0)
+	require.False(t, product.IsAvailable())
+
+	restocked := *product
+	restocked.Quantity = 5
+
+	productStorage := new(mockProductStorage)
+	productStorage.On("AdjustQuantity", mock.Anything, product.Id, 5).Return(&restocked, nil)
+
+	service := NewProductAppService(productStorage, nil)
+	result, err := service.RestockProduct(context.Background(), &domain.RestockProductRequest{Id: product.Id, Quantity: 5})
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.Quantity)
+	assert.True(t, result.IsAvailable())
+
+	productStorage.AssertExpectations(t)
+}
+
+func TestProductAppService_RestockProduct_NonPositiveQuantity(t *testing.T) {
+	product := (&domain.Factory{}).ProductWithQuantity(0)
+	productStorage := new(mockProductStorage)
+	service := NewProductAppService(productStorage, nil)
+
+	for _, quantity := range []int{0, -1} {
+		_, err := service.RestockProduct(context.Background(), &domain.RestockProductRequest{Id: product.Id, Quantity: quantity})
+
+		var validationErr *domain.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.ErrorIs(t, err, domain.ErrProductValidation)
+		assert.Equal(t, "quantity must be positive", validationErr.Fields["quantity"])
+	}
+
+	productStorage.AssertNotCalled(t, "AdjustQuantity", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestProductAppService_ImportProducts_ReportsInvalidRequests(t *testing.T) {
+	productStorage := new(mockProductStorage)
+	productStorage.On("CreateProduct", mock.Anything, mock.Anything).Return(nil).Twice()
+
+	service := NewProductAppService(productStorage, &txUnitOfWork{tx: &domain.TxStorages{Products: productStorage}})
+	results := service.ImportProducts(context.Background(), []*domain.CreateProductRequest{
+		{Description: "Phone", Quantity: 5},
+		{Description: " ", Quantity: 1},
+		{Description: "Cable", Quantity: 0},
+	})
+	require.Len(t, results, 3)
+
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, "Phone", results[0].Product.Description)
+	assert.ErrorIs(t, results[1].Err, domain.ErrProductValidation)
+	assert.Nil(t, results[1].Product)
+	require.NoError(t, results[2].Err)
+	assert.Equal(t, "Cable", results[2].Product.Description)
+
+	productStorage.AssertExpectations(t)
+}
+
+func TestProductAppService_ImportProducts_RejectedRowsDontStopTheBatch(t *testing.T) {
+	productStorage := new(mockProductStorage)
+	productStorage.On("CreateProduct", mock.Anything, mock.MatchedBy(func(p *domain.Product) bool {
+		return p.Description == "Taken"
+	})).Return(fmt.Errorf("%w: duplicate description", domain.ErrProductExists)).Once()
+	productStorage.On("CreateProduct", mock.Anything, mock.Anything).Return(nil).Twice()
+
+	service := NewProductAppService(productStorage, &txUnitOfWork{tx: &domain.TxStorages{Products: productStorage}})
+	results := service.ImportProducts(context.Background(), []*domain.CreateProductRequest{
+		{Description: "Phone", Quantity: 5},
+		{Description: "Taken", Quantity: 1},
+		{Description: "Cable", Quantity: 2},
+	})
+	require.Len(t, results, 3)
+
+	require.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, domain.ErrProductExists)
+	assert.Nil(t, results[1].Product)
+	require.NoError(t, results[2].Err)
+	assert.Equal(t, "Cable", results[2].Product.Description)
+
+	productStorage.AssertExpectations(t)
+}
+
+func TestProductAppService_ImportProducts_FailedBatch(t *testing.T) {
+	storeErr := errors.New("connection reset")
+	productStorage := new(mockProductStorage)
+	productStorage.On("CreateProduct", mock.Anything, mock.Anything).Return(nil).Once()
+	productStorage.On("CreateProduct", mock.Anything, mock.Anything).Return(storeErr).Once()
+
+	service := NewProductAppService(productStorage, &txUnitOfWork{tx: &domain.TxStorages{Products: productStorage}})
+	results := service.ImportProducts(context.Background(), []*domain.CreateProductRequest{
+		{Description: "Phone", Quantity: 5},
+		{Description: "Cable", Quantity: 0},
+		{Quantity: -1},
+	})
+	require.Len(t, results, 3)
+
+	// the transaction is rolled back, so the row stored before the failure is reported too
+	for _, result := range results[:2] {
+		assert.ErrorIs(t, result.Err, storeErr)
+		assert.Nil(t, result.Product)
+	}
+	assert.ErrorIs(t, results[2].Err, domain.ErrProductValidation)
+}
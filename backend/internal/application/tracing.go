@@ -0,0 +1,5 @@
+package application
+
+import "go.opentelemetry.io/otel"
+
+var tracer = otel.Tracer("mts/internal/application")
@@ -2,23 +2,36 @@ package application
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"runtime"
 
 	"mts/internal/domain"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+	"golang.org/x/sync/errgroup"
 )
 
-func NewUserAppService(userStorage domain.UserStorage) domain.UserAppService {
+// NewUserAppService mails new users a link to verificationUrl carrying their token as the token query parameter
+func NewUserAppService(userStorage domain.UserStorage, mailer domain.Mailer, verificationUrl string) domain.UserAppService {
 	return &userAppService{
-		userStorage: userStorage,
+		userStorage:     userStorage,
+		mailer:          mailer,
+		verificationUrl: verificationUrl,
 	}
 }
 
 type userAppService struct {
-	userStorage domain.UserStorage
+	userStorage     domain.UserStorage
+	mailer          domain.Mailer
+	verificationUrl string
 }
 
 func (s *userAppService) RegisterUser(ctx context.Context, req *domain.CreateUserRequest) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserAppService.RegisterUser")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx).With().
 		Str("operation", "RegisterUser").
 		Str("first_name", req.FirstName).
@@ -40,6 +53,13 @@ func (s *userAppService) RegisterUser(ctx context.Context, req *domain.CreateUse
 		return nil, err
 	}
 
+	// the account exists either way, so a mail failure doesn't fail the registration
+	if err = s.mailer.Send(ctx, s.verificationMail(user)); err != nil {
+		logger.Error().Err(err).
+			Str("user_id", user.Id.String()).
+			Msg("failed to send verification email")
+	}
+
 	logger.Info().
 		Str("user_id", user.Id.String()).
 		Msg("user registered successfully")
@@ -47,7 +67,95 @@ func (s *userAppService) RegisterUser(ctx context.Context, req *domain.CreateUse
 	return user, nil
 }
 
+func (s *userAppService) RegisterUsers(ctx context.Context, reqs []*domain.CreateUserRequest) ([]*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserAppService.RegisterUsers")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "RegisterUsers").
+		Int("users_count", len(reqs)).
+		Logger()
+
+	logger.Info().Msg("registering users")
+
+	// nothing is hashed for a batch that would be rejected anyway
+	if err := domain.ValidateCreateUserRequests(reqs); err != nil {
+		logger.Error().Err(err).Msg("invalid users")
+		return nil, err
+	}
+
+	// password hashing is deliberately slow, so the batch is hashed in parallel
+	users := make([]*domain.User, len(reqs))
+	eg := &errgroup.Group{}
+	eg.SetLimit(runtime.GOMAXPROCS(0))
+	for i, req := range reqs {
+		eg.Go(func() error {
+			var err error
+			users[i], err = req.ToDomain()
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		logger.Error().Err(err).Msg("failed to convert requests to domain")
+		return nil, err
+	}
+
+	if err := s.userStorage.CreateUsers(ctx, users); err != nil {
+		logger.Error().Err(err).Msg("failed to create users in storage")
+		return nil, err
+	}
+
+	for _, user := range users {
+		if err := s.mailer.Send(ctx, s.verificationMail(user)); err != nil {
+			logger.Error().Err(err).
+				Str("user_id", user.Id.String()).
+				Msg("failed to send verification email")
+		}
+	}
+
+	logger.Info().Msg("users registered successfully")
+
+	return users, nil
+}
+
+func (s *userAppService) verificationMail(user *domain.User) *domain.Mail {
+	link := s.verificationUrl + "?" + url.Values{"token": {user.VerificationToken}}.Encode()
+
+	return &domain.Mail{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hi %s,\n\nplease confirm your email address by opening the link below:\n\n%s\n",
+			user.FullName(), link),
+	}
+}
+
+func (s *userAppService) VerifyEmail(ctx context.Context, token string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserAppService.VerifyEmail")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "VerifyEmail").
+		Logger()
+
+	logger.Info().Msg("verifying user email")
+
+	user, err := s.userStorage.VerifyEmail(ctx, token)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to verify user email")
+		return nil, err
+	}
+
+	logger.Info().
+		Str("user_id", user.Id.String()).
+		Msg("user email verified successfully")
+
+	return user, nil
+}
+
 func (s *userAppService) Users(ctx context.Context, req *domain.GetUsersRequest) ([]*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserAppService.Users")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx).With().
 		Str("operation", "Users").
 		Int("limit", req.Limit).
@@ -71,6 +179,9 @@ func (s *userAppService) Users(ctx context.Context, req *domain.GetUsersRequest)
 }
 
 func (s *userAppService) CountUsers(ctx context.Context, req *domain.GetUsersRequest) (int, error) {
+	ctx, span := tracer.Start(ctx, "UserAppService.CountUsers")
+	defer span.End()
+
 	logger := zerolog.Ctx(ctx).With().
 		Str("operation", "CountUsers").
 		Int("ids_count", len(req.Ids)).
@@ -90,3 +201,24 @@ func (s *userAppService) CountUsers(ctx context.Context, req *domain.GetUsersReq
 
 	return count, nil
 }
+
+func (s *userAppService) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "UserAppService.DeleteUser")
+	defer span.End()
+
+	logger := zerolog.Ctx(ctx).With().
+		Str("operation", "DeleteUser").
+		Str("user_id", id.String()).
+		Logger()
+
+	logger.Info().Msg("deleting user")
+
+	if err := s.userStorage.DeleteUser(ctx, id); err != nil {
+		logger.Error().Err(err).Msg("failed to delete user in storage")
+		return err
+	}
+
+	logger.Info().Msg("user deleted successfully")
+
+	return nil
+}
@@ -2,10 +2,13 @@ package application
 
 import (
 	"context"
+	"errors"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"mts/internal/domain"
 )
@@ -20,6 +23,11 @@ func (m *mockUserStorage) CreateUser(ctx context.Context, user *domain.User) err
 	return args.Error(0)
 }
 
+func (m *mockUserStorage) CreateUsers(ctx context.Context, users []*domain.User) error {
+	args := m.Called(ctx, users)
+	return args.Error(0)
+}
+
 func (m *mockUserStorage) Users(ctx context.Context, req *domain.GetUsersRequest) ([]*domain.User, error) {
 	args := m.Called(ctx, req)
 	return args.Get(0).([]*domain.User), args.Error(1)
@@ -30,6 +38,53 @@ func (m *mockUserStorage) CountUsers(ctx context.Context, req *domain.GetUsersRe
 	return args.Int(0), args.Error(1)
 }
 
+func (m *mockUserStorage) UsersByIds(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*domain.User, error) {
+	args := m.Called(ctx, ids)
+	users, _ := args.Get(0).(map[uuid.UUID]*domain.User)
+	return users, args.Error(1)
+}
+
+func (m *mockUserStorage) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *mockUserStorage) VerifyEmail(ctx context.Context, token string) (*domain.User, error) {
+	args := m.Called(ctx, token)
+	user, _ := args.Get(0).(*domain.User)
+	return user, args.Error(1)
+}
+
+func (m *mockUserStorage) UserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	args := m.Called(ctx, email)
+	user, _ := args.Get(0).(*domain.User)
+	return user, args.Error(1)
+}
+
+func (m *mockUserStorage) LockUser(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	args := m.Called(ctx, id)
+	user, _ := args.Get(0).(*domain.User)
+	return user, args.Error(1)
+}
+
+func (m *mockUserStorage) CacheStats() domain.CacheStats {
+	args := m.Called()
+	return args.Get(0).(domain.CacheStats)
+}
+
+func (m *mockUserStorage) Close() {
+	m.Called()
+}
+
+type mockMailer struct {
+	mock.Mock
+}
+
+func (m *mockMailer) Send(ctx context.Context, mail *domain.Mail) error {
+	args := m.Called(ctx, mail)
+	return args.Error(0)
+}
+
 func TestUserAppService_RegisterUser(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -43,6 +98,7 @@ func TestUserAppService_RegisterUser(t *testing.T) {
 			request: &domain.CreateUserRequest{
 				FirstName: "John",
 				LastName:  "Doe",
+				Email:     "user@example.com",
 				Age:       25,
 				IsMarried: false,
 				Password:  "password123",
@@ -57,6 +113,7 @@ func TestUserAppService_RegisterUser(t *testing.T) {
 			request: &domain.CreateUserRequest{
 				FirstName: "Jane",
 				LastName:  "Doe",
+				Email:     "user@example.com",
 				Age:       17, // Invalid: too young
 				IsMarried: false,
 				Password:  "password123",
@@ -72,6 +129,7 @@ func TestUserAppService_RegisterUser(t *testing.T) {
 			request: &domain.CreateUserRequest{
 				FirstName: "Bob",
 				LastName:  "Smith",
+				Email:     "user@example.com",
 				Age:       30,
 				IsMarried: true,
 				Password:  "123", // Invalid: too short
@@ -90,7 +148,10 @@ func TestUserAppService_RegisterUser(t *testing.T) {
 			mockStorage := new(mockUserStorage)
 			tt.setupMock(mockStorage)
 
-			userAppService := NewUserAppService(mockStorage)
+			mailer := new(mockMailer)
+			mailer.On("Send", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+			userAppService := NewUserAppService(mockStorage, mailer, "http://localhost/api/v1/users/verify")
 			ctx := context.Background()
 
 			// Act
@@ -118,3 +179,109 @@ func TestUserAppService_RegisterUser(t *testing.T) {
 		})
 	}
 }
+
+func TestUserAppService_RegisterUser_SendsVerificationMail(t *testing.T) {
+	userStorage := new(mockUserStorage)
+	userStorage.On("CreateUser", mock.Anything, mock.Anything).Return(nil)
+
+	var sent *domain.Mail
+	mailer := new(mockMailer)
+	mailer.On("Send", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { sent = args.Get(1).(*domain.Mail) }).
+		Return(errors.New("smtp unavailable"))
+
+	service := NewUserAppService(userStorage, mailer, "http://localhost/api/v1/users/verify")
+	user, err := service.RegisterUser(context.Background(), &domain.CreateUserRequest{
+		FirstName: "John",
+		LastName:  "Doe",
+		Age:       25,
+		Email:     "john.doe@example.com",
+		Password:  "password123",
+	})
+
+	// a failed mail does not undo the registration
+	require.NoError(t, err)
+	assert.False(t, user.EmailVerified)
+	require.NotEmpty(t, user.VerificationToken)
+
+	require.NotNil(t, sent)
+	assert.Equal(t, "john.doe@example.com", sent.To)
+	assert.Contains(t, sent.Body, "http://localhost/api/v1/users/verify?token="+user.VerificationToken)
+}
+
+func TestUserAppService_RegisterUsers(t *testing.T) {
+	newRequest := func(email string) *domain.CreateUserRequest {
+		return &domain.CreateUserRequest{
+			FirstName: "John",
+			LastName:  "Doe",
+			Age:       25,
+			Email:     email,
+			Password:  "password123",
+		}
+	}
+
+	t.Run("registers the whole batch", func(t *testing.T) {
+		userStorage := new(mockUserStorage)
+		userStorage.On("CreateUsers", mock.Anything, mock.Anything).Return(nil)
+		mailer := new(mockMailer)
+		mailer.On("Send", mock.Anything, mock.Anything).Return(nil)
+
+		service := NewUserAppService(userStorage, mailer, "http://localhost/api/v1/users/verify")
+		users, err := service.RegisterUsers(context.Background(), []*domain.CreateUserRequest{
+			newRequest("first@example.com"),
+			newRequest("second@example.com"),
+		})
+		require.NoError(t, err)
+		require.Len(t, users, 2)
+
+		// the users keep the order of the requests and are stored by a single call
+		assert.Equal(t, "first@example.com", users[0].Email)
+		assert.Equal(t, "second@example.com", users[1].Email)
+		for _, user := range users {
+			assert.True(t, user.VerifyPassword("password123"))
+			assert.NotEmpty(t, user.VerificationToken)
+		}
+		userStorage.AssertNumberOfCalls(t, "CreateUsers", 1)
+		mailer.AssertNumberOfCalls(t, "Send", 2)
+	})
+
+	t.Run("validates every user before storing any", func(t *testing.T) {
+		userStorage := new(mockUserStorage)
+		service := NewUserAppService(userStorage, nil, "")
+
+		invalid := newRequest("not-an-email")
+		invalid.Age = 0
+		_, err := service.RegisterUsers(context.Background(), []*domain.CreateUserRequest{
+			newRequest("first@example.com"),
+			invalid,
+		})
+
+		var validationErr *domain.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.ErrorIs(t, err, domain.ErrUserValidation)
+		assert.Contains(t, validationErr.Fields, "users[1].email")
+		assert.Contains(t, validationErr.Fields, "users[1].age")
+		assert.Len(t, validationErr.Fields, 2)
+		userStorage.AssertNotCalled(t, "CreateUsers", mock.Anything, mock.Anything)
+
+		_, err = service.RegisterUsers(context.Background(), nil)
+		assert.ErrorIs(t, err, domain.ErrUserValidation)
+	})
+
+	t.Run("sends no mail when the batch is rejected", func(t *testing.T) {
+		conflict := domain.NewValidationError(domain.ErrUserExists)
+		conflict.Add(domain.UserBatchField(1, "email"), "email first@example.com is already used by users[0].email")
+
+		userStorage := new(mockUserStorage)
+		userStorage.On("CreateUsers", mock.Anything, mock.Anything).Return(conflict.Err())
+		mailer := new(mockMailer)
+
+		service := NewUserAppService(userStorage, mailer, "")
+		_, err := service.RegisterUsers(context.Background(), []*domain.CreateUserRequest{
+			newRequest("first@example.com"),
+			newRequest("first@example.com"),
+		})
+		assert.ErrorIs(t, err, domain.ErrUserExists)
+		mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+}
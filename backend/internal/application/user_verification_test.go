@@ -0,0 +1,67 @@
+package application
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"mts/internal/domain"
+	"mts/internal/repository/mailer"
+	"mts/internal/repository/storage"
+	"shared"
+)
+
+var verificationLinkRegex = regexp.MustCompile(`http://\S+/api/v1/users/verify\?token=\S+`)
+
+type UserVerificationSuite struct {
+	shared.Suite[any]
+	service domain.UserAppService
+}
+
+func (s *UserVerificationSuite) SetupSuite() {
+	s.PostgresEnabled = true
+	s.MailhogEnabled = true
+	s.Suite.SetupSuite()
+
+	s.service = NewUserAppService(
+		storage.NewUserStorage(s.PostgresConn, nil, storage.CacheOptions{}, nil),
+		mailer.NewSmtpMailer(s.Config.Smtp),
+		"http://localhost:8080/api/v1/users/verify",
+	)
+}
+
+func (s *UserVerificationSuite) TestRegisterAndVerify() {
+	user, err := s.service.RegisterUser(s.Ctx, &domain.CreateUserRequest{
+		FirstName: "John",
+		LastName:  "Doe",
+		Age:       25,
+		Email:     "john.doe@example.com",
+		Password:  "password123",
+	})
+	s.Require().NoError(err)
+	s.False(user.EmailVerified)
+
+	messages, err := s.MailhogMessages()
+	s.Require().NoError(err)
+	s.Require().Len(messages, 1)
+	s.Equal("john.doe@example.com", messages[0].To)
+
+	link, err := url.Parse(verificationLinkRegex.FindString(messages[0].Body))
+	s.Require().NoError(err)
+	token := link.Query().Get("token")
+	s.Require().NotEmpty(token)
+
+	verified, err := s.service.VerifyEmail(s.Ctx, token)
+	s.Require().NoError(err)
+	s.Equal(user.Id, verified.Id)
+	s.True(verified.EmailVerified)
+
+	_, err = s.service.VerifyEmail(s.Ctx, token)
+	s.ErrorIs(err, domain.ErrInvalidVerificationToken)
+}
+
+func TestUserVerificationSuite(t *testing.T) {
+	suite.Run(t, new(UserVerificationSuite))
+}
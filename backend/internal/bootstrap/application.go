@@ -2,6 +2,12 @@ package bootstrap
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
@@ -14,15 +20,23 @@ import (
 	"mts/internal/application"
 	"mts/internal/config"
 	"mts/internal/domain"
+	"mts/internal/repository/cache"
+	"mts/internal/repository/mailer"
 	"mts/internal/repository/storage"
+	"mts/internal/repository/webhook"
 	"mts/internal/transport/rest"
 	"shared"
 	sharedConfig "shared/config"
+	"shared/idgen"
 )
 
 const (
 	EnvPrefix      = "MTS"
 	ConfigFilename = "config.yaml"
+	// ConfigFileEnv names an environment-specific config file layered over ConfigFilename
+	ConfigFileEnv = "CONFIG_FILE"
+
+	defaultShutdownTimeout = 5 * time.Second
 )
 
 func NewApp() *Application {
@@ -36,21 +50,48 @@ type Application struct {
 	Config *sharedConfig.Config[config.Service]
 	Logger zerolog.Logger
 
+	// tracing
+	ShutdownTracing func(context.Context) error
+
 	// database connection
 	PostgresConnection *pgxpool.Pool
+	// PostgresReplica serves the storages' reads, nil when no replica is configured
+	PostgresReplica *pgxpool.Pool
+	// SlowQueries keeps the recent slow queries of both pools, nil when the log is disabled
+	SlowQueries *shared.SlowQueryLog
 
 	// repository
-	UserStorage    domain.UserStorage
-	ProductStorage domain.ProductStorage
-	OrderStorage   domain.OrderStorage
+	Cache               domain.Cache
+	UserStorage         domain.UserStorage
+	ProductStorage      domain.ProductStorage
+	OrderStorage        domain.OrderStorage
+	UnitOfWork          domain.UnitOfWork
+	RefreshTokenStorage domain.RefreshTokenStorage
+	Mailer              domain.Mailer
+	// RequestUnitOfWork runs whole requests in a transaction; it makes a single attempt, a retry would run the handler twice
+	RequestUnitOfWork domain.UnitOfWork
+	// ReadOnly rejects the storages' writes while admins have it on
+	ReadOnly *domain.ReadOnlyMode
+	// MailWorkers send mail in the background, nil when SMTP is not configured
+	MailWorkers *shared.WorkerPool
+
+	// application events
+	Events   domain.EventPublisher
+	Webhooks domain.EventSubscriber
 
 	// application service
 	UserAppService    domain.UserAppService
 	ProductAppService domain.ProductAppService
 	OrderAppService   domain.OrderAppService
+	AuthAppService    domain.AuthAppService
 
 	// transport
 	RestServer *fiber.App
+	// Readiness holds API traffic back until startup is done, nil serves it right away
+	Readiness *rest.Readiness
+
+	// Migrate brings the schema up to date before API traffic is served, defaults to the embedded migrations
+	Migrate func(ctx context.Context) error
 }
 
 func (s *Application) Initialize() error {
@@ -59,30 +100,107 @@ func (s *Application) Initialize() error {
 	var err error
 
 	if s.Config == nil {
-		s.Config, err = sharedConfig.Load[config.Service](EnvPrefix, ConfigFilename)
+		s.Config, err = sharedConfig.LoadFiles[config.Service](EnvPrefix, ConfigFilename, os.Getenv(ConfigFileEnv))
 		if err != nil {
 			return err
 		}
 	}
 
+	if err = s.Config.Validate(); err != nil {
+		return err
+	}
+
+	if len(s.Config.Service.Cors.AllowOrigins) == 0 && s.Config.FrontBaseUrl != "" {
+		s.Config.Service.Cors.AllowOrigins = []string{s.Config.FrontBaseUrl}
+	}
+
 	// logger
-	s.Logger = shared.Logger
+	s.Logger, err = shared.ConfigureLogger(s.Config.Logger)
+	if err != nil {
+		return err
+	}
 	s.Ctx = s.Logger.WithContext(s.Ctx)
 
-	s.PostgresConnection, err = shared.ConnectPostgres(s.Ctx, s.Config.Postgres)
+	// tracing
+	s.ShutdownTracing, err = shared.InitTracing(s.Ctx, s.Config.Tracing)
 	if err != nil {
 		return err
 	}
 
+	// domain settings: paging, order limits, registration rules and password hashing
+	domain.Configure(domain.Config{
+		PageSize: domain.PageSize{
+			Default: s.Config.Service.Pagination.DefaultSize,
+			Max:     s.Config.Service.Pagination.MaxSize,
+		},
+		OrderLimits: domain.OrderLimits{
+			MaxItems:        s.Config.Service.OrderLimits.MaxItems,
+			MaxQuantity:     s.Config.Service.OrderLimits.MaxQuantity,
+			MaxItemQuantity: s.Config.Service.OrderLimits.MaxItemQuantity,
+			MaxOpenOrders:   s.Config.Service.OrderLimits.MaxOpenOrders,
+		},
+		UserPolicy: domain.UserPolicy{
+			MinAge:           s.Config.Service.UserPolicy.MinAge,
+			MinPasswordLen:   s.Config.Service.UserPolicy.MinPasswordLength,
+			RequireMixedCase: s.Config.Service.UserPolicy.RequireMixedCase,
+		},
+		PasswordHasher: newPasswordHasher(s.Config.Service.Password),
+	})
+
+	if err = idgen.SetVersion(s.Config.Service.UuidVersion); err != nil {
+		return err
+	}
+
+	// the pools record their slow queries for the debug endpoint
+	s.SlowQueries = shared.NewSlowQueryLog(s.Config.Postgres.SlowQueryLogSize)
+
+	s.PostgresConnection, err = shared.ConnectPostgres(s.Ctx, s.Config.Postgres, s.SlowQueries)
+	if err != nil {
+		return err
+	}
+
+	if s.Config.Postgres.ReplicaEnabled() {
+		s.PostgresReplica, err = shared.ConnectPostgresReplica(s.Ctx, s.Config.Postgres, s.SlowQueries)
+		if err != nil {
+			s.PostgresConnection.Close()
+			return err
+		}
+	}
+
 	// repository
-	s.UserStorage = storage.NewUserStorage(s.PostgresConnection)
-	s.ProductStorage = storage.NewProductStorage(s.PostgresConnection)
-	s.OrderStorage = storage.NewOrderStorage(s.PostgresConnection)
+	s.Cache = cache.NewMemoryCache()
+	cacheOptions := storage.CacheOptions{TTL: s.Config.Service.Cache.TTL, IdsTTL: s.Config.Service.Cache.IdsTTL}
+	s.ReadOnly = domain.NewReadOnlyMode(s.Config.Service.ReadOnly)
+	s.UserStorage = storage.NewUserStorage(s.PostgresConnection, s.PostgresReplica, cacheOptions, s.ReadOnly)
+	s.ProductStorage = storage.NewProductStorage(s.PostgresConnection, s.PostgresReplica, cacheOptions, s.ReadOnly)
+	s.OrderStorage = storage.NewOrderStorage(s.PostgresConnection, s.PostgresReplica, cacheOptions, s.Config.Postgres.Retry, s.ReadOnly)
+	s.UnitOfWork = storage.NewUnitOfWork(s.PostgresConnection, s.Config.Postgres.Retry, s.ReadOnly, s.UserStorage, s.ProductStorage, s.OrderStorage)
+	s.RequestUnitOfWork = storage.NewUnitOfWork(s.PostgresConnection, sharedConfig.Retry{MaxAttempts: 1}, s.ReadOnly, s.UserStorage, s.ProductStorage, s.OrderStorage)
+	s.RefreshTokenStorage = storage.NewRefreshTokenStorage(s.PostgresConnection, s.ReadOnly)
+	s.Mailer = mailer.NewLogMailer()
+	if s.Config.Smtp.Enabled() {
+		s.MailWorkers = shared.NewWorkerPool(s.Config.Smtp.Workers)
+		s.Mailer = mailer.NewAsyncMailer(mailer.NewSmtpMailer(s.Config.Smtp), s.MailWorkers)
+	}
 
 	// application service
-	s.UserAppService = application.NewUserAppService(s.UserStorage)
-	s.ProductAppService = application.NewProductAppService(s.ProductStorage)
-	s.OrderAppService = application.NewOrderAppService(s.OrderStorage, s.ProductStorage, s.UserStorage)
+	s.UserAppService = application.NewUserAppService(s.UserStorage, s.Mailer, s.Config.Service.PublicBaseUrl()+"/api/v1/users/verify")
+	s.ProductAppService = application.NewProductAppService(s.ProductStorage, s.UnitOfWork)
+	s.Events = application.NewSyncEventPublisher()
+	if s.Config.Service.Webhook.Enabled() {
+		s.Webhooks = webhook.NewSender(s.Config.Service.Webhook)
+		s.Events.Subscribe(s.Webhooks.Handle)
+	}
+	s.OrderAppService = application.NewOrderAppService(s.OrderStorage, s.ProductStorage, s.UserStorage, s.UnitOfWork, s.Events)
+	secret, err := s.jwtSecret()
+	if err != nil {
+		return err
+	}
+	s.AuthAppService = application.NewAuthAppService(s.UserStorage, s.RefreshTokenStorage, s.Cache, application.TokenOptions{
+		Secret:          secret,
+		AccessLifetime:  s.Config.Service.TokenLifetime,
+		RefreshLifetime: s.Config.Service.RefreshTokenLifetime,
+	})
 
 	s.Logger.Info().Msg("application initialized")
 
@@ -93,34 +211,75 @@ func (s *Application) Start() error {
 	ctx, cancel := signal.NotifyContext(s.Ctx, syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	// rest server init
-	s.RestServer = rest.New(s.UserAppService, s.ProductAppService, s.OrderAppService)
+	// rest server init, the probes answer while migrations run but the API waits for them
+	s.Readiness = rest.NewReadiness(rest.ReadinessMigrating)
+	s.RestServer = rest.New(s.Config.Service, s.Cache, s.ReadOnly, s.Readiness, s.SlowQueries, s.RequestUnitOfWork, s.UserAppService, s.ProductAppService, s.OrderAppService, s.AuthAppService)
 
-	// apply migrations
-	if err := shared.ApplyMigrations(s.Config.Postgres); err != nil {
-		return err
+	if s.Migrate == nil {
+		s.Migrate = func(ctx context.Context) error {
+			return shared.ApplyMigrations(ctx, s.Config.Postgres)
+		}
 	}
 
-	// start the application
-	var eg errgroup.Group
+	if interval := s.Config.Postgres.StatsInterval; interval > 0 {
+		s.logPoolStats(ctx, interval)
+	}
+
+	return s.serve(ctx)
+}
+
+// serve runs the rest server until ctx is done, then drains in-flight requests
+// before releasing the resources they depend on
+func (s *Application) serve(ctx context.Context) error {
+	eg, ctx := errgroup.WithContext(ctx)
 
 	eg.Go(func() error {
+		listenConfig := fiber.ListenConfig{
+			DisableStartupMessage: true,
+		}
+
+		if socket := s.Config.Service.Socket; socket != "" {
+			ln, err := listenUnix(socket)
+			if err != nil {
+				return err
+			}
+
+			s.Logger.Info().
+				Str("socket", socket).
+				Msg("rest server started")
+
+			// closing the listener on shutdown removes the socket file
+			return s.RestServer.Listener(ln, listenConfig)
+		}
+
 		s.Logger.Info().
 			Str("host", s.Config.Service.Host).
 			Int("port", s.Config.Service.Port).
 			Msg("rest server started")
 
-		return s.RestServer.Listen(s.Config.Service.RestListenAddress(), fiber.ListenConfig{
-			GracefulContext:       s.Ctx,
-			DisableStartupMessage: true,
-		})
+		return s.RestServer.Listen(s.Config.Service.RestListenAddress(), listenConfig)
+	})
+
+	eg.Go(func() error {
+		return s.prepare(ctx)
 	})
 
 	eg.Go(func() error {
 		<-ctx.Done()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		timeout := s.Config.Service.ShutdownTimeout
+		if timeout <= 0 {
+			timeout = defaultShutdownTimeout
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
-		return s.RestServer.ShutdownWithContext(shutdownCtx)
+
+		// the server waits for in-flight requests, so the pool is closed only once nothing uses it
+		err := s.RestServer.ShutdownWithContext(shutdownCtx)
+		s.close(shutdownCtx)
+
+		return errors.Join(err, s.ShutdownTracing(shutdownCtx))
 	})
 
 	s.Logger.Info().Msg("application started")
@@ -135,3 +294,96 @@ func (s *Application) Start() error {
 
 	return err
 }
+
+// prepare applies migrations, warms the caches up and marks the application ready. A failed migration
+// stops the application, a shutdown during startup just leaves it unready.
+func (s *Application) prepare(ctx context.Context) error {
+	if s.Migrate != nil {
+		if err := s.Migrate(ctx); err != nil {
+			if ctx.Err() != nil {
+				s.Logger.Warn().Err(err).Msg("migrations interrupted by shutdown")
+				return nil
+			}
+			return fmt.Errorf("apply migrations: %w", err)
+		}
+	}
+
+	if s.Config.Service.Cache.Warmup {
+		s.Readiness.Set(rest.ReadinessWarming)
+		s.warmupCaches(ctx)
+	}
+
+	s.Readiness.Set(rest.ReadinessReady)
+	s.Logger.Info().Msg("application ready")
+
+	return nil
+}
+
+// listenUnix listens on the Unix socket at path, replacing a socket file left behind by a crashed instance
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil && info.Mode().Type() == fs.ModeSocket {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket %s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket: %w", err)
+		}
+	}
+
+	return net.Listen("unix", path)
+}
+
+// close stops the caches' background cleanup and closes the database connections. Pending webhooks
+// and mail are delivered before, as long as ctx, the rest of the shutdown timeout, allows.
+func (s *Application) close(ctx context.Context) {
+	if s.Webhooks != nil {
+		if err := s.Webhooks.Close(ctx); err != nil {
+			s.Logger.Warn().Err(err).Msg("pending webhooks dropped on shutdown")
+		}
+	}
+	if s.MailWorkers != nil {
+		if err := s.MailWorkers.Close(ctx); err != nil {
+			s.Logger.Warn().Err(err).Msg("pending mail dropped on shutdown")
+		}
+	}
+
+	s.UserStorage.Close()
+	s.ProductStorage.Close()
+	s.OrderStorage.Close()
+
+	if err := s.Cache.Close(); err != nil {
+		s.Logger.Error().Err(err).Msg("failed to close cache")
+	}
+
+	if s.PostgresReplica != nil {
+		s.PostgresReplica.Close()
+	}
+	s.PostgresConnection.Close()
+}
+
+// jwtSecret decodes the configured signing key. Without one a random key is generated,
+// so access tokens stop being accepted on restart and by other instances.
+func (s *Application) jwtSecret() ([]byte, error) {
+	if s.Config.Service.JwtSecret != "" {
+		return s.Config.Service.JwtSecretBytes()
+	}
+
+	s.Logger.Warn().Msg("service.jwt_secret is not set, access tokens are signed with a random key")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func newPasswordHasher(cfg config.Password) domain.PasswordHasher {
+	if cfg.Algorithm == domain.PasswordAlgorithmArgon2id {
+		return domain.NewArgon2idHasher(domain.Argon2idParams{
+			Time:    cfg.Argon2Time,
+			Memory:  cfg.Argon2Memory,
+			Threads: cfg.Argon2Threads,
+		})
+	}
+	return domain.NewBcryptHasher(cfg.BcryptCost)
+}
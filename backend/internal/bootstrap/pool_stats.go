@@ -0,0 +1,50 @@
+package bootstrap
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// logPoolStats logs the connection pool stats every interval until ctx is done, to tell pool exhaustion
+// apart from slow queries under load. The returned channel is closed once logging has stopped.
+func (s *Application) logPoolStats(ctx context.Context, interval time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+
+	pools := map[string]*pgxpool.Pool{"primary": s.PostgresConnection}
+	if s.PostgresReplica != nil {
+		pools["replica"] = s.PostgresReplica
+	}
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			for name, pool := range pools {
+				stat := pool.Stat()
+				s.Logger.Info().
+					Str("pool", name).
+					Int32("acquired", stat.AcquiredConns()).
+					Int32("idle", stat.IdleConns()).
+					Int32("total", stat.TotalConns()).
+					Int32("max", stat.MaxConns()).
+					// acquires that had to wait for a connection, growing steadily when the pool is too small
+					Int64("empty_acquires", stat.EmptyAcquireCount()).
+					Dur("acquire_duration", stat.AcquireDuration()).
+					Msg("postgres pool stats")
+			}
+		}
+	}()
+
+	return done
+}
@@ -0,0 +1,33 @@
+package bootstrap
+
+import (
+	"context"
+
+	"mts/internal/domain"
+)
+
+// productWarmupQueries are the first pages of the product listing clients open most, shaped like the
+// requests the rest handler builds so that they share its cache keys
+func productWarmupQueries() []*domain.GetProductsRequest {
+	available := true
+	return []*domain.GetProductsRequest{
+		{Sort: domain.ProductSortCreatedAt, Order: domain.SortOrderDesc},
+		{Available: &available, Sort: domain.ProductSortCreatedAt, Order: domain.SortOrderDesc},
+		{Sort: domain.ProductSortPrice, Order: domain.SortOrderAsc},
+	}
+}
+
+// warmupCaches fills the product cache with productWarmupQueries before the instance is marked ready,
+// so the first requests after a deploy don't all miss it. It is best effort: failures are logged and
+// the startup goes on with a cold cache.
+func (s *Application) warmupCaches(ctx context.Context) {
+	queries := productWarmupQueries()
+	for _, req := range queries {
+		if _, err := s.ProductStorage.Products(ctx, req); err != nil {
+			s.Logger.Warn().Err(err).Msg("failed to warm up product cache")
+			return
+		}
+	}
+
+	s.Logger.Info().Int("queries", len(queries)).Msg("product cache warmed up")
+}
@@ -0,0 +1,62 @@
+package bootstrap
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+
+	"mts/internal/application"
+	"mts/internal/config"
+	"mts/internal/domain"
+	"mts/internal/repository/cache"
+	"mts/internal/repository/storage"
+	"mts/internal/transport/rest"
+	"shared"
+	sharedConfig "shared/config"
+)
+
+type WarmupSuite struct {
+	shared.Suite[any]
+}
+
+func (s *WarmupSuite) SetupSuite() {
+	s.PostgresEnabled = true
+	s.Suite.SetupSuite()
+}
+
+func (s *WarmupSuite) TestWarmupCaches_FirstRequestHitsCache() {
+	productStorage := storage.NewProductStorage(s.PostgresConn, nil, storage.CacheOptions{}, nil)
+	defer productStorage.Close()
+	s.Require().NoError(productStorage.CreateProduct(s.Ctx, (&domain.Factory{}).Product()))
+
+	app := &Application{
+		Config:         &sharedConfig.Config[config.Service]{Service: &config.Service{}},
+		Logger:         zerolog.Nop(),
+		ProductStorage: productStorage,
+	}
+
+	app.warmupCaches(s.Ctx)
+	warmed := productStorage.CacheStats()
+	s.Equal(len(productWarmupQueries()), warmed.Size)
+
+	server := rest.New(app.Config.Service, cache.NewMemoryCache(), nil, nil, nil, nil, nil,
+		application.NewProductAppService(productStorage, nil), nil, nil)
+
+	targets := []string{"/api/v1/products", "/api/v1/products?available=true", "/api/v1/products?sort=price&order=asc"}
+	for _, target := range targets {
+		resp, err := server.Test(httptest.NewRequest(fiber.MethodGet, target, nil))
+		s.Require().NoError(err)
+		s.Equal(fiber.StatusOK, resp.StatusCode, target)
+	}
+
+	stats := productStorage.CacheStats()
+	s.Equal(warmed.Hits+uint64(len(targets)), stats.Hits)
+	s.Equal(warmed.Misses, stats.Misses)
+}
+
+func TestWarmupSuite(t *testing.T) {
+	suite.Run(t, new(WarmupSuite))
+}
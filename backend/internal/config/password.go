@@ -0,0 +1,34 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Password struct {
+	Algorithm  string `koanf:"algorithm"` // bcrypt or argon2id
+	BcryptCost int    `koanf:"bcrypt_cost"`
+
+	Argon2Time    uint32 `koanf:"argon2_time"`
+	Argon2Memory  uint32 `koanf:"argon2_memory"` // KiB
+	Argon2Threads uint8  `koanf:"argon2_threads"`
+}
+
+func (p *Password) Validate() error {
+	var errs []error
+
+	switch p.Algorithm {
+	case "", "bcrypt", "argon2id":
+	default:
+		errs = append(errs, fmt.Errorf("service: password.algorithm must be bcrypt or argon2id, got %q", p.Algorithm))
+	}
+
+	if p.BcryptCost != 0 && (p.BcryptCost < bcrypt.MinCost || p.BcryptCost > bcrypt.MaxCost) {
+		errs = append(errs, fmt.Errorf("service: password.bcrypt_cost must be between %d and %d, got %d",
+			bcrypt.MinCost, bcrypt.MaxCost, p.BcryptCost))
+	}
+
+	return errors.Join(errs...)
+}
@@ -2,22 +2,366 @@ package config
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
 	"time"
+
+	sharedConfig "shared/config"
+	"shared/idgen"
 )
 
 type Service struct {
-	JwtSecret     string        `koanf:"jwt_secret"`
+	// JwtSecret is the hex-encoded HS256 key signing access tokens; a random key is used when empty
+	JwtSecret string `koanf:"jwt_secret"`
+	// TokenLifetime is how long access tokens are accepted, defaults to 1h
 	TokenLifetime time.Duration `koanf:"token_lifetime"`
+	// RefreshTokenLifetime is how long a refresh token may be exchanged for new tokens, defaults to 30 days
+	RefreshTokenLifetime time.Duration `koanf:"refresh_token_lifetime"`
+
+	// AdminToken authorizes admin-only endpoints as a bearer token; empty disables them
+	AdminToken string `koanf:"admin_token"`
 
 	Host string `koanf:"host"`
 	Port int    `koanf:"port"`
+
+	// Socket is a Unix domain socket path to listen on instead of host:port, for reverse proxies on the same machine;
+	// port is not used then and public_url is required
+	Socket string `koanf:"socket"`
+
+	// PublicUrl is where clients reach the API, used for links in emails; defaults to http://host:port unless listening on a socket
+	PublicUrl string `koanf:"public_url"`
+
+	RequestTimeout time.Duration `koanf:"request_timeout"`
+
+	// BodyLimit caps request bodies in bytes, larger ones are rejected with 413; defaults to fiber's 4 MiB
+	BodyLimit int `koanf:"body_limit"`
+
+	// UuidVersion selects how entity IDs are generated: 4 (random, the default) or 7 (time-ordered,
+	// better insert locality in primary key indexes)
+	UuidVersion int `koanf:"uuid_version"`
+
+	// ShutdownTimeout bounds how long in-flight requests may drain on shutdown; defaults to 5s
+	ShutdownTimeout time.Duration `koanf:"shutdown_timeout"`
+
+	Password Password `koanf:"password"`
+
+	UserPolicy UserPolicy `koanf:"user_policy"`
+
+	Pagination Pagination `koanf:"pagination"`
+
+	OrderLimits OrderLimits `koanf:"order_limits"`
+
+	RateLimit RateLimit `koanf:"rate_limit"`
+
+	Concurrency Concurrency `koanf:"concurrency"`
+
+	Cors Cors `koanf:"cors"`
+
+	Compression Compression `koanf:"compression"`
+
+	Proxy Proxy `koanf:"proxy"`
+
+	Cache Cache `koanf:"cache"`
+
+	Webhook Webhook `koanf:"webhook"`
+
+	Maintenance Maintenance `koanf:"maintenance"`
+
+	// ReadOnly is the initial state of read-only mode, which admins can switch at runtime.
+	// While it is on, the storages reject writes with 503 and reads keep working.
+	ReadOnly bool `koanf:"read_only"`
+
+	// Debug serves the /api/v1/debug endpoints to admins, such as the slow queries kept per postgres.slow_query_log_size
+	Debug bool `koanf:"debug"`
+}
+
+// Webhook configures delivery of order events to an external endpoint; an empty url disables it
+type Webhook struct {
+	Url       string             `koanf:"url"`
+	Secret    string             `koanf:"secret"`     // HMAC-SHA256 key signing every payload
+	Timeout   time.Duration      `koanf:"timeout"`    // per attempt, defaults to 5s
+	QueueSize int                `koanf:"queue_size"` // events waiting for delivery, defaults to 100
+	Workers   int                `koanf:"workers"`    // deliveries running at once; defaults to 1, which keeps events in order
+	Retry     sharedConfig.Retry `koanf:"retry"`
+}
+
+func (w *Webhook) Enabled() bool {
+	return w.Url != ""
+}
+
+// UserPolicy configures the registration rules; zero values keep the defaults (18+, 8 characters)
+type UserPolicy struct {
+	MinAge            int  `koanf:"min_age"`
+	MinPasswordLength int  `koanf:"min_password_length"`
+	RequireMixedCase  bool `koanf:"require_mixed_case"`
+}
+
+// Pagination bounds list page sizes; zero values keep the defaults (10 per page, at most 100)
+type Pagination struct {
+	DefaultSize int `koanf:"default_size"`
+	MaxSize     int `koanf:"max_size"`
+}
+
+// OrderLimits bounds the size of a single order; zero values keep the defaults (100 items, 10000 units,
+// 10000 units per item, unlimited open orders per user)
+type OrderLimits struct {
+	MaxItems        int `koanf:"max_items"`
+	MaxQuantity     int `koanf:"max_quantity"`
+	MaxItemQuantity int `koanf:"max_item_quantity"`
+	MaxOpenOrders   int `koanf:"max_open_orders"`
+}
+
+// Cache configures the storages' in-process result caches
+type Cache struct {
+	TTL    time.Duration `koanf:"ttl"`     // defaults to 1h
+	IdsTTL time.Duration `koanf:"ids_ttl"` // lookups by ids are not cached when zero
+	// Warmup fills the product cache with the first listing pages on startup, before the instance reports ready
+	Warmup bool `koanf:"warmup"`
+	// HttpMaxAge lets clients reuse user and product GET responses without revalidating their ETag;
+	// zero makes them revalidate every time
+	HttpMaxAge time.Duration `koanf:"http_max_age"`
+}
+
+// RateLimit limits requests per client to the registration endpoint; zero requests disables it
+type RateLimit struct {
+	Requests int           `koanf:"requests"`
+	Window   time.Duration `koanf:"window"`
+}
+
+// Concurrency caps the requests served at once, so a traffic spike cannot exhaust the database pool.
+// Requests over the limit are answered with 503; zero max_requests disables it.
+type Concurrency struct {
+	MaxRequests int           `koanf:"max_requests"`
+	RetryAfter  time.Duration `koanf:"retry_after"` // sent to rejected clients, defaults to 1s
+}
+
+// Maintenance is the initial state of maintenance mode, which admins can switch at runtime.
+// While it is on, mutating requests are answered with 503 and reads keep working.
+type Maintenance struct {
+	Enabled    bool          `koanf:"enabled"`
+	RetryAfter time.Duration `koanf:"retry_after"` // sent to rejected clients, defaults to 1m
+}
+
+// Cors configures cross-origin access for the browser front-end; no origins disables CORS
+type Cors struct {
+	AllowOrigins     []string      `koanf:"allow_origins"` // defaults to front_base_url
+	AllowCredentials bool          `koanf:"allow_credentials"`
+	MaxAge           time.Duration `koanf:"max_age"`
+}
+
+// Compression compresses responses for clients accepting brotli, gzip or deflate
+type Compression struct {
+	Enabled   bool   `koanf:"enabled"`
+	Level     string `koanf:"level"`      // best_speed, default or best_compression; defaults to default
+	MinLength int    `koanf:"min_length"` // bytes, shorter bodies are sent as is; defaults to 1 KiB
+}
+
+// CompressionLevels lists the accepted compression levels
+var CompressionLevels = []string{"best_speed", "default", "best_compression"}
+
+// Proxy lists the reverse proxies in front of the service. Only requests coming from a trusted proxy may
+// report the client IP in the header; with no trusted proxies the connection's address is the client IP.
+// Every proxy appends the address it got the request from, so the header is read from the right and the
+// first address that is not a trusted proxy is the client: entries further left may be forged by it.
+type Proxy struct {
+	Trusted []string `koanf:"trusted"` // IPs or CIDRs, e.g. 10.0.0.0/8
+	Header  string   `koanf:"header"`  // defaults to X-Forwarded-For
+}
+
+// TrustedPrefixes returns the trusted proxies as prefixes, single addresses as full-length ones;
+// malformed entries are skipped, Validate reports them
+func (p *Proxy) TrustedPrefixes() []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(p.Trusted))
+	for _, proxy := range p.Trusted {
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(proxy); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return prefixes
+}
+
+func (p *Proxy) ClientIpHeader() string {
+	if p.Header != "" {
+		return p.Header
+	}
+	return "X-Forwarded-For"
 }
 
 func (s *Service) RestListenAddress() string {
 	return fmt.Sprintf("%s:%d", s.Host, s.Port)
 }
 
+func (s *Service) PublicBaseUrl() string {
+	if s.PublicUrl != "" {
+		return strings.TrimSuffix(s.PublicUrl, "/")
+	}
+	return "http://" + s.RestListenAddress()
+}
+
 func (s *Service) JwtSecretBytes() ([]byte, error) {
 	return hex.DecodeString(s.JwtSecret)
 }
+
+func (s *Service) Validate() error {
+	var errs []error
+
+	if s.Socket != "" {
+		// behind the socket only the proxy knows the address clients use
+		if s.PublicUrl == "" {
+			errs = append(errs, errors.New("service: public_url is required when listening on a socket"))
+		}
+	} else if s.Port <= 0 || s.Port > 65535 {
+		errs = append(errs, fmt.Errorf("service: port must be between 1 and 65535, got %d", s.Port))
+	}
+
+	if _, err := s.JwtSecretBytes(); err != nil {
+		errs = append(errs, errors.New("service: jwt_secret must be hex-encoded"))
+	}
+
+	if s.TokenLifetime < 0 {
+		errs = append(errs, errors.New("service: token_lifetime cannot be negative"))
+	}
+
+	if s.RefreshTokenLifetime < 0 {
+		errs = append(errs, errors.New("service: refresh_token_lifetime cannot be negative"))
+	}
+
+	if s.RequestTimeout < 0 {
+		errs = append(errs, errors.New("service: request_timeout cannot be negative"))
+	}
+
+	if s.BodyLimit < 0 {
+		errs = append(errs, errors.New("service: body_limit cannot be negative"))
+	}
+
+	if s.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("service: shutdown_timeout cannot be negative"))
+	}
+
+	if s.UuidVersion != 0 && s.UuidVersion != idgen.V4 && s.UuidVersion != idgen.V7 {
+		errs = append(errs, fmt.Errorf("service: uuid_version must be %d or %d, got %d", idgen.V4, idgen.V7, s.UuidVersion))
+	}
+
+	if err := s.Password.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if s.UserPolicy.MinAge < 0 {
+		errs = append(errs, errors.New("service: user_policy.min_age cannot be negative"))
+	}
+
+	if s.UserPolicy.MinPasswordLength < 0 {
+		errs = append(errs, errors.New("service: user_policy.min_password_length cannot be negative"))
+	}
+
+	if s.Pagination.DefaultSize < 0 {
+		errs = append(errs, errors.New("service: pagination.default_size cannot be negative"))
+	}
+
+	if s.Pagination.MaxSize < 0 {
+		errs = append(errs, errors.New("service: pagination.max_size cannot be negative"))
+	}
+
+	if s.Pagination.MaxSize > 0 && s.Pagination.DefaultSize > s.Pagination.MaxSize {
+		errs = append(errs, fmt.Errorf("service: pagination.default_size cannot exceed pagination.max_size (%d)", s.Pagination.MaxSize))
+	}
+
+	if s.OrderLimits.MaxItems < 0 {
+		errs = append(errs, errors.New("service: order_limits.max_items cannot be negative"))
+	}
+
+	if s.OrderLimits.MaxQuantity < 0 {
+		errs = append(errs, errors.New("service: order_limits.max_quantity cannot be negative"))
+	}
+
+	if s.OrderLimits.MaxItemQuantity < 0 {
+		errs = append(errs, errors.New("service: order_limits.max_item_quantity cannot be negative"))
+	}
+
+	if s.OrderLimits.MaxOpenOrders < 0 {
+		errs = append(errs, errors.New("service: order_limits.max_open_orders cannot be negative"))
+	}
+
+	if s.Compression.Level != "" && !slices.Contains(CompressionLevels, s.Compression.Level) {
+		errs = append(errs, fmt.Errorf("service: compression.level must be one of %s, got %q",
+			strings.Join(CompressionLevels, ", "), s.Compression.Level))
+	}
+
+	if s.Compression.MinLength < 0 {
+		errs = append(errs, errors.New("service: compression.min_length cannot be negative"))
+	}
+
+	if s.Concurrency.MaxRequests < 0 {
+		errs = append(errs, errors.New("service: concurrency.max_requests cannot be negative"))
+	}
+
+	if s.RateLimit.Requests < 0 {
+		errs = append(errs, errors.New("service: rate_limit.requests cannot be negative"))
+	}
+
+	if s.Maintenance.RetryAfter < 0 {
+		errs = append(errs, errors.New("service: maintenance.retry_after cannot be negative"))
+	}
+
+	if s.RateLimit.Requests > 0 && s.RateLimit.Window <= 0 {
+		errs = append(errs, errors.New("service: rate_limit.window must be positive when rate_limit.requests is set"))
+	}
+
+	if s.Cors.AllowCredentials && slices.Contains(s.Cors.AllowOrigins, "*") {
+		errs = append(errs, errors.New("service: cors.allow_origins cannot contain * when cors.allow_credentials is set"))
+	}
+
+	if s.Cache.TTL < 0 {
+		errs = append(errs, errors.New("service: cache.ttl cannot be negative"))
+	}
+
+	if s.Cache.IdsTTL < 0 {
+		errs = append(errs, errors.New("service: cache.ids_ttl cannot be negative"))
+	}
+
+	if s.Cache.HttpMaxAge < 0 {
+		errs = append(errs, errors.New("service: cache.http_max_age cannot be negative"))
+	}
+
+	if s.Webhook.Enabled() && s.Webhook.Secret == "" {
+		errs = append(errs, errors.New("service: webhook.secret is required when webhook.url is set"))
+	}
+
+	if s.Webhook.Timeout < 0 {
+		errs = append(errs, errors.New("service: webhook.timeout cannot be negative"))
+	}
+
+	if s.Webhook.QueueSize < 0 {
+		errs = append(errs, errors.New("service: webhook.queue_size cannot be negative"))
+	}
+
+	if s.Webhook.Workers < 0 {
+		errs = append(errs, errors.New("service: webhook.workers cannot be negative"))
+	}
+
+	if err := s.Webhook.Retry.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("service: webhook: %w", err))
+	}
+
+	if s.Cors.MaxAge < 0 {
+		errs = append(errs, errors.New("service: cors.max_age cannot be negative"))
+	}
+
+	for _, proxy := range s.Proxy.Trusted {
+		if _, err := netip.ParsePrefix(proxy); err == nil {
+			continue
+		}
+		if _, err := netip.ParseAddr(proxy); err != nil {
+			errs = append(errs, fmt.Errorf("service: proxy.trusted must contain IPs or CIDRs, got %q", proxy))
+		}
+	}
+
+	return errors.Join(errs...)
+}
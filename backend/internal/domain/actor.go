@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+type actorKey struct{}
+
+// ContextWithActor records the authenticated user performing the request
+func ContextWithActor(ctx context.Context, userId uuid.UUID) context.Context {
+	return context.WithValue(ctx, actorKey{}, userId)
+}
+
+// ActorFromContext returns the authenticated user performing the request, if any
+func ActorFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userId, ok := ctx.Value(actorKey{}).(uuid.UUID)
+	return userId, ok && userId != uuid.Nil
+}
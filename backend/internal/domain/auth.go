@@ -0,0 +1,113 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"shared/idgen"
+)
+
+// AuthTokens is what a login or refresh hands out: a short-lived access token and the single-use
+// refresh token that gets the next one
+type AuthTokens struct {
+	AccessToken      string
+	ExpiresAt        time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// AccessClaims is what a verified access token asserts about its bearer
+type AccessClaims struct {
+	// TokenId (the jti claim) identifies the token in the revocation list
+	TokenId   string
+	UserId    uuid.UUID
+	Roles     []string // as of the login or refresh that issued the token
+	ExpiresAt time.Time
+}
+
+func (c *AccessClaims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// RefreshToken is a stored refresh token. Only the hash of the token handed to the client is kept,
+// a leaked table doesn't let anyone refresh.
+type RefreshToken struct {
+	Id        uuid.UUID
+	UserId    uuid.UUID
+	TokenHash []byte
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	RotatedAt *time.Time // exchanged for the next token, presenting it again is a reuse
+	RevokedAt *time.Time
+}
+
+// NewRefreshToken issues a refresh token of the user valid for lifetime and returns it with the token for the client
+func NewRefreshToken(userId uuid.UUID, lifetime time.Duration) (*RefreshToken, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	token := hex.EncodeToString(secret)
+
+	now := Now()
+	return &RefreshToken{
+		Id:        idgen.New(),
+		UserId:    userId,
+		TokenHash: HashRefreshToken(token),
+		ExpiresAt: now.Add(lifetime),
+		CreatedAt: now,
+	}, token, nil
+}
+
+// HashRefreshToken is how refresh tokens are stored and looked up
+func HashRefreshToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+func (t *RefreshToken) IsRotated() bool {
+	return t.RotatedAt != nil
+}
+
+// IsActive reports whether the token can still be exchanged
+func (t *RefreshToken) IsActive() bool {
+	return t.RotatedAt == nil && t.RevokedAt == nil && Now().Before(t.ExpiresAt)
+}
+
+type LoginRequest struct {
+	Email    string
+	Password string
+}
+
+type RefreshTokenStorage interface {
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	// RefreshToken finds a token by its hash whatever its state, failing with ErrInvalidRefreshToken
+	RefreshToken(ctx context.Context, hash []byte) (*RefreshToken, error)
+	// RotateRefreshToken marks the token rotated and stores next in one transaction. It fails with
+	// ErrRefreshTokenReused when the token was rotated or revoked meanwhile, e.g. by a concurrent refresh.
+	RotateRefreshToken(ctx context.Context, id uuid.UUID, next *RefreshToken) error
+	// RevokeRefreshToken revokes the token unless it is revoked already
+	RevokeRefreshToken(ctx context.Context, id uuid.UUID) error
+	// RevokeUserRefreshTokens revokes every refresh token of the user that is not revoked yet
+	RevokeUserRefreshTokens(ctx context.Context, userId uuid.UUID) error
+}
+
+type AuthAppService interface {
+	// Login checks the credentials and issues tokens, failing with ErrInvalidCredentials
+	Login(ctx context.Context, req *LoginRequest) (*AuthTokens, error)
+	// Refresh exchanges a refresh token for new tokens, rotating it. It fails with ErrInvalidRefreshToken,
+	// or ErrRefreshTokenReused for a rotated token, in which case every token of its user is revoked.
+	Refresh(ctx context.Context, refreshToken string) (*AuthTokens, error)
+	// Authenticate verifies an access token, failing with ErrInvalidToken, also for revoked tokens, or
+	// ErrAuthUnavailable when the revocation list can't be read
+	Authenticate(ctx context.Context, token string) (*AccessClaims, error)
+	// Logout ends the session: the refresh token of the session is revoked and the access token is until it
+	// expires. It fails with ErrInvalidRefreshToken for a refresh token unknown or of another user.
+	Logout(ctx context.Context, claims *AccessClaims, refreshToken string) error
+}
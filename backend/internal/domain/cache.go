@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+)
+
+type CacheKey = [sha256.Size]byte
+
+// Entity discriminators start every request's cache key, so requests of different entities never share
+// a key even when their encoded filters match byte for byte and the caches share a keyspace (Redis)
+const (
+	userCacheKeyPrefix    = "user"
+	productCacheKeyPrefix = "product"
+	orderCacheKeyPrefix   = "order"
+)
+
+// newCacheKeyBuffer starts encoding a cache key with the entity discriminator
+func newCacheKeyBuffer(entity string) []byte {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, entity...)
+	// the separator keeps one prefix from being the start of another
+	return append(buf, 0)
+}
+
+// appendCacheKeyString appends s with its length, so neighbouring strings cannot run into each other
+func appendCacheKeyString(buf []byte, s string) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+	return append(buf, s...)
+}
+
+// CacheStats reports the effectiveness of a storage result cache
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Size   int
+}
+
+// Cache is a key-value store that can be shared between service instances
+// (in-memory for a single instance, Redis for several)
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// Incr increments the counter at key, starting a ttl window when the key is new,
+	// and returns the new value with the time left until the window expires
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, time.Duration, error)
+	Close() error
+}
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCacheKeyBuffer_SeparatesEntities(t *testing.T) {
+	// the same encoded filters would hash identically for every entity without the discriminator
+	encoded := binary.BigEndian.AppendUint32(nil, 10)
+
+	keys := make(map[CacheKey]string)
+	for _, entity := range []string{userCacheKeyPrefix, productCacheKeyPrefix, orderCacheKeyPrefix} {
+		key := sha256.Sum256(append(newCacheKeyBuffer(entity), encoded...))
+		assert.NotContains(t, keys, key, "%s collides with %s", entity, keys[key])
+		keys[key] = entity
+	}
+}
+
+func TestCacheKey_DistinctAcrossEntities(t *testing.T) {
+	pages := []struct {
+		limit, offset int
+	}{
+		{0, 0},
+		{10, 0},
+		{10, 20},
+	}
+
+	keys := make(map[CacheKey]string)
+	for _, page := range pages {
+		requests := map[string]CacheKey{
+			"users":    (&GetUsersRequest{Limit: page.limit, Offset: page.offset}).CacheKey(),
+			"products": (&GetProductsRequest{Limit: page.limit, Offset: page.offset}).CacheKey(),
+			"orders":   (&GetOrdersRequest{Limit: page.limit, Offset: page.offset}).CacheKey(),
+		}
+		for name, key := range requests {
+			assert.NotContains(t, keys, key, "%s %+v collides with %s", name, page, keys[key])
+			keys[key] = name
+		}
+	}
+}
+
+// TestGetRequests_CacheKeyCoversAllFields sets each field of the list requests in turn, so a filter or ordering
+// added later without being encoded fails here instead of serving another request's cached results
+func TestGetRequests_CacheKeyCoversAllFields(t *testing.T) {
+	requests := []interface{ CacheKey() CacheKey }{
+		&GetUsersRequest{},
+		&GetProductsRequest{},
+		&GetOrdersRequest{},
+	}
+
+	for _, request := range requests {
+		typ := reflect.TypeOf(request).Elem()
+		empty := request.CacheKey()
+
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			t.Run(typ.Name()+"."+field.Name, func(t *testing.T) {
+				changed := reflect.New(typ)
+				changed.Elem().Field(i).Set(cacheKeySample(t, field.Type))
+
+				assert.NotEqual(t, empty, changed.Interface().(interface{ CacheKey() CacheKey }).CacheKey())
+			})
+		}
+	}
+}
+
+// cacheKeySample returns a non-zero value of typ
+func cacheKeySample(t *testing.T, typ reflect.Type) reflect.Value {
+	t.Helper()
+
+	switch {
+	case typ == reflect.TypeOf(uuid.UUID{}):
+		return reflect.ValueOf(uuid.New())
+	case typ == reflect.TypeOf(time.Time{}):
+		return reflect.ValueOf(time.Now())
+	}
+
+	value := reflect.New(typ).Elem()
+	switch typ.Kind() {
+	case reflect.Bool:
+		value.SetBool(true)
+	case reflect.Int:
+		value.SetInt(1)
+	case reflect.String:
+		value.SetString("x")
+	case reflect.Slice:
+		value = reflect.Append(value, cacheKeySample(t, typ.Elem()))
+	case reflect.Pointer:
+		value = reflect.New(typ.Elem())
+		if typ.Elem().Kind() != reflect.Struct {
+			value.Elem().Set(cacheKeySample(t, typ.Elem()))
+		}
+	default:
+		t.Fatalf("no sample value for %s", typ)
+	}
+
+	return value
+}
+
+func TestGetRequests_CacheKeyStringsDoNotRunTogether(t *testing.T) {
+	assert.NotEqual(t,
+		(&GetProductsRequest{Tags: []string{"ab", "c"}}).CacheKey(),
+		(&GetProductsRequest{Tags: []string{"a", "bc"}}).CacheKey(),
+	)
+	assert.NotEqual(t,
+		(&GetOrdersRequest{Statuses: []OrderStatus{"pending", "confirmed"}}).CacheKey(),
+		(&GetOrdersRequest{Statuses: []OrderStatus{"pendingconfirmed"}}).CacheKey(),
+	)
+	assert.NotEqual(t,
+		(&GetUsersRequest{Sort: "created_at", Order: SortOrderAsc}).CacheKey(),
+		(&GetUsersRequest{Sort: "created_atasc"}).CacheKey(),
+	)
+}
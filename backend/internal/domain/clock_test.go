@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setClockForTest(t *testing.T, c Clock) {
+	t.Helper()
+	configureForTest(t, func(config *Config) { config.Clock = c })
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	fake := NewFakeClock(start)
+	setClockForTest(t, fake)
+
+	assert.Equal(t, start, Now())
+
+	fake.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Minute), Now())
+
+	fake.Set(start)
+	assert.Equal(t, start, Now())
+
+	// nil brings the wall clock back
+	setClockForTest(t, nil)
+	assert.WithinDuration(t, time.Now(), Now(), time.Second)
+}
+
+func TestValidate_UsesClock(t *testing.T) {
+	start := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	fake := NewFakeClock(start)
+	setClockForTest(t, fake)
+
+	factory := &Factory{}
+
+	user := factory.User()
+	user.CreatedAt = time.Time{}
+	require.NoError(t, user.Validate())
+	assert.Equal(t, start, user.CreatedAt)
+
+	product := factory.Product()
+	product.CreatedAt, product.UpdatedAt = time.Time{}, time.Time{}
+	require.NoError(t, product.Validate())
+	assert.Equal(t, start, product.CreatedAt)
+	assert.Equal(t, start, product.UpdatedAt)
+
+	order := factory.Order(uuid.New(), product.Id)
+	order.CreatedAt, order.UpdatedAt = time.Time{}, time.Time{}
+	order.Items[0].CreatedAt = time.Time{}
+	require.NoError(t, order.Validate())
+	assert.Equal(t, start, order.CreatedAt)
+	assert.Equal(t, start, order.UpdatedAt)
+	assert.Equal(t, start, order.Items[0].CreatedAt)
+
+	// later changes move UpdatedAt only
+	fake.Advance(time.Hour)
+
+	require.NoError(t, product.ReserveQuantity(1))
+	assert.Equal(t, start, product.CreatedAt)
+	assert.Equal(t, start.Add(time.Hour), product.UpdatedAt)
+
+	require.NoError(t, order.Confirm())
+	assert.Equal(t, start, order.CreatedAt)
+	assert.Equal(t, start.Add(time.Hour), order.UpdatedAt)
+
+	assert.Equal(t, start.Add(time.Hour), NewOrderEvent(OrderConfirmed, order).OccurredAt)
+}
@@ -0,0 +1,59 @@
+package domain
+
+import "sync/atomic"
+
+// Config gathers the deployment-specific settings the domain validates against.
+// The service applies it with Configure before serving; until then the defaults are in effect.
+type Config struct {
+	PageSize    PageSize
+	OrderLimits OrderLimits
+	UserPolicy  UserPolicy
+
+	// PasswordHasher hashes new passwords, hashes of the other algorithms keep verifying
+	PasswordHasher PasswordHasher
+	// Clock is the source of Now, the system clock when nil
+	Clock Clock
+}
+
+func DefaultConfig() Config {
+	return Config{
+		PageSize:    DefaultPageSize(),
+		OrderLimits: DefaultOrderLimits(),
+		UserPolicy:  DefaultUserPolicy(),
+
+		PasswordHasher: defaultPasswordHasher(),
+		Clock:          SystemClock{},
+	}
+}
+
+// config is swapped as a whole, so concurrent requests never see a half-applied configuration
+var config atomic.Pointer[Config]
+
+func init() {
+	defaults := DefaultConfig()
+	config.Store(&defaults)
+}
+
+// Configure applies c, filling the zero settings with their defaults.
+// Bootstrap calls it before the first request; tests call it to try other settings and restore the previous ones.
+func Configure(c Config) {
+	c.PageSize = c.PageSize.withDefaults()
+	c.OrderLimits = c.OrderLimits.withDefaults()
+	c.UserPolicy = c.UserPolicy.withDefaults()
+	if c.PasswordHasher == nil {
+		c.PasswordHasher = defaultPasswordHasher()
+	}
+	if c.Clock == nil {
+		c.Clock = SystemClock{}
+	}
+	config.Store(&c)
+}
+
+// CurrentConfig returns the configuration in effect
+func CurrentConfig() Config {
+	return *config.Load()
+}
+
+func currentConfig() *Config {
+	return config.Load()
+}
@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"testing"
+)
+
+// configureForTest applies the current configuration with change made to it and restores the previous one after t
+func configureForTest(t *testing.T, change func(c *Config)) {
+	t.Helper()
+	previous := CurrentConfig()
+	c := previous
+	change(&c)
+	Configure(c)
+	t.Cleanup(func() { Configure(previous) })
+}
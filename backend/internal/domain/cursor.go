@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const cursorSize = 8 + 16
+
+// Cursor points at the last row of a keyset page ordered by (created_at, id) descending
+type Cursor struct {
+	CreatedAt time.Time
+	Id        uuid.UUID
+}
+
+func NewCursor(createdAt time.Time, id uuid.UUID) *Cursor {
+	return &Cursor{CreatedAt: createdAt, Id: id}
+}
+
+// ParseCursor decodes a cursor produced by Cursor.Encode
+func ParseCursor(s string) (*Cursor, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(buf) != cursorSize {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+	}
+
+	id, err := uuid.FromBytes(buf[8:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidCursor)
+	}
+
+	return &Cursor{
+		CreatedAt: time.UnixMicro(int64(binary.BigEndian.Uint64(buf[:8]))).UTC(),
+		Id:        id,
+	}, nil
+}
+
+// Encode returns an opaque url-safe token; postgres keeps microseconds, so that is the precision stored
+func (c *Cursor) Encode() string {
+	buf := make([]byte, 0, cursorSize)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(c.CreatedAt.UnixMicro()))
+	buf = append(buf, c.Id[:]...)
+
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func (c *Cursor) appendCacheKey(buf []byte) []byte {
+	if c == nil {
+		return append(buf, 0)
+	}
+
+	buf = append(buf, 1)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(c.CreatedAt.UnixMicro()))
+	return append(buf, c.Id[:]...)
+}
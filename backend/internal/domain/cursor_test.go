@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_EncodeParse(t *testing.T) {
+	cursor := NewCursor(time.Date(2025, 3, 14, 15, 9, 26, 535897000, time.UTC), uuid.New())
+
+	parsed, err := ParseCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(parsed.CreatedAt))
+	assert.Equal(t, cursor.Id, parsed.Id)
+}
+
+func TestCursor_TruncatesToMicroseconds(t *testing.T) {
+	createdAt := time.Date(2025, 3, 14, 15, 9, 26, 535897932, time.UTC)
+
+	parsed, err := ParseCursor(NewCursor(createdAt, uuid.New()).Encode())
+	require.NoError(t, err)
+	assert.True(t, createdAt.Truncate(time.Microsecond).Equal(parsed.CreatedAt))
+}
+
+func TestParseCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"not base64!", "AAAA", NewCursor(time.Now(), uuid.New()).Encode() + "AA"} {
+		_, err := ParseCursor(s)
+		assert.ErrorIs(t, err, ErrInvalidCursor, s)
+	}
+}
+
+func TestGetRequests_CacheKeyIncludesCursor(t *testing.T) {
+	cursor := NewCursor(time.Now(), uuid.New())
+
+	assert.NotEqual(t,
+		(&GetProductsRequest{Limit: 10}).CacheKey(),
+		(&GetProductsRequest{Limit: 10, After: cursor}).CacheKey(),
+	)
+	assert.NotEqual(t,
+		(&GetOrdersRequest{Limit: 10}).CacheKey(),
+		(&GetOrdersRequest{Limit: 10, After: cursor}).CacheKey(),
+	)
+	assert.Equal(t,
+		(&GetOrdersRequest{Limit: 10, After: cursor}).CacheKey(),
+		(&GetOrdersRequest{Limit: 10, After: NewCursor(cursor.CreatedAt, cursor.Id)}).CacheKey(),
+	)
+}
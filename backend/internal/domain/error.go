@@ -1,17 +1,125 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
 
 var (
-	ErrUserValidation = errors.New("user validation error")
-	ErrUserNotFound   = errors.New("user not found")
+	ErrUserValidation = newDomainError("USER_VALIDATION_FAILED", "user validation error")
+	ErrUserNotFound   = newDomainError("USER_NOT_FOUND", "user not found")
+	ErrUserExists     = newDomainError("USER_ALREADY_EXISTS", "user already exists")
+
+	ErrInvalidVerificationToken = newDomainError("INVALID_VERIFICATION_TOKEN", "invalid or expired verification token")
+
+	ErrInvalidCredentials = newDomainError("INVALID_CREDENTIALS", "invalid email or password")
+	ErrInvalidToken       = newDomainError("INVALID_TOKEN", "invalid or expired access token")
+	ErrUnauthenticated    = newDomainError("UNAUTHENTICATED", "authentication required")
+	ErrForbidden          = newDomainError("FORBIDDEN", "not allowed for the user's roles")
+
+	ErrInvalidRefreshToken = newDomainError("INVALID_REFRESH_TOKEN", "invalid or expired refresh token")
+	ErrRefreshTokenReused  = newDomainError("REFRESH_TOKEN_REUSED", "refresh token was already used, all sessions are revoked")
 
-	ErrProductValidation = errors.New("product validation error")
-	ErrProductNotFound   = errors.New("product not found")
+	ErrProductValidation = newDomainError("PRODUCT_VALIDATION_FAILED", "product validation error")
+	ErrProductNotFound   = newDomainError("PRODUCT_NOT_FOUND", "product not found")
+	ErrProductExists     = newDomainError("PRODUCT_ALREADY_EXISTS", "product already exists")
+	ErrVersionConflict   = newDomainError("VERSION_CONFLICT", "the resource was changed by someone else, reload it and retry")
 
-	ErrOrderValidation = errors.New("order validation error")
-	ErrOrderNotFound   = errors.New("order not found")
+	ErrOrderValidation    = newDomainError("ORDER_VALIDATION_FAILED", "order validation error")
+	ErrOrderNotFound      = newDomainError("ORDER_NOT_FOUND", "order not found")
+	ErrOrderExists        = newDomainError("ORDER_ALREADY_EXISTS", "order already exists")
+	ErrOrderItemNotFound  = newDomainError("ORDER_ITEM_NOT_FOUND", "order item not found")
+	ErrOrderLimitExceeded = newDomainError("ORDER_LIMIT_EXCEEDED", "open order limit exceeded")
 
-	ErrInsufficientStock = errors.New("insufficient product stock")
-	ErrInvalidQuantity   = errors.New("invalid quantity")
+	ErrInsufficientStock = newDomainError("INSUFFICIENT_STOCK", "insufficient product stock")
+	ErrInvalidQuantity   = newDomainError("INVALID_QUANTITY", "invalid quantity")
+
+	ErrInvalidCursor = newDomainError("INVALID_CURSOR", "invalid cursor")
+	ErrInvalidSort   = newDomainError("INVALID_SORT", "invalid sort")
+
+	ErrInvalidId         = newDomainError("INVALID_ID", "invalid id")
+	ErrInvalidJSON       = newDomainError("INVALID_JSON", "request body is not valid JSON or has fields of the wrong type")
+	ErrRequestValidation = newDomainError("REQUEST_VALIDATION_FAILED", "request validation error")
+	ErrRequestCanceled   = newDomainError("REQUEST_CANCELED", "request canceled")
+	ErrRequestTimeout    = newDomainError("REQUEST_TIMEOUT", "request timed out")
+	ErrMaintenance       = newDomainError("MAINTENANCE", "service is under maintenance, writes are temporarily disabled")
+	ErrReadOnly          = newDomainError("READ_ONLY", "service is read-only, writes are temporarily unavailable")
+	ErrOverloaded        = newDomainError("OVERLOADED", "service is busy, retry later")
+	ErrNotReady          = newDomainError("NOT_READY", "service is starting, retry later")
+	ErrAuthUnavailable   = newDomainError("AUTH_UNAVAILABLE", "access tokens can't be checked right now, retry later")
+	ErrInternal          = newDomainError("INTERNAL", "internal server error")
 )
+
+// DomainError is a sentinel error with a stable machine-readable code, so clients and logs can tell
+// errors apart without matching messages. Sentinels are compared by identity, errors.Is keeps working.
+type DomainError struct {
+	code    string
+	message string
+}
+
+func newDomainError(code, message string) *DomainError {
+	return &DomainError{code: code, message: message}
+}
+
+func (e *DomainError) Error() string {
+	return e.message
+}
+
+func (e *DomainError) Code() string {
+	return e.code
+}
+
+// ErrorCode returns the code of the first DomainError in err's chain, empty when there is none
+func ErrorCode(err error) string {
+	var domainErr *DomainError
+	if errors.As(err, &domainErr) {
+		return domainErr.Code()
+	}
+	return ""
+}
+
+// StockShortage describes a product that can't cover the requested quantity
+type StockShortage struct {
+	ProductId uuid.UUID
+	Requested int
+	Available int
+}
+
+// InsufficientStockError reports every short product of an order at once.
+// It matches ErrInsufficientStock with errors.Is.
+type InsufficientStockError struct {
+	Shortages []StockShortage
+}
+
+func (e *InsufficientStockError) Add(productId uuid.UUID, requested, available int) {
+	e.Shortages = append(e.Shortages, StockShortage{
+		ProductId: productId,
+		Requested: requested,
+		Available: available,
+	})
+}
+
+// Err returns the error if any product was short, nil otherwise
+func (e *InsufficientStockError) Err() error {
+	if len(e.Shortages) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *InsufficientStockError) Error() string {
+	messages := make([]string, 0, len(e.Shortages))
+	for _, shortage := range e.Shortages {
+		messages = append(messages, fmt.Sprintf("product %s has only %d items but %d requested",
+			shortage.ProductId, shortage.Available, shortage.Requested))
+	}
+
+	return ErrInsufficientStock.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *InsufficientStockError) Unwrap() error {
+	return ErrInsufficientStock
+}
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDomainError_Codes(t *testing.T) {
+	tests := []struct {
+		err  *DomainError
+		code string
+	}{
+		{ErrUserValidation, "USER_VALIDATION_FAILED"},
+		{ErrUserNotFound, "USER_NOT_FOUND"},
+		{ErrInvalidVerificationToken, "INVALID_VERIFICATION_TOKEN"},
+		{ErrProductValidation, "PRODUCT_VALIDATION_FAILED"},
+		{ErrProductNotFound, "PRODUCT_NOT_FOUND"},
+		{ErrVersionConflict, "VERSION_CONFLICT"},
+		{ErrOrderValidation, "ORDER_VALIDATION_FAILED"},
+		{ErrOrderNotFound, "ORDER_NOT_FOUND"},
+		{ErrOrderItemNotFound, "ORDER_ITEM_NOT_FOUND"},
+		{ErrOrderLimitExceeded, "ORDER_LIMIT_EXCEEDED"},
+		{ErrInsufficientStock, "INSUFFICIENT_STOCK"},
+		{ErrInvalidQuantity, "INVALID_QUANTITY"},
+		{ErrInvalidCursor, "INVALID_CURSOR"},
+		{ErrInvalidSort, "INVALID_SORT"},
+		{ErrInvalidId, "INVALID_ID"},
+		{ErrReadOnly, "READ_ONLY"},
+		{ErrInvalidCredentials, "INVALID_CREDENTIALS"},
+		{ErrInvalidToken, "INVALID_TOKEN"},
+		{ErrUnauthenticated, "UNAUTHENTICATED"},
+		{ErrForbidden, "FORBIDDEN"},
+		{ErrInvalidRefreshToken, "INVALID_REFRESH_TOKEN"},
+		{ErrRefreshTokenReused, "REFRESH_TOKEN_REUSED"},
+		{ErrInvalidJSON, "INVALID_JSON"},
+		{ErrRequestValidation, "REQUEST_VALIDATION_FAILED"},
+		{ErrRequestCanceled, "REQUEST_CANCELED"},
+		{ErrRequestTimeout, "REQUEST_TIMEOUT"},
+		{ErrMaintenance, "MAINTENANCE"},
+		{ErrOverloaded, "OVERLOADED"},
+		{ErrNotReady, "NOT_READY"},
+		{ErrAuthUnavailable, "AUTH_UNAVAILABLE"},
+		{ErrInternal, "INTERNAL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			assert.Equal(t, tt.code, tt.err.Code())
+
+			wrapped := fmt.Errorf("%w: details", tt.err)
+			assert.ErrorIs(t, wrapped, tt.err)
+			assert.Equal(t, tt.code, ErrorCode(wrapped))
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	validationErr := NewValidationError(ErrProductValidation)
+	validationErr.Add("description", "description is required")
+	assert.Equal(t, "PRODUCT_VALIDATION_FAILED", ErrorCode(validationErr.Err()))
+
+	stockErr := &InsufficientStockError{}
+	stockErr.Add(uuid.New(), 5, 2)
+	assert.Equal(t, "INSUFFICIENT_STOCK", ErrorCode(stockErr.Err()))
+
+	// sentinels stay distinct even though they share a type
+	assert.NotErrorIs(t, ErrUserNotFound, ErrOrderNotFound)
+	assert.Empty(t, ErrorCode(errors.New("boom")))
+	assert.Empty(t, ErrorCode(nil))
+}
@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+type OrderEventType string
+
+const (
+	OrderCreated   OrderEventType = "order.created"
+	OrderConfirmed OrderEventType = "order.confirmed"
+	OrderCancelled OrderEventType = "order.cancelled"
+	OrderCompleted OrderEventType = "order.completed"
+)
+
+// orderEventTypes maps the status an order moved to onto the event announcing it
+var orderEventTypes = map[OrderStatus]OrderEventType{
+	OrderStatusConfirmed: OrderConfirmed,
+	OrderStatusCancelled: OrderCancelled,
+	OrderStatusCompleted: OrderCompleted,
+}
+
+// OrderEvent announces an order lifecycle change that has already been persisted
+type OrderEvent struct {
+	Type       OrderEventType
+	Order      *Order
+	OccurredAt time.Time
+}
+
+func NewOrderEvent(eventType OrderEventType, order *Order) *OrderEvent {
+	return &OrderEvent{
+		Type:       eventType,
+		Order:      order,
+		OccurredAt: Now(),
+	}
+}
+
+// NewOrderStatusEvent returns the event for an order that has just moved to its current status,
+// nil if that status has no event
+func NewOrderStatusEvent(order *Order) *OrderEvent {
+	eventType, ok := orderEventTypes[order.Status]
+	if !ok {
+		return nil
+	}
+	return NewOrderEvent(eventType, order)
+}
+
+// EventHandler reacts to an order event; an error is reported but does not undo the change
+type EventHandler func(ctx context.Context, event *OrderEvent) error
+
+// EventSubscriber forwards order events outside the process
+type EventSubscriber interface {
+	Handle(ctx context.Context, event *OrderEvent) error
+	// Close stops accepting events and waits for the pending deliveries, until ctx is done
+	Close(ctx context.Context) error
+}
+
+// EventPublisher delivers order events to the subscribed handlers
+type EventPublisher interface {
+	Subscribe(handler EventHandler)
+	// Publish hands event to every handler; handler failures are logged, never returned
+	Publish(ctx context.Context, event *OrderEvent)
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "context"
+
+// Mail is a plain text message to a single recipient
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, mail *Mail) error
+}
@@ -5,12 +5,15 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
+
+	"shared/idgen"
 )
 
-type OrderStatus = string
+type OrderStatus string
 
 const (
 	OrderStatusPending   OrderStatus = "pending"
@@ -19,6 +22,21 @@ const (
 	OrderStatusCompleted OrderStatus = "completed"
 )
 
+var orderStatuses = []OrderStatus{OrderStatusPending, OrderStatusConfirmed, OrderStatusCancelled, OrderStatusCompleted}
+
+// ParseOrderStatus converts s to an OrderStatus, failing with ErrOrderValidation for unknown statuses
+func ParseOrderStatus(s string) (OrderStatus, error) {
+	status := OrderStatus(s)
+	if !status.Valid() {
+		return "", fmt.Errorf("%w: invalid order status %q", ErrOrderValidation, s)
+	}
+	return status, nil
+}
+
+func (s OrderStatus) Valid() bool {
+	return slices.Contains(orderStatuses, s)
+}
+
 // OrderItem represents a product in an order with historical information
 type OrderItem struct {
 	Id        uuid.UUID
@@ -41,11 +59,11 @@ type ProductSnapshot struct {
 
 func (item *OrderItem) Validate() error {
 	if item.Id == uuid.Nil {
-		item.Id = uuid.New()
+		item.Id = idgen.New()
 	}
 
 	if item.CreatedAt.IsZero() {
-		item.CreatedAt = time.Now()
+		item.CreatedAt = Now()
 	}
 
 	if item.OrderId == uuid.Nil {
@@ -60,6 +78,10 @@ func (item *OrderItem) Validate() error {
 		return fmt.Errorf("%w: quantity must be positive", ErrOrderValidation)
 	}
 
+	if err := currentConfig().OrderLimits.checkItemQuantity(item.Quantity); err != nil {
+		return err
+	}
+
 	if item.ProductSnapshot.Description == "" {
 		return fmt.Errorf("%w: product snapshot description is required", ErrOrderValidation)
 	}
@@ -68,24 +90,27 @@ func (item *OrderItem) Validate() error {
 }
 
 type Order struct {
-	Id        uuid.UUID
-	UserId    uuid.UUID
-	Status    OrderStatus
-	Items     []*OrderItem
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	Id     uuid.UUID
+	UserId uuid.UUID
+	Status OrderStatus
+	Items  []*OrderItem
+	// TotalQuantity is the sum of the item quantities, stored with the order so lists need not add up the items;
+	// Validate keeps it in line with Items
+	TotalQuantity int
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
 }
 
 func (o *Order) Validate() error {
 	if o.Id == uuid.Nil {
-		o.Id = uuid.New()
+		o.Id = idgen.New()
 	}
 
 	if o.CreatedAt.IsZero() {
-		o.CreatedAt = time.Now()
+		o.CreatedAt = Now()
 	}
 
-	o.UpdatedAt = time.Now()
+	o.UpdatedAt = Now()
 
 	if o.UserId == uuid.Nil {
 		return fmt.Errorf("%w: user ID is required", ErrOrderValidation)
@@ -106,11 +131,13 @@ func (o *Order) Validate() error {
 			return err
 		}
 	}
+	o.TotalQuantity = o.ItemsQuantity()
 
 	return nil
 }
 
-func (o *Order) TotalQuantity() int {
+// ItemsQuantity adds up the quantities of the loaded items
+func (o *Order) ItemsQuantity() int {
 	total := 0
 	for _, item := range o.Items {
 		total += item.Quantity
@@ -128,7 +155,7 @@ func (o *Order) Cancel() error {
 	}
 
 	o.Status = OrderStatusCancelled
-	o.UpdatedAt = time.Now()
+	o.UpdatedAt = Now()
 	return nil
 }
 
@@ -138,7 +165,7 @@ func (o *Order) Confirm() error {
 	}
 
 	o.Status = OrderStatusConfirmed
-	o.UpdatedAt = time.Now()
+	o.UpdatedAt = Now()
 	return nil
 }
 
@@ -148,10 +175,49 @@ func (o *Order) Complete() error {
 	}
 
 	o.Status = OrderStatusCompleted
-	o.UpdatedAt = time.Now()
+	o.UpdatedAt = Now()
 	return nil
 }
 
+// TransitionTo applies the domain transition to status, failing with ErrOrderValidation when it is not allowed
+func (o *Order) TransitionTo(status OrderStatus) error {
+	switch status {
+	case OrderStatusConfirmed:
+		return o.Confirm()
+	case OrderStatusCompleted:
+		return o.Complete()
+	case OrderStatusCancelled:
+		return o.Cancel()
+	default:
+		return fmt.Errorf("%w: order cannot be moved from %s to %s", ErrOrderValidation, o.Status, status)
+	}
+}
+
+// OrderStatusChange is an entry of an order's status audit log
+type OrderStatusChange struct {
+	Id         uuid.UUID
+	OrderId    uuid.UUID
+	FromStatus OrderStatus
+	ToStatus   OrderStatus
+	ActorId    *uuid.UUID // user who made the change, nil when unauthenticated
+	ChangedAt  time.Time
+}
+
+// OrderStatusStats aggregates the orders in one status
+type OrderStatusStats struct {
+	Count         int
+	TotalQuantity int // sum of item quantities across the orders
+}
+
+// NewOrderStatusStatsMap returns zeroed stats for every known status, so statuses without orders are still reported
+func NewOrderStatusStatsMap() map[OrderStatus]*OrderStatusStats {
+	stats := make(map[OrderStatus]*OrderStatusStats, len(orderStatuses))
+	for _, status := range orderStatuses {
+		stats[status] = &OrderStatusStats{}
+	}
+	return stats
+}
+
 type CreateOrderItemRequest struct {
 	ProductId uuid.UUID
 	Quantity  int
@@ -166,7 +232,7 @@ func (r *CreateOrderItemRequest) Validate() error {
 		return fmt.Errorf("%w: quantity must be positive", ErrOrderValidation)
 	}
 
-	return nil
+	return currentConfig().OrderLimits.checkItemQuantity(r.Quantity)
 }
 
 type CreateOrderRequest struct {
@@ -174,6 +240,24 @@ type CreateOrderRequest struct {
 	Items  []CreateOrderItemRequest
 }
 
+// mergeOrderItems folds lines of the same product into the first one, summing their quantities, so an order
+// holds a single item per product. The limits are checked beforehand, which keeps the sums from overflowing.
+func mergeOrderItems(items []CreateOrderItemRequest) []CreateOrderItemRequest {
+	merged := make([]CreateOrderItemRequest, 0, len(items))
+	positions := make(map[uuid.UUID]int, len(items))
+	for _, item := range items {
+		if i, ok := positions[item.ProductId]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		positions[item.ProductId] = len(merged)
+		merged = append(merged, item)
+	}
+
+	return merged
+}
+
+// Validate checks the request and merges duplicate product lines, see mergeOrderItems
 func (r *CreateOrderRequest) Validate() error {
 	if r.UserId == uuid.Nil {
 		return fmt.Errorf("%w: user ID is required", ErrOrderValidation)
@@ -189,6 +273,11 @@ func (r *CreateOrderRequest) Validate() error {
 		}
 	}
 
+	if err := currentConfig().OrderLimits.check(r.Items); err != nil {
+		return err
+	}
+
+	r.Items = mergeOrderItems(r.Items)
 	return nil
 }
 
@@ -202,44 +291,132 @@ func (r *UpdateOrderRequest) Validate() error {
 		return fmt.Errorf("%w: order ID is required", ErrOrderValidation)
 	}
 
-	validStatuses := []OrderStatus{OrderStatusPending, OrderStatusConfirmed, OrderStatusCancelled, OrderStatusCompleted}
-	isValid := false
-	for _, status := range validStatuses {
-		if r.Status == status {
-			isValid = true
-			break
+	if !r.Status.Valid() {
+		return fmt.Errorf("%w: invalid order status %s", ErrOrderValidation, r.Status)
+	}
+
+	return nil
+}
+
+// MaxBulkOrderIds bounds a bulk status update to what fits a single page of orders
+const MaxBulkOrderIds = 100
+
+// BulkUpdateOrderStatusRequest moves many orders to the same status at once
+type BulkUpdateOrderStatusRequest struct {
+	Ids    []uuid.UUID
+	Status OrderStatus
+}
+
+func (r *BulkUpdateOrderStatusRequest) Validate() error {
+	if len(r.Ids) == 0 {
+		return fmt.Errorf("%w: at least one order ID is required", ErrOrderValidation)
+	}
+
+	if len(r.Ids) > MaxBulkOrderIds {
+		return fmt.Errorf("%w: at most %d orders can be updated at once", ErrOrderValidation, MaxBulkOrderIds)
+	}
+
+	for _, id := range r.Ids {
+		if id == uuid.Nil {
+			return fmt.Errorf("%w: order ID is required", ErrOrderValidation)
+		}
+	}
+
+	switch r.Status {
+	case OrderStatusConfirmed, OrderStatusCompleted:
+	case OrderStatusCancelled:
+		// cancellation restores stock, which only the cancel endpoint does
+		return fmt.Errorf("%w: orders must be cancelled one by one", ErrOrderValidation)
+	default:
+		return fmt.Errorf("%w: invalid target status %s", ErrOrderValidation, r.Status)
+	}
+
+	return nil
+}
+
+// OrderStatusUpdateResult is the outcome of a bulk status update for a single order.
+// Err is set, and Status holds the unchanged status, when the order was skipped.
+type OrderStatusUpdateResult struct {
+	OrderId uuid.UUID
+	Status  OrderStatus
+	Err     error
+}
+
+// UpdateOrderItemsRequest replaces the whole item set of a pending order
+type UpdateOrderItemsRequest struct {
+	Id    uuid.UUID
+	Items []CreateOrderItemRequest
+}
+
+// Validate checks the request and merges duplicate product lines, see mergeOrderItems
+func (r *UpdateOrderItemsRequest) Validate() error {
+	if r.Id == uuid.Nil {
+		return fmt.Errorf("%w: order ID is required", ErrOrderValidation)
+	}
+
+	if len(r.Items) == 0 {
+		return fmt.Errorf("%w: order must contain at least one item", ErrOrderValidation)
+	}
+
+	for _, item := range r.Items {
+		if err := item.Validate(); err != nil {
+			return err
 		}
 	}
 
-	if !isValid {
-		return fmt.Errorf("%w: invalid order status %s", ErrOrderValidation, r.Status)
+	if err := currentConfig().OrderLimits.check(r.Items); err != nil {
+		return err
 	}
 
+	r.Items = mergeOrderItems(r.Items)
 	return nil
 }
 
+// OrderSort is a column orders can be listed by
+type OrderSort string
+
+const (
+	OrderSortCreatedAt OrderSort = sortCreatedAt
+	OrderSortUpdatedAt OrderSort = "updated_at"
+)
+
+// ParseOrderSort checks s against the sortable columns, an empty s means the default created_at
+func ParseOrderSort(s string) (OrderSort, error) {
+	return parseSort(s, OrderSortCreatedAt, OrderSortUpdatedAt)
+}
+
 type GetOrdersRequest struct {
-	Ids      []uuid.UUID
-	UserIds  []uuid.UUID
-	Statuses []OrderStatus
-	Limit    int
-	Offset   int
+	Ids        []uuid.UUID
+	UserIds    []uuid.UUID
+	Statuses   []OrderStatus
+	ProductIds []uuid.UUID // orders containing any of the products
+	Sort       OrderSort
+	Order      SortOrder
+	After      *Cursor // keyset pagination, takes precedence over Offset; only for the default order
+	Limit      int
+	Offset     int
 }
 
 func (r *GetOrdersRequest) Validate() {
-	if r.Limit <= 0 {
-		r.Limit = 10
-	}
-	if r.Limit > 100 {
-		r.Limit = 100
-	}
+	r.Limit = currentConfig().PageSize.limit(r.Limit, len(r.Ids))
 	if r.Offset < 0 {
 		r.Offset = 0
 	}
+	if r.Sort == "" {
+		r.Sort = OrderSortCreatedAt
+	}
+	if r.Order == "" {
+		r.Order = SortOrderDesc
+	}
+}
+
+// DefaultOrder reports whether the orders are listed newest first, the only order cursors can page
+func (r *GetOrdersRequest) DefaultOrder() bool {
+	return defaultOrder(r.Sort, r.Order)
 }
 
 func (r *GetOrdersRequest) CacheKey() CacheKey {
-	buf := make([]byte, 0)
+	buf := newCacheKeyBuffer(orderCacheKeyPrefix)
 
 	// ids
 	buf = binary.BigEndian.AppendUint32(buf, uint32(len(r.Ids)))
@@ -256,10 +433,21 @@ func (r *GetOrdersRequest) CacheKey() CacheKey {
 	// statuses
 	buf = binary.BigEndian.AppendUint32(buf, uint32(len(r.Statuses)))
 	for _, status := range r.Statuses {
-		buf = append(buf, []byte(status)...)
+		buf = appendCacheKeyString(buf, string(status))
 	}
 
+	// product ids
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(r.ProductIds)))
+	for _, id := range r.ProductIds {
+		buf = append(buf, id[:]...)
+	}
+
+	// ordering
+	buf = appendCacheKeyString(buf, string(r.Sort))
+	buf = appendCacheKeyString(buf, string(r.Order))
+
 	// pagination
+	buf = r.After.appendCacheKey(buf)
 	buf = binary.BigEndian.AppendUint32(buf, uint32(r.Limit))
 	buf = binary.BigEndian.AppendUint32(buf, uint32(r.Offset))
 
@@ -268,14 +456,57 @@ func (r *GetOrdersRequest) CacheKey() CacheKey {
 
 type OrderStorage interface {
 	CreateOrder(ctx context.Context, order *Order) error
+	// UpdateOrder changes the status, recording the transition with the context's actor in the status history
 	UpdateOrder(ctx context.Context, req *UpdateOrderRequest) (*Order, error)
+	// UpdateOrderStatuses moves all the orders to status in a single transaction, recording each transition
+	UpdateOrderStatuses(ctx context.Context, ids []uuid.UUID, status OrderStatus) error
+	// LockOrder reads the order after locking its row until the end of the unit of work it runs in,
+	// failing with ErrOrderNotFound when there is no such order
+	LockOrder(ctx context.Context, id uuid.UUID) (*Order, error)
+	// LockOrders is LockOrder for many orders at once; unknown ids are absent from the result
+	LockOrders(ctx context.Context, ids []uuid.UUID) ([]*Order, error)
+	// ReplaceOrderItems swaps the stored items for order.Items, failing with ErrOrderValidation unless the order is pending
+	ReplaceOrderItems(ctx context.Context, order *Order) (*Order, error)
+	// RemoveOrderItem deletes one item of a pending or confirmed order and lowers its total quantity.
+	// The last item is never removed, the order is cancelled instead.
+	RemoveOrderItem(ctx context.Context, orderId, itemId uuid.UUID) (*Order, error)
+	// DeleteOrder removes the order and its items for good, without restoring stock
+	DeleteOrder(ctx context.Context, id uuid.UUID) error
 	Orders(ctx context.Context, req *GetOrdersRequest) ([]*Order, error)
 	CountOrders(ctx context.Context, req *GetOrdersRequest) (int, error)
+	// StatusCounts aggregates the orders matching req's filters per status in a single query; pagination is ignored
+	StatusCounts(ctx context.Context, req *GetOrdersRequest) (map[OrderStatus]*OrderStatusStats, error)
+	// OrderStatusHistory lists the status transitions of an order, oldest first
+	OrderStatusHistory(ctx context.Context, orderId uuid.UUID) ([]*OrderStatusChange, error)
+	CacheStats() CacheStats
+	// Close stops background cache maintenance
+	Close()
 }
 
 type OrderAppService interface {
 	CreateOrder(ctx context.Context, req *CreateOrderRequest) (*Order, error)
+	// ValidateOrder runs the checks of CreateOrder and returns the would-be order without reserving stock or storing it
+	ValidateOrder(ctx context.Context, req *CreateOrderRequest) (*Order, error)
 	UpdateOrder(ctx context.Context, req *UpdateOrderRequest) (*Order, error)
+	UpdateOrderItems(ctx context.Context, req *UpdateOrderItemsRequest) (*Order, error)
+	// BulkUpdateStatus applies the status transition to every order it is legal for, reporting a result per requested ID
+	BulkUpdateStatus(ctx context.Context, req *BulkUpdateOrderStatusRequest) ([]*OrderStatusUpdateResult, error)
 	Orders(ctx context.Context, req *GetOrdersRequest) ([]*Order, error)
+	CountOrders(ctx context.Context, req *GetOrdersRequest) (int, error)
+	// StatusCounts reports per-status order counts and item quantities for the filtered orders
+	StatusCounts(ctx context.Context, req *GetOrdersRequest) (map[OrderStatus]*OrderStatusStats, error)
+	// UserOrders lists the orders of an existing user, failing with ErrUserNotFound otherwise
+	UserOrders(ctx context.Context, userId uuid.UUID, req *GetOrdersRequest) ([]*Order, error)
 	CancelOrder(ctx context.Context, orderId uuid.UUID) (*Order, error)
+	// CancelItem cancels one item of a cancellable order and restores its stock; cancelling the last item cancels the order
+	CancelItem(ctx context.Context, orderId, itemId uuid.UUID) (*Order, error)
+	// OrderStatusHistory lists the status transitions of an existing order, failing with ErrOrderNotFound otherwise
+	OrderStatusHistory(ctx context.Context, orderId uuid.UUID) ([]*OrderStatusChange, error)
+	// OrderUser loads the user who placed order, soft-deleted or not; nil when the user no longer exists
+	OrderUser(ctx context.Context, order *Order) (*User, error)
+	// OrderProducts loads the current state of the products in the orders' items with a single query, keyed by id.
+	// Soft-deleted products are included; products that no longer exist are missing from the result.
+	OrderProducts(ctx context.Context, orders []*Order) (map[uuid.UUID]*Product, error)
+	// DeleteOrder hard-deletes an order; unlike CancelOrder it leaves product stock untouched
+	DeleteOrder(ctx context.Context, orderId uuid.UUID) error
 }
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// OrderLimits bounds the size of a single order and how many orders a user may keep open
+type OrderLimits struct {
+	MaxItems        int // order lines
+	MaxQuantity     int // units across all lines
+	MaxItemQuantity int // units of a single product
+	MaxOpenOrders   int // pending or confirmed orders per user, unlimited when zero
+}
+
+func DefaultOrderLimits() OrderLimits {
+	return OrderLimits{
+		MaxItems:        100,
+		MaxQuantity:     10000,
+		MaxItemQuantity: 10000,
+	}
+}
+
+// withDefaults fills the zero limits with their defaults, MaxOpenOrders stays unlimited when zero
+func (l OrderLimits) withDefaults() OrderLimits {
+	defaults := DefaultOrderLimits()
+	if l.MaxItems == 0 {
+		l.MaxItems = defaults.MaxItems
+	}
+	if l.MaxQuantity == 0 {
+		l.MaxQuantity = defaults.MaxQuantity
+	}
+	if l.MaxItemQuantity == 0 {
+		l.MaxItemQuantity = defaults.MaxItemQuantity
+	}
+	return l
+}
+
+// OpenOrderStatuses lists the statuses counted against MaxOpenOrders
+func OpenOrderStatuses() []OrderStatus {
+	return []OrderStatus{OrderStatusPending, OrderStatusConfirmed}
+}
+
+// checkItemQuantity fails with ErrOrderValidation when a single line asks for more units than allowed
+func (l OrderLimits) checkItemQuantity(quantity int) error {
+	if quantity > l.MaxItemQuantity {
+		return fmt.Errorf("%w: item quantity cannot exceed %d", ErrOrderValidation, l.MaxItemQuantity)
+	}
+
+	return nil
+}
+
+// check fails with ErrOrderValidation when items exceed the limits; the quantities must already be positive.
+// Lines of the same product are summed, as mergeOrderItems will fold them into one item.
+func (l OrderLimits) check(items []CreateOrderItemRequest) error {
+	if len(items) > l.MaxItems {
+		return fmt.Errorf("%w: order can contain at most %d items, got %d", ErrOrderValidation, l.MaxItems, len(items))
+	}
+
+	total := 0
+	perProduct := make(map[uuid.UUID]int, len(items))
+	for _, item := range items {
+		// compared before adding so huge quantities cannot overflow the total
+		if item.Quantity > l.MaxQuantity-total {
+			return fmt.Errorf("%w: order total quantity cannot exceed %d", ErrOrderValidation, l.MaxQuantity)
+		}
+		total += item.Quantity
+
+		perProduct[item.ProductId] += item.Quantity
+		if err := l.checkItemQuantity(perProduct[item.ProductId]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
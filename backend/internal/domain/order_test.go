@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"shared/idgen"
+)
+
+func TestParseOrderStatus(t *testing.T) {
+	for _, s := range []string{"pending", "confirmed", "cancelled", "completed"} {
+		t.Run(s, func(t *testing.T) {
+			status, err := ParseOrderStatus(s)
+			require.NoError(t, err)
+			assert.Equal(t, OrderStatus(s), status)
+			assert.True(t, status.Valid())
+		})
+	}
+
+	for _, s := range []string{"", "shipped", "Pending", " pending"} {
+		t.Run("invalid "+s, func(t *testing.T) {
+			status, err := ParseOrderStatus(s)
+			assert.ErrorIs(t, err, ErrOrderValidation)
+			assert.Empty(t, status)
+			assert.False(t, OrderStatus(s).Valid())
+		})
+	}
+}
+
+func setOrderLimitsForTest(t *testing.T, l OrderLimits) {
+	t.Helper()
+	configureForTest(t, func(c *Config) { c.OrderLimits = l })
+}
+
+// orderItems returns lines of the given quantities for distinct products
+func orderItems(quantities ...int) []CreateOrderItemRequest {
+	items := make([]CreateOrderItemRequest, 0, len(quantities))
+	for _, quantity := range quantities {
+		items = append(items, CreateOrderItemRequest{ProductId: uuid.New(), Quantity: quantity})
+	}
+	return items
+}
+
+func TestOrderLimits(t *testing.T) {
+	// no item limit, the overflow case must reach the total check
+	setOrderLimitsForTest(t, OrderLimits{MaxItems: 3, MaxQuantity: 10, MaxItemQuantity: math.MaxInt})
+
+	tests := []struct {
+		name    string
+		items   []CreateOrderItemRequest
+		wantErr string
+	}{
+		{
+			name:  "exactly at both limits",
+			items: orderItems(5, 3, 2),
+		},
+		{
+			name:    "too many items",
+			items:   orderItems(1, 1, 1, 1),
+			wantErr: "order can contain at most 3 items, got 4",
+		},
+		{
+			name:    "total quantity over the limit",
+			items:   orderItems(5, 6),
+			wantErr: "order total quantity cannot exceed 10",
+		},
+		{
+			name:    "quantity that would overflow the total",
+			items:   orderItems(5, math.MaxInt),
+			wantErr: "order total quantity cannot exceed 10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// both the creation and the item update path enforce the limits
+			for _, err := range []error{
+				(&CreateOrderRequest{UserId: uuid.New(), Items: tt.items}).Validate(),
+				(&UpdateOrderItemsRequest{Id: uuid.New(), Items: tt.items}).Validate(),
+			} {
+				if tt.wantErr == "" {
+					assert.NoError(t, err)
+					continue
+				}
+				assert.ErrorIs(t, err, ErrOrderValidation)
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigure_OrderLimitsDefaults(t *testing.T) {
+	setOrderLimitsForTest(t, OrderLimits{MaxItems: 5})
+
+	defaults := DefaultOrderLimits()
+	assert.Equal(t, OrderLimits{
+		MaxItems:        5,
+		MaxQuantity:     defaults.MaxQuantity,
+		MaxItemQuantity: defaults.MaxItemQuantity,
+	}, CurrentConfig().OrderLimits)
+}
+
+func TestOrderLimits_MaxItemQuantity(t *testing.T) {
+	setOrderLimitsForTest(t, OrderLimits{MaxItems: 10, MaxQuantity: 100, MaxItemQuantity: 5})
+
+	tests := []struct {
+		name     string
+		quantity int
+		wantErr  bool
+	}{
+		{name: "below the limit", quantity: 4},
+		{name: "at the limit", quantity: 5},
+		{name: "above the limit", quantity: 6, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := &CreateOrderItemRequest{ProductId: uuid.New(), Quantity: tt.quantity}
+			item := &OrderItem{
+				OrderId:         uuid.New(),
+				ProductId:       line.ProductId,
+				Quantity:        tt.quantity,
+				ProductSnapshot: ProductSnapshot{Description: "Phone"},
+			}
+
+			for _, err := range []error{line.Validate(), item.Validate()} {
+				if !tt.wantErr {
+					assert.NoError(t, err)
+					continue
+				}
+				assert.ErrorIs(t, err, ErrOrderValidation)
+				assert.ErrorContains(t, err, "item quantity cannot exceed 5")
+			}
+		})
+	}
+
+	// duplicate lines are limited by their merged quantity
+	phone := uuid.New()
+	req := &CreateOrderRequest{
+		UserId: uuid.New(),
+		Items:  []CreateOrderItemRequest{{ProductId: phone, Quantity: 3}, {ProductId: phone, Quantity: 3}},
+	}
+	assert.ErrorIs(t, req.Validate(), ErrOrderValidation)
+}
+
+func TestGetOrdersRequest_CacheKey_ProductIds(t *testing.T) {
+	productId := uuid.New()
+
+	withProduct := (&GetOrdersRequest{ProductIds: []uuid.UUID{productId}, Limit: 10}).CacheKey()
+	assert.Equal(t, withProduct, (&GetOrdersRequest{ProductIds: []uuid.UUID{productId}, Limit: 10}).CacheKey())
+	assert.NotEqual(t, withProduct, (&GetOrdersRequest{Limit: 10}).CacheKey())
+	assert.NotEqual(t, withProduct, (&GetOrdersRequest{ProductIds: []uuid.UUID{uuid.New()}, Limit: 10}).CacheKey())
+
+	// product ids are not mistaken for order ids
+	assert.NotEqual(t, withProduct, (&GetOrdersRequest{Ids: []uuid.UUID{productId}, Limit: 10}).CacheKey())
+}
+
+func TestGetOrdersRequest_CacheKey_Sort(t *testing.T) {
+	newest := (&GetOrdersRequest{Sort: OrderSortCreatedAt, Order: SortOrderDesc, Limit: 10}).CacheKey()
+	assert.Equal(t, newest, (&GetOrdersRequest{Sort: OrderSortCreatedAt, Order: SortOrderDesc, Limit: 10}).CacheKey())
+	assert.NotEqual(t, newest, (&GetOrdersRequest{Sort: OrderSortCreatedAt, Order: SortOrderAsc, Limit: 10}).CacheKey())
+	assert.NotEqual(t, newest, (&GetOrdersRequest{Sort: OrderSortUpdatedAt, Order: SortOrderDesc, Limit: 10}).CacheKey())
+}
+
+func TestCreateOrderRequest_Validate_MergesDuplicateLines(t *testing.T) {
+	phone, cable := uuid.New(), uuid.New()
+
+	req := &CreateOrderRequest{
+		UserId: uuid.New(),
+		Items: []CreateOrderItemRequest{
+			{ProductId: phone, Quantity: 1},
+			{ProductId: cable, Quantity: 2},
+			{ProductId: phone, Quantity: 3},
+		},
+	}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, []CreateOrderItemRequest{
+		{ProductId: phone, Quantity: 4},
+		{ProductId: cable, Quantity: 2},
+	}, req.Items)
+
+	update := &UpdateOrderItemsRequest{
+		Id:    uuid.New(),
+		Items: []CreateOrderItemRequest{{ProductId: cable, Quantity: 1}, {ProductId: cable, Quantity: 1}},
+	}
+	require.NoError(t, update.Validate())
+	assert.Equal(t, []CreateOrderItemRequest{{ProductId: cable, Quantity: 2}}, update.Items)
+}
+
+func TestCreateOrderRequest_Validate_LimitsApplyToMergedTotal(t *testing.T) {
+	setOrderLimitsForTest(t, OrderLimits{MaxItems: 10, MaxQuantity: 10})
+	phone := uuid.New()
+
+	// huge duplicate quantities are rejected instead of overflowing the merged sum
+	req := &CreateOrderRequest{
+		UserId: uuid.New(),
+		Items:  []CreateOrderItemRequest{{ProductId: phone, Quantity: math.MaxInt}, {ProductId: phone, Quantity: math.MaxInt}},
+	}
+	assert.ErrorIs(t, req.Validate(), ErrOrderValidation)
+
+	req.Items = []CreateOrderItemRequest{{ProductId: phone, Quantity: 6}, {ProductId: phone, Quantity: 5}}
+	assert.ErrorIs(t, req.Validate(), ErrOrderValidation)
+}
+
+func TestOrder_Validate_TotalQuantity(t *testing.T) {
+	factory := &Factory{}
+	order := factory.Order(uuid.New(), uuid.New(), uuid.New())
+	order.Items[0].Quantity = 4
+
+	require.NoError(t, order.Validate())
+	assert.Equal(t, 5, order.TotalQuantity)
+	assert.Equal(t, order.ItemsQuantity(), order.TotalQuantity)
+}
+
+func TestOrder_Validate_TimeOrderedIds(t *testing.T) {
+	require.NoError(t, idgen.SetVersion(idgen.V7))
+	t.Cleanup(func() { require.NoError(t, idgen.SetVersion(idgen.V4)) })
+
+	var previous uuid.UUID
+	for range 100 {
+		order := &Order{
+			UserId: uuid.New(),
+			Status: OrderStatusPending,
+			Items: []*OrderItem{{
+				ProductId:       uuid.New(),
+				Quantity:        1,
+				ProductSnapshot: ProductSnapshot{Description: "Phone"},
+			}},
+		}
+		require.NoError(t, order.Validate())
+
+		assert.Equal(t, uuid.Version(7), order.Id.Version())
+		assert.Negative(t, bytes.Compare(previous[:], order.Id[:]))
+		// items are validated after their order, so their IDs follow it
+		assert.Negative(t, bytes.Compare(order.Id[:], order.Items[0].Id[:]))
+		previous = order.Items[0].Id
+	}
+}
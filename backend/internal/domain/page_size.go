@@ -0,0 +1,36 @@
+package domain
+
+// PageSize bounds the number of rows a list request returns
+type PageSize struct {
+	Default int
+	Max     int
+}
+
+func DefaultPageSize() PageSize {
+	return PageSize{
+		Default: 10,
+		Max:     100,
+	}
+}
+
+// withDefaults fills the zero bounds with their defaults and keeps the default within the maximum
+func (p PageSize) withDefaults() PageSize {
+	defaults := DefaultPageSize()
+	if p.Default == 0 {
+		p.Default = defaults.Default
+	}
+	if p.Max == 0 {
+		p.Max = defaults.Max
+	}
+	p.Default = min(p.Default, p.Max)
+	return p
+}
+
+// limit normalizes a requested limit: unset picks the default and anything above the maximum is capped.
+// Lookups by ids may return every requested id even past the maximum.
+func (p PageSize) limit(limit, ids int) int {
+	if limit <= 0 {
+		return p.Default
+	}
+	return min(limit, max(p.Max, ids))
+}
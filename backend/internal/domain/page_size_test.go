@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageSize_Validate(t *testing.T) {
+	configureForTest(t, func(c *Config) { c.PageSize = PageSize{Default: 20, Max: 50} })
+
+	tests := []struct {
+		name     string
+		limit    int
+		ids      int
+		expected int
+	}{
+		{name: "unset limit takes the default", limit: 0, expected: 20},
+		{name: "negative limit takes the default", limit: -1, expected: 20},
+		{name: "limit at the maximum is kept", limit: 50, expected: 50},
+		{name: "limit above the maximum is capped", limit: 51, expected: 50},
+		{name: "lookup by ids may exceed the maximum", limit: 80, ids: 80, expected: 80},
+		{name: "lookup by ids is still capped by the ids", limit: 90, ids: 80, expected: 80},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := make([]uuid.UUID, tt.ids)
+
+			users := &GetUsersRequest{Ids: ids, Limit: tt.limit}
+			users.Validate()
+			assert.Equal(t, tt.expected, users.Limit)
+
+			products := &GetProductsRequest{Ids: ids, Limit: tt.limit}
+			products.Validate()
+			assert.Equal(t, tt.expected, products.Limit)
+
+			orders := &GetOrdersRequest{Ids: ids, Limit: tt.limit}
+			orders.Validate()
+			assert.Equal(t, tt.expected, orders.Limit)
+		})
+	}
+}
+
+func TestConfigure_PageSizeDefaults(t *testing.T) {
+	configureForTest(t, func(c *Config) { c.PageSize = PageSize{} })
+	assert.Equal(t, DefaultPageSize(), CurrentConfig().PageSize)
+
+	// a default above the maximum is capped to it
+	configureForTest(t, func(c *Config) { c.PageSize = PageSize{Default: 30, Max: 20} })
+	assert.Equal(t, PageSize{Default: 20, Max: 20}, CurrentConfig().PageSize)
+}
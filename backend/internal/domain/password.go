@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PasswordAlgorithmBcrypt   = "bcrypt"
+	PasswordAlgorithmArgon2id = "argon2id"
+)
+
+// PasswordHasher hashes salted passwords. Hashes must be self-describing
+// (carry their own cost parameters) so they verify after a configuration change.
+type PasswordHasher interface {
+	Algorithm() string
+	Hash(password, salt []byte) ([]byte, error)
+	Verify(hash, password, salt []byte) bool
+}
+
+// passwordVerifiers check hashes of every supported algorithm; the parameters
+// are read from the hash itself, so the defaults verify hashes of any cost
+var passwordVerifiers = map[string]PasswordHasher{
+	PasswordAlgorithmBcrypt:   NewBcryptHasher(bcrypt.DefaultCost),
+	PasswordAlgorithmArgon2id: NewArgon2idHasher(Argon2idParams{}),
+}
+
+func defaultPasswordHasher() PasswordHasher {
+	return passwordVerifiers[PasswordAlgorithmBcrypt]
+}
+
+// passwordHasherFor returns the hasher verifying hashes of algorithm, the configured one when it matches
+func passwordHasherFor(algorithm string) (PasswordHasher, bool) {
+	if algorithm == "" {
+		algorithm = PasswordAlgorithmBcrypt // users created before the algorithm was stored
+	}
+	if h := currentConfig().PasswordHasher; h.Algorithm() == algorithm {
+		return h, true
+	}
+	h, ok := passwordVerifiers[algorithm]
+	return h, ok
+}
+
+type bcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) PasswordHasher {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &bcryptHasher{cost: cost}
+}
+
+func (h *bcryptHasher) Algorithm() string {
+	return PasswordAlgorithmBcrypt
+}
+
+func (h *bcryptHasher) Hash(password, salt []byte) ([]byte, error) {
+	return bcrypt.GenerateFromPassword(saltPassword(password, salt), h.cost)
+}
+
+func (h *bcryptHasher) Verify(hash, password, salt []byte) bool {
+	return bcrypt.CompareHashAndPassword(hash, saltPassword(password, salt)) == nil
+}
+
+type Argon2idParams struct {
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+	KeyLen  uint32
+}
+
+func (p Argon2idParams) withDefaults() Argon2idParams {
+	if p.Time == 0 {
+		p.Time = 1
+	}
+	if p.Memory == 0 {
+		p.Memory = 64 * 1024
+	}
+	if p.Threads == 0 {
+		p.Threads = 4
+	}
+	if p.KeyLen == 0 {
+		p.KeyLen = 32
+	}
+	return p
+}
+
+type argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) PasswordHasher {
+	return &argon2idHasher{params: params.withDefaults()}
+}
+
+func (h *argon2idHasher) Algorithm() string {
+	return PasswordAlgorithmArgon2id
+}
+
+// Hash encodes the parameters with the key as "m=<memory>,t=<time>,p=<threads>$<base64 key>"
+func (h *argon2idHasher) Hash(password, salt []byte) ([]byte, error) {
+	p := h.params
+	key := argon2.IDKey(password, salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+	encoded := fmt.Sprintf("m=%d,t=%d,p=%d$%s", p.Memory, p.Time, p.Threads, base64.RawStdEncoding.EncodeToString(key))
+	return []byte(encoded), nil
+}
+
+func (h *argon2idHasher) Verify(hash, password, salt []byte) bool {
+	paramsPart, keyPart, found := strings.Cut(string(hash), "$")
+	if !found {
+		return false
+	}
+
+	var p Argon2idParams
+	if _, err := fmt.Sscanf(paramsPart, "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return false
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(keyPart)
+	if err != nil {
+		return false
+	}
+
+	actual := argon2.IDKey(password, salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, actual) == 1
+}
+
+func saltPassword(password, salt []byte) []byte {
+	salted := make([]byte, 0, len(password)+len(salt))
+	salted = append(salted, password...)
+	return append(salted, salt...)
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func setPasswordHasherForTest(t *testing.T, h PasswordHasher) {
+	t.Helper()
+	configureForTest(t, func(c *Config) { c.PasswordHasher = h })
+}
+
+func TestPasswordHasher_Bcrypt(t *testing.T) {
+	setPasswordHasherForTest(t, NewBcryptHasher(bcrypt.MinCost))
+
+	user := &User{}
+	require.NoError(t, user.SetPassword("password123"))
+
+	assert.Equal(t, PasswordAlgorithmBcrypt, user.PasswordAlgorithm)
+	cost, err := bcrypt.Cost(user.PasswordHash)
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost, cost)
+
+	assert.True(t, user.VerifyPassword("password123"))
+	assert.False(t, user.VerifyPassword("wrong-password"))
+}
+
+func TestPasswordHasher_Argon2id(t *testing.T) {
+	setPasswordHasherForTest(t, NewArgon2idHasher(Argon2idParams{Memory: 1024}))
+
+	user := &User{}
+	require.NoError(t, user.SetPassword("password123"))
+
+	assert.Equal(t, PasswordAlgorithmArgon2id, user.PasswordAlgorithm)
+	assert.True(t, user.VerifyPassword("password123"))
+	assert.False(t, user.VerifyPassword("wrong-password"))
+}
+
+func TestPasswordHasher_ExistingHashesVerifyAfterSwitch(t *testing.T) {
+	setPasswordHasherForTest(t, NewBcryptHasher(bcrypt.MinCost))
+
+	bcryptUser := &User{}
+	require.NoError(t, bcryptUser.SetPassword("password123"))
+
+	setPasswordHasherForTest(t, NewArgon2idHasher(Argon2idParams{Memory: 1024}))
+
+	argonUser := &User{}
+	require.NoError(t, argonUser.SetPassword("password123"))
+
+	assert.Equal(t, PasswordAlgorithmArgon2id, argonUser.PasswordAlgorithm)
+	assert.True(t, bcryptUser.VerifyPassword("password123"))
+	assert.True(t, argonUser.VerifyPassword("password123"))
+
+	// argon2id parameters are read from the stored hash, not the current hasher
+	setPasswordHasherForTest(t, NewArgon2idHasher(Argon2idParams{Memory: 2048, Time: 2}))
+	assert.True(t, argonUser.VerifyPassword("password123"))
+}
+
+func TestPasswordHasher_LegacyUserWithoutAlgorithm(t *testing.T) {
+	user := &User{}
+	require.NoError(t, user.SetPassword("password123"))
+
+	user.PasswordAlgorithm = ""
+	assert.True(t, user.VerifyPassword("password123"))
+}
+
+func TestUser_Validate_UnknownPasswordAlgorithm(t *testing.T) {
+	user := (&Factory{}).User()
+	user.PasswordAlgorithm = "md5"
+
+	assert.ErrorIs(t, user.Validate(), ErrUserValidation)
+}
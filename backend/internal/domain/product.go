@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"shared/idgen"
 )
 
 type Product struct {
@@ -16,20 +18,27 @@ type Product struct {
 	Description string
 	Tags        []string
 	Quantity    int
+	Price       int // in minor currency units
+	Version     int // starts at 1 and grows with every change, see UpdateProductRequest.Version
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	DeletedAt   *time.Time
 }
 
 func (p *Product) Validate() error {
 	if p.Id == uuid.Nil {
-		p.Id = uuid.New()
+		p.Id = idgen.New()
 	}
 
 	if p.CreatedAt.IsZero() {
-		p.CreatedAt = time.Now()
+		p.CreatedAt = Now()
 	}
 
-	p.UpdatedAt = time.Now()
+	p.UpdatedAt = Now()
+
+	if p.Version == 0 {
+		p.Version = 1
+	}
 
 	if strings.TrimSpace(p.Description) == "" {
 		return fmt.Errorf("%w: description is required", ErrProductValidation)
@@ -39,6 +48,10 @@ func (p *Product) Validate() error {
 		return fmt.Errorf("%w: quantity cannot be negative", ErrProductValidation)
 	}
 
+	if p.Price < 0 {
+		return fmt.Errorf("%w: price cannot be negative", ErrProductValidation)
+	}
+
 	// Clean up tags
 	cleanTags := make([]string, 0, len(p.Tags))
 	for _, tag := range p.Tags {
@@ -52,6 +65,10 @@ func (p *Product) Validate() error {
 	return nil
 }
 
+func (p *Product) IsDeleted() bool {
+	return p.DeletedAt != nil
+}
+
 func (p *Product) IsAvailable() bool {
 	return p.Quantity > 0
 }
@@ -66,7 +83,7 @@ func (p *Product) ReserveQuantity(quantity int) error {
 	}
 
 	p.Quantity -= quantity
-	p.UpdatedAt = time.Now()
+	p.UpdatedAt = Now()
 	return nil
 }
 
@@ -76,7 +93,7 @@ func (p *Product) RestoreQuantity(quantity int) error {
 	}
 
 	p.Quantity += quantity
-	p.UpdatedAt = time.Now()
+	p.UpdatedAt = Now()
 	return nil
 }
 
@@ -84,18 +101,25 @@ type CreateProductRequest struct {
 	Description string
 	Tags        []string
 	Quantity    int
+	Price       int
 }
 
 func (r *CreateProductRequest) Validate() error {
+	errs := NewValidationError(ErrProductValidation)
+
 	if strings.TrimSpace(r.Description) == "" {
-		return fmt.Errorf("%w: description is required", ErrProductValidation)
+		errs.Add("description", "description is required")
 	}
 
 	if r.Quantity < 0 {
-		return fmt.Errorf("%w: quantity cannot be negative", ErrProductValidation)
+		errs.Add("quantity", "quantity cannot be negative")
 	}
 
-	return nil
+	if r.Price < 0 {
+		errs.Add("price", "price cannot be negative")
+	}
+
+	return errs.Err()
 }
 
 func (r *CreateProductRequest) ToDomain() (*Product, error) {
@@ -107,6 +131,7 @@ func (r *CreateProductRequest) ToDomain() (*Product, error) {
 		Description: strings.TrimSpace(r.Description),
 		Tags:        r.Tags,
 		Quantity:    r.Quantity,
+		Price:       r.Price,
 	}
 
 	return product, nil
@@ -117,6 +142,10 @@ type UpdateProductRequest struct {
 	Description *string
 	Tags        []string
 	Quantity    *int
+	Price       *int
+	// Version, when set, makes the update apply only to a product still at this version and fail with
+	// ErrVersionConflict otherwise, so concurrent edits cannot silently overwrite each other
+	Version *int
 }
 
 func (r *UpdateProductRequest) Validate() error {
@@ -132,31 +161,90 @@ func (r *UpdateProductRequest) Validate() error {
 		return fmt.Errorf("%w: quantity cannot be negative", ErrProductValidation)
 	}
 
+	if r.Price != nil && *r.Price < 0 {
+		return fmt.Errorf("%w: price cannot be negative", ErrProductValidation)
+	}
+
+	if r.Version != nil && *r.Version < 1 {
+		return fmt.Errorf("%w: version must be positive", ErrProductValidation)
+	}
+
 	return nil
 }
 
+// ProductImportResult is the outcome of importing one product; Product is nil when Err is set
+type ProductImportResult struct {
+	Product *Product
+	Err     error
+}
+
+// RestockProductRequest adds stock to a product, making it available again if it had run out
+type RestockProductRequest struct {
+	Id       uuid.UUID
+	Quantity int
+}
+
+func (r *RestockProductRequest) Validate() error {
+	errs := NewValidationError(ErrProductValidation)
+
+	if r.Quantity <= 0 {
+		errs.Add("quantity", "quantity must be positive")
+	}
+
+	return errs.Err()
+}
+
+// ProductSort is a column products can be listed by
+type ProductSort string
+
+const (
+	ProductSortCreatedAt ProductSort = sortCreatedAt
+	ProductSortPrice     ProductSort = "price"
+	// ProductSortRelevance ranks the full-text matches of GetProductsRequest.Search
+	ProductSortRelevance ProductSort = "relevance"
+)
+
+// ParseProductSort checks s against the sortable columns, an empty s means the default created_at
+func ParseProductSort(s string) (ProductSort, error) {
+	return parseSort(s, ProductSortCreatedAt, ProductSortPrice, ProductSortRelevance)
+}
+
 type GetProductsRequest struct {
-	Ids       []uuid.UUID
-	Tags      []string
-	Available *bool
-	Limit     int
-	Offset    int
+	Ids            []uuid.UUID
+	IncludeDeleted bool
+	Tags           []string
+	Available      *bool
+	MaxQuantity    *int   // low-stock threshold, inclusive
+	MinPrice       *int   // inclusive
+	MaxPrice       *int   // inclusive
+	Search         string // words or a case-insensitive part of the description, words of the tags
+	Sort           ProductSort
+	Order          SortOrder
+	After          *Cursor // keyset pagination, takes precedence over Offset; only for the default order
+	Limit          int
+	Offset         int
 }
 
 func (r *GetProductsRequest) Validate() {
-	if r.Limit <= 0 {
-		r.Limit = 10
-	}
-	if r.Limit > 100 {
-		r.Limit = 100
-	}
+	r.Limit = currentConfig().PageSize.limit(r.Limit, len(r.Ids))
 	if r.Offset < 0 {
 		r.Offset = 0
 	}
+	if r.Sort == "" {
+		r.Sort = ProductSortCreatedAt
+	}
+	if r.Order == "" {
+		r.Order = SortOrderDesc
+	}
+}
+
+// DefaultOrder reports whether the products are listed newest first, the only order cursors can page
+func (r *GetProductsRequest) DefaultOrder() bool {
+	return defaultOrder(r.Sort, r.Order)
 }
 
 func (r *GetProductsRequest) CacheKey() CacheKey {
-	buf := make([]byte, 0)
+	buf := newCacheKeyBuffer(productCacheKeyPrefix)
 
 	// ids
 	buf = binary.BigEndian.AppendUint32(buf, uint32(len(r.Ids)))
@@ -164,10 +252,17 @@ func (r *GetProductsRequest) CacheKey() CacheKey {
 		buf = append(buf, id[:]...)
 	}
 
+	// deleted filter
+	if r.IncludeDeleted {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+
 	// tags
 	buf = binary.BigEndian.AppendUint32(buf, uint32(len(r.Tags)))
 	for _, tag := range r.Tags {
-		buf = append(buf, []byte(tag)...)
+		buf = appendCacheKeyString(buf, tag)
 	}
 
 	// available filter
@@ -181,23 +276,79 @@ func (r *GetProductsRequest) CacheKey() CacheKey {
 		buf = append(buf, 2) // nil case
 	}
 
+	// max quantity filter
+	if r.MaxQuantity != nil {
+		buf = append(buf, 1)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(*r.MaxQuantity))
+	} else {
+		buf = append(buf, 0)
+	}
+
+	// price range
+	for _, price := range []*int{r.MinPrice, r.MaxPrice} {
+		if price != nil {
+			buf = append(buf, 1)
+			buf = binary.BigEndian.AppendUint64(buf, uint64(*price))
+		} else {
+			buf = append(buf, 0)
+		}
+	}
+
+	// description search
+	buf = appendCacheKeyString(buf, r.Search)
+
+	// ordering
+	buf = appendCacheKeyString(buf, string(r.Sort))
+	buf = appendCacheKeyString(buf, string(r.Order))
+
 	// pagination
+	buf = r.After.appendCacheKey(buf)
 	buf = binary.BigEndian.AppendUint32(buf, uint32(r.Limit))
 	buf = binary.BigEndian.AppendUint32(buf, uint32(r.Offset))
 
 	return sha256.Sum256(buf)
 }
 
+// ProductTagCount is a tag and the number of products carrying it
+type ProductTagCount struct {
+	Tag   string
+	Count int
+}
+
 type ProductStorage interface {
 	CreateProduct(ctx context.Context, product *Product) error
+	// UpsertProduct stores product unless a product that is not deleted has the same description,
+	// in which case that one is returned unchanged; created reports which of the two happened
+	UpsertProduct(ctx context.Context, product *Product) (stored *Product, created bool, err error)
 	UpdateProduct(ctx context.Context, req *UpdateProductRequest) (*Product, error)
+	// AdjustQuantity atomically adds delta to the product's stock and returns the updated product,
+	// failing with an InsufficientStockError when the stock would go negative
+	AdjustQuantity(ctx context.Context, id uuid.UUID, delta int) (*Product, error)
 	Products(ctx context.Context, req *GetProductsRequest) ([]*Product, error)
 	CountProducts(ctx context.Context, req *GetProductsRequest) (int, error)
+	// TagCounts lists the distinct tags of the products matching req's filters with the number of products
+	// carrying each, most used first; pagination is ignored
+	TagCounts(ctx context.Context, req *GetProductsRequest) ([]*ProductTagCount, error)
+	// DeleteProduct soft-deletes a product, hiding it from the catalog while orders keep their snapshots
+	DeleteProduct(ctx context.Context, id uuid.UUID) error
+	CacheStats() CacheStats
+	// Close stops background cache maintenance
+	Close()
 }
 
 type ProductAppService interface {
 	CreateProduct(ctx context.Context, req *CreateProductRequest) (*Product, error)
+	// UpsertProduct finds the product with the request's description or creates it from the request
+	UpsertProduct(ctx context.Context, req *CreateProductRequest) (product *Product, created bool, err error)
 	UpdateProduct(ctx context.Context, req *UpdateProductRequest) (*Product, error)
+	RestockProduct(ctx context.Context, req *RestockProductRequest) (*Product, error)
+	// ImportProducts creates the valid products of a batch in one transaction and reports every request's
+	// outcome in order; invalid requests and rows the storage rejects, like a taken description, do not
+	// stop the others. Any other storage failure fails the whole batch.
+	ImportProducts(ctx context.Context, reqs []*CreateProductRequest) []*ProductImportResult
 	Products(ctx context.Context, req *GetProductsRequest) ([]*Product, error)
 	CountProducts(ctx context.Context, req *GetProductsRequest) (int, error)
+	// TagCounts reports the distinct tags of the filtered products with their product counts
+	TagCounts(ctx context.Context, req *GetProductsRequest) ([]*ProductTagCount, error)
+	DeleteProduct(ctx context.Context, id uuid.UUID) error
 }
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProductsRequest_CacheKey(t *testing.T) {
+	zero, five, six := 0, 5, 6
+
+	tests := []struct {
+		name        string
+		request1    *GetProductsRequest
+		request2    *GetProductsRequest
+		shouldEqual bool
+	}{
+		{
+			name:        "identical max quantity has same cache key",
+			request1:    &GetProductsRequest{MaxQuantity: &five, Limit: 10},
+			request2:    &GetProductsRequest{MaxQuantity: &five, Limit: 10},
+			shouldEqual: true,
+		},
+		{
+			name:        "different max quantity has different cache keys",
+			request1:    &GetProductsRequest{MaxQuantity: &five, Limit: 10},
+			request2:    &GetProductsRequest{MaxQuantity: &six, Limit: 10},
+			shouldEqual: false,
+		},
+		{
+			name:        "zero max quantity differs from no filter",
+			request1:    &GetProductsRequest{MaxQuantity: &zero, Limit: 10},
+			request2:    &GetProductsRequest{Limit: 10},
+			shouldEqual: false,
+		},
+		{
+			name:        "different min price has different cache keys",
+			request1:    &GetProductsRequest{MinPrice: &five, Limit: 10},
+			request2:    &GetProductsRequest{MinPrice: &six, Limit: 10},
+			shouldEqual: false,
+		},
+		{
+			name:        "min price differs from max price",
+			request1:    &GetProductsRequest{MinPrice: &five, Limit: 10},
+			request2:    &GetProductsRequest{MaxPrice: &five, Limit: 10},
+			shouldEqual: false,
+		},
+		{
+			name:        "including deleted differs from the default",
+			request1:    &GetProductsRequest{IncludeDeleted: true, Limit: 10},
+			request2:    &GetProductsRequest{Limit: 10},
+			shouldEqual: false,
+		},
+		{
+			name:        "different searches have different cache keys",
+			request1:    &GetProductsRequest{Search: "phone", Limit: 10},
+			request2:    &GetProductsRequest{Search: "case", Limit: 10},
+			shouldEqual: false,
+		},
+		{
+			name:        "search differs from no search",
+			request1:    &GetProductsRequest{Search: "phone", Limit: 10},
+			request2:    &GetProductsRequest{Limit: 10},
+			shouldEqual: false,
+		},
+		{
+			name:        "different sort columns have different cache keys",
+			request1:    &GetProductsRequest{Sort: ProductSortPrice, Order: SortOrderAsc, Limit: 10},
+			request2:    &GetProductsRequest{Sort: ProductSortCreatedAt, Order: SortOrderAsc, Limit: 10},
+			shouldEqual: false,
+		},
+		{
+			name:        "different sort orders have different cache keys",
+			request1:    &GetProductsRequest{Sort: ProductSortPrice, Order: SortOrderAsc, Limit: 10},
+			request2:    &GetProductsRequest{Sort: ProductSortPrice, Order: SortOrderDesc, Limit: 10},
+			shouldEqual: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key1 := tt.request1.CacheKey()
+			key2 := tt.request2.CacheKey()
+
+			if tt.shouldEqual {
+				assert.Equal(t, key1, key2)
+			} else {
+				assert.NotEqual(t, key1, key2)
+			}
+		})
+	}
+}
+
+func TestCreateProductRequest_Validate_ReportsAllFields(t *testing.T) {
+	err := (&CreateProductRequest{Description: "  ", Quantity: -1}).Validate()
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.ErrorIs(t, err, ErrProductValidation)
+	assert.Equal(t, map[string]string{
+		"description": "description is required",
+		"quantity":    "quantity cannot be negative",
+	}, validationErr.Fields)
+
+	assert.NoError(t, (&CreateProductRequest{Description: "Phone", Quantity: 0}).Validate())
+}
+
+func TestParseProductSort(t *testing.T) {
+	sort, err := ParseProductSort("")
+	require.NoError(t, err)
+	assert.Equal(t, ProductSortCreatedAt, sort)
+
+	sort, err = ParseProductSort("price")
+	require.NoError(t, err)
+	assert.Equal(t, ProductSortPrice, sort)
+
+	_, err = ParseProductSort("quantity")
+	assert.ErrorIs(t, err, ErrInvalidSort)
+
+	_, err = ParseSortOrder("sideways")
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "sync/atomic"
+
+// ReadOnlyMode makes the storages reject writes with ErrReadOnly while it is on, so the service keeps
+// serving reads, e.g. from a replica, while the primary database fails over. Unlike maintenance mode,
+// which turns mutating requests away at the transport, it guards the storages themselves.
+// A nil mode is never on.
+type ReadOnlyMode struct {
+	enabled atomic.Bool
+}
+
+func NewReadOnlyMode(enabled bool) *ReadOnlyMode {
+	mode := &ReadOnlyMode{}
+	mode.enabled.Store(enabled)
+	return mode
+}
+
+func (m *ReadOnlyMode) Enabled() bool {
+	return m != nil && m.enabled.Load()
+}
+
+// Set switches the mode and reports whether it was on before, a nil mode stays off
+func (m *ReadOnlyMode) Set(enabled bool) bool {
+	if m == nil {
+		return false
+	}
+	return m.enabled.Swap(enabled)
+}
+
+// CheckWrite fails with ErrReadOnly while the mode is on
+func (m *ReadOnlyMode) CheckWrite() error {
+	if m.Enabled() {
+		return ErrReadOnly
+	}
+	return nil
+}
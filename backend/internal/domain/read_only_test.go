@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyMode_Nil(t *testing.T) {
+	var mode *ReadOnlyMode
+
+	assert.False(t, mode.Set(true))
+	assert.False(t, mode.Enabled())
+	assert.NoError(t, mode.CheckWrite())
+}
+
+func TestReadOnlyMode_Set(t *testing.T) {
+	mode := NewReadOnlyMode(false)
+
+	assert.False(t, mode.Set(true))
+	assert.ErrorIs(t, mode.CheckWrite(), ErrReadOnly)
+	assert.True(t, mode.Set(false))
+	assert.NoError(t, mode.CheckWrite())
+}
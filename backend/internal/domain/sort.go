@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortOrder is the direction of a list ordering
+type SortOrder string
+
+const (
+	SortOrderAsc  SortOrder = "asc"
+	SortOrderDesc SortOrder = "desc"
+)
+
+// ParseSortOrder accepts asc and desc, an empty s means the default descending order
+func ParseSortOrder(s string) (SortOrder, error) {
+	switch order := SortOrder(s); order {
+	case "":
+		return SortOrderDesc, nil
+	case SortOrderAsc, SortOrderDesc:
+		return order, nil
+	default:
+		return "", fmt.Errorf("%w: unknown order %q, must be asc or desc", ErrInvalidSort, s)
+	}
+}
+
+// sortCreatedAt is the column every list is sorted by unless asked otherwise
+const sortCreatedAt = "created_at"
+
+// parseSort checks s against the sortable columns, an empty s means the default created_at
+func parseSort[S ~string](s string, columns ...S) (S, error) {
+	if s == "" {
+		return sortCreatedAt, nil
+	}
+
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if S(s) == column {
+			return column, nil
+		}
+		names = append(names, string(column))
+	}
+
+	return "", fmt.Errorf("%w: unknown sort column %q, must be one of %s", ErrInvalidSort, s, strings.Join(names, ", "))
+}
+
+// defaultOrder reports whether a list is sorted newest first, the only order cursors can page
+func defaultOrder[S ~string](sort S, order SortOrder) bool {
+	return (sort == "" || sort == sortCreatedAt) && (order == "" || order == SortOrderDesc)
+}
@@ -9,12 +9,14 @@ import (
 type Factory struct{}
 
 func (f *Factory) User() *User {
+	id := uuid.New()
 	user := &User{
-		Id:        uuid.New(),
+		Id:        id,
 		FirstName: "John",
 		LastName:  "Doe",
 		Age:       25,
 		IsMarried: false,
+		Email:     "john.doe." + id.String()[:8] + "@example.com", // emails of live users must be unique
 		CreatedAt: time.Now(),
 	}
 
@@ -29,11 +31,13 @@ func (f *Factory) UserWithAge(age int) *User {
 }
 
 func (f *Factory) Product() *Product {
+	id := uuid.New()
 	return &Product{
-		Id:          uuid.New(),
-		Description: "Test Product",
+		Id:          id,
+		Description: "Test Product " + id.String()[:8], // descriptions must be unique
 		Tags:        []string{"tag1", "tag2"},
 		Quantity:    100,
+		Price:       1000,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -69,12 +73,13 @@ func (f *Factory) Order(userId uuid.UUID, productIds ...uuid.UUID) *Order {
 	}
 
 	return &Order{
-		Id:        uuid.New(),
-		UserId:    userId,
-		Status:    OrderStatusPending,
-		Items:     items,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Id:            uuid.New(),
+		UserId:        userId,
+		Status:        OrderStatusPending,
+		Items:         items,
+		TotalQuantity: len(items),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
 	}
 }
 
@@ -104,7 +109,7 @@ func (f *Factory) CreateUserRequest() *CreateUserRequest {
 
 func (f *Factory) CreateProductRequest() *CreateProductRequest {
 	return &CreateProductRequest{
-		Description: "Test Product",
+		Description: "Test Product " + uuid.NewString()[:8],
 		Tags:        []string{"tag1", "tag2"},
 		Quantity:    100,
 	}
@@ -0,0 +1,31 @@
+package domain
+
+import "context"
+
+// TxStorages are storages bound to the transaction of a unit of work
+type TxStorages struct {
+	Users    UserStorage
+	Products ProductStorage
+	Orders   OrderStorage
+}
+
+type txStoragesKey struct{}
+
+// ContextWithTxStorages records the storages of the transaction a request runs in
+func ContextWithTxStorages(ctx context.Context, tx *TxStorages) context.Context {
+	return context.WithValue(ctx, txStoragesKey{}, tx)
+}
+
+// TxStoragesFromContext returns the storages of the transaction a request runs in, if any
+func TxStoragesFromContext(ctx context.Context) (*TxStorages, bool) {
+	tx, ok := ctx.Value(txStoragesKey{}).(*TxStorages)
+	return tx, ok
+}
+
+// UnitOfWork runs operations spanning several storages in a single transaction
+type UnitOfWork interface {
+	// Do commits when fn returns nil and rolls everything back otherwise.
+	// fn may run more than once when the transaction hits a transient error.
+	// Inside a request transaction (see TxStoragesFromContext) fn joins it instead.
+	Do(ctx context.Context, fn func(ctx context.Context, tx *TxStorages) error) error
+}
@@ -2,39 +2,54 @@ package domain
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
-)
 
-type CacheKey = [sha256.Size]byte
+	"shared/idgen"
+)
 
 var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 
+// Roles grant access to endpoints; every user has RoleUser, admins are granted RoleAdmin in the database
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	Id           uuid.UUID
-	FirstName    string
-	LastName     string
-	Age          int
-	IsMarried    bool
-	PasswordHash []byte
-	Salt         []byte
-	CreatedAt    time.Time
+	Id                uuid.UUID
+	FirstName         string
+	LastName          string
+	Age               int
+	IsMarried         bool
+	Email             string
+	EmailVerified     bool
+	VerificationToken string // issued to be mailed, the storages keep only its hash and never load it back
+	PasswordHash      []byte
+	PasswordAlgorithm string
+	Salt              []byte
+	Roles             []string
+	CreatedAt         time.Time
+	DeletedAt         *time.Time
 }
 
 func (u *User) Validate() error {
 	if u.Id == uuid.Nil {
-		u.Id = uuid.New()
+		u.Id = idgen.New()
 	}
 
 	if u.CreatedAt.IsZero() {
-		u.CreatedAt = time.Now()
+		u.CreatedAt = Now()
 	}
 
 	if strings.TrimSpace(u.FirstName) == "" {
@@ -45,8 +60,13 @@ func (u *User) Validate() error {
 		return fmt.Errorf("%w: last name is required", ErrUserValidation)
 	}
 
-	if u.Age < 18 {
-		return fmt.Errorf("%w: user must be at least 18 years old", ErrUserValidation)
+	if message := currentConfig().UserPolicy.checkAge(u.Age); message != "" {
+		return fmt.Errorf("%w: %s", ErrUserValidation, message)
+	}
+
+	// users registered before emails were collected have none
+	if u.Email != "" && !emailRegex.MatchString(u.Email) {
+		return fmt.Errorf("%w: invalid email address", ErrUserValidation)
 	}
 
 	if len(u.PasswordHash) == 0 {
@@ -57,37 +77,84 @@ func (u *User) Validate() error {
 		return fmt.Errorf("%w: salt is required", ErrUserValidation)
 	}
 
+	if u.PasswordAlgorithm == "" {
+		u.PasswordAlgorithm = PasswordAlgorithmBcrypt
+	}
+
+	if _, ok := passwordHasherFor(u.PasswordAlgorithm); !ok {
+		return fmt.Errorf("%w: unknown password algorithm %s", ErrUserValidation, u.PasswordAlgorithm)
+	}
+
+	if len(u.Roles) == 0 {
+		u.Roles = []string{RoleUser}
+	}
+
+	for _, role := range u.Roles {
+		if role != RoleUser && role != RoleAdmin {
+			return fmt.Errorf("%w: unknown role %s", ErrUserValidation, role)
+		}
+	}
+
 	return nil
 }
 
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt != nil
+}
+
+func (u *User) HasRole(role string) bool {
+	return slices.Contains(u.Roles, role)
+}
+
 func (u *User) FullName() string {
 	return strings.TrimSpace(u.FirstName + " " + u.LastName)
 }
 
+// StartEmailVerification marks the email unverified and issues a new verification token
+func (u *User) StartEmailVerification() error {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+
+	u.EmailVerified = false
+	u.VerificationToken = hex.EncodeToString(token)
+	return nil
+}
+
+// HashVerificationToken is how verification tokens are stored and looked up
+func HashVerificationToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
 func (u *User) SetPassword(password string) error {
-	if len(password) < 8 {
-		return fmt.Errorf("%w: password must be at least 8 characters long", ErrUserValidation)
+	if message := currentConfig().UserPolicy.checkPassword(password); message != "" {
+		return fmt.Errorf("%w: %s", ErrUserValidation, message)
 	}
 
 	// Generate salt
 	salt := uuid.New()
-	u.Salt = salt[:]
 
 	// Hash password with salt
-	saltedPassword := append([]byte(password), u.Salt...)
-	hash, err := bcrypt.GenerateFromPassword(saltedPassword, bcrypt.DefaultCost)
+	hasher := currentConfig().PasswordHasher
+	hash, err := hasher.Hash([]byte(password), salt[:])
 	if err != nil {
 		return fmt.Errorf("%w: failed to hash password: %v", ErrUserValidation, err)
 	}
 
+	u.Salt = salt[:]
 	u.PasswordHash = hash
+	u.PasswordAlgorithm = hasher.Algorithm()
 	return nil
 }
 
 func (u *User) VerifyPassword(password string) bool {
-	saltedPassword := append([]byte(password), u.Salt...)
-	err := bcrypt.CompareHashAndPassword(u.PasswordHash, saltedPassword)
-	return err == nil
+	hasher, ok := passwordHasherFor(u.PasswordAlgorithm)
+	if !ok {
+		return false
+	}
+	return hasher.Verify(u.PasswordHash, []byte(password), u.Salt)
 }
 
 type CreateUserRequest struct {
@@ -95,27 +162,36 @@ type CreateUserRequest struct {
 	LastName  string
 	Age       int
 	IsMarried bool
+	Email     string
 	Password  string
 }
 
 func (r *CreateUserRequest) Validate() error {
+	errs := NewValidationError(ErrUserValidation)
+
 	if strings.TrimSpace(r.FirstName) == "" {
-		return fmt.Errorf("%w: first name is required", ErrUserValidation)
+		errs.Add("first_name", "first name is required")
 	}
 
 	if strings.TrimSpace(r.LastName) == "" {
-		return fmt.Errorf("%w: last name is required", ErrUserValidation)
+		errs.Add("last_name", "last name is required")
 	}
 
-	if r.Age < 18 {
-		return fmt.Errorf("%w: user must be at least 18 years old", ErrUserValidation)
+	if message := currentConfig().UserPolicy.checkAge(r.Age); message != "" {
+		errs.Add("age", message)
 	}
 
-	if len(r.Password) < 8 {
-		return fmt.Errorf("%w: password must be at least 8 characters long", ErrUserValidation)
+	if email := strings.TrimSpace(r.Email); email == "" {
+		errs.Add("email", "email is required")
+	} else if !emailRegex.MatchString(email) {
+		errs.Add("email", "invalid email address")
 	}
 
-	return nil
+	if message := currentConfig().UserPolicy.checkPassword(r.Password); message != "" {
+		errs.Add("password", message)
+	}
+
+	return errs.Err()
 }
 
 func (r *CreateUserRequest) ToDomain() (*User, error) {
@@ -128,35 +204,91 @@ func (r *CreateUserRequest) ToDomain() (*User, error) {
 		LastName:  strings.TrimSpace(r.LastName),
 		Age:       r.Age,
 		IsMarried: r.IsMarried,
+		Email:     strings.TrimSpace(r.Email),
+		Roles:     []string{RoleUser},
 	}
 
 	if err := user.SetPassword(r.Password); err != nil {
 		return nil, err
 	}
 
+	if err := user.StartEmailVerification(); err != nil {
+		return nil, err
+	}
+
 	return user, nil
 }
 
-type GetUsersRequest struct {
-	Ids    []uuid.UUID
-	Limit  int
-	Offset int
+// MaxBulkUsers bounds a bulk registration, every user's password is hashed before anything is stored
+const MaxBulkUsers = 100
+
+// UserBatchField names field of the i-th user of a bulk registration the way the request nests it, e.g. users[2].email
+func UserBatchField(i int, field string) string {
+	return fmt.Sprintf("users[%d].%s", i, field)
 }
 
-func (r *GetUsersRequest) Validate() {
-	if r.Limit <= 0 {
-		r.Limit = 10
+// ValidateCreateUserRequests checks every user of a bulk registration at once,
+// keying the problems by position, see UserBatchField
+func ValidateCreateUserRequests(reqs []*CreateUserRequest) error {
+	errs := NewValidationError(ErrUserValidation)
+
+	if len(reqs) == 0 {
+		errs.Add("users", "at least one user is required")
+	} else if len(reqs) > MaxBulkUsers {
+		errs.Add("users", fmt.Sprintf("at most %d users can be registered at once", MaxBulkUsers))
 	}
-	if r.Limit > 100 {
-		r.Limit = 100
+
+	for i, req := range reqs {
+		var reqErrs *ValidationError
+		if errors.As(req.Validate(), &reqErrs) {
+			errs.addAll(UserBatchField(i, ""), reqErrs)
+		}
 	}
+
+	return errs.Err()
+}
+
+// UserSort is a column users can be listed by
+type UserSort string
+
+const (
+	UserSortCreatedAt UserSort = sortCreatedAt
+	UserSortName      UserSort = "name" // "first_name last_name"
+	UserSortAge       UserSort = "age"
+)
+
+// ParseUserSort checks s against the sortable columns, an empty s means the default created_at
+func ParseUserSort(s string) (UserSort, error) {
+	return parseSort(s, UserSortCreatedAt, UserSortName, UserSortAge)
+}
+
+type GetUsersRequest struct {
+	Ids            []uuid.UUID
+	IncludeDeleted bool
+	CreatedFrom    *time.Time // registration window, inclusive on both ends
+	CreatedTo      *time.Time
+	Name           string // case-insensitive substring of "first_name last_name"
+	Sort           UserSort
+	Order          SortOrder
+	Limit          int
+	Offset         int
+}
+
+func (r *GetUsersRequest) Validate() {
+	r.Limit = currentConfig().PageSize.limit(r.Limit, len(r.Ids))
 	if r.Offset < 0 {
 		r.Offset = 0
 	}
+	if r.Sort == "" {
+		r.Sort = UserSortCreatedAt
+	}
+	if r.Order == "" {
+		r.Order = SortOrderDesc
+	}
 }
 
 func (r *GetUsersRequest) CacheKey() CacheKey {
-	buf := make([]byte, 0)
+	buf := newCacheKeyBuffer(userCacheKeyPrefix)
 
 	// ids
 	buf = binary.BigEndian.AppendUint32(buf, uint32(len(r.Ids)))
@@ -164,6 +296,30 @@ func (r *GetUsersRequest) CacheKey() CacheKey {
 		buf = append(buf, id[:]...)
 	}
 
+	// deleted filter
+	if r.IncludeDeleted {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+
+	// created date range
+	for _, bound := range []*time.Time{r.CreatedFrom, r.CreatedTo} {
+		if bound != nil {
+			buf = append(buf, 1)
+			buf = binary.BigEndian.AppendUint64(buf, uint64(bound.UnixMicro()))
+		} else {
+			buf = append(buf, 0)
+		}
+	}
+
+	// name search
+	buf = appendCacheKeyString(buf, r.Name)
+
+	// ordering
+	buf = appendCacheKeyString(buf, string(r.Sort))
+	buf = appendCacheKeyString(buf, string(r.Order))
+
 	// pagination
 	buf = binary.BigEndian.AppendUint32(buf, uint32(r.Limit))
 	buf = binary.BigEndian.AppendUint32(buf, uint32(r.Offset))
@@ -172,13 +328,38 @@ func (r *GetUsersRequest) CacheKey() CacheKey {
 }
 
 type UserStorage interface {
+	// CreateUser fails with ErrUserExists when the id is taken or a live user has the same email, ignoring case;
+	// a taken email is reported as a ValidationError on the email field
 	CreateUser(ctx context.Context, user *User) error
+	// CreateUsers stores all users with a single insert or none of them. A batch may neither repeat an email
+	// nor take the email of a live user, ignoring case: such users fail it with a ValidationError of kind
+	// ErrUserExists keyed by position, see UserBatchField
+	CreateUsers(ctx context.Context, users []*User) error
 	Users(ctx context.Context, req *GetUsersRequest) ([]*User, error)
 	CountUsers(ctx context.Context, req *GetUsersRequest) (int, error)
+	// UsersByIds resolves user references: the result is keyed by id, duplicate ids are looked up once,
+	// unknown ids are absent and soft-deleted users are included. Iterate ids for positional results.
+	UsersByIds(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	// VerifyEmail marks the user holding token as verified and clears the token,
+	// failing with ErrInvalidVerificationToken when no user holds it
+	VerifyEmail(ctx context.Context, token string) (*User, error)
+	// UserByEmail finds the live user with email, ignoring case, failing with ErrUserNotFound
+	UserByEmail(ctx context.Context, email string) (*User, error)
+	// LockUser reads the live user after locking its row until the end of the unit of work, so operations
+	// checking something of the user one at a time can't interleave. It fails with ErrUserNotFound.
+	LockUser(ctx context.Context, id uuid.UUID) (*User, error)
+	CacheStats() CacheStats
+	// Close stops background cache maintenance
+	Close()
 }
 
 type UserAppService interface {
 	RegisterUser(ctx context.Context, req *CreateUserRequest) (*User, error)
+	// RegisterUsers validates the whole batch before hashing any password, then registers all users or none
+	RegisterUsers(ctx context.Context, reqs []*CreateUserRequest) ([]*User, error)
 	Users(ctx context.Context, req *GetUsersRequest) ([]*User, error)
 	CountUsers(ctx context.Context, req *GetUsersRequest) (int, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	VerifyEmail(ctx context.Context, token string) (*User, error)
 }
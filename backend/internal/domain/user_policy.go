@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// UserPolicy holds the deployment-specific registration rules
+type UserPolicy struct {
+	MinAge           int
+	MinPasswordLen   int
+	RequireMixedCase bool // password needs both an upper and a lower case letter
+}
+
+func DefaultUserPolicy() UserPolicy {
+	return UserPolicy{
+		MinAge:         18,
+		MinPasswordLen: 8,
+	}
+}
+
+// withDefaults fills the zero limits with their defaults
+func (p UserPolicy) withDefaults() UserPolicy {
+	defaults := DefaultUserPolicy()
+	if p.MinAge == 0 {
+		p.MinAge = defaults.MinAge
+	}
+	if p.MinPasswordLen == 0 {
+		p.MinPasswordLen = defaults.MinPasswordLen
+	}
+	return p
+}
+
+// checkAge returns the validation message for an age below the minimum, empty if it is allowed
+func (p UserPolicy) checkAge(age int) string {
+	if age < p.MinAge {
+		return fmt.Sprintf("user must be at least %d years old", p.MinAge)
+	}
+	return ""
+}
+
+// checkPassword returns the validation message for a password breaking the policy, empty if it is allowed
+func (p UserPolicy) checkPassword(password string) string {
+	if len(password) < p.MinPasswordLen {
+		return fmt.Sprintf("password must be at least %d characters long", p.MinPasswordLen)
+	}
+
+	if p.RequireMixedCase {
+		var hasUpper, hasLower bool
+		for _, r := range password {
+			hasUpper = hasUpper || unicode.IsUpper(r)
+			hasLower = hasLower || unicode.IsLower(r)
+		}
+		if !hasUpper || !hasLower {
+			return "password must contain both upper and lower case letters"
+		}
+	}
+
+	return ""
+}
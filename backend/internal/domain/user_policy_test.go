@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setUserPolicyForTest(t *testing.T, p UserPolicy) {
+	t.Helper()
+	configureForTest(t, func(c *Config) { c.UserPolicy = p })
+}
+
+func TestUserPolicy_MinAge(t *testing.T) {
+	setUserPolicyForTest(t, UserPolicy{MinAge: 21})
+
+	req := &CreateUserRequest{FirstName: "John", LastName: "Doe", Age: 20, Email: "john@example.com", Password: "password123"}
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, req.Validate(), &validationErr)
+	assert.Equal(t, map[string]string{"age": "user must be at least 21 years old"}, validationErr.Fields)
+
+	user := &User{FirstName: "John", LastName: "Doe", Age: 20}
+	require.NoError(t, user.SetPassword("password123"))
+	assert.ErrorIs(t, user.Validate(), ErrUserValidation)
+
+	req.Age = 21
+	assert.NoError(t, req.Validate())
+}
+
+func TestUserPolicy_RequireMixedCase(t *testing.T) {
+	setUserPolicyForTest(t, UserPolicy{RequireMixedCase: true})
+
+	req := &CreateUserRequest{FirstName: "John", LastName: "Doe", Age: 25, Email: "john@example.com", Password: "password123"}
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, req.Validate(), &validationErr)
+	assert.Equal(t, map[string]string{"password": "password must contain both upper and lower case letters"}, validationErr.Fields)
+
+	assert.ErrorIs(t, (&User{}).SetPassword("PASSWORD123"), ErrUserValidation)
+
+	req.Password = "Password123"
+	assert.NoError(t, req.Validate())
+}
+
+func TestConfigure_UserPolicyDefaults(t *testing.T) {
+	setUserPolicyForTest(t, UserPolicy{RequireMixedCase: true})
+
+	assert.Equal(t, UserPolicy{MinAge: 18, MinPasswordLen: 8, RequireMixedCase: true}, CurrentConfig().UserPolicy)
+}
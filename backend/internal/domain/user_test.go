@@ -3,6 +3,7 @@ package domain
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -341,6 +342,7 @@ func TestCreateUserRequest_Validate(t *testing.T) {
 			request: &CreateUserRequest{
 				FirstName: "John",
 				LastName:  "Doe",
+				Email:     "user@example.com",
 				Age:       25,
 				IsMarried: false,
 				Password:  "password123",
@@ -352,6 +354,7 @@ func TestCreateUserRequest_Validate(t *testing.T) {
 			request: &CreateUserRequest{
 				FirstName: "Jane",
 				LastName:  "Smith",
+				Email:     "user@example.com",
 				Age:       18,
 				IsMarried: true,
 				Password:  "12345678",
@@ -363,6 +366,7 @@ func TestCreateUserRequest_Validate(t *testing.T) {
 			request: &CreateUserRequest{
 				FirstName: "",
 				LastName:  "Doe",
+				Email:     "user@example.com",
 				Age:       25,
 				IsMarried: false,
 				Password:  "password123",
@@ -375,6 +379,7 @@ func TestCreateUserRequest_Validate(t *testing.T) {
 			request: &CreateUserRequest{
 				FirstName: "   ",
 				LastName:  "Doe",
+				Email:     "user@example.com",
 				Age:       25,
 				IsMarried: false,
 				Password:  "password123",
@@ -387,6 +392,7 @@ func TestCreateUserRequest_Validate(t *testing.T) {
 			request: &CreateUserRequest{
 				FirstName: "John",
 				LastName:  "",
+				Email:     "user@example.com",
 				Age:       25,
 				IsMarried: false,
 				Password:  "password123",
@@ -399,6 +405,7 @@ func TestCreateUserRequest_Validate(t *testing.T) {
 			request: &CreateUserRequest{
 				FirstName: "Young",
 				LastName:  "Person",
+				Email:     "user@example.com",
 				Age:       17,
 				IsMarried: false,
 				Password:  "password123",
@@ -411,6 +418,7 @@ func TestCreateUserRequest_Validate(t *testing.T) {
 			request: &CreateUserRequest{
 				FirstName: "John",
 				LastName:  "Doe",
+				Email:     "user@example.com",
 				Age:       25,
 				IsMarried: false,
 				Password:  "1234567", // 7 characters
@@ -436,6 +444,23 @@ func TestCreateUserRequest_Validate(t *testing.T) {
 	}
 }
 
+func TestCreateUserRequest_Validate_ReportsAllFields(t *testing.T) {
+	err := (&CreateUserRequest{FirstName: " ", Age: 17, Email: "not-an-email", Password: "1234567"}).Validate()
+
+	var validationErr *ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.ErrorIs(t, err, ErrUserValidation)
+	assert.Equal(t, map[string]string{
+		"first_name": "first name is required",
+		"last_name":  "last name is required",
+		"age":        "user must be at least 18 years old",
+		"email":      "invalid email address",
+		"password":   "password must be at least 8 characters long",
+	}, validationErr.Fields)
+	assert.Equal(t, "user validation error: first name is required; last name is required; "+
+		"user must be at least 18 years old; invalid email address; password must be at least 8 characters long", err.Error())
+}
+
 func TestCreateUserRequest_ToDomain(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -448,6 +473,7 @@ func TestCreateUserRequest_ToDomain(t *testing.T) {
 			request: &CreateUserRequest{
 				FirstName: "John",
 				LastName:  "Doe",
+				Email:     "user@example.com",
 				Age:       25,
 				IsMarried: false,
 				Password:  "password123",
@@ -459,6 +485,7 @@ func TestCreateUserRequest_ToDomain(t *testing.T) {
 			request: &CreateUserRequest{
 				FirstName: "  Jane  ",
 				LastName:  "  Smith  ",
+				Email:     "user@example.com",
 				Age:       30,
 				IsMarried: true,
 				Password:  "securePassword456",
@@ -470,6 +497,7 @@ func TestCreateUserRequest_ToDomain(t *testing.T) {
 			request: &CreateUserRequest{
 				FirstName: "",
 				LastName:  "Doe",
+				Email:     "user@example.com",
 				Age:       25,
 				IsMarried: false,
 				Password:  "password123",
@@ -503,11 +531,28 @@ func TestCreateUserRequest_ToDomain(t *testing.T) {
 				assert.NotEmpty(t, user.PasswordHash)
 				assert.NotEmpty(t, user.Salt)
 				assert.True(t, user.VerifyPassword(tt.request.Password))
+
+				assert.Equal(t, []string{RoleUser}, user.Roles)
 			}
 		})
 	}
 }
 
+func TestUser_Validate_Roles(t *testing.T) {
+	user := (&Factory{}).User()
+	require.NoError(t, user.Validate())
+	assert.Equal(t, []string{RoleUser}, user.Roles, "users default to the user role")
+	assert.True(t, user.HasRole(RoleUser))
+	assert.False(t, user.HasRole(RoleAdmin))
+
+	user.Roles = []string{RoleUser, RoleAdmin}
+	require.NoError(t, user.Validate())
+	assert.True(t, user.HasRole(RoleAdmin))
+
+	user.Roles = []string{"root"}
+	assert.ErrorIs(t, user.Validate(), ErrUserValidation)
+}
+
 func TestGetUsersRequest_Validate(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -587,6 +632,7 @@ func TestGetUsersRequest_Validate(t *testing.T) {
 func TestGetUsersRequest_CacheKey(t *testing.T) {
 	userId1 := uuid.New()
 	userId2 := uuid.New()
+	rangeBound := time.Now()
 
 	tests := []struct {
 		name        string
@@ -646,6 +692,49 @@ func TestGetUsersRequest_CacheKey(t *testing.T) {
 			},
 			shouldEqual: false,
 		},
+		{
+			name: "include deleted flag changes cache key",
+			request1: &GetUsersRequest{
+				Limit:  10,
+				Offset: 0,
+			},
+			request2: &GetUsersRequest{
+				IncludeDeleted: true,
+				Limit:          10,
+				Offset:         0,
+			},
+			shouldEqual: false,
+		},
+		{
+			name:        "created from and created to are distinct bounds",
+			request1:    &GetUsersRequest{CreatedFrom: &rangeBound, Limit: 10},
+			request2:    &GetUsersRequest{CreatedTo: &rangeBound, Limit: 10},
+			shouldEqual: false,
+		},
+		{
+			name:        "identical date range has same cache key",
+			request1:    &GetUsersRequest{CreatedFrom: &rangeBound, CreatedTo: &rangeBound, Limit: 10},
+			request2:    &GetUsersRequest{CreatedFrom: &rangeBound, CreatedTo: &rangeBound, Limit: 10},
+			shouldEqual: true,
+		},
+		{
+			name:        "different name searches have different cache keys",
+			request1:    &GetUsersRequest{Name: "alex", Limit: 10},
+			request2:    &GetUsersRequest{Name: "alexa", Limit: 10},
+			shouldEqual: false,
+		},
+		{
+			name:        "different sort columns have different cache keys",
+			request1:    &GetUsersRequest{Sort: UserSortCreatedAt, Order: SortOrderDesc, Limit: 10},
+			request2:    &GetUsersRequest{Sort: UserSortAge, Order: SortOrderDesc, Limit: 10},
+			shouldEqual: false,
+		},
+		{
+			name:        "different sort orders have different cache keys",
+			request1:    &GetUsersRequest{Sort: UserSortAge, Order: SortOrderAsc, Limit: 10},
+			request2:    &GetUsersRequest{Sort: UserSortAge, Order: SortOrderDesc, Limit: 10},
+			shouldEqual: false,
+		},
 		{
 			name: "empty requests have same cache key",
 			request1: &GetUsersRequest{
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"strings"
+)
+
+// ValidationError collects every invalid field of a request instead of stopping at the first one.
+// It matches its kind (ErrUserValidation, ErrProductValidation, ...) with errors.Is.
+type ValidationError struct {
+	kind   error
+	fields []string
+	Fields map[string]string // field name -> problem
+}
+
+func NewValidationError(kind error) *ValidationError {
+	return &ValidationError{
+		kind:   kind,
+		Fields: make(map[string]string),
+	}
+}
+
+// Add records a problem with field, keeping the first one reported for it
+func (e *ValidationError) Add(field, message string) {
+	if _, exists := e.Fields[field]; exists {
+		return
+	}
+
+	e.fields = append(e.fields, field)
+	e.Fields[field] = message
+}
+
+// addAll records the problems of other with prefix prepended to their fields
+func (e *ValidationError) addAll(prefix string, other *ValidationError) {
+	for _, field := range other.fields {
+		e.Add(prefix+field, other.Fields[field])
+	}
+}
+
+// Err returns the error if any field was invalid, nil otherwise
+func (e *ValidationError) Err() error {
+	if len(e.fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.fields))
+	for _, field := range e.fields {
+		messages = append(messages, e.Fields[field])
+	}
+
+	return e.kind.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.kind
+}
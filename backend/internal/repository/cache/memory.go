@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+
+	"mts/internal/domain"
+)
+
+func NewMemoryCache() domain.Cache {
+	return &memoryCache{
+		cache: ttlcache.New[string, []byte](),
+	}
+}
+
+// memoryCache keeps values in process memory, so it is only correct for a single instance
+type memoryCache struct {
+	mu    sync.Mutex
+	cache *ttlcache.Cache[string, []byte]
+}
+
+func (c *memoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.cache.DeleteExpired()
+
+	item := c.cache.Get(key)
+	if item == nil {
+		return nil, false, nil
+	}
+	return item.Value(), true, nil
+}
+
+func (c *memoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.cache.Set(key, value, ttl)
+	return nil
+}
+
+func (c *memoryCache) Delete(_ context.Context, key string) error {
+	c.cache.Delete(key)
+	return nil
+}
+
+func (c *memoryCache) Incr(_ context.Context, key string, ttl time.Duration) (int64, time.Duration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache.DeleteExpired()
+
+	var count int64
+	left := ttl
+	if item := c.cache.Get(key); item != nil {
+		if remaining := time.Until(item.ExpiresAt()); remaining > 0 {
+			count = int64(binary.BigEndian.Uint64(item.Value()))
+			left = remaining
+		}
+	}
+	count++
+
+	c.cache.Set(key, binary.BigEndian.AppendUint64(nil, uint64(count)), left)
+	return count, left, nil
+}
+
+func (c *memoryCache) Close() error {
+	c.cache.Stop()
+	return nil
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache_GetSetDelete(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache()
+
+	_, ok, err := c.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, c.Set(ctx, "key", []byte("value"), time.Minute))
+	value, ok, err := c.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value"), value)
+
+	require.NoError(t, c.Delete(ctx, "key"))
+	_, ok, err = c.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestMemoryCache_Incr(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache()
+
+	count, left, err := c.Incr(ctx, "counter", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, 50*time.Millisecond, left)
+
+	count, left, err = c.Incr(ctx, "counter", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.Less(t, left, 50*time.Millisecond)
+
+	// window expired, the counter starts over
+	time.Sleep(60 * time.Millisecond)
+	count, _, err = c.Incr(ctx, "counter", 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
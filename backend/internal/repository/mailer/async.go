@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"mts/internal/domain"
+	"shared"
+)
+
+// asyncSendTimeout bounds a background send, which no longer has a request deadline to stop it
+const asyncSendTimeout = 30 * time.Second
+
+// NewAsyncMailer sends mail through next on the pool's workers, so Send returns once the mail is queued;
+// failed sends are only logged
+func NewAsyncMailer(next domain.Mailer, pool *shared.WorkerPool) domain.Mailer {
+	return &asyncMailer{next: next, pool: pool}
+}
+
+type asyncMailer struct {
+	next domain.Mailer
+	pool *shared.WorkerPool
+}
+
+func (m *asyncMailer) Send(ctx context.Context, mail *domain.Mail) error {
+	return m.pool.Submit(ctx, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, asyncSendTimeout)
+		defer cancel()
+
+		if err := m.next.Send(ctx, mail); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).
+				Str("to", mail.To).
+				Str("subject", mail.Subject).
+				Msg("failed to send mail")
+		}
+	})
+}
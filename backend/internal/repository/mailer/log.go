@@ -0,0 +1,25 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"mts/internal/domain"
+)
+
+// NewLogMailer returns a mailer that only logs messages, for deployments without SMTP. The body is left
+// out of the log, it carries secrets such as verification links.
+func NewLogMailer() domain.Mailer {
+	return logMailer{}
+}
+
+type logMailer struct{}
+
+func (logMailer) Send(ctx context.Context, mail *domain.Mail) error {
+	zerolog.Ctx(ctx).Info().
+		Str("to", mail.To).
+		Str("subject", mail.Subject).
+		Msg("smtp is not configured, mail not sent")
+	return nil
+}
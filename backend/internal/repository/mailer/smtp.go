@@ -0,0 +1,24 @@
+package mailer
+
+import (
+	"context"
+
+	"mts/internal/domain"
+	"shared"
+	sharedConfig "shared/config"
+)
+
+func NewSmtpMailer(cfg *sharedConfig.Smtp) domain.Mailer {
+	return &smtpMailer{cfg: cfg}
+}
+
+type smtpMailer struct {
+	cfg *sharedConfig.Smtp
+}
+
+func (m *smtpMailer) Send(ctx context.Context, mail *domain.Mail) error {
+	ctx, span := tracer.Start(ctx, "SmtpMailer.Send")
+	defer span.End()
+
+	return shared.SendMail(ctx, m.cfg, mail.To, mail.Subject, mail.Body)
+}
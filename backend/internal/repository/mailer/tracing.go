@@ -0,0 +1,5 @@
+package mailer
+
+import "go.opentelemetry.io/otel"
+
+var tracer = otel.Tracer("mts/internal/repository/mailer")
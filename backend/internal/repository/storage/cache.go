@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+	"github.com/rs/zerolog"
+
+	"mts/internal/domain"
+)
+
+const defaultCacheTTL = time.Hour
+
+// CacheOptions configures the result cache of a storage
+type CacheOptions struct {
+	// TTL is how long list results are kept, defaults to an hour
+	TTL time.Duration
+	// IdsTTL is how long results of lookups by ids are kept. Those rarely repeat and would only
+	// churn the cache, so with zero they are not cached at all.
+	IdsTTL time.Duration
+}
+
+func cacheTTLOrDefault(ttl time.Duration) time.Duration {
+	if ttl <= 0 {
+		return defaultCacheTTL
+	}
+	return ttl
+}
+
+// resultTTL picks the TTL for caching a query result, reporting false when it should not be cached
+func (o CacheOptions) resultTTL(byIds bool) (time.Duration, bool) {
+	if !byIds {
+		return ttlcache.DefaultTTL, true
+	}
+	return o.IdsTTL, o.IdsTTL > 0
+}
+
+// resultCache holds the query results of a storage. The in-process memoryResultCache never fails, but
+// a shared backend can be unreachable, so storages go through cachedResult, cacheResult and
+// invalidate, which log its errors and carry on as if the cache were empty.
+type resultCache[V any] interface {
+	Get(ctx context.Context, key domain.CacheKey) (V, bool, error)
+	Set(ctx context.Context, key domain.CacheKey, value V, ttl time.Duration) error
+	DeleteAll(ctx context.Context) error
+	Len() int
+	// Close stops background maintenance
+	Close()
+}
+
+// cachedResult looks key up in cache, a failing cache counts as a miss
+func cachedResult[V any](ctx context.Context, cache resultCache[V], key domain.CacheKey) (V, bool) {
+	value, ok, err := cache.Get(ctx, key)
+	if err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Msg("cache read failed, falling back to the database")
+		var zero V
+		return zero, false
+	}
+	return value, ok
+}
+
+// cacheResult stores value under key, a failing cache only leaves it uncached
+func cacheResult[V any](ctx context.Context, cache resultCache[V], key domain.CacheKey, value V, ttl time.Duration) {
+	if err := cache.Set(ctx, key, value, ttl); err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Msg("cache write failed")
+	}
+}
+
+// invalidate drops every cached result. A failure is logged as an error, since stale results may be
+// served until they expire.
+func invalidate[V any](ctx context.Context, cache resultCache[V]) {
+	if err := cache.DeleteAll(ctx); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("cache invalidation failed")
+	}
+}
+
+// memoryResultCache keeps results in process memory with ttlcache
+type memoryResultCache[V any] struct {
+	cache *ttlcache.Cache[domain.CacheKey, V]
+	// stopCleanup ends the background eviction of expired entries
+	stopCleanup func()
+}
+
+// newMemoryResultCache caches results for ttl, defaulting to an hour. With cleanup expired entries are
+// evicted in the background until Close.
+func newMemoryResultCache[V any](ttl time.Duration, cleanup bool) *memoryResultCache[V] {
+	c := &memoryResultCache[V]{
+		cache:       ttlcache.New[domain.CacheKey, V](ttlcache.WithTTL[domain.CacheKey, V](cacheTTLOrDefault(ttl))),
+		stopCleanup: func() {},
+	}
+	if cleanup {
+		c.stopCleanup = startCacheCleanup(c.cache)
+	}
+	return c
+}
+
+func (c *memoryResultCache[V]) Get(_ context.Context, key domain.CacheKey) (V, bool, error) {
+	if item := c.cache.Get(key); item != nil {
+		return item.Value(), true, nil
+	}
+	var zero V
+	return zero, false, nil
+}
+
+func (c *memoryResultCache[V]) Set(_ context.Context, key domain.CacheKey, value V, ttl time.Duration) error {
+	c.cache.Set(key, value, ttl)
+	return nil
+}
+
+func (c *memoryResultCache[V]) DeleteAll(context.Context) error {
+	c.cache.DeleteAll()
+	return nil
+}
+
+func (c *memoryResultCache[V]) Len() int {
+	return c.cache.Len()
+}
+
+func (c *memoryResultCache[V]) Close() {
+	c.stopCleanup()
+}
+
+// startCacheCleanup runs ttlcache's expiry loop in the background, so reads no longer scan the cache
+// to evict expired entries. The returned func stops the loop and waits for it to exit; it is safe to
+// call more than once.
+func startCacheCleanup[K comparable, V any](cache *ttlcache.Cache[K, V]) func() {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.Start()
+	}()
+
+	return func() {
+		for {
+			// Stop is a no-op until Start has marked the loop as running, so retry until it exits
+			cache.Stop()
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+	"github.com/stretchr/testify/assert"
+
+	"mts/internal/domain"
+)
+
+var errCacheDown = errors.New("cache backend is down")
+
+// failingResultCache stands in for an unreachable cache backend
+type failingResultCache[V any] struct{}
+
+func (failingResultCache[V]) Get(context.Context, domain.CacheKey) (V, bool, error) {
+	var zero V
+	return zero, false, errCacheDown
+}
+
+func (failingResultCache[V]) Set(context.Context, domain.CacheKey, V, time.Duration) error {
+	return errCacheDown
+}
+
+func (failingResultCache[V]) DeleteAll(context.Context) error {
+	return errCacheDown
+}
+
+func (failingResultCache[V]) Len() int {
+	return 0
+}
+
+func (failingResultCache[V]) Close() {}
+
+func TestResultCache_FailuresAreMisses(t *testing.T) {
+	ctx := context.Background()
+	cache := failingResultCache[int]{}
+	key := domain.CacheKey{1}
+
+	// none of the helpers report the failure to the caller
+	cacheResult(ctx, cache, key, 42, time.Minute)
+	invalidate(ctx, cache)
+
+	value, ok := cachedResult(ctx, cache, key)
+	assert.False(t, ok)
+	assert.Zero(t, value)
+}
+
+func TestMemoryResultCache(t *testing.T) {
+	ctx := context.Background()
+	cache := newMemoryResultCache[int](time.Hour, false)
+	defer cache.Close()
+	key := domain.CacheKey{1}
+
+	_, ok := cachedResult(ctx, cache, key)
+	assert.False(t, ok)
+
+	cacheResult(ctx, cache, key, 42, ttlcache.DefaultTTL)
+	value, ok := cachedResult(ctx, cache, key)
+	assert.True(t, ok)
+	assert.Equal(t, 42, value)
+	assert.Equal(t, 1, cache.Len())
+
+	invalidate(ctx, cache)
+	assert.Zero(t, cache.Len())
+}
+
+func TestStartCacheCleanup_EvictsExpired(t *testing.T) {
+	cache := ttlcache.New[int, string](ttlcache.WithTTL[int, string](20 * time.Millisecond))
+	stop := startCacheCleanup(cache)
+	defer stop()
+
+	cache.Set(1, "expiring", ttlcache.DefaultTTL)
+	cache.Set(2, "kept", time.Hour)
+
+	// nothing reads the cache, the background loop alone removes the expired entry
+	assert.Eventually(t, func() bool { return cache.Len() == 1 }, time.Second, 5*time.Millisecond)
+	assert.NotNil(t, cache.Get(2))
+}
+
+func TestStartCacheCleanup_Stop(t *testing.T) {
+	cache := ttlcache.New[int, string]()
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		stop := startCacheCleanup(cache)
+		// stopping right away must not leave the loop running, nor hang when repeated
+		stop()
+		stop()
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("cache cleanup did not stop")
+	}
+}
+
+func TestCacheOptions_ResultTTL(t *testing.T) {
+	ttl, ok := CacheOptions{}.resultTTL(false)
+	assert.True(t, ok)
+	assert.Equal(t, ttlcache.DefaultTTL, ttl)
+
+	// lookups by ids are skipped unless they get a TTL of their own
+	_, ok = CacheOptions{TTL: time.Hour}.resultTTL(true)
+	assert.False(t, ok)
+
+	ttl, ok = CacheOptions{TTL: time.Hour, IdsTTL: time.Minute}.resultTTL(true)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, ttl)
+}
+
+// BenchmarkCacheRead compares concurrent cache hits on the storage read path with the expired entries
+// evicted inline on every read against eviction by the background loop
+func BenchmarkCacheRead(b *testing.B) {
+	const entries = 10_000
+
+	newCache := func() *ttlcache.Cache[int, string] {
+		cache := ttlcache.New[int, string](ttlcache.WithTTL[int, string](time.Hour))
+		for i := range entries {
+			cache.Set(i, "value", ttlcache.DefaultTTL)
+		}
+		return cache
+	}
+
+	b.Run("inline cleanup", func(b *testing.B) {
+		cache := newCache()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				cache.DeleteExpired()
+				cache.Get(i % entries)
+			}
+		})
+	})
+
+	b.Run("background cleanup", func(b *testing.B) {
+		cache := newCache()
+		stop := startCacheCleanup(cache)
+		defer stop()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				cache.Get(i % entries)
+			}
+		})
+	})
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"mts/internal/domain"
+)
+
+const (
+	// pgQueryCanceled is raised when a statement is canceled by the server (e.g. statement_timeout)
+	pgQueryCanceled = "57014"
+	// pgCheckViolation is raised when a row fails a CHECK constraint
+	pgCheckViolation = "23514"
+	// pgUniqueViolation is raised when a row repeats the key of a unique index or primary key
+	pgUniqueViolation = "23505"
+)
+
+// productsQuantityCheck keeps products.quantity from going negative
+const productsQuantityCheck = "products_quantity_check"
+
+// primary keys of the tables whose ids callers may choose
+const (
+	usersPrimaryKey    = "users_pkey"
+	productsPrimaryKey = "products_pkey"
+	ordersPrimaryKey   = "orders_pkey"
+)
+
+// usersEmailKey keeps the emails of live users unique regardless of case, see CreateUser
+const usersEmailKey = "users_email_key"
+
+// productsDescriptionKey keeps descriptions of products that are not deleted unique, see UpsertProduct
+const productsDescriptionKey = "products_description_key"
+
+// classifyError maps context cancellation and query timeouts to domain errors
+// so the transport layer can tell them apart from other failures
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, domain.ErrRequestCanceled) || errors.Is(err, domain.ErrRequestTimeout) {
+		return err
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("%w: %w", domain.ErrRequestCanceled, err)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) {
+		return fmt.Errorf("%w: %w", domain.ErrRequestTimeout, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgQueryCanceled {
+		return fmt.Errorf("%w: %w", domain.ErrRequestTimeout, err)
+	}
+
+	return err
+}
+
+// isCheckViolation reports whether err was caused by the named CHECK constraint
+func isCheckViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgCheckViolation && pgErr.ConstraintName == constraint
+}
+
+// classifyQuantityError reports a negative product quantity rejected by the database as target,
+// which is only reachable when a caller bypassed the domain validation
+func classifyQuantityError(err error, target error) error {
+	if isCheckViolation(err, productsQuantityCheck) {
+		return fmt.Errorf("%w: quantity cannot be negative: %w", target, err)
+	}
+
+	return classifyError(err)
+}
+
+// isUniqueViolation reports whether err was caused by the named unique index or primary key
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation && pgErr.ConstraintName == constraint
+}
+
+// classifyInsertError reports an insert that repeated the primary key, such as a replayed create, as target
+func classifyInsertError(err error, primaryKey string, target error) error {
+	if isUniqueViolation(err, primaryKey) {
+		return fmt.Errorf("%w: %w", target, err)
+	}
+
+	return classifyError(err)
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+
+	"mts/internal/domain"
+)
+
+func TestClassifyError(t *testing.T) {
+	otherErr := errors.New("connection refused")
+
+	tests := []struct {
+		name        string
+		err         error
+		expectedErr error
+	}{
+		{
+			name:        "nil error",
+			err:         nil,
+			expectedErr: nil,
+		},
+		{
+			name:        "context canceled",
+			err:         fmt.Errorf("query: %w", context.Canceled),
+			expectedErr: domain.ErrRequestCanceled,
+		},
+		{
+			name:        "context deadline exceeded",
+			err:         fmt.Errorf("query: %w", context.DeadlineExceeded),
+			expectedErr: domain.ErrRequestTimeout,
+		},
+		{
+			name:        "server side statement timeout",
+			err:         &pgconn.PgError{Code: pgQueryCanceled},
+			expectedErr: domain.ErrRequestTimeout,
+		},
+		{
+			name:        "other error is passed through",
+			err:         otherErr,
+			expectedErr: otherErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(tt.err)
+
+			if tt.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
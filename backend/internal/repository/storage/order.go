@@ -2,45 +2,80 @@ package storage
 
 import (
 	"context"
-	"time"
+	"errors"
+	"fmt"
+	"sync/atomic"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/jellydator/ttlcache/v3"
 
 	"mts/internal/domain"
+	"shared"
+	sharedConfig "shared/config"
+	"shared/idgen"
 )
 
-func NewOrderStorage(pool *pgxpool.Pool) domain.OrderStorage {
+// NewOrderStorage runs writes on pool and reads on replica, falling back to pool when replica is nil;
+// writes fail with domain.ErrReadOnly while readOnly is on
+func NewOrderStorage(pool *pgxpool.Pool, replica *pgxpool.Pool, cache CacheOptions, retry sharedConfig.Retry, readOnly *domain.ReadOnlyMode) domain.OrderStorage {
+	s := newOrderStorage(pool, cache, retry,
+		newMemoryResultCache[[]*domain.Order](cache.TTL, true),
+		newMemoryResultCache[int](cache.TTL, true),
+	)
+	s.replica = replicaQuerier(replica)
+	s.readOnly = readOnly
+	return s
+}
+
+func newOrderStorage(
+	db querier,
+	cache CacheOptions,
+	retry sharedConfig.Retry,
+	results resultCache[[]*domain.Order],
+	counts resultCache[int],
+) *orderStorage {
 	return &orderStorage{
-		pool: pool,
-		psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
-		cache: ttlcache.New[domain.CacheKey, []*domain.Order](
-			ttlcache.WithTTL[domain.CacheKey, []*domain.Order](time.Hour),
-		),
+		db:           db,
+		psql:         sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		retry:        retry,
+		cacheOptions: cache,
+		cache:        results,
+		countCache:   counts,
 	}
 }
 
 type orderStorage struct {
-	pool  *pgxpool.Pool
-	psql  sq.StatementBuilderType
-	cache *ttlcache.Cache[domain.CacheKey, []*domain.Order]
+	db querier
+	// readOnly rejects writes while it is on, nil never does
+	readOnly *domain.ReadOnlyMode
+	// replica serves Orders, CountOrders, StatusCounts and OrderStatusHistory when set, see readQuerier
+	replica    querier
+	psql       sq.StatementBuilderType
+	retry      sharedConfig.Retry
+	cache      resultCache[[]*domain.Order]
+	countCache resultCache[int]
+	// cacheOptions decides which results are cached and for how long
+	cacheOptions CacheOptions
+
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
 }
 
 func (s *orderStorage) CreateOrder(ctx context.Context, order *domain.Order) error {
-	s.cache.DeleteAll()
+	ctx, span := tracer.Start(ctx, "OrderStorage.CreateOrder")
+	defer span.End()
 
-	if err := order.Validate(); err != nil {
+	if err := s.readOnly.CheckWrite(); err != nil {
 		return err
 	}
 
-	// Start transaction
-	tx, err := s.pool.Begin(ctx)
-	if err != nil {
+	s.invalidateCache(ctx)
+
+	if err := order.Validate(); err != nil {
 		return err
 	}
-	defer tx.Rollback(ctx)
 
 	// Insert order
 	orderDto, err := toOrderDto(order)
@@ -48,22 +83,40 @@ func (s *orderStorage) CreateOrder(ctx context.Context, order *domain.Order) err
 		return err
 	}
 
-	orderQuery := s.psql.Insert("orders").
-		Columns("id", "user_id", "status", "created_at", "updated_at").
-		Values(orderDto.Id, orderDto.UserId, orderDto.Status, orderDto.CreatedAt, orderDto.UpdatedAt)
+	// the whole transaction is repeated on serialization failures and dropped connections
+	err = shared.Retry(ctx, s.retry, func(ctx context.Context) error {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
 
-	sql, args, err := orderQuery.ToSql()
-	if err != nil {
-		return err
-	}
+		orderQuery := s.psql.Insert("orders").
+			Columns("id", "user_id", "status", "total_quantity", "created_at", "updated_at").
+			Values(orderDto.Id, orderDto.UserId, orderDto.Status, orderDto.TotalQuantity, orderDto.CreatedAt, orderDto.UpdatedAt)
 
-	_, err = tx.Exec(ctx, sql, args...)
-	if err != nil {
-		return err
-	}
+		sql, args, err := orderQuery.ToSql()
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return err
+		}
+
+		// Insert order items
+		if err = s.insertOrderItems(ctx, tx, order.Items); err != nil {
+			return err
+		}
+
+		return tx.Commit(ctx)
+	})
 
-	// Insert order items
-	for _, item := range order.Items {
+	return classifyInsertError(err, ordersPrimaryKey, domain.ErrOrderExists)
+}
+
+func (s *orderStorage) insertOrderItems(ctx context.Context, tx pgx.Tx, items []*domain.OrderItem) error {
+	for _, item := range items {
 		itemDto, err := toOrderItemDto(item)
 		if err != nil {
 			return err
@@ -80,42 +133,359 @@ func (s *orderStorage) CreateOrder(ctx context.Context, order *domain.Order) err
 
 		_, err = tx.Exec(ctx, sql, args...)
 		if err != nil {
-			return err
+			return classifyError(err)
 		}
 	}
 
-	return tx.Commit(ctx)
+	return nil
 }
 
 func (s *orderStorage) UpdateOrder(ctx context.Context, req *domain.UpdateOrderRequest) (*domain.Order, error) {
-	s.cache.DeleteAll()
+	ctx, span := tracer.Start(ctx, "OrderStorage.UpdateOrder")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return nil, err
+	}
+
+	s.invalidateCache(ctx)
 
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err = s.setOrderStatus(ctx, tx, req.Id, req.Status); err != nil {
+		return nil, err
+	}
+
+	if err = classifyError(tx.Commit(ctx)); err != nil {
+		return nil, err
+	}
+
+	// Get updated order
+	orders, err := s.Orders(withPrimaryReads(ctx), &domain.GetOrdersRequest{
+		Ids:   []uuid.UUID{req.Id},
+		Limit: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(orders) == 0 {
+		return nil, domain.ErrOrderNotFound
+	}
+
+	return orders[0], nil
+}
+
+func (s *orderStorage) UpdateOrderStatuses(ctx context.Context, ids []uuid.UUID, status domain.OrderStatus) error {
+	ctx, span := tracer.Start(ctx, "OrderStorage.UpdateOrderStatuses")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return err
+	}
+
+	s.invalidateCache(ctx)
+
+	if !status.Valid() {
+		return fmt.Errorf("%w: invalid order status %s", domain.ErrOrderValidation, status)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return classifyError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, id := range ids {
+		if err = s.setOrderStatus(ctx, tx, id, status); err != nil {
+			return err
+		}
+	}
+
+	return classifyError(tx.Commit(ctx))
+}
+
+func (s *orderStorage) LockOrder(ctx context.Context, id uuid.UUID) (*domain.Order, error) {
+	orders, err := s.LockOrders(ctx, []uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(orders) == 0 {
+		return nil, domain.ErrOrderNotFound
+	}
+
+	return orders[0], nil
+}
+
+func (s *orderStorage) LockOrders(ctx context.Context, ids []uuid.UUID) ([]*domain.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderStorage.LockOrders")
+	defer span.End()
+
+	// rows are locked in id order, so two units of work locking overlapping orders can't deadlock;
+	// outside a unit of work the locks are released as soon as the statement ends
+	lockQuery := s.psql.Select("id").
+		From("orders").
+		Where(sq.Eq{"id": ids}).
+		OrderBy("id").
+		Suffix("FOR UPDATE")
+
+	sql, args, err := lockQuery.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	locked, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	if len(locked) == 0 {
+		return nil, nil
+	}
+
+	return s.Orders(withPrimaryReads(ctx), &domain.GetOrdersRequest{
+		Ids:   locked,
+		Limit: len(locked),
+	})
+}
+
+// setOrderStatus updates the status inside tx and records the transition in the status history
+func (s *orderStorage) setOrderStatus(ctx context.Context, tx pgx.Tx, id uuid.UUID, status domain.OrderStatus) error {
+	// the row lock keeps concurrent updates from recording the same previous status
+	selectQuery := s.psql.Select("status").
+		From("orders").
+		Where(sq.Eq{"id": id}).
+		Suffix("FOR UPDATE")
+
+	sql, args, err := selectQuery.ToSql()
+	if err != nil {
+		return err
+	}
+
+	var previousStatus string
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&previousStatus); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrOrderNotFound
+		}
+		return classifyError(err)
+	}
+
+	now := domain.Now()
+
 	updateQuery := s.psql.Update("orders").
-		Set("status", req.Status).
-		Set("updated_at", time.Now()).
-		Where(sq.Eq{"id": req.Id})
+		Set("status", status).
+		Set("updated_at", now).
+		Where(sq.Eq{"id": id})
 
-	sql, args, err := updateQuery.ToSql()
+	sql, args, err = updateQuery.ToSql()
 	if err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return classifyError(err)
+	}
+
+	if domain.OrderStatus(previousStatus) != status {
+		change := &domain.OrderStatusChange{
+			Id:         idgen.New(),
+			OrderId:    id,
+			FromStatus: domain.OrderStatus(previousStatus),
+			ToStatus:   status,
+			ChangedAt:  now,
+		}
+		if actorId, ok := domain.ActorFromContext(ctx); ok {
+			change.ActorId = &actorId
+		}
+
+		dto := toOrderStatusChangeDto(change)
+		historyQuery := s.psql.Insert("order_status_history").
+			Columns("id", "order_id", "from_status", "to_status", "actor_id", "changed_at").
+			Values(dto.Id, dto.OrderId, dto.FromStatus, dto.ToStatus, dto.ActorId, dto.ChangedAt)
+
+		sql, args, err = historyQuery.ToSql()
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return classifyError(err)
+		}
+	}
+
+	return nil
+}
+
+func (s *orderStorage) ReplaceOrderItems(ctx context.Context, order *domain.Order) (*domain.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderStorage.ReplaceOrderItems")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
 		return nil, err
 	}
 
-	result, err := s.pool.Exec(ctx, sql, args...)
+	s.invalidateCache(ctx)
+
+	if err := order.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The status guard locks the order row so a concurrent confirmation can't interleave
+	updateQuery := s.psql.Update("orders").
+		Set("total_quantity", order.TotalQuantity).
+		Set("updated_at", order.UpdatedAt).
+		Where(sq.Eq{"id": order.Id, "status": domain.OrderStatusPending})
+
+	sql, args, err := updateQuery.ToSql()
 	if err != nil {
 		return nil, err
 	}
 
+	result, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
 	if result.RowsAffected() == 0 {
+		return nil, fmt.Errorf("%w: only pending orders can change their items", domain.ErrOrderValidation)
+	}
+
+	deleteQuery := s.psql.Delete("order_items").
+		Where(sq.Eq{"order_id": order.Id})
+
+	sql, args, err = deleteQuery.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(ctx, sql, args...); err != nil {
+		return nil, classifyError(err)
+	}
+
+	if err = s.insertOrderItems(ctx, tx, order.Items); err != nil {
+		return nil, err
+	}
+
+	if err = classifyError(tx.Commit(ctx)); err != nil {
+		return nil, err
+	}
+
+	orders, err := s.Orders(withPrimaryReads(ctx), &domain.GetOrdersRequest{
+		Ids:   []uuid.UUID{order.Id},
+		Limit: 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(orders) == 0 {
 		return nil, domain.ErrOrderNotFound
 	}
 
-	// Get updated order
-	orders, err := s.Orders(ctx, &domain.GetOrdersRequest{
-		Ids:   []uuid.UUID{req.Id},
+	return orders[0], nil
+}
+
+func (s *orderStorage) RemoveOrderItem(ctx context.Context, orderId, itemId uuid.UUID) (*domain.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderStorage.RemoveOrderItem")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return nil, err
+	}
+
+	s.invalidateCache(ctx)
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	// the row lock keeps a concurrent status change or item removal from interleaving
+	selectQuery := s.psql.Select("status").
+		From("orders").
+		Where(sq.Eq{"id": orderId}).
+		Suffix("FOR UPDATE")
+
+	sql, args, err := selectQuery.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var status string
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrOrderNotFound
+		}
+		return nil, classifyError(err)
+	}
+
+	if !(&domain.Order{Status: domain.OrderStatus(status)}).CanBeCancelled() {
+		return nil, fmt.Errorf("%w: items of an order in status %s cannot be cancelled", domain.ErrOrderValidation, status)
+	}
+
+	deleteQuery := s.psql.Delete("order_items").
+		Where(sq.Eq{"id": itemId, "order_id": orderId}).
+		Suffix("RETURNING quantity")
+
+	sql, args, err = deleteQuery.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var quantity int
+	if err = tx.QueryRow(ctx, sql, args...).Scan(&quantity); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrOrderItemNotFound
+		}
+		return nil, classifyError(err)
+	}
+
+	updateQuery := s.psql.Update("orders").
+		Set("total_quantity", sq.Expr("total_quantity - ?", quantity)).
+		Set("updated_at", domain.Now()).
+		Where(sq.Eq{"id": orderId}).
+		Where("EXISTS (SELECT 1 FROM order_items WHERE order_id = ?)", orderId)
+
+	sql, args, err = updateQuery.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return nil, fmt.Errorf("%w: the last item cannot be removed, cancel the order instead", domain.ErrOrderValidation)
+	}
+
+	if err = classifyError(tx.Commit(ctx)); err != nil {
+		return nil, err
+	}
+
+	orders, err := s.Orders(withPrimaryReads(ctx), &domain.GetOrdersRequest{
+		Ids:   []uuid.UUID{orderId},
 		Limit: 1,
 	})
 	if err != nil {
@@ -129,43 +499,86 @@ func (s *orderStorage) UpdateOrder(ctx context.Context, req *domain.UpdateOrderR
 	return orders[0], nil
 }
 
-func (s *orderStorage) Orders(ctx context.Context, req *domain.GetOrdersRequest) ([]*domain.Order, error) {
-	s.cache.DeleteExpired()
-	req.Validate()
+func (s *orderStorage) DeleteOrder(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "OrderStorage.DeleteOrder")
+	defer span.End()
 
-	if cacheOrders := s.cache.Get(req.CacheKey()); cacheOrders != nil {
-		return cacheOrders.Value(), nil
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return err
 	}
 
-	// Query orders
-	query := s.psql.Select("id", "user_id", "status", "created_at", "updated_at").
-		From("orders")
+	s.invalidateCache(ctx)
 
-	if len(req.Ids) > 0 {
-		query = query.Where(sq.Eq{"id": req.Ids})
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return classifyError(err)
 	}
+	defer tx.Rollback(ctx)
 
-	if len(req.UserIds) > 0 {
-		query = query.Where(sq.Eq{"user_id": req.UserIds})
+	// dependent rows are removed explicitly rather than relying on the foreign key cascade
+	for _, table := range []string{"order_items", "order_status_history"} {
+		sql, args, err := s.psql.Delete(table).
+			Where(sq.Eq{"order_id": id}).
+			ToSql()
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(ctx, sql, args...); err != nil {
+			return classifyError(err)
+		}
 	}
 
-	if len(req.Statuses) > 0 {
-		query = query.Where(sq.Eq{"status": req.Statuses})
+	deleteOrderQuery := s.psql.Delete("orders").
+		Where(sq.Eq{"id": id})
+
+	sql, args, err := deleteOrderQuery.ToSql()
+	if err != nil {
+		return err
 	}
 
-	query = query.OrderBy("created_at DESC", "id").
-		Limit(uint64(req.Limit)).
-		Offset(uint64(req.Offset))
+	result, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return classifyError(err)
+	}
 
-	sql, args, err := query.ToSql()
+	if result.RowsAffected() == 0 {
+		return domain.ErrOrderNotFound
+	}
+
+	return classifyError(tx.Commit(ctx))
+}
+
+func (s *orderStorage) Orders(ctx context.Context, req *domain.GetOrdersRequest) ([]*domain.Order, error) {
+	ctx, span := tracer.Start(ctx, "OrderStorage.Orders")
+	defer span.End()
+
+	req.Validate()
+
+	if cacheOrders, ok := cachedResult(ctx, s.cache, req.CacheKey()); ok {
+		s.cacheHits.Add(1)
+		return cacheOrders, nil
+	}
+	s.cacheMisses.Add(1)
+
+	// Query orders
+	query := s.psql.Select("id", "user_id", "status", "total_quantity", "created_at", "updated_at").
+		From("orders")
+	query, err := sortPage(applyOrderFilters(query, req),
+		orderSortColumns, req.Sort, req.Order, req.After, req.Limit, req.Offset)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := s.pool.Query(ctx, sql, args...)
+	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, err
 	}
+
+	rows, err := readQuerier(ctx, s.db, s.replica).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, classifyError(err)
+	}
 	defer rows.Close()
 
 	var orders []*domain.Order
@@ -173,7 +586,7 @@ func (s *orderStorage) Orders(ctx context.Context, req *domain.GetOrdersRequest)
 
 	for rows.Next() {
 		var dto orderDto
-		err := rows.Scan(&dto.Id, &dto.UserId, &dto.Status, &dto.CreatedAt, &dto.UpdatedAt)
+		err := rows.Scan(&dto.Id, &dto.UserId, &dto.Status, &dto.TotalQuantity, &dto.CreatedAt, &dto.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -188,7 +601,7 @@ func (s *orderStorage) Orders(ctx context.Context, req *domain.GetOrdersRequest)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 
 	// Load order items if we have orders
@@ -199,17 +612,96 @@ func (s *orderStorage) Orders(ctx context.Context, req *domain.GetOrdersRequest)
 		}
 	}
 
-	s.cache.Set(req.CacheKey(), orders, ttlcache.DefaultTTL)
+	if ttl, ok := s.cacheOptions.resultTTL(len(req.Ids) > 0); ok {
+		cacheResult(ctx, s.cache, req.CacheKey(), orders, ttl)
+	}
 
 	return orders, nil
 }
 
 func (s *orderStorage) CountOrders(ctx context.Context, req *domain.GetOrdersRequest) (int, error) {
+	ctx, span := tracer.Start(ctx, "OrderStorage.CountOrders")
+	defer span.End()
+
 	req.Validate()
 
+	// neither pagination nor ordering changes the count, so they are left out of the key
+	countReq := *req
+	countReq.Limit, countReq.Offset, countReq.After = 0, 0, nil
+	countReq.Sort, countReq.Order = "", ""
+	cacheKey := countReq.CacheKey()
+
+	if cacheCount, ok := cachedResult(ctx, s.countCache, cacheKey); ok {
+		s.cacheHits.Add(1)
+		return cacheCount, nil
+	}
+	s.cacheMisses.Add(1)
+
 	query := s.psql.Select("COUNT(*)").
 		From("orders")
+	query = applyOrderFilters(query, req)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = readQuerier(ctx, s.db, s.replica).QueryRow(ctx, sql, args...).Scan(&count)
+	if err != nil {
+		return 0, classifyError(err)
+	}
+
+	if ttl, ok := s.cacheOptions.resultTTL(len(req.Ids) > 0); ok {
+		cacheResult(ctx, s.countCache, cacheKey, count, ttl)
+	}
+
+	return count, nil
+}
+
+func (s *orderStorage) StatusCounts(ctx context.Context, req *domain.GetOrdersRequest) (map[domain.OrderStatus]*domain.OrderStatusStats, error) {
+	ctx, span := tracer.Start(ctx, "OrderStorage.StatusCounts")
+	defer span.End()
+
+	query := s.psql.Select("status", "COUNT(*)", "COALESCE(SUM(total_quantity), 0)::bigint").
+		From("orders").
+		GroupBy("status")
+	query = applyOrderFilters(query, req)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := readQuerier(ctx, s.db, s.replica).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	defer rows.Close()
 
+	stats := domain.NewOrderStatusStatsMap()
+	for rows.Next() {
+		var status domain.OrderStatus
+		var statusStats domain.OrderStatusStats
+		if err := rows.Scan(&status, &statusStats.Count, &statusStats.TotalQuantity); err != nil {
+			return nil, err
+		}
+		stats[status] = &statusStats
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(err)
+	}
+
+	return stats, nil
+}
+
+// orderSortColumns allowlists the columns orders can be ordered by, see sortPage
+var orderSortColumns = map[domain.OrderSort]string{
+	domain.OrderSortCreatedAt: "created_at",
+	domain.OrderSortUpdatedAt: "updated_at",
+}
+
+func applyOrderFilters(query sq.SelectBuilder, req *domain.GetOrdersRequest) sq.SelectBuilder {
 	if len(req.Ids) > 0 {
 		query = query.Where(sq.Eq{"id": req.Ids})
 	}
@@ -222,18 +714,54 @@ func (s *orderStorage) CountOrders(ctx context.Context, req *domain.GetOrdersReq
 		query = query.Where(sq.Eq{"status": req.Statuses})
 	}
 
+	// served by order_items_product_id_idx
+	if len(req.ProductIds) > 0 {
+		containsProduct := sq.Select("1").
+			From("order_items").
+			Where("order_items.order_id = orders.id").
+			Where(sq.Eq{"order_items.product_id": req.ProductIds})
+		query = query.Where(sq.Expr("EXISTS (?)", containsProduct))
+	}
+
+	return query
+}
+
+func (s *orderStorage) OrderStatusHistory(ctx context.Context, orderId uuid.UUID) ([]*domain.OrderStatusChange, error) {
+	ctx, span := tracer.Start(ctx, "OrderStorage.OrderStatusHistory")
+	defer span.End()
+
+	query := s.psql.Select("id", "order_id", "from_status", "to_status", "actor_id", "changed_at").
+		From("order_status_history").
+		Where(sq.Eq{"order_id": orderId}).
+		OrderBy("changed_at", "id")
+
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	var count int
-	err = s.pool.QueryRow(ctx, sql, args...).Scan(&count)
+	rows, err := readQuerier(ctx, s.db, s.replica).Query(ctx, sql, args...)
 	if err != nil {
-		return 0, err
+		return nil, classifyError(err)
 	}
+	defer rows.Close()
 
-	return count, nil
+	history := make([]*domain.OrderStatusChange, 0)
+	for rows.Next() {
+		var dto orderStatusChangeDto
+		err := rows.Scan(&dto.Id, &dto.OrderId, &dto.FromStatus, &dto.ToStatus, &dto.ActorId, &dto.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, dto.toDomain())
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, classifyError(err)
+	}
+
+	return history, nil
 }
 
 func (s *orderStorage) loadOrderItems(ctx context.Context, orders []*domain.Order, orderIds []uuid.UUID) error {
@@ -241,16 +769,17 @@ func (s *orderStorage) loadOrderItems(ctx context.Context, orders []*domain.Orde
 	itemQuery := s.psql.Select("id", "order_id", "product_id", "quantity", "product_snapshot", "created_at").
 		From("order_items").
 		Where(sq.Eq{"order_id": orderIds}).
-		OrderBy("created_at")
+		// items added together share created_at
+		OrderBy("created_at", "id")
 
 	sql, args, err := itemQuery.ToSql()
 	if err != nil {
 		return err
 	}
 
-	rows, err := s.pool.Query(ctx, sql, args...)
+	rows, err := readQuerier(ctx, s.db, s.replica).Query(ctx, sql, args...)
 	if err != nil {
-		return err
+		return classifyError(err)
 	}
 	defer rows.Close()
 
@@ -273,7 +802,7 @@ func (s *orderStorage) loadOrderItems(ctx context.Context, orders []*domain.Orde
 	}
 
 	if err = rows.Err(); err != nil {
-		return err
+		return classifyError(err)
 	}
 
 	// Assign items to orders
@@ -283,3 +812,21 @@ func (s *orderStorage) loadOrderItems(ctx context.Context, orders []*domain.Orde
 
 	return nil
 }
+
+func (s *orderStorage) invalidateCache(ctx context.Context) {
+	invalidate(ctx, s.cache)
+	invalidate(ctx, s.countCache)
+}
+
+func (s *orderStorage) CacheStats() domain.CacheStats {
+	return domain.CacheStats{
+		Hits:   s.cacheHits.Load(),
+		Misses: s.cacheMisses.Load(),
+		Size:   s.cache.Len() + s.countCache.Len(),
+	}
+}
+
+func (s *orderStorage) Close() {
+	s.cache.Close()
+	s.countCache.Close()
+}
@@ -1,7 +1,7 @@
 package storage
 
 import (
-	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,40 +10,59 @@ import (
 )
 
 type orderDto struct {
-	Id        uuid.UUID `db:"id"`
-	UserId    uuid.UUID `db:"user_id"`
-	Status    string    `db:"status"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	Id            uuid.UUID `db:"id"`
+	UserId        uuid.UUID `db:"user_id"`
+	Status        string    `db:"status"`
+	TotalQuantity int       `db:"total_quantity"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
 }
 
 type orderItemDto struct {
-	Id              uuid.UUID `db:"id"`
-	OrderId         uuid.UUID `db:"order_id"`
-	ProductId       uuid.UUID `db:"product_id"`
-	Quantity        int       `db:"quantity"`
-	ProductSnapshot string    `db:"product_snapshot"` // JSON encoded ProductSnapshot
-	CreatedAt       time.Time `db:"created_at"`
+	Id              uuid.UUID           `db:"id"`
+	OrderId         uuid.UUID           `db:"order_id"`
+	ProductId       uuid.UUID           `db:"product_id"`
+	Quantity        int                 `db:"quantity"`
+	ProductSnapshot *productSnapshotDto `db:"product_snapshot"` // nil for a JSON null, the column is NOT NULL
+	CreatedAt       time.Time           `db:"created_at"`
+}
+
+// productSnapshotDto is the jsonb of order_items.product_snapshot. The keys are those of the
+// untagged domain.ProductSnapshot, which the snapshots were encoded from before.
+type productSnapshotDto struct {
+	Description string   `json:"Description"`
+	Tags        []string `json:"Tags,omitempty"`
+}
+
+type orderStatusChangeDto struct {
+	Id         uuid.UUID  `db:"id"`
+	OrderId    uuid.UUID  `db:"order_id"`
+	FromStatus string     `db:"from_status"`
+	ToStatus   string     `db:"to_status"`
+	ActorId    *uuid.UUID `db:"actor_id"`
+	ChangedAt  time.Time  `db:"changed_at"`
 }
 
 func (dto *orderDto) toDomain() (*domain.Order, error) {
 	return &domain.Order{
-		Id:        dto.Id,
-		UserId:    dto.UserId,
-		Status:    dto.Status,
-		CreatedAt: dto.CreatedAt,
-		UpdatedAt: dto.UpdatedAt,
-		Items:     []*domain.OrderItem{}, // Items will be loaded separately
+		Id:            dto.Id,
+		UserId:        dto.UserId,
+		Status:        domain.OrderStatus(dto.Status),
+		TotalQuantity: dto.TotalQuantity,
+		CreatedAt:     dto.CreatedAt,
+		UpdatedAt:     dto.UpdatedAt,
+		Items:         []*domain.OrderItem{}, // Items will be loaded separately
 	}, nil
 }
 
 func toOrderDto(order *domain.Order) (*orderDto, error) {
 	return &orderDto{
-		Id:        order.Id,
-		UserId:    order.UserId,
-		Status:    order.Status,
-		CreatedAt: order.CreatedAt,
-		UpdatedAt: order.UpdatedAt,
+		Id:            order.Id,
+		UserId:        order.UserId,
+		Status:        string(order.Status),
+		TotalQuantity: order.TotalQuantity,
+		CreatedAt:     order.CreatedAt,
+		UpdatedAt:     order.UpdatedAt,
 	}, nil
 }
 
@@ -56,31 +75,55 @@ func (dto *orderItemDto) toDomain() (*domain.OrderItem, error) {
 		CreatedAt: dto.CreatedAt,
 	}
 
-	if dto.ProductSnapshot != "" {
-		var snapshot domain.ProductSnapshot
-		if err := json.Unmarshal([]byte(dto.ProductSnapshot), &snapshot); err != nil {
-			return nil, err
-		}
-		item.ProductSnapshot = snapshot
+	// a snapshot missing its description can't be told apart from one of a product without one,
+	// so it is reported instead of being passed on empty
+	if dto.ProductSnapshot == nil {
+		return nil, fmt.Errorf("order item %s has no product snapshot", dto.Id)
+	}
+	if dto.ProductSnapshot.Description == "" {
+		return nil, fmt.Errorf("product snapshot of order item %s has no description", dto.Id)
+	}
+
+	item.ProductSnapshot = domain.ProductSnapshot{
+		Description: dto.ProductSnapshot.Description,
+		Tags:        dto.ProductSnapshot.Tags,
 	}
 
 	return item, nil
 }
 
 func toOrderItemDto(item *domain.OrderItem) (*orderItemDto, error) {
-	dto := &orderItemDto{
+	return &orderItemDto{
 		Id:        item.Id,
 		OrderId:   item.OrderId,
 		ProductId: item.ProductId,
 		Quantity:  item.Quantity,
+		ProductSnapshot: &productSnapshotDto{
+			Description: item.ProductSnapshot.Description,
+			Tags:        item.ProductSnapshot.Tags,
+		},
 		CreatedAt: item.CreatedAt,
-	}
+	}, nil
+}
 
-	snapshotJson, err := json.Marshal(item.ProductSnapshot)
-	if err != nil {
-		return nil, err
+func (dto *orderStatusChangeDto) toDomain() *domain.OrderStatusChange {
+	return &domain.OrderStatusChange{
+		Id:         dto.Id,
+		OrderId:    dto.OrderId,
+		FromStatus: domain.OrderStatus(dto.FromStatus),
+		ToStatus:   domain.OrderStatus(dto.ToStatus),
+		ActorId:    dto.ActorId,
+		ChangedAt:  dto.ChangedAt,
 	}
-	dto.ProductSnapshot = string(snapshotJson)
+}
 
-	return dto, nil
+func toOrderStatusChangeDto(change *domain.OrderStatusChange) *orderStatusChangeDto {
+	return &orderStatusChangeDto{
+		Id:         change.Id,
+		OrderId:    change.OrderId,
+		FromStatus: string(change.FromStatus),
+		ToStatus:   string(change.ToStatus),
+		ActorId:    change.ActorId,
+		ChangedAt:  change.ChangedAt,
+	}
 }
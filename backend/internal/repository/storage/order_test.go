@@ -0,0 +1,557 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"mts/internal/domain"
+	"shared"
+	sharedConfig "shared/config"
+)
+
+type OrderStorageSuite struct {
+	shared.Suite[any]
+	storage        domain.OrderStorage
+	userStorage    domain.UserStorage
+	productStorage domain.ProductStorage
+	factory        *domain.Factory
+}
+
+func (s *OrderStorageSuite) SetupSuite() {
+	s.PostgresEnabled = true
+	s.Suite.SetupSuite()
+	s.storage = NewOrderStorage(s.PostgresConn, nil, CacheOptions{}, sharedConfig.Retry{}, nil)
+	s.userStorage = NewUserStorage(s.PostgresConn, nil, CacheOptions{}, nil)
+	s.productStorage = NewProductStorage(s.PostgresConn, nil, CacheOptions{}, nil)
+	s.factory = &domain.Factory{}
+}
+
+func (s *OrderStorageSuite) TearDownTest() {
+	_, err := s.PostgresConn.Exec(s.Ctx, "TRUNCATE TABLE order_items, orders, products, users RESTART IDENTITY CASCADE")
+	s.Require().NoError(err)
+}
+
+// createOrder stores a pending order for a new user with a single product
+func (s *OrderStorageSuite) createOrder() *domain.Order {
+	user := s.factory.User()
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+
+	product := s.factory.Product()
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, product))
+
+	order := s.factory.Order(user.Id, product.Id)
+	s.Require().NoError(s.storage.CreateOrder(s.Ctx, order))
+	return order
+}
+
+func (s *OrderStorageSuite) TestCreateOrder_DuplicateId() {
+	order := s.createOrder()
+
+	// the replay carries fresh item ids, so only the order's primary key conflicts
+	replay := s.factory.Order(order.UserId, order.Items[0].ProductId)
+	replay.Id = order.Id
+	s.ErrorIs(s.storage.CreateOrder(s.Ctx, replay), domain.ErrOrderExists)
+
+	// nothing of the replay was stored
+	orders, err := s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+	s.Len(orders[0].Items, len(order.Items))
+}
+
+func (s *OrderStorageSuite) TestCountOrders_Cached() {
+	order := s.createOrder()
+	req := &domain.GetOrdersRequest{UserIds: []uuid.UUID{order.UserId}}
+
+	count, err := s.storage.CountOrders(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Equal(1, count)
+
+	// a row inserted behind the storage's back stays invisible while the count is cached
+	_, err = s.PostgresConn.Exec(s.Ctx,
+		"INSERT INTO orders (id, user_id, status, created_at, updated_at) VALUES ($1, $2, 'pending', now(), now())",
+		uuid.New(), order.UserId)
+	s.Require().NoError(err)
+
+	count, err = s.storage.CountOrders(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Equal(1, count)
+
+	// pagination is not part of the count key
+	count, err = s.storage.CountOrders(s.Ctx, &domain.GetOrdersRequest{UserIds: req.UserIds, Limit: 5, Offset: 5})
+	s.Require().NoError(err)
+	s.Equal(1, count)
+}
+
+func (s *OrderStorageSuite) TestOrders_IdsLookupsNotCached() {
+	order := s.createOrder()
+
+	orders, err := s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+
+	// a change made behind the storage's back shows up at once for lookups by id...
+	_, err = s.PostgresConn.Exec(s.Ctx, "UPDATE orders SET status = 'confirmed' WHERE id = $1", order.Id)
+	s.Require().NoError(err)
+
+	orders, err = s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+	s.Equal(domain.OrderStatusConfirmed, orders[0].Status)
+
+	// ...while list queries keep being served from the cache
+	list := &domain.GetOrdersRequest{UserIds: []uuid.UUID{order.UserId}}
+	orders, err = s.storage.Orders(s.Ctx, list)
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+
+	_, err = s.PostgresConn.Exec(s.Ctx, "UPDATE orders SET status = 'completed' WHERE id = $1", order.Id)
+	s.Require().NoError(err)
+
+	orders, err = s.storage.Orders(s.Ctx, list)
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+	s.Equal(domain.OrderStatusConfirmed, orders[0].Status)
+}
+
+func (s *OrderStorageSuite) TestOrders_ByProductIds() {
+	user := s.factory.User()
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+
+	phone, cable, charger := s.factory.Product(), s.factory.Product(), s.factory.Product()
+	for _, product := range []*domain.Product{phone, cable, charger} {
+		s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, product))
+	}
+
+	phoneAndCable := s.factory.Order(user.Id, phone.Id, cable.Id)
+	cableOnly := s.factory.Order(user.Id, cable.Id)
+	chargerOnly := s.factory.Order(user.Id, charger.Id)
+	for _, order := range []*domain.Order{phoneAndCable, cableOnly, chargerOnly} {
+		s.Require().NoError(s.storage.CreateOrder(s.Ctx, order))
+	}
+
+	tests := []struct {
+		name       string
+		productIds []uuid.UUID
+		expected   []uuid.UUID
+	}{
+		{"product in a single order", []uuid.UUID{phone.Id}, []uuid.UUID{phoneAndCable.Id}},
+		{"product shared by orders", []uuid.UUID{cable.Id}, []uuid.UUID{cableOnly.Id, phoneAndCable.Id}},
+		{"any of the products", []uuid.UUID{phone.Id, charger.Id}, []uuid.UUID{chargerOnly.Id, phoneAndCable.Id}},
+		{"product in no order", []uuid.UUID{uuid.New()}, nil},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			req := &domain.GetOrdersRequest{ProductIds: tt.productIds}
+
+			orders, err := s.storage.Orders(s.Ctx, req)
+			s.Require().NoError(err)
+
+			var ids []uuid.UUID
+			for _, order := range orders {
+				ids = append(ids, order.Id)
+				// the filter selects orders, their items are loaded in full
+				if order.Id == phoneAndCable.Id {
+					s.Len(order.Items, 2)
+				}
+			}
+			s.ElementsMatch(tt.expected, ids)
+
+			count, err := s.storage.CountOrders(s.Ctx, req)
+			s.Require().NoError(err)
+			s.Equal(len(tt.expected), count)
+		})
+	}
+
+	// combined with other filters
+	orders, err := s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{
+		ProductIds: []uuid.UUID{cable.Id},
+		UserIds:    []uuid.UUID{uuid.New()},
+	})
+	s.Require().NoError(err)
+	s.Empty(orders)
+}
+
+func (s *OrderStorageSuite) TestStatusCounts() {
+	user, other := s.factory.User(), s.factory.User()
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, other))
+
+	phone, cable := s.factory.Product(), s.factory.Product()
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, phone))
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, cable))
+
+	seed := func(userId uuid.UUID, status domain.OrderStatus, quantity int, productIds ...uuid.UUID) {
+		order := s.factory.Order(userId, productIds...)
+		order.Status = status
+		for _, item := range order.Items {
+			item.Quantity = quantity
+		}
+		s.Require().NoError(s.storage.CreateOrder(s.Ctx, order))
+	}
+	seed(user.Id, domain.OrderStatusPending, 2, phone.Id, cable.Id)
+	seed(user.Id, domain.OrderStatusPending, 1, cable.Id)
+	seed(user.Id, domain.OrderStatusConfirmed, 3, phone.Id)
+	seed(other.Id, domain.OrderStatusCompleted, 5, cable.Id)
+
+	tests := []struct {
+		name     string
+		req      *domain.GetOrdersRequest
+		expected map[domain.OrderStatus]*domain.OrderStatusStats
+	}{
+		{
+			name: "all orders",
+			req:  &domain.GetOrdersRequest{UserIds: []uuid.UUID{user.Id, other.Id}},
+			expected: map[domain.OrderStatus]*domain.OrderStatusStats{
+				domain.OrderStatusPending:   {Count: 2, TotalQuantity: 5},
+				domain.OrderStatusConfirmed: {Count: 1, TotalQuantity: 3},
+				domain.OrderStatusCancelled: {},
+				domain.OrderStatusCompleted: {Count: 1, TotalQuantity: 5},
+			},
+		},
+		{
+			name: "by user",
+			req:  &domain.GetOrdersRequest{UserIds: []uuid.UUID{other.Id}},
+			expected: map[domain.OrderStatus]*domain.OrderStatusStats{
+				domain.OrderStatusPending:   {},
+				domain.OrderStatusConfirmed: {},
+				domain.OrderStatusCancelled: {},
+				domain.OrderStatusCompleted: {Count: 1, TotalQuantity: 5},
+			},
+		},
+		{
+			// whole orders are aggregated, including items of other products
+			name: "by product",
+			req:  &domain.GetOrdersRequest{UserIds: []uuid.UUID{user.Id}, ProductIds: []uuid.UUID{phone.Id}},
+			expected: map[domain.OrderStatus]*domain.OrderStatusStats{
+				domain.OrderStatusPending:   {Count: 1, TotalQuantity: 4},
+				domain.OrderStatusConfirmed: {Count: 1, TotalQuantity: 3},
+				domain.OrderStatusCancelled: {},
+				domain.OrderStatusCompleted: {},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			stats, err := s.storage.StatusCounts(s.Ctx, tt.req)
+			s.Require().NoError(err)
+			s.Equal(tt.expected, stats)
+		})
+	}
+}
+
+func (s *OrderStorageSuite) TestStatusCounts_FollowsItemChanges() {
+	order := s.createOrder()
+	cable := s.factory.Product()
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, cable))
+	req := &domain.GetOrdersRequest{UserIds: []uuid.UUID{order.UserId}}
+
+	order.Items = append(order.Items, s.factory.OrderItem(order.Id, cable.Id, 4))
+	_, err := s.storage.ReplaceOrderItems(s.Ctx, order)
+	s.Require().NoError(err)
+
+	stats, err := s.storage.StatusCounts(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Equal(&domain.OrderStatusStats{Count: 1, TotalQuantity: 5}, stats[domain.OrderStatusPending])
+
+	_, err = s.storage.RemoveOrderItem(s.Ctx, order.Id, order.Items[1].Id)
+	s.Require().NoError(err)
+
+	stats, err = s.storage.StatusCounts(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Equal(&domain.OrderStatusStats{Count: 1, TotalQuantity: 1}, stats[domain.OrderStatusPending])
+}
+
+func (s *OrderStorageSuite) TestCountOrders_InvalidatedByCreate() {
+	order := s.createOrder()
+	req := &domain.GetOrdersRequest{UserIds: []uuid.UUID{order.UserId}}
+
+	count, err := s.storage.CountOrders(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Equal(1, count)
+
+	another := s.factory.Order(order.UserId, order.Items[0].ProductId)
+	s.Require().NoError(s.storage.CreateOrder(s.Ctx, another))
+
+	count, err = s.storage.CountOrders(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Equal(2, count)
+}
+
+func (s *OrderStorageSuite) TestDeleteOrder_RemovesOrderAndItems() {
+	order := s.createOrder()
+	kept := s.createOrder()
+
+	// warm the caches so the deletion has to invalidate them
+	_, err := s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}})
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.storage.DeleteOrder(s.Ctx, order.Id))
+
+	orders, err := s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}})
+	s.Require().NoError(err)
+	s.Empty(orders)
+
+	var items int
+	s.Require().NoError(s.PostgresConn.QueryRow(s.Ctx,
+		"SELECT COUNT(*) FROM order_items WHERE order_id = $1", order.Id).Scan(&items))
+	s.Zero(items)
+
+	// other orders are untouched
+	orders, err = s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{kept.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+	s.Len(orders[0].Items, len(kept.Items))
+}
+
+func (s *OrderStorageSuite) TestTotalQuantity_MatchesItems() {
+	user := s.factory.User()
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+	phone, cable := s.factory.Product(), s.factory.Product()
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, phone))
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, cable))
+
+	order := s.factory.Order(user.Id, phone.Id, cable.Id)
+	order.Items[0].Quantity, order.Items[1].Quantity = 2, 3
+	s.Require().NoError(s.storage.CreateOrder(s.Ctx, order))
+
+	stored := func() *domain.Order {
+		orders, err := s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}})
+		s.Require().NoError(err)
+		s.Require().Len(orders, 1)
+		return orders[0]
+	}
+
+	created := stored()
+	s.Equal(5, created.TotalQuantity)
+	s.Equal(created.ItemsQuantity(), created.TotalQuantity)
+
+	edit := s.factory.Order(user.Id, cable.Id)
+	edit.Id, edit.CreatedAt = order.Id, order.CreatedAt
+	edit.Items[0].Quantity = 4
+	replaced, err := s.storage.ReplaceOrderItems(s.Ctx, edit)
+	s.Require().NoError(err)
+	s.Equal(4, replaced.TotalQuantity)
+
+	edited := stored()
+	s.Equal(4, edited.TotalQuantity)
+	s.Equal(edited.ItemsQuantity(), edited.TotalQuantity)
+}
+
+func (s *OrderStorageSuite) TestRemoveOrderItem() {
+	user := s.factory.User()
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+	phone, cable := s.factory.Product(), s.factory.Product()
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, phone))
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, cable))
+
+	order := s.factory.Order(user.Id, phone.Id, cable.Id)
+	order.Items[0].Quantity, order.Items[1].Quantity = 2, 3
+	s.Require().NoError(s.storage.CreateOrder(s.Ctx, order))
+
+	_, err := s.storage.RemoveOrderItem(s.Ctx, order.Id, uuid.New())
+	s.ErrorIs(err, domain.ErrOrderItemNotFound)
+	_, err = s.storage.RemoveOrderItem(s.Ctx, uuid.New(), order.Items[0].Id)
+	s.ErrorIs(err, domain.ErrOrderNotFound)
+
+	removed, err := s.storage.RemoveOrderItem(s.Ctx, order.Id, order.Items[1].Id)
+	s.Require().NoError(err)
+	s.Require().Len(removed.Items, 1)
+	s.Equal(order.Items[0].Id, removed.Items[0].Id)
+	s.Equal(2, removed.TotalQuantity)
+	s.Equal(domain.OrderStatusPending, removed.Status)
+
+	// the last item stays, the order has to be cancelled instead
+	_, err = s.storage.RemoveOrderItem(s.Ctx, order.Id, order.Items[0].Id)
+	s.ErrorIs(err, domain.ErrOrderValidation)
+
+	orders, err := s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+	s.Len(orders[0].Items, 1)
+	s.Equal(2, orders[0].TotalQuantity)
+}
+
+func (s *OrderStorageSuite) TestDeleteOrder_NotFound() {
+	s.ErrorIs(s.storage.DeleteOrder(s.Ctx, uuid.New()), domain.ErrOrderNotFound)
+}
+
+func (s *OrderStorageSuite) TestDeleteProduct_KeepsOrderSnapshot() {
+	order := s.createOrder()
+	productId := order.Items[0].ProductId
+
+	s.Require().NoError(s.productStorage.DeleteProduct(s.Ctx, productId))
+
+	products, err := s.productStorage.Products(s.Ctx, &domain.GetProductsRequest{})
+	s.Require().NoError(err)
+	s.Empty(products)
+
+	orders, err := s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+	s.Require().Len(orders[0].Items, 1)
+	s.Equal(productId, orders[0].Items[0].ProductId)
+	s.Equal(order.Items[0].ProductSnapshot, orders[0].Items[0].ProductSnapshot)
+}
+
+func (s *OrderStorageSuite) TestProductSnapshot_RoundTrip() {
+	user := s.factory.User()
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+	product := s.factory.Product()
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, product))
+
+	order := s.factory.Order(user.Id, product.Id)
+	order.Items[0].ProductSnapshot = domain.ProductSnapshot{
+		Description: "Phone \"Pro\"",
+		Tags:        []string{"electronics", "sale"},
+	}
+	s.Require().NoError(s.storage.CreateOrder(s.Ctx, order))
+
+	orders, err := s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+	s.Require().Len(orders[0].Items, 1)
+	s.Equal(order.Items[0].ProductSnapshot, orders[0].Items[0].ProductSnapshot)
+
+	// stored as a jsonb object under the keys of the earlier text encoding
+	var description string
+	s.Require().NoError(s.PostgresConn.QueryRow(s.Ctx,
+		"SELECT product_snapshot->>'Description' FROM order_items WHERE id = $1", order.Items[0].Id).Scan(&description))
+	s.Equal("Phone \"Pro\"", description)
+}
+
+func (s *OrderStorageSuite) TestProductSnapshot_NullSnapshot() {
+	order := s.createOrder()
+
+	// the column rejects a missing snapshot and defaults to an empty object
+	_, err := s.PostgresConn.Exec(s.Ctx, "UPDATE order_items SET product_snapshot = NULL WHERE order_id = $1", order.Id)
+	s.Require().Error(err)
+
+	_, err = s.PostgresConn.Exec(s.Ctx, "UPDATE order_items SET product_snapshot = DEFAULT WHERE order_id = $1", order.Id)
+	s.Require().NoError(err)
+	_, err = s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}})
+	s.ErrorContains(err, "product snapshot of order item "+order.Items[0].Id.String()+" has no description")
+
+	// a JSON null still fits the column, it is reported rather than failing the scan
+	_, err = s.PostgresConn.Exec(s.Ctx, "UPDATE order_items SET product_snapshot = 'null' WHERE order_id = $1", order.Id)
+	s.Require().NoError(err)
+	_, err = s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}})
+	s.ErrorContains(err, "order item "+order.Items[0].Id.String()+" has no product snapshot")
+}
+
+func (s *OrderStorageSuite) TestUpdateOrder_RecordsStatusHistory() {
+	order := s.createOrder()
+
+	for _, status := range []domain.OrderStatus{domain.OrderStatusConfirmed, domain.OrderStatusCompleted} {
+		_, err := s.storage.UpdateOrder(s.Ctx, &domain.UpdateOrderRequest{Id: order.Id, Status: status})
+		s.Require().NoError(err)
+	}
+
+	history, err := s.storage.OrderStatusHistory(s.Ctx, order.Id)
+	s.Require().NoError(err)
+	s.Require().Len(history, 2)
+
+	s.Equal(domain.OrderStatusPending, history[0].FromStatus)
+	s.Equal(domain.OrderStatusConfirmed, history[0].ToStatus)
+	s.Equal(domain.OrderStatusConfirmed, history[1].FromStatus)
+	s.Equal(domain.OrderStatusCompleted, history[1].ToStatus)
+	s.False(history[1].ChangedAt.Before(history[0].ChangedAt))
+
+	for _, change := range history {
+		s.Equal(order.Id, change.OrderId)
+		s.Nil(change.ActorId)
+	}
+}
+
+func (s *OrderStorageSuite) TestUpdateOrder_RecordsActor() {
+	order := s.createOrder()
+	actorId := uuid.New()
+
+	status := domain.OrderStatusCancelled
+	_, err := s.storage.UpdateOrder(domain.ContextWithActor(s.Ctx, actorId), &domain.UpdateOrderRequest{
+		Id:     order.Id,
+		Status: status,
+	})
+	s.Require().NoError(err)
+
+	// setting the same status again is not a transition
+	_, err = s.storage.UpdateOrder(s.Ctx, &domain.UpdateOrderRequest{Id: order.Id, Status: status})
+	s.Require().NoError(err)
+
+	history, err := s.storage.OrderStatusHistory(s.Ctx, order.Id)
+	s.Require().NoError(err)
+	s.Require().Len(history, 1)
+	s.Require().NotNil(history[0].ActorId)
+	s.Equal(actorId, *history[0].ActorId)
+}
+
+func (s *OrderStorageSuite) TestUpdateOrderStatuses_AllOrNothing() {
+	first := s.createOrder()
+	second := s.createOrder()
+
+	s.Require().NoError(s.storage.UpdateOrderStatuses(s.Ctx, []uuid.UUID{first.Id, second.Id}, domain.OrderStatusConfirmed))
+
+	orders, err := s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{first.Id, second.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 2)
+	for _, order := range orders {
+		s.Equal(domain.OrderStatusConfirmed, order.Status)
+
+		history, err := s.storage.OrderStatusHistory(s.Ctx, order.Id)
+		s.Require().NoError(err)
+		s.Len(history, 1)
+	}
+
+	// a missing order rolls back the whole batch
+	err = s.storage.UpdateOrderStatuses(s.Ctx, []uuid.UUID{first.Id, uuid.New()}, domain.OrderStatusCompleted)
+	s.ErrorIs(err, domain.ErrOrderNotFound)
+
+	orders, err = s.storage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{first.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(orders, 1)
+	s.Equal(domain.OrderStatusConfirmed, orders[0].Status)
+}
+
+func TestOrderItemDto_ProductSnapshot(t *testing.T) {
+	item := (&domain.Factory{}).OrderItem(uuid.New(), uuid.New(), 2)
+	item.ProductSnapshot.Tags = []string{"electronics", "sale"}
+
+	dto, err := toOrderItemDto(item)
+	require.NoError(t, err)
+	encoded, err := json.Marshal(dto.ProductSnapshot)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Description": "Test Product", "Tags": ["electronics", "sale"]}`, string(encoded))
+
+	var decoded orderItemDto
+	decoded.Id = item.Id
+	require.NoError(t, json.Unmarshal(encoded, &decoded.ProductSnapshot))
+	loaded, err := decoded.toDomain()
+	require.NoError(t, err)
+	assert.Equal(t, item.ProductSnapshot, loaded.ProductSnapshot)
+
+	// snapshots written before the tags were set keep loading
+	require.NoError(t, json.Unmarshal([]byte(`{"Description": "Phone", "Tags": null}`), &decoded.ProductSnapshot))
+	loaded, err = decoded.toDomain()
+	require.NoError(t, err)
+	assert.Equal(t, domain.ProductSnapshot{Description: "Phone"}, loaded.ProductSnapshot)
+
+	require.NoError(t, json.Unmarshal([]byte(`null`), &decoded.ProductSnapshot))
+	assert.Nil(t, decoded.ProductSnapshot)
+	_, err = decoded.toDomain()
+	assert.EqualError(t, err, "order item "+item.Id.String()+" has no product snapshot")
+
+	require.NoError(t, json.Unmarshal([]byte(`{}`), &decoded.ProductSnapshot))
+	_, err = decoded.toDomain()
+	assert.EqualError(t, err, "product snapshot of order item "+item.Id.String()+" has no description")
+}
+
+func TestOrderStorageSuite(t *testing.T) {
+	suite.Run(t, new(OrderStorageSuite))
+}
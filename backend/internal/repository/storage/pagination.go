@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"mts/internal/domain"
+)
+
+// listTiebreakers end the ordering of every user, product and order list. Rows created in one
+// transaction share created_at, so id settles them and repeated queries and pages always agree.
+// Both run in the same direction, so the cursor's row-value comparison matches the ordering.
+var listTiebreakers = []string{"created_at DESC", "id DESC"}
+
+// orderRows orders by leading, when given, and then by listTiebreakers
+func orderRows(query sq.SelectBuilder, leading string, args ...any) sq.SelectBuilder {
+	if leading != "" {
+		query = query.OrderByClause(leading, args...)
+	}
+
+	return query.OrderBy(listTiebreakers...)
+}
+
+// paginate orders rows newest first and pages them by keyset when a cursor is given, by offset otherwise
+func paginate(query sq.SelectBuilder, after *domain.Cursor, limit, offset int) sq.SelectBuilder {
+	query = orderRows(query, "").
+		Limit(uint64(limit))
+
+	if after != nil {
+		return query.Where(sq.Expr("(created_at, id) < (?, ?)", after.CreatedAt, after.Id))
+	}
+
+	return query.Offset(uint64(offset))
+}
+
+// sortPage orders and pages a list. columns allowlists what each sort may order by, so the request never
+// reaches the SQL text; args bind the placeholders of a column expression. created_at DESC is the default
+// order and goes through paginate, which supports cursors; other orders page by offset.
+func sortPage[S ~string](query sq.SelectBuilder, columns map[S]string, sort S, order domain.SortOrder,
+	after *domain.Cursor, limit, offset int, args ...any) (sq.SelectBuilder, error) {
+	column, ok := columns[sort]
+	if !ok {
+		return query, fmt.Errorf("%w: unknown sort column %q", domain.ErrInvalidSort, sort)
+	}
+
+	var direction string
+	switch order {
+	case domain.SortOrderAsc:
+		direction = " ASC"
+	case domain.SortOrderDesc:
+		direction = " DESC"
+	default:
+		return query, fmt.Errorf("%w: unknown order %q", domain.ErrInvalidSort, order)
+	}
+
+	if column == "created_at" && order == domain.SortOrderDesc {
+		return paginate(query, after, limit, offset), nil
+	}
+
+	if after != nil {
+		return query, fmt.Errorf("%w: cursor pagination requires the default order", domain.ErrInvalidSort)
+	}
+
+	return orderRows(query, column+direction, args...).
+		Limit(uint64(limit)).
+		Offset(uint64(offset)), nil
+}
@@ -2,35 +2,61 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
-	"time"
+	"sync/atomic"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/jellydator/ttlcache/v3"
 
 	"mts/internal/domain"
 )
 
-func NewProductStorage(pool *pgxpool.Pool) domain.ProductStorage {
+// NewProductStorage runs writes on pool and reads on replica, falling back to pool when replica is nil;
+// writes fail with domain.ErrReadOnly while readOnly is on
+func NewProductStorage(pool *pgxpool.Pool, replica *pgxpool.Pool, cache CacheOptions, readOnly *domain.ReadOnlyMode) domain.ProductStorage {
+	s := newProductStorage(pool, cache, newMemoryResultCache[[]*domain.Product](cache.TTL, true))
+	s.replica = replicaQuerier(replica)
+	s.readOnly = readOnly
+	return s
+}
+
+func newProductStorage(db querier, cache CacheOptions, results resultCache[[]*domain.Product]) *productStorage {
 	return &productStorage{
-		pool: pool,
-		psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
-		cache: ttlcache.New[domain.CacheKey, []*domain.Product](
-			ttlcache.WithTTL[domain.CacheKey, []*domain.Product](time.Hour),
-		),
+		db:           db,
+		psql:         sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		cacheOptions: cache,
+		cache:        results,
 	}
 }
 
 type productStorage struct {
-	pool  *pgxpool.Pool
-	psql  sq.StatementBuilderType
-	cache *ttlcache.Cache[domain.CacheKey, []*domain.Product]
+	db querier
+	// readOnly rejects writes while it is on, nil never does
+	readOnly *domain.ReadOnlyMode
+	// replica serves Products and CountProducts when set, see readQuerier
+	replica querier
+	psql    sq.StatementBuilderType
+	cache   resultCache[[]*domain.Product]
+	// cacheOptions decides which results are cached and for how long
+	cacheOptions CacheOptions
+
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
 }
 
 func (s *productStorage) CreateProduct(ctx context.Context, product *domain.Product) error {
-	s.cache.DeleteAll()
+	ctx, span := tracer.Start(ctx, "ProductStorage.CreateProduct")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return err
+	}
+
+	invalidate(ctx, s.cache)
 
 	if err := product.Validate(); err != nil {
 		return err
@@ -42,28 +68,120 @@ func (s *productStorage) CreateProduct(ctx context.Context, product *domain.Prod
 	}
 
 	query := s.psql.Insert("products").
-		Columns("id", "description", "tags", "quantity", "created_at", "updated_at").
-		Values(dto.Id, dto.Description, dto.Tags, dto.Quantity, dto.CreatedAt, dto.UpdatedAt)
+		Columns("id", "description", "tags", "quantity", "price", "version", "created_at", "updated_at").
+		Values(dto.Id, dto.Description, dto.Tags, dto.Quantity, dto.Price, dto.Version, dto.CreatedAt, dto.UpdatedAt)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return err
 	}
 
-	_, err = s.pool.Exec(ctx, sql, args...)
-	return err
+	// in a unit of work the savepoint keeps a rejected row from aborting the whole transaction
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return classifyError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, sql, args...)
+	if isUniqueViolation(err, productsPrimaryKey) || isUniqueViolation(err, productsDescriptionKey) {
+		return fmt.Errorf("%w: %w", domain.ErrProductExists, err)
+	}
+	if err != nil {
+		return classifyQuantityError(err, domain.ErrProductValidation)
+	}
+
+	return classifyError(tx.Commit(ctx))
+}
+
+func (s *productStorage) UpsertProduct(ctx context.Context, product *domain.Product) (*domain.Product, bool, error) {
+	ctx, span := tracer.Start(ctx, "ProductStorage.UpsertProduct")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return nil, false, err
+	}
+
+	if err := product.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	dto, err := toProductDto(product)
+	if err != nil {
+		return nil, false, err
+	}
+
+	insertQuery := s.psql.Insert("products").
+		Columns("id", "description", "tags", "quantity", "price", "version", "created_at", "updated_at").
+		Values(dto.Id, dto.Description, dto.Tags, dto.Quantity, dto.Price, dto.Version, dto.CreatedAt, dto.UpdatedAt).
+		Suffix("ON CONFLICT (description) WHERE deleted_at IS NULL DO NOTHING")
+
+	sql, args, err := insertQuery.ToSql()
+	if err != nil {
+		return nil, false, err
+	}
+
+	result, err := s.db.Exec(ctx, sql, args...)
+	if err != nil {
+		if isUniqueViolation(err, productsPrimaryKey) {
+			return nil, false, fmt.Errorf("%w: %w", domain.ErrProductExists, err)
+		}
+		return nil, false, classifyQuantityError(err, domain.ErrProductValidation)
+	}
+
+	// only a new product changes cached listings, a sync repeating known products keeps the cache warm
+	if result.RowsAffected() == 1 {
+		invalidate(ctx, s.cache)
+		return product, true, nil
+	}
+
+	// the conflicting product is committed by now, so it is read from the primary rather than a lagging replica
+	selectQuery := s.psql.Select("id", "description", "tags", "quantity", "price", "version", "created_at", "updated_at", "deleted_at").
+		From("products").
+		Where(sq.Eq{"description": dto.Description, "deleted_at": nil})
+
+	sql, args, err = selectQuery.ToSql()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var existing productDto
+	err = s.db.QueryRow(ctx, sql, args...).
+		Scan(&existing.Id, &existing.Description, &existing.Tags, &existing.Quantity, &existing.Price, &existing.Version, &existing.CreatedAt, &existing.UpdatedAt, &existing.DeletedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// deleted again since the insert conflicted with it
+			return nil, false, domain.ErrProductNotFound
+		}
+		return nil, false, classifyError(err)
+	}
+
+	found, err := existing.toDomain()
+	return found, false, err
 }
 
 func (s *productStorage) UpdateProduct(ctx context.Context, req *domain.UpdateProductRequest) (*domain.Product, error) {
-	s.cache.DeleteAll()
+	ctx, span := tracer.Start(ctx, "ProductStorage.UpdateProduct")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return nil, err
+	}
+
+	invalidate(ctx, s.cache)
 
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
 
 	updateQuery := s.psql.Update("products").
-		Set("updated_at", time.Now()).
-		Where(sq.Eq{"id": req.Id})
+		Set("version", sq.Expr("version + 1")).
+		Set("updated_at", domain.Now()).
+		Where(sq.Eq{"id": req.Id, "deleted_at": nil})
+
+	if req.Version != nil {
+		updateQuery = updateQuery.Where(sq.Eq{"version": *req.Version})
+	}
 
 	if req.Description != nil {
 		updateQuery = updateQuery.Set("description", strings.TrimSpace(*req.Description))
@@ -73,6 +191,10 @@ func (s *productStorage) UpdateProduct(ctx context.Context, req *domain.UpdatePr
 		updateQuery = updateQuery.Set("quantity", *req.Quantity)
 	}
 
+	if req.Price != nil {
+		updateQuery = updateQuery.Set("price", *req.Price)
+	}
+
 	if len(req.Tags) > 0 {
 		// Convert tags to JSON
 		product := &domain.Product{Tags: req.Tags}
@@ -88,13 +210,17 @@ func (s *productStorage) UpdateProduct(ctx context.Context, req *domain.UpdatePr
 		return nil, err
 	}
 
-	_, err = s.pool.Exec(ctx, sql, args...)
+	// updates are how stock gets reserved, so a negative result means there was not enough of it
+	result, err := s.db.Exec(ctx, sql, args...)
 	if err != nil {
-		return nil, err
+		if isUniqueViolation(err, productsDescriptionKey) {
+			return nil, fmt.Errorf("%w: %w", domain.ErrProductExists, err)
+		}
+		return nil, classifyQuantityError(err, domain.ErrInsufficientStock)
 	}
 
 	// Get updated product
-	products, err := s.Products(ctx, &domain.GetProductsRequest{
+	products, err := s.Products(withPrimaryReads(ctx), &domain.GetProductsRequest{
 		Ids:   []uuid.UUID{req.Id},
 		Limit: 1,
 	})
@@ -106,59 +232,113 @@ func (s *productStorage) UpdateProduct(ctx context.Context, req *domain.UpdatePr
 		return nil, domain.ErrProductNotFound
 	}
 
+	// the product exists, so nothing was updated because of its version
+	if result.RowsAffected() == 0 {
+		return nil, fmt.Errorf("%w: product is at version %d", domain.ErrVersionConflict, products[0].Version)
+	}
+
 	return products[0], nil
 }
 
-func (s *productStorage) Products(ctx context.Context, req *domain.GetProductsRequest) ([]*domain.Product, error) {
-	s.cache.DeleteExpired()
-	req.Validate()
+func (s *productStorage) AdjustQuantity(ctx context.Context, id uuid.UUID, delta int) (*domain.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductStorage.AdjustQuantity")
+	defer span.End()
 
-	if cacheProducts := s.cache.Get(req.CacheKey()); cacheProducts != nil {
-		return cacheProducts.Value(), nil
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return nil, err
 	}
 
-	query := s.psql.Select("id", "description", "tags", "quantity", "created_at", "updated_at").
-		From("products")
+	invalidate(ctx, s.cache)
+
+	query := s.psql.Update("products").
+		Set("quantity", sq.Expr("quantity + ?", delta)).
+		Set("version", sq.Expr("version + 1")).
+		Set("updated_at", domain.Now()).
+		Where(sq.Eq{"id": id, "deleted_at": nil}).
+		Suffix("RETURNING id, description, tags, quantity, price, version, created_at, updated_at, deleted_at")
+	if delta < 0 {
+		// the stock is checked on the row the update locks, concurrent takers can't both get the last items
+		query = query.Where(sq.GtOrEq{"quantity": -delta})
+	}
 
-	if len(req.Ids) > 0 {
-		query = query.Where(sq.Eq{"id": req.Ids})
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
 	}
 
-	if len(req.Tags) > 0 {
-		// Search for products that contain any of the specified tags
-		for _, tag := range req.Tags {
-			query = query.Where(sq.Like{"tags": "%" + tag + "%"})
+	var dto productDto
+	err = s.db.QueryRow(ctx, sql, args...).
+		Scan(&dto.Id, &dto.Description, &dto.Tags, &dto.Quantity, &dto.Price, &dto.Version, &dto.CreatedAt, &dto.UpdatedAt, &dto.DeletedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, s.missedAdjustment(ctx, id, delta)
 		}
+		return nil, classifyQuantityError(err, domain.ErrInsufficientStock)
 	}
 
-	if req.Available != nil {
-		if *req.Available {
-			query = query.Where(sq.Gt{"quantity": 0})
-		} else {
-			query = query.Where(sq.Eq{"quantity": 0})
+	return dto.toDomain()
+}
+
+// missedAdjustment tells why AdjustQuantity updated nothing: the product is gone or has too few items
+func (s *productStorage) missedAdjustment(ctx context.Context, id uuid.UUID, delta int) error {
+	query := s.psql.Select("quantity").
+		From("products").
+		Where(sq.Eq{"id": id, "deleted_at": nil})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	var available int
+	if err = s.db.QueryRow(ctx, sql, args...).Scan(&available); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.ErrProductNotFound
 		}
+		return classifyError(err)
 	}
 
-	query = query.OrderBy("created_at DESC", "id").
-		Limit(uint64(req.Limit)).
-		Offset(uint64(req.Offset))
+	shortage := &domain.InsufficientStockError{}
+	shortage.Add(id, -delta, available)
+	return shortage
+}
 
-	sql, args, err := query.ToSql()
+func (s *productStorage) Products(ctx context.Context, req *domain.GetProductsRequest) ([]*domain.Product, error) {
+	ctx, span := tracer.Start(ctx, "ProductStorage.Products")
+	defer span.End()
+
+	req.Validate()
+
+	if cacheProducts, ok := cachedResult(ctx, s.cache, req.CacheKey()); ok {
+		s.cacheHits.Add(1)
+		return cacheProducts, nil
+	}
+	s.cacheMisses.Add(1)
+
+	query := s.psql.Select("id", "description", "tags", "quantity", "price", "version", "created_at", "updated_at", "deleted_at").
+		From("products")
+
+	query, err := orderProducts(applyProductFilters(query, req), req)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := s.pool.Query(ctx, sql, args...)
+	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, err
 	}
+
+	rows, err := readQuerier(ctx, s.db, s.replica).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, classifyError(err)
+	}
 	defer rows.Close()
 
 	var products []*domain.Product
 	for rows.Next() {
 		var dto productDto
 
-		err := rows.Scan(&dto.Id, &dto.Description, &dto.Tags, &dto.Quantity, &dto.CreatedAt, &dto.UpdatedAt)
+		err := rows.Scan(&dto.Id, &dto.Description, &dto.Tags, &dto.Quantity, &dto.Price, &dto.Version, &dto.CreatedAt, &dto.UpdatedAt, &dto.DeletedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -172,25 +352,89 @@ func (s *productStorage) Products(ctx context.Context, req *domain.GetProductsRe
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 
-	s.cache.Set(req.CacheKey(), products, ttlcache.DefaultTTL)
+	if ttl, ok := s.cacheOptions.resultTTL(len(req.Ids) > 0); ok {
+		cacheResult(ctx, s.cache, req.CacheKey(), products, ttl)
+	}
 
 	return products, nil
 }
 
 func (s *productStorage) CountProducts(ctx context.Context, req *domain.GetProductsRequest) (int, error) {
+	ctx, span := tracer.Start(ctx, "ProductStorage.CountProducts")
+	defer span.End()
+
 	req.Validate()
 
-	query := s.psql.Select("COUNT(*)").
-		From("products")
+	query := applyProductFilters(s.psql.Select("COUNT(*)").From("products"), req)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = readQuerier(ctx, s.db, s.replica).QueryRow(ctx, sql, args...).Scan(&count)
+	if err != nil {
+		return 0, classifyError(err)
+	}
+
+	return count, nil
+}
+
+func (s *productStorage) TagCounts(ctx context.Context, req *domain.GetProductsRequest) ([]*domain.ProductTagCount, error) {
+	ctx, span := tracer.Start(ctx, "ProductStorage.TagCounts")
+	defer span.End()
+
+	// tags holds a JSON array, or '' for products without tags, which NULLIF turns into no rows;
+	// a tag repeated within a product still counts the product once
+	query := s.psql.Select("tag", "COUNT(DISTINCT id)").
+		From("products").
+		JoinClause("CROSS JOIN LATERAL jsonb_array_elements_text(NULLIF(tags, '')::jsonb) AS tag").
+		GroupBy("tag").
+		OrderBy("COUNT(DISTINCT id) DESC", "tag")
+	query = applyProductFilters(query, req)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := readQuerier(ctx, s.db, s.replica).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, classifyError(err)
+	}
+	defer rows.Close()
+
+	tags := make([]*domain.ProductTagCount, 0)
+	for rows.Next() {
+		var tag domain.ProductTagCount
+		if err := rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, classifyError(err)
+	}
+
+	return tags, nil
+}
 
+// applyProductFilters adds the request filters so Products and CountProducts always agree
+func applyProductFilters(query sq.SelectBuilder, req *domain.GetProductsRequest) sq.SelectBuilder {
 	if len(req.Ids) > 0 {
 		query = query.Where(sq.Eq{"id": req.Ids})
 	}
 
+	if !req.IncludeDeleted {
+		query = query.Where(sq.Eq{"deleted_at": nil})
+	}
+
 	if len(req.Tags) > 0 {
+		// Search for products that contain any of the specified tags
 		for _, tag := range req.Tags {
 			query = query.Where(sq.Like{"tags": "%" + tag + "%"})
 		}
@@ -204,16 +448,96 @@ func (s *productStorage) CountProducts(ctx context.Context, req *domain.GetProdu
 		}
 	}
 
+	if req.MaxQuantity != nil {
+		query = query.Where(sq.LtOrEq{"quantity": *req.MaxQuantity})
+	}
+
+	if req.MinPrice != nil {
+		query = query.Where(sq.GtOrEq{"price": *req.MinPrice})
+	}
+
+	if req.MaxPrice != nil {
+		query = query.Where(sq.LtOrEq{"price": *req.MaxPrice})
+	}
+
+	// full-text matches find stemmed words in the description and tags (products_search_vector_idx);
+	// ILIKE still finds parts of words and terms the parser drops, such as stop words (products_description_trgm_idx)
+	if req.Search != "" {
+		query = query.Where(sq.Or{
+			sq.Expr("search_vector @@ plainto_tsquery('english', ?)", req.Search),
+			sq.Expr("description ILIKE ?", "%"+escapeLike(req.Search)+"%"),
+		})
+	}
+
+	return query
+}
+
+// productSortColumns allowlists the columns products can be ordered by, so the request never reaches the SQL text
+var productSortColumns = map[domain.ProductSort]string{
+	domain.ProductSortCreatedAt: "created_at",
+	domain.ProductSortPrice:     "price",
+	// normalization 1 divides by the document length, so an exact description outranks a longer one;
+	// products found only by ILIKE rank 0 and come last
+	domain.ProductSortRelevance: "ts_rank(search_vector, plainto_tsquery('english', ?), 1)",
+}
+
+// orderProducts applies the requested ordering and page, see sortPage
+func orderProducts(query sq.SelectBuilder, req *domain.GetProductsRequest) (sq.SelectBuilder, error) {
+	// the rank is the only sort expression with an argument, the search term
+	var args []any
+	if req.Sort == domain.ProductSortRelevance {
+		if req.Search == "" {
+			return query, fmt.Errorf("%w: relevance sort requires a search term", domain.ErrInvalidSort)
+		}
+		args = append(args, req.Search)
+	}
+
+	return sortPage(query, productSortColumns, req.Sort, req.Order, req.After, req.Limit, req.Offset, args...)
+}
+
+func (s *productStorage) DeleteProduct(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "ProductStorage.DeleteProduct")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return err
+	}
+
+	invalidate(ctx, s.cache)
+
+	query := s.psql.Update("products").
+		Set("deleted_at", domain.Now()).
+		Where(sq.Eq{"id": id, "deleted_at": nil})
+
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return 0, err
+		return err
 	}
 
-	var count int
-	err = s.pool.QueryRow(ctx, sql, args...).Scan(&count)
+	result, err := s.db.Exec(ctx, sql, args...)
 	if err != nil {
-		return 0, err
+		return classifyError(err)
 	}
 
-	return count, nil
+	if result.RowsAffected() == 0 {
+		return domain.ErrProductNotFound
+	}
+
+	return nil
+}
+
+func (s *productStorage) invalidateCache(ctx context.Context) {
+	invalidate(ctx, s.cache)
+}
+
+func (s *productStorage) CacheStats() domain.CacheStats {
+	return domain.CacheStats{
+		Hits:   s.cacheHits.Load(),
+		Misses: s.cacheMisses.Load(),
+		Size:   s.cache.Len(),
+	}
+}
+
+func (s *productStorage) Close() {
+	s.cache.Close()
 }
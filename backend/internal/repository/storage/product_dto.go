@@ -10,12 +10,15 @@ import (
 )
 
 type productDto struct {
-	Id          uuid.UUID `db:"id"`
-	Description string    `db:"description"`
-	Tags        string    `db:"tags"` // JSON encoded
-	Quantity    int       `db:"quantity"`
-	CreatedAt   time.Time `db:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at"`
+	Id          uuid.UUID  `db:"id"`
+	Description string     `db:"description"`
+	Tags        string     `db:"tags"` // JSON encoded
+	Quantity    int        `db:"quantity"`
+	Price       int        `db:"price"`
+	Version     int        `db:"version"`
+	CreatedAt   time.Time  `db:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at"`
+	DeletedAt   *time.Time `db:"deleted_at"`
 }
 
 func (dto *productDto) toDomain() (*domain.Product, error) {
@@ -23,8 +26,11 @@ func (dto *productDto) toDomain() (*domain.Product, error) {
 		Id:          dto.Id,
 		Description: dto.Description,
 		Quantity:    dto.Quantity,
+		Price:       dto.Price,
+		Version:     dto.Version,
 		CreatedAt:   dto.CreatedAt,
 		UpdatedAt:   dto.UpdatedAt,
+		DeletedAt:   dto.DeletedAt,
 	}
 
 	if dto.Tags != "" {
@@ -43,8 +49,11 @@ func toProductDto(product *domain.Product) (*productDto, error) {
 		Id:          product.Id,
 		Description: product.Description,
 		Quantity:    product.Quantity,
+		Price:       product.Price,
+		Version:     product.Version,
 		CreatedAt:   product.CreatedAt,
 		UpdatedAt:   product.UpdatedAt,
+		DeletedAt:   product.DeletedAt,
 	}
 
 	if len(product.Tags) > 0 {
@@ -0,0 +1,750 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+
+	"mts/internal/domain"
+	"shared"
+)
+
+type ProductStorageSuite struct {
+	shared.Suite[any]
+	storage domain.ProductStorage
+	factory *domain.Factory
+}
+
+func (s *ProductStorageSuite) SetupSuite() {
+	s.PostgresEnabled = true
+	s.Suite.SetupSuite()
+	s.storage = NewProductStorage(s.PostgresConn, nil, CacheOptions{}, nil)
+	s.factory = &domain.Factory{}
+}
+
+func (s *ProductStorageSuite) TearDownTest() {
+	_, err := s.PostgresConn.Exec(s.Ctx, "TRUNCATE TABLE products RESTART IDENTITY CASCADE")
+	s.Require().NoError(err)
+}
+
+func (s *ProductStorageSuite) createProducts(quantities ...int) {
+	for _, quantity := range quantities {
+		s.Require().NoError(s.storage.CreateProduct(s.Ctx, s.factory.ProductWithQuantity(quantity)))
+	}
+}
+
+func (s *ProductStorageSuite) TestTagCounts() {
+	for _, product := range []struct {
+		tags     []string
+		quantity int
+	}{
+		{[]string{"electronics", "mobile"}, 5},
+		{[]string{"electronics", "accessories"}, 0},
+		{[]string{"accessories", "accessories", "mobile"}, 1},
+		{nil, 3},
+	} {
+		p := s.factory.ProductWithQuantity(product.quantity)
+		p.Tags = product.tags
+		s.Require().NoError(s.storage.CreateProduct(s.Ctx, p))
+	}
+	deleted := s.factory.Product()
+	deleted.Tags = []string{"electronics"}
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, deleted))
+	s.Require().NoError(s.storage.DeleteProduct(s.Ctx, deleted.Id))
+
+	// a tag repeated within a product counts it once, deleted products are left out; ties go by name
+	tags, err := s.storage.TagCounts(s.Ctx, &domain.GetProductsRequest{})
+	s.Require().NoError(err)
+	s.Equal([]*domain.ProductTagCount{
+		{Tag: "accessories", Count: 2},
+		{Tag: "electronics", Count: 2},
+		{Tag: "mobile", Count: 2},
+	}, tags)
+
+	available := true
+	tags, err = s.storage.TagCounts(s.Ctx, &domain.GetProductsRequest{Available: &available})
+	s.Require().NoError(err)
+	s.Equal([]*domain.ProductTagCount{
+		{Tag: "mobile", Count: 2},
+		{Tag: "accessories", Count: 1},
+		{Tag: "electronics", Count: 1},
+	}, tags)
+}
+
+func (s *ProductStorageSuite) TestProducts_CacheDown() {
+	storage := newProductStorage(s.PostgresConn, CacheOptions{}, failingResultCache[[]*domain.Product]{})
+
+	// writes go through even though the cache can't be invalidated
+	product := s.factory.Product()
+	s.Require().NoError(storage.CreateProduct(s.Ctx, product))
+
+	for range 2 {
+		products, err := storage.Products(s.Ctx, &domain.GetProductsRequest{})
+		s.Require().NoError(err)
+		s.Require().Len(products, 1)
+		s.Equal(product.Id, products[0].Id)
+	}
+
+	stats := storage.CacheStats()
+	s.Zero(stats.Hits)
+	s.Equal(uint64(2), stats.Misses)
+}
+
+func (s *ProductStorageSuite) TestProducts_MaxQuantity() {
+	s.createProducts(0, 3, 5, 6, 100)
+
+	maxQuantity := 5
+	products, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{MaxQuantity: &maxQuantity})
+	s.Require().NoError(err)
+	s.Len(products, 3)
+	for _, product := range products {
+		s.LessOrEqual(product.Quantity, maxQuantity)
+	}
+
+	count, err := s.storage.CountProducts(s.Ctx, &domain.GetProductsRequest{MaxQuantity: &maxQuantity})
+	s.Require().NoError(err)
+	s.Equal(3, count)
+}
+
+func (s *ProductStorageSuite) TestProducts_MaxQuantityWithPagination() {
+	s.createProducts(1, 2, 3, 4, 50)
+
+	maxQuantity := 4
+	page1, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{MaxQuantity: &maxQuantity, Limit: 3})
+	s.Require().NoError(err)
+	s.Len(page1, 3)
+
+	page2, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{MaxQuantity: &maxQuantity, Limit: 3, Offset: 3})
+	s.Require().NoError(err)
+	s.Len(page2, 1)
+	s.LessOrEqual(page2[0].Quantity, maxQuantity)
+
+	// the count ignores pagination but honors the threshold
+	count, err := s.storage.CountProducts(s.Ctx, &domain.GetProductsRequest{MaxQuantity: &maxQuantity, Limit: 3, Offset: 3})
+	s.Require().NoError(err)
+	s.Equal(4, count)
+}
+
+func (s *ProductStorageSuite) TestProducts_MaxQuantityWithAvailable() {
+	s.createProducts(0, 0, 2, 9)
+
+	maxQuantity, available := 5, true
+	req := &domain.GetProductsRequest{MaxQuantity: &maxQuantity, Available: &available}
+
+	products, err := s.storage.Products(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Len(products, 1)
+	s.Equal(2, products[0].Quantity)
+
+	count, err := s.storage.CountProducts(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Equal(1, count)
+}
+
+func (s *ProductStorageSuite) TestProducts_CursorPagination() {
+	s.createProducts(1, 2, 3, 4, 5, 6, 7)
+
+	existing, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{Limit: 100})
+	s.Require().NoError(err)
+
+	page, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{Limit: 3})
+	s.Require().NoError(err)
+
+	// rows inserted while paging are newer than the first page, so they neither show up
+	// nor shift the remaining rows between pages
+	inserted := make(chan struct{})
+	go func() {
+		defer close(inserted)
+		for range 5 {
+			s.NoError(s.storage.CreateProduct(s.Ctx, s.factory.Product()))
+		}
+	}()
+
+	var seen []uuid.UUID
+	for {
+		for _, product := range page {
+			seen = append(seen, product.Id)
+		}
+		if len(page) < 3 {
+			break
+		}
+
+		last := page[len(page)-1]
+		page, err = s.storage.Products(s.Ctx, &domain.GetProductsRequest{
+			After: domain.NewCursor(last.CreatedAt, last.Id),
+			Limit: 3,
+		})
+		s.Require().NoError(err)
+	}
+	<-inserted
+
+	expected := make([]uuid.UUID, 0, len(existing))
+	for _, product := range existing {
+		expected = append(expected, product.Id)
+	}
+	s.Equal(expected, seen)
+}
+
+func (s *ProductStorageSuite) TestCreateProduct_DuplicateId() {
+	product := s.factory.Product()
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, product))
+
+	replay := s.factory.Product()
+	replay.Id = product.Id
+	s.ErrorIs(s.storage.CreateProduct(s.Ctx, replay), domain.ErrProductExists)
+}
+
+func (s *ProductStorageSuite) TestCreateProduct_DuplicateDescription() {
+	product := s.factory.ProductWithDescription("Phone")
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, product))
+
+	s.ErrorIs(s.storage.CreateProduct(s.Ctx, s.factory.ProductWithDescription("Phone")), domain.ErrProductExists)
+
+	other := s.factory.ProductWithDescription("Cable")
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, other))
+	description := "Phone"
+	_, err := s.storage.UpdateProduct(s.Ctx, &domain.UpdateProductRequest{Id: other.Id, Description: &description})
+	s.ErrorIs(err, domain.ErrProductExists)
+}
+
+func (s *ProductStorageSuite) TestUpsertProduct_CreatesMissing() {
+	product := s.factory.ProductWithDescription("Phone")
+
+	stored, created, err := s.storage.UpsertProduct(s.Ctx, product)
+	s.Require().NoError(err)
+	s.True(created)
+	s.Equal(product.Id, stored.Id)
+
+	products, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{Ids: []uuid.UUID{product.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(products, 1)
+	s.Equal("Phone", products[0].Description)
+}
+
+func (s *ProductStorageSuite) TestUpsertProduct_ReturnsExisting() {
+	existing := s.factory.ProductWithDescription("Phone")
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, existing))
+
+	// a cached listing must survive an upsert that changes nothing
+	_, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{})
+	s.Require().NoError(err)
+
+	replay := s.factory.ProductWithDescription("Phone")
+	replay.Quantity = 1
+	stored, created, err := s.storage.UpsertProduct(s.Ctx, replay)
+	s.Require().NoError(err)
+	s.False(created)
+	s.Equal(existing.Id, stored.Id)
+	s.Equal(existing.Quantity, stored.Quantity)
+	s.Equal(1, s.storage.CacheStats().Size)
+
+	count, err := s.storage.CountProducts(s.Ctx, &domain.GetProductsRequest{})
+	s.Require().NoError(err)
+	s.Equal(1, count)
+}
+
+func (s *ProductStorageSuite) TestUpsertProduct_IgnoresDeleted() {
+	deleted := s.factory.ProductWithDescription("Phone")
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, deleted))
+	s.Require().NoError(s.storage.DeleteProduct(s.Ctx, deleted.Id))
+
+	stored, created, err := s.storage.UpsertProduct(s.Ctx, s.factory.ProductWithDescription("Phone"))
+	s.Require().NoError(err)
+	s.True(created)
+	s.NotEqual(deleted.Id, stored.Id)
+}
+
+func (s *ProductStorageSuite) TestReadOnlyMode() {
+	mode := domain.NewReadOnlyMode(false)
+	storage := NewProductStorage(s.PostgresConn, nil, CacheOptions{}, mode)
+	product := s.factory.Product()
+	s.Require().NoError(storage.CreateProduct(s.Ctx, product))
+
+	mode.Set(true)
+
+	s.ErrorIs(storage.CreateProduct(s.Ctx, s.factory.Product()), domain.ErrReadOnly)
+	_, _, err := storage.UpsertProduct(s.Ctx, s.factory.Product())
+	s.ErrorIs(err, domain.ErrReadOnly)
+	quantity := 5
+	_, err = storage.UpdateProduct(s.Ctx, &domain.UpdateProductRequest{Id: product.Id, Quantity: &quantity})
+	s.ErrorIs(err, domain.ErrReadOnly)
+	_, err = storage.AdjustQuantity(s.Ctx, product.Id, -1)
+	s.ErrorIs(err, domain.ErrReadOnly)
+	s.ErrorIs(storage.DeleteProduct(s.Ctx, product.Id), domain.ErrReadOnly)
+
+	products, err := storage.Products(s.Ctx, &domain.GetProductsRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(products, 1)
+	s.Equal(product.Quantity, products[0].Quantity)
+
+	count, err := storage.CountProducts(s.Ctx, &domain.GetProductsRequest{})
+	s.Require().NoError(err)
+	s.Equal(1, count)
+
+	mode.Set(false)
+	_, err = storage.AdjustQuantity(s.Ctx, product.Id, -1)
+	s.NoError(err)
+}
+
+func (s *ProductStorageSuite) TestQuantityCheck_NegativeWrites() {
+	product := s.factory.ProductWithQuantity(3)
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, product))
+
+	// a writer the domain validation knows nothing about, here a trigger taking 5 more items on every write
+	_, err := s.PostgresConn.Exec(s.Ctx, `CREATE FUNCTION take_five() RETURNS trigger AS $$
+		BEGIN
+			NEW.quantity := NEW.quantity - 5;
+			RETURN NEW;
+		END $$ LANGUAGE plpgsql`)
+	s.Require().NoError(err)
+	defer func() {
+		_, err := s.PostgresConn.Exec(s.Ctx, "DROP FUNCTION take_five() CASCADE")
+		s.NoError(err)
+	}()
+	_, err = s.PostgresConn.Exec(s.Ctx, "CREATE TRIGGER take_five BEFORE INSERT OR UPDATE ON products FOR EACH ROW EXECUTE FUNCTION take_five()")
+	s.Require().NoError(err)
+
+	// the database rejects what the validation let through, and the storage maps it to a domain error
+	quantity := 2
+	_, err = s.storage.UpdateProduct(s.Ctx, &domain.UpdateProductRequest{Id: product.Id, Quantity: &quantity})
+	s.ErrorIs(err, domain.ErrInsufficientStock)
+
+	_, err = s.storage.AdjustQuantity(s.Ctx, product.Id, 1)
+	s.ErrorIs(err, domain.ErrInsufficientStock)
+
+	err = s.storage.CreateProduct(s.Ctx, s.factory.ProductWithQuantity(1))
+	s.ErrorIs(err, domain.ErrProductValidation)
+
+	products, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(products, 1)
+	s.Equal(3, products[0].Quantity)
+}
+
+func (s *ProductStorageSuite) TestAdjustQuantity_RestocksEmptyProduct() {
+	product := s.factory.ProductWithQuantity(0)
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, product))
+
+	// warm the cache so the restock has to invalidate it
+	available := true
+	products, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{Available: &available})
+	s.Require().NoError(err)
+	s.Empty(products)
+
+	restocked, err := s.storage.AdjustQuantity(s.Ctx, product.Id, 5)
+	s.Require().NoError(err)
+	s.Equal(product.Id, restocked.Id)
+	s.Equal(5, restocked.Quantity)
+	s.True(restocked.IsAvailable())
+
+	products, err = s.storage.Products(s.Ctx, &domain.GetProductsRequest{Available: &available})
+	s.Require().NoError(err)
+	s.Require().Len(products, 1)
+	s.Equal(product.Id, products[0].Id)
+}
+
+func (s *ProductStorageSuite) TestAdjustQuantity_Errors() {
+	product := s.factory.ProductWithQuantity(2)
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, product))
+
+	_, err := s.storage.AdjustQuantity(s.Ctx, product.Id, -3)
+	s.ErrorIs(err, domain.ErrInsufficientStock)
+	var stockErr *domain.InsufficientStockError
+	s.Require().ErrorAs(err, &stockErr)
+	s.Equal([]domain.StockShortage{{ProductId: product.Id, Requested: 3, Available: 2}}, stockErr.Shortages)
+
+	products, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{Ids: []uuid.UUID{product.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(products, 1)
+	s.Equal(2, products[0].Quantity)
+
+	_, err = s.storage.AdjustQuantity(s.Ctx, uuid.New(), -1)
+	s.ErrorIs(err, domain.ErrProductNotFound)
+
+	_, err = s.storage.AdjustQuantity(s.Ctx, uuid.New(), 1)
+	s.ErrorIs(err, domain.ErrProductNotFound)
+}
+
+func (s *ProductStorageSuite) TestUpdateProduct_Version() {
+	product := s.factory.ProductWithQuantity(2)
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, product))
+
+	quantity, version := 4, 1
+	updated, err := s.storage.UpdateProduct(s.Ctx, &domain.UpdateProductRequest{Id: product.Id, Quantity: &quantity, Version: &version})
+	s.Require().NoError(err)
+	s.Equal(4, updated.Quantity)
+	s.Equal(2, updated.Version)
+
+	// the same version again was already superseded
+	quantity = 7
+	_, err = s.storage.UpdateProduct(s.Ctx, &domain.UpdateProductRequest{Id: product.Id, Quantity: &quantity, Version: &version})
+	s.ErrorIs(err, domain.ErrVersionConflict)
+
+	// an unversioned update always applies and still bumps the version
+	updated, err = s.storage.UpdateProduct(s.Ctx, &domain.UpdateProductRequest{Id: product.Id, Quantity: &quantity})
+	s.Require().NoError(err)
+	s.Equal(7, updated.Quantity)
+	s.Equal(3, updated.Version)
+}
+
+func (s *ProductStorageSuite) TestDeleteProduct_HiddenByDefault() {
+	deleted, kept := s.factory.Product(), s.factory.Product()
+	for _, product := range []*domain.Product{deleted, kept} {
+		s.Require().NoError(s.storage.CreateProduct(s.Ctx, product))
+	}
+
+	// warm the cache so the deletion has to invalidate it
+	products, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{})
+	s.Require().NoError(err)
+	s.Len(products, 2)
+
+	s.Require().NoError(s.storage.DeleteProduct(s.Ctx, deleted.Id))
+
+	products, err = s.storage.Products(s.Ctx, &domain.GetProductsRequest{})
+	s.Require().NoError(err)
+	s.Require().Len(products, 1)
+	s.Equal(kept.Id, products[0].Id)
+
+	count, err := s.storage.CountProducts(s.Ctx, &domain.GetProductsRequest{})
+	s.Require().NoError(err)
+	s.Equal(1, count)
+
+	// lookups by id hide it as well, so it can no longer be ordered
+	products, err = s.storage.Products(s.Ctx, &domain.GetProductsRequest{Ids: []uuid.UUID{deleted.Id}})
+	s.Require().NoError(err)
+	s.Empty(products)
+}
+
+func (s *ProductStorageSuite) TestDeleteProduct_VisibleWhenRequested() {
+	product := s.factory.Product()
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, product))
+	s.Require().NoError(s.storage.DeleteProduct(s.Ctx, product.Id))
+
+	req := &domain.GetProductsRequest{IncludeDeleted: true}
+	products, err := s.storage.Products(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Require().Len(products, 1)
+	s.True(products[0].IsDeleted())
+
+	count, err := s.storage.CountProducts(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Equal(1, count)
+}
+
+func (s *ProductStorageSuite) TestDeleteProduct_Errors() {
+	s.ErrorIs(s.storage.DeleteProduct(s.Ctx, uuid.New()), domain.ErrProductNotFound)
+
+	product := s.factory.ProductWithQuantity(2)
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, product))
+	s.Require().NoError(s.storage.DeleteProduct(s.Ctx, product.Id))
+
+	s.ErrorIs(s.storage.DeleteProduct(s.Ctx, product.Id), domain.ErrProductNotFound)
+
+	// a deleted product's stock is frozen
+	_, err := s.storage.AdjustQuantity(s.Ctx, product.Id, 1)
+	s.ErrorIs(err, domain.ErrProductNotFound)
+
+	quantity := 5
+	_, err = s.storage.UpdateProduct(s.Ctx, &domain.UpdateProductRequest{Id: product.Id, Quantity: &quantity})
+	s.ErrorIs(err, domain.ErrProductNotFound)
+
+	products, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{Ids: []uuid.UUID{product.Id}, IncludeDeleted: true})
+	s.Require().NoError(err)
+	s.Require().Len(products, 1)
+	s.Equal(2, products[0].Quantity)
+}
+
+func (s *ProductStorageSuite) createPricedProducts(prices ...int) {
+	for _, price := range prices {
+		product := s.factory.Product()
+		product.Price = price
+		s.Require().NoError(s.storage.CreateProduct(s.Ctx, product))
+	}
+}
+
+func (s *ProductStorageSuite) TestProducts_PriceRange() {
+	s.createPricedProducts(0, 500, 1000, 1500, 2000)
+
+	minPrice, maxPrice := 500, 1500
+	req := &domain.GetProductsRequest{MinPrice: &minPrice, MaxPrice: &maxPrice}
+	products, err := s.storage.Products(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Len(products, 3)
+	for _, product := range products {
+		s.GreaterOrEqual(product.Price, minPrice)
+		s.LessOrEqual(product.Price, maxPrice)
+	}
+
+	count, err := s.storage.CountProducts(s.Ctx, &domain.GetProductsRequest{MinPrice: &minPrice, MaxPrice: &maxPrice})
+	s.Require().NoError(err)
+	s.Equal(3, count)
+
+	// a single bound is open on the other side
+	products, err = s.storage.Products(s.Ctx, &domain.GetProductsRequest{MinPrice: &maxPrice})
+	s.Require().NoError(err)
+	s.Len(products, 2)
+}
+
+func (s *ProductStorageSuite) TestProducts_SortByPrice() {
+	s.createPricedProducts(1500, 0, 2000, 500, 1000)
+
+	prices := func(products []*domain.Product) []int {
+		result := make([]int, 0, len(products))
+		for _, product := range products {
+			result = append(result, product.Price)
+		}
+		return result
+	}
+
+	asc, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{Sort: domain.ProductSortPrice, Order: domain.SortOrderAsc})
+	s.Require().NoError(err)
+	s.Equal([]int{0, 500, 1000, 1500, 2000}, prices(asc))
+
+	desc, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{Sort: domain.ProductSortPrice, Order: domain.SortOrderDesc})
+	s.Require().NoError(err)
+	s.Equal([]int{2000, 1500, 1000, 500, 0}, prices(desc))
+
+	// offset pages follow the same order
+	page, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{
+		Sort:   domain.ProductSortPrice,
+		Order:  domain.SortOrderAsc,
+		Limit:  2,
+		Offset: 2,
+	})
+	s.Require().NoError(err)
+	s.Equal([]int{1000, 1500}, prices(page))
+}
+
+func (s *ProductStorageSuite) TestProducts_InvalidSort() {
+	s.createPricedProducts(100)
+
+	_, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{Sort: "quantity; DROP TABLE products"})
+	s.ErrorIs(err, domain.ErrInvalidSort)
+
+	_, err = s.storage.Products(s.Ctx, &domain.GetProductsRequest{
+		Sort:  domain.ProductSortPrice,
+		After: domain.NewCursor(time.Now(), uuid.New()),
+	})
+	s.ErrorIs(err, domain.ErrInvalidSort)
+}
+
+func (s *ProductStorageSuite) createDescribedProducts(descriptions ...string) {
+	for _, description := range descriptions {
+		product := s.factory.Product()
+		product.Description = description
+		s.Require().NoError(s.storage.CreateProduct(s.Ctx, product))
+	}
+}
+
+func descriptions(products []*domain.Product) []string {
+	result := make([]string, 0, len(products))
+	for _, product := range products {
+		result = append(result, product.Description)
+	}
+	return result
+}
+
+func (s *ProductStorageSuite) TestProducts_Search() {
+	s.createDescribedProducts("Red Phone", "phone case", "Headphones", "Laptop", "100% cotton_shirt")
+
+	products, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{Search: "PHONE"})
+	s.Require().NoError(err)
+	s.ElementsMatch([]string{"Red Phone", "phone case", "Headphones"}, descriptions(products))
+
+	count, err := s.storage.CountProducts(s.Ctx, &domain.GetProductsRequest{Search: "PHONE"})
+	s.Require().NoError(err)
+	s.Equal(3, count)
+
+	// LIKE wildcards in the term match literally
+	products, err = s.storage.Products(s.Ctx, &domain.GetProductsRequest{Search: "0% cotton_"})
+	s.Require().NoError(err)
+	s.Equal([]string{"100% cotton_shirt"}, descriptions(products))
+
+	products, err = s.storage.Products(s.Ctx, &domain.GetProductsRequest{Search: "%"})
+	s.Require().NoError(err)
+	s.Len(products, 1)
+
+	count, err = s.storage.CountProducts(s.Ctx, &domain.GetProductsRequest{Search: "tablet"})
+	s.Require().NoError(err)
+	s.Zero(count)
+}
+
+func (s *ProductStorageSuite) TestProducts_SearchRelevance() {
+	s.createDescribedProducts("Wireless headphones", "Phone", "Red phone case with a long strap and extra padding", "Laptop")
+	tagged := s.factory.Product()
+	tagged.Description = "Charger"
+	tagged.Tags = []string{"phones", "accessories"}
+	s.Require().NoError(s.storage.CreateProduct(s.Ctx, tagged))
+
+	products, err := s.storage.Products(s.Ctx, &domain.GetProductsRequest{
+		Search: "phones",
+		Sort:   domain.ProductSortRelevance,
+		Order:  domain.SortOrderDesc,
+	})
+	s.Require().NoError(err)
+	// the exact description outranks the longer one and description words outrank tags;
+	// "headphones" is only found as a part of a word and ranks last
+	s.Equal([]string{
+		"Phone",
+		"Red phone case with a long strap and extra padding",
+		"Charger",
+		"Wireless headphones",
+	}, descriptions(products))
+
+	_, err = s.storage.Products(s.Ctx, &domain.GetProductsRequest{Sort: domain.ProductSortRelevance})
+	s.ErrorIs(err, domain.ErrInvalidSort)
+}
+
+func (s *ProductStorageSuite) TestProducts_SearchUsesIndex() {
+	s.createDescribedProducts("Phone", "Laptop")
+
+	query, args, err := applyProductFilters(
+		sq.StatementBuilder.PlaceholderFormat(sq.Dollar).Select("id").From("products"),
+		&domain.GetProductsRequest{Search: "phone"},
+	).ToSql()
+	s.Require().NoError(err)
+
+	tx, err := s.PostgresConn.Begin(s.Ctx)
+	s.Require().NoError(err)
+	defer tx.Rollback(s.Ctx)
+
+	// a handful of rows is cheaper to scan, so the planner has to be told off it
+	_, err = tx.Exec(s.Ctx, "SET LOCAL enable_seqscan = off")
+	s.Require().NoError(err)
+
+	rows, err := tx.Query(s.Ctx, "EXPLAIN "+query, args...)
+	s.Require().NoError(err)
+	var plan strings.Builder
+	for rows.Next() {
+		var line string
+		s.Require().NoError(rows.Scan(&line))
+		plan.WriteString(line + "\n")
+	}
+	s.Require().NoError(rows.Err())
+
+	s.Contains(plan.String(), "products_search_vector_idx")
+}
+
+func (s *ProductStorageSuite) TestListQueries_ReadIndexesInOrder() {
+	psql := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	products := func(req *domain.GetProductsRequest) sq.SelectBuilder {
+		req.Validate()
+		query, err := orderProducts(applyProductFilters(psql.Select("id").From("products"), req), req)
+		s.Require().NoError(err)
+		return query
+	}
+	orders := func(req *domain.GetOrdersRequest) sq.SelectBuilder {
+		req.Validate()
+		query, err := sortPage(applyOrderFilters(psql.Select("id").From("orders"), req),
+			orderSortColumns, req.Sort, req.Order, req.After, req.Limit, req.Offset)
+		s.Require().NoError(err)
+		return query
+	}
+
+	tests := map[string]struct {
+		query sq.SelectBuilder
+		index string
+	}{
+		"products": {products(&domain.GetProductsRequest{Limit: 3}), "products_active_idx"},
+		"products by cursor": {
+			products(&domain.GetProductsRequest{Limit: 3, After: domain.NewCursor(time.Now(), uuid.New())}),
+			"products_active_idx",
+		},
+		"products by price": {
+			products(&domain.GetProductsRequest{Sort: domain.ProductSortPrice, Order: domain.SortOrderAsc, Limit: 3}),
+			"products_price_idx",
+		},
+		"orders": {orders(&domain.GetOrdersRequest{Limit: 3}), "orders_created_at_idx"},
+	}
+
+	for name, tt := range tests {
+		query, args, err := tt.query.ToSql()
+		s.Require().NoError(err)
+
+		plan := func() string {
+			tx, err := s.PostgresConn.Begin(s.Ctx)
+			s.Require().NoError(err)
+			defer tx.Rollback(s.Ctx)
+
+			// a handful of rows is cheaper to scan and sort, so the planner has to be told off it
+			_, err = tx.Exec(s.Ctx, "SET LOCAL enable_seqscan = off")
+			s.Require().NoError(err)
+
+			rows, err := tx.Query(s.Ctx, "EXPLAIN "+query, args...)
+			s.Require().NoError(err)
+			defer rows.Close()
+
+			var plan strings.Builder
+			for rows.Next() {
+				var line string
+				s.Require().NoError(rows.Scan(&line))
+				plan.WriteString(line + "\n")
+			}
+			s.Require().NoError(rows.Err())
+			return plan.String()
+		}()
+
+		// the index hands the rows over in the listing's order, nothing is sorted on top of it
+		s.Contains(plan, tt.index, name)
+		s.NotContains(plan, "Sort", name)
+	}
+}
+
+func (s *ProductStorageSuite) TestProducts_TiedCreatedAtOrderIsStable() {
+	createdAt := time.Now().Truncate(time.Microsecond)
+	for range 7 {
+		product := s.factory.Product()
+		product.CreatedAt = createdAt
+		s.Require().NoError(s.storage.CreateProduct(s.Ctx, product))
+	}
+
+	// a fresh storage per query, so every list is read from the database and not from the cache
+	ids := func(req domain.GetProductsRequest) []uuid.UUID {
+		storage := NewProductStorage(s.PostgresConn, nil, CacheOptions{}, nil)
+		defer storage.Close()
+
+		products, err := storage.Products(s.Ctx, &req)
+		s.Require().NoError(err)
+		result := make([]uuid.UUID, 0, len(products))
+		for _, product := range products {
+			result = append(result, product.Id)
+		}
+		return result
+	}
+
+	for _, sort := range []domain.ProductSort{domain.ProductSortCreatedAt, domain.ProductSortPrice} {
+		all := ids(domain.GetProductsRequest{Sort: sort, Limit: 10})
+		s.Require().Len(all, 7)
+		for range 3 {
+			s.Equal(all, ids(domain.GetProductsRequest{Sort: sort, Limit: 10}), sort)
+		}
+
+		// offset pages neither skip nor repeat rows
+		var paged []uuid.UUID
+		for offset := 0; offset < len(all); offset += 3 {
+			paged = append(paged, ids(domain.GetProductsRequest{Sort: sort, Limit: 3, Offset: offset})...)
+		}
+		s.Equal(all, paged, sort)
+	}
+
+	// keyset pages follow the same order as offset pages
+	all := ids(domain.GetProductsRequest{Limit: 10})
+	var paged []uuid.UUID
+	var after *domain.Cursor
+	for len(paged) < len(all) {
+		page := ids(domain.GetProductsRequest{Limit: 3, After: after})
+		s.Require().NotEmpty(page)
+		paged = append(paged, page...)
+		after = domain.NewCursor(createdAt, page[len(page)-1])
+	}
+	s.Equal(all, paged)
+}
+
+func TestProductStorageSuite(t *testing.T) {
+	suite.Run(t, new(ProductStorageSuite))
+}
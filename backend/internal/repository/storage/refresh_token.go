@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mts/internal/domain"
+)
+
+// NewRefreshTokenStorage keeps refresh tokens on the primary, a token must be found right after it is issued;
+// writes fail with domain.ErrReadOnly while readOnly is on
+func NewRefreshTokenStorage(pool *pgxpool.Pool, readOnly *domain.ReadOnlyMode) domain.RefreshTokenStorage {
+	return &refreshTokenStorage{
+		db:       pool,
+		readOnly: readOnly,
+		psql:     sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+type refreshTokenStorage struct {
+	db querier
+	// readOnly rejects writes while it is on, nil never does
+	readOnly *domain.ReadOnlyMode
+	psql     sq.StatementBuilderType
+}
+
+func (s *refreshTokenStorage) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	ctx, span := tracer.Start(ctx, "RefreshTokenStorage.CreateRefreshToken")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return err
+	}
+
+	return s.insert(ctx, s.db, token)
+}
+
+func (s *refreshTokenStorage) insert(ctx context.Context, db querier, token *domain.RefreshToken) error {
+	query := s.psql.Insert("refresh_tokens").
+		Columns("id", "user_id", "token_hash", "expires_at", "created_at").
+		Values(token.Id, token.UserId, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(ctx, sql, args...)
+	return classifyError(err)
+}
+
+func (s *refreshTokenStorage) RefreshToken(ctx context.Context, hash []byte) (*domain.RefreshToken, error) {
+	ctx, span := tracer.Start(ctx, "RefreshTokenStorage.RefreshToken")
+	defer span.End()
+
+	query := s.psql.Select(refreshTokenColumns...).
+		From("refresh_tokens").
+		Where(sq.Eq{"token_hash": hash})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var dto refreshTokenDto
+	if err = s.db.QueryRow(ctx, sql, args...).Scan(dto.scanTargets()...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrInvalidRefreshToken
+		}
+		return nil, classifyError(err)
+	}
+
+	return dto.toDomain(), nil
+}
+
+func (s *refreshTokenStorage) RotateRefreshToken(ctx context.Context, id uuid.UUID, next *domain.RefreshToken) error {
+	ctx, span := tracer.Start(ctx, "RefreshTokenStorage.RotateRefreshToken")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return classifyError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	// the conditions make concurrent refreshes with the same token race for a single winner
+	query := s.psql.Update("refresh_tokens").
+		Set("rotated_at", domain.Now()).
+		Where(sq.Eq{"id": id, "rotated_at": nil, "revoked_at": nil})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return classifyError(err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrRefreshTokenReused
+	}
+
+	if err = s.insert(ctx, tx, next); err != nil {
+		return err
+	}
+
+	return classifyError(tx.Commit(ctx))
+}
+
+func (s *refreshTokenStorage) RevokeRefreshToken(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "RefreshTokenStorage.RevokeRefreshToken")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return err
+	}
+
+	query := s.psql.Update("refresh_tokens").
+		Set("revoked_at", domain.Now()).
+		Where(sq.Eq{"id": id, "revoked_at": nil})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	return classifyError(err)
+}
+
+func (s *refreshTokenStorage) RevokeUserRefreshTokens(ctx context.Context, userId uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "RefreshTokenStorage.RevokeUserRefreshTokens")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return err
+	}
+
+	query := s.psql.Update("refresh_tokens").
+		Set("revoked_at", domain.Now()).
+		Where(sq.Eq{"user_id": userId, "revoked_at": nil})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(ctx, sql, args...)
+	return classifyError(err)
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"mts/internal/domain"
+)
+
+type refreshTokenDto struct {
+	Id        uuid.UUID  `db:"id"`
+	UserId    uuid.UUID  `db:"user_id"`
+	TokenHash []byte     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	RotatedAt *time.Time `db:"rotated_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+// refreshTokenColumns lists the selected refresh token columns in the order of refreshTokenDto.scanTargets
+var refreshTokenColumns = []string{
+	"id", "user_id", "token_hash", "expires_at", "created_at", "rotated_at", "revoked_at",
+}
+
+func (dto *refreshTokenDto) scanTargets() []any {
+	return []any{
+		&dto.Id, &dto.UserId, &dto.TokenHash, &dto.ExpiresAt, &dto.CreatedAt, &dto.RotatedAt, &dto.RevokedAt,
+	}
+}
+
+func (dto *refreshTokenDto) toDomain() *domain.RefreshToken {
+	return &domain.RefreshToken{
+		Id:        dto.Id,
+		UserId:    dto.UserId,
+		TokenHash: dto.TokenHash,
+		ExpiresAt: dto.ExpiresAt,
+		CreatedAt: dto.CreatedAt,
+		RotatedAt: dto.RotatedAt,
+		RevokedAt: dto.RevokedAt,
+	}
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"mts/internal/domain"
+	"shared"
+)
+
+type RefreshTokenStorageSuite struct {
+	shared.Suite[any]
+	storage domain.RefreshTokenStorage
+	user    *domain.User
+}
+
+func (s *RefreshTokenStorageSuite) SetupSuite() {
+	s.PostgresEnabled = true
+	s.Suite.SetupSuite()
+	s.storage = NewRefreshTokenStorage(s.PostgresConn, nil)
+}
+
+func (s *RefreshTokenStorageSuite) SetupTest() {
+	s.user = (&domain.Factory{}).User()
+	s.Require().NoError(NewUserStorage(s.PostgresConn, nil, CacheOptions{}, nil).CreateUser(s.Ctx, s.user))
+}
+
+func (s *RefreshTokenStorageSuite) TearDownTest() {
+	_, err := s.PostgresConn.Exec(s.Ctx, "TRUNCATE TABLE users RESTART IDENTITY CASCADE")
+	s.Require().NoError(err)
+}
+
+func (s *RefreshTokenStorageSuite) newToken() (*domain.RefreshToken, string) {
+	token, secret, err := domain.NewRefreshToken(s.user.Id, time.Hour)
+	s.Require().NoError(err)
+	return token, secret
+}
+
+func (s *RefreshTokenStorageSuite) TestCreateAndFind() {
+	token, secret := s.newToken()
+	s.Require().NoError(s.storage.CreateRefreshToken(s.Ctx, token))
+
+	found, err := s.storage.RefreshToken(s.Ctx, domain.HashRefreshToken(secret))
+	s.Require().NoError(err)
+	s.Equal(token.Id, found.Id)
+	s.Equal(s.user.Id, found.UserId)
+	s.True(found.IsActive())
+
+	_, err = s.storage.RefreshToken(s.Ctx, domain.HashRefreshToken("unknown"))
+	s.ErrorIs(err, domain.ErrInvalidRefreshToken)
+}
+
+func (s *RefreshTokenStorageSuite) TestRotateRefreshToken() {
+	current, currentSecret := s.newToken()
+	s.Require().NoError(s.storage.CreateRefreshToken(s.Ctx, current))
+
+	next, nextSecret := s.newToken()
+	s.Require().NoError(s.storage.RotateRefreshToken(s.Ctx, current.Id, next))
+
+	rotated, err := s.storage.RefreshToken(s.Ctx, domain.HashRefreshToken(currentSecret))
+	s.Require().NoError(err)
+	s.True(rotated.IsRotated())
+
+	found, err := s.storage.RefreshToken(s.Ctx, domain.HashRefreshToken(nextSecret))
+	s.Require().NoError(err)
+	s.True(found.IsActive())
+
+	// a second rotation of the same token loses and stores nothing
+	other, otherSecret := s.newToken()
+	s.ErrorIs(s.storage.RotateRefreshToken(s.Ctx, current.Id, other), domain.ErrRefreshTokenReused)
+	_, err = s.storage.RefreshToken(s.Ctx, domain.HashRefreshToken(otherSecret))
+	s.ErrorIs(err, domain.ErrInvalidRefreshToken)
+}
+
+func (s *RefreshTokenStorageSuite) TestRevokeRefreshToken() {
+	revoked, revokedSecret := s.newToken()
+	kept, keptSecret := s.newToken()
+	s.Require().NoError(s.storage.CreateRefreshToken(s.Ctx, revoked))
+	s.Require().NoError(s.storage.CreateRefreshToken(s.Ctx, kept))
+
+	s.Require().NoError(s.storage.RevokeRefreshToken(s.Ctx, revoked.Id))
+
+	found, err := s.storage.RefreshToken(s.Ctx, domain.HashRefreshToken(revokedSecret))
+	s.Require().NoError(err)
+	s.False(found.IsActive())
+
+	found, err = s.storage.RefreshToken(s.Ctx, domain.HashRefreshToken(keptSecret))
+	s.Require().NoError(err)
+	s.True(found.IsActive())
+
+	next, _ := s.newToken()
+	s.ErrorIs(s.storage.RotateRefreshToken(s.Ctx, revoked.Id, next), domain.ErrRefreshTokenReused)
+}
+
+func (s *RefreshTokenStorageSuite) TestRevokeUserRefreshTokens() {
+	first, firstSecret := s.newToken()
+	second, secondSecret := s.newToken()
+	s.Require().NoError(s.storage.CreateRefreshToken(s.Ctx, first))
+	s.Require().NoError(s.storage.CreateRefreshToken(s.Ctx, second))
+
+	s.Require().NoError(s.storage.RevokeUserRefreshTokens(s.Ctx, s.user.Id))
+
+	for _, secret := range []string{firstSecret, secondSecret} {
+		found, err := s.storage.RefreshToken(s.Ctx, domain.HashRefreshToken(secret))
+		s.Require().NoError(err)
+		s.False(found.IsActive())
+	}
+
+	next, _ := s.newToken()
+	s.ErrorIs(s.storage.RotateRefreshToken(s.Ctx, first.Id, next), domain.ErrRefreshTokenReused)
+}
+
+func (s *RefreshTokenStorageSuite) TestReadOnlyMode() {
+	storage := NewRefreshTokenStorage(s.PostgresConn, domain.NewReadOnlyMode(true))
+
+	token, _ := s.newToken()
+	s.ErrorIs(storage.CreateRefreshToken(s.Ctx, token), domain.ErrReadOnly)
+	s.ErrorIs(storage.RevokeRefreshToken(s.Ctx, token.Id), domain.ErrReadOnly)
+	s.ErrorIs(storage.RevokeUserRefreshTokens(s.Ctx, s.user.Id), domain.ErrReadOnly)
+}
+
+func TestRefreshTokenStorageSuite(t *testing.T) {
+	suite.Run(t, new(RefreshTokenStorageSuite))
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type primaryReadsKey struct{}
+
+// withPrimaryReads sends the reads under ctx to the primary. Storages use it to read back
+// a row they have just written, which a lagging replica may not have yet.
+func withPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+// readQuerier picks where a read runs: the replica when there is one, the primary otherwise
+func readQuerier(ctx context.Context, primary, replica querier) querier {
+	if replica == nil || ctx.Value(primaryReadsKey{}) != nil {
+		return primary
+	}
+	return replica
+}
+
+// replicaQuerier keeps a missing replica pool a nil querier rather than a nil pointer inside one
+func replicaQuerier(replica *pgxpool.Pool) querier {
+	if replica == nil {
+		return nil
+	}
+	return replica
+}
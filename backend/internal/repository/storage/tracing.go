@@ -0,0 +1,5 @@
+package storage
+
+import "go.opentelemetry.io/otel"
+
+var tracer = otel.Tracer("mts/internal/repository/storage")
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"mts/internal/domain"
+	"shared"
+	sharedConfig "shared/config"
+)
+
+// querier is what the storages run statements on: the pool, or the transaction of a unit of work.
+// Begin on a transaction opens a savepoint, so storage methods stay atomic inside a unit of work.
+type querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// cacheInvalidator drops the cached results of a storage
+type cacheInvalidator interface {
+	invalidateCache(ctx context.Context)
+}
+
+// singleAttempt keeps transaction-scoped storages from retrying on their own:
+// after a serialization failure only the whole unit of work can be retried
+var singleAttempt = sharedConfig.Retry{MaxAttempts: 1}
+
+// NewUnitOfWork runs callbacks in a single transaction. The given storages share the database
+// with the transaction-scoped ones, so their caches are dropped once a unit of work ends.
+// The transaction-scoped storages reject writes while readOnly is on, like the given ones.
+func NewUnitOfWork(
+	pool *pgxpool.Pool,
+	retry sharedConfig.Retry,
+	readOnly *domain.ReadOnlyMode,
+	userStorage domain.UserStorage,
+	productStorage domain.ProductStorage,
+	orderStorage domain.OrderStorage,
+) domain.UnitOfWork {
+	uow := &unitOfWork{pool: pool, retry: retry, readOnly: readOnly}
+	for _, storage := range []any{userStorage, productStorage, orderStorage} {
+		if invalidator, ok := storage.(cacheInvalidator); ok {
+			uow.invalidators = append(uow.invalidators, invalidator)
+		}
+	}
+	return uow
+}
+
+type unitOfWork struct {
+	pool         *pgxpool.Pool
+	retry        sharedConfig.Retry
+	readOnly     *domain.ReadOnlyMode
+	invalidators []cacheInvalidator
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx *domain.TxStorages) error) error {
+	ctx, span := tracer.Start(ctx, "UnitOfWork.Do")
+	defer span.End()
+
+	// a second transaction would not see the request's writes and could wait on its row locks
+	if tx, ok := domain.TxStoragesFromContext(ctx); ok {
+		return fn(ctx, tx)
+	}
+
+	// the whole callback is repeated on serialization failures and dropped connections
+	err := shared.Retry(ctx, u.retry, func(ctx context.Context) error {
+		tx, err := u.pool.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		// fresh storages per attempt, so nothing read inside the transaction outlives it
+		users := newUserStorage(tx, CacheOptions{}, newMemoryResultCache[[]*domain.User](0, false))
+		products := newProductStorage(tx, CacheOptions{}, newMemoryResultCache[[]*domain.Product](0, false))
+		orders := newOrderStorage(tx, CacheOptions{}, singleAttempt,
+			newMemoryResultCache[[]*domain.Order](0, false),
+			newMemoryResultCache[int](0, false),
+		)
+		users.readOnly, products.readOnly, orders.readOnly = u.readOnly, u.readOnly, u.readOnly
+
+		err = fn(ctx, &domain.TxStorages{Users: users, Products: products, Orders: orders})
+		if err != nil {
+			return err
+		}
+
+		return tx.Commit(ctx)
+	})
+
+	// a failed commit may still have been applied, so the caches are dropped either way
+	for _, invalidator := range u.invalidators {
+		invalidator.invalidateCache(ctx)
+	}
+
+	return classifyError(err)
+}
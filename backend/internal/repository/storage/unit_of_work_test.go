@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+
+	"mts/internal/domain"
+	"shared"
+	sharedConfig "shared/config"
+)
+
+type UnitOfWorkSuite struct {
+	shared.Suite[any]
+	unitOfWork     domain.UnitOfWork
+	userStorage    domain.UserStorage
+	productStorage domain.ProductStorage
+	orderStorage   domain.OrderStorage
+	factory        *domain.Factory
+}
+
+func (s *UnitOfWorkSuite) SetupSuite() {
+	s.PostgresEnabled = true
+	s.Suite.SetupSuite()
+	s.userStorage = NewUserStorage(s.PostgresConn, nil, CacheOptions{}, nil)
+	s.productStorage = NewProductStorage(s.PostgresConn, nil, CacheOptions{}, nil)
+	s.orderStorage = NewOrderStorage(s.PostgresConn, nil, CacheOptions{}, sharedConfig.Retry{}, nil)
+	s.unitOfWork = NewUnitOfWork(s.PostgresConn, sharedConfig.Retry{}, nil, s.userStorage, s.productStorage, s.orderStorage)
+	s.factory = &domain.Factory{}
+}
+
+func (s *UnitOfWorkSuite) TearDownTest() {
+	_, err := s.PostgresConn.Exec(s.Ctx, "TRUNCATE TABLE order_items, orders, products, users RESTART IDENTITY CASCADE")
+	s.Require().NoError(err)
+}
+
+// placeOrder reserves quantity of the product and stores an order for it inside tx
+func (s *UnitOfWorkSuite) placeOrder(ctx context.Context, tx *domain.TxStorages, userId uuid.UUID, product *domain.Product, quantity int) *domain.Order {
+	remaining := product.Quantity - quantity
+	_, err := tx.Products.UpdateProduct(ctx, &domain.UpdateProductRequest{Id: product.Id, Quantity: &remaining})
+	s.Require().NoError(err)
+
+	order := s.factory.Order(userId, product.Id)
+	s.Require().NoError(tx.Orders.CreateOrder(ctx, order))
+	return order
+}
+
+func (s *UnitOfWorkSuite) productQuantity(id uuid.UUID) int {
+	products, err := s.productStorage.Products(s.Ctx, &domain.GetProductsRequest{Ids: []uuid.UUID{id}})
+	s.Require().NoError(err)
+	s.Require().Len(products, 1)
+	return products[0].Quantity
+}
+
+func (s *UnitOfWorkSuite) TestDo_RollsBackOnError() {
+	user := s.factory.User()
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+	product := s.factory.ProductWithQuantity(10)
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, product))
+
+	// warm the cache, a rolled back unit of work must not leave it stale either
+	s.Equal(10, s.productQuantity(product.Id))
+
+	failure := errors.New("payment declined")
+	var orderId uuid.UUID
+	err := s.unitOfWork.Do(s.Ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+		orderId = s.placeOrder(ctx, tx, user.Id, product, 3).Id
+
+		// both writes are visible inside the transaction
+		products, err := tx.Products.Products(ctx, &domain.GetProductsRequest{Ids: []uuid.UUID{product.Id}})
+		s.Require().NoError(err)
+		s.Equal(7, products[0].Quantity)
+
+		return failure
+	})
+	s.ErrorIs(err, failure)
+
+	s.Equal(10, s.productQuantity(product.Id))
+
+	orders, err := s.orderStorage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{orderId}})
+	s.Require().NoError(err)
+	s.Empty(orders)
+
+	var items int
+	s.Require().NoError(s.PostgresConn.QueryRow(s.Ctx, "SELECT COUNT(*) FROM order_items").Scan(&items))
+	s.Zero(items)
+}
+
+func (s *UnitOfWorkSuite) TestDo_ReadOnlyMode() {
+	product := s.factory.ProductWithQuantity(5)
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, product))
+
+	unitOfWork := NewUnitOfWork(s.PostgresConn, sharedConfig.Retry{}, domain.NewReadOnlyMode(true),
+		s.userStorage, s.productStorage, s.orderStorage)
+
+	err := unitOfWork.Do(s.Ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+		// reads inside the transaction keep working
+		products, err := tx.Products.Products(ctx, &domain.GetProductsRequest{Ids: []uuid.UUID{product.Id}})
+		s.Require().NoError(err)
+		s.Require().Len(products, 1)
+
+		_, err = tx.Products.AdjustQuantity(ctx, product.Id, -1)
+		return err
+	})
+	s.ErrorIs(err, domain.ErrReadOnly)
+}
+
+func (s *UnitOfWorkSuite) TestDo_Commits() {
+	user := s.factory.User()
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+	product := s.factory.ProductWithQuantity(10)
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, product))
+	s.Equal(10, s.productQuantity(product.Id))
+
+	var orderId uuid.UUID
+	err := s.unitOfWork.Do(s.Ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+		orderId = s.placeOrder(ctx, tx, user.Id, product, 3).Id
+		return nil
+	})
+	s.Require().NoError(err)
+
+	// the root storages' caches were dropped on commit
+	s.Equal(7, s.productQuantity(product.Id))
+
+	orders, err := s.orderStorage.Orders(s.Ctx, &domain.GetOrdersRequest{Ids: []uuid.UUID{orderId}})
+	s.Require().NoError(err)
+	s.Len(orders, 1)
+}
+
+func (s *UnitOfWorkSuite) TestLockUser_HoldsLockUntilTheEnd() {
+	user := s.factory.User()
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+
+	err := s.unitOfWork.Do(s.Ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+		locked, err := tx.Users.LockUser(ctx, user.Id)
+		s.Require().NoError(err)
+		s.Equal(user.Email, locked.Email)
+
+		// another locker can't take the row until this one ends
+		_, err = s.PostgresConn.Exec(s.Ctx, "SELECT 1 FROM users WHERE id = $1 FOR NO KEY UPDATE NOWAIT", user.Id)
+		s.Error(err)
+		return nil
+	})
+	s.Require().NoError(err)
+
+	_, err = s.PostgresConn.Exec(s.Ctx, "SELECT 1 FROM users WHERE id = $1 FOR NO KEY UPDATE NOWAIT", user.Id)
+	s.NoError(err)
+
+	_, err = s.userStorage.LockUser(s.Ctx, uuid.New())
+	s.ErrorIs(err, domain.ErrUserNotFound)
+	s.Require().NoError(s.userStorage.DeleteUser(s.Ctx, user.Id))
+	_, err = s.userStorage.LockUser(s.Ctx, user.Id)
+	s.ErrorIs(err, domain.ErrUserNotFound)
+}
+
+func (s *UnitOfWorkSuite) TestDo_JoinsRequestTransaction() {
+	user := s.factory.User()
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+	product := s.factory.ProductWithQuantity(10)
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, product))
+
+	failure := errors.New("later step failed")
+	err := s.unitOfWork.Do(s.Ctx, func(ctx context.Context, outer *domain.TxStorages) error {
+		ctx = domain.ContextWithTxStorages(ctx, outer)
+
+		// the nested unit of work commits nothing on its own
+		s.Require().NoError(s.unitOfWork.Do(ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+			s.Same(outer, tx)
+			s.placeOrder(ctx, tx, user.Id, product, 3)
+			return nil
+		}))
+
+		return failure
+	})
+	s.ErrorIs(err, failure)
+
+	s.Equal(10, s.productQuantity(product.Id))
+
+	var orders int
+	s.Require().NoError(s.PostgresConn.QueryRow(s.Ctx, "SELECT COUNT(*) FROM orders").Scan(&orders))
+	s.Zero(orders)
+}
+
+func (s *UnitOfWorkSuite) TestLockOrders_HoldsLocksUntilTheEnd() {
+	user := s.factory.User()
+	s.Require().NoError(s.userStorage.CreateUser(s.Ctx, user))
+	product := s.factory.ProductWithQuantity(10)
+	s.Require().NoError(s.productStorage.CreateProduct(s.Ctx, product))
+	order := s.factory.Order(user.Id, product.Id)
+	s.Require().NoError(s.orderStorage.CreateOrder(s.Ctx, order))
+
+	err := s.unitOfWork.Do(s.Ctx, func(ctx context.Context, tx *domain.TxStorages) error {
+		orders, err := tx.Orders.LockOrders(ctx, []uuid.UUID{order.Id, uuid.New()})
+		s.Require().NoError(err)
+		s.Require().Len(orders, 1)
+		s.Equal(order.Id, orders[0].Id)
+		s.Len(orders[0].Items, 1)
+
+		// another transaction can't take the row until this one ends
+		_, err = s.PostgresConn.Exec(s.Ctx, "SELECT 1 FROM orders WHERE id = $1 FOR UPDATE NOWAIT", order.Id)
+		s.Error(err)
+		return nil
+	})
+	s.Require().NoError(err)
+
+	_, err = s.PostgresConn.Exec(s.Ctx, "SELECT 1 FROM orders WHERE id = $1 FOR UPDATE NOWAIT", order.Id)
+	s.NoError(err)
+
+	_, err = s.orderStorage.LockOrder(s.Ctx, uuid.New())
+	s.ErrorIs(err, domain.ErrOrderNotFound)
+}
+
+func TestUnitOfWorkSuite(t *testing.T) {
+	suite.Run(t, new(UnitOfWorkSuite))
+}
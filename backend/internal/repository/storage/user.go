@@ -2,33 +2,62 @@ package storage
 
 import (
 	"context"
-	"time"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/jellydator/ttlcache/v3"
 
 	"mts/internal/domain"
 )
 
-func NewUserStorage(pool *pgxpool.Pool) domain.UserStorage {
+// NewUserStorage runs writes on pool and reads on replica, falling back to pool when replica is nil;
+// writes fail with domain.ErrReadOnly while readOnly is on
+func NewUserStorage(pool *pgxpool.Pool, replica *pgxpool.Pool, cache CacheOptions, readOnly *domain.ReadOnlyMode) domain.UserStorage {
+	s := newUserStorage(pool, cache, newMemoryResultCache[[]*domain.User](cache.TTL, true))
+	s.replica = replicaQuerier(replica)
+	s.readOnly = readOnly
+	return s
+}
+
+func newUserStorage(db querier, cache CacheOptions, results resultCache[[]*domain.User]) *userStorage {
 	return &userStorage{
-		pool: pool,
-		psql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
-		cache: ttlcache.New[domain.CacheKey, []*domain.User](
-			ttlcache.WithTTL[domain.CacheKey, []*domain.User](time.Hour),
-		),
+		db:           db,
+		psql:         sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		cacheOptions: cache,
+		cache:        results,
 	}
 }
 
 type userStorage struct {
-	pool  *pgxpool.Pool
-	psql  sq.StatementBuilderType
-	cache *ttlcache.Cache[domain.CacheKey, []*domain.User]
+	db querier
+	// readOnly rejects writes while it is on, nil never does
+	readOnly *domain.ReadOnlyMode
+	// replica serves Users and CountUsers when set, see readQuerier
+	replica querier
+	psql    sq.StatementBuilderType
+	cache   resultCache[[]*domain.User]
+	// cacheOptions decides which results are cached and for how long
+	cacheOptions CacheOptions
+
+	cacheHits   atomic.Uint64
+	cacheMisses atomic.Uint64
 }
 
 func (s *userStorage) CreateUser(ctx context.Context, user *domain.User) error {
-	s.cache.DeleteAll()
+	ctx, span := tracer.Start(ctx, "UserStorage.CreateUser")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return err
+	}
+
+	invalidate(ctx, s.cache)
 
 	if err := user.Validate(); err != nil {
 		return err
@@ -40,54 +69,136 @@ func (s *userStorage) CreateUser(ctx context.Context, user *domain.User) error {
 	}
 
 	query := s.psql.Insert("users").
-		Columns("id", "first_name", "last_name", "age", "is_married", "password_hash", "salt", "created_at").
-		Values(dto.Id, dto.FirstName, dto.LastName, dto.Age, dto.IsMarried, dto.PasswordHash, dto.Salt, dto.CreatedAt)
+		Columns(userInsertColumns...).
+		Values(dto.insertValues()...)
 
 	sql, args, err := query.ToSql()
 	if err != nil {
 		return err
 	}
 
-	_, err = s.pool.Exec(ctx, sql, args...)
-	return err
+	_, err = s.db.Exec(ctx, sql, args...)
+	if isUniqueViolation(err, usersEmailKey) {
+		conflict := domain.NewValidationError(domain.ErrUserExists)
+		conflict.Add("email", fmt.Sprintf("email %s is already registered", user.Email))
+		return conflict
+	}
+	return classifyInsertError(err, usersPrimaryKey, domain.ErrUserExists)
 }
 
-func (s *userStorage) Users(ctx context.Context, req *domain.GetUsersRequest) ([]*domain.User, error) {
-	s.cache.DeleteExpired()
-	req.Validate()
+// CreateUsers inserts the batch in one statement. The unique email index decides what is taken, also against
+// concurrent registrations: a row whose email a live user holds is skipped rather than failing the statement,
+// so every conflict of the batch is reported before it is rolled back.
+func (s *userStorage) CreateUsers(ctx context.Context, users []*domain.User) error {
+	ctx, span := tracer.Start(ctx, "UserStorage.CreateUsers")
+	defer span.End()
 
-	if cacheUsers := s.cache.Get(req.CacheKey()); cacheUsers != nil {
-		return cacheUsers.Value(), nil
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return err
 	}
 
-	query := s.psql.Select("id", "first_name", "last_name", "age", "is_married", "password_hash", "salt", "created_at").
-		From("users")
+	if len(users) == 0 {
+		return nil
+	}
 
-	if len(req.Ids) > 0 {
-		query = query.Where(sq.Eq{"id": req.Ids})
+	invalidate(ctx, s.cache)
+
+	for i, user := range users {
+		if err := user.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", domain.UserBatchField(i, "user"), err)
+		}
 	}
 
-	query = query.OrderBy("created_at DESC", "id").
-		Limit(uint64(req.Limit)).
-		Offset(uint64(req.Offset))
+	// a repeated email would only be skipped like a taken one, it is reported with the user it repeats
+	conflicts := domain.NewValidationError(domain.ErrUserExists)
+	first := make(map[string]int, len(users))
+	query := s.psql.Insert("users").Columns(userInsertColumns...)
+	for i, user := range users {
+		if user.Email != "" {
+			email := strings.ToLower(user.Email)
+			if j, ok := first[email]; ok {
+				conflicts.Add(domain.UserBatchField(i, "email"),
+					fmt.Sprintf("email %s is already used by %s", user.Email, domain.UserBatchField(j, "email")))
+				continue
+			}
+			first[email] = i
+		}
+
+		dto, err := toUserDto(user)
+		if err != nil {
+			return err
+		}
+		query = query.Values(dto.insertValues()...)
+	}
+	query = query.Suffix("ON CONFLICT (lower(email)) WHERE deleted_at IS NULL DO NOTHING RETURNING id")
 
 	sql, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return classifyError(err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return classifyError(err)
+	}
+	inserted, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return classifyInsertError(err, usersPrimaryKey, domain.ErrUserExists)
+	}
+
+	for i, user := range users {
+		if user.Email != "" && first[strings.ToLower(user.Email)] == i && !slices.Contains(inserted, user.Id) {
+			conflicts.Add(domain.UserBatchField(i, "email"),
+				fmt.Sprintf("email %s is already registered", user.Email))
+		}
+	}
+	if err = conflicts.Err(); err != nil {
+		return err
+	}
+
+	return classifyError(tx.Commit(ctx))
+}
+
+func (s *userStorage) Users(ctx context.Context, req *domain.GetUsersRequest) ([]*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserStorage.Users")
+	defer span.End()
+
+	req.Validate()
+
+	if cacheUsers, ok := cachedResult(ctx, s.cache, req.CacheKey()); ok {
+		s.cacheHits.Add(1)
+		return cacheUsers, nil
+	}
+	s.cacheMisses.Add(1)
+
+	query, err := sortPage(applyUserFilters(s.psql.Select(userColumns...).From("users"), req),
+		userSortColumns, req.Sort, req.Order, nil, req.Limit, req.Offset)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := s.pool.Query(ctx, sql, args...)
+	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, err
 	}
+
+	rows, err := readQuerier(ctx, s.db, s.replica).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, classifyError(err)
+	}
 	defer rows.Close()
 
 	var users []*domain.User
 	for rows.Next() {
 		var dto userDto
 
-		err := rows.Scan(&dto.Id, &dto.FirstName, &dto.LastName, &dto.Age, &dto.IsMarried, &dto.PasswordHash, &dto.Salt, &dto.CreatedAt)
-		if err != nil {
+		if err := rows.Scan(dto.scanTargets()...); err != nil {
 			return nil, err
 		}
 
@@ -100,34 +211,247 @@ func (s *userStorage) Users(ctx context.Context, req *domain.GetUsersRequest) ([
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, classifyError(err)
 	}
 
-	s.cache.Set(req.CacheKey(), users, ttlcache.DefaultTTL)
+	if ttl, ok := s.cacheOptions.resultTTL(len(req.Ids) > 0); ok {
+		cacheResult(ctx, s.cache, req.CacheKey(), users, ttl)
+	}
 
 	return users, nil
 }
 
 func (s *userStorage) CountUsers(ctx context.Context, req *domain.GetUsersRequest) (int, error) {
+	ctx, span := tracer.Start(ctx, "UserStorage.CountUsers")
+	defer span.End()
+
 	req.Validate()
 
-	query := s.psql.Select("COUNT(*)").
-		From("users")
+	query := applyUserFilters(s.psql.Select("COUNT(*)").From("users"), req)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, err
+	}
 
+	var count int
+	err = readQuerier(ctx, s.db, s.replica).QueryRow(ctx, sql, args...).Scan(&count)
+	if err != nil {
+		return 0, classifyError(err)
+	}
+
+	return count, nil
+}
+
+// applyUserFilters adds the request filters so Users and CountUsers always agree
+func applyUserFilters(query sq.SelectBuilder, req *domain.GetUsersRequest) sq.SelectBuilder {
 	if len(req.Ids) > 0 {
 		query = query.Where(sq.Eq{"id": req.Ids})
 	}
 
+	if !req.IncludeDeleted {
+		query = query.Where(sq.Eq{"deleted_at": nil})
+	}
+
+	if req.CreatedFrom != nil {
+		query = query.Where(sq.GtOrEq{"created_at": *req.CreatedFrom})
+	}
+
+	if req.CreatedTo != nil {
+		query = query.Where(sq.LtOrEq{"created_at": *req.CreatedTo})
+	}
+
+	// the expression matches users_full_name_trgm_idx, so the trigram index serves the search
+	if req.Name != "" {
+		query = query.Where(sq.Expr("(first_name || ' ' || last_name) ILIKE ?", "%"+escapeLike(req.Name)+"%"))
+	}
+
+	return query
+}
+
+// userSortColumns allowlists the columns users can be ordered by, see sortPage
+var userSortColumns = map[domain.UserSort]string{
+	domain.UserSortCreatedAt: "created_at",
+	// the same expression the name search matches
+	domain.UserSortName: "(first_name || ' ' || last_name)",
+	domain.UserSortAge:  "age",
+}
+
+// escapeLike makes the LIKE wildcards in s match literally
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// usersByIdsBatchSize keeps each id lookup to a moderately sized ANY($1) query
+const usersByIdsBatchSize = 100
+
+func (s *userStorage) UsersByIds(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserStorage.UsersByIds")
+	defer span.End()
+
+	uniqueIds := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		uniqueIds = append(uniqueIds, id)
+	}
+
+	result := make(map[uuid.UUID]*domain.User, len(uniqueIds))
+	for batch := range slices.Chunk(uniqueIds, usersByIdsBatchSize) {
+		users, err := s.Users(ctx, &domain.GetUsersRequest{
+			Ids:            batch,
+			IncludeDeleted: true,
+			Limit:          len(batch),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, user := range users {
+			result[user.Id] = user
+		}
+	}
+
+	return result, nil
+}
+
+func (s *userStorage) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "UserStorage.DeleteUser")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return err
+	}
+
+	invalidate(ctx, s.cache)
+
+	query := s.psql.Update("users").
+		Set("deleted_at", domain.Now()).
+		Where(sq.Eq{"id": id, "deleted_at": nil})
+
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return 0, err
+		return err
 	}
 
-	var count int
-	err = s.pool.QueryRow(ctx, sql, args...).Scan(&count)
+	result, err := s.db.Exec(ctx, sql, args...)
 	if err != nil {
-		return 0, err
+		return classifyError(err)
 	}
 
-	return count, nil
+	if result.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (s *userStorage) VerifyEmail(ctx context.Context, token string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserStorage.VerifyEmail")
+	defer span.End()
+
+	if err := s.readOnly.CheckWrite(); err != nil {
+		return nil, err
+	}
+
+	invalidate(ctx, s.cache)
+
+	if token == "" {
+		return nil, domain.ErrInvalidVerificationToken
+	}
+
+	query := s.psql.Update("users").
+		Set("email_verified", true).
+		Set("verification_token_hash", nil).
+		Where(sq.Eq{"verification_token_hash": domain.HashVerificationToken(token), "deleted_at": nil}).
+		Suffix("RETURNING " + strings.Join(userColumns, ", "))
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var dto userDto
+	if err = s.db.QueryRow(ctx, sql, args...).Scan(dto.scanTargets()...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrInvalidVerificationToken
+		}
+		return nil, classifyError(err)
+	}
+
+	return dto.toDomain()
+}
+
+// UserByEmail reads from the primary, a user logging in right after registering may not be on the replica yet
+func (s *userStorage) UserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserStorage.UserByEmail")
+	defer span.End()
+
+	query := s.psql.Select(userColumns...).
+		From("users").
+		Where("lower(email) = lower(?)", email).
+		Where(sq.Eq{"deleted_at": nil})
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var dto userDto
+	if err = s.db.QueryRow(ctx, sql, args...).Scan(dto.scanTargets()...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, classifyError(err)
+	}
+
+	return dto.toDomain()
+}
+
+func (s *userStorage) LockUser(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "UserStorage.LockUser")
+	defer span.End()
+
+	// NO KEY UPDATE is enough to serialize the lockers, while inserts referencing the user go on;
+	// outside a unit of work the lock is released as soon as the statement ends
+	query := s.psql.Select(userColumns...).
+		From("users").
+		Where(sq.Eq{"id": id, "deleted_at": nil}).
+		Suffix("FOR NO KEY UPDATE")
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var dto userDto
+	if err = s.db.QueryRow(ctx, sql, args...).Scan(dto.scanTargets()...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, classifyError(err)
+	}
+
+	return dto.toDomain()
+}
+
+func (s *userStorage) invalidateCache(ctx context.Context) {
+	invalidate(ctx, s.cache)
+}
+
+func (s *userStorage) CacheStats() domain.CacheStats {
+	return domain.CacheStats{
+		Hits:   s.cacheHits.Load(),
+		Misses: s.cacheMisses.Load(),
+		Size:   s.cache.Len(),
+	}
+}
+
+func (s *userStorage) Close() {
+	s.cache.Close()
 }
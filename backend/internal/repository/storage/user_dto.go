@@ -1,7 +1,6 @@
 package storage
 
 import (
-	"encoding/hex"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,40 +9,66 @@ import (
 )
 
 type userDto struct {
-	Id           uuid.UUID `db:"id"`
-	FirstName    string    `db:"first_name"`
-	LastName     string    `db:"last_name"`
-	Age          int       `db:"age"`
-	IsMarried    bool      `db:"is_married"`
-	PasswordHash string    `db:"password_hash"`
-	Salt         string    `db:"salt"`
-	CreatedAt    time.Time `db:"created_at"`
+	Id                    uuid.UUID  `db:"id"`
+	FirstName             string     `db:"first_name"`
+	LastName              string     `db:"last_name"`
+	Age                   int        `db:"age"`
+	IsMarried             bool       `db:"is_married"`
+	Email                 *string    `db:"email"`
+	EmailVerified         bool       `db:"email_verified"`
+	VerificationTokenHash []byte     `db:"verification_token_hash"`
+	PasswordHash          []byte     `db:"password_hash"`
+	PasswordAlgorithm     string     `db:"password_algorithm"`
+	Salt                  []byte     `db:"salt"`
+	Roles                 []string   `db:"roles"`
+	CreatedAt             time.Time  `db:"created_at"`
+	DeletedAt             *time.Time `db:"deleted_at"`
 }
 
-func (dto *userDto) toDomain() (*domain.User, error) {
-	user := &domain.User{
-		Id:        dto.Id,
-		FirstName: dto.FirstName,
-		LastName:  dto.LastName,
-		Age:       dto.Age,
-		IsMarried: dto.IsMarried,
-		CreatedAt: dto.CreatedAt,
+// userInsertColumns lists the columns a new user is inserted with, in the order of userDto.insertValues
+var userInsertColumns = []string{
+	"id", "first_name", "last_name", "age", "is_married", "email", "email_verified", "verification_token_hash",
+	"password_hash", "password_algorithm", "salt", "roles", "created_at",
+}
+
+func (dto *userDto) insertValues() []any {
+	return []any{
+		dto.Id, dto.FirstName, dto.LastName, dto.Age, dto.IsMarried, dto.Email, dto.EmailVerified, dto.VerificationTokenHash,
+		dto.PasswordHash, dto.PasswordAlgorithm, dto.Salt, dto.Roles, dto.CreatedAt,
+	}
+}
+
+// userColumns lists the selected user columns in the order of userDto.scanTargets
+var userColumns = []string{
+	"id", "first_name", "last_name", "age", "is_married", "email", "email_verified",
+	"password_hash", "password_algorithm", "salt", "roles", "created_at", "deleted_at",
+}
+
+func (dto *userDto) scanTargets() []any {
+	return []any{
+		&dto.Id, &dto.FirstName, &dto.LastName, &dto.Age, &dto.IsMarried, &dto.Email, &dto.EmailVerified,
+		&dto.PasswordHash, &dto.PasswordAlgorithm, &dto.Salt, &dto.Roles, &dto.CreatedAt, &dto.DeletedAt,
 	}
+}
 
-	if dto.PasswordHash != "" {
-		passwordHash, err := hex.DecodeString(dto.PasswordHash)
-		if err != nil {
-			return nil, err
-		}
-		user.PasswordHash = passwordHash
+func (dto *userDto) toDomain() (*domain.User, error) {
+	user := &domain.User{
+		Id:                dto.Id,
+		FirstName:         dto.FirstName,
+		LastName:          dto.LastName,
+		Age:               dto.Age,
+		IsMarried:         dto.IsMarried,
+		EmailVerified:     dto.EmailVerified,
+		PasswordHash:      dto.PasswordHash,
+		PasswordAlgorithm: dto.PasswordAlgorithm,
+		Salt:              dto.Salt,
+		Roles:             dto.Roles,
+		CreatedAt:         dto.CreatedAt,
+		DeletedAt:         dto.DeletedAt,
 	}
 
-	if dto.Salt != "" {
-		salt, err := hex.DecodeString(dto.Salt)
-		if err != nil {
-			return nil, err
-		}
-		user.Salt = salt
+	if dto.Email != nil {
+		user.Email = *dto.Email
 	}
 
 	return user, nil
@@ -51,20 +76,26 @@ func (dto *userDto) toDomain() (*domain.User, error) {
 
 func toUserDto(user *domain.User) (*userDto, error) {
 	dto := &userDto{
-		Id:        user.Id,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Age:       user.Age,
-		IsMarried: user.IsMarried,
-		CreatedAt: user.CreatedAt,
+		Id:                user.Id,
+		FirstName:         user.FirstName,
+		LastName:          user.LastName,
+		Age:               user.Age,
+		IsMarried:         user.IsMarried,
+		EmailVerified:     user.EmailVerified,
+		PasswordHash:      user.PasswordHash,
+		PasswordAlgorithm: user.PasswordAlgorithm,
+		Salt:              user.Salt,
+		Roles:             user.Roles,
+		CreatedAt:         user.CreatedAt,
+		DeletedAt:         user.DeletedAt,
 	}
 
-	if len(user.PasswordHash) > 0 {
-		dto.PasswordHash = hex.EncodeToString(user.PasswordHash)
+	if user.Email != "" {
+		dto.Email = &user.Email
 	}
 
-	if len(user.Salt) > 0 {
-		dto.Salt = hex.EncodeToString(user.Salt)
+	if user.VerificationToken != "" {
+		dto.VerificationTokenHash = domain.HashVerificationToken(user.VerificationToken)
 	}
 
 	return dto, nil
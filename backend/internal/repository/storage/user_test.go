@@ -1,9 +1,18 @@
 package storage
 
 import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"github.com/pressly/goose/v3"
 	"github.com/stretchr/testify/suite"
 
 	"mts/internal/domain"
@@ -18,7 +27,7 @@ type UserStorageSuite struct {
 func (s *UserStorageSuite) SetupSuite() {
 	s.PostgresEnabled = true
 	s.Suite.SetupSuite()
-	s.storage = NewUserStorage(s.PostgresConn)
+	s.storage = NewUserStorage(s.PostgresConn, nil, CacheOptions{}, nil)
 }
 
 func (s *UserStorageSuite) TearDownTest() {
@@ -45,6 +54,193 @@ func (s *UserStorageSuite) TestCreateUser_Success() {
 	s.NotEmpty(user.Salt)
 }
 
+func (s *UserStorageSuite) TestCreateUser_DuplicateId() {
+	user := (&domain.Factory{}).User()
+	s.Require().NoError(s.storage.CreateUser(s.Ctx, user))
+
+	// a replayed create keeps the id it was first given
+	replay := (&domain.Factory{}).User()
+	replay.Id = user.Id
+	err := s.storage.CreateUser(s.Ctx, replay)
+	s.ErrorIs(err, domain.ErrUserExists)
+	s.Equal("USER_ALREADY_EXISTS", domain.ErrorCode(err))
+}
+
+func (s *UserStorageSuite) TestCreateUser_DuplicateEmail() {
+	factory := &domain.Factory{}
+	registered := factory.User()
+	registered.Email = "Taken@example.com"
+	s.Require().NoError(s.storage.CreateUser(s.Ctx, registered))
+
+	duplicate := factory.User()
+	duplicate.Email = "taken@EXAMPLE.com"
+	err := s.storage.CreateUser(s.Ctx, duplicate)
+	s.Require().ErrorIs(err, domain.ErrUserExists)
+
+	var conflict *domain.ValidationError
+	s.Require().ErrorAs(err, &conflict)
+	s.Equal(map[string]string{"email": "email taken@EXAMPLE.com is already registered"}, conflict.Fields)
+
+	// the email of a deleted user is free again
+	s.Require().NoError(s.storage.DeleteUser(s.Ctx, registered.Id))
+	s.NoError(s.storage.CreateUser(s.Ctx, duplicate))
+}
+
+func (s *UserStorageSuite) TestCreateUsers_Success() {
+	factory := &domain.Factory{}
+	users := []*domain.User{factory.User(), factory.User(), factory.User()}
+	users[0].Email = "first@example.com"
+	users[1].Email = "second@example.com"
+	users[2].Email = ""
+
+	s.Require().NoError(s.storage.CreateUsers(s.Ctx, users))
+
+	stored, err := s.storage.UsersByIds(s.Ctx, []uuid.UUID{users[0].Id, users[1].Id, users[2].Id})
+	s.Require().NoError(err)
+	s.Len(stored, 3)
+	s.Equal("second@example.com", stored[users[1].Id].Email)
+	s.Equal(users[1].PasswordHash, stored[users[1].Id].PasswordHash)
+}
+
+func (s *UserStorageSuite) TestCreateUsers_DuplicateEmail() {
+	factory := &domain.Factory{}
+	registered := factory.User()
+	registered.Email = "taken@example.com"
+	s.Require().NoError(s.storage.CreateUser(s.Ctx, registered))
+
+	users := []*domain.User{factory.User(), factory.User(), factory.User(), factory.User()}
+	users[0].Email = "fresh@example.com"
+	users[1].Email = "Taken@Example.com"
+	users[2].Email = "other@example.com"
+	users[3].Email = "FRESH@example.com"
+
+	err := s.storage.CreateUsers(s.Ctx, users)
+	s.Require().ErrorIs(err, domain.ErrUserExists)
+
+	var conflicts *domain.ValidationError
+	s.Require().ErrorAs(err, &conflicts)
+	s.Equal(map[string]string{
+		"users[1].email": "email Taken@Example.com is already registered",
+		"users[3].email": "email FRESH@example.com is already used by users[0].email",
+	}, conflicts.Fields)
+
+	// the batch is rolled back as a whole
+	count, err := s.storage.CountUsers(s.Ctx, &domain.GetUsersRequest{})
+	s.Require().NoError(err)
+	s.Equal(1, count)
+
+	// the email of a deleted user is free again
+	s.Require().NoError(s.storage.DeleteUser(s.Ctx, registered.Id))
+	users = []*domain.User{factory.User()}
+	users[0].Email = "taken@example.com"
+	s.NoError(s.storage.CreateUsers(s.Ctx, users))
+}
+
+func (s *UserStorageSuite) TestCreateUsers_ConcurrentRegistration() {
+	factory := &domain.Factory{}
+	concurrent := factory.User()
+	concurrent.Email = "race@example.com"
+
+	tx, err := s.PostgresConn.Begin(s.Ctx)
+	s.Require().NoError(err)
+	defer tx.Rollback(s.Ctx)
+	s.Require().NoError(newUserStorage(tx, CacheOptions{}, newMemoryResultCache[[]*domain.User](0, false)).
+		CreateUser(s.Ctx, concurrent))
+
+	users := []*domain.User{factory.User(), factory.User()}
+	users[1].Email = "Race@example.com"
+
+	// the batch waits on the index entry of the uncommitted registration and loses once it commits
+	done := make(chan error, 1)
+	go func() {
+		done <- s.storage.CreateUsers(s.Ctx, users)
+	}()
+	select {
+	case err = <-done:
+		s.FailNow("the batch did not wait for the concurrent registration", "returned %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	s.Require().NoError(tx.Commit(s.Ctx))
+
+	err = <-done
+	var conflicts *domain.ValidationError
+	s.Require().ErrorAs(err, &conflicts)
+	s.ErrorIs(err, domain.ErrUserExists)
+	s.Equal(map[string]string{"users[1].email": "email Race@example.com is already registered"}, conflicts.Fields)
+
+	count, err := s.storage.CountUsers(s.Ctx, &domain.GetUsersRequest{})
+	s.Require().NoError(err)
+	s.Equal(1, count)
+}
+
+func (s *UserStorageSuite) TestCreateUser_StoresRawPasswordBytes() {
+	user := (&domain.Factory{}).User()
+	s.Require().NoError(s.storage.CreateUser(s.Ctx, user))
+
+	var hash, salt []byte
+	s.Require().NoError(s.PostgresConn.QueryRow(s.Ctx,
+		"SELECT password_hash, salt FROM users WHERE id = $1", user.Id).Scan(&hash, &salt))
+	s.Equal(user.PasswordHash, hash)
+	s.Equal(user.Salt, salt)
+
+	users, err := s.storage.Users(s.Ctx, &domain.GetUsersRequest{Ids: []uuid.UUID{user.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(users, 1)
+	s.Equal(user.PasswordHash, users[0].PasswordHash)
+	s.Equal(user.Salt, users[0].Salt)
+	s.True(users[0].VerifyPassword("password123"))
+	s.False(users[0].VerifyPassword("wrong-password"))
+}
+
+func (s *UserStorageSuite) TestCreateUser_StoresRoles() {
+	plain := (&domain.Factory{}).User()
+	admin := (&domain.Factory{}).User()
+	admin.Roles = []string{domain.RoleUser, domain.RoleAdmin}
+	s.Require().NoError(s.storage.CreateUser(s.Ctx, plain))
+	s.Require().NoError(s.storage.CreateUser(s.Ctx, admin))
+
+	users, err := s.storage.UsersByIds(s.Ctx, []uuid.UUID{plain.Id, admin.Id})
+	s.Require().NoError(err)
+	s.Equal([]string{domain.RoleUser}, users[plain.Id].Roles)
+	s.Equal([]string{domain.RoleUser, domain.RoleAdmin}, users[admin.Id].Roles)
+}
+
+// passwordBytesMigration is the version of 00009_users_password_bytea.sql
+const passwordBytesMigration = 9
+
+func (s *UserStorageSuite) TestPasswordBytesMigration_ConvertsHexText() {
+	user := (&domain.Factory{}).User()
+
+	migrationDir, err := shared.MigrationDirectory("postgres")
+	s.Require().NoError(err)
+	db, err := sql.Open("postgres", s.Config.Postgres.Dsn())
+	s.Require().NoError(err)
+	migrations, err := goose.NewProvider(goose.DialectPostgres, db, os.DirFS(migrationDir))
+	s.Require().NoError(err)
+	defer migrations.Close()
+
+	// the columns are turned back into hex text by the migration's down step and converted by its up step
+	_, err = migrations.ApplyVersion(s.Ctx, passwordBytesMigration, false)
+	s.Require().NoError(err)
+	_, err = s.PostgresConn.Exec(s.Ctx,
+		"INSERT INTO users (id, first_name, last_name, age, password_hash, password_algorithm, salt) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		user.Id, user.FirstName, user.LastName, user.Age,
+		hex.EncodeToString(user.PasswordHash), user.PasswordAlgorithm, hex.EncodeToString(user.Salt))
+	s.Require().NoError(err)
+	_, err = migrations.ApplyVersion(s.Ctx, passwordBytesMigration, true)
+	s.Require().NoError(err)
+
+	// pooled connections cached the statements of the text columns
+	s.PostgresConn.Reset()
+
+	users, err := s.storage.Users(s.Ctx, &domain.GetUsersRequest{Ids: []uuid.UUID{user.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(users, 1)
+	s.Equal(user.PasswordHash, users[0].PasswordHash)
+	s.Equal(user.Salt, users[0].Salt)
+	s.True(users[0].VerifyPassword("password123"))
+}
+
 func (s *UserStorageSuite) TestCreateUser_MinimalValidFields() {
 	user := &domain.User{
 		FirstName: "Jane",
@@ -317,8 +513,8 @@ func (s *UserStorageSuite) TestUsers_Cache() {
 	err = s.storage.CreateUser(s.Ctx, user)
 	s.Require().NoError(err)
 
+	// lookups by ids are not cached, so list the users instead
 	req := &domain.GetUsersRequest{
-		Ids:    []uuid.UUID{user.Id},
 		Limit:  1,
 		Offset: 0,
 	}
@@ -353,6 +549,194 @@ func (s *UserStorageSuite) TestUsers_Cache() {
 	s.Len(users3, 1)
 }
 
+func (s *UserStorageSuite) TestUsers_CacheStats() {
+	storage := NewUserStorage(s.PostgresConn, nil, CacheOptions{}, nil)
+
+	user := &domain.User{
+		FirstName: "Stats",
+		LastName:  "Test",
+		Age:       30,
+		IsMarried: false,
+	}
+	err := user.SetPassword("password123")
+	s.Require().NoError(err)
+
+	err = storage.CreateUser(s.Ctx, user)
+	s.Require().NoError(err)
+
+	req := &domain.GetUsersRequest{
+		Limit:  10,
+		Offset: 0,
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err = storage.Users(s.Ctx, req)
+		s.Require().NoError(err)
+	}
+
+	stats := storage.CacheStats()
+	s.Equal(uint64(1), stats.Misses)
+	s.Equal(uint64(2), stats.Hits)
+	s.Equal(1, stats.Size)
+}
+
+func (s *UserStorageSuite) TestUsers_IdsLookupsNotCached() {
+	storage := NewUserStorage(s.PostgresConn, nil, CacheOptions{}, nil)
+
+	user := (&domain.Factory{}).User()
+	s.Require().NoError(storage.CreateUser(s.Ctx, user))
+
+	byIds := &domain.GetUsersRequest{Ids: []uuid.UUID{user.Id}, Limit: 10}
+	for range 2 {
+		users, err := storage.Users(s.Ctx, byIds)
+		s.Require().NoError(err)
+		s.Len(users, 1)
+	}
+
+	stats := storage.CacheStats()
+	s.Equal(uint64(2), stats.Misses)
+	s.Zero(stats.Hits)
+	s.Zero(stats.Size)
+
+	// list queries are still cached
+	list := &domain.GetUsersRequest{Limit: 10}
+	for range 2 {
+		_, err := storage.Users(s.Ctx, list)
+		s.Require().NoError(err)
+	}
+
+	stats = storage.CacheStats()
+	s.Equal(uint64(1), stats.Hits)
+	s.Equal(1, stats.Size)
+}
+
+func (s *UserStorageSuite) TestUsers_IdsLookupsCachedWithTTL() {
+	storage := NewUserStorage(s.PostgresConn, nil, CacheOptions{IdsTTL: time.Minute}, nil)
+
+	user := (&domain.Factory{}).User()
+	s.Require().NoError(storage.CreateUser(s.Ctx, user))
+
+	req := &domain.GetUsersRequest{Ids: []uuid.UUID{user.Id}, Limit: 10}
+	for range 2 {
+		_, err := storage.Users(s.Ctx, req)
+		s.Require().NoError(err)
+	}
+
+	stats := storage.CacheStats()
+	s.Equal(uint64(1), stats.Hits)
+	s.Equal(1, stats.Size)
+}
+
+func (s *UserStorageSuite) TestUsers_CanceledContext() {
+	ctx, cancel := context.WithCancel(s.Ctx)
+	cancel()
+
+	req := &domain.GetUsersRequest{
+		Ids:   []uuid.UUID{uuid.New()},
+		Limit: 1,
+	}
+	_, err := s.storage.Users(ctx, req)
+	s.Require().Error(err)
+	s.ErrorIs(err, domain.ErrRequestCanceled)
+	s.ErrorIs(err, context.Canceled)
+
+	_, err = s.storage.CountUsers(ctx, req)
+	s.Require().Error(err)
+	s.ErrorIs(err, domain.ErrRequestCanceled)
+}
+
+func (s *UserStorageSuite) TestDeleteUser_HiddenByDefault() {
+	var testUsers []*domain.User
+	for i := 0; i < 3; i++ {
+		user := &domain.User{
+			FirstName: "Delete",
+			LastName:  "Test",
+			Age:       25,
+			IsMarried: false,
+		}
+		err := user.SetPassword("password123")
+		s.Require().NoError(err)
+
+		err = s.storage.CreateUser(s.Ctx, user)
+		s.Require().NoError(err)
+
+		testUsers = append(testUsers, user)
+	}
+
+	err := s.storage.DeleteUser(s.Ctx, testUsers[0].Id)
+	s.Require().NoError(err)
+
+	users, err := s.storage.Users(s.Ctx, &domain.GetUsersRequest{})
+	s.Require().NoError(err)
+	s.Len(users, 2)
+	for _, user := range users {
+		s.NotEqual(testUsers[0].Id, user.Id)
+		s.False(user.IsDeleted())
+	}
+
+	count, err := s.storage.CountUsers(s.Ctx, &domain.GetUsersRequest{})
+	s.Require().NoError(err)
+	s.Equal(2, count)
+
+	users, err = s.storage.Users(s.Ctx, &domain.GetUsersRequest{
+		Ids: []uuid.UUID{testUsers[0].Id},
+	})
+	s.Require().NoError(err)
+	s.Empty(users)
+}
+
+func (s *UserStorageSuite) TestDeleteUser_VisibleWhenRequested() {
+	user := &domain.User{
+		FirstName: "Deleted",
+		LastName:  "Visible",
+		Age:       25,
+		IsMarried: false,
+	}
+	err := user.SetPassword("password123")
+	s.Require().NoError(err)
+
+	err = s.storage.CreateUser(s.Ctx, user)
+	s.Require().NoError(err)
+
+	err = s.storage.DeleteUser(s.Ctx, user.Id)
+	s.Require().NoError(err)
+
+	req := &domain.GetUsersRequest{
+		Ids:            []uuid.UUID{user.Id},
+		IncludeDeleted: true,
+	}
+	users, err := s.storage.Users(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Require().Len(users, 1)
+	s.True(users[0].IsDeleted())
+
+	count, err := s.storage.CountUsers(s.Ctx, req)
+	s.Require().NoError(err)
+	s.Equal(1, count)
+}
+
+func (s *UserStorageSuite) TestDeleteUser_NotFound() {
+	err := s.storage.DeleteUser(s.Ctx, uuid.New())
+	s.ErrorIs(err, domain.ErrUserNotFound)
+}
+
+func (s *UserStorageSuite) TestDeleteUser_AlreadyDeleted() {
+	user := &domain.User{
+		FirstName: "Twice",
+		LastName:  "Deleted",
+		Age:       25,
+		IsMarried: false,
+	}
+	err := user.SetPassword("password123")
+	s.Require().NoError(err)
+
+	err = s.storage.CreateUser(s.Ctx, user)
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.storage.DeleteUser(s.Ctx, user.Id))
+	s.ErrorIs(s.storage.DeleteUser(s.Ctx, user.Id), domain.ErrUserNotFound)
+}
+
 func (s *UserStorageSuite) TestUsers_RequestValidation() {
 	s.Run("zero limit defaults to 10", func() {
 		req := &domain.GetUsersRequest{
@@ -385,6 +769,296 @@ func (s *UserStorageSuite) TestUsers_RequestValidation() {
 	})
 }
 
+func (s *UserStorageSuite) TestUsersByIds_DuplicatesAndOrder() {
+	factory := &domain.Factory{}
+	first, second := factory.User(), factory.User()
+	s.Require().NoError(s.storage.CreateUser(s.Ctx, first))
+	s.Require().NoError(s.storage.CreateUser(s.Ctx, second))
+
+	unknown := uuid.New()
+	ids := []uuid.UUID{first.Id, second.Id, first.Id, unknown, first.Id}
+
+	users, err := s.storage.UsersByIds(s.Ctx, ids)
+	s.Require().NoError(err)
+	s.Len(users, 2)
+
+	// positional resolution follows the requested ids, created_at ordering is irrelevant
+	resolved := make([]*domain.User, 0, len(ids))
+	for _, id := range ids {
+		resolved = append(resolved, users[id])
+	}
+	s.Equal(first.Id, resolved[0].Id)
+	s.Equal(second.Id, resolved[1].Id)
+	s.Equal(first.Id, resolved[2].Id)
+	s.Nil(resolved[3])
+	s.Equal(first.Id, resolved[4].Id)
+}
+
+func (s *UserStorageSuite) TestUsersByIds_IncludesDeleted() {
+	user := (&domain.Factory{}).User()
+	s.Require().NoError(s.storage.CreateUser(s.Ctx, user))
+	s.Require().NoError(s.storage.DeleteUser(s.Ctx, user.Id))
+
+	users, err := s.storage.UsersByIds(s.Ctx, []uuid.UUID{user.Id})
+	s.Require().NoError(err)
+	s.Require().Contains(users, user.Id)
+	s.True(users[user.Id].IsDeleted())
+}
+
+func (s *UserStorageSuite) TestUsersByIds_MoreThanOnePage() {
+	factory := &domain.Factory{}
+	ids := make([]uuid.UUID, 0, usersByIdsBatchSize+5)
+	for range usersByIdsBatchSize + 5 {
+		user := factory.User()
+		s.Require().NoError(s.storage.CreateUser(s.Ctx, user))
+		ids = append(ids, user.Id)
+	}
+
+	users, err := s.storage.UsersByIds(s.Ctx, ids)
+	s.Require().NoError(err)
+	s.Len(users, len(ids))
+}
+
+func (s *UserStorageSuite) TestUsersByIds_Empty() {
+	users, err := s.storage.UsersByIds(s.Ctx, nil)
+	s.Require().NoError(err)
+	s.Empty(users)
+}
+
+func (s *UserStorageSuite) TestVerifyEmail() {
+	user := (&domain.Factory{}).User()
+	s.Require().NoError(user.StartEmailVerification())
+	s.Require().NoError(s.storage.CreateUser(s.Ctx, user))
+
+	// only the hash of the mailed token is stored
+	var stored []byte
+	s.Require().NoError(s.PostgresConn.QueryRow(s.Ctx, "SELECT verification_token_hash FROM users WHERE id = $1", user.Id).Scan(&stored))
+	s.Equal(domain.HashVerificationToken(user.VerificationToken), stored)
+
+	verified, err := s.storage.VerifyEmail(s.Ctx, user.VerificationToken)
+	s.Require().NoError(err)
+	s.Equal(user.Id, verified.Id)
+	s.Equal(user.Email, verified.Email)
+	s.True(verified.EmailVerified)
+	s.Empty(verified.VerificationToken)
+
+	// the token is single use
+	_, err = s.storage.VerifyEmail(s.Ctx, user.VerificationToken)
+	s.ErrorIs(err, domain.ErrInvalidVerificationToken)
+
+	users, err := s.storage.Users(s.Ctx, &domain.GetUsersRequest{Ids: []uuid.UUID{user.Id}})
+	s.Require().NoError(err)
+	s.Require().Len(users, 1)
+	s.True(users[0].EmailVerified)
+}
+
+func (s *UserStorageSuite) TestVerifyEmail_UnknownToken() {
+	_, err := s.storage.VerifyEmail(s.Ctx, "unknown")
+	s.ErrorIs(err, domain.ErrInvalidVerificationToken)
+
+	_, err = s.storage.VerifyEmail(s.Ctx, "")
+	s.ErrorIs(err, domain.ErrInvalidVerificationToken)
+}
+
+func (s *UserStorageSuite) TestUserByEmail() {
+	factory := &domain.Factory{}
+	email := "login." + uuid.NewString()[:8] + "@example.com"
+
+	registered := factory.User()
+	registered.Email = email
+	s.Require().NoError(s.storage.CreateUser(s.Ctx, registered))
+
+	// emails are matched regardless of case
+	user, err := s.storage.UserByEmail(s.Ctx, strings.ToUpper(email))
+	s.Require().NoError(err)
+	s.Equal(registered.Id, user.Id)
+
+	// deleted users can't be found
+	s.Require().NoError(s.storage.DeleteUser(s.Ctx, registered.Id))
+	_, err = s.storage.UserByEmail(s.Ctx, email)
+	s.ErrorIs(err, domain.ErrUserNotFound)
+
+	_, err = s.storage.UserByEmail(s.Ctx, "nobody@example.com")
+	s.ErrorIs(err, domain.ErrUserNotFound)
+}
+
+func (s *UserStorageSuite) TestUsers_CreatedDateRange() {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	var created []*domain.User
+	for _, age := range []time.Duration{72 * time.Hour, 48 * time.Hour, 24 * time.Hour, 0} {
+		user := (&domain.Factory{}).User()
+		user.CreatedAt = now.Add(-age)
+		s.Require().NoError(s.storage.CreateUser(s.Ctx, user))
+		created = append(created, user)
+	}
+
+	from, to := now.Add(-48*time.Hour), now.Add(-24*time.Hour)
+
+	tests := []struct {
+		name     string
+		req      *domain.GetUsersRequest
+		expected []*domain.User
+	}{
+		{
+			name:     "both bounds are inclusive",
+			req:      &domain.GetUsersRequest{CreatedFrom: &from, CreatedTo: &to},
+			expected: []*domain.User{created[2], created[1]},
+		},
+		{
+			name:     "only lower bound",
+			req:      &domain.GetUsersRequest{CreatedFrom: &to},
+			expected: []*domain.User{created[3], created[2]},
+		},
+		{
+			name:     "only upper bound",
+			req:      &domain.GetUsersRequest{CreatedTo: &from},
+			expected: []*domain.User{created[1], created[0]},
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			users, err := s.storage.Users(s.Ctx, tt.req)
+			s.Require().NoError(err)
+			s.Require().Len(users, len(tt.expected))
+			for i, user := range users {
+				s.Equal(tt.expected[i].Id, user.Id)
+			}
+
+			count, err := s.storage.CountUsers(s.Ctx, tt.req)
+			s.Require().NoError(err)
+			s.Equal(len(tt.expected), count)
+		})
+	}
+}
+
+func (s *UserStorageSuite) TestUsers_NameSearch() {
+	// filler users the search must skip
+	for i := range 200 {
+		user := (&domain.Factory{}).User()
+		user.FirstName = fmt.Sprintf("Filler%d", i)
+		user.LastName = "Person"
+		s.Require().NoError(s.storage.CreateUser(s.Ctx, user))
+	}
+
+	for _, name := range [][2]string{{"Alexander", "Pushkin"}, {"alexandra", "Smith"}, {"Sasha", "ALEXEEV"}, {"Percent", "100%_Sure"}} {
+		user := (&domain.Factory{}).User()
+		user.FirstName, user.LastName = name[0], name[1]
+		s.Require().NoError(s.storage.CreateUser(s.Ctx, user))
+	}
+
+	tests := []struct {
+		name     string
+		search   string
+		expected []string
+	}{
+		{
+			name:     "case-insensitive part of first or last name",
+			search:   "aLeX",
+			expected: []string{"Alexander Pushkin", "alexandra Smith", "Sasha ALEXEEV"},
+		},
+		{
+			name:     "across first and last name",
+			search:   "ander push",
+			expected: []string{"Alexander Pushkin"},
+		},
+		{
+			name:     "wildcards match literally",
+			search:   "0%_",
+			expected: []string{"Percent 100%_Sure"},
+		},
+		{
+			name:   "no match",
+			search: "zzz",
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			req := &domain.GetUsersRequest{Name: tt.search}
+			users, err := s.storage.Users(s.Ctx, req)
+			s.Require().NoError(err)
+
+			names := make([]string, 0, len(users))
+			for _, user := range users {
+				names = append(names, user.FullName())
+			}
+			s.ElementsMatch(tt.expected, names)
+
+			count, err := s.storage.CountUsers(s.Ctx, &domain.GetUsersRequest{Name: tt.search})
+			s.Require().NoError(err)
+			s.Equal(len(tt.expected), count)
+		})
+	}
+}
+
+func (s *UserStorageSuite) TestUsers_NameSearchUsesTrigramIndex() {
+	tx, err := s.PostgresConn.Begin(s.Ctx)
+	s.Require().NoError(err)
+	defer func() { _ = tx.Rollback(s.Ctx) }()
+
+	// the table is tiny, so a sequential scan would win without this
+	_, err = tx.Exec(s.Ctx, "SET LOCAL enable_seqscan = off")
+	s.Require().NoError(err)
+
+	query, args, err := applyUserFilters(
+		sq.StatementBuilder.PlaceholderFormat(sq.Dollar).Select("id").From("users"),
+		&domain.GetUsersRequest{Name: "alex"},
+	).ToSql()
+	s.Require().NoError(err)
+
+	rows, err := tx.Query(s.Ctx, "EXPLAIN "+query, args...)
+	s.Require().NoError(err)
+	defer rows.Close()
+
+	var plan strings.Builder
+	for rows.Next() {
+		var line string
+		s.Require().NoError(rows.Scan(&line))
+		plan.WriteString(line + "\n")
+	}
+	s.Require().NoError(rows.Err())
+	s.Contains(plan.String(), "users_full_name_trgm_idx")
+}
+
+func (s *UserStorageSuite) TestUsers_Sort() {
+	factory := &domain.Factory{}
+	for _, user := range []struct {
+		first, last string
+		age         int
+	}{{"Boris", "Petrov", 40}, {"Anna", "Ivanova", 30}, {"Clara", "Sidorova", 20}} {
+		u := factory.User()
+		u.FirstName, u.LastName, u.Age = user.first, user.last, user.age
+		s.Require().NoError(s.storage.CreateUser(s.Ctx, u))
+	}
+
+	tests := []struct {
+		sort     domain.UserSort
+		order    domain.SortOrder
+		expected []string
+	}{
+		{domain.UserSortName, domain.SortOrderAsc, []string{"Anna", "Boris", "Clara"}},
+		{domain.UserSortName, domain.SortOrderDesc, []string{"Clara", "Boris", "Anna"}},
+		{domain.UserSortAge, domain.SortOrderAsc, []string{"Clara", "Anna", "Boris"}},
+		{domain.UserSortAge, domain.SortOrderDesc, []string{"Boris", "Anna", "Clara"}},
+	}
+
+	for _, tt := range tests {
+		users, err := s.storage.Users(s.Ctx, &domain.GetUsersRequest{Sort: tt.sort, Order: tt.order})
+		s.Require().NoError(err)
+
+		names := make([]string, 0, len(users))
+		for _, user := range users {
+			names = append(names, user.FirstName)
+		}
+		s.Equal(tt.expected, names, "%s %s", tt.sort, tt.order)
+	}
+
+	_, err := s.storage.Users(s.Ctx, &domain.GetUsersRequest{Sort: "password_hash"})
+	s.ErrorIs(err, domain.ErrInvalidSort)
+}
+
 func TestUserStorageSuite(t *testing.T) {
 	suite.Run(t, new(UserStorageSuite))
 }
@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"mts/internal/domain"
+)
+
+type eventPayload struct {
+	Type       string       `json:"type"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Order      orderPayload `json:"order"`
+}
+
+type orderPayload struct {
+	Id        uuid.UUID          `json:"id"`
+	UserId    uuid.UUID          `json:"user_id"`
+	Status    string             `json:"status"`
+	Items     []orderItemPayload `json:"items"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+type orderItemPayload struct {
+	ProductId uuid.UUID `json:"product_id"`
+	Quantity  int       `json:"quantity"`
+}
+
+func newEventPayload(event *domain.OrderEvent) *eventPayload {
+	order := event.Order
+
+	items := make([]orderItemPayload, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, orderItemPayload{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	return &eventPayload{
+		Type:       string(event.Type),
+		OccurredAt: event.OccurredAt,
+		Order: orderPayload{
+			Id:        order.Id,
+			UserId:    order.UserId,
+			Status:    string(order.Status),
+			Items:     items,
+			CreatedAt: order.CreatedAt,
+			UpdatedAt: order.UpdatedAt,
+		},
+	}
+}
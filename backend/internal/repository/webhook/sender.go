@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"mts/internal/config"
+	"mts/internal/domain"
+	"shared"
+	sharedConfig "shared/config"
+)
+
+const (
+	// SignatureHeader carries the hex encoded HMAC-SHA256 of the request body, keyed with the webhook secret
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+
+	defaultTimeout = 5 * time.Second
+	// a single worker delivers events in the order they happened
+	defaultWorkers = 1
+)
+
+// the pool's errors, under the names subscribers of the sender already check
+var (
+	ErrQueueFull    = shared.ErrPoolFull
+	ErrSenderClosed = shared.ErrPoolClosed
+)
+
+// NewSender starts cfg.Workers workers delivering order events to cfg.Url; events are queued,
+// so handling one never waits for the receiver
+func NewSender(cfg config.Webhook) domain.EventSubscriber {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	return &sender{
+		cfg:    cfg,
+		client: &http.Client{Timeout: timeout},
+		pool:   shared.NewWorkerPool(sharedConfig.WorkerPool{Size: workers, QueueSize: cfg.QueueSize}),
+	}
+}
+
+type sender struct {
+	cfg    config.Webhook
+	client *http.Client
+	pool   *shared.WorkerPool
+}
+
+func (s *sender) Handle(ctx context.Context, event *domain.OrderEvent) error {
+	// the order is marshalled now, the caller may change it while the event waits in the queue
+	body, err := json.Marshal(newEventPayload(event))
+	if err != nil {
+		return err
+	}
+	eventType, orderId := event.Type, event.Order.Id
+
+	return s.pool.Submit(ctx, func(ctx context.Context) {
+		if err := s.deliver(ctx, eventType, body); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).
+				Str("event", string(eventType)).
+				Str("order_id", orderId.String()).
+				Msg("failed to deliver webhook")
+		}
+	})
+}
+
+// Close stops accepting events and waits for the queued ones to be delivered; once ctx is done the
+// deliveries in progress are canceled and the queued ones dropped
+func (s *sender) Close(ctx context.Context) error {
+	return s.pool.Close(ctx)
+}
+
+// deliver posts the event, retrying network failures and 5xx/429 responses with backoff
+func (s *sender) deliver(ctx context.Context, eventType domain.OrderEventType, body []byte) error {
+	ctx, span := tracer.Start(ctx, "WebhookSender.Deliver")
+	defer span.End()
+
+	for attempt := 1; ; attempt++ {
+		retryable, err := s.post(ctx, eventType, body)
+		if err == nil || !retryable || attempt >= s.cfg.Retry.Attempts() {
+			return err
+		}
+
+		timer := time.NewTimer(s.cfg.Retry.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *sender) post(ctx context.Context, eventType domain.OrderEventType, body []byte) (retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(eventType))
+	req.Header.Set(SignatureHeader, Sign(s.cfg.Secret, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		retryable = resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, fmt.Errorf("webhook receiver responded with %s", resp.Status)
+	}
+
+	return false, nil
+}
+
+// Sign returns the signature a receiver should expect in SignatureHeader for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/application"
+	"mts/internal/config"
+	"mts/internal/domain"
+	sharedConfig "shared/config"
+)
+
+type receivedWebhook struct {
+	event     string
+	signature string
+	body      []byte
+}
+
+func TestSender_DeliversSignedPayload(t *testing.T) {
+	received := make(chan receivedWebhook, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{
+			event:     r.Header.Get(EventHeader),
+			signature: r.Header.Get(SignatureHeader),
+			body:      body,
+		}
+	}))
+	defer server.Close()
+
+	sender := NewSender(config.Webhook{Url: server.URL, Secret: "s3cret"})
+	defer sender.Close(context.Background())
+
+	events := application.NewSyncEventPublisher()
+	events.Subscribe(sender.Handle)
+
+	order := (&domain.Factory{}).Order(uuid.New(), uuid.New())
+	events.Publish(context.Background(), domain.NewOrderEvent(domain.OrderCreated, order))
+
+	var webhook receivedWebhook
+	select {
+	case webhook = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	assert.Equal(t, "order.created", webhook.event)
+	assert.Equal(t, Sign("s3cret", webhook.body), webhook.signature)
+	assert.NotEqual(t, Sign("other", webhook.body), webhook.signature)
+
+	var payload eventPayload
+	require.NoError(t, json.Unmarshal(webhook.body, &payload))
+	assert.Equal(t, "order.created", payload.Type)
+	assert.Equal(t, order.Id, payload.Order.Id)
+	assert.Equal(t, order.UserId, payload.Order.UserId)
+	assert.Equal(t, "pending", payload.Order.Status)
+	require.Len(t, payload.Order.Items, 1)
+	assert.Equal(t, order.Items[0].ProductId, payload.Order.Items[0].ProductId)
+}
+
+func TestSender_RetriesServerErrors(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	sender := NewSender(config.Webhook{
+		Url:    server.URL,
+		Secret: "s3cret",
+		Retry:  sharedConfig.Retry{MaxAttempts: 5, InitialBackoff: time.Millisecond},
+	})
+
+	order := (&domain.Factory{}).Order(uuid.New(), uuid.New())
+	require.NoError(t, sender.Handle(context.Background(), domain.NewOrderEvent(domain.OrderConfirmed, order)))
+
+	// closing waits for the queued delivery
+	require.NoError(t, sender.Close(context.Background()))
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestSender_ClientErrorsAreNotRetried(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sender := NewSender(config.Webhook{
+		Url:    server.URL,
+		Secret: "s3cret",
+		Retry:  sharedConfig.Retry{MaxAttempts: 5, InitialBackoff: time.Millisecond},
+	})
+
+	order := (&domain.Factory{}).Order(uuid.New(), uuid.New())
+	require.NoError(t, sender.Handle(context.Background(), domain.NewOrderEvent(domain.OrderCompleted, order)))
+	require.NoError(t, sender.Close(context.Background()))
+
+	assert.Equal(t, int32(1), attempts.Load())
+	assert.ErrorIs(t, sender.Handle(context.Background(), domain.NewOrderEvent(domain.OrderCompleted, order)), ErrSenderClosed)
+}
+
+func TestSender_SendsTheOrderAsOfTheEvent(t *testing.T) {
+	received := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+	}))
+	defer server.Close()
+
+	sender := NewSender(config.Webhook{Url: server.URL, Secret: "s3cret"})
+
+	order := (&domain.Factory{}).Order(uuid.New(), uuid.New())
+	require.NoError(t, sender.Handle(context.Background(), domain.NewOrderEvent(domain.OrderCreated, order)))
+	// the caller goes on with the order while the event is queued
+	order.Status = domain.OrderStatusCancelled
+	order.Items = nil
+	require.NoError(t, sender.Close(context.Background()))
+
+	var payload eventPayload
+	require.NoError(t, json.Unmarshal(<-received, &payload))
+	assert.Equal(t, "pending", payload.Order.Status)
+	assert.Len(t, payload.Order.Items, 1)
+}
+
+func TestSender_CloseInterruptsRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sender := NewSender(config.Webhook{
+		Url:    server.URL,
+		Secret: "s3cret",
+		Retry:  sharedConfig.Retry{MaxAttempts: 5, InitialBackoff: time.Hour},
+	})
+
+	order := (&domain.Factory{}).Order(uuid.New(), uuid.New())
+	require.NoError(t, sender.Handle(context.Background(), domain.NewOrderEvent(domain.OrderConfirmed, order)))
+	require.Eventually(t, func() bool { return attempts.Load() == 1 }, 5*time.Second, time.Millisecond)
+
+	// the delivery waits an hour before its next attempt, shutdown does not
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.ErrorIs(t, sender.Close(ctx), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), attempts.Load())
+}
@@ -15,6 +15,28 @@
 //
 // @tag.name Orders
 // @tag.description Order management with stock control
+//
+// @tag.name Auth
+// @tag.description Access tokens of users
+//
+// @tag.name Admin
+// @tag.description Operational switches
+//
+// @tag.name Health
+// @tag.description Probes for orchestrators and load balancers
+//
+// @tag.name Debug
+// @tag.description Performance diagnostics, served when service.debug is on
+//
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description Admin token as "Bearer <token>"
+//
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token from /api/v1/auth/login as "Bearer <token>"
 package rest
 
 import (
@@ -22,55 +44,138 @@ import (
 
 	"github.com/Flussen/swagger-fiber-v3"
 	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
 
-	_ "mts/internal/transport/rest/docs"
-
+	"mts/internal/config"
 	"mts/internal/domain"
 )
 
 func New(
+	cfg *config.Service,
+	cache domain.Cache,
+	readOnly *domain.ReadOnlyMode,
+	readiness *Readiness,
+	slowQueries *shared.SlowQueryLog,
+	// unitOfWork runs the routes with transactionMiddleware in one transaction, nil runs them without
+	unitOfWork domain.UnitOfWork,
 	userAppService domain.UserAppService,
 	productAppService domain.ProductAppService,
 	orderAppService domain.OrderAppService,
+	authAppService domain.AuthAppService,
 ) *fiber.App {
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		ErrorHandler:    errorHandler,
+		BodyLimit:       cfg.BodyLimit,
+		StructValidator: newStructValidator(),
+	})
+
+	app.Use(requestid.New())
+	app.Use(clientIpMiddleware(cfg.Proxy))
+	app.Use(recoverMiddleware())
+	app.Use(tracingMiddleware())
+	// before the limiter and the readiness check, so their 503s carry the CORS headers too
+	app.Use(corsMiddleware(cfg.Cors))
+	app.Use(concurrencyLimitMiddleware(cfg.Concurrency, healthPath, readyPath))
+	app.Use(readinessMiddleware(readiness, healthPath, readyPath))
+	app.Use(compressionMiddleware(cfg.Compression))
 
 	// Используем shared логер и middleware
 	app.Use(func(c fiber.Ctx) error {
 		shared.Logger.Info().
 			Str("method", c.Method()).
 			Str("path", c.Path()).
+			Str("ip", clientIp(c)).
+			Str("request_id", requestid.FromContext(c)).
 			Msg("request received")
 		return c.Next()
 	})
+	app.Use(timeoutMiddleware(cfg.RequestTimeout))
+	app.Use(authMiddleware(authAppService))
+	app.Use(actorMiddleware())
+
+	// without storages to guard, as in handler tests, the switch is only reported
+	if readOnly == nil {
+		readOnly = domain.NewReadOnlyMode(cfg.ReadOnly)
+	}
+
+	maintenance := newMaintenanceMode(cfg.Maintenance)
+	app.Use(maintenanceMiddleware(maintenance))
 
 	app.Get("/docs/*", swagger.HandlerDefault)
+	app.Get(healthPath, health)
+	app.Get(readyPath, newReadinessHandler(readiness).ready)
 
 	v1 := app.Group("/api/v1")
+	v1.Get("openapi.json", openapiSpec)
 
-	// Users routes
 	user := newUserHandler(userAppService)
+	product := newProductHandler(productAppService)
+	order := newOrderHandler(orderAppService)
+	auth := newAuthHandler(authAppService)
+	httpCache := httpCacheMiddleware(cfg.Cache.HttpMaxAge)
+	inTransaction := transactionMiddleware(unitOfWork)
+
+	// Users routes
 	v1.Group("/users").
-		Post("", user.registerUser).
-		Get("", user.getUsers).
-		Get(":user_id", user.getUser)
+		Post("", user.registerUser, rateLimitMiddleware(cache, "register", cfg.RateLimit.Requests, cfg.RateLimit.Window)).
+		Get("", user.getUsers, httpCache).
+		Post("bulk", user.registerUsers, adminMiddleware(cfg.AdminToken)).
+		Get("verify", user.verifyEmail).
+		Get("me", user.getCurrentUser, requireUserMiddleware()).
+		Get(":user_id", user.getUser, httpCache).
+		Delete(":user_id", user.deleteUser, adminMiddleware(cfg.AdminToken)).
+		Get(":user_id/orders", order.getUserOrders)
+
+	// Auth routes
+	v1.Group("/auth").
+		Post("login", auth.login).
+		Post("refresh", auth.refresh).
+		Post("logout", auth.logout, requireUserMiddleware())
 
 	// Products routes
-	product := newProductHandler(productAppService)
 	v1.Group("/products").
 		Post("", product.createProduct).
-		Get("", product.getProducts).
-		Get(":product_id", product.getProduct).
-		Put(":product_id", product.updateProduct)
+		Get("", product.getProducts, httpCache).
+		Get("tags", product.getProductTags, httpCache).
+		Get("export", product.exportProducts).
+		Post("import", product.importProducts).
+		Post("upsert", product.upsertProduct).
+		Get(":product_id", product.getProduct, httpCache).
+		Put(":product_id", product.updateProduct).
+		Delete(":product_id", product.deleteProduct, adminMiddleware(cfg.AdminToken)).
+		Post(":product_id/restock", product.restockProduct)
 
 	// Orders routes
-	order := newOrderHandler(orderAppService)
 	v1.Group("/orders").
 		Post("", order.createOrder).
+		Post("validate", order.validateOrder).
 		Get("", order.getOrders).
+		Get("stats", order.getOrderStats, adminMiddleware(cfg.AdminToken)).
+		Post("bulk-status", order.bulkUpdateOrderStatus, adminMiddleware(cfg.AdminToken)).
 		Get(":order_id", order.getOrder).
+		Get(":order_id/history", order.getOrderStatusHistory).
+		Get(":order_id/invoice", order.getOrderInvoice).
 		Put(":order_id", order.updateOrder).
+		Delete(":order_id", order.deleteOrder, adminMiddleware(cfg.AdminToken)).
+		Put(":order_id/items", order.updateOrderItems, inTransaction).
+		Post(":order_id/items/:item_id/cancel", order.cancelOrderItem).
 		Post(":order_id/cancel", order.cancelOrder)
 
+	// Admin routes
+	admin := newMaintenanceHandler(maintenance)
+	readOnlyAdmin := newReadOnlyHandler(readOnly)
+	v1.Group("/admin", adminMiddleware(cfg.AdminToken)).
+		Get("maintenance", admin.getMaintenance).
+		Put("maintenance", admin.setMaintenance).
+		Get("read-only", readOnlyAdmin.getReadOnly).
+		Put("read-only", readOnlyAdmin.setReadOnly)
+
+	// Debug routes
+	if cfg.Debug {
+		debug := newDebugHandler(slowQueries)
+		v1.Group("/debug", adminMiddleware(cfg.AdminToken)).
+			Get("slow-queries", debug.getSlowQueries)
+	}
+
 	return app
 }
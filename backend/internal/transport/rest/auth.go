@@ -0,0 +1,110 @@
+package rest
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v3"
+
+	"mts/internal/domain"
+)
+
+type authHandler struct {
+	authAppService domain.AuthAppService
+}
+
+func newAuthHandler(authAppService domain.AuthAppService) *authHandler {
+	return &authHandler{
+		authAppService: authAppService,
+	}
+}
+
+// login exchanges credentials for an access token
+// @Summary Log in
+// @Description Check the email and password of a user and issue an access token, valid for service.token_lifetime, with a refresh token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body LoginRequest true "User credentials"
+// @Success 200 {object} TokenResponse "Logged in successfully"
+// @Failure 400 {object} ErrorResponse "Bad request - missing email or password"
+// @Failure 401 {object} ErrorResponse "Unauthorized - wrong email or password"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/auth/login [post]
+func (h *authHandler) login(c fiber.Ctx) error {
+	var req LoginRequest
+	if err := bindJSON(c, &req); err != nil {
+		return err
+	}
+
+	tokens, err := h.authAppService.Login(c.Context(), req.ToDomain())
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCredentials) {
+			return withStatus(fiber.StatusUnauthorized, err)
+		}
+		return err
+	}
+
+	return c.JSON(NewTokenResponse(tokens))
+}
+
+// refresh exchanges a refresh token for new tokens
+// @Summary Refresh tokens
+// @Description Issue a new access token and a new refresh token. The presented refresh token is rotated and can't be used again; presenting a rotated token is treated as theft and revokes every refresh token of the user
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshRequest true "Refresh token"
+// @Success 200 {object} TokenResponse "Tokens refreshed successfully"
+// @Failure 400 {object} ErrorResponse "Bad request - missing refresh token"
+// @Failure 401 {object} ErrorResponse "Unauthorized - unknown, expired, revoked or reused refresh token"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/auth/refresh [post]
+func (h *authHandler) refresh(c fiber.Ctx) error {
+	var req RefreshRequest
+	if err := bindJSON(c, &req); err != nil {
+		return err
+	}
+
+	tokens, err := h.authAppService.Refresh(c.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidRefreshToken) || errors.Is(err, domain.ErrRefreshTokenReused) {
+			return withStatus(fiber.StatusUnauthorized, err)
+		}
+		return err
+	}
+
+	return c.JSON(NewTokenResponse(tokens))
+}
+
+// logout ends the session of the request
+// @Summary Log out
+// @Description Revoke the access token the request is made with and the refresh token of the session; the access token is rejected from then on until it would have expired and the refresh token can't be exchanged any more. Presenting an already rotated refresh token revokes every refresh token of the user. Other access tokens of the user are left alone
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body LogoutRequest true "Refresh token of the session"
+// @Success 204 "Logged out successfully"
+// @Failure 400 {object} ErrorResponse "Bad request - missing refresh token"
+// @Failure 401 {object} ErrorResponse "Unauthorized - missing, invalid, expired or already revoked access token, or a refresh token unknown or of another user"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "Service unavailable - the revocation list can't be checked"
+// @Router /api/v1/auth/logout [post]
+func (h *authHandler) logout(c fiber.Ctx) error {
+	var req LogoutRequest
+	if err := bindJSON(c, &req); err != nil {
+		return err
+	}
+
+	// set by authMiddleware, requireUserMiddleware ran before
+	claims := c.Locals(localAccessClaims).(*domain.AccessClaims)
+
+	if err := h.authAppService.Logout(c.Context(), claims, req.RefreshToken); err != nil {
+		if errors.Is(err, domain.ErrInvalidRefreshToken) {
+			return withStatus(fiber.StatusUnauthorized, err)
+		}
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,86 @@
+package rest
+
+import (
+	"strings"
+	"time"
+
+	"mts/internal/domain"
+)
+
+// LoginRequest represents request to log in
+// @Description Credentials of a registered user
+type LoginRequest struct {
+	// Email
+	// @Description Email address given at registration
+	// @Example john.doe@example.com
+	Email string `json:"email" binding:"required" validate:"required" example:"john.doe@example.com"`
+
+	// Password
+	// @Description User's password
+	// @Example password123
+	Password string `json:"password" binding:"required" validate:"required" example:"password123"`
+} // @name LoginRequest
+
+func (r *LoginRequest) ToDomain() *domain.LoginRequest {
+	return &domain.LoginRequest{
+		Email:    strings.TrimSpace(r.Email),
+		Password: r.Password,
+	}
+}
+
+// RefreshRequest represents request to refresh the tokens
+// @Description Refresh token from the last login or refresh
+type RefreshRequest struct {
+	// Refresh token
+	// @Description Single-use refresh token
+	// @Example 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+	RefreshToken string `json:"refresh_token" binding:"required" validate:"required" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+} // @name RefreshRequest
+
+// LogoutRequest represents request to end the session
+// @Description Refresh token of the session to end
+type LogoutRequest struct {
+	// Refresh token
+	// @Description Refresh token from the last login or refresh of the session, revoked with the access token
+	// @Example 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+	RefreshToken string `json:"refresh_token" binding:"required" validate:"required" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+} // @name LogoutRequest
+
+// TokenResponse represents issued tokens
+// @Description Access token to send as "Authorization: Bearer <token>" and the refresh token getting the next one
+type TokenResponse struct {
+	// Access token
+	// @Description Signed JWT identifying the user
+	// @Example eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...
+	AccessToken string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+
+	// Token type
+	// @Description Always Bearer
+	// @Example Bearer
+	TokenType string `json:"token_type" example:"Bearer"`
+
+	// Expires at
+	// @Description When the access token stops being accepted
+	// @Example 2024-01-15T11:30:00Z
+	ExpiresAt time.Time `json:"expires_at" example:"2024-01-15T11:30:00Z"`
+
+	// Refresh token
+	// @Description Single-use token for POST /api/v1/auth/refresh, replaced by every refresh
+	// @Example 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+	RefreshToken string `json:"refresh_token" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+
+	// Refresh token expires at
+	// @Description When the refresh token stops being accepted
+	// @Example 2024-02-14T10:30:00Z
+	RefreshExpiresAt time.Time `json:"refresh_expires_at" example:"2024-02-14T10:30:00Z"`
+} // @name TokenResponse
+
+func NewTokenResponse(tokens *domain.AuthTokens) *TokenResponse {
+	return &TokenResponse{
+		AccessToken:      tokens.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresAt:        tokens.ExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+	}
+}
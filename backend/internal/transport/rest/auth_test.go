@@ -0,0 +1,287 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/application"
+	"mts/internal/config"
+	"mts/internal/domain"
+	"mts/internal/repository/cache"
+)
+
+func TestLogin(t *testing.T) {
+	user := (&domain.Factory{}).User()
+	users := &fixedUserStorage{users: []*domain.User{user}}
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil,
+		nil, application.NewUserAppService(users, nil, ""), nil, nil, application.NewAuthAppService(users, discardRefreshTokenStorage{}, cache.NewMemoryCache(), application.TokenOptions{Secret: []byte("secret")}))
+
+	loginRequest := func(body string) *http.Request {
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/auth/login", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return req
+	}
+
+	resp, err := app.Test(loginRequest(`{"email": " ` + user.Email + `", "password": "password123"}`))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var token TokenResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&token))
+	assert.Equal(t, "Bearer", token.TokenType)
+	assert.NotEmpty(t, token.RefreshToken)
+
+	req := httptest.NewRequest(fiber.MethodGet, "/api/v1/users/me", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token.AccessToken)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(loginRequest(`{"email": "` + user.Email + `", "password": "wrong password"}`))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, domain.ErrInvalidCredentials.Code(), errResp.Code)
+}
+
+func TestLogout(t *testing.T) {
+	user := (&domain.Factory{}).User()
+	users := &fixedUserStorage{users: []*domain.User{user}}
+	auth := application.NewAuthAppService(users, newStoredRefreshTokenStorage(), cache.NewMemoryCache(), application.TokenOptions{Secret: []byte("secret")})
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil,
+		nil, application.NewUserAppService(users, nil, ""), nil, nil, auth)
+
+	tokens, err := auth.Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	authorized := func(method, target, body string) *http.Request {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tokens.AccessToken)
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return req
+	}
+	logoutBody := `{"refresh_token": "` + tokens.RefreshToken + `"}`
+
+	resp, err := app.Test(authorized(fiber.MethodPost, "/api/v1/auth/logout", `{}`))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode, "the refresh token of the session is required")
+
+	resp, err = app.Test(authorized(fiber.MethodPost, "/api/v1/auth/logout", `{"refresh_token": "unknown"}`))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = app.Test(authorized(fiber.MethodPost, "/api/v1/auth/logout", logoutBody))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+
+	// the logged-out tokens are rejected
+	resp, err = app.Test(authorized(fiber.MethodGet, "/api/v1/users/me", ""))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/auth/refresh", strings.NewReader(logoutBody))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = app.Test(authorized(fiber.MethodPost, "/api/v1/auth/logout", logoutBody))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/auth/logout", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestAuthMiddleware_RevocationsUnavailable(t *testing.T) {
+	user := (&domain.Factory{}).User()
+	users := &fixedUserStorage{users: []*domain.User{user}}
+	revocations := &unavailableCache{Cache: cache.NewMemoryCache()}
+	auth := application.NewAuthAppService(users, discardRefreshTokenStorage{}, revocations, application.TokenOptions{Secret: []byte("secret")})
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil,
+		nil, application.NewUserAppService(users, nil, ""), nil, nil, auth)
+
+	tokens, err := auth.Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	revocations.down = true
+	req := httptest.NewRequest(fiber.MethodGet, "/api/v1/users/me", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tokens.AccessToken)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode, "a possibly revoked token is not let through")
+
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, domain.ErrAuthUnavailable.Code(), errResp.Code)
+}
+
+func TestAdminRoutes_RequireAdminRole(t *testing.T) {
+	plain := (&domain.Factory{}).User()
+	plain.Roles = []string{domain.RoleUser}
+	admin := (&domain.Factory{}).User()
+	admin.Email = "admin@example.com"
+	admin.Roles = []string{domain.RoleUser, domain.RoleAdmin}
+
+	users := &fixedUserStorage{users: []*domain.User{plain, admin}}
+	auth := application.NewAuthAppService(users, discardRefreshTokenStorage{}, cache.NewMemoryCache(), application.TokenOptions{Secret: []byte("secret")})
+	orderAppService := new(mockOrderAppService)
+	orderAppService.On("StatusCounts", mock.Anything, mock.Anything).Return(domain.NewOrderStatusStatsMap(), nil)
+	// admin users get through without an admin token configured
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, orderAppService, auth)
+
+	stats := func(user *domain.User) *http.Response {
+		req := httptest.NewRequest(fiber.MethodGet, "/api/v1/orders/stats", nil)
+		if user != nil {
+			tokens, err := auth.Login(context.Background(), &domain.LoginRequest{Email: user.Email, Password: "password123"})
+			require.NoError(t, err)
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer "+tokens.AccessToken)
+		}
+
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := stats(plain)
+	assert.Equal(t, fiber.StatusForbidden, resp.StatusCode)
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, domain.ErrForbidden.Code(), errResp.Code)
+
+	assert.Equal(t, fiber.StatusOK, stats(admin).StatusCode)
+	assert.Equal(t, fiber.StatusForbidden, stats(nil).StatusCode, "anonymous request without admin token")
+}
+
+func TestRequireRoleMiddleware(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Get("/admin", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	}, func(c fiber.Ctx) error {
+		if roles := c.Get("X-Roles"); roles != "" {
+			c.Locals(localAccessClaims, &domain.AccessClaims{Roles: strings.Split(roles, ",")})
+		}
+		return c.Next()
+	}, requireRoleMiddleware(domain.RoleAdmin))
+
+	tests := []struct {
+		roles  string
+		status int
+	}{
+		{"", fiber.StatusUnauthorized},
+		{"user", fiber.StatusForbidden},
+		{"user,admin", fiber.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.roles, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/admin", nil)
+			req.Header.Set("X-Roles", tt.roles)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestRefresh_InvalidToken(t *testing.T) {
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, nil,
+		application.NewAuthAppService(&fixedUserStorage{}, discardRefreshTokenStorage{}, cache.NewMemoryCache(), application.TokenOptions{Secret: []byte("secret")}))
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/auth/refresh", strings.NewReader(`{"refresh_token": "unknown"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, domain.ErrInvalidRefreshToken.Code(), errResp.Code)
+}
+
+// discardRefreshTokenStorage accepts new refresh tokens and never finds one
+type discardRefreshTokenStorage struct {
+	domain.RefreshTokenStorage
+}
+
+func (discardRefreshTokenStorage) CreateRefreshToken(context.Context, *domain.RefreshToken) error {
+	return nil
+}
+
+func (discardRefreshTokenStorage) RefreshToken(context.Context, []byte) (*domain.RefreshToken, error) {
+	return nil, domain.ErrInvalidRefreshToken
+}
+
+// storedRefreshTokenStorage keeps refresh tokens in a map keyed by their hash
+type storedRefreshTokenStorage struct {
+	domain.RefreshTokenStorage
+	mu     sync.Mutex
+	tokens map[string]*domain.RefreshToken
+}
+
+func newStoredRefreshTokenStorage() *storedRefreshTokenStorage {
+	return &storedRefreshTokenStorage{tokens: make(map[string]*domain.RefreshToken)}
+}
+
+func (s *storedRefreshTokenStorage) CreateRefreshToken(_ context.Context, token *domain.RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *token
+	s.tokens[string(token.TokenHash)] = &stored
+	return nil
+}
+
+func (s *storedRefreshTokenStorage) RefreshToken(_ context.Context, hash []byte) (*domain.RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[string(hash)]
+	if !ok {
+		return nil, domain.ErrInvalidRefreshToken
+	}
+	found := *token
+	return &found, nil
+}
+
+func (s *storedRefreshTokenStorage) RevokeRefreshToken(_ context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := domain.Now()
+	for _, token := range s.tokens {
+		if token.Id == id && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// unavailableCache fails every read while down
+type unavailableCache struct {
+	domain.Cache
+	down bool
+}
+
+func (c *unavailableCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if c.down {
+		return nil, false, errors.New("connection refused")
+	}
+	return c.Cache.Get(ctx, key)
+}
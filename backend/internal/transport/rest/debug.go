@@ -0,0 +1,45 @@
+package rest
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v3"
+
+	"shared"
+)
+
+type debugHandler struct {
+	slowQueries *shared.SlowQueryLog
+}
+
+func newDebugHandler(slowQueries *shared.SlowQueryLog) *debugHandler {
+	return &debugHandler{
+		slowQueries: slowQueries,
+	}
+}
+
+// getSlowQueries lists the slowest recent queries
+// @Summary Get slow queries
+// @Description List the slowest of the recent queries that took at least postgres.slow_query_threshold, slowest first (admin only, served when service.debug is on). Only the last postgres.slow_query_log_size slow queries of this instance are kept; args are counted but never shown
+// @Tags Debug
+// @Produce json
+// @Security AdminToken
+// @Security BearerAuth
+// @Param limit query int false "Number of queries to return, all kept queries by default" minimum(1)
+// @Success 200 {object} SlowQueriesResponse "Slow queries"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid limit"
+// @Failure 401 {object} ErrorResponse "Unauthorized - admin token or access token is missing"
+// @Failure 403 {object} ErrorResponse "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role"
+// @Router /api/v1/debug/slow-queries [get]
+func (h *debugHandler) getSlowQueries(c fiber.Ctx) error {
+	var limit int
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid limit, must be a positive integer")
+		}
+	}
+
+	return c.JSON(NewSlowQueriesResponse(h.slowQueries, limit))
+}
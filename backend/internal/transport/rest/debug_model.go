@@ -0,0 +1,69 @@
+package rest
+
+import (
+	"time"
+
+	"shared"
+)
+
+// SlowQuery represents a query that took at least the slow query threshold
+// @Description Slow query of this instance
+type SlowQuery struct {
+	// SQL
+	// @Description SQL text of the query
+	// @Example "SELECT id FROM products WHERE description ILIKE $1"
+	Sql string `json:"sql" example:"SELECT id FROM products WHERE description ILIKE $1"`
+
+	// Args count
+	// @Description Number of query args, their values may hold personal data and are not kept
+	// @Example 1
+	ArgsCount int `json:"args_count" example:"1"`
+
+	// Duration
+	// @Description Milliseconds the query took
+	// @Example 812.5
+	DurationMs float64 `json:"duration_ms" example:"812.5"`
+
+	// Started at
+	// @Description When the query started
+	// @Example 2024-01-15T10:30:00Z
+	StartedAt time.Time `json:"started_at" example:"2024-01-15T10:30:00Z"`
+
+	// Error (optional)
+	// @Description Error the query failed with, e.g. when it was canceled by the statement timeout
+	// @Example "canceling statement due to statement timeout"
+	Error string `json:"error,omitempty" example:"canceling statement due to statement timeout"`
+} // @name SlowQuery
+
+// SlowQueriesResponse represents the slowest recent queries
+// @Description Slowest recent queries of this instance, slowest first
+type SlowQueriesResponse struct {
+	// Log size
+	// @Description Number of recent slow queries the instance keeps, 0 when none are kept
+	// @Example 100
+	LogSize int `json:"log_size" example:"100"`
+
+	// Queries
+	// @Description Slow queries, slowest first
+	Queries []*SlowQuery `json:"queries"`
+} // @name SlowQueriesResponse
+
+func NewSlowQueriesResponse(log *shared.SlowQueryLog, limit int) *SlowQueriesResponse {
+	slowest := log.Slowest(limit)
+
+	queries := make([]*SlowQuery, 0, len(slowest))
+	for _, query := range slowest {
+		queries = append(queries, &SlowQuery{
+			Sql:        query.Sql,
+			ArgsCount:  query.ArgsCount,
+			DurationMs: float64(query.Duration) / float64(time.Millisecond),
+			StartedAt:  query.StartedAt,
+			Error:      query.Error,
+		})
+	}
+
+	return &SlowQueriesResponse{
+		LogSize: log.Size(),
+		Queries: queries,
+	}
+}
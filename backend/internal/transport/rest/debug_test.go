@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/config"
+	"mts/internal/repository/cache"
+	"shared"
+)
+
+func TestGetSlowQueries(t *testing.T) {
+	slowQueries := shared.NewSlowQueryLog(2)
+
+	app := New(&config.Service{AdminToken: "secret", Debug: true}, cache.NewMemoryCache(), nil, nil, slowQueries, nil, nil, nil, nil, nil)
+	get := func(path, authorization string) *http.Response {
+		req := httptest.NewRequest(fiber.MethodGet, path, nil)
+		if authorization != "" {
+			req.Header.Set(fiber.HeaderAuthorization, authorization)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	slowest := func(path string) SlowQueriesResponse {
+		resp := get(path, "Bearer secret")
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var body SlowQueriesResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body
+	}
+
+	assert.Empty(t, slowest("/api/v1/debug/slow-queries").Queries)
+
+	startedAt := time.Now().UTC().Truncate(time.Second)
+	slowQueries.Add(shared.SlowQuery{Sql: "SELECT pg_sleep(3)", Duration: 3 * time.Second, StartedAt: startedAt})
+	slowQueries.Add(shared.SlowQuery{Sql: "SELECT pg_sleep(1)", Duration: time.Second, StartedAt: startedAt})
+	slowQueries.Add(shared.SlowQuery{
+		Sql:       "SELECT * FROM users WHERE email = $1",
+		ArgsCount: 1,
+		Duration:  1500 * time.Millisecond,
+		StartedAt: startedAt,
+		Error:     "canceled",
+	})
+
+	// only the last two queries are kept, even though the first was the slowest
+	body := slowest("/api/v1/debug/slow-queries")
+	assert.Equal(t, 2, body.LogSize)
+	require.Len(t, body.Queries, 2)
+	assert.Equal(t, &SlowQuery{
+		Sql:        "SELECT * FROM users WHERE email = $1",
+		ArgsCount:  1,
+		DurationMs: 1500,
+		StartedAt:  startedAt,
+		Error:      "canceled",
+	}, body.Queries[0])
+	assert.Equal(t, "SELECT pg_sleep(1)", body.Queries[1].Sql)
+
+	body = slowest("/api/v1/debug/slow-queries?limit=1")
+	require.Len(t, body.Queries, 1)
+	assert.Equal(t, "SELECT * FROM users WHERE email = $1", body.Queries[0].Sql)
+
+	assert.Equal(t, fiber.StatusBadRequest, get("/api/v1/debug/slow-queries?limit=0", "Bearer secret").StatusCode)
+	assert.Equal(t, fiber.StatusUnauthorized, get("/api/v1/debug/slow-queries", "").StatusCode)
+}
+
+func TestGetSlowQueries_DebugDisabled(t *testing.T) {
+	app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(fiber.MethodGet, "/api/v1/debug/slow-queries", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer secret")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
@@ -17,68 +17,55 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/orders": {
+        "/api/v1/admin/maintenance": {
             "get": {
-                "description": "Retrieve a paginated list of all orders in the system",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "AdminToken": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Report whether mutating endpoints are currently rejected with 503 (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
-                ],
-                "summary": "Get orders list",
-                "parameters": [
-                    {
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number for pagination",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "maximum": 100,
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Number of items per page",
-                        "name": "size",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Filter orders by user ID",
-                        "name": "user_id",
-                        "in": "query"
-                    }
+                    "Admin"
                 ],
+                "summary": "Get maintenance mode",
                 "responses": {
                     "200": {
-                        "description": "Orders retrieved successfully",
+                        "description": "Current maintenance mode",
                         "schema": {
-                            "$ref": "#/definitions/OrdersResponse"
+                            "$ref": "#/definitions/MaintenanceStatus"
                         }
                     },
-                    "400": {
-                        "description": "Bad request - invalid pagination parameters or user ID",
+                    "401": {
+                        "description": "Unauthorized - admin token or access token is missing",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "403": {
+                        "description": "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     }
                 }
             },
-            "post": {
-                "description": "Create a new order with multiple items, automatically handles stock reservation",
+            "put": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Turn maintenance mode on or off for this instance (admin only). While it is on, POST, PUT, PATCH and DELETE requests are answered with 503 and a Retry-After header, reads keep working",
                 "consumes": [
                     "application/json"
                 ],
@@ -86,41 +73,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Admin"
                 ],
-                "summary": "Create new order",
+                "summary": "Switch maintenance mode",
                 "parameters": [
                     {
-                        "description": "Order creation data",
+                        "description": "Desired maintenance mode",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/CreateOrderRequest"
+                            "$ref": "#/definitions/MaintenanceRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Order created successfully",
+                    "200": {
+                        "description": "Maintenance mode after the switch",
                         "schema": {
-                            "$ref": "#/definitions/Order"
+                            "$ref": "#/definitions/MaintenanceStatus"
                         }
                     },
                     "400": {
-                        "description": "Bad request - validation failed or insufficient stock",
+                        "description": "Bad request - invalid JSON or missing enabled flag",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not found - user or product not found",
+                    "401": {
+                        "description": "Unauthorized - admin token or access token is missing",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "403": {
+                        "description": "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
@@ -128,50 +115,39 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/orders/{order_id}": {
+        "/api/v1/admin/read-only": {
             "get": {
-                "description": "Retrieve detailed information about a specific order using its unique identifier",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "AdminToken": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Report whether the storages currently reject writes with 503 (admin only)",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
-                ],
-                "summary": "Get order by ID",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Order unique identifier",
-                        "name": "order_id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "Admin"
                 ],
+                "summary": "Get read-only mode",
                 "responses": {
                     "200": {
-                        "description": "Order information retrieved successfully",
-                        "schema": {
-                            "$ref": "#/definitions/Order"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad request - invalid order ID format",
+                        "description": "Current read-only mode",
                         "schema": {
-                            "$ref": "#/definitions/ErrorResponse"
+                            "$ref": "#/definitions/ReadOnlyStatus"
                         }
                     },
-                    "404": {
-                        "description": "Not found - order with specified ID does not exist",
+                    "401": {
+                        "description": "Unauthorized - admin token or access token is missing",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "403": {
+                        "description": "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
@@ -179,7 +155,15 @@ const docTemplate = `{
                 }
             },
             "put": {
-                "description": "Update an existing order's status or other mutable fields",
+                "security": [
+                    {
+                        "AdminToken": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Turn read-only mode on or off for this instance (admin only), e.g. while the primary database fails over. While it is on, every write to the database fails with 503 and code READ_ONLY, reads keep working",
                 "consumes": [
                     "application/json"
                 ],
@@ -187,49 +171,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Admin"
                 ],
-                "summary": "Update order",
+                "summary": "Switch read-only mode",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Order unique identifier",
-                        "name": "order_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Order update data",
+                        "description": "Desired read-only mode",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/UpdateOrderRequest"
+                            "$ref": "#/definitions/ReadOnlyRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Order updated successfully",
+                        "description": "Read-only mode after the switch",
                         "schema": {
-                            "$ref": "#/definitions/Order"
+                            "$ref": "#/definitions/ReadOnlyStatus"
                         }
                     },
                     "400": {
-                        "description": "Bad request - invalid order ID format or validation failed",
+                        "description": "Bad request - invalid JSON or missing enabled flag",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not found - order with specified ID does not exist",
+                    "401": {
+                        "description": "Unauthorized - admin token or access token is missing",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "403": {
+                        "description": "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
@@ -237,9 +213,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/orders/{order_id}/cancel": {
+        "/api/v1/auth/login": {
             "post": {
-                "description": "Cancel an order and restore product quantities back to inventory",
+                "description": "Check the email and password of a user and issue an access token, valid for service.token_lifetime, with a refresh token",
                 "consumes": [
                     "application/json"
                 ],
@@ -247,34 +223,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Orders"
+                    "Auth"
                 ],
-                "summary": "Cancel order",
+                "summary": "Log in",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Order unique identifier",
-                        "name": "order_id",
-                        "in": "path",
-                        "required": true
+                        "description": "User credentials",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/LoginRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Order cancelled successfully",
+                        "description": "Logged in successfully",
                         "schema": {
-                            "$ref": "#/definitions/Order"
+                            "$ref": "#/definitions/TokenResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request - invalid order ID format or order cannot be cancelled",
+                        "description": "Bad request - missing email or password",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not found - order with specified ID does not exist",
+                    "401": {
+                        "description": "Unauthorized - wrong email or password",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
@@ -288,9 +265,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/products": {
-            "get": {
-                "description": "Retrieve a paginated list of all products in the system",
+        "/api/v1/auth/logout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Revoke the access token the request is made with and the refresh token of the session; the access token is rejected from then on until it would have expired and the refresh token can't be exchanged any more. Presenting an already rotated refresh token revokes every refresh token of the user. Other access tokens of the user are left alone",
                 "consumes": [
                     "application/json"
                 ],
@@ -298,37 +280,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Products"
+                    "Auth"
                 ],
-                "summary": "Get products list",
+                "summary": "Log out",
                 "parameters": [
                     {
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number for pagination",
-                        "name": "page",
-                        "in": "query"
-                    },
-                    {
-                        "maximum": 100,
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Number of items per page",
-                        "name": "size",
-                        "in": "query"
+                        "description": "Refresh token of the session",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/LogoutRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Products retrieved successfully",
+                    "204": {
+                        "description": "Logged out successfully"
+                    },
+                    "400": {
+                        "description": "Bad request - missing refresh token",
                         "schema": {
-                            "$ref": "#/definitions/ProductsResponse"
+                            "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad request - invalid pagination parameters",
+                    "401": {
+                        "description": "Unauthorized - missing, invalid, expired or already revoked access token, or a refresh token unknown or of another user",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
@@ -338,11 +315,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Service unavailable - the revocation list can't be checked",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
                     }
                 }
-            },
+            }
+        },
+        "/api/v1/auth/refresh": {
             "post": {
-                "description": "Create a new product with description, tags, and initial quantity",
+                "description": "Issue a new access token and a new refresh token. The presented refresh token is rotated and can't be used again; presenting a rotated token is treated as theft and revokes every refresh token of the user",
                 "consumes": [
                     "application/json"
                 ],
@@ -350,29 +335,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Products"
+                    "Auth"
                 ],
-                "summary": "Create new product",
+                "summary": "Refresh tokens",
                 "parameters": [
                     {
-                        "description": "Product creation data",
+                        "description": "Refresh token",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/CreateProductRequest"
+                            "$ref": "#/definitions/RefreshRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Product created successfully",
+                    "200": {
+                        "description": "Tokens refreshed successfully",
                         "schema": {
-                            "$ref": "#/definitions/Product"
+                            "$ref": "#/definitions/TokenResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request - validation failed",
+                        "description": "Bad request - missing refresh token",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - unknown, expired, revoked or reused refresh token",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
@@ -386,58 +377,64 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/products/{product_id}": {
+        "/api/v1/debug/slow-queries": {
             "get": {
-                "description": "Retrieve detailed information about a specific product using its unique identifier",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "AdminToken": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "List the slowest of the recent queries that took at least postgres.slow_query_threshold, slowest first (admin only, served when service.debug is on). Only the last postgres.slow_query_log_size slow queries of this instance are kept; args are counted but never shown",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Products"
+                    "Debug"
                 ],
-                "summary": "Get product by ID",
+                "summary": "Get slow queries",
                 "parameters": [
                     {
-                        "type": "string",
-                        "format": "uuid",
-                        "description": "Product unique identifier",
-                        "name": "product_id",
-                        "in": "path",
-                        "required": true
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Number of queries to return, all kept queries by default",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Product information retrieved successfully",
+                        "description": "Slow queries",
                         "schema": {
-                            "$ref": "#/definitions/Product"
+                            "$ref": "#/definitions/SlowQueriesResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request - invalid product ID format",
+                        "description": "Bad request - invalid limit",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not found - product with specified ID does not exist",
+                    "401": {
+                        "description": "Unauthorized - admin token or access token is missing",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "403": {
+                        "description": "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Update an existing product's information including description, tags, and quantity",
+            }
+        },
+        "/api/v1/orders": {
+            "get": {
+                "description": "Retrieve a paginated list of all orders in the system",
                 "consumes": [
                     "application/json"
                 ],
@@ -445,43 +442,148 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Products"
+                    "Orders"
                 ],
-                "summary": "Update product",
+                "summary": "Get orders list",
                 "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number for pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Number of items per page, service.pagination.default_size (10 unless configured) when omitted; more than service.pagination.max_size (100 unless configured) is rejected with 400",
+                        "name": "size",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "format": "uuid",
-                        "description": "Product unique identifier",
-                        "name": "product_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Keyset cursor from pagination.next_cursor, takes precedence over page; only with the default order",
+                        "name": "cursor",
+                        "in": "query"
                     },
                     {
-                        "description": "Product update data",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/UpdateProductRequest"
-                        }
-                    }
-                ],
-                "responses": {
+                        "enum": [
+                            "created_at",
+                            "updated_at"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Column to sort by",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort direction",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Filter orders by user ID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Filter orders containing the product",
+                        "name": "product_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "products"
+                        ],
+                        "type": "string",
+                        "description": "Embed related resources",
+                        "name": "expand",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
                     "200": {
-                        "description": "Product updated successfully",
+                        "description": "Orders retrieved successfully",
                         "schema": {
-                            "$ref": "#/definitions/Product"
+                            "$ref": "#/definitions/OrdersResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request - invalid product ID format or validation failed",
+                        "description": "Bad request - invalid pagination parameters, cursor, sort, user ID, product ID or expand value",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new order with multiple items, automatically handles stock reservation",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Create new order",
+                "parameters": [
+                    {
+                        "description": "Order creation data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/CreateOrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Order created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - validation failed or insufficient stock (short products listed in shortages)",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not found - product with specified ID does not exist",
+                        "description": "Not found - user or product not found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - an order with the same ID already exists",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests - the user already has the maximum number of open orders",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
@@ -495,9 +597,17 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/users": {
-            "get": {
-                "description": "Retrieve a paginated list of all users in the system",
+        "/api/v1/orders/bulk-status": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Apply a status transition to many orders at once (admin only). Orders the transition is illegal for are skipped and reported; the rest are updated in a single transaction",
                 "consumes": [
                     "application/json"
                 ],
@@ -505,37 +615,109 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Orders"
                 ],
-                "summary": "Get users list",
+                "summary": "Bulk update order status",
                 "parameters": [
                     {
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number for pagination",
-                        "name": "page",
+                        "description": "Orders and target status",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/BulkUpdateOrderStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Per-order results",
+                        "schema": {
+                            "$ref": "#/definitions/BulkUpdateOrderStatusResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid JSON, no orders or unsupported target status",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - admin token or access token is missing",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/stats": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Count orders and their item quantities per status in a single query (admin only). Every status is reported, with zeros when no orders match",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get order statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Only aggregate orders of the user",
+                        "name": "user_id",
                         "in": "query"
                     },
                     {
-                        "maximum": 100,
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Number of items per page",
-                        "name": "size",
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Only aggregate orders containing the product",
+                        "name": "product_id",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Users retrieved successfully",
+                        "description": "Per-status aggregates",
                         "schema": {
-                            "$ref": "#/definitions/UsersResponse"
+                            "$ref": "#/definitions/OrderStatsResponse"
                         }
                     },
                     "400": {
-                        "description": "Bad request - invalid pagination parameters",
+                        "description": "Bad request - invalid user ID or product ID",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - admin token or access token is missing",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
@@ -547,9 +729,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/v1/orders/validate": {
             "post": {
-                "description": "Register a new user with validation (age \u003e= 18, password \u003e= 8 chars)",
+                "description": "Run the checks of order creation (user, open order limit, products, stock) and return the would-be order with its totals, without reserving stock or storing anything. The returned order has no ID, status or timestamps",
                 "consumes": [
                     "application/json"
                 ],
@@ -557,29 +741,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Orders"
                 ],
-                "summary": "Register new user",
+                "summary": "Validate order",
                 "parameters": [
                     {
-                        "description": "User registration data",
+                        "description": "Order creation data",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/CreateUserRequest"
+                            "$ref": "#/definitions/CreateOrderRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "User registered successfully",
+                    "200": {
+                        "description": "The order would be created",
                         "schema": {
-                            "$ref": "#/definitions/User"
+                            "$ref": "#/definitions/OrderPreview"
                         }
                     },
                     "400": {
-                        "description": "Bad request - validation failed",
+                        "description": "Bad request - validation failed or insufficient stock (short products listed in shortages)",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - user or product not found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests - the user already has the maximum number of open orders",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
@@ -593,9 +789,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/users/{user_id}": {
+        "/api/v1/orders/{order_id}": {
             "get": {
-                "description": "Retrieve detailed information about a specific user using their unique identifier",
+                "description": "Retrieve detailed information about a specific order using its unique identifier",
                 "consumes": [
                     "application/json"
                 ],
@@ -603,34 +799,44 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Orders"
                 ],
-                "summary": "Get user by ID",
+                "summary": "Get order by ID",
                 "parameters": [
                     {
                         "type": "string",
                         "format": "uuid",
-                        "description": "User unique identifier",
-                        "name": "user_id",
+                        "description": "Order unique identifier",
+                        "name": "order_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "user",
+                            "products"
+                        ],
+                        "type": "string",
+                        "description": "Embed related resources, comma-separated",
+                        "name": "expand",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "User information retrieved successfully",
+                        "description": "Order information retrieved successfully",
                         "schema": {
-                            "$ref": "#/definitions/User"
+                            "$ref": "#/definitions/Order"
                         }
                     },
                     "400": {
-                        "description": "Bad request - invalid user ID format",
+                        "description": "Bad request - invalid order ID format or expand value",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "Not found - user with specified ID does not exist",
+                        "description": "Not found - order with specified ID does not exist",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
@@ -642,214 +848,2353 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "CreateOrderItemRequest": {
-            "description": "Request item for creating an order",
-            "type": "object",
-            "required": [
-                "product_id",
-                "quantity"
-            ],
-            "properties": {
-                "product_id": {
-                    "description": "Product ID\n@Description ID of the product to order\n@Example 456e7890-e12b-34d5-a678-901234567890",
-                    "type": "string",
-                    "example": "456e7890-e12b-34d5-a678-901234567890"
-                },
-                "quantity": {
-                    "description": "Quantity\n@Description Quantity to order\n@Example 2",
-                    "type": "integer",
-                    "example": 2
-                }
-            }
-        },
-        "CreateOrderRequest": {
-            "description": "Request payload for creating an order",
-            "type": "object",
-            "required": [
-                "items",
-                "user_id"
-            ],
-            "properties": {
-                "items": {
-                    "description": "Items\n@Description List of items to order (at least one required)",
-                    "type": "array",
-                    "minItems": 1,
-                    "items": {
-                        "$ref": "#/definitions/CreateOrderItemRequest"
-                    }
-                },
-                "user_id": {
+            },
+            "put": {
+                "description": "Update an existing order's status or other mutable fields",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Update order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order unique identifier",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Order update data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/UpdateOrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid order ID format, unknown status or validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - order with specified ID does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Permanently delete an order and its items (admin only). Unlike cancellation, product stock is not restored",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Delete order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order unique identifier",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Order deleted successfully"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid order ID format",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - admin token or access token is missing",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - order with specified ID does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{order_id}/cancel": {
+            "post": {
+                "description": "Cancel an order and restore product quantities back to inventory",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Cancel order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order unique identifier",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order cancelled successfully",
+                        "schema": {
+                            "$ref": "#/definitions/Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid order ID format or order cannot be cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - order with specified ID does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{order_id}/history": {
+            "get": {
+                "description": "Retrieve every status transition of an order with its time and actor, oldest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get order status history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order unique identifier",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order status history retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/OrderStatusHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid order ID format",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - order with specified ID does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{order_id}/invoice": {
+            "get": {
+                "description": "Download a PDF invoice of the order with its ID, date, line items from the product snapshots and the total quantity",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Download order invoice",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order unique identifier",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "PDF invoice",
+                        "schema": {
+                            "type": "file"
+                        },
+                        "headers": {
+                            "Content-Disposition": {
+                                "type": "string",
+                                "description": "Attachment named invoice-\u003corder_id\u003e.pdf"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid order ID format",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - order with specified ID does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{order_id}/items": {
+            "put": {
+                "description": "Replace the items of a pending order, restoring stock for removed items and reserving it for added ones",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Update order items",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order unique identifier",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New order items",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/UpdateOrderItemsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order items updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - validation failed, insufficient stock (short products listed in shortages) or order is not pending",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - order or product not found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{order_id}/items/{item_id}/cancel": {
+            "post": {
+                "description": "Remove one item from a pending or confirmed order, restoring its quantity to inventory and lowering the order total in one transaction. Cancelling the last item cancels the whole order",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Cancel order item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order unique identifier",
+                        "name": "order_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Order item unique identifier",
+                        "name": "item_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Item cancelled; the order is cancelled when it was the last item",
+                        "schema": {
+                            "$ref": "#/definitions/Order"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid order or item ID format, or the order cannot be cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - order or item does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products": {
+            "get": {
+                "description": "Retrieve a paginated list of all products in the system",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Get products list",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number for pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Number of items per page, service.pagination.default_size (10 unless configured) when omitted; more than service.pagination.max_size (100 unless configured) is rejected with 400",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Keyset cursor from pagination.next_cursor, takes precedence over page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only products that are in stock (true) or out of stock (false)",
+                        "name": "available",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Only products with quantity less than or equal to this value",
+                        "name": "max_quantity",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Only products priced at or above this value, in minor currency units",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Only products priced at or below this value, in minor currency units",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Words of the description or tags (full-text, stemmed) or a case-insensitive part of the description",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "price",
+                            "relevance"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Column to sort by, relevance requires q and is the default with it",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort direction",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated product IDs to fetch at once, at most the maximum page size; the other parameters are ignored and missing_ids lists the IDs not found",
+                        "name": "ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously received response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Products retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/ProductsResponse"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "How long the response may be reused"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of the response body"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified - the If-None-Match ETag is still current"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid pagination parameters, cursor, filters, sort or IDs",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new product with description, tags, and initial quantity",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Create new product",
+                "parameters": [
+                    {
+                        "description": "Product creation data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/CreateProductRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Product created successfully",
+                        "schema": {
+                            "$ref": "#/definitions/Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - a product with the same ID or description already exists",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/export": {
+            "get": {
+                "description": "Stream the products matching the list filters as a CSV file, fetched in batches so large catalogs are not buffered. Text cells starting with =, +, -, @, a tab or a carriage return are prefixed with ' so spreadsheets show them as text",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Export products",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only products that are in stock (true) or out of stock (false)",
+                        "name": "available",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Only products with quantity less than or equal to this value",
+                        "name": "max_quantity",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Only products priced at or above this value, in minor currency units",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "Only products priced at or below this value, in minor currency units",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Words of the description or tags (full-text, stemmed) or a case-insensitive part of the description",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "price",
+                            "relevance"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Column to sort by, relevance requires q and is the default with it",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort direction",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV with the columns id, description, tags, quantity, price, available, created_at",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid filters or sort",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/import": {
+            "post": {
+                "description": "Create products from newline-delimited JSON, one product object per line. Lines are read as a stream and stored in batches of 100, each batch in its own transaction; invalid lines are reported and do not stop the rest of the file. A line whose description is already taken by a product that is not deleted, in the catalog or earlier in the file, fails as a conflict instead of updating that product",
+                "consumes": [
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Import products",
+                "parameters": [
+                    {
+                        "description": "One CreateProductRequest object per line",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Per-line import results",
+                        "schema": {
+                            "$ref": "#/definitions/ImportProductsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/tags": {
+            "get": {
+                "description": "List the distinct tags of the catalog with the number of products carrying each, most used first, for faceted browsing",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Get product tags",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only count products that are in stock (true) or out of stock (false)",
+                        "name": "available",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously received response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tags retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/ProductTagsResponse"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "How long the response may be reused"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of the response body"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified - the If-None-Match ETag is still current"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid available filter",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/upsert": {
+            "post": {
+                "description": "Find the product with exactly this description or create it from the request. Tags, quantity and price are only used when the product is created, an existing product is returned unchanged",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Get or create product by description",
+                "parameters": [
+                    {
+                        "description": "Product to find or create",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/CreateProductRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Existing product",
+                        "schema": {
+                            "$ref": "#/definitions/UpsertProductResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Product created",
+                        "schema": {
+                            "$ref": "#/definitions/UpsertProductResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/{product_id}": {
+            "get": {
+                "description": "Retrieve detailed information about a specific product using its unique identifier",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Get product by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Product unique identifier",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously received response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product information retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/Product"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "How long the response may be reused"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of the response body"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified - the If-None-Match ETag is still current"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid product ID format",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - product with specified ID does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update an existing product's information including description, tags, and quantity",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Update product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Product unique identifier",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Product update data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/UpdateProductRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product updated successfully",
+                        "schema": {
+                            "$ref": "#/definitions/Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid product ID format or validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - product with specified ID does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - another product has the new description, or the product is no longer at the given version",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft-delete a product (admin only); it disappears from the catalog and can no longer be ordered, existing orders keep their item snapshots",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Delete product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Product unique identifier",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Product deleted successfully"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid product ID format",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - admin token or access token is missing",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - product with specified ID does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/products/{product_id}/restock": {
+            "post": {
+                "description": "Atomically add stock to a product, making an out-of-stock product available again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Products"
+                ],
+                "summary": "Restock product",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "Product unique identifier",
+                        "name": "product_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Quantity to add",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/RestockProductRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Product restocked successfully",
+                        "schema": {
+                            "$ref": "#/definitions/Product"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid product ID format or non-positive quantity",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - product with specified ID does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users": {
+            "get": {
+                "description": "Retrieve a paginated list of all users in the system",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get users list",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number for pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Number of items per page, service.pagination.default_size (10 unless configured) when omitted; more than service.pagination.max_size (100 unless configured) is rejected with 400",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Only users registered at or after this time (RFC3339)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "format": "date-time",
+                        "description": "Only users registered at or before this time (RFC3339)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive part of the user's full name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "name",
+                            "age"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Column to sort by, name is the full name",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort direction",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously received response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Users retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/UsersResponse"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "How long the response may be reused"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of the response body"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified - the If-None-Match ETag is still current"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid pagination parameters, dates or sort",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Register a new user with validation (by default age \u003e= 18 and password \u003e= 8 chars, configurable via service.user_policy)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Register new user",
+                "parameters": [
+                    {
+                        "description": "User registration data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/CreateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User registered successfully",
+                        "schema": {
+                            "$ref": "#/definitions/User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - a user with the same ID or email already exists, a taken email is reported in fields",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many requests - retry after the Retry-After delay",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Register many users at once (admin only). Every user is validated before any is stored, then all are inserted in a single transaction or none. A batch may not repeat an email or take the email of a registered user; the conflicting users are reported in fields by position, e.g. users[2].email",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Bulk register users",
+                "parameters": [
+                    {
+                        "description": "Users to register",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/BulkRegisterUsersRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Users registered successfully",
+                        "schema": {
+                            "$ref": "#/definitions/BulkRegisterUsersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - validation failed, problems are keyed by position",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - admin token or access token is missing",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict - an email is repeated or already registered, nothing was registered",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/me": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve the user the access token was issued to",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get current user",
+                "responses": {
+                    "200": {
+                        "description": "User information retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/User"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - missing, invalid or expired access token",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - the user was deleted",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/verify": {
+            "get": {
+                "description": "Confirm the email address with the token mailed on registration. The token is single use",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Verify email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Verification token from the email",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Email verified successfully",
+                        "schema": {
+                            "$ref": "#/definitions/User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - missing token",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - invalid or already used token",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{user_id}": {
+            "get": {
+                "description": "Retrieve detailed information about a specific user using their unique identifier",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get user by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User unique identifier",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously received response",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User information retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/User"
+                        },
+                        "headers": {
+                            "Cache-Control": {
+                                "type": "string",
+                                "description": "How long the response may be reused"
+                            },
+                            "ETag": {
+                                "type": "string",
+                                "description": "Hash of the response body"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified - the If-None-Match ETag is still current"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid user ID format",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - user with specified ID does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft-delete a user (admin only); the user is hidden from listings but its orders are kept",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Delete user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User unique identifier",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "User deleted successfully"
+                    },
+                    "400": {
+                        "description": "Bad request - invalid user ID format",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized - admin token or access token is missing",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - user with specified ID does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/users/{user_id}/orders": {
+            "get": {
+                "description": "Retrieve a paginated list of orders placed by a specific user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get user orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "uuid",
+                        "description": "User unique identifier",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number for pagination",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "Number of items per page, service.pagination.default_size (10 unless configured) when omitted; more than service.pagination.max_size (100 unless configured) is rejected with 400",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Keyset cursor from pagination.next_cursor, takes precedence over page; only with the default order",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "updated_at"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Column to sort by",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort direction",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Orders retrieved successfully",
+                        "schema": {
+                            "$ref": "#/definitions/OrdersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request - invalid user ID format, cursor or sort",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found - user with specified ID does not exist",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Report that the instance is up. Dependencies are not checked and the concurrency limit does not apply",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "The instance is up",
+                        "schema": {
+                            "$ref": "#/definitions/HealthStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/ready": {
+            "get": {
+                "description": "Report whether startup is done. While migrations are applied the status is \"migrating\", while the caches are warmed up \"warming\", both with 503, and every other API request is answered with 503 NOT_READY",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "The instance takes traffic",
+                        "schema": {
+                            "$ref": "#/definitions/HealthStatus"
+                        }
+                    },
+                    "503": {
+                        "description": "The instance is starting up",
+                        "schema": {
+                            "$ref": "#/definitions/HealthStatus"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "BulkRegisterUsersRequest": {
+            "description": "Request payload for a bulk user registration",
+            "type": "object",
+            "required": [
+                "users"
+            ],
+            "properties": {
+                "users": {
+                    "description": "Users\n@Description Users to register (1 to 100), all or none of them are registered",
+                    "type": "array",
+                    "maxItems": 100,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/CreateUserRequest"
+                    }
+                }
+            }
+        },
+        "BulkRegisterUsersResponse": {
+            "description": "Registered users in the order of the request",
+            "type": "object",
+            "properties": {
+                "users": {
+                    "description": "Users\n@Description Registered users",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/User"
+                    }
+                }
+            }
+        },
+        "BulkUpdateOrderStatusRequest": {
+            "description": "Request payload for a bulk order status update",
+            "type": "object",
+            "required": [
+                "ids",
+                "status"
+            ],
+            "properties": {
+                "ids": {
+                    "description": "IDs\n@Description Orders to update (1 to 100)",
+                    "type": "array",
+                    "maxItems": 100,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "status": {
+                    "description": "Status\n@Description Target status; orders are cancelled one by one through the cancel endpoint\n@Example \"confirmed\"",
+                    "type": "string",
+                    "enum": [
+                        "confirmed",
+                        "completed"
+                    ],
+                    "example": "confirmed"
+                }
+            }
+        },
+        "BulkUpdateOrderStatusResponse": {
+            "description": "Per-order results in request order",
+            "type": "object",
+            "properties": {
+                "results": {
+                    "description": "Results\n@Description One result per distinct requested order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/OrderStatusUpdateResult"
+                    }
+                }
+            }
+        },
+        "CreateOrderItemRequest": {
+            "description": "Request item for creating an order",
+            "type": "object",
+            "required": [
+                "product_id",
+                "quantity"
+            ],
+            "properties": {
+                "product_id": {
+                    "description": "Product ID\n@Description ID of the product to order\n@Example 456e7890-e12b-34d5-a678-901234567890",
+                    "type": "string",
+                    "example": "456e7890-e12b-34d5-a678-901234567890"
+                },
+                "quantity": {
+                    "description": "Quantity\n@Description Quantity to order\n@Example 2",
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "CreateOrderRequest": {
+            "description": "Request payload for creating an order",
+            "type": "object",
+            "required": [
+                "items",
+                "user_id"
+            ],
+            "properties": {
+                "items": {
+                    "description": "Items\n@Description List of items to order (at least one required); lines of the same product are merged into one item with the summed quantity",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/CreateOrderItemRequest"
+                    }
+                },
+                "user_id": {
                     "description": "User ID\n@Description ID of the user creating the order\n@Example 123e4567-e89b-12d3-a456-426614174000",
                     "type": "string",
-                    "example": "123e4567-e89b-12d3-a456-426614174000"
+                    "example": "123e4567-e89b-12d3-a456-426614174000"
+                }
+            }
+        },
+        "CreateProductRequest": {
+            "description": "Request payload for creating a product",
+            "type": "object",
+            "required": [
+                "description",
+                "quantity"
+            ],
+            "properties": {
+                "description": {
+                    "description": "Description\n@Description Product description (required)\n@Example \"High-quality smartphone\"",
+                    "type": "string",
+                    "example": "High-quality smartphone"
+                },
+                "price": {
+                    "description": "Price\n@Description Price in minor currency units\n@Example 49990",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 49990
+                },
+                "quantity": {
+                    "description": "Quantity\n@Description Initial quantity in stock\n@Example 100",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 100
+                },
+                "tags": {
+                    "description": "Tags\n@Description Product tags for categorization\n@Example [\"electronics\", \"mobile\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "electronics",
+                        "mobile"
+                    ]
+                }
+            }
+        },
+        "CreateUserRequest": {
+            "description": "Request payload for user registration",
+            "type": "object",
+            "required": [
+                "age",
+                "email",
+                "first_name",
+                "last_name",
+                "password"
+            ],
+            "properties": {
+                "age": {
+                    "description": "Age\n@Description User's age (must meet the configured minimum, 18 by default)\n@Example 25",
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 25
+                },
+                "email": {
+                    "description": "Email\n@Description User's email address, a verification link is sent to it (required)\n@Example john.doe@example.com",
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                },
+                "first_name": {
+                    "description": "First name\n@Description User's first name (required)\n@Example John",
+                    "type": "string",
+                    "example": "John"
+                },
+                "is_married": {
+                    "description": "Is married\n@Description Whether the user is married\n@Example false",
+                    "type": "boolean",
+                    "example": false
+                },
+                "last_name": {
+                    "description": "Last name\n@Description User's last name (required)\n@Example Doe",
+                    "type": "string",
+                    "example": "Doe"
+                },
+                "password": {
+                    "description": "Password\n@Description User's password (at least the configured length, 8 characters by default)\n@Example password123",
+                    "type": "string",
+                    "example": "password123"
+                }
+            }
+        },
+        "ErrorResponse": {
+            "description": "Error response format",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Error code (optional)\n@Description Stable machine-readable error code, e.g. USER_NOT_FOUND or INSUFFICIENT_STOCK\n@Example \"USER_NOT_FOUND\"",
+                    "type": "string",
+                    "example": "USER_NOT_FOUND"
+                },
+                "fields": {
+                    "description": "Field errors (optional)\n@Description Problems with individual request fields, keyed by field name",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "age": "user must be at least 18 years old",
+                        "first_name": "first name is required"
+                    }
+                },
+                "message": {
+                    "description": "Error message\n@Description Human-readable error message\n@Example \"Validation failed\"",
+                    "type": "string",
+                    "example": "Validation failed"
+                },
+                "shortages": {
+                    "description": "Stock shortages (optional)\n@Description Products that can't cover the requested quantity",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/StockShortage"
+                    }
+                }
+            }
+        },
+        "HealthStatus": {
+            "description": "State of the instance",
+            "type": "object",
+            "properties": {
+                "status": {
+                    "description": "Status\n@Description \"ok\" for liveness; \"ready\" or the startup step, e.g. \"migrating\", for readiness\n@Example \"ok\"",
+                    "type": "string",
+                    "example": "ok"
                 }
             }
         },
-        "CreateProductRequest": {
-            "description": "Request payload for creating a product",
+        "ImportProductResult": {
+            "description": "Result of importing a single line, either id or error is set",
             "type": "object",
-            "required": [
-                "description",
-                "quantity"
-            ],
             "properties": {
-                "description": {
-                    "description": "Description\n@Description Product description (required)\n@Example \"High-quality smartphone\"",
+                "error": {
+                    "description": "Error\n@Description Reason the line was not imported\n@Example \"product validation failed: description is required\"",
                     "type": "string",
-                    "example": "High-quality smartphone"
+                    "example": "product validation failed: description is required"
                 },
-                "quantity": {
-                    "description": "Quantity\n@Description Initial quantity in stock\n@Example 100",
+                "id": {
+                    "description": "Product ID\n@Description ID of the created product\n@Example \"123e4567-e89b-12d3-a456-426614174000\"",
+                    "type": "string",
+                    "example": "123e4567-e89b-12d3-a456-426614174000"
+                },
+                "line": {
+                    "description": "Line\n@Description Line number in the uploaded file, starting at 1\n@Example 1",
                     "type": "integer",
-                    "minimum": 0,
-                    "example": 100
+                    "example": 1
+                }
+            }
+        },
+        "ImportProductsResponse": {
+            "description": "Per-line results of a products import",
+            "type": "object",
+            "properties": {
+                "created": {
+                    "description": "Created\n@Description Number of created products\n@Example 2",
+                    "type": "integer",
+                    "example": 2
                 },
-                "tags": {
-                    "description": "Tags\n@Description Product tags for categorization\n@Example [\"electronics\", \"mobile\"]",
+                "failed": {
+                    "description": "Failed\n@Description Number of lines that were not imported\n@Example 1",
+                    "type": "integer",
+                    "example": 1
+                },
+                "results": {
+                    "description": "Results\n@Description Results in file order, blank lines are skipped",
                     "type": "array",
                     "items": {
-                        "type": "string"
-                    },
-                    "example": [
-                        "electronics",
-                        "mobile"
-                    ]
+                        "$ref": "#/definitions/ImportProductResult"
+                    }
                 }
             }
         },
-        "CreateUserRequest": {
-            "description": "Request payload for user registration",
+        "LoginRequest": {
+            "description": "Credentials of a registered user",
             "type": "object",
             "required": [
-                "age",
-                "first_name",
-                "last_name",
+                "email",
                 "password"
             ],
             "properties": {
-                "age": {
-                    "description": "Age\n@Description User's age (must be 18 or older)\n@Example 25",
+                "email": {
+                    "description": "Email\n@Description Email address given at registration\n@Example john.doe@example.com",
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                },
+                "password": {
+                    "description": "Password\n@Description User's password\n@Example password123",
+                    "type": "string",
+                    "example": "password123"
+                }
+            }
+        },
+        "LogoutRequest": {
+            "description": "Refresh token of the session to end",
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "description": "Refresh token\n@Description Refresh token from the last login or refresh of the session, revoked with the access token\n@Example 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                }
+            }
+        },
+        "MaintenanceRequest": {
+            "description": "Request payload for switching maintenance mode",
+            "type": "object",
+            "required": [
+                "enabled"
+            ],
+            "properties": {
+                "enabled": {
+                    "description": "Enabled\n@Description Whether mutating endpoints should be rejected\n@Example true",
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "MaintenanceStatus": {
+            "description": "Maintenance mode of the instance",
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "description": "Enabled\n@Description Whether mutating endpoints are rejected with 503\n@Example false",
+                    "type": "boolean",
+                    "example": false
+                },
+                "retry_after": {
+                    "description": "Retry after\n@Description Seconds rejected clients are asked to wait before retrying\n@Example 60",
                     "type": "integer",
-                    "minimum": 18,
-                    "example": 25
+                    "example": 60
+                }
+            }
+        },
+        "Order": {
+            "description": "Order information with items",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Created at\n@Description When the order was created\n@Example 2024-01-15T10:30:00Z",
+                    "type": "string",
+                    "example": "2024-01-15T10:30:00Z"
                 },
-                "first_name": {
-                    "description": "First name\n@Description User's first name (required)\n@Example John",
+                "id": {
+                    "description": "Order ID\n@Description Unique identifier for the order\n@Example 987e6543-e21d-12c3-b456-426614174000",
                     "type": "string",
-                    "example": "John"
+                    "example": "987e6543-e21d-12c3-b456-426614174000"
                 },
-                "is_married": {
-                    "description": "Is married\n@Description Whether the user is married\n@Example false",
+                "items": {
+                    "description": "Items\n@Description List of items in the order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/OrderItem"
+                    }
+                },
+                "status": {
+                    "description": "Status\n@Description Current order status\n@Example \"pending\"",
+                    "type": "string",
+                    "example": "pending"
+                },
+                "total_quantity": {
+                    "description": "Total quantity\n@Description Total quantity of all items in the order\n@Example 5",
+                    "type": "integer",
+                    "example": 5
+                },
+                "updated_at": {
+                    "description": "Updated at\n@Description When the order was last updated\n@Example 2024-01-15T10:30:00Z",
+                    "type": "string",
+                    "example": "2024-01-15T10:30:00Z"
+                },
+                "user": {
+                    "description": "User\n@Description User who created the order, only with expand=user",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/OrderUser"
+                        }
+                    ]
+                },
+                "user_id": {
+                    "description": "User ID\n@Description ID of the user who created the order\n@Example 123e4567-e89b-12d3-a456-426614174000",
+                    "type": "string",
+                    "example": "123e4567-e89b-12d3-a456-426614174000"
+                }
+            }
+        },
+        "OrderItem": {
+            "description": "Order item with historical product information",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Created at\n@Description When the order item was created\n@Example 2024-01-15T10:30:00Z",
+                    "type": "string",
+                    "example": "2024-01-15T10:30:00Z"
+                },
+                "id": {
+                    "description": "Item ID\n@Description Unique identifier for the order item\n@Example 789e0123-e45f-67g8-h901-234567890123",
+                    "type": "string",
+                    "example": "789e0123-e45f-67g8-h901-234567890123"
+                },
+                "product": {
+                    "description": "Product\n@Description Current state of the product, only with expand=products",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/OrderItemProduct"
+                        }
+                    ]
+                },
+                "product_id": {
+                    "description": "Product ID\n@Description ID of the product (current)\n@Example 456e7890-e12b-34d5-a678-901234567890",
+                    "type": "string",
+                    "example": "456e7890-e12b-34d5-a678-901234567890"
+                },
+                "product_snapshot": {
+                    "description": "Product snapshot\n@Description Historical product information at order time",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/ProductSnapshot"
+                        }
+                    ]
+                },
+                "quantity": {
+                    "description": "Quantity\n@Description Quantity of the product in the order\n@Example 2",
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "OrderItemProduct": {
+            "description": "Product as it is now, to compare with the snapshot taken at order time",
+            "type": "object",
+            "properties": {
+                "available": {
+                    "description": "Available\n@Description Whether the product can be ordered now\n@Example true",
+                    "type": "boolean",
+                    "example": true
+                },
+                "changed": {
+                    "description": "Changed\n@Description Whether the description or tags differ from the snapshot\n@Example true",
+                    "type": "boolean",
+                    "example": true
+                },
+                "deleted": {
+                    "description": "Deleted\n@Description Whether the product was deleted; the other fields are absent when it no longer exists at all\n@Example false",
                     "type": "boolean",
                     "example": false
                 },
-                "last_name": {
-                    "description": "Last name\n@Description User's last name (required)\n@Example Doe",
+                "description": {
+                    "description": "Description\n@Description Current product description\n@Example \"High-quality smartphone, 2024 edition\"",
                     "type": "string",
-                    "example": "Doe"
+                    "example": "High-quality smartphone, 2024 edition"
                 },
-                "password": {
-                    "description": "Password\n@Description User's password (minimum 8 characters)\n@Example password123",
+                "quantity": {
+                    "description": "Quantity\n@Description Current quantity in stock\n@Example 8",
+                    "type": "integer",
+                    "example": 8
+                }
+            }
+        },
+        "OrderPreview": {
+            "description": "Would-be order of a dry run; it has no ID, status or timestamps as nothing is stored",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "Items\n@Description List of items the order would have",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/OrderPreviewItem"
+                    }
+                },
+                "total_quantity": {
+                    "description": "Total quantity\n@Description Total quantity of all items in the order\n@Example 5",
+                    "type": "integer",
+                    "example": 5
+                },
+                "user_id": {
+                    "description": "User ID\n@Description ID of the user the order is for\n@Example 123e4567-e89b-12d3-a456-426614174000",
                     "type": "string",
-                    "minLength": 8,
-                    "example": "password123"
+                    "example": "123e4567-e89b-12d3-a456-426614174000"
+                }
+            }
+        },
+        "OrderPreviewItem": {
+            "description": "Item of a would-be order",
+            "type": "object",
+            "properties": {
+                "product_id": {
+                    "description": "Product ID\n@Description ID of the product\n@Example 456e7890-e12b-34d5-a678-901234567890",
+                    "type": "string",
+                    "example": "456e7890-e12b-34d5-a678-901234567890"
+                },
+                "product_snapshot": {
+                    "description": "Product snapshot\n@Description Product information the order would capture",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/ProductSnapshot"
+                        }
+                    ]
+                },
+                "quantity": {
+                    "description": "Quantity\n@Description Quantity of the product in the order\n@Example 2",
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "OrderStatsResponse": {
+            "description": "Order aggregates keyed by status; every status is present",
+            "type": "object",
+            "properties": {
+                "statuses": {
+                    "description": "Statuses\n@Description Aggregates keyed by order status (pending, confirmed, cancelled, completed)",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/OrderStatusStats"
+                    }
+                }
+            }
+        },
+        "OrderStatusChange": {
+            "description": "Order status transition",
+            "type": "object",
+            "properties": {
+                "actor_id": {
+                    "description": "Actor ID\n@Description User who changed the status, omitted when unknown\n@Example \"123e4567-e89b-12d3-a456-426614174000\"",
+                    "type": "string",
+                    "example": "123e4567-e89b-12d3-a456-426614174000"
+                },
+                "changed_at": {
+                    "description": "Changed at\n@Description Time of the change\n@Example \"2023-01-01T12:00:00Z\"",
+                    "type": "string",
+                    "example": "2023-01-01T12:00:00Z"
+                },
+                "from_status": {
+                    "description": "From status\n@Description Status before the change\n@Example \"pending\"",
+                    "type": "string",
+                    "example": "pending"
+                },
+                "to_status": {
+                    "description": "To status\n@Description Status after the change\n@Example \"confirmed\"",
+                    "type": "string",
+                    "example": "confirmed"
+                }
+            }
+        },
+        "OrderStatusHistoryResponse": {
+            "description": "Order status transitions, oldest first",
+            "type": "object",
+            "properties": {
+                "history": {
+                    "description": "History\n@Description Status transitions, oldest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/OrderStatusChange"
+                    }
                 }
             }
         },
-        "ErrorResponse": {
-            "description": "Error response format",
+        "OrderStatusStats": {
+            "description": "Order count and item quantity for a status",
             "type": "object",
             "properties": {
-                "code": {
-                    "description": "Error code (optional)\n@Description Machine-readable error code\n@Example \"INVALID_INPUT\"",
-                    "type": "string",
-                    "example": "INVALID_INPUT"
+                "count": {
+                    "description": "Count\n@Description Number of orders in the status\n@Example 12",
+                    "type": "integer",
+                    "example": 12
                 },
-                "message": {
-                    "description": "Error message\n@Description Human-readable error message\n@Example \"Validation failed\"",
-                    "type": "string",
-                    "example": "Validation failed"
+                "total_quantity": {
+                    "description": "Total quantity\n@Description Sum of item quantities across the orders\n@Example 30",
+                    "type": "integer",
+                    "example": 30
                 }
             }
         },
-        "Order": {
-            "description": "Order information with items",
+        "OrderStatusUpdateResult": {
+            "description": "Per-order result of a bulk status update",
             "type": "object",
             "properties": {
-                "created_at": {
-                    "description": "Created at\n@Description When the order was created\n@Example 2024-01-15T10:30:00Z",
+                "error": {
+                    "description": "Error\n@Description Why the order was skipped\n@Example \"order validation error: order cannot be confirmed in status cancelled\"",
                     "type": "string",
-                    "example": "2024-01-15T10:30:00Z"
+                    "example": "order validation error: order cannot be confirmed in status cancelled"
                 },
-                "id": {
-                    "description": "Order ID\n@Description Unique identifier for the order\n@Example 987e6543-e21d-12c3-b456-426614174000",
+                "order_id": {
+                    "description": "Order ID\n@Description Order unique identifier\n@Example \"123e4567-e89b-12d3-a456-426614174000\"",
                     "type": "string",
-                    "example": "987e6543-e21d-12c3-b456-426614174000"
-                },
-                "items": {
-                    "description": "Items\n@Description List of items in the order",
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/OrderItem"
-                    }
+                    "example": "123e4567-e89b-12d3-a456-426614174000"
                 },
                 "status": {
-                    "description": "Status\n@Description Current order status\n@Example \"pending\"",
-                    "type": "string",
-                    "example": "pending"
-                },
-                "total_quantity": {
-                    "description": "Total quantity\n@Description Total quantity of all items in the order\n@Example 5",
-                    "type": "integer",
-                    "example": 5
-                },
-                "updated_at": {
-                    "description": "Updated at\n@Description When the order was last updated\n@Example 2024-01-15T10:30:00Z",
+                    "description": "Status\n@Description Order status after the update, omitted for unknown orders\n@Example \"confirmed\"",
                     "type": "string",
-                    "example": "2024-01-15T10:30:00Z"
+                    "example": "confirmed"
                 },
-                "user_id": {
-                    "description": "User ID\n@Description ID of the user who created the order\n@Example 123e4567-e89b-12d3-a456-426614174000",
-                    "type": "string",
-                    "example": "123e4567-e89b-12d3-a456-426614174000"
+                "updated": {
+                    "description": "Updated\n@Description Whether the order was moved to the target status\n@Example true",
+                    "type": "boolean",
+                    "example": true
                 }
             }
         },
-        "OrderItem": {
-            "description": "Order item with historical product information",
+        "OrderUser": {
+            "description": "Minimal details of the user who created the order",
             "type": "object",
             "properties": {
-                "created_at": {
-                    "description": "Created at\n@Description When the order item was created\n@Example 2024-01-15T10:30:00Z",
+                "deleted_at": {
+                    "description": "Deleted at\n@Description When the user was soft-deleted, absent for active users\n@Example 2024-02-01T12:00:00Z",
                     "type": "string",
-                    "example": "2024-01-15T10:30:00Z"
+                    "example": "2024-02-01T12:00:00Z"
                 },
-                "id": {
-                    "description": "Item ID\n@Description Unique identifier for the order item\n@Example 789e0123-e45f-67g8-h901-234567890123",
+                "email": {
+                    "description": "Email\n@Description User's email address\n@Example \"john.doe@example.com\"",
                     "type": "string",
-                    "example": "789e0123-e45f-67g8-h901-234567890123"
+                    "example": "john.doe@example.com"
                 },
-                "product_id": {
-                    "description": "Product ID\n@Description ID of the product (current)\n@Example 456e7890-e12b-34d5-a678-901234567890",
+                "first_name": {
+                    "description": "First name\n@Description User's first name\n@Example \"John\"",
                     "type": "string",
-                    "example": "456e7890-e12b-34d5-a678-901234567890"
+                    "example": "John"
                 },
-                "product_snapshot": {
-                    "description": "Product snapshot\n@Description Historical product information at order time",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/ProductSnapshot"
-                        }
-                    ]
+                "id": {
+                    "description": "User ID\n@Description Unique identifier for the user\n@Example 123e4567-e89b-12d3-a456-426614174000",
+                    "type": "string",
+                    "example": "123e4567-e89b-12d3-a456-426614174000"
                 },
-                "quantity": {
-                    "description": "Quantity\n@Description Quantity of the product in the order\n@Example 2",
-                    "type": "integer",
-                    "example": 2
+                "last_name": {
+                    "description": "Last name\n@Description User's last name\n@Example \"Doe\"",
+                    "type": "string",
+                    "example": "Doe"
                 }
             }
         },
@@ -878,11 +3223,36 @@ const docTemplate = `{
             "description": "Pagination metadata for API responses",
             "type": "object",
             "properties": {
+                "first": {
+                    "description": "First page link\n@Description Link to the first page\n@Example \"/api/v1/products?page=1\u0026size=10\"",
+                    "type": "string",
+                    "example": "/api/v1/products?page=1\u0026size=10"
+                },
+                "last": {
+                    "description": "Last page link\n@Description Link to the last page\n@Example \"/api/v1/products?page=10\u0026size=10\"",
+                    "type": "string",
+                    "example": "/api/v1/products?page=10\u0026size=10"
+                },
+                "next": {
+                    "description": "Next page link\n@Description Link to the next page, omitted on the last page\n@Example \"/api/v1/products?page=3\u0026size=10\"",
+                    "type": "string",
+                    "example": "/api/v1/products?page=3\u0026size=10"
+                },
+                "next_cursor": {
+                    "description": "Next cursor\n@Description Opaque cursor for the next page, omitted on the last page\n@Example \"AAYjZ0q9bWDtF0tTq6yNQb6Jt3Xo2FHy\"",
+                    "type": "string",
+                    "example": "AAYjZ0q9bWDtF0tTq6yNQb6Jt3Xo2FHy"
+                },
                 "page": {
                     "description": "Page number (1-based)\n@Description Current page number\n@Example 1",
                     "type": "integer",
                     "example": 1
                 },
+                "prev": {
+                    "description": "Previous page link\n@Description Link to the previous page, omitted on the first page\n@Example \"/api/v1/products?page=1\u0026size=10\"",
+                    "type": "string",
+                    "example": "/api/v1/products?page=1\u0026size=10"
+                },
                 "size": {
                     "description": "Size (items per page)\n@Description Number of items per page\n@Example 10",
                     "type": "integer",
@@ -924,6 +3294,11 @@ const docTemplate = `{
                     "type": "string",
                     "example": "456e7890-e12b-34d5-a678-901234567890"
                 },
+                "price": {
+                    "description": "Price\n@Description Price in minor currency units\n@Example 49990",
+                    "type": "integer",
+                    "example": 49990
+                },
                 "quantity": {
                     "description": "Quantity\n@Description Available quantity in stock\n@Example 100",
                     "type": "integer",
@@ -944,6 +3319,11 @@ const docTemplate = `{
                     "description": "Updated at\n@Description When the product was last updated\n@Example 2024-01-15T10:30:00Z",
                     "type": "string",
                     "example": "2024-01-15T10:30:00Z"
+                },
+                "version": {
+                    "description": "Version\n@Description Grows with every change; send it back with an update to reject the update if the product changed meanwhile\n@Example 3",
+                    "type": "integer",
+                    "example": 3
                 }
             }
         },
@@ -969,10 +3349,46 @@ const docTemplate = `{
                 }
             }
         },
+        "ProductTagCount": {
+            "description": "Product tag with its product count",
+            "type": "object",
+            "properties": {
+                "count": {
+                    "description": "Count\n@Description Number of products carrying the tag\n@Example 12",
+                    "type": "integer",
+                    "example": 12
+                },
+                "tag": {
+                    "description": "Tag\n@Description Tag name\n@Example \"electronics\"",
+                    "type": "string",
+                    "example": "electronics"
+                }
+            }
+        },
+        "ProductTagsResponse": {
+            "description": "Distinct product tags, most used first",
+            "type": "object",
+            "properties": {
+                "tags": {
+                    "description": "Tags\n@Description Tags with their product counts",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ProductTagCount"
+                    }
+                }
+            }
+        },
         "ProductsResponse": {
             "description": "Paginated response containing list of products",
             "type": "object",
             "properties": {
+                "missing_ids": {
+                    "description": "Missing IDs\n@Description Requested IDs with no product, only set when fetching by ids",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "pagination": {
                     "description": "Pagination\n@Description Pagination information",
                     "allOf": [
@@ -990,6 +3406,177 @@ const docTemplate = `{
                 }
             }
         },
+        "ReadOnlyRequest": {
+            "description": "Request payload for switching read-only mode",
+            "type": "object",
+            "required": [
+                "enabled"
+            ],
+            "properties": {
+                "enabled": {
+                    "description": "Enabled\n@Description Whether writes should be rejected\n@Example true",
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "ReadOnlyStatus": {
+            "description": "Read-only mode of the instance",
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "description": "Enabled\n@Description Whether writes are rejected with 503\n@Example false",
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "RefreshRequest": {
+            "description": "Refresh token from the last login or refresh",
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "description": "Refresh token\n@Description Single-use refresh token\n@Example 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                }
+            }
+        },
+        "RestockProductRequest": {
+            "description": "Request payload for restocking a product",
+            "type": "object",
+            "required": [
+                "quantity"
+            ],
+            "properties": {
+                "quantity": {
+                    "description": "Quantity\n@Description Quantity to add to the stock (must be positive)\n@Example 50",
+                    "type": "integer",
+                    "example": 50
+                }
+            }
+        },
+        "SlowQueriesResponse": {
+            "description": "Slowest recent queries of this instance, slowest first",
+            "type": "object",
+            "properties": {
+                "log_size": {
+                    "description": "Log size\n@Description Number of recent slow queries the instance keeps, 0 when none are kept\n@Example 100",
+                    "type": "integer",
+                    "example": 100
+                },
+                "queries": {
+                    "description": "Queries\n@Description Slow queries, slowest first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/SlowQuery"
+                    }
+                }
+            }
+        },
+        "SlowQuery": {
+            "description": "Slow query of this instance",
+            "type": "object",
+            "properties": {
+                "args_count": {
+                    "description": "Args count\n@Description Number of query args, their values may hold personal data and are not kept\n@Example 1",
+                    "type": "integer",
+                    "example": 1
+                },
+                "duration_ms": {
+                    "description": "Duration\n@Description Milliseconds the query took\n@Example 812.5",
+                    "type": "number",
+                    "example": 812.5
+                },
+                "error": {
+                    "description": "Error (optional)\n@Description Error the query failed with, e.g. when it was canceled by the statement timeout\n@Example \"canceling statement due to statement timeout\"",
+                    "type": "string",
+                    "example": "canceling statement due to statement timeout"
+                },
+                "sql": {
+                    "description": "SQL\n@Description SQL text of the query\n@Example \"SELECT id FROM products WHERE description ILIKE $1\"",
+                    "type": "string",
+                    "example": "SELECT id FROM products WHERE description ILIKE $1"
+                },
+                "started_at": {
+                    "description": "Started at\n@Description When the query started\n@Example 2024-01-15T10:30:00Z",
+                    "type": "string",
+                    "example": "2024-01-15T10:30:00Z"
+                }
+            }
+        },
+        "StockShortage": {
+            "description": "Requested versus available quantity of a product",
+            "type": "object",
+            "properties": {
+                "available": {
+                    "description": "Available quantity\n@Description Quantity currently in stock\n@Example 2",
+                    "type": "integer",
+                    "example": 2
+                },
+                "product_id": {
+                    "description": "Product ID\n@Description Product unique identifier\n@Example \"123e4567-e89b-12d3-a456-426614174000\"",
+                    "type": "string",
+                    "example": "123e4567-e89b-12d3-a456-426614174000"
+                },
+                "requested": {
+                    "description": "Requested quantity\n@Description Quantity the order needs\n@Example 5",
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "TokenResponse": {
+            "description": "Access token to send as \"Authorization: Bearer \u003ctoken\u003e\" and the refresh token getting the next one",
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "description": "Access token\n@Description Signed JWT identifying the user\n@Example eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...",
+                    "type": "string",
+                    "example": "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."
+                },
+                "expires_at": {
+                    "description": "Expires at\n@Description When the access token stops being accepted\n@Example 2024-01-15T11:30:00Z",
+                    "type": "string",
+                    "example": "2024-01-15T11:30:00Z"
+                },
+                "refresh_expires_at": {
+                    "description": "Refresh token expires at\n@Description When the refresh token stops being accepted\n@Example 2024-02-14T10:30:00Z",
+                    "type": "string",
+                    "example": "2024-02-14T10:30:00Z"
+                },
+                "refresh_token": {
+                    "description": "Refresh token\n@Description Single-use token for POST /api/v1/auth/refresh, replaced by every refresh\n@Example 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                },
+                "token_type": {
+                    "description": "Token type\n@Description Always Bearer\n@Example Bearer",
+                    "type": "string",
+                    "example": "Bearer"
+                }
+            }
+        },
+        "UpdateOrderItemsRequest": {
+            "description": "Request payload for replacing order items",
+            "type": "object",
+            "required": [
+                "items"
+            ],
+            "properties": {
+                "items": {
+                    "description": "Items\n@Description New list of items for the order (at least one required); lines of the same product are merged into one item with the summed quantity",
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/CreateOrderItemRequest"
+                    }
+                }
+            }
+        },
         "UpdateOrderRequest": {
             "description": "Request payload for updating order status",
             "type": "object",
@@ -1019,6 +3606,12 @@ const docTemplate = `{
                     "type": "string",
                     "example": "Updated smartphone description"
                 },
+                "price": {
+                    "description": "Price\n@Description Price in minor currency units (optional)\n@Example 45990",
+                    "type": "integer",
+                    "minimum": 0,
+                    "example": 45990
+                },
                 "quantity": {
                     "description": "Quantity\n@Description Quantity in stock (optional)\n@Example 150",
                     "type": "integer",
@@ -1036,6 +3629,31 @@ const docTemplate = `{
                         "mobile",
                         "updated"
                     ]
+                },
+                "version": {
+                    "description": "Version\n@Description Version of the product the changes are based on (optional); a product changed since is not updated and 409 VERSION_CONFLICT is returned\n@Example 3",
+                    "type": "integer",
+                    "minimum": 1,
+                    "example": 3
+                }
+            }
+        },
+        "UpsertProductResponse": {
+            "description": "Product found or created by description",
+            "type": "object",
+            "properties": {
+                "created": {
+                    "description": "Created\n@Description Whether the product was created by this request\n@Example true",
+                    "type": "boolean",
+                    "example": true
+                },
+                "product": {
+                    "description": "Product\n@Description Existing or newly created product",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/Product"
+                        }
+                    ]
                 }
             }
         },
@@ -1044,7 +3662,7 @@ const docTemplate = `{
             "type": "object",
             "properties": {
                 "age": {
-                    "description": "Age\n@Description User's age (must be 18 or older)\n@Example 25",
+                    "description": "Age\n@Description User's age\n@Example 25",
                     "type": "integer",
                     "example": 25
                 },
@@ -1053,6 +3671,16 @@ const docTemplate = `{
                     "type": "string",
                     "example": "2024-01-15T10:30:00Z"
                 },
+                "email": {
+                    "description": "Email\n@Description User's email address, omitted for users registered before emails were collected\n@Example john.doe@example.com",
+                    "type": "string",
+                    "example": "john.doe@example.com"
+                },
+                "email_verified": {
+                    "description": "Email verified\n@Description Whether the user confirmed their email address\n@Example true",
+                    "type": "boolean",
+                    "example": true
+                },
                 "first_name": {
                     "description": "First name\n@Description User's first name\n@Example John",
                     "type": "string",
@@ -1077,6 +3705,16 @@ const docTemplate = `{
                     "description": "Last name\n@Description User's last name\n@Example Doe",
                     "type": "string",
                     "example": "Doe"
+                },
+                "roles": {
+                    "description": "Roles\n@Description Roles granting access to endpoints, admin is needed for admin-only ones\n@Example [\"user\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "user"
+                    ]
                 }
             }
         },
@@ -1102,6 +3740,20 @@ const docTemplate = `{
             }
         }
     },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "Admin token as \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "Access token from /api/v1/auth/login as \"Bearer \u003ctoken\u003e\"",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
+    },
     "tags": [
         {
             "description": "User registration and management",
@@ -1114,6 +3766,22 @@ const docTemplate = `{
         {
             "description": "Order management with stock control",
             "name": "Orders"
+        },
+        {
+            "description": "Access tokens of users",
+            "name": "Auth"
+        },
+        {
+            "description": "Operational switches",
+            "name": "Admin"
+        },
+        {
+            "description": "Probes for orchestrators and load balancers",
+            "name": "Health"
+        },
+        {
+            "description": "Performance diagnostics, served when service.debug is on",
+            "name": "Debug"
         }
     ]
 }`
@@ -0,0 +1,160 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+	"github.com/google/uuid"
+
+	"mts/internal/domain"
+	"shared"
+)
+
+// StatusClientClosedRequest is the non-standard status used when the client went away
+const StatusClientClosedRequest = 499
+
+// errorHandler renders every error returned by handlers as an ErrorResponse.
+// Handlers return fiber errors for expected failures and pass unclassified ones through.
+func errorHandler(c fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	var fields map[string]string
+	var shortages []*StockShortage
+
+	var fiberErr *fiber.Error
+	var statusErr *statusError
+	var validationErr *domain.ValidationError
+	var stockErr *domain.InsufficientStockError
+	switch {
+	case errors.As(err, &validationErr):
+		status = fiber.StatusBadRequest
+		// conflicts can be reported field by field too, withStatus then sets their status
+		if errors.As(err, &statusErr) {
+			status = statusErr.status
+		}
+		fields = validationErr.Fields
+	case errors.As(err, &stockErr):
+		status = fiber.StatusBadRequest
+		shortages = NewStockShortages(stockErr.Shortages)
+	case errors.As(err, &statusErr):
+		status = statusErr.status
+	case errors.As(err, &fiberErr):
+		status = fiberErr.Code
+	case errors.Is(err, domain.ErrRequestCanceled):
+		status = StatusClientClosedRequest
+	case errors.Is(err, domain.ErrRequestTimeout):
+		status = fiber.StatusGatewayTimeout
+	case errors.Is(err, domain.ErrReadOnly):
+		status = fiber.StatusServiceUnavailable
+	}
+
+	code := domain.ErrorCode(err)
+
+	if status >= fiber.StatusInternalServerError {
+		shared.Logger.Error().
+			Err(err).
+			Str("code", code).
+			Str("request_id", requestid.FromContext(c)).
+			Str("method", c.Method()).
+			Str("path", c.Path()).
+			Int("status", status).
+			Msg("request failed")
+	}
+
+	return c.Status(status).JSON(ErrorResponse{
+		Message:   err.Error(),
+		Code:      code,
+		Fields:    fields,
+		Shortages: shortages,
+	})
+}
+
+// badRequest keeps structured details (field errors, stock shortages) for errorHandler to render,
+// other errors become plain 400s
+func badRequest(err error) error {
+	var validationErr *domain.ValidationError
+	var stockErr *domain.InsufficientStockError
+	if errors.As(err, &validationErr) || errors.As(err, &stockErr) {
+		return err
+	}
+	return withStatus(fiber.StatusBadRequest, err)
+}
+
+// bindJSON parses the request body into out and checks its validate tags. Parser errors are logged and
+// replaced by domain.ErrInvalidJSON, so decoder details such as offsets never reach clients
+func bindJSON(c fiber.Ctx, out any) error {
+	err := c.Bind().JSON(out)
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		return err
+	}
+
+	shared.Logger.Warn().
+		Err(err).
+		Str("method", c.Method()).
+		Str("path", c.Path()).
+		Msg("malformed JSON body")
+
+	return withStatus(fiber.StatusBadRequest, domain.ErrInvalidJSON)
+}
+
+// parseUUIDParam parses the named path parameter, rejecting malformed ids with a 400 INVALID_ID
+func parseUUIDParam(c fiber.Ctx, name string) (uuid.UUID, error) {
+	return parseUUID(name, c.Params(name))
+}
+
+func parseUUID(name, value string) (uuid.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, withStatus(fiber.StatusBadRequest, fmt.Errorf("%w: %s must be a UUID", domain.ErrInvalidId, name))
+	}
+	return id, nil
+}
+
+// parseUUIDList parses a comma-separated list of ids, dropping duplicates; more than limit distinct ids are rejected
+func parseUUIDList(name, value string, limit int) ([]uuid.UUID, error) {
+	parts := strings.Split(value, ",")
+	ids := make([]uuid.UUID, 0, len(parts))
+	seen := make(map[uuid.UUID]struct{}, len(parts))
+	for _, part := range parts {
+		id, err := parseUUID(name, strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+
+	if len(ids) > limit {
+		return nil, withStatus(fiber.StatusBadRequest, fmt.Errorf("%w: %s can hold at most %d IDs", domain.ErrInvalidId, name, limit))
+	}
+	return ids, nil
+}
+
+// statusError sets the response status of an error while keeping it in the chain, unlike fiber.Error,
+// so errorHandler still reports its domain code
+type statusError struct {
+	status int
+	err    error
+}
+
+func withStatus(status int, err error) error {
+	return &statusError{status: status, err: err}
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
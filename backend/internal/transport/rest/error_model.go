@@ -1,5 +1,11 @@
 package rest
 
+import (
+	"github.com/google/uuid"
+
+	"mts/internal/domain"
+)
+
 // ErrorResponse represents an error in API responses
 // @Description Error response format
 type ErrorResponse struct {
@@ -9,7 +15,46 @@ type ErrorResponse struct {
 	Message string `json:"message" example:"Validation failed"`
 
 	// Error code (optional)
-	// @Description Machine-readable error code
-	// @Example "INVALID_INPUT"
-	Code string `json:"code,omitempty" example:"INVALID_INPUT"`
+	// @Description Stable machine-readable error code, e.g. USER_NOT_FOUND or INSUFFICIENT_STOCK
+	// @Example "USER_NOT_FOUND"
+	Code string `json:"code,omitempty" example:"USER_NOT_FOUND"`
+
+	// Field errors (optional)
+	// @Description Problems with individual request fields, keyed by field name
+	Fields map[string]string `json:"fields,omitempty" example:"first_name:first name is required,age:user must be at least 18 years old"`
+
+	// Stock shortages (optional)
+	// @Description Products that can't cover the requested quantity
+	Shortages []*StockShortage `json:"shortages,omitempty"`
 } // @name ErrorResponse
+
+// StockShortage represents a product short of stock
+// @Description Requested versus available quantity of a product
+type StockShortage struct {
+	// Product ID
+	// @Description Product unique identifier
+	// @Example "123e4567-e89b-12d3-a456-426614174000"
+	ProductId uuid.UUID `json:"product_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+
+	// Requested quantity
+	// @Description Quantity the order needs
+	// @Example 5
+	Requested int `json:"requested" example:"5"`
+
+	// Available quantity
+	// @Description Quantity currently in stock
+	// @Example 2
+	Available int `json:"available" example:"2"`
+} // @name StockShortage
+
+func NewStockShortages(domainShortages []domain.StockShortage) []*StockShortage {
+	shortages := make([]*StockShortage, 0, len(domainShortages))
+	for _, shortage := range domainShortages {
+		shortages = append(shortages, &StockShortage{
+			ProductId: shortage.ProductId,
+			Requested: shortage.Requested,
+			Available: shortage.Available,
+		})
+	}
+	return shortages
+}
@@ -0,0 +1,331 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/application"
+	"mts/internal/config"
+	"mts/internal/domain"
+	"mts/internal/repository/cache"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "fiber error keeps its status",
+			err:            fiber.NewError(fiber.StatusNotFound, "user not found"),
+			expectedStatus: fiber.StatusNotFound,
+		},
+		{
+			name:           "domain error with a status keeps its code",
+			err:            withStatus(fiber.StatusNotFound, fmt.Errorf("%w: %s", domain.ErrUserNotFound, uuid.Nil)),
+			expectedStatus: fiber.StatusNotFound,
+			expectedCode:   "USER_NOT_FOUND",
+		},
+		{
+			name:           "stock shortage",
+			err:            badRequest(domain.ErrInsufficientStock),
+			expectedStatus: fiber.StatusBadRequest,
+			expectedCode:   "INSUFFICIENT_STOCK",
+		},
+		{
+			name:           "canceled request",
+			err:            fmt.Errorf("%w: context canceled", domain.ErrRequestCanceled),
+			expectedStatus: StatusClientClosedRequest,
+			expectedCode:   "REQUEST_CANCELED",
+		},
+		{
+			name:           "timed out request",
+			err:            fmt.Errorf("%w: context deadline exceeded", domain.ErrRequestTimeout),
+			expectedStatus: fiber.StatusGatewayTimeout,
+			expectedCode:   "REQUEST_TIMEOUT",
+		},
+		{
+			name:           "unclassified error",
+			err:            errors.New("boom"),
+			expectedStatus: fiber.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+			app.Get("/", func(c fiber.Ctx) error {
+				return tt.err
+			})
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			var errResp ErrorResponse
+			require.NoError(t, json.Unmarshal(body, &errResp))
+			assert.Equal(t, tt.err.Error(), errResp.Message)
+			assert.Equal(t, tt.expectedCode, errResp.Code)
+		})
+	}
+}
+
+func TestErrorHandler_ValidationFields(t *testing.T) {
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil,
+		nil, application.NewUserAppService(nil, nil, ""), application.NewProductAppService(nil, nil), nil, nil)
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedFields []string
+	}{
+		{
+			name:           "user failing several domain rules",
+			path:           "/api/v1/users",
+			body:           `{"first_name": " ", "last_name": " ", "age": 16, "email": "john@example.com", "password": "short"}`,
+			expectedFields: []string{"first_name", "last_name", "age", "password"},
+		},
+		{
+			name:           "user missing several fields",
+			path:           "/api/v1/users",
+			body:           `{"age": 16, "password": "short"}`,
+			expectedFields: []string{"first_name", "last_name", "email"},
+		},
+		{
+			name:           "product missing description with negative quantity",
+			path:           "/api/v1/products",
+			body:           `{"quantity": -1}`,
+			expectedFields: []string{"description", "quantity"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+			var errResp ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			assert.Len(t, errResp.Fields, len(tt.expectedFields))
+			for _, field := range tt.expectedFields {
+				assert.NotEmpty(t, errResp.Fields[field], field)
+				assert.Contains(t, errResp.Message, errResp.Fields[field])
+			}
+		})
+	}
+}
+
+func TestBindJSON_MalformedBodies(t *testing.T) {
+	// no application services: a malformed body must be rejected before any of them is needed
+	app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, nil, nil)
+	productPath := "/api/v1/products/" + uuid.NewString()
+	orderPath := "/api/v1/orders/" + uuid.NewString()
+
+	routes := []struct {
+		method     string
+		path       string
+		mismatched string
+	}{
+		{fiber.MethodPost, "/api/v1/users", `{"first_name": "John", "age": "twenty"}`},
+		{fiber.MethodPost, "/api/v1/products", `{"description": "Phone", "price": "cheap"}`},
+		{fiber.MethodPut, productPath, `{"price": "cheap"}`},
+		{fiber.MethodPost, productPath + "/restock", `{"quantity": "ten"}`},
+		{fiber.MethodPost, "/api/v1/orders", `{"user_id": "` + uuid.NewString() + `", "items": {"quantity": 1}}`},
+		{fiber.MethodPut, orderPath, `{"status": 1}`},
+		{fiber.MethodPut, orderPath + "/items", `{"items": "none"}`},
+		{fiber.MethodPost, "/api/v1/orders/bulk-status", `{"ids": "all", "status": "confirmed"}`},
+	}
+
+	for _, route := range routes {
+		for name, body := range map[string]string{
+			"truncated":  `{"quantity": 1, "description": "Pho`,
+			"mismatched": route.mismatched,
+		} {
+			t.Run(route.method+" "+route.path+" "+name, func(t *testing.T) {
+				req := httptest.NewRequest(route.method, route.path, strings.NewReader(body))
+				req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+				req.Header.Set(fiber.HeaderAuthorization, "Bearer secret")
+
+				resp, err := app.Test(req)
+				require.NoError(t, err)
+				assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+				var errResp ErrorResponse
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+				assert.Equal(t, ErrorResponse{
+					Message: domain.ErrInvalidJSON.Error(),
+					Code:    "INVALID_JSON",
+				}, errResp)
+			})
+		}
+	}
+}
+
+func TestParseUUIDParam_InvalidIds(t *testing.T) {
+	// no application services: a malformed id must be rejected before any of them is needed
+	app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, nil, nil)
+
+	routes := []struct {
+		method string
+		path   string
+		param  string
+	}{
+		{fiber.MethodGet, "/api/v1/users/42", "user_id"},
+		{fiber.MethodDelete, "/api/v1/users/42", "user_id"},
+		{fiber.MethodGet, "/api/v1/users/42/orders", "user_id"},
+		{fiber.MethodGet, "/api/v1/products/42", "product_id"},
+		{fiber.MethodPut, "/api/v1/products/42", "product_id"},
+		{fiber.MethodDelete, "/api/v1/products/42", "product_id"},
+		{fiber.MethodPost, "/api/v1/products/42/restock", "product_id"},
+		{fiber.MethodGet, "/api/v1/orders/42", "order_id"},
+		{fiber.MethodGet, "/api/v1/orders/42/history", "order_id"},
+		{fiber.MethodGet, "/api/v1/orders/42/invoice", "order_id"},
+		{fiber.MethodPut, "/api/v1/orders/42", "order_id"},
+		{fiber.MethodDelete, "/api/v1/orders/42", "order_id"},
+		{fiber.MethodPut, "/api/v1/orders/42/items", "order_id"},
+		{fiber.MethodPost, "/api/v1/orders/42/cancel", "order_id"},
+		{fiber.MethodGet, "/api/v1/orders?user_id=42", "user_id"},
+		{fiber.MethodGet, "/api/v1/orders?product_id=42", "product_id"},
+	}
+
+	for _, route := range routes {
+		t.Run(route.method+" "+route.path, func(t *testing.T) {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer secret")
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+			var errResp ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			assert.Equal(t, ErrorResponse{
+				Message: "invalid id: " + route.param + " must be a UUID",
+				Code:    "INVALID_ID",
+			}, errResp)
+		})
+	}
+}
+
+func TestParseUUIDParam(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Get("/:id", func(c fiber.Ctx) error {
+		id, err := parseUUIDParam(c, "id")
+		if err != nil {
+			return err
+		}
+		return c.SendString(id.String())
+	})
+
+	id := uuid.New()
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/"+id.String(), nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, id.String(), string(body))
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/not-a-uuid", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestErrorHandler_PlainValidationError(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Get("/", func(c fiber.Ctx) error {
+		return badRequest(fmt.Errorf("%w: only pending orders can change their items", domain.ErrOrderValidation))
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Empty(t, errResp.Fields)
+}
+
+func TestErrorHandler_StockShortages(t *testing.T) {
+	stockErr := &domain.InsufficientStockError{}
+	stockErr.Add(uuid.New(), 3, 1)
+	stockErr.Add(uuid.New(), 2, 0)
+
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Post("/", func(c fiber.Ctx) error {
+		return badRequest(fmt.Errorf("create order: %w", stockErr))
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	require.Len(t, errResp.Shortages, 2)
+	for i, shortage := range stockErr.Shortages {
+		assert.Equal(t, shortage.ProductId, errResp.Shortages[i].ProductId)
+		assert.Equal(t, shortage.Requested, errResp.Shortages[i].Requested)
+		assert.Equal(t, shortage.Available, errResp.Shortages[i].Available)
+	}
+}
+
+func TestTimeoutMiddleware(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Use(timeoutMiddleware(10 * time.Millisecond))
+	app.Get("/", func(c fiber.Ctx) error {
+		_, ok := c.Context().Deadline()
+		assert.True(t, ok)
+
+		<-c.Context().Done()
+		return fmt.Errorf("%w: %w", domain.ErrRequestTimeout, c.Context().Err())
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusGatewayTimeout, resp.StatusCode)
+}
+
+func TestBodyLimit(t *testing.T) {
+	app := New(&config.Service{BodyLimit: 1024}, cache.NewMemoryCache(), nil, nil, nil,
+		nil, nil, application.NewProductAppService(nil, nil), nil, nil)
+
+	// the limit is enforced while fasthttp reads the request, which app.Test reports as an error
+	// instead of the response, so serve over a real listener
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(ln, fiber.ListenConfig{DisableStartupMessage: true}) }()
+	defer func() { _ = app.Shutdown() }()
+
+	body := `{"description": "` + strings.Repeat("a", 2048) + `", "quantity": 1}`
+	resp, err := http.Post("http://"+ln.Addr().String()+"/api/v1/products", fiber.MIMEApplicationJSON, strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, fiber.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, "Request Entity Too Large", errResp.Message)
+}
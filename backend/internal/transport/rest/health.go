@@ -0,0 +1,19 @@
+package rest
+
+import (
+	"github.com/gofiber/fiber/v3"
+)
+
+// healthPath is served even when the concurrency limit is reached, so probes do not restart a busy instance
+const healthPath = "/health"
+
+// health reports that the process serves requests
+// @Summary Liveness probe
+// @Description Report that the instance is up. Dependencies are not checked and the concurrency limit does not apply
+// @Tags Health
+// @Produce json
+// @Success 200 {object} HealthStatus "The instance is up"
+// @Router /health [get]
+func health(c fiber.Ctx) error {
+	return c.JSON(&HealthStatus{Status: "ok"})
+}
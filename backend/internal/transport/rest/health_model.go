@@ -0,0 +1,10 @@
+package rest
+
+// HealthStatus represents the state reported by a probe
+// @Description State of the instance
+type HealthStatus struct {
+	// Status
+	// @Description "ok" for liveness; "ready" or the startup step, e.g. "migrating", for readiness
+	// @Example "ok"
+	Status string `json:"status" example:"ok"`
+} // @name HealthStatus
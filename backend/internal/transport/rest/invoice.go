@@ -0,0 +1,73 @@
+package rest
+
+import (
+	"strconv"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"mts/internal/domain"
+)
+
+const (
+	// invoiceItemWidth and invoiceQuantityWidth split the 180mm between the A4 margins
+	invoiceItemWidth     = 140.0
+	invoiceQuantityWidth = 40.0
+	invoiceFont          = "Helvetica"
+)
+
+// newInvoice lays out a one-page invoice of the order. Rendering errors are kept in the document,
+// see gofpdf.Fpdf.Error, so the caller can check them before anything is sent.
+// Snapshots carry no prices yet, so the totals are item quantities.
+func newInvoice(order *domain.Order) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Invoice "+order.Id.String(), true)
+	// the core fonts only cover Windows-1252, other characters of the descriptions are dropped
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.AddPage()
+	pdf.SetFont(invoiceFont, "B", 18)
+	pdf.Cell(0, 10, "Invoice")
+	pdf.Ln(14)
+
+	pdf.SetFont(invoiceFont, "", 11)
+	for _, line := range []string{
+		"Order: " + order.Id.String(),
+		"Date: " + order.CreatedAt.UTC().Format("2006-01-02"),
+		"Status: " + string(order.Status),
+	} {
+		pdf.Cell(0, 6, line)
+		pdf.Ln(6)
+	}
+	pdf.Ln(6)
+
+	pdf.SetFont(invoiceFont, "B", 11)
+	pdf.CellFormat(invoiceItemWidth, 8, "Item", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(invoiceQuantityWidth, 8, "Quantity", "1", 1, "R", false, 0, "")
+
+	pdf.SetFont(invoiceFont, "", 11)
+	for _, item := range order.Items {
+		description := fitInvoiceText(pdf, translate(item.ProductSnapshot.Description), invoiceItemWidth-2*pdf.GetCellMargin())
+		pdf.CellFormat(invoiceItemWidth, 7, description, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(invoiceQuantityWidth, 7, strconv.Itoa(item.Quantity), "1", 1, "R", false, 0, "")
+	}
+
+	pdf.SetFont(invoiceFont, "B", 11)
+	pdf.CellFormat(invoiceItemWidth, 8, "Total quantity", "1", 0, "L", false, 0, "")
+	pdf.CellFormat(invoiceQuantityWidth, 8, strconv.Itoa(order.ItemsQuantity()), "1", 1, "R", false, 0, "")
+
+	return pdf
+}
+
+// fitInvoiceText shortens s with an ellipsis until it fits width in the current font.
+// s is already translated to the single-byte font encoding, so it is cut by bytes.
+func fitInvoiceText(pdf *gofpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+
+	for len(s) > 0 && pdf.GetStringWidth(s+"...") > width {
+		s = s[:len(s)-1]
+	}
+
+	return s + "..."
+}
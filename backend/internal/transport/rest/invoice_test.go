@@ -0,0 +1,38 @@
+package rest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/domain"
+)
+
+func TestNewInvoice(t *testing.T) {
+	order := (&domain.Factory{}).Order(uuid.New(), uuid.New(), uuid.New())
+	order.Items[0].ProductSnapshot.Description = "Café grinder"
+	order.Items[0].Quantity = 2
+	order.Items[1].ProductSnapshot.Description = strings.Repeat("Very long description ", 20)
+
+	invoice := newInvoice(order)
+	require.NoError(t, invoice.Error())
+
+	// uncompressed, the page content shows the text as drawn
+	invoice.SetCompression(false)
+	var buf bytes.Buffer
+	require.NoError(t, invoice.Output(&buf))
+	content := buf.String()
+
+	assert.Contains(t, content, "Order: "+order.Id.String())
+	assert.Contains(t, content, "Date: "+order.CreatedAt.UTC().Format("2006-01-02"))
+	// the description is drawn in the font's Windows-1252 encoding
+	assert.Contains(t, content, "Caf\xe9 grinder")
+	assert.Contains(t, content, "Very long description Very long description")
+	assert.NotContains(t, content, strings.Repeat("Very long description ", 20))
+	assert.Contains(t, content, "(Total quantity)")
+	assert.Contains(t, content, "(3)")
+}
@@ -0,0 +1,87 @@
+package rest
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"mts/internal/config"
+	"shared"
+)
+
+const defaultMaintenanceRetryAfter = time.Minute
+
+// maintenanceExempt are the route groups that stay writable in maintenance mode: admins switch it and
+// read-only mode off under /admin and log in under /auth to get the token for it
+var maintenanceExempt = []string{"/api/v1/admin", "/api/v1/auth"}
+
+// maintenanceMode is switched by admins while requests are being served
+type maintenanceMode struct {
+	enabled    atomic.Bool
+	retryAfter time.Duration
+}
+
+func newMaintenanceMode(cfg config.Maintenance) *maintenanceMode {
+	mode := &maintenanceMode{retryAfter: cfg.RetryAfter}
+	if mode.retryAfter <= 0 {
+		mode.retryAfter = defaultMaintenanceRetryAfter
+	}
+	mode.enabled.Store(cfg.Enabled)
+
+	return mode
+}
+
+type maintenanceHandler struct {
+	mode *maintenanceMode
+}
+
+func newMaintenanceHandler(mode *maintenanceMode) *maintenanceHandler {
+	return &maintenanceHandler{
+		mode: mode,
+	}
+}
+
+// getMaintenance reports whether maintenance mode is on
+// @Summary Get maintenance mode
+// @Description Report whether mutating endpoints are currently rejected with 503 (admin only)
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Security BearerAuth
+// @Success 200 {object} MaintenanceStatus "Current maintenance mode"
+// @Failure 401 {object} ErrorResponse "Unauthorized - admin token or access token is missing"
+// @Failure 403 {object} ErrorResponse "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role"
+// @Router /api/v1/admin/maintenance [get]
+func (h *maintenanceHandler) getMaintenance(c fiber.Ctx) error {
+	return c.JSON(NewMaintenanceStatus(h.mode))
+}
+
+// setMaintenance switches maintenance mode on or off
+// @Summary Switch maintenance mode
+// @Description Turn maintenance mode on or off for this instance (admin only). While it is on, POST, PUT, PATCH and DELETE requests are answered with 503 and a Retry-After header, reads keep working
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Security BearerAuth
+// @Param request body MaintenanceRequest true "Desired maintenance mode"
+// @Success 200 {object} MaintenanceStatus "Maintenance mode after the switch"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid JSON or missing enabled flag"
+// @Failure 401 {object} ErrorResponse "Unauthorized - admin token or access token is missing"
+// @Failure 403 {object} ErrorResponse "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role"
+// @Router /api/v1/admin/maintenance [put]
+func (h *maintenanceHandler) setMaintenance(c fiber.Ctx) error {
+	var req MaintenanceRequest
+	if err := bindJSON(c, &req); err != nil {
+		return err
+	}
+
+	if previous := h.mode.enabled.Swap(*req.Enabled); previous != *req.Enabled {
+		shared.Logger.Warn().
+			Bool("enabled", *req.Enabled).
+			Msg("maintenance mode switched")
+	}
+
+	return c.JSON(NewMaintenanceStatus(h.mode))
+}
@@ -0,0 +1,31 @@
+package rest
+
+// MaintenanceRequest represents request to switch maintenance mode
+// @Description Request payload for switching maintenance mode
+type MaintenanceRequest struct {
+	// Enabled
+	// @Description Whether mutating endpoints should be rejected
+	// @Example true
+	Enabled *bool `json:"enabled" binding:"required" validate:"required" example:"true"`
+} // @name MaintenanceRequest
+
+// MaintenanceStatus represents the current maintenance mode
+// @Description Maintenance mode of the instance
+type MaintenanceStatus struct {
+	// Enabled
+	// @Description Whether mutating endpoints are rejected with 503
+	// @Example false
+	Enabled bool `json:"enabled" example:"false"`
+
+	// Retry after
+	// @Description Seconds rejected clients are asked to wait before retrying
+	// @Example 60
+	RetryAfter int `json:"retry_after" example:"60"`
+} // @name MaintenanceStatus
+
+func NewMaintenanceStatus(mode *maintenanceMode) *MaintenanceStatus {
+	return &MaintenanceStatus{
+		Enabled:    mode.enabled.Load(),
+		RetryAfter: retryAfterSeconds(mode.retryAfter),
+	}
+}
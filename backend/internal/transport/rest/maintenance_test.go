@@ -0,0 +1,129 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/config"
+	"mts/internal/domain"
+	"mts/internal/repository/cache"
+)
+
+const maintenancePath = "/api/v1/admin/maintenance"
+
+func TestMaintenanceMode(t *testing.T) {
+	orderAppService := new(mockOrderAppService)
+	orderAppService.On("StatusCounts", mock.Anything, mock.Anything).Return(domain.NewOrderStatusStatsMap(), nil)
+
+	app := New(&config.Service{
+		AdminToken:  "secret",
+		Maintenance: config.Maintenance{RetryAfter: 90 * time.Second},
+	}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, orderAppService, nil)
+
+	send := func(method, path, body string) *http.Response {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer secret")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+	setMaintenance := func(enabled string) MaintenanceStatus {
+		resp := send(fiber.MethodPut, maintenancePath, `{"enabled": `+enabled+`}`)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var status MaintenanceStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		return status
+	}
+	// the body is invalid, so a write that gets past maintenance mode fails validation before reaching the service
+	createOrder := func() *http.Response {
+		return send(fiber.MethodPost, "/api/v1/orders", `{}`)
+	}
+
+	assert.Equal(t, fiber.StatusBadRequest, createOrder().StatusCode)
+
+	assert.Equal(t, MaintenanceStatus{Enabled: true, RetryAfter: 90}, setMaintenance("true"))
+
+	blocked := createOrder()
+	assert.Equal(t, fiber.StatusServiceUnavailable, blocked.StatusCode)
+	assert.Equal(t, "90", blocked.Header.Get(fiber.HeaderRetryAfter))
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(blocked.Body).Decode(&errResp))
+	assert.Equal(t, "MAINTENANCE", errResp.Code)
+
+	assert.Equal(t, fiber.StatusServiceUnavailable, send(fiber.MethodDelete, "/api/v1/orders/"+uuid.New().String(), "").StatusCode)
+
+	// reads keep working
+	assert.Equal(t, fiber.StatusOK, send(fiber.MethodGet, "/api/v1/orders/stats", "").StatusCode)
+
+	// admins still switch the other modes and log in; the invalid login body is rejected past maintenance mode
+	assert.Equal(t, fiber.StatusOK, send(fiber.MethodPut, "/api/v1/admin/read-only", `{"enabled": false}`).StatusCode)
+	assert.Equal(t, fiber.StatusBadRequest, send(fiber.MethodPost, "/api/v1/auth/login", `{}`).StatusCode)
+
+	var status MaintenanceStatus
+	resp := send(fiber.MethodGet, maintenancePath, "")
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	assert.True(t, status.Enabled)
+
+	// the route is served with a trailing slash too, so the exemption has to cover it
+	resp = send(fiber.MethodPut, maintenancePath+"/", `{"enabled": false}`)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, fiber.StatusBadRequest, createOrder().StatusCode)
+
+	assert.Equal(t, MaintenanceStatus{Enabled: true, RetryAfter: 90}, setMaintenance("true"))
+	assert.Equal(t, MaintenanceStatus{Enabled: false, RetryAfter: 90}, setMaintenance("false"))
+	assert.Equal(t, fiber.StatusBadRequest, createOrder().StatusCode)
+
+	orderAppService.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+	orderAppService.AssertNotCalled(t, "DeleteOrder", mock.Anything, mock.Anything)
+}
+
+func TestMaintenanceMode_EnabledFromConfig(t *testing.T) {
+	orderAppService := new(mockOrderAppService)
+	orderAppService.On("StatusCounts", mock.Anything, mock.Anything).Return(domain.NewOrderStatusStatsMap(), nil)
+
+	app := New(&config.Service{
+		AdminToken:  "secret",
+		Maintenance: config.Maintenance{Enabled: true},
+	}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, orderAppService, nil)
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/orders", strings.NewReader(`{}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "60", resp.Header.Get(fiber.HeaderRetryAfter))
+
+	req = httptest.NewRequest(fiber.MethodGet, "/api/v1/orders/stats", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer secret")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func TestMaintenanceMode_RequiresAdminToken(t *testing.T) {
+	app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, new(mockOrderAppService), nil)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPut, maintenancePath, strings.NewReader(`{"enabled": true}`)))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+
+	req := httptest.NewRequest(fiber.MethodPut, maintenancePath, strings.NewReader(`{}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer secret")
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,524 @@
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/cors"
+	"github.com/gofiber/fiber/v3/middleware/etag"
+	"github.com/gofiber/fiber/v3/middleware/requestid"
+	"github.com/google/uuid"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"mts/internal/config"
+	"mts/internal/domain"
+	"shared"
+)
+
+const (
+	// localUserId is the fiber.Ctx local holding the authenticated user's id
+	localUserId = "user_id"
+	// localAccessClaims is the fiber.Ctx local holding the verified *domain.AccessClaims of the request
+	localAccessClaims = "access_claims"
+	// localClientIp is the fiber.Ctx local holding the client address resolved by clientIpMiddleware
+	localClientIp = "client_ip"
+	// localConcurrencySlot is the fiber.Ctx local holding the *concurrencySlot of the request
+	localConcurrencySlot = "concurrency_slot"
+)
+
+var tracer = otel.Tracer("mts/internal/transport/rest")
+
+// clientIpMiddleware resolves the client address once per request, see clientIp
+func clientIpMiddleware(proxy config.Proxy) fiber.Handler {
+	trusted := proxy.TrustedPrefixes()
+	header := proxy.ClientIpHeader()
+
+	return func(c fiber.Ctx) error {
+		c.Locals(localClientIp, resolveClientIp(c.IP(), c.Get(header), trusted))
+		return c.Next()
+	}
+}
+
+// clientIp is the address rate limits and logs use for the client of the request
+func clientIp(c fiber.Ctx) string {
+	if ip, ok := c.Locals(localClientIp).(string); ok {
+		return ip
+	}
+	return c.IP()
+}
+
+// resolveClientIp walks the comma-separated addresses of the proxy header from the right, starting at remote,
+// the connection's address. Each trusted proxy appended the address it got the request from, so the first
+// address that is not a trusted proxy is the client; the ones left of it are whatever the client sent.
+// A malformed entry stops the walk at the trusted proxy that passed it on.
+func resolveClientIp(remote, header string, trusted []netip.Prefix) string {
+	isTrusted := func(ip string) (netip.Addr, bool) {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = addr.Unmap()
+		return addr, slices.ContainsFunc(trusted, func(prefix netip.Prefix) bool { return prefix.Contains(addr) })
+	}
+
+	client := remote
+	if _, ok := isTrusted(remote); !ok || header == "" {
+		return client
+	}
+
+	hops := strings.Split(header, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := isTrusted(strings.TrimSpace(hops[i]))
+		if !addr.IsValid() {
+			return client
+		}
+		client = addr.String()
+		if !ok {
+			return client
+		}
+	}
+
+	return client
+}
+
+// tracingMiddleware starts a server span per request, continuing the caller's trace when propagated
+func tracingMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.Context(), propagation.HeaderCarrier(c.GetReqHeaders()))
+		ctx, span := tracer.Start(ctx, c.Method()+" "+c.Path(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Method()),
+				attribute.String("url.path", c.Path()),
+			),
+		)
+		defer span.End()
+
+		c.SetContext(ctx)
+		err := c.Next()
+
+		span.SetName(c.Method() + " " + c.Route().Path)
+		span.SetAttributes(attribute.String("http.route", c.Route().Path))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+
+		span.SetAttributes(attribute.Int("http.response.status_code", c.Response().StatusCode()))
+		return nil
+	}
+}
+
+// recoverMiddleware turns a panicking handler into a 500 and logs the panic with its stack and request ID.
+// The panic value is not echoed to the client, it may hold internals.
+func recoverMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) (err error) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			// deferred calls run on top of the panicking frames, so the stack recorded here reaches the handler
+			stackErr := pkgerrors.WithStack(fmt.Errorf("panic: %v", recovered))
+			shared.Logger.Error().
+				Stack().
+				Err(stackErr).
+				Str("request_id", requestid.FromContext(c)).
+				Str("method", c.Method()).
+				Str("path", c.Path()).
+				Msg("handler panicked")
+
+			err = c.Status(fiber.StatusInternalServerError).JSON(ErrorResponse{
+				Message: domain.ErrInternal.Error(),
+				Code:    domain.ErrorCode(domain.ErrInternal),
+			})
+		}()
+
+		return c.Next()
+	}
+}
+
+// adminMiddleware admits users with the admin role and requests bearing the configured admin token, which is
+// meant for operators and tooling; with no token configured only admin users get through
+func adminMiddleware(token string) fiber.Handler {
+	requireAdmin := requireRoleMiddleware(domain.RoleAdmin)
+
+	return func(c fiber.Ctx) error {
+		if _, ok := c.Locals(localAccessClaims).(*domain.AccessClaims); ok {
+			return requireAdmin(c)
+		}
+
+		if token == "" {
+			return fiber.NewError(fiber.StatusForbidden, "admin endpoints are disabled")
+		}
+
+		bearer, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || bearer == "" {
+			return fiber.NewError(fiber.StatusUnauthorized, "admin token is required")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			return fiber.NewError(fiber.StatusForbidden, "invalid admin token")
+		}
+
+		return c.Next()
+	}
+}
+
+// authMiddleware identifies the user of a request bearing a valid access token. Requests without one stay
+// anonymous rather than rejected, the endpoints needing a user require it with requireUserMiddleware; the
+// header may also carry the admin token, which is no access token. When revoked tokens can't be told apart,
+// the request is answered 503 instead.
+func authMiddleware(auth domain.AuthAppService) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		bearer, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || bearer == "" || auth == nil {
+			return c.Next()
+		}
+
+		claims, err := auth.Authenticate(c.Context(), bearer)
+		if errors.Is(err, domain.ErrAuthUnavailable) {
+			return withStatus(fiber.StatusServiceUnavailable, err)
+		}
+		if err != nil {
+			zerolog.Ctx(c.Context()).Debug().Err(err).Msg("bearer token is not a valid access token")
+			return c.Next()
+		}
+
+		c.Locals(localUserId, claims.UserId)
+		c.Locals(localAccessClaims, claims)
+		return c.Next()
+	}
+}
+
+// requireUserMiddleware answers 401 to requests authMiddleware left anonymous
+func requireUserMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if _, ok := c.Locals(localUserId).(uuid.UUID); !ok {
+			c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+			return withStatus(fiber.StatusUnauthorized, domain.ErrUnauthenticated)
+		}
+		return c.Next()
+	}
+}
+
+// requireRoleMiddleware answers 401 to anonymous requests and 403 to users without role, as of their access token
+func requireRoleMiddleware(role string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		claims, ok := c.Locals(localAccessClaims).(*domain.AccessClaims)
+		if !ok {
+			c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+			return withStatus(fiber.StatusUnauthorized, domain.ErrUnauthenticated)
+		}
+
+		if !claims.HasRole(role) {
+			return withStatus(fiber.StatusForbidden, domain.ErrForbidden)
+		}
+
+		return c.Next()
+	}
+}
+
+// actorMiddleware passes the authenticated user down to the storages, e.g. for audit records
+func actorMiddleware() fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if userId, ok := c.Locals(localUserId).(uuid.UUID); ok {
+			c.SetContext(domain.ContextWithActor(c.Context(), userId))
+		}
+		return c.Next()
+	}
+}
+
+// errResponseNotSuccessful rolls back the transaction of a request answered without an error but with a non-2xx status
+var errResponseNotSuccessful = errors.New("response is not successful")
+
+// transactionMiddleware runs the rest of the chain in a single transaction, for handlers spanning several
+// storage calls; they opt in by pulling the storages with domain.TxStoragesFromContext. The transaction is
+// committed on a 2xx response and rolled back on errors, other statuses and panics. The unit of work must
+// make a single attempt, since retrying it would run the handlers twice.
+func transactionMiddleware(unitOfWork domain.UnitOfWork) fiber.Handler {
+	if unitOfWork == nil {
+		return func(c fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return func(c fiber.Ctx) error {
+		parent := c.Context()
+		defer c.SetContext(parent)
+
+		err := unitOfWork.Do(parent, func(ctx context.Context, tx *domain.TxStorages) error {
+			c.SetContext(domain.ContextWithTxStorages(ctx, tx))
+			if err := c.Next(); err != nil {
+				return err
+			}
+
+			if status := c.Response().StatusCode(); status < fiber.StatusOK || status >= fiber.StatusMultipleChoices {
+				return errResponseNotSuccessful
+			}
+			return nil
+		})
+		if errors.Is(err, errResponseNotSuccessful) {
+			return nil
+		}
+		return err
+	}
+}
+
+// timeoutMiddleware bounds the request context so storage queries are canceled after the deadline
+func timeoutMiddleware(timeout time.Duration) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.Context(), timeout)
+		defer cancel()
+
+		c.SetContext(ctx)
+		return c.Next()
+	}
+}
+
+// httpCacheMiddleware lets clients and proxies cache GET responses: it tags successful responses with
+// an ETag hashed from the body and a Cache-Control header, and answers 304 Not Modified when the
+// client's If-None-Match still matches. With no max age clients must revalidate on every use.
+func httpCacheMiddleware(maxAge time.Duration) fiber.Handler {
+	cacheControl := "private, no-cache"
+	if seconds := int(maxAge.Seconds()); seconds > 0 {
+		cacheControl = "private, max-age=" + strconv.Itoa(seconds)
+	}
+
+	tag := etag.New()
+	return func(c fiber.Ctx) error {
+		// the etag middleware runs the handler itself
+		if err := tag(c); err != nil {
+			return err
+		}
+
+		if status := c.Response().StatusCode(); status == fiber.StatusOK || status == fiber.StatusNotModified {
+			c.Set(fiber.HeaderCacheControl, cacheControl)
+		}
+		return nil
+	}
+}
+
+// rateLimitMiddleware allows limit requests per window for each client using a fixed-window counter in cache.
+// Clients are keyed by user id when authenticated and by IP otherwise.
+func rateLimitMiddleware(cache domain.Cache, name string, limit int, window time.Duration) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if limit <= 0 || window <= 0 {
+			return c.Next()
+		}
+
+		key := "ratelimit:" + name + ":ip:" + clientIp(c)
+		if userId, ok := c.Locals(localUserId).(uuid.UUID); ok {
+			key = "ratelimit:" + name + ":user:" + userId.String()
+		}
+
+		count, retryAfter, err := cache.Incr(c.Context(), key, window)
+		if err != nil {
+			// the limiter must not take the API down with the cache backend
+			zerolog.Ctx(c.Context()).Warn().Err(err).Str("key", key).Msg("rate limit check failed")
+			return c.Next()
+		}
+
+		if count > int64(limit) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfterSeconds(retryAfter)))
+			return fiber.NewError(fiber.StatusTooManyRequests, "too many requests")
+		}
+
+		return c.Next()
+	}
+}
+
+// maintenanceMiddleware answers mutating requests with 503 while maintenance mode is on; reads are served as usual
+func maintenanceMiddleware(mode *maintenanceMode) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if !mode.enabled.Load() || slices.ContainsFunc(maintenanceExempt, func(prefix string) bool {
+			return hasPathPrefix(c.Path(), prefix)
+		}) {
+			return c.Next()
+		}
+
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfterSeconds(mode.retryAfter)))
+		return withStatus(fiber.StatusServiceUnavailable, domain.ErrMaintenance)
+	}
+}
+
+// defaultConcurrencyRetryAfter is short, a slot frees up as soon as any request completes
+const defaultConcurrencyRetryAfter = time.Second
+
+// concurrencyLimitMiddleware serves at most cfg.MaxRequests requests at once, answering the rest with 503 right away
+// instead of queueing them on the database pool. Requests to the exempt paths are never limited.
+func concurrencyLimitMiddleware(cfg config.Concurrency, exempt ...string) fiber.Handler {
+	if cfg.MaxRequests <= 0 {
+		return func(c fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	retryAfter := cfg.RetryAfter
+	if retryAfter <= 0 {
+		retryAfter = defaultConcurrencyRetryAfter
+	}
+
+	slots := make(chan struct{}, cfg.MaxRequests)
+	return func(c fiber.Ctx) error {
+		if slices.Contains(exempt, c.Path()) {
+			return c.Next()
+		}
+
+		select {
+		case slots <- struct{}{}:
+			slot := &concurrencySlot{release: sync.OnceFunc(func() { <-slots })}
+			c.Locals(localConcurrencySlot, slot)
+			defer func() {
+				if !slot.streamed {
+					slot.release()
+				}
+			}()
+			return c.Next()
+		default:
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfterSeconds(retryAfter)))
+			return withStatus(fiber.StatusServiceUnavailable, domain.ErrOverloaded)
+		}
+	}
+}
+
+// concurrencySlot is the place a request holds under concurrencyLimitMiddleware. It is given back when the
+// handler returns, unless the response is streamed: then sendStream gives it back once the body is written.
+type concurrencySlot struct {
+	release  func()
+	streamed bool
+}
+
+// sendStream is c.SendStreamWriter for bodies written after the handler returns, the request keeps its
+// concurrency slot until write is done so long exports stay under the limit
+func sendStream(c fiber.Ctx, write func(w *bufio.Writer)) error {
+	slot, ok := c.Locals(localConcurrencySlot).(*concurrencySlot)
+	if !ok {
+		return c.SendStreamWriter(write)
+	}
+
+	slot.streamed = true
+	return c.SendStreamWriter(func(w *bufio.Writer) {
+		defer slot.release()
+		write(w)
+	})
+}
+
+// hasPathPrefix reports whether path is the route at prefix or lies below it. A trailing slash or a
+// sub-path matches like the route itself, a sibling that merely starts with the same letters does not.
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// retryAfterSeconds rounds d up to the whole seconds of a Retry-After header
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// defaultCompressionMinLength is the body size below which compression rarely pays off
+const defaultCompressionMinLength = 1024
+
+// compressionMiddleware compresses responses with brotli, gzip or deflate, whichever the client accepts first.
+// It does what fiber's compress middleware does, which cannot skip short bodies as it only looks at the request.
+// Streams like the CSV export are compressed on the fly; PDF invoices are compressed already and left as is.
+func compressionMiddleware(cfg config.Compression) fiber.Handler {
+	if !cfg.Enabled {
+		return func(c fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	minLength := cfg.MinLength
+	if minLength <= 0 {
+		minLength = defaultCompressionMinLength
+	}
+
+	brotliLevel, level := fasthttp.CompressBrotliDefaultCompression, fasthttp.CompressDefaultCompression
+	switch cfg.Level {
+	case "best_speed":
+		brotliLevel, level = fasthttp.CompressBrotliBestSpeed, fasthttp.CompressBestSpeed
+	case "best_compression":
+		brotliLevel, level = fasthttp.CompressBrotliBestCompression, fasthttp.CompressBestCompression
+	}
+	compress := fasthttp.CompressHandlerBrotliLevel(func(*fasthttp.RequestCtx) {}, brotliLevel, level)
+
+	return func(c fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		// caches must not hand a compressed body to clients that did not ask for one, nor the other way round
+		c.Vary(fiber.HeaderAcceptEncoding)
+		resp := c.Response()
+		if !resp.IsBodyStream() && len(resp.Body()) < minLength {
+			return nil
+		}
+		if strings.HasPrefix(string(resp.Header.ContentType()), "application/pdf") {
+			return nil
+		}
+
+		compress(c.RequestCtx())
+
+		// the ETag was taken from the plain body, the compressed one is only semantically the same
+		if etag := resp.Header.Peek(fiber.HeaderETag); len(resp.Header.Peek(fiber.HeaderContentEncoding)) > 0 &&
+			len(etag) > 0 && !bytes.HasPrefix(etag, []byte("W/")) {
+			resp.Header.Set(fiber.HeaderETag, "W/"+string(etag))
+		}
+		return nil
+	}
+}
+
+// corsMiddleware answers preflight requests and sets Access-Control-Allow-* headers for the configured origins
+func corsMiddleware(cfg config.Cors) fiber.Handler {
+	if len(cfg.AllowOrigins) == 0 {
+		return func(c fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return cors.New(cors.Config{
+		AllowOrigins: cfg.AllowOrigins,
+		AllowMethods: []string{
+			fiber.MethodGet, fiber.MethodPost, fiber.MethodPut, fiber.MethodDelete, fiber.MethodOptions,
+		},
+		AllowHeaders: []string{
+			fiber.HeaderOrigin, fiber.HeaderContentType, fiber.HeaderAccept, fiber.HeaderAuthorization,
+			fiber.HeaderIfNoneMatch,
+		},
+		// the ETag is read by front-end code revalidating with If-None-Match
+		ExposeHeaders:    []string{fiber.HeaderRetryAfter, fiber.HeaderETag},
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           int(cfg.MaxAge.Seconds()),
+	})
+}
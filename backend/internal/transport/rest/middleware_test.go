@@ -0,0 +1,671 @@
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/config"
+	"mts/internal/domain"
+	"mts/internal/repository/cache"
+	"shared"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Post("/", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	}, rateLimitMiddleware(cache.NewMemoryCache(), "test", 2, 100*time.Millisecond))
+
+	send := func() *http.Response {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/", nil))
+		require.NoError(t, err)
+		return resp
+	}
+
+	assert.Equal(t, fiber.StatusCreated, send().StatusCode)
+	assert.Equal(t, fiber.StatusCreated, send().StatusCode)
+
+	limited := send()
+	assert.Equal(t, fiber.StatusTooManyRequests, limited.StatusCode)
+	assert.Equal(t, "1", limited.Header.Get(fiber.HeaderRetryAfter))
+
+	body, err := io.ReadAll(limited.Body)
+	require.NoError(t, err)
+	var errResp ErrorResponse
+	require.NoError(t, json.Unmarshal(body, &errResp))
+	assert.Equal(t, "too many requests", errResp.Message)
+
+	// the counter resets once the window is over
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, fiber.StatusCreated, send().StatusCode)
+}
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	const limit, requests = 2, 6
+
+	var inFlight, peak atomic.Int32
+	entered := make(chan struct{}, requests)
+	release := make(chan struct{})
+
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Use(concurrencyLimitMiddleware(config.Concurrency{MaxRequests: limit}, healthPath))
+	app.Get(healthPath, health)
+	app.Get("/slow", func(c fiber.Ctx) error {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			if seen := peak.Load(); current <= seen || peak.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+
+		entered <- struct{}{}
+		<-release
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	statuses := make(chan *http.Response, requests)
+	for range requests {
+		go func() {
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/slow", nil), fiber.TestConfig{Timeout: 5 * time.Second})
+			assert.NoError(t, err)
+			statuses <- resp
+		}()
+	}
+
+	// the requests over the limit are rejected without waiting for a slot
+	for range requests - limit {
+		select {
+		case resp := <-statuses:
+			require.NotNil(t, resp)
+			assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+			assert.Equal(t, "1", resp.Header.Get(fiber.HeaderRetryAfter))
+
+			var errResp ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			assert.Equal(t, "OVERLOADED", errResp.Code)
+		case <-time.After(5 * time.Second):
+			t.Fatal("requests over the limit were not rejected")
+		}
+	}
+	for range limit {
+		<-entered
+	}
+
+	// probes are answered while every slot is taken
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, healthPath, nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	close(release)
+	for range limit {
+		resp := <-statuses
+		require.NotNil(t, resp)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	}
+	assert.EqualValues(t, limit, peak.Load(), "more requests than the limit were served at once")
+
+	// the slots are given back
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/slow", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+}
+
+func TestConcurrencyLimitMiddleware_HoldsSlotWhileStreaming(t *testing.T) {
+	writing := make(chan struct{})
+	release := make(chan struct{})
+
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Use(concurrencyLimitMiddleware(config.Concurrency{MaxRequests: 1}))
+	app.Get("/export", func(c fiber.Ctx) error {
+		return sendStream(c, func(w *bufio.Writer) {
+			close(writing)
+			<-release
+			_, _ = w.WriteString("done")
+		})
+	})
+	app.Get("/", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	exported := make(chan *http.Response, 1)
+	go func() {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/export", nil), fiber.TestConfig{Timeout: 5 * time.Second})
+		assert.NoError(t, err)
+		exported <- resp
+	}()
+	<-writing
+
+	// the export handler has returned, its body is still being written
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	close(release)
+	resp = <-exported
+	require.NotNil(t, resp)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "done", string(body))
+
+	// the slot is given back once the body is written
+	require.Eventually(t, func() bool {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		return err == nil && resp.StatusCode == fiber.StatusOK
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestNew_ServiceUnavailableCarriesCors(t *testing.T) {
+	app := New(&config.Service{
+		Cors: config.Cors{AllowOrigins: []string{"http://front.local"}},
+	}, cache.NewMemoryCache(), nil, NewReadiness(ReadinessMigrating), nil, nil, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(fiber.MethodGet, "/api/v1/products", nil)
+	req.Header.Set(fiber.HeaderOrigin, "http://front.local")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	assert.Equal(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, "http://front.local", resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+}
+
+func TestRateLimitMiddleware_KeyedByUser(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Post("/", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	}, func(c fiber.Ctx) error {
+		if userId, err := uuid.Parse(c.Get("X-User-Id")); err == nil {
+			c.Locals(localUserId, userId)
+		}
+		return c.Next()
+	}, rateLimitMiddleware(cache.NewMemoryCache(), "test", 1, time.Minute))
+
+	send := func(userId uuid.UUID) int {
+		req := httptest.NewRequest(fiber.MethodPost, "/", nil)
+		req.Header.Set("X-User-Id", userId.String())
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	first, second := uuid.New(), uuid.New()
+	assert.Equal(t, fiber.StatusCreated, send(first))
+	assert.Equal(t, fiber.StatusTooManyRequests, send(first))
+
+	// same IP, different user
+	assert.Equal(t, fiber.StatusCreated, send(second))
+}
+
+func TestClientIpMiddleware(t *testing.T) {
+	// app.Test connects from 0.0.0.0
+	newApp := func(proxy config.Proxy) *fiber.App {
+		app := fiber.New()
+		app.Use(clientIpMiddleware(proxy))
+		app.Get("/", func(c fiber.Ctx) error {
+			return c.SendString(clientIp(c))
+		})
+		return app
+	}
+	resolve := func(app *fiber.App, header, value string) string {
+		req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		if value != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	tests := []struct {
+		name   string
+		proxy  config.Proxy
+		header string
+		value  string
+		want   string
+	}{
+		{
+			name:   "no trusted proxies ignores the header",
+			header: fiber.HeaderXForwardedFor,
+			value:  "203.0.113.7",
+			want:   "0.0.0.0",
+		},
+		{
+			name:   "untrusted source cannot spoof the client",
+			proxy:  config.Proxy{Trusted: []string{"10.0.0.0/8"}},
+			header: fiber.HeaderXForwardedFor,
+			value:  "203.0.113.7",
+			want:   "0.0.0.0",
+		},
+		{
+			name:   "trusted proxy reports the client",
+			proxy:  config.Proxy{Trusted: []string{"0.0.0.0"}},
+			header: fiber.HeaderXForwardedFor,
+			value:  "2001:db8::1",
+			want:   "2001:db8::1",
+		},
+		{
+			name:   "trusted hops are skipped from the right",
+			proxy:  config.Proxy{Trusted: []string{"10.0.0.0/8", "0.0.0.0/32"}},
+			header: fiber.HeaderXForwardedFor,
+			value:  "203.0.113.7, 10.0.0.2",
+			want:   "203.0.113.7",
+		},
+		{
+			name:   "addresses the client prepended are ignored",
+			proxy:  config.Proxy{Trusted: []string{"10.0.0.0/8", "0.0.0.0"}},
+			header: fiber.HeaderXForwardedFor,
+			value:  "10.0.0.1, 192.0.2.66, 198.51.100.4, 10.0.0.2",
+			want:   "198.51.100.4",
+		},
+		{
+			name:   "a client claiming to be a trusted proxy is still the last untrusted hop",
+			proxy:  config.Proxy{Trusted: []string{"10.0.0.0/8", "0.0.0.0"}},
+			header: fiber.HeaderXForwardedFor,
+			value:  "10.0.0.9,198.51.100.4",
+			want:   "198.51.100.4",
+		},
+		{
+			name:   "only trusted hops resolve to the leftmost",
+			proxy:  config.Proxy{Trusted: []string{"10.0.0.0/8", "0.0.0.0"}},
+			header: fiber.HeaderXForwardedFor,
+			value:  "10.0.0.3, 10.0.0.2",
+			want:   "10.0.0.3",
+		},
+		{
+			name:   "malformed entry stops at the proxy that passed it on",
+			proxy:  config.Proxy{Trusted: []string{"10.0.0.0/8", "0.0.0.0"}},
+			header: fiber.HeaderXForwardedFor,
+			value:  "198.51.100.4, not an ip, 10.0.0.2",
+			want:   "10.0.0.2",
+		},
+		{
+			name:   "malformed header falls back to the proxy",
+			proxy:  config.Proxy{Trusted: []string{"0.0.0.0"}},
+			header: fiber.HeaderXForwardedFor,
+			value:  "not an ip",
+			want:   "0.0.0.0",
+		},
+		{
+			name:   "configured header replaces X-Forwarded-For",
+			proxy:  config.Proxy{Trusted: []string{"0.0.0.0"}, Header: "X-Real-Ip"},
+			header: "X-Real-Ip",
+			value:  "198.51.100.4",
+			want:   "198.51.100.4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, resolve(newApp(tt.proxy), tt.header, tt.value))
+		})
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	previous := shared.Logger
+	shared.Logger = zerolog.New(&logs)
+	t.Cleanup(func() { shared.Logger = previous })
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, nil, nil)
+	app.Get("/panic", func(c fiber.Ctx) error {
+		var products map[string]int
+		products["phone"]++ // assignment to a nil map
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/panic", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, ErrorResponse{Message: "internal server error", Code: "INTERNAL"}, errResp)
+
+	var entry struct {
+		Level     string           `json:"level"`
+		Message   string           `json:"message"`
+		Error     string           `json:"error"`
+		RequestId string           `json:"request_id"`
+		Stack     []map[string]any `json:"stack"`
+	}
+	for line := range strings.Lines(logs.String()) {
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		if entry.Message == "handler panicked" {
+			break
+		}
+	}
+	require.Equal(t, "handler panicked", entry.Message)
+	assert.Equal(t, "error", entry.Level)
+	assert.Contains(t, entry.Error, "assignment to entry in nil map")
+	assert.Equal(t, resp.Header.Get(fiber.HeaderXRequestID), entry.RequestId)
+	assert.NotEmpty(t, entry.RequestId)
+
+	// the stack reaches down to the panicking handler
+	var funcs []string
+	for _, frame := range entry.Stack {
+		funcs = append(funcs, fmt.Sprint(frame["func"]))
+	}
+	assert.Contains(t, strings.Join(funcs, " "), "TestRecoverMiddleware")
+}
+
+func TestRateLimitMiddleware_Disabled(t *testing.T) {
+	app := fiber.New()
+	app.Post("/", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	}, rateLimitMiddleware(cache.NewMemoryCache(), "test", 0, time.Minute))
+
+	for range 5 {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+	}
+}
+
+func TestCorsMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Use(corsMiddleware(config.Cors{
+		AllowOrigins:     []string{"http://front.local"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}))
+	app.Get("/", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	preflight := func(origin string) *http.Response {
+		req := httptest.NewRequest(fiber.MethodOptions, "/", nil)
+		req.Header.Set(fiber.HeaderOrigin, origin)
+		req.Header.Set(fiber.HeaderAccessControlRequestMethod, fiber.MethodGet)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("allowed origin", func(t *testing.T) {
+		resp := preflight("http://front.local")
+
+		assert.Equal(t, fiber.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "http://front.local", resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+		assert.Equal(t, "true", resp.Header.Get(fiber.HeaderAccessControlAllowCredentials))
+		assert.Equal(t, "600", resp.Header.Get(fiber.HeaderAccessControlMaxAge))
+		assert.Contains(t, resp.Header.Get(fiber.HeaderAccessControlAllowMethods), fiber.MethodDelete)
+		assert.Contains(t, resp.Header.Get(fiber.HeaderAccessControlAllowHeaders), fiber.HeaderContentType)
+		assert.Contains(t, resp.Header.Get(fiber.HeaderAccessControlAllowHeaders), fiber.HeaderIfNoneMatch)
+	})
+
+	t.Run("allowed origin simple request", func(t *testing.T) {
+		req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		req.Header.Set(fiber.HeaderOrigin, "http://front.local")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		assert.Equal(t, "http://front.local", resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+		assert.Contains(t, resp.Header.Get(fiber.HeaderAccessControlExposeHeaders), fiber.HeaderETag)
+	})
+
+	t.Run("disallowed origin", func(t *testing.T) {
+		resp := preflight("http://evil.local")
+
+		assert.Empty(t, resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+		assert.Empty(t, resp.Header.Get(fiber.HeaderAccessControlAllowCredentials))
+	})
+}
+
+func TestCorsMiddleware_NoOrigins(t *testing.T) {
+	app := fiber.New()
+	app.Use(corsMiddleware(config.Cors{}))
+	app.Get("/", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+	req.Header.Set(fiber.HeaderOrigin, "http://front.local")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(fiber.HeaderAccessControlAllowOrigin))
+}
+
+func TestHttpCacheMiddleware(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Get("/ok", func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{"id": 1})
+	}, httpCacheMiddleware(90*time.Second))
+	app.Get("/missing", func(c fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusNotFound, "not found")
+	}, httpCacheMiddleware(90*time.Second))
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/ok", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get(fiber.HeaderETag))
+	assert.Equal(t, "private, max-age=90", resp.Header.Get(fiber.HeaderCacheControl))
+
+	req := httptest.NewRequest(fiber.MethodGet, "/ok", nil)
+	req.Header.Set(fiber.HeaderIfNoneMatch, resp.Header.Get(fiber.HeaderETag))
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+	assert.Equal(t, "private, max-age=90", resp.Header.Get(fiber.HeaderCacheControl))
+
+	// errors are neither tagged nor cacheable
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/missing", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(fiber.HeaderETag))
+	assert.Empty(t, resp.Header.Get(fiber.HeaderCacheControl))
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"description":"Phone","tags":["electronics"]},`, 100)
+
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Use(compressionMiddleware(config.Compression{Enabled: true, MinLength: 512}))
+	app.Get("/large", func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{"products": large})
+	})
+	app.Get("/small", func(c fiber.Ctx) error {
+		return c.JSON(fiber.Map{"id": 1})
+	})
+	app.Get("/cached", func(c fiber.Ctx) error {
+		return c.SendString(large)
+	}, httpCacheMiddleware(0))
+	app.Get("/stream", func(c fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		return c.SendStreamWriter(func(w *bufio.Writer) {
+			_, _ = w.WriteString(large)
+		})
+	})
+	app.Get("/pdf", func(c fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		return c.SendString(large)
+	})
+
+	get := func(path, acceptEncoding string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest(fiber.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set(fiber.HeaderAcceptEncoding, acceptEncoding)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+		return resp
+	}
+	gunzip := func(resp *http.Response) string {
+		t.Helper()
+		reader, err := gzip.NewReader(resp.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	resp := get("/large", "gzip")
+	assert.Equal(t, "gzip", resp.Header.Get(fiber.HeaderContentEncoding))
+	assert.Equal(t, fiber.HeaderAcceptEncoding, resp.Header.Get(fiber.HeaderVary))
+	var body map[string]string
+	require.NoError(t, json.Unmarshal([]byte(gunzip(resp)), &body))
+	assert.Equal(t, large, body["products"])
+
+	// the compressed body is not byte for byte the tagged one, so its ETag is weak
+	compressed, plain := get("/cached", "gzip"), get("/cached", "")
+	assert.Equal(t, fiber.HeaderAcceptEncoding, compressed.Header.Get(fiber.HeaderVary))
+	assert.Equal(t, "W/"+plain.Header.Get(fiber.HeaderETag), compressed.Header.Get(fiber.HeaderETag))
+	assert.NotContains(t, plain.Header.Get(fiber.HeaderETag), "W/")
+
+	// and still revalidates
+	req := httptest.NewRequest(fiber.MethodGet, "/cached", nil)
+	req.Header.Set(fiber.HeaderAcceptEncoding, "gzip")
+	req.Header.Set(fiber.HeaderIfNoneMatch, compressed.Header.Get(fiber.HeaderETag))
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+
+	// streams are compressed as they are written
+	resp = get("/stream", "gzip")
+	assert.Equal(t, "gzip", resp.Header.Get(fiber.HeaderContentEncoding))
+	assert.Equal(t, large, gunzip(resp))
+
+	for name, resp := range map[string]*http.Response{
+		"client not accepting gzip": get("/large", ""),
+		"body under min_length":     get("/small", "gzip"),
+		"already compressed type":   get("/pdf", "gzip"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Empty(t, resp.Header.Get(fiber.HeaderContentEncoding))
+		})
+	}
+
+	// disabled compression leaves everything as is
+	app = fiber.New()
+	app.Use(compressionMiddleware(config.Compression{}))
+	app.Get("/large", func(c fiber.Ctx) error {
+		return c.SendString(large)
+	})
+	resp = get("/large", "gzip")
+	assert.Empty(t, resp.Header.Get(fiber.HeaderContentEncoding))
+	assert.Empty(t, resp.Header.Get(fiber.HeaderVary))
+}
+
+func TestHasPathPrefix(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{path: "/api/v1/admin/maintenance", expected: true},
+		{path: "/api/v1/admin/maintenance/", expected: true},
+		{path: "/api/v1/admin/maintenance/schedule", expected: true},
+		{path: "/api/v1/admin/maintenance-window", expected: false},
+		{path: "/api/v1/admin", expected: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, hasPathPrefix(tt.path, maintenancePath), tt.path)
+	}
+}
+
+// stagingUnitOfWork keeps the products created inside a unit of work apart until it commits
+type stagingUnitOfWork struct {
+	committed []*domain.Product
+}
+
+func (u *stagingUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx *domain.TxStorages) error) error {
+	products := &stagingProductStorage{}
+	if err := fn(ctx, &domain.TxStorages{Products: products}); err != nil {
+		return err
+	}
+
+	u.committed = append(u.committed, products.staged...)
+	return nil
+}
+
+type stagingProductStorage struct {
+	domain.ProductStorage
+	staged []*domain.Product
+}
+
+func (s *stagingProductStorage) CreateProduct(_ context.Context, product *domain.Product) error {
+	s.staged = append(s.staged, product)
+	return nil
+}
+
+func TestTransactionMiddleware(t *testing.T) {
+	factory := &domain.Factory{}
+	unitOfWork := &stagingUnitOfWork{}
+
+	// createProducts writes two products in the request transaction, then answers with the given outcome
+	createProducts := func(outcome func(c fiber.Ctx) error) fiber.Handler {
+		return func(c fiber.Ctx) error {
+			tx, ok := domain.TxStoragesFromContext(c.Context())
+			require.True(t, ok)
+
+			require.NoError(t, tx.Products.CreateProduct(c.Context(), factory.Product()))
+			require.NoError(t, tx.Products.CreateProduct(c.Context(), factory.Product()))
+			return outcome(c)
+		}
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Use(recoverMiddleware())
+	app.Post("/created", createProducts(func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	}), transactionMiddleware(unitOfWork))
+	app.Post("/failed", createProducts(func(c fiber.Ctx) error {
+		return domain.ErrInsufficientStock
+	}), transactionMiddleware(unitOfWork))
+	app.Post("/rejected", createProducts(func(c fiber.Ctx) error {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"message": "conflict"})
+	}), transactionMiddleware(unitOfWork))
+	app.Post("/panicked", createProducts(func(c fiber.Ctx) error {
+		panic("handler bug")
+	}), transactionMiddleware(unitOfWork))
+	app.Get("/outside", func(c fiber.Ctx) error {
+		_, ok := domain.TxStoragesFromContext(c.Context())
+		assert.False(t, ok)
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	send := func(method, path string) int {
+		resp, err := app.Test(httptest.NewRequest(method, path, nil))
+		require.NoError(t, err)
+		return resp.StatusCode
+	}
+
+	assert.NotEqual(t, fiber.StatusOK, send(fiber.MethodPost, "/failed"))
+	assert.Empty(t, unitOfWork.committed, "writes of a failed handler were committed")
+
+	assert.Equal(t, fiber.StatusConflict, send(fiber.MethodPost, "/rejected"))
+	assert.Empty(t, unitOfWork.committed, "writes of a non-2xx response were committed")
+
+	assert.Equal(t, fiber.StatusInternalServerError, send(fiber.MethodPost, "/panicked"))
+	assert.Empty(t, unitOfWork.committed, "writes of a panicking handler were committed")
+
+	assert.Equal(t, fiber.StatusCreated, send(fiber.MethodPost, "/created"))
+	assert.Len(t, unitOfWork.committed, 2)
+
+	// handlers that did not opt in run without a transaction
+	assert.Equal(t, fiber.StatusOK, send(fiber.MethodGet, "/outside"))
+}
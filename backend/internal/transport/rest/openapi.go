@@ -0,0 +1,13 @@
+package rest
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"mts/internal/transport/rest/docs"
+)
+
+// openapiSpec serves the generated swagger document for client code generators
+func openapiSpec(c fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.SendString(docs.SwaggerInfo.ReadDoc())
+}
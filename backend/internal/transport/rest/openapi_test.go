@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/config"
+	"mts/internal/repository/cache"
+)
+
+func TestOpenapiSpec(t *testing.T) {
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, nil, nil)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/openapi.json", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, fiber.MIMEApplicationJSONCharsetUTF8, resp.Header.Get(fiber.HeaderContentType))
+
+	var spec struct {
+		Swagger string                    `json:"swagger"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&spec))
+	assert.Equal(t, "2.0", spec.Swagger)
+
+	for path, method := range map[string]string{
+		"/api/v1/users":                     "post",
+		"/api/v1/products":                  "get",
+		"/api/v1/orders":                    "post",
+		"/api/v1/orders/{order_id}/history": "get",
+	} {
+		assert.Contains(t, spec.Paths[path], method, path)
+	}
+}
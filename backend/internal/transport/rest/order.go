@@ -1,10 +1,16 @@
 package rest
 
 import (
+	"bufio"
+	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog"
 
 	"mts/internal/domain"
 )
@@ -27,32 +33,70 @@ func newOrderHandler(orderAppService domain.OrderAppService) *orderHandler {
 // @Produce json
 // @Param request body CreateOrderRequest true "Order creation data"
 // @Success 201 {object} Order "Order created successfully"
-// @Failure 400 {object} ErrorResponse "Bad request - validation failed or insufficient stock"
+// @Failure 400 {object} ErrorResponse "Bad request - validation failed or insufficient stock (short products listed in shortages)"
 // @Failure 404 {object} ErrorResponse "Not found - user or product not found"
+// @Failure 409 {object} ErrorResponse "Conflict - an order with the same ID already exists"
+// @Failure 429 {object} ErrorResponse "Too many requests - the user already has the maximum number of open orders"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/orders [post]
 func (h *orderHandler) createOrder(c fiber.Ctx) error {
 	var req CreateOrderRequest
-	if err := c.Bind().JSON(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	if err := bindJSON(c, &req); err != nil {
+		return err
 	}
 
 	order, err := h.orderAppService.CreateOrder(c.Context(), req.ToDomain())
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if errors.Is(err, domain.ErrOrderValidation) {
-			status = fiber.StatusBadRequest
-		} else if errors.Is(err, domain.ErrUserNotFound) || errors.Is(err, domain.ErrProductNotFound) {
-			status = fiber.StatusNotFound
-		} else if errors.Is(err, domain.ErrInsufficientStock) {
-			status = fiber.StatusBadRequest
-		}
-		return fiber.NewError(status, err.Error())
+		return createOrderError(err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(NewOrder(order))
 }
 
+// validateOrder checks an order without placing it
+// @Summary Validate order
+// @Description Run the checks of order creation (user, open order limit, products, stock) and return the would-be order with its totals, without reserving stock or storing anything. The returned order has no ID, status or timestamps
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param request body CreateOrderRequest true "Order creation data"
+// @Success 200 {object} OrderPreview "The order would be created"
+// @Failure 400 {object} ErrorResponse "Bad request - validation failed or insufficient stock (short products listed in shortages)"
+// @Failure 404 {object} ErrorResponse "Not found - user or product not found"
+// @Failure 429 {object} ErrorResponse "Too many requests - the user already has the maximum number of open orders"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/orders/validate [post]
+func (h *orderHandler) validateOrder(c fiber.Ctx) error {
+	var req CreateOrderRequest
+	if err := bindJSON(c, &req); err != nil {
+		return err
+	}
+
+	order, err := h.orderAppService.ValidateOrder(c.Context(), req.ToDomain())
+	if err != nil {
+		return createOrderError(err)
+	}
+
+	return c.JSON(NewOrderPreview(order))
+}
+
+// createOrderError maps the failures of order creation, shared by its dry run
+func createOrderError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrOrderValidation):
+		return withStatus(fiber.StatusBadRequest, err)
+	case errors.Is(err, domain.ErrUserNotFound), errors.Is(err, domain.ErrProductNotFound):
+		return withStatus(fiber.StatusNotFound, err)
+	case errors.Is(err, domain.ErrInsufficientStock):
+		return badRequest(err)
+	case errors.Is(err, domain.ErrOrderLimitExceeded):
+		return withStatus(fiber.StatusTooManyRequests, err)
+	case errors.Is(err, domain.ErrOrderExists):
+		return withStatus(fiber.StatusConflict, err)
+	}
+	return err
+}
+
 // getOrders retrieves a paginated list of orders
 // @Summary Get orders list
 // @Description Retrieve a paginated list of all orders in the system
@@ -60,39 +104,195 @@ func (h *orderHandler) createOrder(c fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number for pagination" default(1) minimum(1)
-// @Param size query int false "Number of items per page" default(10) minimum(1) maximum(100)
+// @Param size query int false "Number of items per page, service.pagination.default_size (10 unless configured) when omitted; more than service.pagination.max_size (100 unless configured) is rejected with 400" minimum(1)
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor, takes precedence over page; only with the default order"
+// @Param sort query string false "Column to sort by" Enums(created_at, updated_at) default(created_at)
+// @Param order query string false "Sort direction" Enums(asc, desc) default(desc)
 // @Param user_id query string false "Filter orders by user ID" format(uuid)
+// @Param product_id query string false "Filter orders containing the product" format(uuid)
+// @Param expand query string false "Embed related resources" Enums(products)
 // @Success 200 {object} OrdersResponse "Orders retrieved successfully"
-// @Failure 400 {object} ErrorResponse "Bad request - invalid pagination parameters or user ID"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid pagination parameters, cursor, sort, user ID, product ID or expand value"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/orders [get]
 func (h *orderHandler) getOrders(c fiber.Ctx) error {
-	pagination := NewPaginationFromRequest(c)
+	req, pagination, err := orderPageFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	if err := orderFiltersFromRequest(c, req); err != nil {
+		return err
+	}
+
+	expand, err := expandFromRequest(c, "products")
+	if err != nil {
+		return err
+	}
 
+	orders, err := h.orderAppService.Orders(c.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	count, err := h.orderAppService.CountOrders(c.Context(), &domain.GetOrdersRequest{
+		UserIds:    req.UserIds,
+		ProductIds: req.ProductIds,
+	})
+	if err != nil {
+		return err
+	}
+
+	pagination.Total = count
+	pagination.CalculateTotalPages()
+	pagination.SetLinks(c.Path(), string(c.Request().URI().QueryString()))
+	setOrdersNextCursor(pagination, req, orders)
+
+	response := NewOrdersResponse(orders, *pagination)
+	if expand["products"] {
+		if err := h.expandProducts(c, orders, response.Orders); err != nil {
+			return err
+		}
+	}
+
+	return c.JSON(response)
+}
+
+// orderPageFromRequest parses the pagination, cursor and ordering shared by the order lists
+func orderPageFromRequest(c fiber.Ctx) (*domain.GetOrdersRequest, *Pagination, error) {
+	pagination, err := NewPaginationFromRequest(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	after, err := cursorFromRequest(c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req := &domain.GetOrdersRequest{
+		After:  after,
+		Limit:  pagination.Limit(),
+		Offset: pagination.Offset(),
+	}
+	if req.Sort, req.Order, err = sortFromRequest(c, domain.ParseOrderSort); err != nil {
+		return nil, nil, err
+	}
+	if after != nil && !req.DefaultOrder() {
+		return nil, nil, fiber.NewError(fiber.StatusBadRequest, "cursor pagination is only supported with the default created_at desc order")
+	}
+
+	return req, pagination, nil
+}
+
+// getOrderStats aggregates orders per status
+// @Summary Get order statistics
+// @Description Count orders and their item quantities per status in a single query (admin only). Every status is reported, with zeros when no orders match
+// @Tags Orders
+// @Produce json
+// @Security AdminToken
+// @Security BearerAuth
+// @Param user_id query string false "Only aggregate orders of the user" format(uuid)
+// @Param product_id query string false "Only aggregate orders containing the product" format(uuid)
+// @Success 200 {object} OrderStatsResponse "Per-status aggregates"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid user ID or product ID"
+// @Failure 401 {object} ErrorResponse "Unauthorized - admin token or access token is missing"
+// @Failure 403 {object} ErrorResponse "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/orders/stats [get]
+func (h *orderHandler) getOrderStats(c fiber.Ctx) error {
 	req := &domain.GetOrdersRequest{}
+	if err := orderFiltersFromRequest(c, req); err != nil {
+		return err
+	}
 
-	// Parse optional user_id filter
+	stats, err := h.orderAppService.StatusCounts(c.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(NewOrderStatsResponse(stats))
+}
+
+// orderFiltersFromRequest applies the optional user_id and product_id query filters to req
+func orderFiltersFromRequest(c fiber.Ctx, req *domain.GetOrdersRequest) error {
 	if userIdStr := c.Query("user_id"); userIdStr != "" {
-		userId, err := uuid.Parse(userIdStr)
+		userId, err := parseUUID("user_id", userIdStr)
 		if err != nil {
-			return fiber.NewError(fiber.StatusBadRequest, "invalid user_id format")
+			return err
 		}
 		req.UserIds = []uuid.UUID{userId}
 	}
 
-	orders, err := h.orderAppService.Orders(c.Context(), req)
+	if productIdStr := c.Query("product_id"); productIdStr != "" {
+		productId, err := parseUUID("product_id", productIdStr)
+		if err != nil {
+			return err
+		}
+		req.ProductIds = []uuid.UUID{productId}
+	}
+
+	return nil
+}
+
+// getUserOrders retrieves a paginated list of orders placed by a user
+// @Summary Get user orders
+// @Description Retrieve a paginated list of orders placed by a specific user
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param user_id path string true "User unique identifier" format(uuid)
+// @Param page query int false "Page number for pagination" default(1) minimum(1)
+// @Param size query int false "Number of items per page, service.pagination.default_size (10 unless configured) when omitted; more than service.pagination.max_size (100 unless configured) is rejected with 400" minimum(1)
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor, takes precedence over page; only with the default order"
+// @Param sort query string false "Column to sort by" Enums(created_at, updated_at) default(created_at)
+// @Param order query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Success 200 {object} OrdersResponse "Orders retrieved successfully"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid user ID format, cursor or sort"
+// @Failure 404 {object} ErrorResponse "Not found - user with specified ID does not exist"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/users/{user_id}/orders [get]
+func (h *orderHandler) getUserOrders(c fiber.Ctx) error {
+	userId, err := parseUUIDParam(c, "user_id")
+	if err != nil {
+		return err
+	}
+
+	req, pagination, err := orderPageFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	orders, err := h.orderAppService.UserOrders(c.Context(), userId, req)
+	if err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return withStatus(fiber.StatusNotFound, err)
+		}
+		return err
+	}
+
+	count, err := h.orderAppService.CountOrders(c.Context(), &domain.GetOrdersRequest{
+		UserIds: []uuid.UUID{userId},
+	})
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	// Note: For simplicity, not implementing count for orders in this example
-	// In production, you would add CountOrders method to the app service
-	pagination.Total = len(orders)
+	pagination.Total = count
 	pagination.CalculateTotalPages()
+	pagination.SetLinks(c.Path(), string(c.Request().URI().QueryString()))
+	setOrdersNextCursor(pagination, req, orders)
 
 	return c.JSON(NewOrdersResponse(orders, *pagination))
 }
 
+func setOrdersNextCursor(pagination *Pagination, req *domain.GetOrdersRequest, orders []*domain.Order) {
+	if len(orders) > 0 && req.DefaultOrder() {
+		last := orders[len(orders)-1]
+		pagination.SetNextCursor(len(orders), last.CreatedAt, last.Id)
+	}
+}
+
 // getOrder retrieves a specific order by ID
 // @Summary Get order by ID
 // @Description Retrieve detailed information about a specific order using its unique identifier
@@ -100,29 +300,163 @@ func (h *orderHandler) getOrders(c fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param order_id path string true "Order unique identifier" format(uuid)
+// @Param expand query string false "Embed related resources, comma-separated" Enums(user, products)
 // @Success 200 {object} Order "Order information retrieved successfully"
-// @Failure 400 {object} ErrorResponse "Bad request - invalid order ID format"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid order ID format or expand value"
 // @Failure 404 {object} ErrorResponse "Not found - order with specified ID does not exist"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/orders/{order_id} [get]
 func (h *orderHandler) getOrder(c fiber.Ctx) error {
-	orderId, err := uuid.Parse(c.Params("order_id"))
+	orderId, err := parseUUIDParam(c, "order_id")
+	if err != nil {
+		return err
+	}
+
+	expand, err := expandFromRequest(c, "user", "products")
+	if err != nil {
+		return err
+	}
+
+	orders, err := h.orderAppService.Orders(c.Context(), &domain.GetOrdersRequest{
+		Ids: []uuid.UUID{orderId},
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(orders) == 0 {
+		return withStatus(fiber.StatusNotFound, domain.ErrOrderNotFound)
+	}
+
+	order := NewOrder(orders[0])
+	if expand["user"] {
+		user, err := h.orderAppService.OrderUser(c.Context(), orders[0])
+		if err != nil {
+			return err
+		}
+		if user != nil {
+			order.User = NewOrderUser(user)
+		}
+	}
+	if expand["products"] {
+		if err := h.expandProducts(c, orders, []*Order{order}); err != nil {
+			return err
+		}
+	}
+
+	return c.JSON(order)
+}
+
+// expandProducts embeds the current state of the ordered products into the responses built from domainOrders.
+// The products of all orders are loaded at once.
+func (h *orderHandler) expandProducts(c fiber.Ctx, domainOrders []*domain.Order, orders []*Order) error {
+	products, err := h.orderAppService.OrderProducts(c.Context(), domainOrders)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid order ID format")
+		return err
+	}
+
+	for i, domainOrder := range domainOrders {
+		for j, domainItem := range domainOrder.Items {
+			orders[i].Items[j].Product = NewOrderItemProduct(domainItem, products[domainItem.ProductId])
+		}
+	}
+
+	return nil
+}
+
+// getOrderInvoice renders an order as a PDF invoice
+// @Summary Download order invoice
+// @Description Download a PDF invoice of the order with its ID, date, line items from the product snapshots and the total quantity
+// @Tags Orders
+// @Produce application/pdf
+// @Param order_id path string true "Order unique identifier" format(uuid)
+// @Success 200 {file} file "PDF invoice"
+// @Header 200 {string} Content-Disposition "Attachment named invoice-<order_id>.pdf"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid order ID format"
+// @Failure 404 {object} ErrorResponse "Not found - order with specified ID does not exist"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/orders/{order_id}/invoice [get]
+func (h *orderHandler) getOrderInvoice(c fiber.Ctx) error {
+	orderId, err := parseUUIDParam(c, "order_id")
+	if err != nil {
+		return err
 	}
 
 	orders, err := h.orderAppService.Orders(c.Context(), &domain.GetOrdersRequest{
 		Ids: []uuid.UUID{orderId},
 	})
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	if len(orders) == 0 {
-		return fiber.NewError(fiber.StatusNotFound, domain.ErrOrderNotFound.Error())
+		return withStatus(fiber.StatusNotFound, domain.ErrOrderNotFound)
 	}
 
-	return c.JSON(NewOrder(orders[0]))
+	// the layout is checked before streaming, so a failed rendering still gets an error status
+	invoice := newInvoice(orders[0])
+	if err = invoice.Error(); err != nil {
+		return fmt.Errorf("render invoice: %w", err)
+	}
+
+	ctx := context.WithoutCancel(c.Context())
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="invoice-%s.pdf"`, orderId))
+
+	return sendStream(c, func(w *bufio.Writer) {
+		if err := invoice.Output(w); err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Msg("failed to write order invoice")
+			return
+		}
+		_ = w.Flush()
+	})
+}
+
+// expandFromRequest parses the comma-separated expand query parameter, rejecting resources not in allowed
+func expandFromRequest(c fiber.Ctx, allowed ...string) (map[string]bool, error) {
+	expand := make(map[string]bool)
+	for _, resource := range strings.Split(c.Query("expand"), ",") {
+		resource = strings.TrimSpace(resource)
+		if resource == "" {
+			continue
+		}
+		if !slices.Contains(allowed, resource) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("unsupported expand %q, expected one of: %s", resource, strings.Join(allowed, ", ")))
+		}
+		expand[resource] = true
+	}
+
+	return expand, nil
+}
+
+// getOrderStatusHistory retrieves the status timeline of an order
+// @Summary Get order status history
+// @Description Retrieve every status transition of an order with its time and actor, oldest first
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param order_id path string true "Order unique identifier" format(uuid)
+// @Success 200 {object} OrderStatusHistoryResponse "Order status history retrieved successfully"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid order ID format"
+// @Failure 404 {object} ErrorResponse "Not found - order with specified ID does not exist"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/orders/{order_id}/history [get]
+func (h *orderHandler) getOrderStatusHistory(c fiber.Ctx) error {
+	orderId, err := parseUUIDParam(c, "order_id")
+	if err != nil {
+		return err
+	}
+
+	history, err := h.orderAppService.OrderStatusHistory(c.Context(), orderId)
+	if err != nil {
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			return withStatus(fiber.StatusNotFound, err)
+		}
+		return err
+	}
+
+	return c.JSON(NewOrderStatusHistoryResponse(history))
 }
 
 // updateOrder updates an existing order status
@@ -134,37 +468,147 @@ func (h *orderHandler) getOrder(c fiber.Ctx) error {
 // @Param order_id path string true "Order unique identifier" format(uuid)
 // @Param request body UpdateOrderRequest true "Order update data"
 // @Success 200 {object} Order "Order updated successfully"
-// @Failure 400 {object} ErrorResponse "Bad request - invalid order ID format or validation failed"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid order ID format, unknown status or validation failed"
 // @Failure 404 {object} ErrorResponse "Not found - order with specified ID does not exist"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/orders/{order_id} [put]
 func (h *orderHandler) updateOrder(c fiber.Ctx) error {
-	orderId, err := uuid.Parse(c.Params("order_id"))
+	orderId, err := parseUUIDParam(c, "order_id")
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid order ID format")
+		return err
 	}
 
 	var req UpdateOrderRequest
-	if err := c.Bind().JSON(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	if err := bindJSON(c, &req); err != nil {
+		return err
 	}
 
-	updateReq := req.ToDomain(orderId)
+	updateReq, err := req.ToDomain(orderId)
+	if err != nil {
+		return withStatus(fiber.StatusBadRequest, err)
+	}
 
 	order, err := h.orderAppService.UpdateOrder(c.Context(), updateReq)
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if errors.Is(err, domain.ErrOrderNotFound) {
-			status = fiber.StatusNotFound
-		} else if errors.Is(err, domain.ErrOrderValidation) {
-			status = fiber.StatusBadRequest
+		switch {
+		case errors.Is(err, domain.ErrOrderNotFound):
+			return withStatus(fiber.StatusNotFound, err)
+		case errors.Is(err, domain.ErrOrderValidation):
+			return withStatus(fiber.StatusBadRequest, err)
 		}
-		return fiber.NewError(status, err.Error())
+		return err
 	}
 
 	return c.JSON(NewOrder(order))
 }
 
+// bulkUpdateOrderStatus moves many orders to the same status
+// @Summary Bulk update order status
+// @Description Apply a status transition to many orders at once (admin only). Orders the transition is illegal for are skipped and reported; the rest are updated in a single transaction
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Security BearerAuth
+// @Param request body BulkUpdateOrderStatusRequest true "Orders and target status"
+// @Success 200 {object} BulkUpdateOrderStatusResponse "Per-order results"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid JSON, no orders or unsupported target status"
+// @Failure 401 {object} ErrorResponse "Unauthorized - admin token or access token is missing"
+// @Failure 403 {object} ErrorResponse "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/orders/bulk-status [post]
+func (h *orderHandler) bulkUpdateOrderStatus(c fiber.Ctx) error {
+	var req BulkUpdateOrderStatusRequest
+	if err := bindJSON(c, &req); err != nil {
+		return err
+	}
+
+	bulkReq, err := req.ToDomain()
+	if err != nil {
+		return withStatus(fiber.StatusBadRequest, err)
+	}
+
+	results, err := h.orderAppService.BulkUpdateStatus(c.Context(), bulkReq)
+	if err != nil {
+		if errors.Is(err, domain.ErrOrderValidation) {
+			return withStatus(fiber.StatusBadRequest, err)
+		}
+		return err
+	}
+
+	return c.JSON(NewBulkUpdateOrderStatusResponse(results))
+}
+
+// updateOrderItems replaces the items of a pending order
+// @Summary Update order items
+// @Description Replace the items of a pending order, restoring stock for removed items and reserving it for added ones
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Param order_id path string true "Order unique identifier" format(uuid)
+// @Param request body UpdateOrderItemsRequest true "New order items"
+// @Success 200 {object} Order "Order items updated successfully"
+// @Failure 400 {object} ErrorResponse "Bad request - validation failed, insufficient stock (short products listed in shortages) or order is not pending"
+// @Failure 404 {object} ErrorResponse "Not found - order or product not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/orders/{order_id}/items [put]
+func (h *orderHandler) updateOrderItems(c fiber.Ctx) error {
+	orderId, err := parseUUIDParam(c, "order_id")
+	if err != nil {
+		return err
+	}
+
+	var req UpdateOrderItemsRequest
+	if err := bindJSON(c, &req); err != nil {
+		return err
+	}
+
+	order, err := h.orderAppService.UpdateOrderItems(c.Context(), req.ToDomain(orderId))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrOrderValidation), errors.Is(err, domain.ErrInsufficientStock):
+			return badRequest(err)
+		case errors.Is(err, domain.ErrOrderNotFound), errors.Is(err, domain.ErrProductNotFound):
+			return withStatus(fiber.StatusNotFound, err)
+		}
+		return err
+	}
+
+	return c.JSON(NewOrder(order))
+}
+
+// deleteOrder hard-deletes an order
+// @Summary Delete order
+// @Description Permanently delete an order and its items (admin only). Unlike cancellation, product stock is not restored
+// @Tags Orders
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Security BearerAuth
+// @Param order_id path string true "Order unique identifier" format(uuid)
+// @Success 204 "Order deleted successfully"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid order ID format"
+// @Failure 401 {object} ErrorResponse "Unauthorized - admin token or access token is missing"
+// @Failure 403 {object} ErrorResponse "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role"
+// @Failure 404 {object} ErrorResponse "Not found - order with specified ID does not exist"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/orders/{order_id} [delete]
+func (h *orderHandler) deleteOrder(c fiber.Ctx) error {
+	orderId, err := parseUUIDParam(c, "order_id")
+	if err != nil {
+		return err
+	}
+
+	if err = h.orderAppService.DeleteOrder(c.Context(), orderId); err != nil {
+		if errors.Is(err, domain.ErrOrderNotFound) {
+			return withStatus(fiber.StatusNotFound, err)
+		}
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
 // cancelOrder cancels an order and restores product quantities
 // @Summary Cancel order
 // @Description Cancel an order and restore product quantities back to inventory
@@ -178,9 +622,9 @@ func (h *orderHandler) updateOrder(c fiber.Ctx) error {
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/orders/{order_id}/cancel [post]
 func (h *orderHandler) cancelOrder(c fiber.Ctx) error {
-	orderId, err := uuid.Parse(c.Params("order_id"))
+	orderId, err := parseUUIDParam(c, "order_id")
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid order ID format")
+		return err
 	}
 
 	updateReq := &domain.UpdateOrderRequest{
@@ -190,13 +634,50 @@ func (h *orderHandler) cancelOrder(c fiber.Ctx) error {
 
 	order, err := h.orderAppService.UpdateOrder(c.Context(), updateReq)
 	if err != nil {
-		statusCode := fiber.StatusInternalServerError
-		if errors.Is(err, domain.ErrOrderNotFound) {
-			statusCode = fiber.StatusNotFound
-		} else if errors.Is(err, domain.ErrOrderValidation) {
-			statusCode = fiber.StatusBadRequest
+		switch {
+		case errors.Is(err, domain.ErrOrderNotFound):
+			return withStatus(fiber.StatusNotFound, err)
+		case errors.Is(err, domain.ErrOrderValidation):
+			return withStatus(fiber.StatusBadRequest, err)
+		}
+		return err
+	}
+
+	return c.JSON(NewOrder(order))
+}
+
+// cancelOrderItem cancels one item of an order and restores its product quantity
+// @Summary Cancel order item
+// @Description Remove one item from a pending or confirmed order, restoring its quantity to inventory and lowering the order total in one transaction. Cancelling the last item cancels the whole order
+// @Tags Orders
+// @Produce json
+// @Param order_id path string true "Order unique identifier" format(uuid)
+// @Param item_id path string true "Order item unique identifier" format(uuid)
+// @Success 200 {object} Order "Item cancelled; the order is cancelled when it was the last item"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid order or item ID format, or the order cannot be cancelled"
+// @Failure 404 {object} ErrorResponse "Not found - order or item does not exist"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/orders/{order_id}/items/{item_id}/cancel [post]
+func (h *orderHandler) cancelOrderItem(c fiber.Ctx) error {
+	orderId, err := parseUUIDParam(c, "order_id")
+	if err != nil {
+		return err
+	}
+
+	itemId, err := parseUUIDParam(c, "item_id")
+	if err != nil {
+		return err
+	}
+
+	order, err := h.orderAppService.CancelItem(c.Context(), orderId, itemId)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrOrderNotFound), errors.Is(err, domain.ErrOrderItemNotFound):
+			return withStatus(fiber.StatusNotFound, err)
+		case errors.Is(err, domain.ErrOrderValidation):
+			return withStatus(fiber.StatusBadRequest, err)
 		}
-		return fiber.NewError(statusCode, err.Error())
+		return err
 	}
 
 	return c.JSON(NewOrder(order))
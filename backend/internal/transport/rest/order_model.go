@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,6 +31,10 @@ type OrderItem struct {
 	// @Description Historical product information at order time
 	ProductSnapshot ProductSnapshot `json:"product_snapshot"`
 
+	// Product
+	// @Description Current state of the product, only with expand=products
+	Product *OrderItemProduct `json:"product,omitempty"`
+
 	// Created at
 	// @Description When the order item was created
 	// @Example 2024-01-15T10:30:00Z
@@ -50,6 +55,51 @@ type ProductSnapshot struct {
 	Tags []string `json:"tags" example:"electronics,mobile"`
 } // @name ProductSnapshot
 
+// OrderItemProduct represents the current state of an ordered product
+// @Description Product as it is now, to compare with the snapshot taken at order time
+type OrderItemProduct struct {
+	// Deleted
+	// @Description Whether the product was deleted; the other fields are absent when it no longer exists at all
+	// @Example false
+	Deleted bool `json:"deleted" example:"false"`
+
+	// Changed
+	// @Description Whether the description or tags differ from the snapshot
+	// @Example true
+	Changed bool `json:"changed" example:"true"`
+
+	// Description
+	// @Description Current product description
+	// @Example "High-quality smartphone, 2024 edition"
+	Description string `json:"description,omitempty" example:"High-quality smartphone, 2024 edition"`
+
+	// Quantity
+	// @Description Current quantity in stock
+	// @Example 8
+	Quantity *int `json:"quantity,omitempty" example:"8"`
+
+	// Available
+	// @Description Whether the product can be ordered now
+	// @Example true
+	Available bool `json:"available" example:"true"`
+} // @name OrderItemProduct
+
+// NewOrderItemProduct describes the current state of the item's product, a nil product meaning it no longer exists
+func NewOrderItemProduct(domainItem *domain.OrderItem, domainProduct *domain.Product) *OrderItemProduct {
+	if domainProduct == nil {
+		return &OrderItemProduct{Deleted: true, Changed: true}
+	}
+
+	snapshot := domainItem.ProductSnapshot
+	return &OrderItemProduct{
+		Deleted:     domainProduct.IsDeleted(),
+		Changed:     domainProduct.Description != snapshot.Description || !slices.Equal(domainProduct.Tags, snapshot.Tags),
+		Description: domainProduct.Description,
+		Quantity:    &domainProduct.Quantity,
+		Available:   domainProduct.IsAvailable() && !domainProduct.IsDeleted(),
+	}
+}
+
 // Order represents an order in the API
 // @Description Order information with items
 type Order struct {
@@ -63,6 +113,10 @@ type Order struct {
 	// @Example 123e4567-e89b-12d3-a456-426614174000
 	UserId uuid.UUID `json:"user_id" example:"123e4567-e89b-12d3-a456-426614174000" swaggertype:"string"`
 
+	// User
+	// @Description User who created the order, only with expand=user
+	User *OrderUser `json:"user,omitempty"`
+
 	// Status
 	// @Description Current order status
 	// @Example "pending"
@@ -88,6 +142,81 @@ type Order struct {
 	UpdatedAt time.Time `json:"updated_at" example:"2024-01-15T10:30:00Z"`
 } // @name Order
 
+// OrderPreview represents an order that passed validation without being placed
+// @Description Would-be order of a dry run; it has no ID, status or timestamps as nothing is stored
+type OrderPreview struct {
+	// User ID
+	// @Description ID of the user the order is for
+	// @Example 123e4567-e89b-12d3-a456-426614174000
+	UserId uuid.UUID `json:"user_id" example:"123e4567-e89b-12d3-a456-426614174000" swaggertype:"string"`
+
+	// Items
+	// @Description List of items the order would have
+	Items []*OrderPreviewItem `json:"items"`
+
+	// Total quantity
+	// @Description Total quantity of all items in the order
+	// @Example 5
+	TotalQuantity int `json:"total_quantity" example:"5"`
+} // @name OrderPreview
+
+// OrderPreviewItem represents an item of an order that was not placed
+// @Description Item of a would-be order
+type OrderPreviewItem struct {
+	// Product ID
+	// @Description ID of the product
+	// @Example 456e7890-e12b-34d5-a678-901234567890
+	ProductId uuid.UUID `json:"product_id" example:"456e7890-e12b-34d5-a678-901234567890" swaggertype:"string"`
+
+	// Quantity
+	// @Description Quantity of the product in the order
+	// @Example 2
+	Quantity int `json:"quantity" example:"2"`
+
+	// Product snapshot
+	// @Description Product information the order would capture
+	ProductSnapshot ProductSnapshot `json:"product_snapshot"`
+} // @name OrderPreviewItem
+
+// OrderUser represents the user embedded in an expanded order
+// @Description Minimal details of the user who created the order
+type OrderUser struct {
+	// User ID
+	// @Description Unique identifier for the user
+	// @Example 123e4567-e89b-12d3-a456-426614174000
+	Id uuid.UUID `json:"id" example:"123e4567-e89b-12d3-a456-426614174000" swaggertype:"string"`
+
+	// First name
+	// @Description User's first name
+	// @Example "John"
+	FirstName string `json:"first_name" example:"John"`
+
+	// Last name
+	// @Description User's last name
+	// @Example "Doe"
+	LastName string `json:"last_name" example:"Doe"`
+
+	// Email
+	// @Description User's email address
+	// @Example "john.doe@example.com"
+	Email string `json:"email" example:"john.doe@example.com"`
+
+	// Deleted at
+	// @Description When the user was soft-deleted, absent for active users
+	// @Example 2024-02-01T12:00:00Z
+	DeletedAt *time.Time `json:"deleted_at,omitempty" example:"2024-02-01T12:00:00Z"`
+} // @name OrderUser
+
+func NewOrderUser(domainUser *domain.User) *OrderUser {
+	return &OrderUser{
+		Id:        domainUser.Id,
+		FirstName: domainUser.FirstName,
+		LastName:  domainUser.LastName,
+		Email:     domainUser.Email,
+		DeletedAt: domainUser.DeletedAt,
+	}
+}
+
 // CreateOrderItemRequest represents request to add an item to order
 // @Description Request item for creating an order
 type CreateOrderItemRequest struct {
@@ -99,7 +228,7 @@ type CreateOrderItemRequest struct {
 	// Quantity
 	// @Description Quantity to order
 	// @Example 2
-	Quantity int `json:"quantity" binding:"required" validate:"required,gt=0" example:"2"`
+	Quantity int `json:"quantity" binding:"required" validate:"gt=0" example:"2"`
 } // @name CreateOrderItemRequest
 
 // CreateOrderRequest represents request to create a new order
@@ -111,8 +240,8 @@ type CreateOrderRequest struct {
 	UserId uuid.UUID `json:"user_id" binding:"required" validate:"required" example:"123e4567-e89b-12d3-a456-426614174000" swaggertype:"string"`
 
 	// Items
-	// @Description List of items to order (at least one required)
-	Items []CreateOrderItemRequest `json:"items" binding:"required" validate:"required,min=1"`
+	// @Description List of items to order (at least one required); lines of the same product are merged into one item with the summed quantity
+	Items []CreateOrderItemRequest `json:"items" binding:"required" validate:"required,min=1,dive"`
 } // @name CreateOrderRequest
 
 func (req *CreateOrderRequest) ToDomain() *domain.CreateOrderRequest {
@@ -139,22 +268,114 @@ type UpdateOrderRequest struct {
 	Status string `json:"status" binding:"required" validate:"required,oneof=pending confirmed cancelled completed" example:"confirmed"`
 } // @name UpdateOrderRequest
 
-func (req *UpdateOrderRequest) ToDomain(orderId uuid.UUID) *domain.UpdateOrderRequest {
-	var status domain.OrderStatus
-	switch req.Status {
-	case "pending":
-		status = domain.OrderStatusPending
-	case "confirmed":
-		status = domain.OrderStatusConfirmed
-	case "cancelled":
-		status = domain.OrderStatusCancelled
-	case "completed":
-		status = domain.OrderStatusCompleted
+func (req *UpdateOrderRequest) ToDomain(orderId uuid.UUID) (*domain.UpdateOrderRequest, error) {
+	status, err := domain.ParseOrderStatus(req.Status)
+	if err != nil {
+		return nil, err
 	}
 
 	return &domain.UpdateOrderRequest{
 		Id:     orderId,
 		Status: status,
+	}, nil
+}
+
+// BulkUpdateOrderStatusRequest represents request to move many orders to the same status
+// @Description Request payload for a bulk order status update
+type BulkUpdateOrderStatusRequest struct {
+	// IDs
+	// @Description Orders to update (1 to 100)
+	Ids []uuid.UUID `json:"ids" binding:"required" validate:"required,min=1,max=100"`
+
+	// Status
+	// @Description Target status; orders are cancelled one by one through the cancel endpoint
+	// @Example "confirmed"
+	Status string `json:"status" binding:"required" validate:"required,oneof=confirmed completed" example:"confirmed"`
+} // @name BulkUpdateOrderStatusRequest
+
+func (req *BulkUpdateOrderStatusRequest) ToDomain() (*domain.BulkUpdateOrderStatusRequest, error) {
+	status, err := domain.ParseOrderStatus(req.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.BulkUpdateOrderStatusRequest{
+		Ids:    req.Ids,
+		Status: status,
+	}, nil
+}
+
+// OrderStatusUpdateResult represents the outcome of a bulk status update for one order
+// @Description Per-order result of a bulk status update
+type OrderStatusUpdateResult struct {
+	// Order ID
+	// @Description Order unique identifier
+	// @Example "123e4567-e89b-12d3-a456-426614174000"
+	OrderId uuid.UUID `json:"order_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+
+	// Updated
+	// @Description Whether the order was moved to the target status
+	// @Example true
+	Updated bool `json:"updated" example:"true"`
+
+	// Status
+	// @Description Order status after the update, omitted for unknown orders
+	// @Example "confirmed"
+	Status string `json:"status,omitempty" example:"confirmed" enum:"pending,confirmed,cancelled,completed"`
+
+	// Error
+	// @Description Why the order was skipped
+	// @Example "order validation error: order cannot be confirmed in status cancelled"
+	Error string `json:"error,omitempty" example:"order validation error: order cannot be confirmed in status cancelled"`
+} // @name OrderStatusUpdateResult
+
+// BulkUpdateOrderStatusResponse represents the outcome of a bulk status update
+// @Description Per-order results in request order
+type BulkUpdateOrderStatusResponse struct {
+	// Results
+	// @Description One result per distinct requested order
+	Results []*OrderStatusUpdateResult `json:"results"`
+} // @name BulkUpdateOrderStatusResponse
+
+func NewBulkUpdateOrderStatusResponse(results []*domain.OrderStatusUpdateResult) *BulkUpdateOrderStatusResponse {
+	response := &BulkUpdateOrderStatusResponse{
+		Results: make([]*OrderStatusUpdateResult, 0, len(results)),
+	}
+	for _, result := range results {
+		item := &OrderStatusUpdateResult{
+			OrderId: result.OrderId,
+			Updated: result.Err == nil,
+			Status:  string(result.Status),
+		}
+		if result.Err != nil {
+			item.Error = result.Err.Error()
+		}
+		response.Results = append(response.Results, item)
+	}
+
+	return response
+}
+
+// UpdateOrderItemsRequest represents request to replace the items of a pending order
+// @Description Request payload for replacing order items
+type UpdateOrderItemsRequest struct {
+	// Items
+	// @Description New list of items for the order (at least one required); lines of the same product are merged into one item with the summed quantity
+	Items []CreateOrderItemRequest `json:"items" binding:"required" validate:"required,min=1,dive"`
+} // @name UpdateOrderItemsRequest
+
+func (req *UpdateOrderItemsRequest) ToDomain(orderId uuid.UUID) *domain.UpdateOrderItemsRequest {
+	items := make([]domain.CreateOrderItemRequest, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, domain.CreateOrderItemRequest{
+			ProductId: item.ProductId,
+			Quantity:  item.Quantity,
+		})
+	}
+
+	return &domain.UpdateOrderItemsRequest{
+		Id:    orderId,
+		Items: items,
 	}
 }
 
@@ -170,6 +391,52 @@ type OrdersResponse struct {
 	Pagination *Pagination `json:"pagination"`
 } // @name OrdersResponse
 
+// OrderStatusChange represents an entry of the order status audit log
+// @Description Order status transition
+type OrderStatusChange struct {
+	// From status
+	// @Description Status before the change
+	// @Example "pending"
+	FromStatus string `json:"from_status" example:"pending" enum:"pending,confirmed,cancelled,completed"`
+
+	// To status
+	// @Description Status after the change
+	// @Example "confirmed"
+	ToStatus string `json:"to_status" example:"confirmed" enum:"pending,confirmed,cancelled,completed"`
+
+	// Actor ID
+	// @Description User who changed the status, omitted when unknown
+	// @Example "123e4567-e89b-12d3-a456-426614174000"
+	ActorId *uuid.UUID `json:"actor_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+
+	// Changed at
+	// @Description Time of the change
+	// @Example "2023-01-01T12:00:00Z"
+	ChangedAt time.Time `json:"changed_at" example:"2023-01-01T12:00:00Z"`
+} // @name OrderStatusChange
+
+// OrderStatusHistoryResponse represents the status timeline of an order
+// @Description Order status transitions, oldest first
+type OrderStatusHistoryResponse struct {
+	// History
+	// @Description Status transitions, oldest first
+	History []*OrderStatusChange `json:"history"`
+} // @name OrderStatusHistoryResponse
+
+func NewOrderStatusHistoryResponse(history []*domain.OrderStatusChange) *OrderStatusHistoryResponse {
+	changes := make([]*OrderStatusChange, 0, len(history))
+	for _, change := range history {
+		changes = append(changes, &OrderStatusChange{
+			FromStatus: string(change.FromStatus),
+			ToStatus:   string(change.ToStatus),
+			ActorId:    change.ActorId,
+			ChangedAt:  change.ChangedAt,
+		})
+	}
+
+	return &OrderStatusHistoryResponse{History: changes}
+}
+
 func NewOrderItem(domainItem *domain.OrderItem) *OrderItem {
 	return &OrderItem{
 		Id:        domainItem.Id,
@@ -192,14 +459,35 @@ func NewOrder(domainOrder *domain.Order) *Order {
 	return &Order{
 		Id:            domainOrder.Id,
 		UserId:        domainOrder.UserId,
-		Status:        domainOrder.Status,
+		Status:        string(domainOrder.Status),
 		Items:         items,
-		TotalQuantity: domainOrder.TotalQuantity(),
+		TotalQuantity: domainOrder.TotalQuantity,
 		CreatedAt:     domainOrder.CreatedAt,
 		UpdatedAt:     domainOrder.UpdatedAt,
 	}
 }
 
+// NewOrderPreview describes a validated order that was not stored, leaving out what only a stored order has
+func NewOrderPreview(domainOrder *domain.Order) *OrderPreview {
+	items := make([]*OrderPreviewItem, 0, len(domainOrder.Items))
+	for _, domainItem := range domainOrder.Items {
+		items = append(items, &OrderPreviewItem{
+			ProductId: domainItem.ProductId,
+			Quantity:  domainItem.Quantity,
+			ProductSnapshot: ProductSnapshot{
+				Description: domainItem.ProductSnapshot.Description,
+				Tags:        domainItem.ProductSnapshot.Tags,
+			},
+		})
+	}
+
+	return &OrderPreview{
+		UserId:        domainOrder.UserId,
+		Items:         items,
+		TotalQuantity: domainOrder.TotalQuantity,
+	}
+}
+
 func NewOrdersResponse(domainOrders []*domain.Order, pagination Pagination) *OrdersResponse {
 	orders := make([]*Order, 0, len(domainOrders))
 	for _, domainOrder := range domainOrders {
@@ -211,3 +499,39 @@ func NewOrdersResponse(domainOrders []*domain.Order, pagination Pagination) *Ord
 		Pagination: &pagination,
 	}
 }
+
+// OrderStatusStats represents the aggregates of the orders in one status
+// @Description Order count and item quantity for a status
+type OrderStatusStats struct {
+	// Count
+	// @Description Number of orders in the status
+	// @Example 12
+	Count int `json:"count" example:"12"`
+
+	// Total quantity
+	// @Description Sum of item quantities across the orders
+	// @Example 30
+	TotalQuantity int `json:"total_quantity" example:"30"`
+} // @name OrderStatusStats
+
+// OrderStatsResponse represents per-status order aggregates
+// @Description Order aggregates keyed by status; every status is present
+type OrderStatsResponse struct {
+	// Statuses
+	// @Description Aggregates keyed by order status (pending, confirmed, cancelled, completed)
+	Statuses map[string]*OrderStatusStats `json:"statuses"`
+} // @name OrderStatsResponse
+
+func NewOrderStatsResponse(stats map[domain.OrderStatus]*domain.OrderStatusStats) *OrderStatsResponse {
+	response := &OrderStatsResponse{
+		Statuses: make(map[string]*OrderStatusStats, len(stats)),
+	}
+	for status, statusStats := range stats {
+		response.Statuses[string(status)] = &OrderStatusStats{
+			Count:         statusStats.Count,
+			TotalQuantity: statusStats.TotalQuantity,
+		}
+	}
+
+	return response
+}
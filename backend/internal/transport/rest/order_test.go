@@ -0,0 +1,905 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/config"
+	"mts/internal/domain"
+	"mts/internal/repository/cache"
+)
+
+type mockOrderAppService struct {
+	mock.Mock
+}
+
+func (m *mockOrderAppService) CreateOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.Order, error) {
+	args := m.Called(ctx, req)
+	order, _ := args.Get(0).(*domain.Order)
+	return order, args.Error(1)
+}
+
+func (m *mockOrderAppService) ValidateOrder(ctx context.Context, req *domain.CreateOrderRequest) (*domain.Order, error) {
+	args := m.Called(ctx, req)
+	order, _ := args.Get(0).(*domain.Order)
+	return order, args.Error(1)
+}
+
+func (m *mockOrderAppService) UpdateOrder(ctx context.Context, req *domain.UpdateOrderRequest) (*domain.Order, error) {
+	args := m.Called(ctx, req)
+	order, _ := args.Get(0).(*domain.Order)
+	return order, args.Error(1)
+}
+
+func (m *mockOrderAppService) UpdateOrderItems(ctx context.Context, req *domain.UpdateOrderItemsRequest) (*domain.Order, error) {
+	args := m.Called(ctx, req)
+	order, _ := args.Get(0).(*domain.Order)
+	return order, args.Error(1)
+}
+
+func (m *mockOrderAppService) Orders(ctx context.Context, req *domain.GetOrdersRequest) ([]*domain.Order, error) {
+	args := m.Called(ctx, req)
+	orders, _ := args.Get(0).([]*domain.Order)
+	return orders, args.Error(1)
+}
+
+func (m *mockOrderAppService) CountOrders(ctx context.Context, req *domain.GetOrdersRequest) (int, error) {
+	args := m.Called(ctx, req)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockOrderAppService) StatusCounts(ctx context.Context, req *domain.GetOrdersRequest) (map[domain.OrderStatus]*domain.OrderStatusStats, error) {
+	args := m.Called(ctx, req)
+	stats, _ := args.Get(0).(map[domain.OrderStatus]*domain.OrderStatusStats)
+	return stats, args.Error(1)
+}
+
+func (m *mockOrderAppService) UserOrders(ctx context.Context, userId uuid.UUID, req *domain.GetOrdersRequest) ([]*domain.Order, error) {
+	args := m.Called(ctx, userId, req)
+	orders, _ := args.Get(0).([]*domain.Order)
+	return orders, args.Error(1)
+}
+
+func (m *mockOrderAppService) CancelOrder(ctx context.Context, orderId uuid.UUID) (*domain.Order, error) {
+	args := m.Called(ctx, orderId)
+	order, _ := args.Get(0).(*domain.Order)
+	return order, args.Error(1)
+}
+
+func (m *mockOrderAppService) OrderStatusHistory(ctx context.Context, orderId uuid.UUID) ([]*domain.OrderStatusChange, error) {
+	args := m.Called(ctx, orderId)
+	history, _ := args.Get(0).([]*domain.OrderStatusChange)
+	return history, args.Error(1)
+}
+
+func (m *mockOrderAppService) BulkUpdateStatus(ctx context.Context, req *domain.BulkUpdateOrderStatusRequest) ([]*domain.OrderStatusUpdateResult, error) {
+	args := m.Called(ctx, req)
+	results, _ := args.Get(0).([]*domain.OrderStatusUpdateResult)
+	return results, args.Error(1)
+}
+
+func (m *mockOrderAppService) OrderUser(ctx context.Context, order *domain.Order) (*domain.User, error) {
+	args := m.Called(ctx, order)
+	user, _ := args.Get(0).(*domain.User)
+	return user, args.Error(1)
+}
+
+func (m *mockOrderAppService) CancelItem(ctx context.Context, orderId, itemId uuid.UUID) (*domain.Order, error) {
+	args := m.Called(ctx, orderId, itemId)
+	order, _ := args.Get(0).(*domain.Order)
+	return order, args.Error(1)
+}
+
+func (m *mockOrderAppService) OrderProducts(ctx context.Context, orders []*domain.Order) (map[uuid.UUID]*domain.Product, error) {
+	args := m.Called(ctx, orders)
+	products, _ := args.Get(0).(map[uuid.UUID]*domain.Product)
+	return products, args.Error(1)
+}
+
+func (m *mockOrderAppService) DeleteOrder(ctx context.Context, orderId uuid.UUID) error {
+	args := m.Called(ctx, orderId)
+	return args.Error(0)
+}
+
+func newTestApp(orderAppService domain.OrderAppService) *fiber.App {
+	return New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, orderAppService, nil)
+}
+
+func TestGetUserOrders(t *testing.T) {
+	factory := &domain.Factory{}
+	userId := uuid.New()
+
+	tests := []struct {
+		name           string
+		path           string
+		setupMock      func(*mockOrderAppService)
+		expectedStatus int
+		expectedOrders int
+		expectedTotal  int
+	}{
+		{
+			name: "user with multiple orders",
+			path: "/api/v1/users/" + userId.String() + "/orders?page=1&size=2",
+			setupMock: func(m *mockOrderAppService) {
+				m.On("UserOrders", mock.Anything, userId, mock.MatchedBy(func(req *domain.GetOrdersRequest) bool {
+					return req.Limit == 2 && req.Offset == 0
+				})).Return([]*domain.Order{
+					factory.Order(userId, uuid.New()),
+					factory.Order(userId, uuid.New()),
+				}, nil)
+				m.On("CountOrders", mock.Anything, mock.Anything).Return(3, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+			expectedOrders: 2,
+			expectedTotal:  3,
+		},
+		{
+			name: "user without orders",
+			path: "/api/v1/users/" + userId.String() + "/orders",
+			setupMock: func(m *mockOrderAppService) {
+				m.On("UserOrders", mock.Anything, userId, mock.Anything).Return([]*domain.Order{}, nil)
+				m.On("CountOrders", mock.Anything, mock.Anything).Return(0, nil)
+			},
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name: "nonexistent user",
+			path: "/api/v1/users/" + userId.String() + "/orders",
+			setupMock: func(m *mockOrderAppService) {
+				m.On("UserOrders", mock.Anything, userId, mock.Anything).Return(nil, domain.ErrUserNotFound)
+			},
+			expectedStatus: fiber.StatusNotFound,
+		},
+		{
+			name:           "invalid user id",
+			path:           "/api/v1/users/not-a-uuid/orders",
+			setupMock:      func(m *mockOrderAppService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderAppService := new(mockOrderAppService)
+			tt.setupMock(orderAppService)
+
+			resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, tt.path, nil))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedStatus == fiber.StatusOK {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+
+				var ordersResp OrdersResponse
+				require.NoError(t, json.Unmarshal(body, &ordersResp))
+				assert.Len(t, ordersResp.Orders, tt.expectedOrders)
+				assert.Equal(t, tt.expectedTotal, ordersResp.Pagination.Total)
+			}
+
+			orderAppService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetOrders_Cursor(t *testing.T) {
+	factory := &domain.Factory{}
+	userId := uuid.New()
+
+	t.Run("cursor is passed through and a full page links the next one", func(t *testing.T) {
+		after := domain.NewCursor(time.Now().UTC().Truncate(time.Microsecond), uuid.New())
+		orders := []*domain.Order{factory.Order(userId, uuid.New()), factory.Order(userId, uuid.New())}
+
+		orderAppService := new(mockOrderAppService)
+		orderAppService.On("Orders", mock.Anything, mock.MatchedBy(func(req *domain.GetOrdersRequest) bool {
+			return req.After != nil && req.After.Id == after.Id && req.After.CreatedAt.Equal(after.CreatedAt) && req.Limit == 2
+		})).Return(orders, nil)
+		orderAppService.On("CountOrders", mock.Anything, mock.Anything).Return(5, nil)
+
+		resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders?size=2&cursor="+after.Encode(), nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var ordersResp OrdersResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&ordersResp))
+
+		last := orders[len(orders)-1]
+		assert.Equal(t, domain.NewCursor(last.CreatedAt, last.Id).Encode(), ordersResp.Pagination.NextCursor)
+		orderAppService.AssertExpectations(t)
+	})
+
+	t.Run("last page has no next cursor", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+		orderAppService.On("Orders", mock.Anything, mock.Anything).Return([]*domain.Order{factory.Order(userId, uuid.New())}, nil)
+		orderAppService.On("CountOrders", mock.Anything, mock.Anything).Return(1, nil)
+
+		resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders?size=2", nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var ordersResp OrdersResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&ordersResp))
+		assert.Empty(t, ordersResp.Pagination.NextCursor)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+
+		resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders?cursor=garbage", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		orderAppService.AssertNotCalled(t, "Orders", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetOrders_Sort(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		order domain.SortOrder
+	}{
+		{"sort=updated_at&order=asc", domain.SortOrderAsc},
+		{"sort=updated_at&order=desc", domain.SortOrderDesc},
+	} {
+		t.Run(tt.query, func(t *testing.T) {
+			orderAppService := new(mockOrderAppService)
+			orderAppService.On("Orders", mock.Anything, mock.MatchedBy(func(req *domain.GetOrdersRequest) bool {
+				return req.Sort == domain.OrderSortUpdatedAt && req.Order == tt.order
+			})).Return([]*domain.Order{(&domain.Factory{}).Order(uuid.New(), uuid.New())}, nil)
+			orderAppService.On("CountOrders", mock.Anything, mock.Anything).Return(5, nil)
+
+			resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders?size=1&"+tt.query, nil))
+			require.NoError(t, err)
+			require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+			// cursors only follow the default order
+			var ordersResp OrdersResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&ordersResp))
+			assert.Empty(t, ordersResp.Pagination.NextCursor)
+			orderAppService.AssertExpectations(t)
+		})
+	}
+
+	cursor := domain.NewCursor(time.Now(), uuid.New()).Encode()
+	for _, query := range []string{"sort=status", "order=up", "sort=updated_at&cursor=" + cursor} {
+		t.Run(query, func(t *testing.T) {
+			orderAppService := new(mockOrderAppService)
+
+			resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders?"+query, nil))
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+			orderAppService.AssertNotCalled(t, "Orders", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGetOrderInvoice(t *testing.T) {
+	order := (&domain.Factory{}).Order(uuid.New(), uuid.New(), uuid.New())
+	missing := uuid.New()
+
+	orderAppService := new(mockOrderAppService)
+	orderAppService.On("Orders", mock.Anything, mock.MatchedBy(func(req *domain.GetOrdersRequest) bool {
+		return len(req.Ids) == 1 && req.Ids[0] == order.Id
+	})).Return([]*domain.Order{order}, nil)
+	orderAppService.On("Orders", mock.Anything, mock.MatchedBy(func(req *domain.GetOrdersRequest) bool {
+		return len(req.Ids) == 1 && req.Ids[0] == missing
+	})).Return([]*domain.Order{}, nil)
+	app := newTestApp(orderAppService)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders/"+order.Id.String()+"/invoice", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/pdf", resp.Header.Get(fiber.HeaderContentType))
+	assert.Equal(t, `attachment; filename="invoice-`+order.Id.String()+`.pdf"`, resp.Header.Get(fiber.HeaderContentDisposition))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(body), "%PDF-"), "body is not a PDF")
+	assert.Contains(t, string(body), "%%EOF")
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders/"+missing.String()+"/invoice", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders/garbage/invoice", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestGetOrders_ProductFilter(t *testing.T) {
+	productId := uuid.New()
+
+	t.Run("product id filters both the page and the total", func(t *testing.T) {
+		byProduct := mock.MatchedBy(func(req *domain.GetOrdersRequest) bool {
+			return len(req.ProductIds) == 1 && req.ProductIds[0] == productId
+		})
+
+		orderAppService := new(mockOrderAppService)
+		orderAppService.On("Orders", mock.Anything, byProduct).Return([]*domain.Order{}, nil)
+		orderAppService.On("CountOrders", mock.Anything, byProduct).Return(0, nil)
+
+		resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders?product_id="+productId.String(), nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		orderAppService.AssertExpectations(t)
+	})
+
+	t.Run("invalid product id", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+
+		resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders?product_id=garbage", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		orderAppService.AssertNotCalled(t, "Orders", mock.Anything, mock.Anything)
+	})
+}
+
+func TestDeleteOrder(t *testing.T) {
+	orderId := uuid.New()
+	path := "/api/v1/orders/" + orderId.String()
+
+	tests := []struct {
+		name           string
+		adminToken     string
+		authorization  string
+		path           string
+		setupMock      func(*mockOrderAppService)
+		expectedStatus int
+	}{
+		{
+			name:          "admin deletes order",
+			adminToken:    "secret",
+			authorization: "Bearer secret",
+			path:          path,
+			setupMock: func(m *mockOrderAppService) {
+				m.On("DeleteOrder", mock.Anything, orderId).Return(nil)
+			},
+			expectedStatus: fiber.StatusNoContent,
+		},
+		{
+			name:          "nonexistent order",
+			adminToken:    "secret",
+			authorization: "Bearer secret",
+			path:          path,
+			setupMock: func(m *mockOrderAppService) {
+				m.On("DeleteOrder", mock.Anything, orderId).Return(domain.ErrOrderNotFound)
+			},
+			expectedStatus: fiber.StatusNotFound,
+		},
+		{
+			name:           "invalid order id",
+			adminToken:     "secret",
+			authorization:  "Bearer secret",
+			path:           "/api/v1/orders/not-a-uuid",
+			setupMock:      func(m *mockOrderAppService) {},
+			expectedStatus: fiber.StatusBadRequest,
+		},
+		{
+			name:           "missing token",
+			adminToken:     "secret",
+			path:           path,
+			setupMock:      func(m *mockOrderAppService) {},
+			expectedStatus: fiber.StatusUnauthorized,
+		},
+		{
+			name:           "wrong token",
+			adminToken:     "secret",
+			authorization:  "Bearer guess",
+			path:           path,
+			setupMock:      func(m *mockOrderAppService) {},
+			expectedStatus: fiber.StatusForbidden,
+		},
+		{
+			name:           "admin endpoints disabled",
+			authorization:  "Bearer ",
+			path:           path,
+			setupMock:      func(m *mockOrderAppService) {},
+			expectedStatus: fiber.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderAppService := new(mockOrderAppService)
+			tt.setupMock(orderAppService)
+
+			app := New(&config.Service{AdminToken: tt.adminToken}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, orderAppService, nil)
+
+			req := httptest.NewRequest(fiber.MethodDelete, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.authorization)
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			orderAppService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateOrder_Status(t *testing.T) {
+	order := (&domain.Factory{}).Order(uuid.New(), uuid.New())
+	path := "/api/v1/orders/" + order.Id.String()
+
+	t.Run("known status is parsed", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+		orderAppService.On("UpdateOrder", mock.Anything, &domain.UpdateOrderRequest{
+			Id:     order.Id,
+			Status: domain.OrderStatusConfirmed,
+		}).Return(order, nil)
+
+		req := httptest.NewRequest(fiber.MethodPut, path, strings.NewReader(`{"status": "confirmed"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+		resp, err := newTestApp(orderAppService).Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		orderAppService.AssertExpectations(t)
+	})
+
+	t.Run("unknown status is rejected", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+
+		req := httptest.NewRequest(fiber.MethodPut, path, strings.NewReader(`{"status": "shipped"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+		resp, err := newTestApp(orderAppService).Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		orderAppService.AssertNotCalled(t, "UpdateOrder", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetOrderStatusHistory(t *testing.T) {
+	orderId := uuid.New()
+	actorId := uuid.New()
+	changedAt := time.Now().UTC().Truncate(time.Second)
+
+	t.Run("transitions are returned oldest first", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+		orderAppService.On("OrderStatusHistory", mock.Anything, orderId).Return([]*domain.OrderStatusChange{
+			{
+				OrderId:    orderId,
+				FromStatus: domain.OrderStatusPending,
+				ToStatus:   domain.OrderStatusConfirmed,
+				ChangedAt:  changedAt,
+			},
+			{
+				OrderId:    orderId,
+				FromStatus: domain.OrderStatusConfirmed,
+				ToStatus:   domain.OrderStatusCompleted,
+				ActorId:    &actorId,
+				ChangedAt:  changedAt.Add(time.Minute),
+			},
+		}, nil)
+
+		resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders/"+orderId.String()+"/history", nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var body OrderStatusHistoryResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.Len(t, body.History, 2)
+		assert.Equal(t, "pending", body.History[0].FromStatus)
+		assert.Equal(t, "confirmed", body.History[0].ToStatus)
+		assert.Nil(t, body.History[0].ActorId)
+		assert.Equal(t, "confirmed", body.History[1].FromStatus)
+		assert.Equal(t, "completed", body.History[1].ToStatus)
+		require.NotNil(t, body.History[1].ActorId)
+		assert.Equal(t, actorId, *body.History[1].ActorId)
+	})
+
+	t.Run("nonexistent order", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+		orderAppService.On("OrderStatusHistory", mock.Anything, orderId).Return(nil, domain.ErrOrderNotFound)
+
+		resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders/"+orderId.String()+"/history", nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestBulkUpdateOrderStatus(t *testing.T) {
+	confirmedId, skippedId := uuid.New(), uuid.New()
+
+	bulkRequest := func(body string) *http.Request {
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/orders/bulk-status", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer secret")
+		return req
+	}
+
+	t.Run("mixed batch reports each order", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+		orderAppService.On("BulkUpdateStatus", mock.Anything, &domain.BulkUpdateOrderStatusRequest{
+			Ids:    []uuid.UUID{confirmedId, skippedId},
+			Status: domain.OrderStatusConfirmed,
+		}).Return([]*domain.OrderStatusUpdateResult{
+			{OrderId: confirmedId, Status: domain.OrderStatusConfirmed},
+			{
+				OrderId: skippedId,
+				Status:  domain.OrderStatusCompleted,
+				Err:     fmt.Errorf("%w: order cannot be confirmed in status completed", domain.ErrOrderValidation),
+			},
+		}, nil)
+
+		app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, orderAppService, nil)
+		resp, err := app.Test(bulkRequest(`{"ids": ["` + confirmedId.String() + `", "` + skippedId.String() + `"], "status": "confirmed"}`))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var body BulkUpdateOrderStatusResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		require.Len(t, body.Results, 2)
+		assert.Equal(t, &OrderStatusUpdateResult{OrderId: confirmedId, Updated: true, Status: "confirmed"}, body.Results[0])
+		assert.Equal(t, skippedId, body.Results[1].OrderId)
+		assert.False(t, body.Results[1].Updated)
+		assert.Equal(t, "completed", body.Results[1].Status)
+		assert.Contains(t, body.Results[1].Error, "cannot be confirmed")
+	})
+
+	t.Run("unknown status is rejected", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+
+		app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, orderAppService, nil)
+		resp, err := app.Test(bulkRequest(`{"ids": ["` + confirmedId.String() + `"], "status": "shipped"}`))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		orderAppService.AssertNotCalled(t, "BulkUpdateStatus", mock.Anything, mock.Anything)
+	})
+
+	t.Run("too many ids are rejected", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+
+		ids := make([]string, domain.MaxBulkOrderIds+1)
+		for i := range ids {
+			ids[i] = `"` + uuid.NewString() + `"`
+		}
+
+		app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, orderAppService, nil)
+		resp, err := app.Test(bulkRequest(`{"ids": [` + strings.Join(ids, ",") + `], "status": "confirmed"}`))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		var errResp ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		assert.Equal(t, domain.ErrRequestValidation.Code(), errResp.Code)
+		assert.Equal(t, "ids must contain at most 100 items", errResp.Fields["ids"])
+		orderAppService.AssertNotCalled(t, "BulkUpdateStatus", mock.Anything, mock.Anything)
+	})
+
+	t.Run("requires the admin token", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+
+		req := bulkRequest(`{"ids": ["` + confirmedId.String() + `"], "status": "confirmed"}`)
+		req.Header.Del(fiber.HeaderAuthorization)
+
+		app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, orderAppService, nil)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+func TestGetOrderStats(t *testing.T) {
+	productId := uuid.New()
+
+	statsRequest := func(query string) *http.Request {
+		req := httptest.NewRequest(fiber.MethodGet, "/api/v1/orders/stats"+query, nil)
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer secret")
+		return req
+	}
+
+	t.Run("reports every status", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+		stats := domain.NewOrderStatusStatsMap()
+		stats[domain.OrderStatusPending] = &domain.OrderStatusStats{Count: 2, TotalQuantity: 5}
+		orderAppService.On("StatusCounts", mock.Anything, &domain.GetOrdersRequest{
+			ProductIds: []uuid.UUID{productId},
+		}).Return(stats, nil)
+
+		app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, orderAppService, nil)
+		resp, err := app.Test(statsRequest("?product_id=" + productId.String()))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var body OrderStatsResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, map[string]*OrderStatusStats{
+			"pending":   {Count: 2, TotalQuantity: 5},
+			"confirmed": {},
+			"cancelled": {},
+			"completed": {},
+		}, body.Statuses)
+	})
+
+	t.Run("invalid filter is rejected", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+
+		app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, orderAppService, nil)
+		resp, err := app.Test(statsRequest("?user_id=nope"))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		orderAppService.AssertNotCalled(t, "StatusCounts", mock.Anything, mock.Anything)
+	})
+
+	t.Run("requires the admin token", func(t *testing.T) {
+		orderAppService := new(mockOrderAppService)
+
+		req := statsRequest("")
+		req.Header.Del(fiber.HeaderAuthorization)
+
+		app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, nil, orderAppService, nil)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+func TestGetOrder_ExpandUser(t *testing.T) {
+	factory := &domain.Factory{}
+	active := factory.User()
+	deletedAt := time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC)
+	deleted := factory.User()
+	deleted.DeletedAt = &deletedAt
+
+	getOrder := func(t *testing.T, user *domain.User, query string) (int, map[string]json.RawMessage, *mockOrderAppService) {
+		t.Helper()
+
+		order := factory.Order(user.Id, uuid.New())
+		orderAppService := new(mockOrderAppService)
+		orderAppService.On("Orders", mock.Anything, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}}).
+			Return([]*domain.Order{order}, nil)
+		orderAppService.On("OrderUser", mock.Anything, order).Return(user, nil)
+
+		resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders/"+order.Id.String()+query, nil))
+		require.NoError(t, err)
+
+		var body map[string]json.RawMessage
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return resp.StatusCode, body, orderAppService
+	}
+
+	t.Run("user is not embedded by default", func(t *testing.T) {
+		status, body, orderAppService := getOrder(t, active, "")
+		require.Equal(t, fiber.StatusOK, status)
+		assert.NotContains(t, body, "user")
+		assert.Contains(t, body, "user_id")
+		orderAppService.AssertNotCalled(t, "OrderUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("expand embeds the user", func(t *testing.T) {
+		status, body, _ := getOrder(t, active, "?expand=user")
+		require.Equal(t, fiber.StatusOK, status)
+
+		var user OrderUser
+		require.NoError(t, json.Unmarshal(body["user"], &user))
+		assert.Equal(t, OrderUser{
+			Id:        active.Id,
+			FirstName: active.FirstName,
+			LastName:  active.LastName,
+			Email:     active.Email,
+		}, user)
+	})
+
+	t.Run("soft-deleted user is embedded with its deletion time", func(t *testing.T) {
+		status, body, _ := getOrder(t, deleted, "?expand=user")
+		require.Equal(t, fiber.StatusOK, status)
+
+		var user OrderUser
+		require.NoError(t, json.Unmarshal(body["user"], &user))
+		assert.Equal(t, deleted.Id, user.Id)
+		require.NotNil(t, user.DeletedAt)
+		assert.True(t, deletedAt.Equal(*user.DeletedAt))
+	})
+
+	t.Run("unknown expand is rejected", func(t *testing.T) {
+		status, _, orderAppService := getOrder(t, active, "?expand=user,items")
+		assert.Equal(t, fiber.StatusBadRequest, status)
+		orderAppService.AssertNotCalled(t, "Orders", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetOrders_ExpandProducts(t *testing.T) {
+	factory := &domain.Factory{}
+	userId := uuid.New()
+
+	unchanged := factory.Product()
+	unchanged.Description, unchanged.Tags = "Test Product", []string{"tag1"}
+	modified := factory.ProductWithQuantity(0)
+	deletedAt := time.Now()
+	softDeleted := factory.Product()
+	softDeleted.Description, softDeleted.Tags, softDeleted.DeletedAt = "Test Product", []string{"tag1"}, &deletedAt
+	removedId := uuid.New()
+
+	orders := []*domain.Order{
+		factory.Order(userId, unchanged.Id, modified.Id),
+		factory.Order(userId, softDeleted.Id, removedId, unchanged.Id),
+	}
+
+	orderAppService := new(mockOrderAppService)
+	orderAppService.On("Orders", mock.Anything, mock.Anything).Return(orders, nil)
+	orderAppService.On("CountOrders", mock.Anything, mock.Anything).Return(len(orders), nil)
+	// the products of every order are loaded in one call
+	orderAppService.On("OrderProducts", mock.Anything, orders).Return(map[uuid.UUID]*domain.Product{
+		unchanged.Id:   unchanged,
+		modified.Id:    modified,
+		softDeleted.Id: softDeleted,
+	}, nil).Once()
+
+	resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders?expand=products", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var ordersResp OrdersResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&ordersResp))
+	require.Len(t, ordersResp.Orders, 2)
+	orderAppService.AssertExpectations(t)
+
+	quantity := func(q int) *int { return &q }
+	assert.Equal(t, &OrderItemProduct{Description: "Test Product", Quantity: quantity(100), Available: true}, ordersResp.Orders[0].Items[0].Product)
+	assert.Equal(t, &OrderItemProduct{Changed: true, Description: modified.Description, Quantity: quantity(0)}, ordersResp.Orders[0].Items[1].Product)
+	assert.Equal(t, &OrderItemProduct{Deleted: true, Description: "Test Product", Quantity: quantity(100)}, ordersResp.Orders[1].Items[0].Product)
+	assert.Equal(t, &OrderItemProduct{Deleted: true, Changed: true}, ordersResp.Orders[1].Items[1].Product)
+	assert.Equal(t, "Test Product", ordersResp.Orders[1].Items[1].ProductSnapshot.Description, "the snapshot is kept")
+}
+
+func TestGetOrder_ExpandProducts(t *testing.T) {
+	factory := &domain.Factory{}
+	product := factory.Product()
+	order := factory.Order(uuid.New(), product.Id)
+
+	getOrder := func(t *testing.T, query string) (*Order, *mockOrderAppService) {
+		t.Helper()
+
+		orderAppService := new(mockOrderAppService)
+		orderAppService.On("Orders", mock.Anything, &domain.GetOrdersRequest{Ids: []uuid.UUID{order.Id}}).
+			Return([]*domain.Order{order}, nil)
+		orderAppService.On("OrderProducts", mock.Anything, []*domain.Order{order}).
+			Return(map[uuid.UUID]*domain.Product{product.Id: product}, nil)
+
+		resp, err := newTestApp(orderAppService).Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/orders/"+order.Id.String()+query, nil))
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var body Order
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return &body, orderAppService
+	}
+
+	body, orderAppService := getOrder(t, "")
+	assert.Nil(t, body.Items[0].Product)
+	orderAppService.AssertNotCalled(t, "OrderProducts", mock.Anything, mock.Anything)
+
+	body, _ = getOrder(t, "?expand=products")
+	require.NotNil(t, body.Items[0].Product)
+	assert.Equal(t, product.Description, body.Items[0].Product.Description)
+	assert.True(t, body.Items[0].Product.Changed)
+}
+
+func TestCancelOrderItem(t *testing.T) {
+	factory := &domain.Factory{}
+	order := factory.Order(uuid.New(), uuid.New())
+	missingItemId := uuid.New()
+
+	orderAppService := new(mockOrderAppService)
+	orderAppService.On("CancelItem", mock.Anything, order.Id, order.Items[0].Id).Return(order, nil)
+	orderAppService.On("CancelItem", mock.Anything, order.Id, missingItemId).Return(nil, domain.ErrOrderItemNotFound)
+	app := newTestApp(orderAppService)
+
+	cancel := func(itemId string) *http.Response {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/orders/"+order.Id.String()+"/items/"+itemId+"/cancel", nil))
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := cancel(order.Items[0].Id.String())
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var body Order
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, order.Id, body.Id)
+
+	resp = cancel(missingItemId.String())
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, "ORDER_ITEM_NOT_FOUND", errResp.Code)
+
+	assert.Equal(t, fiber.StatusBadRequest, cancel("not-a-uuid").StatusCode)
+}
+
+func TestUpdateOrderItems_RunsInRequestTransaction(t *testing.T) {
+	factory := &domain.Factory{}
+	order := factory.Order(uuid.New(), uuid.New())
+	body := `{"items": [{"product_id": "` + uuid.NewString() + `", "quantity": 2}]}`
+
+	inTransaction := mock.MatchedBy(func(ctx context.Context) bool {
+		_, ok := domain.TxStoragesFromContext(ctx)
+		return ok
+	})
+	orderAppService := new(mockOrderAppService)
+	orderAppService.On("UpdateOrderItems", inTransaction, mock.Anything).Return(order, nil).Once()
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, &stagingUnitOfWork{}, nil, nil, orderAppService, nil)
+	req := httptest.NewRequest(fiber.MethodPut, "/api/v1/orders/"+order.Id.String()+"/items", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	orderAppService.AssertExpectations(t)
+}
+
+func TestValidateOrder(t *testing.T) {
+	factory := &domain.Factory{}
+	userId, productId := uuid.New(), uuid.New()
+	body := `{"user_id": "` + userId.String() + `", "items": [{"product_id": "` + productId.String() + `", "quantity": 3}]}`
+
+	validateRequest := func() *http.Request {
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/orders/validate", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return req
+	}
+	isRequest := mock.MatchedBy(func(req *domain.CreateOrderRequest) bool {
+		return req.UserId == userId && len(req.Items) == 1 && req.Items[0].Quantity == 3
+	})
+
+	t.Run("valid order is returned without being created", func(t *testing.T) {
+		order := factory.Order(userId, productId)
+		order.Id = uuid.Nil
+		orderAppService := new(mockOrderAppService)
+		orderAppService.On("ValidateOrder", mock.Anything, isRequest).Return(order, nil).Once()
+
+		resp, err := newTestApp(orderAppService).Test(validateRequest())
+		require.NoError(t, err)
+		require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+		var result map[string]any
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		assert.Equal(t, userId.String(), result["user_id"])
+		assert.EqualValues(t, order.TotalQuantity, result["total_quantity"])
+		// nothing was stored, so there is no ID or timestamp to report
+		for _, field := range []string{"id", "status", "created_at", "updated_at"} {
+			assert.NotContains(t, result, field)
+		}
+		items := result["items"].([]any)
+		require.Len(t, items, len(order.Items))
+		for _, field := range []string{"id", "created_at"} {
+			assert.NotContains(t, items[0], field)
+		}
+
+		orderAppService.AssertExpectations(t)
+		orderAppService.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+	})
+
+	t.Run("insufficient stock is reported like on creation", func(t *testing.T) {
+		stockErr := &domain.InsufficientStockError{}
+		stockErr.Add(productId, 3, 1)
+		orderAppService := new(mockOrderAppService)
+		orderAppService.On("ValidateOrder", mock.Anything, isRequest).Return(nil, stockErr.Err()).Once()
+
+		resp, err := newTestApp(orderAppService).Test(validateRequest())
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		var errResp ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		assert.Equal(t, "INSUFFICIENT_STOCK", errResp.Code)
+		require.Len(t, errResp.Shortages, 1)
+		assert.Equal(t, 1, errResp.Shortages[0].Available)
+	})
+}
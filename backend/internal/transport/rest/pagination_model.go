@@ -1,9 +1,15 @@
 package rest
 
 import (
+	"fmt"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+
+	"mts/internal/domain"
 )
 
 // Pagination represents pagination information
@@ -28,16 +34,39 @@ type Pagination struct {
 	// @Description Total number of pages
 	// @Example 10
 	TotalPages int `json:"total_pages" example:"10"`
+
+	// Next cursor
+	// @Description Opaque cursor for the next page, omitted on the last page
+	// @Example "AAYjZ0q9bWDtF0tTq6yNQb6Jt3Xo2FHy"
+	NextCursor string `json:"next_cursor,omitempty" example:"AAYjZ0q9bWDtF0tTq6yNQb6Jt3Xo2FHy"`
+
+	// First page link
+	// @Description Link to the first page
+	// @Example "/api/v1/products?page=1&size=10"
+	First string `json:"first,omitempty" example:"/api/v1/products?page=1&size=10"`
+
+	// Previous page link
+	// @Description Link to the previous page, omitted on the first page
+	// @Example "/api/v1/products?page=1&size=10"
+	Prev string `json:"prev,omitempty" example:"/api/v1/products?page=1&size=10"`
+
+	// Next page link
+	// @Description Link to the next page, omitted on the last page
+	// @Example "/api/v1/products?page=3&size=10"
+	Next string `json:"next,omitempty" example:"/api/v1/products?page=3&size=10"`
+
+	// Last page link
+	// @Description Link to the last page
+	// @Example "/api/v1/products?page=10&size=10"
+	Last string `json:"last,omitempty" example:"/api/v1/products?page=10&size=10"`
 } // @name Pagination
 
 func (p *Pagination) Limit() int {
+	bounds := domain.CurrentConfig().PageSize
 	if p.Size <= 0 {
-		return 10
+		return bounds.Default
 	}
-	if p.Size > 100 {
-		return 100
-	}
-	return p.Size
+	return min(p.Size, bounds.Max)
 }
 
 func (p *Pagination) Offset() int {
@@ -47,9 +76,12 @@ func (p *Pagination) Offset() int {
 	return (p.Page - 1) * p.Limit()
 }
 
-func NewPaginationFromRequest(c fiber.Ctx) *Pagination {
+// NewPaginationFromRequest reads page and size, rejecting a size above the configured maximum
+// rather than silently returning fewer rows than asked for
+func NewPaginationFromRequest(c fiber.Ctx) (*Pagination, error) {
+	bounds := domain.CurrentConfig().PageSize
 	page := 1
-	size := 10
+	size := bounds.Default
 
 	if pageStr := c.Query("page"); pageStr != "" {
 		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
@@ -58,7 +90,10 @@ func NewPaginationFromRequest(c fiber.Ctx) *Pagination {
 	}
 
 	if sizeStr := c.Query("size"); sizeStr != "" {
-		if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 && s <= 100 {
+		if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 {
+			if s > bounds.Max {
+				return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("invalid size, must be at most %d", bounds.Max))
+			}
 			size = s
 		}
 	}
@@ -66,6 +101,43 @@ func NewPaginationFromRequest(c fiber.Ctx) *Pagination {
 	return &Pagination{
 		Page: page,
 		Size: size,
+	}, nil
+}
+
+// cursorFromRequest parses the optional keyset cursor, nil when the client pages by offset
+func cursorFromRequest(c fiber.Ctx) (*domain.Cursor, error) {
+	cursorStr := c.Query("cursor")
+	if cursorStr == "" {
+		return nil, nil
+	}
+
+	cursor, err := domain.ParseCursor(cursorStr)
+	if err != nil {
+		return nil, withStatus(fiber.StatusBadRequest, err)
+	}
+
+	return cursor, nil
+}
+
+// sortFromRequest parses the optional sort and order parameters, parse checks sort against the list's columns
+func sortFromRequest[S ~string](c fiber.Ctx, parse func(string) (S, error)) (S, domain.SortOrder, error) {
+	sort, err := parse(c.Query("sort"))
+	if err != nil {
+		return "", "", withStatus(fiber.StatusBadRequest, err)
+	}
+
+	order, err := domain.ParseSortOrder(c.Query("order"))
+	if err != nil {
+		return "", "", withStatus(fiber.StatusBadRequest, err)
+	}
+
+	return sort, order, nil
+}
+
+// SetNextCursor points the next page past the last row, if the page is full and more rows may follow
+func (p *Pagination) SetNextCursor(pageLen int, createdAt time.Time, id uuid.UUID) {
+	if pageLen > 0 && pageLen == p.Limit() {
+		p.NextCursor = domain.NewCursor(createdAt, id).Encode()
 	}
 }
 
@@ -76,3 +148,28 @@ func (p *Pagination) CalculateTotalPages() {
 	}
 	p.TotalPages = (p.Total + p.Size - 1) / p.Size
 }
+
+// SetLinks fills the navigation links from the request path and raw query, keeping the
+// filters of the current request; call it after CalculateTotalPages
+func (p *Pagination) SetLinks(path, rawQuery string) {
+	query, _ := url.ParseQuery(rawQuery)
+	// links page by offset, a cursor would override the page number
+	query.Del("cursor")
+	query.Set("size", strconv.Itoa(p.Limit()))
+
+	link := func(page int) string {
+		query.Set("page", strconv.Itoa(page))
+		return path + "?" + query.Encode()
+	}
+
+	lastPage := max(p.TotalPages, 1)
+
+	p.First = link(1)
+	p.Last = link(lastPage)
+	if p.Page > 1 {
+		p.Prev = link(min(p.Page-1, lastPage))
+	}
+	if p.Page < lastPage {
+		p.Next = link(p.Page + 1)
+	}
+}
@@ -0,0 +1,118 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/domain"
+)
+
+func TestPagination_SetLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     int
+		rawQuery string
+		expected Pagination
+	}{
+		{
+			name:     "first page has no prev",
+			page:     1,
+			rawQuery: "size=10&available=true",
+			expected: Pagination{
+				First: "/api/v1/products?available=true&page=1&size=10",
+				Next:  "/api/v1/products?available=true&page=2&size=10",
+				Last:  "/api/v1/products?available=true&page=3&size=10",
+			},
+		},
+		{
+			name:     "middle page has both",
+			page:     2,
+			rawQuery: "page=2&size=10&cursor=abc",
+			expected: Pagination{
+				First: "/api/v1/products?page=1&size=10",
+				Prev:  "/api/v1/products?page=1&size=10",
+				Next:  "/api/v1/products?page=3&size=10",
+				Last:  "/api/v1/products?page=3&size=10",
+			},
+		},
+		{
+			name:     "last page has no next",
+			page:     3,
+			rawQuery: "page=3",
+			expected: Pagination{
+				First: "/api/v1/products?page=1&size=10",
+				Prev:  "/api/v1/products?page=2&size=10",
+				Last:  "/api/v1/products?page=3&size=10",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pagination := &Pagination{Page: tt.page, Size: 10, Total: 25}
+			pagination.CalculateTotalPages()
+			pagination.SetLinks("/api/v1/products", tt.rawQuery)
+
+			assert.Equal(t, tt.expected.First, pagination.First)
+			assert.Equal(t, tt.expected.Prev, pagination.Prev)
+			assert.Equal(t, tt.expected.Next, pagination.Next)
+			assert.Equal(t, tt.expected.Last, pagination.Last)
+		})
+	}
+}
+
+func TestNewPaginationFromRequest_Size(t *testing.T) {
+	previous := domain.CurrentConfig()
+	domainConfig := previous
+	domainConfig.PageSize = domain.PageSize{Default: 20, Max: 50}
+	domain.Configure(domainConfig)
+	t.Cleanup(func() { domain.Configure(previous) })
+
+	app := fiber.New(fiber.Config{ErrorHandler: errorHandler})
+	app.Get("/", func(c fiber.Ctx) error {
+		pagination, err := NewPaginationFromRequest(c)
+		if err != nil {
+			return err
+		}
+		return c.JSON(fiber.Map{"size": pagination.Size, "limit": pagination.Limit()})
+	})
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedLimit  int
+	}{
+		{name: "default size", query: "", expectedStatus: fiber.StatusOK, expectedLimit: 20},
+		{name: "max size", query: "?size=50", expectedStatus: fiber.StatusOK, expectedLimit: 50},
+		{name: "over max size", query: "?size=51", expectedStatus: fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/"+tt.query, nil))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+
+			if tt.expectedStatus != fiber.StatusOK {
+				var errResp ErrorResponse
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+				assert.Equal(t, "invalid size, must be at most 50", errResp.Message)
+				return
+			}
+
+			var body struct {
+				Size  int `json:"size"`
+				Limit int `json:"limit"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tt.expectedLimit, body.Size)
+			assert.Equal(t, tt.expectedLimit, body.Limit)
+		})
+	}
+}
@@ -1,14 +1,30 @@
 package rest
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog"
 
 	"mts/internal/domain"
 )
 
+const (
+	// importBatchSize is the number of imported lines stored in one transaction
+	importBatchSize = 100
+	// maxImportLineSize bounds a single JSON line of an import
+	maxImportLineSize = 1024 * 1024
+)
+
 type productHandler struct {
 	productAppService domain.ProductAppService
 }
@@ -28,26 +44,63 @@ func newProductHandler(productAppService domain.ProductAppService) *productHandl
 // @Param request body CreateProductRequest true "Product creation data"
 // @Success 201 {object} Product "Product created successfully"
 // @Failure 400 {object} ErrorResponse "Bad request - validation failed"
+// @Failure 409 {object} ErrorResponse "Conflict - a product with the same ID or description already exists"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/products [post]
 func (h *productHandler) createProduct(c fiber.Ctx) error {
 	var req CreateProductRequest
-	if err := c.Bind().JSON(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	if err := bindJSON(c, &req); err != nil {
+		return err
 	}
 
 	product, err := h.productAppService.CreateProduct(c.Context(), req.ToDomain())
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if errors.Is(err, domain.ErrProductValidation) {
-			status = fiber.StatusBadRequest
+		switch {
+		case errors.Is(err, domain.ErrProductValidation):
+			return badRequest(err)
+		case errors.Is(err, domain.ErrProductExists):
+			return withStatus(fiber.StatusConflict, err)
 		}
-		return fiber.NewError(status, err.Error())
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(NewProduct(product))
 }
 
+// upsertProduct returns the product with the given description, creating it when there is none
+// @Summary Get or create product by description
+// @Description Find the product with exactly this description or create it from the request. Tags, quantity and price are only used when the product is created, an existing product is returned unchanged
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Param request body CreateProductRequest true "Product to find or create"
+// @Success 200 {object} UpsertProductResponse "Existing product"
+// @Success 201 {object} UpsertProductResponse "Product created"
+// @Failure 400 {object} ErrorResponse "Bad request - validation failed"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/products/upsert [post]
+func (h *productHandler) upsertProduct(c fiber.Ctx) error {
+	var req CreateProductRequest
+	if err := bindJSON(c, &req); err != nil {
+		return err
+	}
+
+	product, created, err := h.productAppService.UpsertProduct(c.Context(), req.ToDomain())
+	if err != nil {
+		if errors.Is(err, domain.ErrProductValidation) {
+			return badRequest(err)
+		}
+		return err
+	}
+
+	status := fiber.StatusOK
+	if created {
+		status = fiber.StatusCreated
+	}
+
+	return c.Status(status).JSON(UpsertProductResponse{Product: NewProduct(product), Created: created})
+}
+
 // getProducts retrieves a paginated list of products
 // @Summary Get products list
 // @Description Retrieve a paginated list of all products in the system
@@ -55,30 +108,112 @@ func (h *productHandler) createProduct(c fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number for pagination" default(1) minimum(1)
-// @Param size query int false "Number of items per page" default(10) minimum(1) maximum(100)
+// @Param size query int false "Number of items per page, service.pagination.default_size (10 unless configured) when omitted; more than service.pagination.max_size (100 unless configured) is rejected with 400" minimum(1)
+// @Param cursor query string false "Keyset cursor from pagination.next_cursor, takes precedence over page"
+// @Param available query bool false "Only products that are in stock (true) or out of stock (false)"
+// @Param max_quantity query int false "Only products with quantity less than or equal to this value" minimum(0)
+// @Param min_price query int false "Only products priced at or above this value, in minor currency units" minimum(0)
+// @Param max_price query int false "Only products priced at or below this value, in minor currency units" minimum(0)
+// @Param q query string false "Words of the description or tags (full-text, stemmed) or a case-insensitive part of the description"
+// @Param sort query string false "Column to sort by, relevance requires q and is the default with it" Enums(created_at, price, relevance) default(created_at)
+// @Param order query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Param ids query string false "Comma-separated product IDs to fetch at once, at most the maximum page size; the other parameters are ignored and missing_ids lists the IDs not found"
+// @Param If-None-Match header string false "ETag of a previously received response"
 // @Success 200 {object} ProductsResponse "Products retrieved successfully"
-// @Failure 400 {object} ErrorResponse "Bad request - invalid pagination parameters"
+// @Header 200 {string} ETag "Hash of the response body"
+// @Header 200 {string} Cache-Control "How long the response may be reused"
+// @Success 304 "Not modified - the If-None-Match ETag is still current"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid pagination parameters, cursor, filters, sort or IDs"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/products [get]
 func (h *productHandler) getProducts(c fiber.Ctx) error {
-	pagination := NewPaginationFromRequest(c)
+	if c.Query("ids") != "" {
+		return h.getProductsByIds(c)
+	}
 
-	products, err := h.productAppService.Products(c.Context(), &domain.GetProductsRequest{})
+	pagination, err := NewPaginationFromRequest(c)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	count, err := h.productAppService.CountProducts(c.Context(), &domain.GetProductsRequest{})
+	after, err := cursorFromRequest(c)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return err
+	}
+
+	req, err := productFiltersFromRequest(c)
+	if err != nil {
+		return err
+	}
+	if after != nil && !req.DefaultOrder() {
+		return fiber.NewError(fiber.StatusBadRequest, "cursor pagination is only supported with the default created_at desc order")
+	}
+	req.After = after
+	req.Limit = pagination.Limit()
+	req.Offset = pagination.Offset()
+
+	products, err := h.productAppService.Products(c.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	count, err := h.productAppService.CountProducts(c.Context(), &domain.GetProductsRequest{
+		Available:   req.Available,
+		MaxQuantity: req.MaxQuantity,
+		MinPrice:    req.MinPrice,
+		MaxPrice:    req.MaxPrice,
+		Search:      req.Search,
+	})
+	if err != nil {
+		return err
 	}
 
 	pagination.Total = count
 	pagination.CalculateTotalPages()
+	pagination.SetLinks(c.Path(), string(c.Request().URI().QueryString()))
+	if len(products) > 0 && req.DefaultOrder() {
+		last := products[len(products)-1]
+		pagination.SetNextCursor(len(products), last.CreatedAt, last.Id)
+	}
 
 	return c.JSON(NewProductsResponse(products, *pagination))
 }
 
+// getProductsByIds answers the ids form of getProducts: all requested products in a single page,
+// with the ids of those not found or deleted in missing_ids
+func (h *productHandler) getProductsByIds(c fiber.Ctx) error {
+	ids, err := parseUUIDList("ids", c.Query("ids"), domain.CurrentConfig().PageSize.Max)
+	if err != nil {
+		return err
+	}
+
+	products, err := h.productAppService.Products(c.Context(), &domain.GetProductsRequest{
+		Ids:   ids,
+		Limit: len(ids),
+	})
+	if err != nil {
+		return err
+	}
+
+	found := make(map[uuid.UUID]struct{}, len(products))
+	for _, product := range products {
+		found[product.Id] = struct{}{}
+	}
+	missing := make([]uuid.UUID, 0, len(ids)-len(found))
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	pagination := Pagination{Page: 1, Size: len(ids), Total: len(products)}
+	pagination.CalculateTotalPages()
+
+	resp := NewProductsResponse(products, pagination)
+	resp.MissingIds = missing
+	return c.JSON(resp)
+}
+
 // getProduct retrieves a specific product by ID
 // @Summary Get product by ID
 // @Description Retrieve detailed information about a specific product using its unique identifier
@@ -86,26 +221,30 @@ func (h *productHandler) getProducts(c fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param product_id path string true "Product unique identifier" format(uuid)
+// @Param If-None-Match header string false "ETag of a previously received response"
 // @Success 200 {object} Product "Product information retrieved successfully"
+// @Header 200 {string} ETag "Hash of the response body"
+// @Header 200 {string} Cache-Control "How long the response may be reused"
+// @Success 304 "Not modified - the If-None-Match ETag is still current"
 // @Failure 400 {object} ErrorResponse "Bad request - invalid product ID format"
 // @Failure 404 {object} ErrorResponse "Not found - product with specified ID does not exist"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/products/{product_id} [get]
 func (h *productHandler) getProduct(c fiber.Ctx) error {
-	productId, err := uuid.Parse(c.Params("product_id"))
+	productId, err := parseUUIDParam(c, "product_id")
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid product ID format")
+		return err
 	}
 
 	products, err := h.productAppService.Products(c.Context(), &domain.GetProductsRequest{
 		Ids: []uuid.UUID{productId},
 	})
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	if len(products) == 0 {
-		return fiber.NewError(fiber.StatusNotFound, domain.ErrProductNotFound.Error())
+		return withStatus(fiber.StatusNotFound, domain.ErrProductNotFound)
 	}
 
 	return c.JSON(NewProduct(products[0]))
@@ -122,31 +261,360 @@ func (h *productHandler) getProduct(c fiber.Ctx) error {
 // @Success 200 {object} Product "Product updated successfully"
 // @Failure 400 {object} ErrorResponse "Bad request - invalid product ID format or validation failed"
 // @Failure 404 {object} ErrorResponse "Not found - product with specified ID does not exist"
+// @Failure 409 {object} ErrorResponse "Conflict - another product has the new description, or the product is no longer at the given version"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/products/{product_id} [put]
 func (h *productHandler) updateProduct(c fiber.Ctx) error {
-	productId, err := uuid.Parse(c.Params("product_id"))
+	productId, err := parseUUIDParam(c, "product_id")
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid product ID format")
+		return err
 	}
 
 	var req UpdateProductRequest
-	if err := c.Bind().JSON(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	if err := bindJSON(c, &req); err != nil {
+		return err
 	}
 
 	updateReq := req.ToDomain(productId)
 
 	product, err := h.productAppService.UpdateProduct(c.Context(), updateReq)
 	if err != nil {
-		status := fiber.StatusInternalServerError
+		switch {
+		case errors.Is(err, domain.ErrProductNotFound):
+			return withStatus(fiber.StatusNotFound, err)
+		case errors.Is(err, domain.ErrProductValidation), errors.Is(err, domain.ErrInsufficientStock):
+			return withStatus(fiber.StatusBadRequest, err)
+		case errors.Is(err, domain.ErrProductExists), errors.Is(err, domain.ErrVersionConflict):
+			return withStatus(fiber.StatusConflict, err)
+		}
+		return err
+	}
+
+	return c.JSON(NewProduct(product))
+}
+
+// restockProduct adds stock to a product
+// @Summary Restock product
+// @Description Atomically add stock to a product, making an out-of-stock product available again
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Param product_id path string true "Product unique identifier" format(uuid)
+// @Param request body RestockProductRequest true "Quantity to add"
+// @Success 200 {object} Product "Product restocked successfully"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid product ID format or non-positive quantity"
+// @Failure 404 {object} ErrorResponse "Not found - product with specified ID does not exist"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/products/{product_id}/restock [post]
+func (h *productHandler) restockProduct(c fiber.Ctx) error {
+	productId, err := parseUUIDParam(c, "product_id")
+	if err != nil {
+		return err
+	}
+
+	var req RestockProductRequest
+	if err := bindJSON(c, &req); err != nil {
+		return err
+	}
+
+	product, err := h.productAppService.RestockProduct(c.Context(), req.ToDomain(productId))
+	if err != nil {
 		if errors.Is(err, domain.ErrProductNotFound) {
-			status = fiber.StatusNotFound
-		} else if errors.Is(err, domain.ErrProductValidation) {
-			status = fiber.StatusBadRequest
+			return withStatus(fiber.StatusNotFound, err)
 		}
-		return fiber.NewError(status, err.Error())
+		return err
 	}
 
 	return c.JSON(NewProduct(product))
 }
+
+// deleteProduct soft-deletes a product
+// @Summary Delete product
+// @Description Soft-delete a product (admin only); it disappears from the catalog and can no longer be ordered, existing orders keep their item snapshots
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Security BearerAuth
+// @Param product_id path string true "Product unique identifier" format(uuid)
+// @Success 204 "Product deleted successfully"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid product ID format"
+// @Failure 401 {object} ErrorResponse "Unauthorized - admin token or access token is missing"
+// @Failure 403 {object} ErrorResponse "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role"
+// @Failure 404 {object} ErrorResponse "Not found - product with specified ID does not exist"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/products/{product_id} [delete]
+func (h *productHandler) deleteProduct(c fiber.Ctx) error {
+	productId, err := parseUUIDParam(c, "product_id")
+	if err != nil {
+		return err
+	}
+
+	if err = h.productAppService.DeleteProduct(c.Context(), productId); err != nil {
+		if errors.Is(err, domain.ErrProductNotFound) {
+			return withStatus(fiber.StatusNotFound, err)
+		}
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// getProductTags lists the distinct product tags with their product counts
+// @Summary Get product tags
+// @Description List the distinct tags of the catalog with the number of products carrying each, most used first, for faceted browsing
+// @Tags Products
+// @Produce json
+// @Param available query bool false "Only count products that are in stock (true) or out of stock (false)"
+// @Param If-None-Match header string false "ETag of a previously received response"
+// @Success 200 {object} ProductTagsResponse "Tags retrieved successfully"
+// @Header 200 {string} ETag "Hash of the response body"
+// @Header 200 {string} Cache-Control "How long the response may be reused"
+// @Success 304 "Not modified - the If-None-Match ETag is still current"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid available filter"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/products/tags [get]
+func (h *productHandler) getProductTags(c fiber.Ctx) error {
+	available, err := availableFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	tags, err := h.productAppService.TagCounts(c.Context(), &domain.GetProductsRequest{Available: available})
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(NewProductTagsResponse(tags))
+}
+
+// exportProducts streams the products as CSV
+// @Summary Export products
+// @Description Stream the products matching the list filters as a CSV file, fetched in batches so large catalogs are not buffered. Text cells starting with =, +, -, @, a tab or a carriage return are prefixed with ' so spreadsheets show them as text
+// @Tags Products
+// @Produce text/csv
+// @Param available query bool false "Only products that are in stock (true) or out of stock (false)"
+// @Param max_quantity query int false "Only products with quantity less than or equal to this value" minimum(0)
+// @Param min_price query int false "Only products priced at or above this value, in minor currency units" minimum(0)
+// @Param max_price query int false "Only products priced at or below this value, in minor currency units" minimum(0)
+// @Param q query string false "Words of the description or tags (full-text, stemmed) or a case-insensitive part of the description"
+// @Param sort query string false "Column to sort by, relevance requires q and is the default with it" Enums(created_at, price, relevance) default(created_at)
+// @Param order query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Success 200 {string} string "CSV with the columns id, description, tags, quantity, price, available, created_at"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid filters or sort"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/products/export [get]
+func (h *productHandler) exportProducts(c fiber.Ctx) error {
+	filters, err := productFiltersFromRequest(c)
+	if err != nil {
+		return err
+	}
+
+	batchSize := domain.CurrentConfig().PageSize.Max
+	offset := 0
+	nextBatch := func(ctx context.Context, last *domain.Product) ([]*domain.Product, error) {
+		req := *filters
+		req.Limit = batchSize
+		// only the default order can be paged by cursor, the others fall back to offsets
+		if last != nil && req.DefaultOrder() {
+			req.After = domain.NewCursor(last.CreatedAt, last.Id)
+		} else {
+			req.Offset = offset
+		}
+		offset += batchSize
+		return h.productAppService.Products(ctx, &req)
+	}
+
+	// the first batch is fetched before streaming, so a failing query still gets an error status
+	products, err := nextBatch(c.Context(), nil)
+	if err != nil {
+		return err
+	}
+
+	// the body is written after the handler returns, when the request context is already done
+	ctx := context.WithoutCancel(c.Context())
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="products.csv"`)
+
+	return sendStream(c, func(w *bufio.Writer) {
+		records := csv.NewWriter(w)
+		_ = records.Write(productCsvHeader)
+
+		for {
+			for _, product := range products {
+				_ = records.Write(productCsvRecord(product))
+			}
+			records.Flush()
+			if err := records.Error(); err != nil {
+				zerolog.Ctx(ctx).Error().Err(err).Msg("failed to write products export")
+				return
+			}
+			if err := w.Flush(); err != nil {
+				// the client went away
+				return
+			}
+
+			if len(products) < batchSize {
+				return
+			}
+
+			products, err = nextBatch(ctx, products[len(products)-1])
+			if err != nil {
+				// the status is already sent, the client sees a truncated file
+				zerolog.Ctx(ctx).Error().Err(err).Msg("failed to fetch products for export")
+				return
+			}
+		}
+	})
+}
+
+// importProducts creates products from a JSON-lines body
+// @Summary Import products
+// @Description Create products from newline-delimited JSON, one product object per line. Lines are read as a stream and stored in batches of 100, each batch in its own transaction; invalid lines are reported and do not stop the rest of the file. A line whose description is already taken by a product that is not deleted, in the catalog or earlier in the file, fails as a conflict instead of updating that product
+// @Tags Products
+// @Accept application/x-ndjson
+// @Produce json
+// @Param request body string true "One CreateProductRequest object per line"
+// @Success 200 {object} ImportProductsResponse "Per-line import results"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/products/import [post]
+func (h *productHandler) importProducts(c fiber.Ctx) error {
+	// lines are decoded one at a time, reading straight from the connection when the
+	// server streams request bodies and from the buffered body, bounded by the body limit, otherwise
+	body := c.Request().BodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+
+	resp := &ImportProductsResponse{Results: []*ImportProductResult{}}
+	var batch []*domain.CreateProductRequest
+	var batchLines []*ImportProductResult
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		for i, result := range h.productAppService.ImportProducts(c.Context(), batch) {
+			batchLines[i].setOutcome(result)
+		}
+		batch, batchLines = batch[:0], batchLines[:0]
+	}
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		result := &ImportProductResult{Line: line}
+		resp.Results = append(resp.Results, result)
+
+		var req CreateProductRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			result.Error = "invalid JSON: " + err.Error()
+			continue
+		}
+
+		batch = append(batch, req.ToDomain())
+		batchLines = append(batchLines, result)
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		// the rest of the body cannot be split into lines, the lines before it are already imported
+		line++
+		if errors.Is(err, bufio.ErrTooLong) {
+			resp.Results = append(resp.Results, &ImportProductResult{
+				Line:  line,
+				Error: fmt.Sprintf("line exceeds %d bytes, import stopped", maxImportLineSize),
+			})
+		} else {
+			return err
+		}
+	}
+
+	for _, result := range resp.Results {
+		if result.Id != nil {
+			resp.Created++
+		} else {
+			resp.Failed++
+		}
+	}
+
+	return c.JSON(resp)
+}
+
+// productFiltersFromRequest parses the filters shared by the list and export endpoints
+func productFiltersFromRequest(c fiber.Ctx) (*domain.GetProductsRequest, error) {
+	req := &domain.GetProductsRequest{
+		Search: strings.TrimSpace(c.Query("q")),
+	}
+
+	available, err := availableFromRequest(c)
+	if err != nil {
+		return nil, err
+	}
+	req.Available = available
+
+	// Parse optional max_quantity filter
+	if maxQuantityStr := c.Query("max_quantity"); maxQuantityStr != "" {
+		maxQuantity, err := strconv.Atoi(maxQuantityStr)
+		if err != nil || maxQuantity < 0 {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "invalid max_quantity, must be a non-negative integer")
+		}
+		req.MaxQuantity = &maxQuantity
+	}
+
+	// Parse optional price range, both ends inclusive
+	for _, bound := range []struct {
+		param  string
+		target **int
+	}{{"min_price", &req.MinPrice}, {"max_price", &req.MaxPrice}} {
+		if priceStr := c.Query(bound.param); priceStr != "" {
+			price, err := strconv.Atoi(priceStr)
+			if err != nil || price < 0 {
+				return nil, fiber.NewError(fiber.StatusBadRequest, "invalid "+bound.param+", must be a non-negative integer")
+			}
+			*bound.target = &price
+		}
+	}
+	if req.MinPrice != nil && req.MaxPrice != nil && *req.MinPrice > *req.MaxPrice {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "min_price cannot be greater than max_price")
+	}
+
+	if req.Sort, req.Order, err = sortFromRequest(c, domain.ParseProductSort); err != nil {
+		return nil, err
+	}
+	// searches list the best matches first unless another order is asked for
+	if req.Search != "" && c.Query("sort") == "" {
+		req.Sort = domain.ProductSortRelevance
+	}
+	if req.Sort == domain.ProductSortRelevance && req.Search == "" {
+		return nil, withStatus(fiber.StatusBadRequest, fmt.Errorf("%w: relevance sort requires q", domain.ErrInvalidSort))
+	}
+
+	return req, nil
+}
+
+// availableFromRequest parses the optional available filter, nil when the stock does not matter
+func availableFromRequest(c fiber.Ctx) (*bool, error) {
+	availableStr := c.Query("available")
+	if availableStr == "" {
+		return nil, nil
+	}
+
+	available, err := strconv.ParseBool(availableStr)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid available, must be true or false")
+	}
+	return &available, nil
+}
@@ -1,6 +1,9 @@
 package rest
 
 import (
+	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -31,11 +34,21 @@ type Product struct {
 	// @Example 100
 	Quantity int `json:"quantity" example:"100"`
 
+	// Price
+	// @Description Price in minor currency units
+	// @Example 49990
+	Price int `json:"price" example:"49990"`
+
 	// Available
 	// @Description Whether the product is available (quantity > 0)
 	// @Example true
 	Available bool `json:"available" example:"true"`
 
+	// Version
+	// @Description Grows with every change; send it back with an update to reject the update if the product changed meanwhile
+	// @Example 3
+	Version int `json:"version" example:"3"`
+
 	// Created at
 	// @Description When the product was created
 	// @Example 2024-01-15T10:30:00Z
@@ -64,6 +77,11 @@ type CreateProductRequest struct {
 	// @Description Initial quantity in stock
 	// @Example 100
 	Quantity int `json:"quantity" binding:"required" validate:"gte=0" example:"100"`
+
+	// Price
+	// @Description Price in minor currency units
+	// @Example 49990
+	Price int `json:"price" validate:"gte=0" example:"49990"`
 } // @name CreateProductRequest
 
 func (req *CreateProductRequest) ToDomain() *domain.CreateProductRequest {
@@ -71,6 +89,7 @@ func (req *CreateProductRequest) ToDomain() *domain.CreateProductRequest {
 		Description: req.Description,
 		Tags:        req.Tags,
 		Quantity:    req.Quantity,
+		Price:       req.Price,
 	}
 }
 
@@ -91,6 +110,16 @@ type UpdateProductRequest struct {
 	// @Description Quantity in stock (optional)
 	// @Example 150
 	Quantity *int `json:"quantity,omitempty" validate:"omitempty,gte=0" example:"150"`
+
+	// Price
+	// @Description Price in minor currency units (optional)
+	// @Example 45990
+	Price *int `json:"price,omitempty" validate:"omitempty,gte=0" example:"45990"`
+
+	// Version
+	// @Description Version of the product the changes are based on (optional); a product changed since is not updated and 409 VERSION_CONFLICT is returned
+	// @Example 3
+	Version *int `json:"version,omitempty" validate:"omitempty,gte=1" example:"3"`
 } // @name UpdateProductRequest
 
 func (req *UpdateProductRequest) ToDomain(productId uuid.UUID) *domain.UpdateProductRequest {
@@ -99,6 +128,24 @@ func (req *UpdateProductRequest) ToDomain(productId uuid.UUID) *domain.UpdatePro
 		Description: req.Description,
 		Tags:        req.Tags,
 		Quantity:    req.Quantity,
+		Price:       req.Price,
+		Version:     req.Version,
+	}
+}
+
+// RestockProductRequest represents request to add stock to a product
+// @Description Request payload for restocking a product
+type RestockProductRequest struct {
+	// Quantity
+	// @Description Quantity to add to the stock (must be positive)
+	// @Example 50
+	Quantity int `json:"quantity" binding:"required" validate:"gt=0" example:"50"`
+} // @name RestockProductRequest
+
+func (req *RestockProductRequest) ToDomain(productId uuid.UUID) *domain.RestockProductRequest {
+	return &domain.RestockProductRequest{
+		Id:       productId,
+		Quantity: req.Quantity,
 	}
 }
 
@@ -112,15 +159,109 @@ type ProductsResponse struct {
 	// Pagination
 	// @Description Pagination information
 	Pagination *Pagination `json:"pagination"`
+
+	// Missing IDs
+	// @Description Requested IDs with no product, only set when fetching by ids
+	MissingIds []uuid.UUID `json:"missing_ids,omitempty"`
 } // @name ProductsResponse
 
+// ProductTagCount represents a tag and how many products carry it
+// @Description Product tag with its product count
+type ProductTagCount struct {
+	// Tag
+	// @Description Tag name
+	// @Example "electronics"
+	Tag string `json:"tag" example:"electronics"`
+
+	// Count
+	// @Description Number of products carrying the tag
+	// @Example 12
+	Count int `json:"count" example:"12"`
+} // @name ProductTagCount
+
+// ProductTagsResponse represents the distinct product tags
+// @Description Distinct product tags, most used first
+type ProductTagsResponse struct {
+	// Tags
+	// @Description Tags with their product counts
+	Tags []*ProductTagCount `json:"tags"`
+} // @name ProductTagsResponse
+
+// UpsertProductResponse represents the outcome of a get or create by description
+// @Description Product found or created by description
+type UpsertProductResponse struct {
+	// Product
+	// @Description Existing or newly created product
+	Product *Product `json:"product"`
+
+	// Created
+	// @Description Whether the product was created by this request
+	// @Example true
+	Created bool `json:"created" example:"true"`
+} // @name UpsertProductResponse
+
+// ImportProductResult represents the outcome of one imported line
+// @Description Result of importing a single line, either id or error is set
+type ImportProductResult struct {
+	// Line
+	// @Description Line number in the uploaded file, starting at 1
+	// @Example 1
+	Line int `json:"line" example:"1"`
+
+	// Product ID
+	// @Description ID of the created product
+	// @Example "123e4567-e89b-12d3-a456-426614174000"
+	Id *uuid.UUID `json:"id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+
+	// Error
+	// @Description Reason the line was not imported
+	// @Example "product validation failed: description is required"
+	Error string `json:"error,omitempty" example:"product validation failed: description is required"`
+} // @name ImportProductResult
+
+// ImportProductsResponse represents the result of a products import
+// @Description Per-line results of a products import
+type ImportProductsResponse struct {
+	// Created
+	// @Description Number of created products
+	// @Example 2
+	Created int `json:"created" example:"2"`
+
+	// Failed
+	// @Description Number of lines that were not imported
+	// @Example 1
+	Failed int `json:"failed" example:"1"`
+
+	// Results
+	// @Description Results in file order, blank lines are skipped
+	Results []*ImportProductResult `json:"results"`
+} // @name ImportProductsResponse
+
+func (r *ImportProductResult) setOutcome(result *domain.ProductImportResult) {
+	if result.Err != nil {
+		// validation failures and conflicts describe the line, anything else is an internal error
+		switch {
+		case errors.Is(result.Err, domain.ErrProductValidation):
+			r.Error = result.Err.Error()
+		case errors.Is(result.Err, domain.ErrProductExists):
+			r.Error = domain.ErrProductExists.Error() + ": the description is taken by another product"
+		default:
+			r.Error = "failed to store product"
+		}
+		return
+	}
+	r.Id = &result.Product.Id
+}
+
 func NewProduct(domainProduct *domain.Product) *Product {
 	return &Product{
 		Id:          domainProduct.Id,
 		Description: domainProduct.Description,
 		Tags:        domainProduct.Tags,
 		Quantity:    domainProduct.Quantity,
+		Price:       domainProduct.Price,
 		Available:   domainProduct.IsAvailable(),
+		Version:     domainProduct.Version,
 		CreatedAt:   domainProduct.CreatedAt,
 		UpdatedAt:   domainProduct.UpdatedAt,
 	}
@@ -137,3 +278,36 @@ func NewProductsResponse(domainProducts []*domain.Product, pagination Pagination
 		Pagination: &pagination,
 	}
 }
+
+func NewProductTagsResponse(domainTags []*domain.ProductTagCount) *ProductTagsResponse {
+	tags := make([]*ProductTagCount, 0, len(domainTags))
+	for _, tag := range domainTags {
+		tags = append(tags, &ProductTagCount{Tag: tag.Tag, Count: tag.Count})
+	}
+
+	return &ProductTagsResponse{Tags: tags}
+}
+
+var productCsvHeader = []string{"id", "description", "tags", "quantity", "price", "available", "created_at"}
+
+// productCsvRecord renders a product as an export row matching productCsvHeader
+func productCsvRecord(product *domain.Product) []string {
+	return []string{
+		product.Id.String(),
+		csvText(product.Description),
+		csvText(strings.Join(product.Tags, ",")),
+		strconv.Itoa(product.Quantity),
+		strconv.Itoa(product.Price),
+		strconv.FormatBool(product.IsAvailable()),
+		product.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// csvText keeps a spreadsheet from running user text as a formula: a cell starting with one of
+// = + - @, or a tab or carriage return, gets a leading ' so it is shown as text
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,597 @@
+package rest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/application"
+	"mts/internal/config"
+	"mts/internal/domain"
+	"mts/internal/repository/cache"
+)
+
+type mockProductAppService struct {
+	mock.Mock
+}
+
+func (m *mockProductAppService) CreateProduct(ctx context.Context, req *domain.CreateProductRequest) (*domain.Product, error) {
+	args := m.Called(ctx, req)
+	product, _ := args.Get(0).(*domain.Product)
+	return product, args.Error(1)
+}
+
+func (m *mockProductAppService) UpsertProduct(ctx context.Context, req *domain.CreateProductRequest) (*domain.Product, bool, error) {
+	args := m.Called(ctx, req)
+	product, _ := args.Get(0).(*domain.Product)
+	return product, args.Bool(1), args.Error(2)
+}
+
+func (m *mockProductAppService) UpdateProduct(ctx context.Context, req *domain.UpdateProductRequest) (*domain.Product, error) {
+	args := m.Called(ctx, req)
+	product, _ := args.Get(0).(*domain.Product)
+	return product, args.Error(1)
+}
+
+func (m *mockProductAppService) RestockProduct(ctx context.Context, req *domain.RestockProductRequest) (*domain.Product, error) {
+	args := m.Called(ctx, req)
+	product, _ := args.Get(0).(*domain.Product)
+	return product, args.Error(1)
+}
+
+func (m *mockProductAppService) ImportProducts(ctx context.Context, reqs []*domain.CreateProductRequest) []*domain.ProductImportResult {
+	args := m.Called(ctx, reqs)
+	results, _ := args.Get(0).([]*domain.ProductImportResult)
+	return results
+}
+
+func (m *mockProductAppService) Products(ctx context.Context, req *domain.GetProductsRequest) ([]*domain.Product, error) {
+	args := m.Called(ctx, req)
+	products, _ := args.Get(0).([]*domain.Product)
+	return products, args.Error(1)
+}
+
+func (m *mockProductAppService) CountProducts(ctx context.Context, req *domain.GetProductsRequest) (int, error) {
+	args := m.Called(ctx, req)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockProductAppService) TagCounts(ctx context.Context, req *domain.GetProductsRequest) ([]*domain.ProductTagCount, error) {
+	args := m.Called(ctx, req)
+	tags, _ := args.Get(0).([]*domain.ProductTagCount)
+	return tags, args.Error(1)
+}
+
+func (m *mockProductAppService) DeleteProduct(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func TestRestockProduct_NonPositiveQuantity(t *testing.T) {
+	// the request is rejected before the storage is reached
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, application.NewProductAppService(nil, nil), nil, nil)
+
+	for _, body := range []string{`{"quantity": 0}`, `{"quantity": -5}`, `{}`} {
+		t.Run(body, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, "/api/v1/products/"+uuid.NewString()+"/restock", strings.NewReader(body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+			var errResp ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			assert.Equal(t, "quantity must be positive", errResp.Fields["quantity"])
+		})
+	}
+}
+
+func TestGetProducts_AvailableFilter(t *testing.T) {
+	available := mock.MatchedBy(func(req *domain.GetProductsRequest) bool {
+		return req.Available != nil && *req.Available
+	})
+
+	productAppService := new(mockProductAppService)
+	productAppService.On("Products", mock.Anything, available).Return([]*domain.Product{}, nil).Once()
+	productAppService.On("CountProducts", mock.Anything, available).Return(0, nil).Once()
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/products?available=true", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/products?available=maybe", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	productAppService.AssertExpectations(t)
+}
+
+func TestExportProducts(t *testing.T) {
+	previous := domain.CurrentConfig()
+	domainConfig := previous
+	domainConfig.PageSize = domain.PageSize{Max: 2}
+	domain.Configure(domainConfig)
+	t.Cleanup(func() { domain.Configure(previous) })
+
+	createdAt := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	phone := &domain.Product{Id: uuid.New(), Description: "Phone", Tags: []string{"electronics", "mobile"}, Quantity: 5, Price: 49990, CreatedAt: createdAt}
+	cable := &domain.Product{Id: uuid.New(), Description: `USB "C" cable`, Quantity: 0, CreatedAt: createdAt}
+	charger := &domain.Product{Id: uuid.New(), Description: "=HYPERLINK(\"http://evil\")", Tags: []string{"@accessories", "-sale"}, Quantity: 1, CreatedAt: createdAt}
+
+	productAppService := new(mockProductAppService)
+	// the export pages through the catalog with the keyset cursor, keeping the filters
+	productAppService.On("Products", mock.Anything, mock.MatchedBy(func(req *domain.GetProductsRequest) bool {
+		return req.After == nil && req.Limit == 2 && *req.MaxQuantity == 5
+	})).Return([]*domain.Product{phone, cable}, nil).Once()
+	productAppService.On("Products", mock.Anything, mock.MatchedBy(func(req *domain.GetProductsRequest) bool {
+		return req.After != nil && req.After.Id == cable.Id && *req.MaxQuantity == 5
+	})).Return([]*domain.Product{charger}, nil).Once()
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/products/export?max_quantity=5", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/csv; charset=utf-8", resp.Header.Get(fiber.HeaderContentType))
+	assert.Equal(t, `attachment; filename="products.csv"`, resp.Header.Get(fiber.HeaderContentDisposition))
+
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"id", "description", "tags", "quantity", "price", "available", "created_at"},
+		{phone.Id.String(), "Phone", "electronics,mobile", "5", "49990", "true", "2024-01-15T10:30:00Z"},
+		{cable.Id.String(), `USB "C" cable`, "", "0", "0", "false", "2024-01-15T10:30:00Z"},
+		// text that a spreadsheet would run as a formula is exported as text
+		{charger.Id.String(), `'=HYPERLINK("http://evil")`, "'@accessories,-sale", "1", "0", "true", "2024-01-15T10:30:00Z"},
+	}, records)
+
+	productAppService.AssertExpectations(t)
+}
+
+func TestExportProducts_InvalidFilter(t *testing.T) {
+	productAppService := new(mockProductAppService)
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/products/export?max_quantity=-1", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+	productAppService.AssertNotCalled(t, "Products", mock.Anything, mock.Anything)
+}
+
+func TestImportProducts(t *testing.T) {
+	phone := &domain.Product{Id: uuid.New(), Description: "Phone", Quantity: 5}
+	cable := &domain.Product{Id: uuid.New(), Description: "Cable"}
+	validationErr := (&domain.CreateProductRequest{Quantity: -1}).Validate()
+
+	productAppService := new(mockProductAppService)
+	// lines that are not JSON never reach the service, the rest go in one batch
+	productAppService.On("ImportProducts", mock.Anything, []*domain.CreateProductRequest{
+		{Description: "Phone", Quantity: 5},
+		{Quantity: -1},
+		{Description: "Cable", Tags: []string{"accessories"}},
+	}).Return([]*domain.ProductImportResult{
+		{Product: phone},
+		{Err: validationErr},
+		{Product: cable},
+	}).Once()
+
+	body := strings.Join([]string{
+		`{"description": "Phone", "quantity": 5}`,
+		`{"description": `,
+		``,
+		`{"quantity": -1}`,
+		`{"description": "Cable", "tags": ["accessories"]}`,
+		`[1, 2]`,
+	}, "\n")
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/products/import", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, "application/x-ndjson")
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var result ImportProductsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, 2, result.Created)
+	assert.Equal(t, 3, result.Failed)
+	require.Len(t, result.Results, 5)
+
+	// blank lines are skipped but still counted
+	assert.Equal(t, []int{1, 2, 4, 5, 6}, []int{
+		result.Results[0].Line, result.Results[1].Line, result.Results[2].Line,
+		result.Results[3].Line, result.Results[4].Line,
+	})
+	assert.Equal(t, &phone.Id, result.Results[0].Id)
+	assert.Contains(t, result.Results[1].Error, "invalid JSON")
+	assert.Nil(t, result.Results[2].Id)
+	assert.Equal(t, validationErr.Error(), result.Results[2].Error)
+	assert.Equal(t, &cable.Id, result.Results[3].Id)
+	assert.Contains(t, result.Results[4].Error, "invalid JSON")
+
+	productAppService.AssertExpectations(t)
+}
+
+func TestImportProducts_StorageFailureIsNotLeaked(t *testing.T) {
+	productAppService := new(mockProductAppService)
+	productAppService.On("ImportProducts", mock.Anything, mock.Anything).Return([]*domain.ProductImportResult{
+		{Err: errors.New("pq: connection reset by peer")},
+	}).Once()
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/products/import",
+		strings.NewReader(`{"description": "Phone", "quantity": 5}`)))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var result ImportProductsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, "failed to store product", result.Results[0].Error)
+	assert.Equal(t, 1, result.Failed)
+}
+
+func TestImportProducts_TakenDescription(t *testing.T) {
+	productAppService := new(mockProductAppService)
+	productAppService.On("ImportProducts", mock.Anything, mock.Anything).Return([]*domain.ProductImportResult{
+		{Err: fmt.Errorf("%w: %w", domain.ErrProductExists, errors.New(`duplicate key value violates unique constraint "products_description_key"`))},
+	}).Once()
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/products/import",
+		strings.NewReader(`{"description": "Phone", "quantity": 5}`)))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var result ImportProductsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.Len(t, result.Results, 1)
+	assert.Equal(t, "product already exists: the description is taken by another product", result.Results[0].Error)
+	assert.Equal(t, 1, result.Failed)
+}
+
+func TestImportProducts_Batches(t *testing.T) {
+	productAppService := new(mockProductAppService)
+	for _, size := range []int{importBatchSize, 1} {
+		productAppService.On("ImportProducts", mock.Anything, mock.MatchedBy(func(reqs []*domain.CreateProductRequest) bool {
+			return len(reqs) == size
+		})).Return(func() []*domain.ProductImportResult {
+			results := make([]*domain.ProductImportResult, size)
+			for i := range results {
+				results[i] = &domain.ProductImportResult{Product: &domain.Product{Id: uuid.New()}}
+			}
+			return results
+		}()).Once()
+	}
+
+	lines := make([]string, importBatchSize+1)
+	for i := range lines {
+		lines[i] = `{"description": "Phone", "quantity": 1}`
+	}
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/products/import",
+		strings.NewReader(strings.Join(lines, "\n"))))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var result ImportProductsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, importBatchSize+1, result.Created)
+	assert.Zero(t, result.Failed)
+
+	productAppService.AssertExpectations(t)
+}
+
+func TestGetProducts_PriceFilterAndSort(t *testing.T) {
+	productAppService := new(mockProductAppService)
+	productAppService.On("Products", mock.Anything, mock.MatchedBy(func(req *domain.GetProductsRequest) bool {
+		return *req.MinPrice == 100 && *req.MaxPrice == 500 &&
+			req.Sort == domain.ProductSortPrice && req.Order == domain.SortOrderAsc
+	})).Return([]*domain.Product{{Id: uuid.New(), Description: "Phone", Price: 100}}, nil).Once()
+	// the total counts the same price range
+	productAppService.On("CountProducts", mock.Anything, mock.MatchedBy(func(req *domain.GetProductsRequest) bool {
+		return *req.MinPrice == 100 && *req.MaxPrice == 500
+	})).Return(1, nil).Once()
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet,
+		"/api/v1/products?min_price=100&max_price=500&sort=price&order=asc&size=1", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var result ProductsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.Len(t, result.Products, 1)
+	assert.Equal(t, 100, result.Products[0].Price)
+	// cursors only follow the default order
+	assert.Empty(t, result.Pagination.NextCursor)
+
+	productAppService.AssertExpectations(t)
+}
+
+func TestGetProducts_Search(t *testing.T) {
+	productAppService := new(mockProductAppService)
+	productAppService.On("Products", mock.Anything, mock.MatchedBy(func(req *domain.GetProductsRequest) bool {
+		// the best matches come first unless another order is asked for
+		return req.Search == "red phone" && req.Sort == domain.ProductSortRelevance
+	})).Return([]*domain.Product{{Id: uuid.New(), Description: "Red phone"}}, nil).Once()
+	// the total counts the same matches
+	productAppService.On("CountProducts", mock.Anything, mock.MatchedBy(func(req *domain.GetProductsRequest) bool {
+		return req.Search == "red phone"
+	})).Return(1, nil).Once()
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/products?q=+red+phone+", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var result ProductsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.Len(t, result.Products, 1)
+	assert.Equal(t, 1, result.Pagination.Total)
+
+	// relevance has nothing to rank without a search term
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/products?sort=relevance", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	productAppService.AssertExpectations(t)
+}
+
+func TestGetProducts_ByIds(t *testing.T) {
+	phone := &domain.Product{Id: uuid.New(), Description: "Phone"}
+	cable := &domain.Product{Id: uuid.New(), Description: "Cable"}
+	missing := uuid.New()
+
+	productAppService := new(mockProductAppService)
+	productAppService.On("Products", mock.Anything, mock.MatchedBy(func(req *domain.GetProductsRequest) bool {
+		// the duplicate phone id is only asked for once
+		return assert.ObjectsAreEqual([]uuid.UUID{phone.Id, missing, cable.Id}, req.Ids) && req.Limit == 3
+	})).Return([]*domain.Product{phone, cable}, nil).Once()
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet,
+		"/api/v1/products?ids="+phone.Id.String()+","+missing.String()+",+"+cable.Id.String()+","+phone.Id.String(), nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var result ProductsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	require.Len(t, result.Products, 2)
+	assert.Equal(t, phone.Id, result.Products[0].Id)
+	assert.Equal(t, cable.Id, result.Products[1].Id)
+	assert.Equal(t, []uuid.UUID{missing}, result.MissingIds)
+	assert.Equal(t, 2, result.Pagination.Total)
+	// lookups by ids are never counted
+	productAppService.AssertNotCalled(t, "CountProducts", mock.Anything, mock.Anything)
+
+	tooMany := make([]string, 0, 101)
+	for range 101 {
+		tooMany = append(tooMany, uuid.NewString())
+	}
+	for name, ids := range map[string]string{
+		"malformed id":  phone.Id.String() + ",nope",
+		"too many ids":  strings.Join(tooMany, ","),
+		"empty element": phone.Id.String() + ",",
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/products?ids="+ids, nil))
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+		})
+	}
+
+	productAppService.AssertExpectations(t)
+}
+
+func TestGetProductTags(t *testing.T) {
+	productAppService := new(mockProductAppService)
+	productAppService.On("TagCounts", mock.Anything, mock.MatchedBy(func(req *domain.GetProductsRequest) bool {
+		return req.Available != nil && *req.Available
+	})).Return([]*domain.ProductTagCount{{Tag: "electronics", Count: 2}, {Tag: "mobile", Count: 1}}, nil).Once()
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/products/tags?available=true", nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	var result ProductTagsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, []*ProductTagCount{{Tag: "electronics", Count: 2}, {Tag: "mobile", Count: 1}}, result.Tags)
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/products/tags?available=maybe", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	productAppService.AssertExpectations(t)
+}
+
+func TestGetProduct_ETag(t *testing.T) {
+	product := (&domain.Factory{}).Product()
+
+	productAppService := new(mockProductAppService)
+	productAppService.On("Products", mock.Anything, mock.Anything).Return([]*domain.Product{product}, nil)
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	target := "/api/v1/products/" + product.Id.String()
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, target, nil))
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+	tag := resp.Header.Get(fiber.HeaderETag)
+	require.NotEmpty(t, tag)
+	assert.Equal(t, "private, no-cache", resp.Header.Get(fiber.HeaderCacheControl))
+
+	// an unchanged product is not sent again
+	req := httptest.NewRequest(fiber.MethodGet, target, nil)
+	req.Header.Set(fiber.HeaderIfNoneMatch, tag)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotModified, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Empty(t, body)
+
+	// a changed one is
+	product.Quantity++
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	assert.NotEqual(t, tag, resp.Header.Get(fiber.HeaderETag))
+}
+
+func TestCreateProduct_Conflict(t *testing.T) {
+	productAppService := new(mockProductAppService)
+	productAppService.On("CreateProduct", mock.Anything, mock.Anything).
+		Return(nil, fmt.Errorf("%w: duplicate key", domain.ErrProductExists))
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/products", strings.NewReader(`{"description": "Phone", "quantity": 1}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, "PRODUCT_ALREADY_EXISTS", errResp.Code)
+}
+
+func TestUpdateProduct_VersionConflict(t *testing.T) {
+	id := uuid.New()
+	productAppService := new(mockProductAppService)
+	productAppService.On("UpdateProduct", mock.Anything, mock.MatchedBy(func(req *domain.UpdateProductRequest) bool {
+		return req.Id == id && req.Version != nil && *req.Version == 3
+	})).Return(nil, fmt.Errorf("%w: product is at version 4", domain.ErrVersionConflict))
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+	req := httptest.NewRequest(fiber.MethodPut, "/api/v1/products/"+id.String(), strings.NewReader(`{"quantity": 1, "version": 3}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusConflict, resp.StatusCode)
+
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, "VERSION_CONFLICT", errResp.Code)
+	productAppService.AssertExpectations(t)
+}
+
+func TestUpsertProduct(t *testing.T) {
+	phone := &domain.Product{Id: uuid.New(), Description: "Phone", Quantity: 5}
+	cable := &domain.Product{Id: uuid.New(), Description: "Cable"}
+
+	productAppService := new(mockProductAppService)
+	productAppService.On("UpsertProduct", mock.Anything, &domain.CreateProductRequest{Description: "Phone", Quantity: 1}).
+		Return(phone, false, nil)
+	productAppService.On("UpsertProduct", mock.Anything, &domain.CreateProductRequest{Description: "Cable", Tags: []string{"accessories"}}).
+		Return(cable, true, nil)
+
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		product *domain.Product
+		created bool
+	}{
+		{"existing description", `{"description": "Phone", "quantity": 1}`, fiber.StatusOK, phone, false},
+		{"new description", `{"description": "Cable", "tags": ["accessories"]}`, fiber.StatusCreated, cable, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, "/api/v1/products/upsert", strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+
+			var body UpsertProductResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tt.product.Id, body.Product.Id)
+			assert.Equal(t, tt.created, body.Created)
+		})
+	}
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/products/upsert", strings.NewReader(`{"quantity": 1}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+}
+
+func TestDeleteProduct(t *testing.T) {
+	deleted, missing := uuid.New(), uuid.New()
+
+	productAppService := new(mockProductAppService)
+	productAppService.On("DeleteProduct", mock.Anything, deleted).Return(nil)
+	productAppService.On("DeleteProduct", mock.Anything, missing).Return(domain.ErrProductNotFound)
+
+	app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+
+	tests := []struct {
+		id     string
+		status int
+	}{
+		{deleted.String(), fiber.StatusNoContent},
+		{missing.String(), fiber.StatusNotFound},
+		{"not-a-uuid", fiber.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodDelete, "/api/v1/products/"+tt.id, nil)
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer secret")
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestGetProducts_InvalidPriceOrSort(t *testing.T) {
+	cursor := domain.NewCursor(time.Now(), uuid.New()).Encode()
+
+	tests := map[string]string{
+		"unknown sort column":  "sort=quantity",
+		"unknown order":        "order=up",
+		"negative price":       "min_price=-1",
+		"inverted price range": "min_price=500&max_price=100",
+		"cursor with sort":     "sort=price&cursor=" + cursor,
+	}
+
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			productAppService := new(mockProductAppService)
+
+			app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil, nil, nil, productAppService, nil, nil)
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/products?"+query, nil))
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+			productAppService.AssertNotCalled(t, "Products", mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package rest
+
+import (
+	"github.com/gofiber/fiber/v3"
+
+	"mts/internal/domain"
+	"shared"
+)
+
+type readOnlyHandler struct {
+	mode *domain.ReadOnlyMode
+}
+
+func newReadOnlyHandler(mode *domain.ReadOnlyMode) *readOnlyHandler {
+	return &readOnlyHandler{
+		mode: mode,
+	}
+}
+
+// getReadOnly reports whether read-only mode is on
+// @Summary Get read-only mode
+// @Description Report whether the storages currently reject writes with 503 (admin only)
+// @Tags Admin
+// @Produce json
+// @Security AdminToken
+// @Security BearerAuth
+// @Success 200 {object} ReadOnlyStatus "Current read-only mode"
+// @Failure 401 {object} ErrorResponse "Unauthorized - admin token or access token is missing"
+// @Failure 403 {object} ErrorResponse "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role"
+// @Router /api/v1/admin/read-only [get]
+func (h *readOnlyHandler) getReadOnly(c fiber.Ctx) error {
+	return c.JSON(NewReadOnlyStatus(h.mode))
+}
+
+// setReadOnly switches read-only mode on or off
+// @Summary Switch read-only mode
+// @Description Turn read-only mode on or off for this instance (admin only), e.g. while the primary database fails over. While it is on, every write to the database fails with 503 and code READ_ONLY, reads keep working
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Security BearerAuth
+// @Param request body ReadOnlyRequest true "Desired read-only mode"
+// @Success 200 {object} ReadOnlyStatus "Read-only mode after the switch"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid JSON or missing enabled flag"
+// @Failure 401 {object} ErrorResponse "Unauthorized - admin token or access token is missing"
+// @Failure 403 {object} ErrorResponse "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role"
+// @Router /api/v1/admin/read-only [put]
+func (h *readOnlyHandler) setReadOnly(c fiber.Ctx) error {
+	var req ReadOnlyRequest
+	if err := bindJSON(c, &req); err != nil {
+		return err
+	}
+
+	if previous := h.mode.Set(*req.Enabled); previous != *req.Enabled {
+		shared.Logger.Warn().
+			Bool("enabled", *req.Enabled).
+			Msg("read-only mode switched")
+	}
+
+	return c.JSON(NewReadOnlyStatus(h.mode))
+}
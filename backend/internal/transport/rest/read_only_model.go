@@ -0,0 +1,27 @@
+package rest
+
+import "mts/internal/domain"
+
+// ReadOnlyRequest represents request to switch read-only mode
+// @Description Request payload for switching read-only mode
+type ReadOnlyRequest struct {
+	// Enabled
+	// @Description Whether writes should be rejected
+	// @Example true
+	Enabled *bool `json:"enabled" binding:"required" validate:"required" example:"true"`
+} // @name ReadOnlyRequest
+
+// ReadOnlyStatus represents the current read-only mode
+// @Description Read-only mode of the instance
+type ReadOnlyStatus struct {
+	// Enabled
+	// @Description Whether writes are rejected with 503
+	// @Example false
+	Enabled bool `json:"enabled" example:"false"`
+} // @name ReadOnlyStatus
+
+func NewReadOnlyStatus(mode *domain.ReadOnlyMode) *ReadOnlyStatus {
+	return &ReadOnlyStatus{
+		Enabled: mode.Enabled(),
+	}
+}
@@ -0,0 +1,93 @@
+package rest
+
+import (
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+
+	"mts/internal/domain"
+)
+
+const (
+	// readyPath is answered during startup too, so orchestrators can tell a starting instance from a dead one
+	readyPath = "/ready"
+
+	notReadyRetryAfter = time.Second
+)
+
+// ReadinessState is the startup step the instance is in
+type ReadinessState string
+
+const (
+	ReadinessMigrating ReadinessState = "migrating"
+	ReadinessWarming   ReadinessState = "warming"
+	ReadinessReady     ReadinessState = "ready"
+)
+
+// Readiness tracks startup, which runs while the listener already accepts connections.
+// Until it is ready, API requests are answered with 503 and only the probes are served.
+// A nil readiness is always ready.
+type Readiness struct {
+	state atomic.Value
+}
+
+func NewReadiness(state ReadinessState) *Readiness {
+	readiness := &Readiness{}
+	readiness.state.Store(state)
+	return readiness
+}
+
+func (r *Readiness) State() ReadinessState {
+	if r == nil {
+		return ReadinessReady
+	}
+	return r.state.Load().(ReadinessState)
+}
+
+func (r *Readiness) Set(state ReadinessState) {
+	if r != nil {
+		r.state.Store(state)
+	}
+}
+
+// readinessMiddleware turns requests away until startup is done; the exempt paths are always served
+func readinessMiddleware(readiness *Readiness, exempt ...string) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if readiness.State() == ReadinessReady || slices.Contains(exempt, c.Path()) {
+			return c.Next()
+		}
+
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfterSeconds(notReadyRetryAfter)))
+		return withStatus(fiber.StatusServiceUnavailable, domain.ErrNotReady)
+	}
+}
+
+type readinessHandler struct {
+	readiness *Readiness
+}
+
+func newReadinessHandler(readiness *Readiness) *readinessHandler {
+	return &readinessHandler{
+		readiness: readiness,
+	}
+}
+
+// ready reports whether the instance takes traffic
+// @Summary Readiness probe
+// @Description Report whether startup is done. While migrations are applied the status is "migrating", while the caches are warmed up "warming", both with 503, and every other API request is answered with 503 NOT_READY
+// @Tags Health
+// @Produce json
+// @Success 200 {object} HealthStatus "The instance takes traffic"
+// @Failure 503 {object} HealthStatus "The instance is starting up"
+// @Router /ready [get]
+func (h *readinessHandler) ready(c fiber.Ctx) error {
+	state := h.readiness.State()
+	if state != ReadinessReady {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+
+	return c.JSON(&HealthStatus{Status: string(state)})
+}
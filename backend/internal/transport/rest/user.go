@@ -2,6 +2,8 @@ package rest
 
 import (
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
 	"github.com/google/uuid"
@@ -21,33 +23,102 @@ func newUserHandler(userAppService domain.UserAppService) *userHandler {
 
 // registerUser registers a new user in the system
 // @Summary Register new user
-// @Description Register a new user with validation (age >= 18, password >= 8 chars)
+// @Description Register a new user with validation (by default age >= 18 and password >= 8 chars, configurable via service.user_policy)
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param request body CreateUserRequest true "User registration data"
 // @Success 201 {object} User "User registered successfully"
 // @Failure 400 {object} ErrorResponse "Bad request - validation failed"
+// @Failure 409 {object} ErrorResponse "Conflict - a user with the same ID or email already exists, a taken email is reported in fields"
+// @Failure 429 {object} ErrorResponse "Too many requests - retry after the Retry-After delay"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/users [post]
 func (h *userHandler) registerUser(c fiber.Ctx) error {
 	var req CreateUserRequest
-	if err := c.Bind().JSON(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	if err := bindJSON(c, &req); err != nil {
+		return err
 	}
 
 	user, err := h.userAppService.RegisterUser(c.Context(), req.ToDomain())
 	if err != nil {
-		status := fiber.StatusInternalServerError
-		if errors.Is(err, domain.ErrUserValidation) {
-			status = fiber.StatusBadRequest
+		switch {
+		case errors.Is(err, domain.ErrUserValidation):
+			return badRequest(err)
+		case errors.Is(err, domain.ErrUserExists):
+			return withStatus(fiber.StatusConflict, err)
 		}
-		return fiber.NewError(status, err.Error())
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(NewUser(user))
 }
 
+// registerUsers registers many users in a single transaction
+// @Summary Bulk register users
+// @Description Register many users at once (admin only). Every user is validated before any is stored, then all are inserted in a single transaction or none. A batch may not repeat an email or take the email of a registered user; the conflicting users are reported in fields by position, e.g. users[2].email
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Security BearerAuth
+// @Param request body BulkRegisterUsersRequest true "Users to register"
+// @Success 201 {object} BulkRegisterUsersResponse "Users registered successfully"
+// @Failure 400 {object} ErrorResponse "Bad request - validation failed, problems are keyed by position"
+// @Failure 401 {object} ErrorResponse "Unauthorized - admin token or access token is missing"
+// @Failure 403 {object} ErrorResponse "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role"
+// @Failure 409 {object} ErrorResponse "Conflict - an email is repeated or already registered, nothing was registered"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/users/bulk [post]
+func (h *userHandler) registerUsers(c fiber.Ctx) error {
+	var req BulkRegisterUsersRequest
+	if err := bindJSON(c, &req); err != nil {
+		return err
+	}
+
+	users, err := h.userAppService.RegisterUsers(c.Context(), req.ToDomain())
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUserValidation):
+			return badRequest(err)
+		case errors.Is(err, domain.ErrUserExists):
+			return withStatus(fiber.StatusConflict, err)
+		}
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(NewBulkRegisterUsersResponse(users))
+}
+
+// verifyEmail confirms a user's email address
+// @Summary Verify email
+// @Description Confirm the email address with the token mailed on registration. The token is single use
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param token query string true "Verification token from the email"
+// @Success 200 {object} User "Email verified successfully"
+// @Failure 400 {object} ErrorResponse "Bad request - missing token"
+// @Failure 404 {object} ErrorResponse "Not found - invalid or already used token"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/users/verify [get]
+func (h *userHandler) verifyEmail(c fiber.Ctx) error {
+	token := c.Query("token")
+	if token == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "token is required")
+	}
+
+	user, err := h.userAppService.VerifyEmail(c.Context(), token)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidVerificationToken) {
+			return withStatus(fiber.StatusNotFound, err)
+		}
+		return err
+	}
+
+	return c.JSON(NewUser(user))
+}
+
 // getUsers retrieves a paginated list of users
 // @Summary Get users list
 // @Description Retrieve a paginated list of all users in the system
@@ -55,33 +126,103 @@ func (h *userHandler) registerUser(c fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number for pagination" default(1) minimum(1)
-// @Param size query int false "Number of items per page" default(10) minimum(1) maximum(100)
+// @Param size query int false "Number of items per page, service.pagination.default_size (10 unless configured) when omitted; more than service.pagination.max_size (100 unless configured) is rejected with 400" minimum(1)
+// @Param created_from query string false "Only users registered at or after this time (RFC3339)" format(date-time)
+// @Param created_to query string false "Only users registered at or before this time (RFC3339)" format(date-time)
+// @Param name query string false "Case-insensitive part of the user's full name"
+// @Param sort query string false "Column to sort by, name is the full name" Enums(created_at, name, age) default(created_at)
+// @Param order query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Param If-None-Match header string false "ETag of a previously received response"
 // @Success 200 {object} UsersResponse "Users retrieved successfully"
-// @Failure 400 {object} ErrorResponse "Bad request - invalid pagination parameters"
+// @Header 200 {string} ETag "Hash of the response body"
+// @Header 200 {string} Cache-Control "How long the response may be reused"
+// @Success 304 "Not modified - the If-None-Match ETag is still current"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid pagination parameters, dates or sort"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/users [get]
 func (h *userHandler) getUsers(c fiber.Ctx) error {
-	pagination := NewPaginationFromRequest(c)
+	pagination, err := NewPaginationFromRequest(c)
+	if err != nil {
+		return err
+	}
 
-	users, err := h.userAppService.Users(c.Context(), &domain.GetUsersRequest{
-		Limit:  pagination.Limit(),
-		Offset: pagination.Offset(),
-	})
+	createdFrom, err := timeFromQuery(c, "created_from")
+	if err != nil {
+		return err
+	}
+
+	createdTo, err := timeFromQuery(c, "created_to")
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
-	count, err := h.userAppService.CountUsers(c.Context(), &domain.GetUsersRequest{})
+	sort, order, err := sortFromRequest(c, domain.ParseUserSort)
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return err
+	}
+
+	req := &domain.GetUsersRequest{
+		CreatedFrom: createdFrom,
+		CreatedTo:   createdTo,
+		Name:        strings.TrimSpace(c.Query("name")),
+		Sort:        sort,
+		Order:       order,
+		Limit:       pagination.Limit(),
+		Offset:      pagination.Offset(),
+	}
+
+	users, err := h.userAppService.Users(c.Context(), req)
+	if err != nil {
+		return err
+	}
+
+	count, err := h.userAppService.CountUsers(c.Context(), &domain.GetUsersRequest{
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+		Name:        req.Name,
+	})
+	if err != nil {
+		return err
 	}
 
 	pagination.Total = count
 	pagination.CalculateTotalPages()
+	pagination.SetLinks(c.Path(), string(c.Request().URI().QueryString()))
 
 	return c.JSON(NewUsersResponse(users, *pagination))
 }
 
+// getCurrentUser retrieves the authenticated user
+// @Summary Get current user
+// @Description Retrieve the user the access token was issued to
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} User "User information retrieved successfully"
+// @Failure 401 {object} ErrorResponse "Unauthorized - missing, invalid or expired access token"
+// @Failure 404 {object} ErrorResponse "Not found - the user was deleted"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/users/me [get]
+func (h *userHandler) getCurrentUser(c fiber.Ctx) error {
+	// set by authMiddleware, requireUserMiddleware ran before
+	userId := c.Locals(localUserId).(uuid.UUID)
+
+	users, err := h.userAppService.Users(c.Context(), &domain.GetUsersRequest{
+		Ids:   []uuid.UUID{userId},
+		Limit: 1,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(users) == 0 {
+		return withStatus(fiber.StatusNotFound, domain.ErrUserNotFound)
+	}
+
+	return c.JSON(NewUser(users[0]))
+}
+
 // getUser retrieves a specific user by ID
 // @Summary Get user by ID
 // @Description Retrieve detailed information about a specific user using their unique identifier
@@ -89,15 +230,19 @@ func (h *userHandler) getUsers(c fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param user_id path string true "User unique identifier" format(uuid)
+// @Param If-None-Match header string false "ETag of a previously received response"
 // @Success 200 {object} User "User information retrieved successfully"
+// @Header 200 {string} ETag "Hash of the response body"
+// @Header 200 {string} Cache-Control "How long the response may be reused"
+// @Success 304 "Not modified - the If-None-Match ETag is still current"
 // @Failure 400 {object} ErrorResponse "Bad request - invalid user ID format"
 // @Failure 404 {object} ErrorResponse "Not found - user with specified ID does not exist"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/users/{user_id} [get]
 func (h *userHandler) getUser(c fiber.Ctx) error {
-	userId, err := uuid.Parse(c.Params("user_id"))
+	userId, err := parseUUIDParam(c, "user_id")
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid user ID format")
+		return err
 	}
 
 	users, err := h.userAppService.Users(c.Context(), &domain.GetUsersRequest{
@@ -105,12 +250,59 @@ func (h *userHandler) getUser(c fiber.Ctx) error {
 		Limit: 1,
 	})
 	if err != nil {
-		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	if len(users) == 0 {
-		return fiber.NewError(fiber.StatusNotFound, domain.ErrUserNotFound.Error())
+		return withStatus(fiber.StatusNotFound, domain.ErrUserNotFound)
 	}
 
 	return c.JSON(NewUser(users[0]))
 }
+
+// deleteUser soft-deletes a user
+// @Summary Delete user
+// @Description Soft-delete a user (admin only); the user is hidden from listings but its orders are kept
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Security BearerAuth
+// @Param user_id path string true "User unique identifier" format(uuid)
+// @Success 204 "User deleted successfully"
+// @Failure 400 {object} ErrorResponse "Bad request - invalid user ID format"
+// @Failure 401 {object} ErrorResponse "Unauthorized - admin token or access token is missing"
+// @Failure 403 {object} ErrorResponse "Forbidden - invalid admin token, admin endpoints disabled or user without the admin role"
+// @Failure 404 {object} ErrorResponse "Not found - user with specified ID does not exist"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/users/{user_id} [delete]
+func (h *userHandler) deleteUser(c fiber.Ctx) error {
+	userId, err := parseUUIDParam(c, "user_id")
+	if err != nil {
+		return err
+	}
+
+	if err = h.userAppService.DeleteUser(c.Context(), userId); err != nil {
+		if errors.Is(err, domain.ErrUserNotFound) {
+			return withStatus(fiber.StatusNotFound, err)
+		}
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// timeFromQuery parses an optional RFC3339 query parameter
+func timeFromQuery(c fiber.Ctx, param string) (*time.Time, error) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid "+param+", must be an RFC3339 timestamp")
+	}
+
+	return &t, nil
+}
@@ -32,7 +32,7 @@ type User struct {
 	FullName string `json:"full_name" example:"John Doe"`
 
 	// Age
-	// @Description User's age (must be 18 or older)
+	// @Description User's age
 	// @Example 25
 	Age int `json:"age" example:"25"`
 
@@ -41,6 +41,21 @@ type User struct {
 	// @Example false
 	IsMarried bool `json:"is_married" example:"false"`
 
+	// Email
+	// @Description User's email address, omitted for users registered before emails were collected
+	// @Example john.doe@example.com
+	Email string `json:"email,omitempty" example:"john.doe@example.com"`
+
+	// Email verified
+	// @Description Whether the user confirmed their email address
+	// @Example true
+	EmailVerified bool `json:"email_verified" example:"true"`
+
+	// Roles
+	// @Description Roles granting access to endpoints, admin is needed for admin-only ones
+	// @Example ["user"]
+	Roles []string `json:"roles" example:"user"`
+
 	// Created at
 	// @Description When the user was created
 	// @Example 2024-01-15T10:30:00Z
@@ -61,19 +76,24 @@ type CreateUserRequest struct {
 	LastName string `json:"last_name" binding:"required" validate:"required" example:"Doe"`
 
 	// Age
-	// @Description User's age (must be 18 or older)
+	// @Description User's age (must meet the configured minimum, 18 by default)
 	// @Example 25
-	Age int `json:"age" binding:"required" validate:"required,gte=18" example:"25"`
+	Age int `json:"age" binding:"required" validate:"required,gte=1" example:"25"`
 
 	// Is married
 	// @Description Whether the user is married
 	// @Example false
 	IsMarried bool `json:"is_married" example:"false"`
 
+	// Email
+	// @Description User's email address, a verification link is sent to it (required)
+	// @Example john.doe@example.com
+	Email string `json:"email" binding:"required" validate:"required,email" example:"john.doe@example.com"`
+
 	// Password
-	// @Description User's password (minimum 8 characters)
+	// @Description User's password (at least the configured length, 8 characters by default)
 	// @Example password123
-	Password string `json:"password" binding:"required" validate:"required,min=8" example:"password123"`
+	Password string `json:"password" binding:"required" validate:"required" example:"password123"`
 } // @name CreateUserRequest
 
 func (req *CreateUserRequest) ToDomain() *domain.CreateUserRequest {
@@ -82,10 +102,43 @@ func (req *CreateUserRequest) ToDomain() *domain.CreateUserRequest {
 		LastName:  req.LastName,
 		Age:       req.Age,
 		IsMarried: req.IsMarried,
+		Email:     req.Email,
 		Password:  req.Password,
 	}
 }
 
+// BulkRegisterUsersRequest represents request to register many users at once
+// @Description Request payload for a bulk user registration
+type BulkRegisterUsersRequest struct {
+	// Users
+	// @Description Users to register (1 to 100), all or none of them are registered
+	Users []*CreateUserRequest `json:"users" binding:"required" validate:"required,min=1,max=100,dive,required"`
+} // @name BulkRegisterUsersRequest
+
+func (req *BulkRegisterUsersRequest) ToDomain() []*domain.CreateUserRequest {
+	reqs := make([]*domain.CreateUserRequest, 0, len(req.Users))
+	for _, user := range req.Users {
+		reqs = append(reqs, user.ToDomain())
+	}
+	return reqs
+}
+
+// BulkRegisterUsersResponse represents the users of a bulk registration
+// @Description Registered users in the order of the request
+type BulkRegisterUsersResponse struct {
+	// Users
+	// @Description Registered users
+	Users []*User `json:"users"`
+} // @name BulkRegisterUsersResponse
+
+func NewBulkRegisterUsersResponse(domainUsers []*domain.User) *BulkRegisterUsersResponse {
+	users := make([]*User, 0, len(domainUsers))
+	for _, user := range domainUsers {
+		users = append(users, NewUser(user))
+	}
+	return &BulkRegisterUsersResponse{Users: users}
+}
+
 // UsersResponse represents paginated list of users
 // @Description Paginated response containing list of users
 type UsersResponse struct {
@@ -100,13 +153,16 @@ type UsersResponse struct {
 
 func NewUser(domainUser *domain.User) *User {
 	return &User{
-		Id:        domainUser.Id,
-		FirstName: domainUser.FirstName,
-		LastName:  domainUser.LastName,
-		FullName:  domainUser.FullName(),
-		Age:       domainUser.Age,
-		IsMarried: domainUser.IsMarried,
-		CreatedAt: domainUser.CreatedAt,
+		Id:            domainUser.Id,
+		FirstName:     domainUser.FirstName,
+		LastName:      domainUser.LastName,
+		FullName:      domainUser.FullName(),
+		Age:           domainUser.Age,
+		IsMarried:     domainUser.IsMarried,
+		Email:         domainUser.Email,
+		EmailVerified: domainUser.EmailVerified,
+		Roles:         domainUser.Roles,
+		CreatedAt:     domainUser.CreatedAt,
 	}
 }
 
@@ -0,0 +1,284 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/application"
+	"mts/internal/config"
+	"mts/internal/domain"
+	"mts/internal/repository/cache"
+	"mts/internal/repository/mailer"
+)
+
+func TestGetUsers_InvalidCreatedRange(t *testing.T) {
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil,
+		nil, application.NewUserAppService(nil, nil, ""), nil, nil, nil)
+
+	for _, query := range []string{
+		"created_from=yesterday",
+		"created_to=2024-01-01",
+	} {
+		t.Run(query, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/users?"+query, nil))
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+			var errResp ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			assert.Contains(t, errResp.Message, "RFC3339")
+		})
+	}
+}
+
+func TestGetUsers_Sort(t *testing.T) {
+	users := &recordingUserStorage{}
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil,
+		nil, application.NewUserAppService(users, nil, ""), nil, nil, nil)
+
+	tests := []struct {
+		query string
+		sort  domain.UserSort
+		order domain.SortOrder
+	}{
+		{"", domain.UserSortCreatedAt, domain.SortOrderDesc},
+		{"sort=name&order=asc", domain.UserSortName, domain.SortOrderAsc},
+		{"sort=age&order=desc", domain.UserSortAge, domain.SortOrderDesc},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/users?"+tt.query, nil))
+			require.NoError(t, err)
+			require.Equal(t, fiber.StatusOK, resp.StatusCode)
+
+			req := users.last
+			require.NotNil(t, req)
+			assert.Equal(t, tt.sort, req.Sort)
+			assert.Equal(t, tt.order, req.Order)
+		})
+	}
+
+	for _, query := range []string{"sort=password_hash", "sort=age&order=sideways"} {
+		t.Run(query, func(t *testing.T) {
+			users.last = nil
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/users?"+query, nil))
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+			assert.Nil(t, users.last, "an invalid sort reached the storage")
+
+			var errResp ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			assert.Equal(t, domain.ErrInvalidSort.Code(), errResp.Code)
+		})
+	}
+}
+
+func TestRegisterUsers(t *testing.T) {
+	users := &batchUserStorage{}
+	app := New(&config.Service{AdminToken: "secret"}, cache.NewMemoryCache(), nil, nil, nil,
+		nil, application.NewUserAppService(users, mailer.NewLogMailer(), ""), nil, nil, nil)
+
+	register := func(emails ...string) *http.Response {
+		t.Helper()
+
+		req := BulkRegisterUsersRequest{}
+		for _, email := range emails {
+			req.Users = append(req.Users, &CreateUserRequest{
+				FirstName: "John",
+				LastName:  "Doe",
+				Age:       25,
+				Email:     email,
+				Password:  "password123",
+			})
+		}
+		body, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		httpReq := httptest.NewRequest(fiber.MethodPost, "/api/v1/users/bulk", bytes.NewReader(body))
+		httpReq.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		httpReq.Header.Set(fiber.HeaderAuthorization, "Bearer secret")
+
+		resp, err := app.Test(httpReq)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("registers the batch", func(t *testing.T) {
+		resp := register("first@example.com", "second@example.com")
+		require.Equal(t, fiber.StatusCreated, resp.StatusCode)
+
+		var registered BulkRegisterUsersResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&registered))
+		require.Len(t, registered.Users, 2)
+		assert.Equal(t, "first@example.com", registered.Users[0].Email)
+		assert.Equal(t, "second@example.com", registered.Users[1].Email)
+		assert.Len(t, users.stored, 2)
+	})
+
+	t.Run("reports the conflicting position", func(t *testing.T) {
+		users.stored = nil
+
+		resp := register("first@example.com", "second@example.com", "first@example.com")
+		require.Equal(t, fiber.StatusConflict, resp.StatusCode)
+
+		var errResp ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		assert.Equal(t, domain.ErrUserExists.Code(), errResp.Code)
+		assert.Equal(t, map[string]string{
+			"users[2].email": "email first@example.com is already used by users[0].email",
+		}, errResp.Fields)
+		assert.Empty(t, users.stored)
+	})
+
+	t.Run("reports invalid users by position", func(t *testing.T) {
+		resp := register("first@example.com", "")
+		require.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+		var errResp ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+		assert.Contains(t, errResp.Fields, "users[1].email")
+	})
+
+	t.Run("requires an admin", func(t *testing.T) {
+		httpReq := httptest.NewRequest(fiber.MethodPost, "/api/v1/users/bulk", strings.NewReader(`{"users":[]}`))
+		httpReq.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+		resp, err := app.Test(httpReq)
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+// batchUserStorage stores batches whose emails are distinct, like the postgres storage with no users yet
+type batchUserStorage struct {
+	domain.UserStorage
+	stored []*domain.User
+}
+
+func (s *batchUserStorage) CreateUsers(_ context.Context, users []*domain.User) error {
+	conflicts := domain.NewValidationError(domain.ErrUserExists)
+	first := make(map[string]int)
+	for i, user := range users {
+		if j, ok := first[user.Email]; ok {
+			conflicts.Add(domain.UserBatchField(i, "email"),
+				"email "+user.Email+" is already used by "+domain.UserBatchField(j, "email"))
+			continue
+		}
+		first[user.Email] = i
+	}
+	if err := conflicts.Err(); err != nil {
+		return err
+	}
+
+	s.stored = append(s.stored, users...)
+	return nil
+}
+
+// recordingUserStorage remembers the last list request and finds no users
+type recordingUserStorage struct {
+	domain.UserStorage
+	last *domain.GetUsersRequest
+}
+
+func (s *recordingUserStorage) Users(_ context.Context, req *domain.GetUsersRequest) ([]*domain.User, error) {
+	s.last = req
+	return nil, nil
+}
+
+func (s *recordingUserStorage) CountUsers(context.Context, *domain.GetUsersRequest) (int, error) {
+	return 0, nil
+}
+
+func TestGetCurrentUser(t *testing.T) {
+	user := (&domain.Factory{}).User()
+	deleted := (&domain.Factory{}).User()
+	deleted.Email = "deleted@example.com"
+
+	users := &fixedUserStorage{users: []*domain.User{user, deleted}}
+	auth := application.NewAuthAppService(users, discardRefreshTokenStorage{}, cache.NewMemoryCache(), application.TokenOptions{Secret: []byte("secret")})
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil,
+		nil, application.NewUserAppService(users, nil, ""), nil, nil, auth)
+
+	login := func(email string) string {
+		token, err := auth.Login(context.Background(), &domain.LoginRequest{Email: email, Password: "password123"})
+		require.NoError(t, err)
+		return token.AccessToken
+	}
+	userToken := login(user.Email)
+	deletedToken := login(deleted.Email)
+	users.users = users.users[:1]
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+		code          string
+	}{
+		{"authenticated", "Bearer " + userToken, fiber.StatusOK, ""},
+		{"missing token", "", fiber.StatusUnauthorized, domain.ErrUnauthenticated.Code()},
+		{"invalid token", "Bearer " + userToken + "x", fiber.StatusUnauthorized, domain.ErrUnauthenticated.Code()},
+		{"deleted user", "Bearer " + deletedToken, fiber.StatusNotFound, domain.ErrUserNotFound.Code()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/api/v1/users/me", nil)
+			if tt.authorization != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.authorization)
+			}
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			require.Equal(t, tt.status, resp.StatusCode)
+
+			if tt.status != fiber.StatusOK {
+				var errResp ErrorResponse
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+				assert.Equal(t, tt.code, errResp.Code)
+				return
+			}
+
+			var body User
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, user.Id, body.Id)
+			assert.Equal(t, user.Email, body.Email)
+		})
+	}
+}
+
+// fixedUserStorage serves a fixed set of live users
+type fixedUserStorage struct {
+	domain.UserStorage
+	users []*domain.User
+}
+
+func (s *fixedUserStorage) Users(_ context.Context, req *domain.GetUsersRequest) ([]*domain.User, error) {
+	var users []*domain.User
+	for _, user := range s.users {
+		if slices.Contains(req.Ids, user.Id) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (s *fixedUserStorage) UserByEmail(_ context.Context, email string) (*domain.User, error) {
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"mts/internal/domain"
+)
+
+// structValidator runs the `validate` tags of bound request models, so malformed requests are
+// rejected with field-level errors before they reach the application layer
+type structValidator struct {
+	validate *validator.Validate
+}
+
+func newStructValidator() *structValidator {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	// report fields under their JSON names, as clients send them
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	return &structValidator{validate: validate}
+}
+
+// Validate fails with a domain.ValidationError of kind domain.ErrRequestValidation listing every invalid field
+func (v *structValidator) Validate(out any) error {
+	err := v.validate.Struct(out)
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+
+	validationErr := domain.NewValidationError(domain.ErrRequestValidation)
+	for _, fieldErr := range fieldErrs {
+		field := fieldPath(fieldErr)
+		validationErr.Add(field, field+" "+fieldProblem(fieldErr))
+	}
+
+	return validationErr.Err()
+}
+
+// fieldPath drops the struct name from the namespace, e.g. items[0].quantity
+func fieldPath(fieldErr validator.FieldError) string {
+	_, path, found := strings.Cut(fieldErr.Namespace(), ".")
+	if !found {
+		return fieldErr.Field()
+	}
+	return path
+}
+
+func fieldProblem(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	case "gt":
+		if fieldErr.Param() == "0" {
+			return "must be positive"
+		}
+		return "must be greater than " + fieldErr.Param()
+	case "gte":
+		return "must be at least " + fieldErr.Param()
+	case "lte":
+		return "must be at most " + fieldErr.Param()
+	case "min":
+		if fieldErr.Kind() == reflect.Slice {
+			return "must contain at least " + itemCount(fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at least %s characters long", fieldErr.Param())
+	case "max":
+		if fieldErr.Kind() == reflect.Slice {
+			return "must contain at most " + itemCount(fieldErr.Param())
+		}
+		return fmt.Sprintf("must be at most %s characters long", fieldErr.Param())
+	}
+	return "is invalid (" + fieldErr.Tag() + ")"
+}
+
+func itemCount(count string) string {
+	if count == "1" {
+		return "1 item"
+	}
+	return count + " items"
+}
@@ -0,0 +1,130 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"mts/internal/application"
+	"mts/internal/config"
+	"mts/internal/domain"
+	"mts/internal/repository/cache"
+)
+
+func TestStructValidator_RejectsTagViolations(t *testing.T) {
+	// application services have no storages, so only the transport layer can answer
+	app := New(&config.Service{}, cache.NewMemoryCache(), nil, nil, nil,
+		nil, application.NewUserAppService(nil, nil, ""), application.NewProductAppService(nil, nil), new(mockOrderAppService), nil)
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedFields map[string]string
+	}{
+		{
+			name: "user missing required fields",
+			path: "/api/v1/users",
+			body: `{"first_name": "John", "age": 25}`,
+			expectedFields: map[string]string{
+				"last_name": "last_name is required",
+				"email":     "email is required",
+				"password":  "password is required",
+			},
+		},
+		{
+			name: "user age below minimum",
+			path: "/api/v1/users",
+			body: `{"first_name": "John", "last_name": "Doe", "age": -3, "email": "john@example.com", "password": "password123"}`,
+			expectedFields: map[string]string{
+				"age": "age must be at least 1",
+			},
+		},
+		{
+			name: "user with malformed email",
+			path: "/api/v1/users",
+			body: `{"first_name": "John", "last_name": "Doe", "age": 25, "email": "john", "password": "password123"}`,
+			expectedFields: map[string]string{
+				"email": "email must be a valid email",
+			},
+		},
+		{
+			name: "product with negative price",
+			path: "/api/v1/products",
+			body: `{"description": "Phone", "quantity": 1, "price": -1}`,
+			expectedFields: map[string]string{
+				"price": "price must be at least 0",
+			},
+		},
+		{
+			name: "order without items",
+			path: "/api/v1/orders",
+			body: `{"user_id": "123e4567-e89b-12d3-a456-426614174000", "items": []}`,
+			expectedFields: map[string]string{
+				"items": "items must contain at least 1 item",
+			},
+		},
+		{
+			name: "order with invalid items",
+			path: "/api/v1/orders",
+			body: `{"user_id": "123e4567-e89b-12d3-a456-426614174000", "items": [{"product_id": "456e7890-e12b-34d5-a678-901234567890", "quantity": 0}, {"quantity": 1}]}`,
+			expectedFields: map[string]string{
+				"items[0].quantity":   "items[0].quantity must be positive",
+				"items[1].product_id": "items[1].product_id is required",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+			resp, err := app.Test(req)
+			require.NoError(t, err)
+			assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+			var errResp ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			assert.Equal(t, domain.ErrRequestValidation.Code(), errResp.Code)
+			assert.Equal(t, tt.expectedFields, errResp.Fields)
+		})
+	}
+}
+
+func TestStructValidator_NestedFieldPaths(t *testing.T) {
+	productId := uuid.New()
+
+	err := newStructValidator().Validate(&CreateOrderRequest{
+		UserId: uuid.New(),
+		Items:  []CreateOrderItemRequest{{ProductId: productId, Quantity: 1}, {ProductId: productId, Quantity: 0}},
+	})
+
+	var validationErr *domain.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.ErrorIs(t, err, domain.ErrRequestValidation)
+	assert.Equal(t, map[string]string{"items[1].quantity": "items[1].quantity must be positive"}, validationErr.Fields)
+
+	err = newStructValidator().Validate(&UpdateOrderItemsRequest{
+		Items: []CreateOrderItemRequest{{Quantity: -2}},
+	})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, map[string]string{
+		"items[0].product_id": "items[0].product_id is required",
+		"items[0].quantity":   "items[0].quantity must be positive",
+	}, validationErr.Fields)
+
+	err = newStructValidator().Validate(&UpdateOrderItemsRequest{Items: []CreateOrderItemRequest{}})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, map[string]string{"items": "items must contain at least 1 item"}, validationErr.Fields)
+
+	assert.NoError(t, newStructValidator().Validate(&UpdateOrderItemsRequest{
+		Items: []CreateOrderItemRequest{{ProductId: productId, Quantity: 1}},
+	}))
+}
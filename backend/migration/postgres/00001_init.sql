@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS users
+(
+    id            UUID PRIMARY KEY,
+    first_name    TEXT        NOT NULL,
+    last_name     TEXT        NOT NULL,
+    age           INTEGER     NOT NULL,
+    is_married    BOOLEAN     NOT NULL DEFAULT FALSE,
+    password_hash TEXT        NOT NULL,
+    salt          TEXT        NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at DESC, id);
+
+CREATE TABLE IF NOT EXISTS products
+(
+    id          UUID PRIMARY KEY,
+    description TEXT        NOT NULL,
+    tags        TEXT        NOT NULL DEFAULT '',
+    quantity    INTEGER     NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS products_created_at_idx ON products (created_at DESC, id);
+
+CREATE TABLE IF NOT EXISTS orders
+(
+    id         UUID PRIMARY KEY,
+    user_id    UUID        NOT NULL REFERENCES users (id),
+    status     TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS orders_user_id_idx ON orders (user_id);
+CREATE INDEX IF NOT EXISTS orders_created_at_idx ON orders (created_at DESC, id);
+
+CREATE TABLE IF NOT EXISTS order_items
+(
+    id               UUID PRIMARY KEY,
+    order_id         UUID        NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    product_id       UUID        NOT NULL REFERENCES products (id),
+    quantity         INTEGER     NOT NULL,
+    product_snapshot TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS order_items_order_id_idx ON order_items (order_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS users;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE products ADD CONSTRAINT products_quantity_check CHECK (quantity >= 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_quantity_check;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE products ADD COLUMN IF NOT EXISTS price INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE products ADD CONSTRAINT products_price_check CHECK (price >= 0);
+
+CREATE INDEX IF NOT EXISTS products_price_idx ON products (price, created_at DESC, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS products_price_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS price;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ALTER COLUMN password_hash TYPE BYTEA USING decode(password_hash, 'hex'),
+    ALTER COLUMN salt TYPE BYTEA USING decode(salt, 'hex');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    ALTER COLUMN password_hash TYPE TEXT USING encode(password_hash, 'hex'),
+    ALTER COLUMN salt TYPE TEXT USING encode(salt, 'hex');
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- backs filtering orders by the products they contain, see applyOrderFilters
+CREATE INDEX IF NOT EXISTS order_items_product_id_idx ON order_items (product_id, order_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS order_items_product_id_idx;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- full-text search over the description and the JSON encoded tags, see applyProductFilters;
+-- description words weigh more than tags when ranking
+ALTER TABLE products ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', description), 'A') ||
+    setweight(to_tsvector('english', tags), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS products_search_vector_idx ON products USING gin (search_vector);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS products_search_vector_idx;
+ALTER TABLE products DROP COLUMN IF EXISTS search_vector;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Descriptions were not unique before. Of the live products sharing one, the oldest keeps it
+-- and the others get their id appended, so no product is lost and the index can be built.
+UPDATE products
+SET description = description || ' (' || id || ')'
+WHERE id IN (
+    SELECT id
+    FROM (
+        SELECT id, row_number() OVER (PARTITION BY description ORDER BY created_at, id) AS position
+        FROM products
+        WHERE deleted_at IS NULL
+    ) AS duplicates
+    WHERE position > 1
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX IF NOT EXISTS products_description_key ON products (description) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS products_description_key;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Emails were not unique before. Of the live users sharing an email, ignoring case, the most recent
+-- registration is the one logins resolved to, so it is kept and the older ones are soft-deleted.
+UPDATE users
+SET deleted_at = now()
+WHERE id IN (
+    SELECT id
+    FROM (
+        SELECT id, row_number() OVER (PARTITION BY lower(email) ORDER BY created_at DESC, id DESC) AS position
+        FROM users
+        WHERE email IS NOT NULL AND deleted_at IS NULL
+    ) AS registrations
+    WHERE position > 1
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (lower(email)) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS users_email_key;
+-- +goose StatementEnd
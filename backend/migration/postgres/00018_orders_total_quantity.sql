@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS total_quantity INT NOT NULL DEFAULT 0;
+
+-- backfills the orders placed before the column, later ones store it with their items
+UPDATE orders
+SET total_quantity = totals.quantity
+FROM (
+    SELECT order_id, SUM(quantity) AS quantity
+    FROM order_items
+    GROUP BY order_id
+) AS totals
+WHERE totals.order_id = orders.id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE orders DROP COLUMN IF EXISTS total_quantity;
+-- +goose StatementEnd
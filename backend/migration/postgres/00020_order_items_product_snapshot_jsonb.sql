@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- snapshots that are missing or don't hold a JSON object are rebuilt from the product's current description,
+-- the only snapshot field an item can't do without
+UPDATE order_items
+SET product_snapshot = jsonb_build_object('Description', products.description)::text
+FROM products
+WHERE products.id = order_items.product_id
+  AND CASE
+          WHEN pg_input_is_valid(order_items.product_snapshot, 'jsonb')
+              THEN jsonb_typeof(order_items.product_snapshot::jsonb) <> 'object'
+          ELSE true
+      END;
+
+ALTER TABLE order_items
+    ALTER COLUMN product_snapshot TYPE JSONB USING product_snapshot::jsonb,
+    ALTER COLUMN product_snapshot SET DEFAULT '{}',
+    ALTER COLUMN product_snapshot SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE order_items
+    ALTER COLUMN product_snapshot DROP NOT NULL,
+    ALTER COLUMN product_snapshot DROP DEFAULT,
+    ALTER COLUMN product_snapshot TYPE TEXT USING product_snapshot::text;
+-- +goose StatementEnd
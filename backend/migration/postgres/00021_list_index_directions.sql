@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Lists end their ordering with created_at DESC, id DESC and page by (created_at, id) < cursor. With id
+-- ascending the indexes could serve neither in order, so they are rebuilt in the listing's directions.
+DROP INDEX IF EXISTS users_created_at_idx;
+CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at DESC, id DESC);
+
+DROP INDEX IF EXISTS users_active_idx;
+CREATE INDEX IF NOT EXISTS users_active_idx ON users (created_at DESC, id DESC) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS products_created_at_idx;
+CREATE INDEX IF NOT EXISTS products_created_at_idx ON products (created_at DESC, id DESC);
+
+DROP INDEX IF EXISTS products_active_idx;
+CREATE INDEX IF NOT EXISTS products_active_idx ON products (created_at DESC, id DESC) WHERE deleted_at IS NULL;
+
+-- the cheapest first listing, the one the cache warmup loads, reads it in order
+DROP INDEX IF EXISTS products_price_idx;
+CREATE INDEX IF NOT EXISTS products_price_idx ON products (price, created_at DESC, id DESC);
+
+DROP INDEX IF EXISTS orders_created_at_idx;
+CREATE INDEX IF NOT EXISTS orders_created_at_idx ON orders (created_at DESC, id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS users_created_at_idx;
+CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at DESC, id);
+
+DROP INDEX IF EXISTS users_active_idx;
+CREATE INDEX IF NOT EXISTS users_active_idx ON users (created_at DESC, id) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS products_created_at_idx;
+CREATE INDEX IF NOT EXISTS products_created_at_idx ON products (created_at DESC, id);
+
+DROP INDEX IF EXISTS products_active_idx;
+CREATE INDEX IF NOT EXISTS products_active_idx ON products (created_at DESC, id) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS products_price_idx;
+CREATE INDEX IF NOT EXISTS products_price_idx ON products (price, created_at DESC, id);
+
+DROP INDEX IF EXISTS orders_created_at_idx;
+CREATE INDEX IF NOT EXISTS orders_created_at_idx ON orders (created_at DESC, id);
+-- +goose StatementEnd
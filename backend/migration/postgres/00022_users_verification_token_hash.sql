@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Pending verification tokens are kept as SHA-256 hashes, like refresh tokens, so a leaked table can't
+-- confirm anyone's email. Tokens already mailed keep working, their hashes are computed here.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_token_hash BYTEA;
+
+UPDATE users
+SET verification_token_hash = sha256(convert_to(verification_token, 'UTF8'))
+WHERE verification_token IS NOT NULL;
+
+DROP INDEX IF EXISTS users_verification_token_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS verification_token;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_verification_token_hash_idx ON users (verification_token_hash) WHERE verification_token_hash IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- the tokens can't be recovered from their hashes, pending verifications have to be requested again
+DROP INDEX IF EXISTS users_verification_token_hash_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS verification_token_hash;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS verification_token TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS users_verification_token_idx ON users (verification_token) WHERE verification_token IS NOT NULL;
+-- +goose StatementEnd
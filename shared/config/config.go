@@ -1,7 +1,10 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"reflect"
 	"strings"
 
 	"github.com/knadh/koanf"
@@ -13,14 +16,28 @@ import (
 const defaultEnvPrefix = "APP"
 const defaultFilename = "config.yaml"
 
+var ErrValidation = errors.New("config validation")
+
+type validator interface {
+	Validate() error
+}
+
 type Config[S any] struct {
 	Logger       *Logger   `koanf:"logger"`
 	Postgres     *Postgres `koanf:"postgres"`
+	Tracing      *Tracing  `koanf:"tracing"`
+	Smtp         *Smtp     `koanf:"smtp"`
 	Service      *S        `koanf:"service"`
 	FrontBaseUrl string    `koanf:"front_base_url"`
 }
 
 func Load[S any](envPrefix string, filename string) (*Config[S], error) {
+	return LoadFiles[S](envPrefix, filename)
+}
+
+// LoadFiles layers the config files in order, later files overriding earlier ones, and env vars over all of them.
+// Missing and empty filenames are skipped; with no filenames config.yaml is loaded.
+func LoadFiles[S any](envPrefix string, filenames ...string) (*Config[S], error) {
 	var cfg Config[S]
 
 	k := koanf.New(".")
@@ -33,25 +50,26 @@ func Load[S any](envPrefix string, filename string) (*Config[S], error) {
 		envPrefix += "_"
 	}
 
-	if filename == "" {
-		filename = defaultFilename
+	if len(filenames) == 0 || (len(filenames) == 1 && filenames[0] == "") {
+		filenames = []string{defaultFilename}
 	}
 
-	if _, err := os.Stat(filename); !os.IsNotExist(err) {
-		if err = k.Load(file.Provider(filename), yaml.Parser()); err != nil {
-			return nil, err
+	for _, filename := range filenames {
+		if filename == "" {
+			continue
+		}
+
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			continue
 		}
-	}
 
-	err := k.Load(env.Provider(envPrefix, ".", func(s string) string {
-		key := strings.ToLower(strings.TrimPrefix(s, envPrefix))
-		parts := strings.Split(key, "_")
-		if len(parts) >= 3 {
-			result := parts[0] + "." + strings.Join(parts[1:], "_")
-			return result
+		if err := k.Load(file.Provider(filename), yaml.Parser()); err != nil {
+			return nil, fmt.Errorf("load %s: %w", filename, err)
 		}
-		return strings.Replace(key, "_", ".", -1) // TODO: make robust implementation
-	}), nil)
+	}
+
+	keys := envKeys(reflect.TypeOf(cfg))
+	err := k.Load(env.Provider(envPrefix, ".", envKeyTransformer(envPrefix, keys)), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -62,3 +80,45 @@ func Load[S any](envPrefix string, filename string) (*Config[S], error) {
 
 	return &cfg, nil
 }
+
+// Validate checks required sections and bounds, reporting every problem at once.
+// The service section is validated too when it implements Validate() error.
+func (c *Config[S]) Validate() error {
+	var errs []error
+
+	if c.Postgres == nil {
+		errs = append(errs, errors.New("postgres section is required"))
+	} else if err := c.Postgres.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.Logger != nil {
+		if err := c.Logger.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Tracing != nil {
+		if err := c.Tracing.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := c.Smtp.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.Service == nil {
+		errs = append(errs, errors.New("service section is required"))
+	} else if v, ok := any(c.Service).(validator); ok {
+		if err := v.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrValidation, errors.Join(errs...))
+	}
+
+	return nil
+}
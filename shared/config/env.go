@@ -0,0 +1,79 @@
+package config
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// envKeys maps env var names (without prefix) to koanf paths using the koanf tags of t,
+// e.g. POSTGRES_MAX_CONN_LIFETIME -> postgres.max_conn_lifetime
+func envKeys(t reflect.Type) map[string]string {
+	keys := make(map[string]string)
+	collectEnvKeys(t, "", keys)
+	return keys
+}
+
+func collectEnvKeys(t reflect.Type, prefix string, keys map[string]string) {
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("koanf"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		path := tag
+		if prefix != "" {
+			path = prefix + "." + tag
+		}
+
+		if isNestedStruct(field.Type) {
+			collectEnvKeys(field.Type, path, keys)
+			continue
+		}
+
+		envKey := strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+		if _, exists := keys[envKey]; !exists {
+			keys[envKey] = path
+		}
+	}
+}
+
+func isNestedStruct(t reflect.Type) bool {
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return false
+	}
+	return !reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// envKeyTransformer returns the koanf env callback resolving names through the struct tags.
+// Unknown variables fall back to a flat lower-cased key.
+func envKeyTransformer(envPrefix string, keys map[string]string) func(string) string {
+	return func(s string) string {
+		key := strings.TrimPrefix(s, envPrefix)
+		if path, ok := keys[strings.ToUpper(key)]; ok {
+			return path
+		}
+		return strings.ToLower(key)
+	}
+}
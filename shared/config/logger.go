@@ -1,5 +1,43 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	LoggerFormatJson    = "json"
+	LoggerFormatConsole = "console"
+
+	LoggerOutputStdout = "stdout"
+	LoggerOutputStderr = "stderr"
+)
+
+var (
+	LoggerLevels  = []string{"debug", "info", "warn", "error"}
+	LoggerFormats = []string{LoggerFormatJson, LoggerFormatConsole}
+)
+
 type Logger struct {
+	// Level is the lowest severity written; everything is logged when empty
 	Level string `koanf:"level"`
+	// Format is json (default) or console, a human-readable layout for local runs
+	Format string `koanf:"format"`
+	// Output is stdout (default), stderr or a file path; the file is created if missing and appended to
+	Output string `koanf:"output"`
+}
+
+func (l *Logger) Validate() error {
+	var errs []error
+
+	if l.Level != "" && !slices.Contains(LoggerLevels, l.Level) {
+		errs = append(errs, fmt.Errorf("logger: level must be one of %v, got %q", LoggerLevels, l.Level))
+	}
+
+	if l.Format != "" && !slices.Contains(LoggerFormats, l.Format) {
+		errs = append(errs, fmt.Errorf("logger: format must be one of %v, got %q", LoggerFormats, l.Format))
+	}
+
+	return errors.Join(errs...)
 }
@@ -1,11 +1,16 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"time"
 )
 
 type Postgres struct {
+	// Url is a postgres:// connection URL such as a platform's DATABASE_URL; when set it replaces
+	// host, port, username, password, database and ssl_mode
+	Url               string        `koanf:"url"`
 	Host              string        `koanf:"host"`
 	Port              int           `koanf:"port"`
 	Username          string        `koanf:"username"`
@@ -17,9 +22,24 @@ type Postgres struct {
 	MaxConnLifetime   time.Duration `koanf:"max_conn_lifetime"`
 	MaxConnIdleTime   time.Duration `koanf:"max_conn_idle_time"`
 	HealthCheckPeriod time.Duration `koanf:"health_check_period"`
+	Retry             Retry         `koanf:"retry"`
+	// StatementTimeout makes the server cancel any statement running longer, zero leaves it unbounded
+	StatementTimeout time.Duration `koanf:"statement_timeout"`
+	// SlowQueryThreshold logs queries taking at least this long at warn level, zero disables it
+	SlowQueryThreshold time.Duration `koanf:"slow_query_threshold"`
+	// SlowQueryLogSize keeps the last this many slow queries in memory for the debug endpoint, zero keeps none
+	SlowQueryLogSize int `koanf:"slow_query_log_size"`
+	// ReplicaDsn optionally points reads at a replica, writes always go to the primary
+	ReplicaDsn string `koanf:"replica_dsn"`
+	// StatsInterval logs the connection pool stats this often, zero disables it
+	StatsInterval time.Duration `koanf:"stats_interval"`
 }
 
 func (s *Postgres) Dsn() string {
+	if s.Url != "" {
+		return s.Url
+	}
+
 	return fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		s.Host,
@@ -31,6 +51,78 @@ func (s *Postgres) Dsn() string {
 	)
 }
 
+func (s *Postgres) ReplicaEnabled() bool {
+	return s.ReplicaDsn != ""
+}
+
 func (s *Postgres) Dialect() string {
 	return "postgres"
 }
+
+func (s *Postgres) Validate() error {
+	var errs []error
+
+	if s.Url != "" {
+		if err := validatePostgresUrl(s.Url); err != nil {
+			errs = append(errs, err)
+		}
+	} else {
+		if s.Host == "" {
+			errs = append(errs, errors.New("postgres: host is required"))
+		}
+
+		if s.Port <= 0 || s.Port > 65535 {
+			errs = append(errs, fmt.Errorf("postgres: port must be between 1 and 65535, got %d", s.Port))
+		}
+
+		if s.Database == "" {
+			errs = append(errs, errors.New("postgres: database is required"))
+		}
+	}
+
+	if s.MaxConns < 0 || s.MinConns < 0 {
+		errs = append(errs, errors.New("postgres: connection limits cannot be negative"))
+	}
+
+	if s.MaxConns > 0 && s.MinConns > s.MaxConns {
+		errs = append(errs, fmt.Errorf("postgres: min_conns (%d) cannot exceed max_conns (%d)", s.MinConns, s.MaxConns))
+	}
+
+	if s.StatementTimeout < 0 {
+		errs = append(errs, errors.New("postgres: statement_timeout cannot be negative"))
+	}
+
+	if s.SlowQueryThreshold < 0 {
+		errs = append(errs, errors.New("postgres: slow_query_threshold cannot be negative"))
+	}
+
+	if s.SlowQueryLogSize < 0 {
+		errs = append(errs, errors.New("postgres: slow_query_log_size cannot be negative"))
+	}
+
+	if s.StatsInterval < 0 {
+		errs = append(errs, errors.New("postgres: stats_interval cannot be negative"))
+	}
+
+	if err := s.Retry.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("postgres: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// validatePostgresUrl checks the shape of a connection URL; the host may be left out to use the default
+// socket, so only the scheme is required
+func validatePostgresUrl(rawUrl string) error {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		// the parse error quotes the URL, which holds the password
+		return errors.New("postgres: url is not a valid URL")
+	}
+
+	if parsed.Scheme != "postgres" && parsed.Scheme != "postgresql" {
+		return fmt.Errorf("postgres: url must start with postgres:// or postgresql://, got scheme %q", parsed.Scheme)
+	}
+
+	return nil
+}
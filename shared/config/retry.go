@@ -0,0 +1,57 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	defaultRetryMaxAttempts    = 3
+	defaultRetryInitialBackoff = 50 * time.Millisecond
+	defaultRetryMaxBackoff     = time.Second
+)
+
+// Retry configures re-execution of operations failing with transient errors; zero values fall back to defaults
+type Retry struct {
+	MaxAttempts    int           `koanf:"max_attempts"` // including the first attempt, 1 disables retries
+	InitialBackoff time.Duration `koanf:"initial_backoff"`
+	MaxBackoff     time.Duration `koanf:"max_backoff"`
+}
+
+func (r Retry) Attempts() int {
+	if r.MaxAttempts <= 0 {
+		return defaultRetryMaxAttempts
+	}
+	return r.MaxAttempts
+}
+
+// Backoff returns the delay before the given retry (1-based), doubling from InitialBackoff up to MaxBackoff
+func (r Retry) Backoff(retry int) time.Duration {
+	backoff, maxBackoff := r.InitialBackoff, r.MaxBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryInitialBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+
+	for i := 1; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, maxBackoff)
+}
+
+func (r Retry) Validate() error {
+	var errs []error
+
+	if r.MaxAttempts < 0 {
+		errs = append(errs, errors.New("retry: max_attempts cannot be negative"))
+	}
+
+	if r.InitialBackoff < 0 || r.MaxBackoff < 0 {
+		errs = append(errs, errors.New("retry: backoff cannot be negative"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Smtp struct {
+	// Host of the SMTP server; mail is only logged when empty
+	Host     string `koanf:"host"`
+	Port     int    `koanf:"port"`
+	Username string `koanf:"username"` // PLAIN auth is skipped when empty
+	Password string `koanf:"password"`
+	From     string `koanf:"from"`
+	// Workers send mail in the background, so a slow server never holds up a request
+	Workers WorkerPool `koanf:"workers"`
+}
+
+func (s *Smtp) Enabled() bool {
+	return s != nil && s.Host != ""
+}
+
+func (s *Smtp) Address() string {
+	return fmt.Sprintf("%s:%d", s.Host, s.Port)
+}
+
+func (s *Smtp) Validate() error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	var errs []error
+
+	if s.Port <= 0 || s.Port > 65535 {
+		errs = append(errs, fmt.Errorf("smtp: port must be between 1 and 65535, got %d", s.Port))
+	}
+
+	if s.From == "" {
+		errs = append(errs, errors.New("smtp: from is required"))
+	}
+
+	if err := s.Workers.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("smtp: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
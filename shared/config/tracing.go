@@ -0,0 +1,34 @@
+package config
+
+import "fmt"
+
+// defaultTracingSampleRatio samples every trace when no ratio is configured
+const defaultTracingSampleRatio = 1
+
+type Tracing struct {
+	// OtlpEndpoint is the OTLP/HTTP collector address (host:port); tracing is a no-op when empty
+	OtlpEndpoint string `koanf:"otlp_endpoint"`
+	Insecure     bool   `koanf:"insecure"`
+	ServiceName  string `koanf:"service_name"`
+	// SampleRatio is the share of new traces sampled, 0 samples none; every trace is sampled when it is not set
+	SampleRatio *float64 `koanf:"sample_ratio"`
+}
+
+func (t *Tracing) Enabled() bool {
+	return t != nil && t.OtlpEndpoint != ""
+}
+
+// Ratio is the configured SampleRatio, defaultTracingSampleRatio when it is not set
+func (t *Tracing) Ratio() float64 {
+	if t.SampleRatio == nil {
+		return defaultTracingSampleRatio
+	}
+	return *t.SampleRatio
+}
+
+func (t *Tracing) Validate() error {
+	if ratio := t.Ratio(); ratio < 0 || ratio > 1 {
+		return fmt.Errorf("tracing: sample_ratio must be between 0 and 1, got %v", ratio)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package config
+
+import "errors"
+
+const (
+	defaultWorkerPoolSize      = 4
+	defaultWorkerPoolQueueSize = 100
+)
+
+// WorkerPool configures a bounded pool of background workers; zero values fall back to defaults
+type WorkerPool struct {
+	Size      int `koanf:"size"`       // jobs running at once
+	QueueSize int `koanf:"queue_size"` // jobs waiting for a free worker
+}
+
+func (p WorkerPool) Workers() int {
+	if p.Size <= 0 {
+		return defaultWorkerPoolSize
+	}
+	return p.Size
+}
+
+func (p WorkerPool) Capacity() int {
+	if p.QueueSize <= 0 {
+		return defaultWorkerPoolQueueSize
+	}
+	return p.QueueSize
+}
+
+func (p WorkerPool) Validate() error {
+	var errs []error
+
+	if p.Size < 0 {
+		errs = append(errs, errors.New("workers: size cannot be negative"))
+	}
+
+	if p.QueueSize < 0 {
+		errs = append(errs, errors.New("workers: queue_size cannot be negative"))
+	}
+
+	return errors.Join(errs...)
+}
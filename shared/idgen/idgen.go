@@ -0,0 +1,46 @@
+// Package idgen generates entity identifiers, so the UUID version is chosen in one place
+package idgen
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// UUID versions New can generate
+const (
+	V4 = 4 // random
+	V7 = 7 // time-ordered, new keys land at the end of B-tree indexes instead of all over them
+)
+
+// version is read by every New, so it is stored atomically and may be switched while IDs are generated
+var version atomic.Int64
+
+// SetVersion switches New to UUIDs of version; zero means the default v4.
+// An unsupported version fails and leaves the current one in place.
+func SetVersion(v int) error {
+	switch v {
+	case 0:
+		v = V4
+	case V4, V7:
+	default:
+		return fmt.Errorf("unsupported UUID version %d, expected %d or %d", v, V4, V7)
+	}
+	version.Store(int64(v))
+	return nil
+}
+
+// New returns a new identifier of the configured version
+func New() uuid.UUID {
+	if version.Load() == V7 {
+		return newV7()
+	}
+	return uuid.New()
+}
+
+// newV7 panics like uuid.New when the random source fails.
+// IDs generated within the same millisecond stay ordered thanks to the sub-millisecond counter of the uuid package.
+func newV7() uuid.UUID {
+	return uuid.Must(uuid.NewV7())
+}
@@ -0,0 +1,46 @@
+package idgen
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setVersionForTest(t *testing.T, v int) {
+	t.Helper()
+	require.NoError(t, SetVersion(v))
+	t.Cleanup(func() { version.Store(V4) })
+}
+
+func TestNew_V4ByDefault(t *testing.T) {
+	assert.Equal(t, uuid.Version(4), New().Version())
+}
+
+func TestNew_V7IsMonotonic(t *testing.T) {
+	setVersionForTest(t, V7)
+
+	// far more IDs than fit in distinct milliseconds
+	previous := New()
+	assert.Equal(t, uuid.Version(7), previous.Version())
+	for range 10000 {
+		next := New()
+		require.Negative(t, bytes.Compare(previous[:], next[:]), "%s generated after %s", next, previous)
+		previous = next
+	}
+}
+
+func TestSetVersion(t *testing.T) {
+	setVersionForTest(t, V4)
+	assert.Equal(t, uuid.Version(4), New().Version())
+
+	require.NoError(t, SetVersion(V7))
+	assert.Equal(t, uuid.Version(7), New().Version())
+
+	require.NoError(t, SetVersion(0))
+	assert.Equal(t, uuid.Version(4), New().Version())
+
+	assert.ErrorContains(t, SetVersion(6), "unsupported UUID version 6")
+}
@@ -1,18 +1,90 @@
 package shared
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/pkgerrors"
+
+	"shared/config"
 )
 
+// Logger writes JSON to stdout until ConfigureLogger applies the configuration
 var Logger zerolog.Logger
 
+// loggerFile is the file the global Logger writes to, closed when the logger is configured again
+var loggerFile *os.File
+
 func init() {
 	// Enable stack traces for errors
 	zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
 	zerolog.TimeFieldFormat = time.DateTime
-	Logger = zerolog.New(os.Stdout).With().Timestamp().Stack().Logger()
+	Logger = NewLogger(os.Stdout, nil)
+}
+
+// ConfigureLogger replaces the global Logger with one built from the config and returns it.
+// On error the previous logger is kept.
+func ConfigureLogger(cfg *config.Logger) (zerolog.Logger, error) {
+	var output string
+	if cfg != nil {
+		output = cfg.Output
+	}
+
+	w, err := openLoggerOutput(output)
+	if err != nil {
+		return Logger, err
+	}
+
+	previous := loggerFile
+	loggerFile, _ = w.(*os.File)
+	if loggerFile == os.Stdout || loggerFile == os.Stderr {
+		loggerFile = nil
+	}
+
+	Logger = NewLogger(w, cfg)
+
+	if previous != nil {
+		previous.Close()
+	}
+
+	return Logger, nil
+}
+
+// NewLogger builds a logger writing to w. A nil config keeps the defaults: JSON at every level.
+// The config is expected to be validated, an unknown level is ignored. Output is not used here.
+func NewLogger(w io.Writer, cfg *config.Logger) zerolog.Logger {
+	if cfg == nil {
+		cfg = &config.Logger{}
+	}
+
+	if cfg.Format == config.LoggerFormatConsole {
+		w = zerolog.ConsoleWriter{Out: w, TimeFormat: time.DateTime}
+	}
+
+	logger := zerolog.New(w).With().Timestamp().Stack().Logger()
+
+	if level, err := zerolog.ParseLevel(cfg.Level); err == nil && cfg.Level != "" {
+		logger = logger.Level(level)
+	}
+
+	return logger
+}
+
+func openLoggerOutput(output string) (io.Writer, error) {
+	switch output {
+	case "", config.LoggerOutputStdout:
+		return os.Stdout, nil
+	case config.LoggerOutputStderr:
+		return os.Stderr, nil
+	}
+
+	file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open log output: %w", err)
+	}
+
+	return file, nil
 }
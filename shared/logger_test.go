@@ -0,0 +1,101 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"shared/config"
+)
+
+func TestNewLogger_Level(t *testing.T) {
+	tests := []struct {
+		level    string
+		expected []string
+	}{
+		{level: "", expected: []string{"debug", "info", "warn", "error"}},
+		{level: "debug", expected: []string{"debug", "info", "warn", "error"}},
+		{level: "info", expected: []string{"info", "warn", "error"}},
+		{level: "warn", expected: []string{"warn", "error"}},
+		{level: "error", expected: []string{"error"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("level "+tt.level, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := NewLogger(&buf, &config.Logger{Level: tt.level})
+
+			logger.Debug().Msg("debug")
+			logger.Info().Msg("info")
+			logger.Warn().Msg("warn")
+			logger.Error().Msg("error")
+
+			var written []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				var entry map[string]any
+				require.NoError(t, json.Unmarshal([]byte(line), &entry))
+				written = append(written, entry["message"].(string))
+			}
+			assert.Equal(t, tt.expected, written)
+		})
+	}
+}
+
+func TestNewLogger_ConsoleFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, &config.Logger{Format: config.LoggerFormatConsole})
+
+	logger.Info().Str("user_id", "42").Msg("user created")
+
+	line := buf.String()
+	assert.False(t, json.Valid([]byte(line)), "console output should not be JSON: %s", line)
+	assert.Contains(t, line, "INF")
+	assert.Contains(t, line, "user created")
+	assert.Contains(t, line, "user_id=")
+	assert.Contains(t, line, "42")
+}
+
+func TestConfigureLogger_Output(t *testing.T) {
+	previous := Logger
+	t.Cleanup(func() {
+		_, err := ConfigureLogger(nil)
+		require.NoError(t, err)
+		Logger = previous
+	})
+
+	for output, expected := range map[string]*os.File{"": os.Stdout, "stdout": os.Stdout, "stderr": os.Stderr} {
+		w, err := openLoggerOutput(output)
+		require.NoError(t, err)
+		assert.Same(t, expected, w, "output %q", output)
+	}
+
+	path := filepath.Join(t.TempDir(), "mts.log")
+	require.NoError(t, os.WriteFile(path, []byte("{\"message\":\"earlier run\"}\n"), 0o644))
+
+	logger, err := ConfigureLogger(&config.Logger{Level: "warn", Output: path})
+	require.NoError(t, err)
+	logger.Info().Msg("filtered")
+	Logger.Warn().Msg("written")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2, "the file is appended to: %s", data)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "written", entry["message"])
+
+	// a path that cannot be opened keeps the current logger
+	_, err = ConfigureLogger(&config.Logger{Output: filepath.Join(t.TempDir(), "missing", "mts.log")})
+	assert.ErrorContains(t, err, "open log output")
+	assert.Equal(t, zerolog.WarnLevel, Logger.GetLevel())
+}
@@ -1,6 +1,7 @@
 package shared
 
 import (
+	"context"
 	"database/sql"
 	"os"
 	"path"
@@ -37,7 +38,8 @@ func MigrationDirectory(dialect string) (string, error) {
 	return "", ErrMigrationDirectoryNotFound
 }
 
-func ApplyMigrations(cfg config.Migration) error {
+// ApplyMigrations brings the schema up to date, canceling ctx stops it between and inside migrations
+func ApplyMigrations(ctx context.Context, cfg config.Migration) error {
 	migrationDir, err := MigrationDirectory(cfg.Dialect())
 	if err != nil {
 		return err
@@ -46,9 +48,10 @@ func ApplyMigrations(cfg config.Migration) error {
 	if err != nil {
 		return err
 	}
+	defer conn.Close()
 	if err = goose.SetDialect(cfg.Dialect()); err != nil {
 		return err
 	}
 	goose.SetLogger(GooseLogger{logger: Logger})
-	return goose.Up(conn, migrationDir)
+	return goose.UpContext(ctx, conn, migrationDir)
 }
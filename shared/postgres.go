@@ -2,6 +2,7 @@ package shared
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog"
@@ -11,8 +12,18 @@ import (
 	"shared/config"
 )
 
-func ConnectPostgres(ctx context.Context, cfg *config.Postgres) (*pgxpool.Pool, error) {
-	poolConfig, err := pgxpool.ParseConfig(cfg.Dsn())
+// ConnectPostgres opens a pool to cfg.Dsn, its slow queries are recorded to slowQueries unless it is nil
+func ConnectPostgres(ctx context.Context, cfg *config.Postgres, slowQueries *SlowQueryLog) (*pgxpool.Pool, error) {
+	return connectPostgres(ctx, cfg, cfg.Dsn(), slowQueries, "connected to postgres")
+}
+
+// ConnectPostgresReplica opens a pool to cfg.ReplicaDsn with the primary's pool settings
+func ConnectPostgresReplica(ctx context.Context, cfg *config.Postgres, slowQueries *SlowQueryLog) (*pgxpool.Pool, error) {
+	return connectPostgres(ctx, cfg, cfg.ReplicaDsn, slowQueries, "connected to postgres replica")
+}
+
+func connectPostgres(ctx context.Context, cfg *config.Postgres, dsn string, slowQueries *SlowQueryLog, message string) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -22,6 +33,15 @@ func ConnectPostgres(ctx context.Context, cfg *config.Postgres) (*pgxpool.Pool,
 	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
 	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
 	poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	poolConfig.ConnConfig.Tracer = &postgresTracer{
+		logger:             &Logger,
+		slowQueryThreshold: cfg.SlowQueryThreshold,
+		slowQueries:        slowQueries,
+	}
+	if cfg.StatementTimeout > 0 {
+		// the server cancels the statement with query_canceled (57014) once the timeout passes
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
@@ -35,11 +55,11 @@ func ConnectPostgres(ctx context.Context, cfg *config.Postgres) (*pgxpool.Pool,
 
 	zerolog.Ctx(ctx).
 		Info().
-		Str("host", cfg.Host).
-		Int("port", cfg.Port).
-		Str("username", cfg.Username).
-		Str("database", cfg.Database).
-		Msg("connected to postgres")
+		Str("host", poolConfig.ConnConfig.Host).
+		Uint16("port", poolConfig.ConnConfig.Port).
+		Str("username", poolConfig.ConnConfig.User).
+		Str("database", poolConfig.ConnConfig.Database).
+		Msg(message)
 
 	return pool, nil
 }
@@ -1,8 +1,15 @@
 package shared
 
 import (
+	"bytes"
+	"net"
+	"net/url"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -16,7 +23,7 @@ func (s *PostgresSuite) SetupSuite() {
 }
 
 func (s *PostgresSuite) TestConnectPostgres() {
-	postgresConn, err := ConnectPostgres(s.Ctx, s.Config.Postgres)
+	postgresConn, err := ConnectPostgres(s.Ctx, s.Config.Postgres, nil)
 	s.Require().NoError(err)
 	s.Require().NotNil(postgresConn)
 
@@ -29,6 +36,72 @@ func (s *PostgresSuite) TestConnectPostgres() {
 	s.Require().Contains(version, "PostgreSQL")
 }
 
+func (s *PostgresSuite) TestConnectPostgres_Url() {
+	fields := s.Config.Postgres
+	cfg := *fields
+	cfg.Url = (&url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(fields.Username, fields.Password),
+		Host:     net.JoinHostPort(fields.Host, strconv.Itoa(fields.Port)),
+		Path:     fields.Database,
+		RawQuery: "sslmode=" + fields.SslMode,
+	}).String()
+	// the url wins over the fields, which no longer point at the database
+	cfg.Host, cfg.Port, cfg.Database = "unreachable.invalid", 1, "missing"
+	s.Require().NoError(cfg.Validate())
+
+	postgresConn, err := ConnectPostgres(s.Ctx, &cfg, nil)
+	s.Require().NoError(err)
+	defer postgresConn.Close()
+
+	var database string
+	s.Require().NoError(postgresConn.QueryRow(s.Ctx, "SELECT current_database()").Scan(&database))
+	s.Equal(fields.Database, database)
+}
+
+func (s *PostgresSuite) TestStatementTimeout_LogsSlowQuery() {
+	var buf bytes.Buffer
+	previous := Logger
+	Logger = zerolog.New(&buf)
+	defer func() { Logger = previous }()
+
+	cfg := *s.Config.Postgres
+	cfg.StatementTimeout = 100 * time.Millisecond
+	cfg.SlowQueryThreshold = 50 * time.Millisecond
+
+	postgresConn, err := ConnectPostgres(s.Ctx, &cfg, nil)
+	s.Require().NoError(err)
+	defer postgresConn.Close()
+
+	_, err = postgresConn.Exec(s.Ctx, "SELECT pg_sleep(1)")
+	var pgErr *pgconn.PgError
+	s.Require().ErrorAs(err, &pgErr)
+	s.Equal("57014", pgErr.Code, "the server cancels the statement")
+
+	s.Contains(buf.String(), `"level":"warn"`)
+	s.Contains(buf.String(), `"message":"slow query"`)
+	s.Contains(buf.String(), `"sql":"SELECT pg_sleep(1)"`)
+}
+
+func (s *PostgresSuite) TestSlowQueries_KeepsSlowQuery() {
+	slowQueries := NewSlowQueryLog(5)
+
+	cfg := *s.Config.Postgres
+	cfg.SlowQueryThreshold = 50 * time.Millisecond
+
+	postgresConn, err := ConnectPostgres(s.Ctx, &cfg, slowQueries)
+	s.Require().NoError(err)
+	defer postgresConn.Close()
+
+	_, err = postgresConn.Exec(s.Ctx, "SELECT pg_sleep(0.1)")
+	s.Require().NoError(err)
+
+	queries := slowQueries.Slowest(0)
+	s.Require().NotEmpty(queries)
+	s.Equal("SELECT pg_sleep(0.1)", queries[0].Sql)
+	s.GreaterOrEqual(queries[0].Duration, 100*time.Millisecond)
+}
+
 func TestPostgresSuite(t *testing.T) {
 	suite.Run(t, new(PostgresSuite))
 }
@@ -0,0 +1,57 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"shared/config"
+)
+
+const (
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+	pgConnectionException  = "08" // class prefix
+)
+
+// Retry runs fn until it succeeds, fails with a non-transient error or runs out of attempts,
+// backing off exponentially between attempts. Waiting stops as soon as ctx is done.
+func Retry(ctx context.Context, cfg config.Retry, fn func(ctx context.Context) error) error {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil || !IsTransientPostgresError(err) || attempt >= cfg.Attempts() {
+			return err
+		}
+
+		timer := time.NewTimer(cfg.Backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// IsTransientPostgresError reports whether err is likely to go away when the operation is repeated:
+// serialization failures, deadlocks and connection errors
+func IsTransientPostgresError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgSerializationFailure ||
+			pgErr.Code == pgDeadlockDetected ||
+			strings.HasPrefix(pgErr.Code, pgConnectionException)
+	}
+
+	// the connection failed before the statement reached the server
+	var connectErr *pgconn.ConnectError
+	return errors.As(err, &connectErr) || pgconn.SafeToRetry(err)
+}
@@ -0,0 +1,101 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"shared/config"
+)
+
+// fakeExecutor fails with the queued errors before succeeding
+type fakeExecutor struct {
+	errs  []error
+	calls int
+}
+
+func (e *fakeExecutor) exec(context.Context) error {
+	e.calls++
+	if len(e.errs) == 0 {
+		return nil
+	}
+
+	err := e.errs[0]
+	e.errs = e.errs[1:]
+	return err
+}
+
+var fastRetry = config.Retry{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+func TestRetry_SucceedsAfterTransientErrors(t *testing.T) {
+	executor := &fakeExecutor{errs: []error{
+		&pgconn.PgError{Code: pgSerializationFailure},
+		&pgconn.PgError{Code: "08006"},
+	}}
+
+	require.NoError(t, Retry(context.Background(), fastRetry, executor.exec))
+	assert.Equal(t, 3, executor.calls)
+}
+
+func TestRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	executor := &fakeExecutor{errs: []error{
+		&pgconn.PgError{Code: pgDeadlockDetected},
+		&pgconn.PgError{Code: pgDeadlockDetected},
+		&pgconn.PgError{Code: pgDeadlockDetected},
+		&pgconn.PgError{Code: pgDeadlockDetected},
+	}}
+
+	err := Retry(context.Background(), fastRetry, executor.exec)
+
+	var pgErr *pgconn.PgError
+	require.ErrorAs(t, err, &pgErr)
+	assert.Equal(t, pgDeadlockDetected, pgErr.Code)
+	assert.Equal(t, 3, executor.calls)
+}
+
+func TestRetry_DoesNotRetryPermanentErrors(t *testing.T) {
+	permanent := &pgconn.PgError{Code: "23505"} // unique violation
+	executor := &fakeExecutor{errs: []error{permanent}}
+
+	assert.ErrorIs(t, Retry(context.Background(), fastRetry, executor.exec), permanent)
+	assert.Equal(t, 1, executor.calls)
+}
+
+func TestRetry_StopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	executor := &fakeExecutor{errs: []error{&pgconn.PgError{Code: pgSerializationFailure}}}
+
+	slowRetry := config.Retry{MaxAttempts: 5, InitialBackoff: time.Hour}
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	err := Retry(ctx, slowRetry, executor.exec)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, executor.calls)
+}
+
+func TestIsTransientPostgresError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+	}{
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}, transient: true},
+		{name: "deadlock", err: &pgconn.PgError{Code: "40P01"}, transient: true},
+		{name: "connection failure", err: &pgconn.PgError{Code: "08006"}, transient: true},
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}},
+		{name: "context canceled", err: context.Canceled},
+		{name: "plain error", err: errors.New("boom")},
+		{name: "nil", err: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.transient, IsTransientPostgresError(tt.err))
+		})
+	}
+}
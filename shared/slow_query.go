@@ -0,0 +1,81 @@
+package shared
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SlowQuery is a query that took at least the slow query threshold
+type SlowQuery struct {
+	Sql string
+	// ArgsCount stands in for the args, they may hold personal data
+	ArgsCount int
+	Duration  time.Duration
+	StartedAt time.Time
+	// Error is the message of the error the query failed with, empty on success
+	Error string
+}
+
+// SlowQueryLog is a ring buffer of the last slow queries, the oldest query is dropped once it is full.
+// A nil log keeps nothing.
+type SlowQueryLog struct {
+	mu      sync.Mutex
+	queries []SlowQuery
+	// next is the slot the next query is written to once the buffer is full
+	next int
+}
+
+// NewSlowQueryLog returns a log of the last size queries, nil when size is not positive
+func NewSlowQueryLog(size int) *SlowQueryLog {
+	if size <= 0 {
+		return nil
+	}
+	return &SlowQueryLog{queries: make([]SlowQuery, 0, size)}
+}
+
+func (l *SlowQueryLog) Add(query SlowQuery) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.queries) < cap(l.queries) {
+		l.queries = append(l.queries, query)
+		return
+	}
+
+	l.queries[l.next] = query
+	l.next = (l.next + 1) % len(l.queries)
+}
+
+// Slowest returns up to n kept queries, slowest first; n <= 0 returns all of them
+func (l *SlowQueryLog) Slowest(n int) []SlowQuery {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	queries := slices.Clone(l.queries)
+	l.mu.Unlock()
+
+	slices.SortStableFunc(queries, func(a, b SlowQuery) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+
+	if n > 0 && n < len(queries) {
+		queries = queries[:n]
+	}
+	return queries
+}
+
+// Size is the number of queries the log keeps at most
+func (l *SlowQueryLog) Size() int {
+	if l == nil {
+		return 0
+	}
+	return cap(l.queries)
+}
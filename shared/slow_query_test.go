@@ -0,0 +1,49 @@
+package shared
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowQueryLog_KeepsLastQueries(t *testing.T) {
+	log := NewSlowQueryLog(3)
+
+	for i := range 5 {
+		log.Add(SlowQuery{Sql: fmt.Sprintf("SELECT %d", i), Duration: time.Duration(10-i) * time.Millisecond})
+	}
+
+	// the two oldest, which were also the slowest, are dropped
+	queries := log.Slowest(0)
+	require.Len(t, queries, 3)
+	assert.Equal(t, "SELECT 2", queries[0].Sql)
+	assert.Equal(t, "SELECT 3", queries[1].Sql)
+	assert.Equal(t, "SELECT 4", queries[2].Sql)
+	assert.Equal(t, 3, log.Size())
+}
+
+func TestSlowQueryLog_Slowest(t *testing.T) {
+	log := NewSlowQueryLog(10)
+	log.Add(SlowQuery{Sql: "SELECT fast", Duration: time.Millisecond})
+	log.Add(SlowQuery{Sql: "SELECT slowest", Duration: time.Second})
+	log.Add(SlowQuery{Sql: "SELECT slow", Duration: 100 * time.Millisecond})
+
+	queries := log.Slowest(2)
+	require.Len(t, queries, 2)
+	assert.Equal(t, "SELECT slowest", queries[0].Sql)
+	assert.Equal(t, "SELECT slow", queries[1].Sql)
+
+	assert.Len(t, log.Slowest(5), 3)
+}
+
+func TestSlowQueryLog_Disabled(t *testing.T) {
+	log := NewSlowQueryLog(0)
+	assert.Nil(t, log)
+
+	log.Add(SlowQuery{Sql: "SELECT 1"})
+	assert.Empty(t, log.Slowest(0))
+	assert.Zero(t, log.Size())
+}
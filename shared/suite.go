@@ -3,7 +3,9 @@ package shared
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -55,6 +57,7 @@ type Suite[S any] struct {
 	MailhogEnabled   bool
 	MailhogContainer testcontainers.Container
 	MailhogPort      int
+	MailhogApiPort   int
 }
 
 func (s *Suite[S]) SetupSuite() {
@@ -129,7 +132,7 @@ func (s *Suite[S]) startPostgres() {
 		Msg("postgres up")
 
 	// connect to postgres
-	s.PostgresConn, err = ConnectPostgres(s.Ctx, s.Config.Postgres)
+	s.PostgresConn, err = ConnectPostgres(s.Ctx, s.Config.Postgres, nil)
 	s.Require().NoError(err)
 }
 
@@ -159,6 +162,7 @@ func (s *Suite[S]) startMailhog() {
 		ExposedPorts: []string{"1025/tcp", "8025/tcp"},
 		WaitingFor: wait.ForAll(
 			wait.ForListeningPort("1025/tcp"),
+			wait.ForListeningPort("8025/tcp"),
 		),
 	}
 	var err error
@@ -171,6 +175,61 @@ func (s *Suite[S]) startMailhog() {
 	mailhogPort, err := s.MailhogContainer.MappedPort(s.Ctx, "1025")
 	s.Require().NoError(err)
 	s.MailhogPort = mailhogPort.Int()
+
+	mailhogApiPort, err := s.MailhogContainer.MappedPort(s.Ctx, "8025")
+	s.Require().NoError(err)
+	s.MailhogApiPort = mailhogApiPort.Int()
+
+	s.Config.Smtp = &config.Smtp{
+		Host: "localhost",
+		Port: s.MailhogPort,
+		From: "noreply@example.com",
+	}
+}
+
+// MailhogMessage is a message captured by Mailhog
+type MailhogMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// MailhogMessages returns the messages captured by Mailhog, newest first
+func (s *Suite[S]) MailhogMessages() ([]MailhogMessage, error) {
+	url := fmt.Sprintf("http://localhost:%d/api/v2/messages", s.MailhogApiPort)
+	req, err := http.NewRequestWithContext(s.Ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var payload struct {
+		Items []struct {
+			Content struct {
+				Headers map[string][]string
+				Body    string
+			}
+		}
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	messages := make([]MailhogMessage, 0, len(payload.Items))
+	for _, item := range payload.Items {
+		messages = append(messages, MailhogMessage{
+			To:      strings.Join(item.Content.Headers["To"], ", "),
+			Subject: strings.Join(item.Content.Headers["Subject"], ", "),
+			Body:    item.Content.Body,
+		})
+	}
+
+	return messages, nil
 }
 
 func (s *Suite[S]) startLdap() {
@@ -0,0 +1,177 @@
+package shared
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"shared/config"
+)
+
+// InitTracing installs a global OTLP tracer provider and returns its shutdown function.
+// Without a configured endpoint the global no-op provider is kept.
+func InitTracing(ctx context.Context, cfg *config.Tracing) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !cfg.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.OtlpEndpoint)}
+	if cfg.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "mts"
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Ratio()))),
+	)
+	otel.SetTracerProvider(provider)
+
+	zerolog.Ctx(ctx).
+		Info().
+		Str("endpoint", cfg.OtlpEndpoint).
+		Str("service_name", serviceName).
+		Float64("sample_ratio", cfg.Ratio()).
+		Msg("tracing enabled")
+
+	return provider.Shutdown, nil
+}
+
+// postgresTracer creates a client span for every query executed through the pool,
+// logs the queries that take at least slowQueryThreshold and, at debug level, every query
+type postgresTracer struct {
+	logger             *zerolog.Logger
+	slowQueryThreshold time.Duration
+	// slowQueries keeps the slow queries for the debug endpoint, nil keeps none
+	slowQueries *SlowQueryLog
+}
+
+type tracedQueryKey struct{}
+
+// tracedQuery is what the tracer needs to remember about a query until it ends
+type tracedQuery struct {
+	start     time.Time
+	sql       string
+	argsCount int
+	// args are only set when queries are logged at debug level, see redactQueryArgs
+	args []any
+}
+
+// redactedQueryArg replaces the query args that may hold secrets or personal data in logs
+const redactedQueryArg = "[REDACTED]"
+
+func (t *postgresTracer) debug() bool {
+	return t.logger.Debug().Enabled()
+}
+
+func (t *postgresTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	if debug := t.debug(); debug || t.slowQueryThreshold > 0 {
+		query := &tracedQuery{
+			start:     time.Now(),
+			sql:       data.SQL,
+			argsCount: len(data.Args),
+		}
+		if debug {
+			query.args = redactQueryArgs(data.Args)
+		}
+		ctx = context.WithValue(ctx, tracedQueryKey{}, query)
+	}
+
+	ctx, _ = otel.Tracer("shared/postgres").Start(ctx, "postgres.query",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			attribute.String("db.statement", data.SQL),
+		),
+	)
+	return ctx
+}
+
+func (t *postgresTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+	span.End()
+
+	query, ok := ctx.Value(tracedQueryKey{}).(*tracedQuery)
+	if !ok {
+		return
+	}
+	duration := time.Since(query.start)
+
+	if query.args != nil {
+		t.logger.Debug().
+			Err(data.Err).
+			Str("sql", query.sql).
+			Int("args_count", query.argsCount).
+			Interface("args", query.args).
+			Dur("duration", duration).
+			Msg("query")
+	}
+
+	// the args are only counted, they may hold personal data
+	if t.slowQueryThreshold > 0 && duration >= t.slowQueryThreshold {
+		t.logger.Warn().
+			Err(data.Err).
+			Str("sql", query.sql).
+			Int("args_count", query.argsCount).
+			Dur("duration", duration).
+			Msg("slow query")
+
+		slowQuery := SlowQuery{
+			Sql:       query.sql,
+			ArgsCount: query.argsCount,
+			Duration:  duration,
+			StartedAt: query.start,
+		}
+		if data.Err != nil {
+			slowQuery.Error = data.Err.Error()
+		}
+		t.slowQueries.Add(slowQuery)
+	}
+}
+
+// redactQueryArgs keeps the args that can't carry credentials or personal data: numbers, booleans,
+// times and UUIDs. Strings and bytes, such as password hashes, salts, tokens and emails, are redacted.
+func redactQueryArgs(args []any) []any {
+	redacted := make([]any, len(args))
+	for i, arg := range args {
+		switch arg.(type) {
+		case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64,
+			time.Time, time.Duration, uuid.UUID:
+			redacted[i] = arg
+		default:
+			redacted[i] = redactedQueryArg
+		}
+	}
+	return redacted
+}
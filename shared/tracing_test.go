@@ -0,0 +1,103 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresTracer_SlowQuery(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Level(zerolog.InfoLevel)
+	tracer := &postgresTracer{logger: &logger, slowQueryThreshold: 20 * time.Millisecond, slowQueries: NewSlowQueryLog(10)}
+
+	// a fast query is not logged
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	assert.Zero(t, buf.Len())
+
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{
+		SQL:  "SELECT * FROM users WHERE email = $1 AND age > $2",
+		Args: []any{"john@example.com", 18},
+	})
+	time.Sleep(30 * time.Millisecond)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("canceled")})
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "slow query", entry["message"])
+	assert.Equal(t, "SELECT * FROM users WHERE email = $1 AND age > $2", entry["sql"])
+	assert.EqualValues(t, 2, entry["args_count"])
+	assert.Equal(t, "canceled", entry["error"])
+	assert.GreaterOrEqual(t, entry["duration"], float64(30))
+	assert.NotContains(t, buf.String(), "john@example.com")
+
+	// the slow query is kept for the debug endpoint, the fast one is not
+	queries := tracer.slowQueries.Slowest(0)
+	require.Len(t, queries, 1)
+	assert.Equal(t, "SELECT * FROM users WHERE email = $1 AND age > $2", queries[0].Sql)
+	assert.Equal(t, 2, queries[0].ArgsCount)
+	assert.Equal(t, "canceled", queries[0].Error)
+	assert.GreaterOrEqual(t, queries[0].Duration, 30*time.Millisecond)
+	assert.False(t, queries[0].StartedAt.IsZero())
+}
+
+func TestPostgresTracer_SlowQueryDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Level(zerolog.InfoLevel)
+	tracer := &postgresTracer{logger: &logger}
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT pg_sleep(1)"})
+	time.Sleep(5 * time.Millisecond)
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	assert.Zero(t, buf.Len())
+}
+
+func TestPostgresTracer_DebugQueryLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Level(zerolog.DebugLevel)
+	tracer := &postgresTracer{logger: &logger}
+
+	id := uuid.New()
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{
+		SQL:  "INSERT INTO users (id, email, password_hash, salt, age) VALUES ($1, $2, $3, $4, $5)",
+		Args: []any{id, "john@example.com", []byte("hash"), []byte("salt"), 25},
+	})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "debug", entry["level"])
+	assert.Equal(t, "query", entry["message"])
+	assert.Equal(t, "INSERT INTO users (id, email, password_hash, salt, age) VALUES ($1, $2, $3, $4, $5)", entry["sql"])
+	assert.EqualValues(t, 5, entry["args_count"])
+	assert.Equal(t, []any{id.String(), redactedQueryArg, redactedQueryArg, redactedQueryArg, float64(25)}, entry["args"])
+	assert.Contains(t, entry, "duration")
+	assert.NotContains(t, buf.String(), "john@example.com")
+	// the bytes would be logged base64 encoded
+	assert.NotContains(t, buf.String(), "aGFzaA")
+}
+
+func TestPostgresTracer_NoQueryLogAboveDebug(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf).Level(zerolog.InfoLevel)
+	tracer := &postgresTracer{logger: &logger}
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT $1", Args: []any{1}})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+
+	assert.Zero(t, buf.Len())
+	// nothing is remembered about the query when it is not going to be logged
+	assert.Nil(t, ctx.Value(tracedQueryKey{}))
+}
@@ -0,0 +1,116 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog"
+
+	"shared/config"
+)
+
+var (
+	ErrPoolFull   = errors.New("worker pool queue is full, job dropped")
+	ErrPoolClosed = errors.New("worker pool is closed, job dropped")
+)
+
+// WorkerPool runs jobs on a fixed number of goroutines. Submitting never blocks: jobs wait in a bounded
+// queue and are refused once it is full. Close stops accepting jobs and waits for the queued ones.
+type WorkerPool struct {
+	mu     sync.RWMutex
+	closed bool
+	jobs   chan poolJob
+	wg     sync.WaitGroup
+	// stopped is canceled when Close gives up waiting, the jobs' contexts are canceled with it
+	stopped context.Context
+	stop    context.CancelFunc
+}
+
+type poolJob struct {
+	ctx context.Context
+	run func(ctx context.Context)
+}
+
+func NewWorkerPool(cfg config.WorkerPool) *WorkerPool {
+	p := &WorkerPool{jobs: make(chan poolJob, cfg.Capacity())}
+	p.stopped, p.stop = context.WithCancel(context.Background())
+
+	p.wg.Add(cfg.Workers())
+	for range cfg.Workers() {
+		go p.work()
+	}
+
+	return p
+}
+
+// Submit queues run to be called with ctx; the job outlives the request that submitted it,
+// so ctx keeps its values but not its cancellation. It is canceled only when Close gives up on the job.
+func (p *WorkerPool) Submit(ctx context.Context, run func(ctx context.Context)) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrPoolClosed
+	}
+
+	select {
+	case p.jobs <- poolJob{ctx: context.WithoutCancel(ctx), run: run}:
+		return nil
+	default:
+		return ErrPoolFull
+	}
+}
+
+// Close stops accepting jobs and returns once the queued ones have run. When ctx is done first, the
+// contexts of the jobs still running or queued are canceled and Close returns without waiting for them.
+func (p *WorkerPool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		p.stop()
+		return fmt.Errorf("worker pool jobs left unfinished: %w", ctx.Err())
+	}
+}
+
+func (p *WorkerPool) work() {
+	defer p.wg.Done()
+
+	for job := range p.jobs {
+		p.run(job)
+	}
+}
+
+// run keeps a panicking job from taking its worker down with it
+func (p *WorkerPool) run(job poolJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			zerolog.Ctx(job.ctx).Error().Err(fmt.Errorf("%v", r)).Msg("worker pool job panicked")
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(job.ctx)
+	defer cancel()
+	// AfterFunc cancels in a goroutine of its own, a job started after Close gave up must see it at once
+	if p.stopped.Err() != nil {
+		cancel()
+	}
+	defer context.AfterFunc(p.stopped, cancel)()
+
+	job.run(ctx)
+}
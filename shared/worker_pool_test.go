@@ -0,0 +1,163 @@
+package shared
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"shared/config"
+)
+
+func TestWorkerPool_RunsJobs(t *testing.T) {
+	pool := NewWorkerPool(config.WorkerPool{Size: 2, QueueSize: 10})
+	defer pool.Close(context.Background())
+
+	type key struct{}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+	cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(5)
+	var ran atomic.Int32
+	for range 5 {
+		require.NoError(t, pool.Submit(ctx, func(ctx context.Context) {
+			defer wg.Done()
+			// the submitter's values reach the job, its cancellation does not
+			if ctx.Value(key{}) == "value" && ctx.Err() == nil {
+				ran.Add(1)
+			}
+		}))
+	}
+
+	wg.Wait()
+	assert.EqualValues(t, 5, ran.Load())
+}
+
+func TestWorkerPool_LimitsConcurrency(t *testing.T) {
+	pool := NewWorkerPool(config.WorkerPool{Size: 3, QueueSize: 20})
+
+	var running, peak atomic.Int32
+	for range 20 {
+		require.NoError(t, pool.Submit(context.Background(), func(context.Context) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		}))
+	}
+
+	require.NoError(t, pool.Close(context.Background()))
+	assert.EqualValues(t, 3, peak.Load())
+}
+
+func TestWorkerPool_CloseDrainsQueuedJobs(t *testing.T) {
+	pool := NewWorkerPool(config.WorkerPool{Size: 1, QueueSize: 10})
+
+	release := make(chan struct{})
+	var done atomic.Int32
+	require.NoError(t, pool.Submit(context.Background(), func(context.Context) {
+		<-release
+		done.Add(1)
+	}))
+	for range 5 {
+		require.NoError(t, pool.Submit(context.Background(), func(context.Context) {
+			done.Add(1)
+		}))
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		assert.NoError(t, pool.Close(context.Background()))
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("close returned before the queued jobs ran")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("close never returned")
+	}
+	assert.EqualValues(t, 6, done.Load())
+
+	assert.ErrorIs(t, pool.Submit(context.Background(), func(context.Context) {}), ErrPoolClosed)
+}
+
+func TestWorkerPool_RefusesJobsWhenFull(t *testing.T) {
+	pool := NewWorkerPool(config.WorkerPool{Size: 1, QueueSize: 1})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	require.NoError(t, pool.Submit(context.Background(), func(context.Context) {
+		close(started)
+		<-release
+	}))
+	<-started
+
+	require.NoError(t, pool.Submit(context.Background(), func(context.Context) {}))
+	assert.ErrorIs(t, pool.Submit(context.Background(), func(context.Context) {}), ErrPoolFull)
+
+	close(release)
+	require.NoError(t, pool.Close(context.Background()))
+}
+
+func TestWorkerPool_SurvivesPanickingJob(t *testing.T) {
+	pool := NewWorkerPool(config.WorkerPool{Size: 1, QueueSize: 2})
+
+	var ran atomic.Bool
+	require.NoError(t, pool.Submit(context.Background(), func(context.Context) { panic("boom") }))
+	require.NoError(t, pool.Submit(context.Background(), func(context.Context) { ran.Store(true) }))
+
+	require.NoError(t, pool.Close(context.Background()))
+	assert.True(t, ran.Load())
+}
+
+func TestWorkerPool_CloseGivesUpAtTheDeadline(t *testing.T) {
+	pool := NewWorkerPool(config.WorkerPool{Size: 1, QueueSize: 2})
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	require.NoError(t, pool.Submit(context.Background(), func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+	}))
+	var queuedErr atomic.Value
+	require.NoError(t, pool.Submit(context.Background(), func(ctx context.Context) {
+		queuedErr.Store(ctx.Err())
+	}))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := pool.Close(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the job that outlived the deadline is told to stop, the queued one runs already canceled
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the running job was not canceled")
+	}
+	assert.Eventually(t, func() bool {
+		return queuedErr.Load() == context.Canceled
+	}, 5*time.Second, time.Millisecond)
+}